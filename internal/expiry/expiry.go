@@ -0,0 +1,105 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package expiry implements the `benthos_expires_at` metadata convention,
+// which allows a message to carry a deadline after which any stage of a
+// pipeline is free to drop it.
+package expiry
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+// MetadataKey is the metadata key holding the expiry deadline of a message.
+const MetadataKey = "benthos_expires_at"
+
+// Parse a deadline value, which is either an RFC 3339 timestamp or a unix
+// timestamp in (optionally fractional) seconds.
+func Parse(v string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+		return t, nil
+	}
+	secs, err := strconv.ParseFloat(v, 64)
+	if err != nil || math.IsNaN(secs) || math.IsInf(secs, 0) {
+		return time.Time{}, fmt.Errorf("expected an RFC 3339 timestamp or unix seconds, got %q", v)
+	}
+	whole, frac := math.Modf(secs)
+	return time.Unix(int64(whole), int64(frac*1e9)), nil
+}
+
+// Format a deadline in the canonical form written to metadata.
+func Format(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// FromMessage returns the deadline of a message from the metadata key
+// provided, and false if the message has no deadline.
+func FromMessage(msg *service.Message, key string) (time.Time, bool, error) {
+	v, exists := msg.MetaGet(key)
+	if !exists || v == "" {
+		return time.Time{}, false, nil
+	}
+	t, err := Parse(v)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("metadata %v: %w", key, err)
+	}
+	return t, true, nil
+}
+
+// Remaining returns the time left before a message expires, which is zero or
+// negative when it has already expired, and false if the message has no
+// deadline. A deadline that cannot be parsed is treated as no deadline, as
+// outputs should not reject messages over a malformed convention.
+func Remaining(msg *service.Message, now time.Time) (time.Duration, bool) {
+	t, exists, err := FromMessage(msg, MetadataKey)
+	if err != nil || !exists {
+		return 0, false
+	}
+	return t.Sub(now), true
+}
+
+// Expired returns true if a message carries a deadline that has passed.
+func Expired(msg *service.Message, now time.Time) bool {
+	rem, exists := Remaining(msg, now)
+	return exists && rem <= 0
+}
+
+// FilterBatch returns the messages of a batch that have not expired. When no
+// messages have expired the original batch is returned.
+func FilterBatch(batch service.MessageBatch, now time.Time) service.MessageBatch {
+	var kept service.MessageBatch
+	for i, msg := range batch {
+		if !Expired(msg, now) {
+			if kept != nil {
+				kept = append(kept, msg)
+			}
+			continue
+		}
+		if kept == nil {
+			kept = make(service.MessageBatch, i, len(batch))
+			copy(kept, batch[:i])
+		}
+	}
+	if kept == nil {
+		return batch
+	}
+	return kept
+}
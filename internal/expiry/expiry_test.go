@@ -0,0 +1,79 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expiry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct {
+		input    string
+		expected time.Time
+	}{
+		{input: "2024-06-01T12:00:00Z", expected: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)},
+		{input: "2024-06-01T14:00:00.5+02:00", expected: time.Date(2024, 6, 1, 12, 0, 0, 500000000, time.UTC)},
+		{input: "1717243200", expected: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)},
+		{input: " 1717243200.25 ", expected: time.Date(2024, 6, 1, 12, 0, 0, 250000000, time.UTC)},
+	} {
+		t.Run(test.input, func(t *testing.T) {
+			actual, err := Parse(test.input)
+			require.NoError(t, err)
+			assert.True(t, test.expected.Equal(actual), actual)
+		})
+	}
+
+	for _, input := range []string{"", "nope", "NaN", "2024-06-01"} {
+		_, err := Parse(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestFilterBatch(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	newMsg := func(content string, deadline any) *service.Message {
+		msg := service.NewMessage([]byte(content))
+		if deadline != nil {
+			msg.MetaSetMut(MetadataKey, deadline)
+		}
+		return msg
+	}
+
+	batch := service.MessageBatch{
+		newMsg("a", nil),
+		newMsg("b", "999"),
+		newMsg("c", "1001"),
+		newMsg("d", "1000"),
+		newMsg("e", "not a deadline"),
+	}
+
+	var contents []string
+	for _, msg := range FilterBatch(batch, now) {
+		mBytes, err := msg.AsBytes()
+		require.NoError(t, err)
+		contents = append(contents, string(mBytes))
+	}
+	assert.Equal(t, []string{"a", "c", "e"}, contents)
+
+	unexpired := batch[:1]
+	assert.Equal(t, unexpired, FilterBatch(unexpired, now))
+}
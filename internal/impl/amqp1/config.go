@@ -37,6 +37,11 @@ const (
 	azureRenewLockField   = "azure_renew_lock"
 	getMessageHeaderField = "read_header"
 	creditField           = "credit"
+	nackActionField       = "nack_action"
+
+	nackActionModify  = "modify"
+	nackActionRelease = "release"
+	nackActionReject  = "reject"
 
 	// Output
 	targetAddrField  = "target_address"
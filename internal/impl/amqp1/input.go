@@ -74,6 +74,15 @@ func amqp1InputSpec() *service.ConfigSpec {
 				Version("4.26.0").
 				Default(64).
 				Advanced(),
+			service.NewStringAnnotatedEnumField(nackActionField, map[string]string{
+				nackActionModify:  "Settle the message with the `modified` outcome and the `delivery-failed` flag set, allowing the broker to redeliver it and increment its delivery count.",
+				nackActionRelease: "Settle the message with the `released` outcome, allowing the broker to redeliver it without incrementing its delivery count.",
+				nackActionReject:  "Settle the message with the `rejected` outcome, which most brokers will route to a dead letter queue when one is configured.",
+			}).
+				Description("The settlement outcome to use when a message is nacked, which happens when it could not be delivered to the output and was rejected.").
+				Version("4.31.0").
+				Default(nackActionModify).
+				Advanced(),
 			service.NewTLSToggledField(tlsField),
 			saslFieldSpec(),
 		).LintRule(`
@@ -101,6 +110,7 @@ type amqp1Reader struct {
 	renewLock  bool
 	getHeader  bool
 	credit     int // max_in_flight
+	nackAction string
 	connOpts   *amqp.ConnOptions
 	log        *service.Logger
 
@@ -154,6 +164,10 @@ func amqp1ReaderFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (
 		return nil, err
 	}
 
+	if a.nackAction, err = conf.FieldString(nackActionField); err != nil {
+		return nil, err
+	}
+
 	if err := saslOptFnsFromParsed(conf, a.connOpts); err != nil {
 		return nil, err
 	}
@@ -263,28 +277,7 @@ func (a *amqp1Reader) ReadBatch(ctx context.Context) (service.MessageBatch, serv
 		part = service.NewMessage(nil)
 	}
 
-	if amqpMsg.Properties != nil {
-		amqpSetMetadata(part, "amqp_content_type", amqpMsg.Properties.ContentType)
-		amqpSetMetadata(part, "amqp_content_encoding", amqpMsg.Properties.ContentEncoding)
-		amqpSetMetadata(part, "amqp_creation_time", amqpMsg.Properties.CreationTime)
-	}
-	if a.getHeader && amqpMsg.Header != nil {
-		amqpSetMetadata(part, "amqp_durable", amqpMsg.Header.Durable)
-		amqpSetMetadata(part, "amqp_priority", amqpMsg.Header.Priority)
-		amqpSetMetadata(part, "amqp_ttl", amqpMsg.Header.TTL)
-		amqpSetMetadata(part, "amqp_first_acquirer", amqpMsg.Header.FirstAcquirer)
-		amqpSetMetadata(part, "amqp_delivery_count", amqpMsg.Header.DeliveryCount)
-	}
-
-	if amqpMsg.Annotations != nil {
-		for k, v := range amqpMsg.Annotations {
-			keyStr, keyIsStr := k.(string)
-			valStr, valIsStr := v.(string)
-			if keyIsStr && valIsStr {
-				amqpSetMetadata(part, keyStr, valStr)
-			}
-		}
-	}
+	amqpSetMessageMetadata(part, amqpMsg, a.getHeader)
 
 	var done chan struct{}
 	if a.renewLock {
@@ -300,6 +293,15 @@ func (a *amqp1Reader) ReadBatch(ctx context.Context) (service.MessageBatch, serv
 		// TODO: These methods were moved in v0.16.0, but nacking seems broken
 		// (integration tests fail)
 		if res != nil {
+			switch a.nackAction {
+			case nackActionRelease:
+				return conn.receiver.ReleaseMessage(ctx, amqpMsg)
+			case nackActionReject:
+				return conn.receiver.RejectMessage(ctx, amqpMsg, &amqp.Error{
+					Condition:   amqp.ErrCondInternalError,
+					Description: res.Error(),
+				})
+			}
 			return conn.receiver.ModifyMessage(ctx, amqpMsg, &amqp.ModifyMessageOptions{
 				DeliveryFailed:    true,
 				UndeliverableHere: false,
@@ -499,6 +501,45 @@ func (a *amqp1Reader) renewWithContext(ctx context.Context, msg *amqp.Message) (
 	return expirations[0], nil
 }
 
+func amqpSetMessageMetadata(part *service.Message, amqpMsg *amqp.Message, getHeader bool) {
+	if amqpMsg.Properties != nil {
+		amqpSetMetadata(part, "amqp_content_type", amqpMsg.Properties.ContentType)
+		amqpSetMetadata(part, "amqp_content_encoding", amqpMsg.Properties.ContentEncoding)
+		amqpSetMetadata(part, "amqp_creation_time", amqpMsg.Properties.CreationTime)
+		amqpSetMetadata(part, "amqp_message_id", amqpMsg.Properties.MessageID)
+		amqpSetMetadata(part, "amqp_correlation_id", amqpMsg.Properties.CorrelationID)
+		amqpSetMetadata(part, "amqp_user_id", amqpMsg.Properties.UserID)
+		amqpSetMetadata(part, "amqp_to", amqpMsg.Properties.To)
+		amqpSetMetadata(part, "amqp_subject", amqpMsg.Properties.Subject)
+		amqpSetMetadata(part, "amqp_reply_to", amqpMsg.Properties.ReplyTo)
+		amqpSetMetadata(part, "amqp_absolute_expiry_time", amqpMsg.Properties.AbsoluteExpiryTime)
+		amqpSetMetadata(part, "amqp_group_id", amqpMsg.Properties.GroupID)
+		amqpSetMetadata(part, "amqp_group_sequence", amqpMsg.Properties.GroupSequence)
+		amqpSetMetadata(part, "amqp_reply_to_group_id", amqpMsg.Properties.ReplyToGroupID)
+	}
+	if getHeader && amqpMsg.Header != nil {
+		amqpSetMetadata(part, "amqp_durable", amqpMsg.Header.Durable)
+		amqpSetMetadata(part, "amqp_priority", amqpMsg.Header.Priority)
+		amqpSetMetadata(part, "amqp_ttl", amqpMsg.Header.TTL)
+		amqpSetMetadata(part, "amqp_first_acquirer", amqpMsg.Header.FirstAcquirer)
+		amqpSetMetadata(part, "amqp_delivery_count", amqpMsg.Header.DeliveryCount)
+	}
+
+	for k, v := range amqpMsg.ApplicationProperties {
+		amqpSetMetadata(part, k, v)
+	}
+
+	if amqpMsg.Annotations != nil {
+		for k, v := range amqpMsg.Annotations {
+			keyStr, keyIsStr := k.(string)
+			valStr, valIsStr := v.(string)
+			if keyIsStr && valIsStr {
+				amqpSetMetadata(part, keyStr, valStr)
+			}
+		}
+	}
+}
+
 func amqpSetMetadata(p *service.Message, k string, v any) {
 	var metaValue string
 	metaKey := strings.ReplaceAll(k, "-", "_")
@@ -525,6 +566,14 @@ func amqpSetMetadata(p *service.Message, k string, v any) {
 		metaValue = strconv.Itoa(int(v))
 	case int64:
 		metaValue = strconv.Itoa(int(v))
+	case uint64:
+		metaValue = strconv.FormatUint(v, 10)
+	case *uint32:
+		metaValue = strconv.FormatUint(uint64(*v), 10)
+	case amqp.UUID:
+		metaValue = v.String()
+	case *time.Time:
+		metaValue = v.Format(time.RFC3339)
 	case nil:
 		metaValue = ""
 	case string:
@@ -0,0 +1,90 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package amqp1
+
+import (
+	"testing"
+
+	"github.com/Azure/go-amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestAMQP1MessageMetadata(t *testing.T) {
+	subject, replyTo := "foo", "bar"
+	groupSeq := uint32(5)
+
+	amqpMsg := amqp.NewMessage([]byte("hello world"))
+	amqpMsg.Properties = &amqp.MessageProperties{
+		MessageID:     uint64(10),
+		CorrelationID: "corr-1",
+		Subject:       &subject,
+		ReplyTo:       &replyTo,
+		GroupSequence: &groupSeq,
+	}
+	amqpMsg.ApplicationProperties = map[string]any{
+		"tenant-id": "acme",
+		"retries":   int32(3),
+	}
+	amqpMsg.Annotations = amqp.Annotations{
+		"x-opt-foo": "baz",
+		"x-opt-num": int64(7),
+	}
+
+	part := service.NewMessage(nil)
+	amqpSetMessageMetadata(part, amqpMsg, false)
+
+	meta := map[string]any{}
+	require.NoError(t, part.MetaWalkMut(func(key string, value any) error {
+		meta[key] = value
+		return nil
+	}))
+
+	assert.Equal(t, map[string]any{
+		"amqp_message_id":     "10",
+		"amqp_correlation_id": "corr-1",
+		"amqp_subject":        "foo",
+		"amqp_reply_to":       "bar",
+		"amqp_group_sequence": "5",
+		"tenant_id":           "acme",
+		"retries":             "3",
+		"x_opt_foo":           "baz",
+	}, meta)
+}
+
+func TestAMQP1InputNackAction(t *testing.T) {
+	pConf, err := amqp1InputSpec().ParseYAML(`
+urls: [ amqp://localhost:5672 ]
+source_address: /foo
+nack_action: release
+`, nil)
+	require.NoError(t, err)
+
+	r, err := amqp1ReaderFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	assert.Equal(t, nackActionRelease, r.nackAction)
+
+	pConf, err = amqp1InputSpec().ParseYAML(`
+urls: [ amqp://localhost:5672 ]
+source_address: /foo
+`, nil)
+	require.NoError(t, err)
+
+	r, err = amqp1ReaderFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	assert.Equal(t, nackActionModify, r.nackAction)
+}
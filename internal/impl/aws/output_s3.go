@@ -52,6 +52,7 @@ const (
 	s3oFieldKMSKeyID                = "kms_key_id"
 	s3oFieldServerSideEncryption    = "server_side_encryption"
 	s3oFieldBatching                = "batching"
+	s3oFieldAggregate               = "aggregate"
 )
 
 type s3TagPair struct {
@@ -76,6 +77,7 @@ type s3oConfig struct {
 	KMSKeyID                string
 	ServerSideEncryption    string
 	UsePathStyle            bool
+	Aggregate               *s3AggregateConfig
 
 	aconf aws.Config
 }
@@ -139,6 +141,11 @@ func s3oConfigFromParsed(pConf *service.ParsedConfig) (conf s3oConfig, err error
 	if conf.ServerSideEncryption, err = pConf.FieldString(s3oFieldServerSideEncryption); err != nil {
 		return
 	}
+	if pConf.Contains(s3oFieldAggregate) {
+		if conf.Aggregate, err = s3AggregateConfigFromParsed(pConf.Namespace(s3oFieldAggregate)); err != nil {
+			return
+		}
+	}
 	if conf.aconf, err = GetSession(context.TODO(), pConf); err != nil {
 		return
 	}
@@ -209,7 +216,27 @@ output:
       processors:
         - archive:
             format: json_array
-`+"```"+``+service.OutputPerformanceDocs(true, false)).
+`+"```"+`
+
+== Aggregation
+
+When the `+"`aggregate`"+` field is set messages are buffered in memory and written as larger objects, where messages that resolve the same `+"`aggregate.partition`"+` are joined into the same object with `+"`aggregate.separator`"+`. An object is uploaded once it reaches `+"`aggregate.max_bytes`"+` or has been open for `+"`aggregate.max_age`"+`, whichever happens first, and the next message of that partition opens a new object. The `+"`path`"+` and all other object fields are resolved from the first message of each object, and therefore the `+"`path`"+` should contain something unique to each object, such as a timestamp or a UUID.
+
+For example, in order to write messages as newline delimited objects partitioned by the hour:
+
+`+"```yaml"+`
+output:
+  aws_s3:
+    bucket: TODO
+    path: events/${! now().ts_format("2006/01/02/15") }/${! uuid_v4() }.jsonl
+    max_in_flight: 1000
+    aggregate:
+      partition: ${! now().ts_format("2006/01/02/15") }
+      max_bytes: 67108864
+      max_age: 5m
+`+"```"+`
+
+Messages are only acknowledged once the object they were written to has been uploaded, which means no data is lost when an upload fails or when the output is shut down, as pending objects are flushed on close. However, this also means that an object can only aggregate messages from batches that are in flight at the same time, and therefore `+"`max_in_flight`"+` should be set high enough to reach the desired object sizes. The number of open objects is limited by `+"`aggregate.max_partitions`"+`, where opening an object for a new partition beyond that limit causes the oldest open object to be uploaded early, and therefore the memory used by aggregation is bounded by `+"`aggregate.max_partitions`"+` multiplied by `+"`aggregate.max_bytes`"+`.`+service.OutputPerformanceDocs(true, false)).
 		Fields(
 			service.NewStringField(s3oFieldBucket).
 				Description("The bucket to upload messages to."),
@@ -276,6 +303,7 @@ output:
 				Advanced().
 				Default("5s"),
 			service.NewBatchPolicyField(s3oFieldBatching),
+			s3AggregateFieldSpec(),
 		).
 		Fields(config.SessionFields()...)
 }
@@ -301,10 +329,15 @@ func init() {
 	}
 }
 
+type s3Uploader interface {
+	Upload(ctx context.Context, input *s3.PutObjectInput, opts ...func(*manager.Uploader)) (*manager.UploadOutput, error)
+}
+
 type amazonS3Writer struct {
-	conf     s3oConfig
-	uploader *manager.Uploader
-	log      *service.Logger
+	conf       s3oConfig
+	uploader   s3Uploader
+	aggregator *s3Aggregator
+	log        *service.Logger
 }
 
 func newAmazonS3Writer(conf s3oConfig, mgr *service.Resources) (*amazonS3Writer, error) {
@@ -324,6 +357,9 @@ func (a *amazonS3Writer) Connect(ctx context.Context) error {
 		o.UsePathStyle = a.conf.UsePathStyle
 	})
 	a.uploader = manager.NewUploader(client)
+	if a.conf.Aggregate != nil {
+		a.aggregator = newS3Aggregator(*a.conf.Aggregate, a)
+	}
 	return nil
 }
 
@@ -332,119 +368,139 @@ func (a *amazonS3Writer) WriteBatch(wctx context.Context, msg service.MessageBat
 		return service.ErrNotConnected
 	}
 
+	if a.aggregator != nil {
+		return a.aggregator.writeBatch(wctx, msg)
+	}
+
 	ctx, cancel := context.WithTimeout(wctx, a.conf.Timeout)
 	defer cancel()
 
 	return msg.WalkWithBatchedErrors(func(i int, m *service.Message) error {
-		metadata := map[string]string{}
-		_ = a.conf.Metadata.WalkMut(m, func(k string, v any) error {
-			metadata[k] = bloblang.ValueToString(v)
-			return nil
-		})
-
-		var contentEncoding *string
-		ce, err := msg.TryInterpolatedString(i, a.conf.ContentEncoding)
+		uploadInput, err := a.objectInput(msg, i)
 		if err != nil {
-			return fmt.Errorf("content encoding interpolation: %w", err)
-		}
-		if ce != "" {
-			contentEncoding = aws.String(ce)
-		}
-		var cacheControl *string
-		if ce, err = msg.TryInterpolatedString(i, a.conf.CacheControl); err != nil {
-			return fmt.Errorf("cache control interpolation: %w", err)
-		}
-		if ce != "" {
-			cacheControl = aws.String(ce)
-		}
-		var contentDisposition *string
-		if ce, err = msg.TryInterpolatedString(i, a.conf.ContentDisposition); err != nil {
-			return fmt.Errorf("content disposition interpolation: %w", err)
-		}
-		if ce != "" {
-			contentDisposition = aws.String(ce)
-		}
-		var contentLanguage *string
-		if ce, err = msg.TryInterpolatedString(i, a.conf.ContentLanguage); err != nil {
-			return fmt.Errorf("content language interpolation: %w", err)
-		}
-		if ce != "" {
-			contentLanguage = aws.String(ce)
-		}
-		var websiteRedirectLocation *string
-		if ce, err = msg.TryInterpolatedString(i, a.conf.WebsiteRedirectLocation); err != nil {
-			return fmt.Errorf("website redirect location interpolation: %w", err)
-		}
-		if ce != "" {
-			websiteRedirectLocation = aws.String(ce)
+			return err
 		}
 
-		key, err := msg.TryInterpolatedString(i, a.conf.Path)
+		mBytes, err := m.AsBytes()
 		if err != nil {
-			return fmt.Errorf("key interpolation: %w", err)
+			return err
 		}
+		uploadInput.Body = bytes.NewReader(mBytes)
 
-		contentType, err := msg.TryInterpolatedString(i, a.conf.ContentType)
-		if err != nil {
-			return fmt.Errorf("content type interpolation: %w", err)
+		if _, err := a.uploader.Upload(ctx, uploadInput); err != nil {
+			return err
 		}
+		return nil
+	})
+}
 
-		storageClass, err := msg.TryInterpolatedString(i, a.conf.StorageClass)
-		if err != nil {
-			return fmt.Errorf("storage class interpolation: %w", err)
-		}
+// objectInput creates the upload input of an object from the message at index
+// i of a batch, leaving the body to be set by the caller.
+func (a *amazonS3Writer) objectInput(msg service.MessageBatch, i int) (*s3.PutObjectInput, error) {
+	m := msg[i]
 
-		mBytes, err := m.AsBytes()
-		if err != nil {
-			return err
-		}
+	metadata := map[string]string{}
+	_ = a.conf.Metadata.WalkMut(m, func(k string, v any) error {
+		metadata[k] = bloblang.ValueToString(v)
+		return nil
+	})
 
-		uploadInput := &s3.PutObjectInput{
-			Bucket:                  &a.conf.Bucket,
-			Key:                     aws.String(key),
-			Body:                    bytes.NewReader(mBytes),
-			ContentType:             aws.String(contentType),
-			ContentEncoding:         contentEncoding,
-			CacheControl:            cacheControl,
-			ContentDisposition:      contentDisposition,
-			ContentLanguage:         contentLanguage,
-			WebsiteRedirectLocation: websiteRedirectLocation,
-			StorageClass:            types.StorageClass(storageClass),
-			Metadata:                metadata,
-		}
+	var contentEncoding *string
+	ce, err := msg.TryInterpolatedString(i, a.conf.ContentEncoding)
+	if err != nil {
+		return nil, fmt.Errorf("content encoding interpolation: %w", err)
+	}
+	if ce != "" {
+		contentEncoding = aws.String(ce)
+	}
+	var cacheControl *string
+	if ce, err = msg.TryInterpolatedString(i, a.conf.CacheControl); err != nil {
+		return nil, fmt.Errorf("cache control interpolation: %w", err)
+	}
+	if ce != "" {
+		cacheControl = aws.String(ce)
+	}
+	var contentDisposition *string
+	if ce, err = msg.TryInterpolatedString(i, a.conf.ContentDisposition); err != nil {
+		return nil, fmt.Errorf("content disposition interpolation: %w", err)
+	}
+	if ce != "" {
+		contentDisposition = aws.String(ce)
+	}
+	var contentLanguage *string
+	if ce, err = msg.TryInterpolatedString(i, a.conf.ContentLanguage); err != nil {
+		return nil, fmt.Errorf("content language interpolation: %w", err)
+	}
+	if ce != "" {
+		contentLanguage = aws.String(ce)
+	}
+	var websiteRedirectLocation *string
+	if ce, err = msg.TryInterpolatedString(i, a.conf.WebsiteRedirectLocation); err != nil {
+		return nil, fmt.Errorf("website redirect location interpolation: %w", err)
+	}
+	if ce != "" {
+		websiteRedirectLocation = aws.String(ce)
+	}
+
+	key, err := msg.TryInterpolatedString(i, a.conf.Path)
+	if err != nil {
+		return nil, fmt.Errorf("key interpolation: %w", err)
+	}
+
+	contentType, err := msg.TryInterpolatedString(i, a.conf.ContentType)
+	if err != nil {
+		return nil, fmt.Errorf("content type interpolation: %w", err)
+	}
+
+	storageClass, err := msg.TryInterpolatedString(i, a.conf.StorageClass)
+	if err != nil {
+		return nil, fmt.Errorf("storage class interpolation: %w", err)
+	}
 
-		// Prepare tags, escaping keys and values to ensure they're valid query string parameters.
-		if len(a.conf.Tags) > 0 {
-			tags := make([]string, len(a.conf.Tags))
-			for j, pair := range a.conf.Tags {
-				tagStr, err := msg.TryInterpolatedString(i, pair.value)
-				if err != nil {
-					return fmt.Errorf("tag %v interpolation: %w", pair.key, err)
-				}
-				tags[j] = url.QueryEscape(pair.key) + "=" + url.QueryEscape(tagStr)
+	uploadInput := &s3.PutObjectInput{
+		Bucket:                  &a.conf.Bucket,
+		Key:                     aws.String(key),
+		ContentType:             aws.String(contentType),
+		ContentEncoding:         contentEncoding,
+		CacheControl:            cacheControl,
+		ContentDisposition:      contentDisposition,
+		ContentLanguage:         contentLanguage,
+		WebsiteRedirectLocation: websiteRedirectLocation,
+		StorageClass:            types.StorageClass(storageClass),
+		Metadata:                metadata,
+	}
+
+	// Prepare tags, escaping keys and values to ensure they're valid query string parameters.
+	if len(a.conf.Tags) > 0 {
+		tags := make([]string, len(a.conf.Tags))
+		for j, pair := range a.conf.Tags {
+			tagStr, err := msg.TryInterpolatedString(i, pair.value)
+			if err != nil {
+				return nil, fmt.Errorf("tag %v interpolation: %w", pair.key, err)
 			}
-			uploadInput.Tagging = aws.String(strings.Join(tags, "&"))
+			tags[j] = url.QueryEscape(pair.key) + "=" + url.QueryEscape(tagStr)
 		}
+		uploadInput.Tagging = aws.String(strings.Join(tags, "&"))
+	}
 
-		if a.conf.KMSKeyID != "" {
-			uploadInput.ServerSideEncryption = types.ServerSideEncryptionAwsKms
-			uploadInput.SSEKMSKeyId = &a.conf.KMSKeyID
-		}
+	if a.conf.KMSKeyID != "" {
+		uploadInput.ServerSideEncryption = types.ServerSideEncryptionAwsKms
+		uploadInput.SSEKMSKeyId = &a.conf.KMSKeyID
+	}
 
-		// NOTE: This overrides the ServerSideEncryption set above. We need this to preserve
-		// backwards compatibility, where it is allowed to only set kms_key_id in the config and
-		// the ServerSideEncryption value of "aws:kms" is implied.
-		if a.conf.ServerSideEncryption != "" {
-			uploadInput.ServerSideEncryption = types.ServerSideEncryption(a.conf.ServerSideEncryption)
-		}
+	// NOTE: This overrides the ServerSideEncryption set above. We need this to preserve
+	// backwards compatibility, where it is allowed to only set kms_key_id in the config and
+	// the ServerSideEncryption value of "aws:kms" is implied.
+	if a.conf.ServerSideEncryption != "" {
+		uploadInput.ServerSideEncryption = types.ServerSideEncryption(a.conf.ServerSideEncryption)
+	}
 
-		if _, err := a.uploader.Upload(ctx, uploadInput); err != nil {
-			return err
-		}
-		return nil
-	})
+	return uploadInput, nil
 }
 
-func (a *amazonS3Writer) Close(context.Context) error {
+func (a *amazonS3Writer) Close(ctx context.Context) error {
+	if a.aggregator != nil {
+		return a.aggregator.close(ctx)
+	}
 	return nil
 }
@@ -0,0 +1,329 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/shutdown"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	// S3 Output Aggregate Fields
+	s3oAggFieldPartition     = "partition"
+	s3oAggFieldMaxBytes      = "max_bytes"
+	s3oAggFieldMaxAge        = "max_age"
+	s3oAggFieldMaxPartitions = "max_partitions"
+	s3oAggFieldSeparator     = "separator"
+)
+
+func s3AggregateFieldSpec() *service.ConfigField {
+	return service.NewObjectField(s3oFieldAggregate,
+		service.NewInterpolatedStringField(s3oAggFieldPartition).
+			Description("An interpolated string resolved for each message, messages that resolve the same partition are written to the same object.").
+			Default("").
+			Example(`${! now().ts_format("2006/01/02/15") }`).
+			Example(`${! meta("kafka_topic") }`),
+		service.NewIntField(s3oAggFieldMaxBytes).
+			Description("The size in bytes that an object must reach before it is uploaded.").
+			Default(67108864),
+		service.NewDurationField(s3oAggFieldMaxAge).
+			Description("The maximum period that an object is kept open before it is uploaded, regardless of its size.").
+			Default("5m"),
+		service.NewIntField(s3oAggFieldMaxPartitions).
+			Description("The maximum number of objects that can be open at any given time. Opening an object for a new partition beyond this limit causes the oldest open object to be uploaded.").
+			Default(64),
+		service.NewStringField(s3oAggFieldSeparator).
+			Description("A string inserted between the messages of an object.").
+			Default("\n"),
+	).
+		Description("When set messages are aggregated into larger objects per partition, which are uploaded once they reach a size or age threshold. Check out the <<aggregation, aggregation section>> for more information.").
+		Version("4.31.0").
+		Optional().
+		Advanced()
+}
+
+type s3AggregateConfig struct {
+	Partition     *service.InterpolatedString
+	MaxBytes      int
+	MaxAge        time.Duration
+	MaxPartitions int
+	Separator     []byte
+}
+
+func s3AggregateConfigFromParsed(pConf *service.ParsedConfig) (*s3AggregateConfig, error) {
+	conf := &s3AggregateConfig{}
+
+	var err error
+	if conf.Partition, err = pConf.FieldInterpolatedString(s3oAggFieldPartition); err != nil {
+		return nil, err
+	}
+	if conf.MaxBytes, err = pConf.FieldInt(s3oAggFieldMaxBytes); err != nil {
+		return nil, err
+	}
+	if conf.MaxBytes < 1 {
+		return nil, errors.New("aggregate max_bytes must be at least 1")
+	}
+	if conf.MaxAge, err = pConf.FieldDuration(s3oAggFieldMaxAge); err != nil {
+		return nil, err
+	}
+	if conf.MaxAge <= 0 {
+		return nil, errors.New("aggregate max_age must be greater than zero")
+	}
+	if conf.MaxPartitions, err = pConf.FieldInt(s3oAggFieldMaxPartitions); err != nil {
+		return nil, err
+	}
+	if conf.MaxPartitions < 1 {
+		return nil, errors.New("aggregate max_partitions must be at least 1")
+	}
+	var sep string
+	if sep, err = pConf.FieldString(s3oAggFieldSeparator); err != nil {
+		return nil, err
+	}
+	conf.Separator = []byte(sep)
+	return conf, nil
+}
+
+// s3AggObject is an object that messages are being aggregated into. Once an
+// object is removed from the open set its buffer is no longer modified, and
+// done is closed after the upload attempt has finished.
+type s3AggObject struct {
+	input  *s3.PutObjectInput
+	buf    bytes.Buffer
+	opened time.Time
+
+	done chan struct{}
+	err  error
+}
+
+// s3Aggregator buffers messages into objects per partition and uploads them
+// once they reach a size or age threshold. Writes block until the objects
+// that their messages were written to have been uploaded, and therefore
+// messages are only acknowledged once they are durable.
+type s3Aggregator struct {
+	conf s3AggregateConfig
+	w    *amazonS3Writer
+
+	mut     sync.Mutex
+	open    map[string]*s3AggObject
+	closed  bool
+	flushWG sync.WaitGroup
+
+	shutSig *shutdown.Signaller
+}
+
+func newS3Aggregator(conf s3AggregateConfig, w *amazonS3Writer) *s3Aggregator {
+	a := &s3Aggregator{
+		conf:    conf,
+		w:       w,
+		open:    map[string]*s3AggObject{},
+		shutSig: shutdown.NewSignaller(),
+	}
+	go a.ageLoop()
+	return a
+}
+
+func (a *s3Aggregator) ageLoop() {
+	defer a.shutSig.TriggerHasStopped()
+
+	interval := a.conf.MaxAge / 10
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-a.shutSig.SoftStopChan():
+			return
+		}
+
+		var expired []*s3AggObject
+		a.mut.Lock()
+		for p, obj := range a.open {
+			if time.Since(obj.opened) >= a.conf.MaxAge {
+				expired = append(expired, a.removeLocked(p))
+			}
+		}
+		a.mut.Unlock()
+
+		for _, obj := range expired {
+			a.flush(obj)
+		}
+	}
+}
+
+// removeLocked removes an object from the open set so that it can be flushed,
+// the caller must hold the lock and must call flush on the object returned.
+func (a *s3Aggregator) removeLocked(partition string) *s3AggObject {
+	obj := a.open[partition]
+	delete(a.open, partition)
+	a.flushWG.Add(1)
+	return obj
+}
+
+func (a *s3Aggregator) oldestLocked() (partition string) {
+	var oldest time.Time
+	for p, obj := range a.open {
+		if oldest.IsZero() || obj.opened.Before(oldest) {
+			partition, oldest = p, obj.opened
+		}
+	}
+	return
+}
+
+func (a *s3Aggregator) flush(obj *s3AggObject) {
+	go func() {
+		defer a.flushWG.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), a.w.conf.Timeout)
+		defer cancel()
+
+		obj.input.Body = bytes.NewReader(obj.buf.Bytes())
+		if _, obj.err = a.w.uploader.Upload(ctx, obj.input); obj.err != nil {
+			a.w.log.Errorf("Failed to upload aggregated object '%v': %v", *obj.input.Key, obj.err)
+		}
+		close(obj.done)
+	}()
+}
+
+func (a *s3Aggregator) writeBatch(ctx context.Context, batch service.MessageBatch) error {
+	objects := make([]*s3AggObject, len(batch))
+	msgErrs := make([]error, len(batch))
+
+	partitions := make([]string, len(batch))
+	contents := make([][]byte, len(batch))
+	for i, m := range batch {
+		if partitions[i], msgErrs[i] = batch.TryInterpolatedString(i, a.conf.Partition); msgErrs[i] != nil {
+			msgErrs[i] = fmt.Errorf("partition interpolation: %w", msgErrs[i])
+			continue
+		}
+		contents[i], msgErrs[i] = m.AsBytes()
+	}
+
+	var toFlush []*s3AggObject
+
+	a.mut.Lock()
+	if a.closed {
+		a.mut.Unlock()
+		return service.ErrNotConnected
+	}
+	for i, p := range partitions {
+		if msgErrs[i] != nil {
+			continue
+		}
+		obj, exists := a.open[p]
+		if !exists {
+			// The object is described by its first message, which is resolved
+			// before anything is buffered so that a failed message is never
+			// written to an object.
+			input, err := a.w.objectInput(batch, i)
+			if err != nil {
+				msgErrs[i] = err
+				continue
+			}
+			if len(a.open) >= a.conf.MaxPartitions {
+				toFlush = append(toFlush, a.removeLocked(a.oldestLocked()))
+			}
+			obj = &s3AggObject{
+				input:  input,
+				opened: time.Now(),
+				done:   make(chan struct{}),
+			}
+			a.open[p] = obj
+		}
+		if obj.buf.Len() > 0 {
+			_, _ = obj.buf.Write(a.conf.Separator)
+		}
+		_, _ = obj.buf.Write(contents[i])
+		objects[i] = obj
+		if obj.buf.Len() >= a.conf.MaxBytes {
+			toFlush = append(toFlush, a.removeLocked(p))
+		}
+	}
+	a.mut.Unlock()
+
+	for _, obj := range toFlush {
+		a.flush(obj)
+	}
+
+	var batchErr *service.BatchError
+	for i, obj := range objects {
+		if obj != nil {
+			select {
+			case <-obj.done:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			msgErrs[i] = obj.err
+		}
+		if msgErrs[i] != nil {
+			if batchErr == nil {
+				batchErr = service.NewBatchError(batch, msgErrs[i])
+			}
+			batchErr.Failed(i, msgErrs[i])
+		}
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+// close uploads all open objects and waits for pending uploads to finish.
+func (a *s3Aggregator) close(ctx context.Context) error {
+	a.shutSig.TriggerSoftStop()
+
+	var remaining []*s3AggObject
+	a.mut.Lock()
+	a.closed = true
+	for p := range a.open {
+		remaining = append(remaining, a.removeLocked(p))
+	}
+	a.mut.Unlock()
+
+	for _, obj := range remaining {
+		a.flush(obj)
+	}
+
+	flushed := make(chan struct{})
+	go func() {
+		a.flushWG.Wait()
+		close(flushed)
+	}()
+
+	select {
+	case <-flushed:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-a.shutSig.HasStoppedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
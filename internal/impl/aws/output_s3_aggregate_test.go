@@ -0,0 +1,261 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type mockS3Uploader struct {
+	mut     sync.Mutex
+	objects map[string]string
+	err     error
+}
+
+func (m *mockS3Uploader) Upload(ctx context.Context, input *s3.PutObjectInput, opts ...func(*manager.Uploader)) (*manager.UploadOutput, error) {
+	body, err := io.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.objects[*input.Key] = string(body)
+	return &manager.UploadOutput{}, nil
+}
+
+func (m *mockS3Uploader) uploaded() map[string]string {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	objs := make(map[string]string, len(m.objects))
+	for k, v := range m.objects {
+		objs[k] = v
+	}
+	return objs
+}
+
+func writeBatchAsync(w *amazonS3Writer, contents ...string) <-chan error {
+	batch := make(service.MessageBatch, len(contents))
+	for i, c := range contents {
+		batch[i] = service.NewMessage([]byte(c))
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- w.WriteBatch(context.Background(), batch)
+	}()
+	return errChan
+}
+
+func openPartitions(w *amazonS3Writer) int {
+	w.aggregator.mut.Lock()
+	defer w.aggregator.mut.Unlock()
+	return len(w.aggregator.open)
+}
+
+func TestS3AggregateMaxBytes(t *testing.T) {
+	conf, err := s3oOutputSpec().ParseYAML(`
+bucket: foo
+path: ${! content() }.txt
+aggregate:
+  max_bytes: 7
+  max_age: 50ms
+`, nil)
+	require.NoError(t, err)
+
+	wConf, err := s3oConfigFromParsed(conf)
+	require.NoError(t, err)
+	require.NotNil(t, wConf.Aggregate)
+
+	w, err := newAmazonS3Writer(wConf, service.MockResources())
+	require.NoError(t, err)
+
+	u := &mockS3Uploader{objects: map[string]string{}}
+	w.uploader = u
+	w.aggregator = newS3Aggregator(*wConf.Aggregate, w)
+	t.Cleanup(func() { _ = w.Close(context.Background()) })
+
+	require.NoError(t, <-writeBatchAsync(w, "aaa", "bbb", "ccc"))
+	assert.Equal(t, map[string]string{
+		"aaa.txt": "aaa\nbbb",
+		"ccc.txt": "ccc",
+	}, u.uploaded())
+}
+
+func TestS3AggregatePartitionsFlushedOnClose(t *testing.T) {
+	conf, err := s3oOutputSpec().ParseYAML(`
+bucket: foo
+path: ${! content().slice(0, 1) }/${! content() }.txt
+aggregate:
+  partition: ${! content().slice(0, 1) }
+  max_age: 1h
+  separator: ","
+`, nil)
+	require.NoError(t, err)
+
+	wConf, err := s3oConfigFromParsed(conf)
+	require.NoError(t, err)
+	require.NotNil(t, wConf.Aggregate)
+
+	w, err := newAmazonS3Writer(wConf, service.MockResources())
+	require.NoError(t, err)
+
+	u := &mockS3Uploader{objects: map[string]string{}}
+	w.uploader = u
+	w.aggregator = newS3Aggregator(*wConf.Aggregate, w)
+	t.Cleanup(func() { _ = w.Close(context.Background()) })
+
+	errA := writeBatchAsync(w, "a1", "b1")
+	errB := writeBatchAsync(w, "a2")
+
+	assert.Eventually(t, func() bool {
+		w.aggregator.mut.Lock()
+		defer w.aggregator.mut.Unlock()
+		return len(w.aggregator.open) == 2 && w.aggregator.open["a"].buf.Len() == 5
+	}, time.Second, time.Millisecond)
+	assert.Empty(t, u.uploaded())
+
+	require.NoError(t, w.Close(context.Background()))
+	require.NoError(t, <-errA)
+	require.NoError(t, <-errB)
+
+	objs := u.uploaded()
+	assert.Len(t, objs, 2)
+	assert.Equal(t, "b1", objs["b/b1.txt"])
+	if v, exists := objs["a/a1.txt"]; exists {
+		assert.Equal(t, "a1,a2", v)
+	} else {
+		assert.Equal(t, "a2,a1", objs["a/a2.txt"])
+	}
+
+	require.ErrorIs(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("a3")),
+	}), service.ErrNotConnected)
+}
+
+func TestS3AggregateMaxPartitions(t *testing.T) {
+	conf, err := s3oOutputSpec().ParseYAML(`
+bucket: foo
+path: ${! content() }.txt
+aggregate:
+  partition: ${! content() }
+  max_age: 1h
+  max_partitions: 1
+`, nil)
+	require.NoError(t, err)
+
+	wConf, err := s3oConfigFromParsed(conf)
+	require.NoError(t, err)
+	require.NotNil(t, wConf.Aggregate)
+
+	w, err := newAmazonS3Writer(wConf, service.MockResources())
+	require.NoError(t, err)
+
+	u := &mockS3Uploader{objects: map[string]string{}}
+	w.uploader = u
+	w.aggregator = newS3Aggregator(*wConf.Aggregate, w)
+	t.Cleanup(func() { _ = w.Close(context.Background()) })
+
+	errA := writeBatchAsync(w, "a")
+	assert.Eventually(t, func() bool {
+		return openPartitions(w) == 1
+	}, time.Second, time.Millisecond)
+
+	errB := writeBatchAsync(w, "b")
+	require.NoError(t, <-errA)
+	assert.Equal(t, map[string]string{"a.txt": "a"}, u.uploaded())
+
+	require.NoError(t, w.Close(context.Background()))
+	require.NoError(t, <-errB)
+	assert.Equal(t, map[string]string{"a.txt": "a", "b.txt": "b"}, u.uploaded())
+}
+
+func TestS3AggregateUploadError(t *testing.T) {
+	conf, err := s3oOutputSpec().ParseYAML(`
+bucket: foo
+path: ${! content() }.txt
+aggregate:
+  max_age: 10ms
+`, nil)
+	require.NoError(t, err)
+
+	wConf, err := s3oConfigFromParsed(conf)
+	require.NoError(t, err)
+	require.NotNil(t, wConf.Aggregate)
+
+	w, err := newAmazonS3Writer(wConf, service.MockResources())
+	require.NoError(t, err)
+
+	u := &mockS3Uploader{objects: map[string]string{}}
+	w.uploader = u
+	w.aggregator = newS3Aggregator(*wConf.Aggregate, w)
+	t.Cleanup(func() { _ = w.Close(context.Background()) })
+
+	u.err = errors.New("nope")
+
+	err = <-writeBatchAsync(w, "a", "b")
+	require.Error(t, err)
+
+	var bErr *service.BatchError
+	require.ErrorAs(t, err, &bErr)
+	assert.Equal(t, 2, bErr.IndexedErrors())
+}
+
+func TestS3AggregateInterpolationError(t *testing.T) {
+	conf, err := s3oOutputSpec().ParseYAML(`
+bucket: foo
+path: ${! json("id") }.txt
+aggregate:
+  partition: ${! json("id") }
+  max_age: 10ms
+`, nil)
+	require.NoError(t, err)
+
+	wConf, err := s3oConfigFromParsed(conf)
+	require.NoError(t, err)
+	require.NotNil(t, wConf.Aggregate)
+
+	w, err := newAmazonS3Writer(wConf, service.MockResources())
+	require.NoError(t, err)
+
+	u := &mockS3Uploader{objects: map[string]string{}}
+	w.uploader = u
+	w.aggregator = newS3Aggregator(*wConf.Aggregate, w)
+	t.Cleanup(func() { _ = w.Close(context.Background()) })
+
+	err = <-writeBatchAsync(w, `{"id":"a"}`, `nope`, `{"id":"a"}`)
+	require.Error(t, err)
+
+	var bErr *service.BatchError
+	require.ErrorAs(t, err, &bErr)
+	assert.Equal(t, 1, bErr.IndexedErrors())
+	assert.Equal(t, map[string]string{"a.txt": `{"id":"a"}` + "\n" + `{"id":"a"}`}, u.uploaded())
+}
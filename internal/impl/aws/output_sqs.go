@@ -33,6 +33,7 @@ import (
 	"github.com/redpanda-data/benthos/v4/public/bloblang"
 	"github.com/redpanda-data/benthos/v4/public/service"
 
+	"github.com/redpanda-data/connect/v4/internal/expiry"
 	"github.com/redpanda-data/connect/v4/internal/impl/aws/config"
 	"github.com/redpanda-data/connect/v4/internal/retries"
 )
@@ -100,6 +101,8 @@ func sqsoOutputSpec() *service.ConfigSpec {
 		Description(`
 Metadata values are sent along with the payload as attributes with the data type String. If the number of metadata values in a message exceeds the message attribute limit (10) then the top ten keys ordered alphabetically will be selected.
 
+Messages with an expiry deadline in the metadata key `+"`benthos_expires_at`"+` that has passed are dropped rather than sent, and the deadline of messages that are sent is always written as an attribute ahead of other metadata. SQS does not support a TTL for individual messages, see the xref:components:processors/check_expiry.adoc[`+"`check_expiry`"+` processor] for more information.
+
 The fields `+"`message_group_id`, `message_deduplication_id` and `delay_seconds`"+` can be set dynamically using xref:configuration:interpolation.adoc#bloblang-queries[function interpolations], which are resolved individually for each message of a batch.
 
 == Credentials
@@ -193,6 +196,9 @@ func (a *sqsWriter) getSQSAttributes(batch service.MessageBatch, i int) (sqsAttr
 	msg := batch[i]
 	keys := []string{}
 	_ = a.conf.Metadata.WalkMut(msg, func(k string, v any) error {
+		if k == expiry.MetadataKey {
+			return nil
+		}
 		if isValidSQSAttribute(k, bloblang.ValueToString(v)) {
 			keys = append(keys, k)
 		} else {
@@ -200,9 +206,16 @@ func (a *sqsWriter) getSQSAttributes(batch service.MessageBatch, i int) (sqsAttr
 		}
 		return nil
 	})
+	sort.Strings(keys)
+
+	// The expiry deadline is always written, and ahead of other metadata so
+	// that it isn't cut by the attribute limit.
+	if _, exists := msg.MetaGet(expiry.MetadataKey); exists {
+		keys = append([]string{expiry.MetadataKey}, keys...)
+	}
+
 	var values map[string]types.MessageAttributeValue
 	if len(keys) > 0 {
-		sort.Strings(keys)
 		values = map[string]types.MessageAttributeValue{}
 
 		for i, k := range keys {
@@ -268,6 +281,11 @@ func (a *sqsWriter) WriteBatch(ctx context.Context, batch service.MessageBatch)
 		return service.ErrNotConnected
 	}
 
+	if batch = expiry.FilterBatch(batch, time.Now()); len(batch) == 0 {
+		a.log.Debug("Dropping batch of expired messages")
+		return nil
+	}
+
 	backOff := a.conf.backoffCtor()
 
 	entries := []types.SendMessageBatchRequestEntry{}
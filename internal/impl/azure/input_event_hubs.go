@@ -0,0 +1,589 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs/checkpoints"
+	"github.com/Jeffail/checkpoint"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	// Event Hubs Input Fields
+	ehiFieldConnectionString    = "connection_string"
+	ehiFieldNamespace           = "namespace"
+	ehiFieldEventHub            = "event_hub"
+	ehiFieldConsumerGroup       = "consumer_group"
+	ehiFieldStartFromOldest     = "start_from_oldest"
+	ehiFieldCheckpointContainer = "checkpoint_container"
+	ehiFieldCheckpointLimit     = "checkpoint_limit"
+	ehiFieldPrefetchCount       = "prefetch_count"
+	ehiFieldCommitPeriod        = "commit_period"
+	ehiFieldRebalancePeriod     = "rebalance_period"
+	ehiFieldLeasePeriod         = "lease_period"
+)
+
+func ehiSpec() *service.ConfigSpec {
+	return azureComponentSpec(true).
+		Beta().
+		Version("4.31.0").
+		Summary(`Consumes events from an Azure Event Hub, optionally balancing partitions across clients and storing checkpoints within an Azure Blob Storage container.`).
+		Description(`
+When a `+"`"+ehiFieldCheckpointContainer+"`"+` is configured the partitions of the event hub are distributed across all clients that share the same consumer group and container, and the offset of the latest acknowledged event of each partition is stored within the container, which allows consumption to resume from the correct position after restarts. Partitions are balanced and checkpoints are stored by the official Azure Event Hubs SDK, and therefore existing checkpoints created by other Event Hubs processors can be resumed from. The storage account of the container is configured with the `+"`storage_*`"+` fields.
+
+When a `+"`"+ehiFieldCheckpointContainer+"`"+` is not configured this input consumes every partition of the event hub and starts from the position determined by `+"`"+ehiFieldStartFromOldest+"`"+` each time it connects.
+
+Redpanda Connect will not store the offset of an event unless it has been acknowledged at the output level, which ensures at-least-once delivery guarantees.
+
+== Authentication
+
+When a `+"`"+ehiFieldConnectionString+"`"+` is set it is used to authenticate with shared access signatures, and the connection string of either the namespace or the event hub itself can be used. When the connection string does not contain an `+"`EntityPath`"+` then the field `+"`"+ehiFieldEventHub+"`"+` must be set.
+
+Otherwise the fully qualified `+"`"+ehiFieldNamespace+"`"+` and the `+"`"+ehiFieldEventHub+"`"+` must be set, and credentials are obtained in the same way as the other Azure components, using the default Azure credential chain (environment variables, workload identity, managed identity, or the Azure CLI).
+
+== Ordering
+
+By default events of a partition can be processed in parallel, up to a limit determined by the field `+"`"+ehiFieldCheckpointLimit+"`"+`. However, if strict ordered processing is required then this value must be set to 1 in order to process partition events in lock-step.
+
+== Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- eventhub_name
+- eventhub_consumer_group
+- eventhub_partition_id
+- eventhub_partition_key
+- eventhub_offset
+- eventhub_sequence_number
+- eventhub_enqueued_time
+- All application properties of the event
+`+"```"+`
+
+You can access these metadata fields using xref:configuration:interpolation.adoc#bloblang-queries[function interpolation].`).
+		Fields(
+			service.NewStringField(ehiFieldConnectionString).
+				Description("The connection string of the Event Hubs namespace or the event hub. When empty the `"+ehiFieldNamespace+"` field must be set.").
+				Example("Endpoint=sb://foo.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=bar;EntityPath=baz").
+				Default("").
+				Secret(),
+			service.NewStringField(ehiFieldNamespace).
+				Description("The fully qualified namespace to connect to using the default Azure credential chain. This field is ignored when `"+ehiFieldConnectionString+"` is set.").
+				Example("foo.servicebus.windows.net").
+				Default(""),
+			service.NewStringField(ehiFieldEventHub).
+				Description("The name of the event hub to consume from. This field is required when the `"+ehiFieldNamespace+"` is used or the connection string does not contain an `EntityPath`.").
+				Default(""),
+			service.NewStringField(ehiFieldConsumerGroup).
+				Description("The consumer group to consume as.").
+				Default("$Default"),
+			service.NewBoolField(ehiFieldStartFromOldest).
+				Description("Whether to consume from the oldest available event when a checkpoint does not yet exist for a partition, otherwise only events enqueued after the partition is first consumed are read.").
+				Default(true),
+			service.NewStringField(ehiFieldCheckpointContainer).
+				Description("The name of a blob storage container used for storing checkpoints and coordinating the ownership of partitions across clients. When empty checkpoints are not stored and every partition is consumed by this input.").
+				Default(""),
+			service.NewIntField(ehiFieldCheckpointLimit).
+				Description("The maximum gap between the in flight offset versus the latest acknowledged offset of a partition at a given time. Increasing this limit enables parallel processing and batching at the output level. Any given offset will not be committed unless all events before it have been acknowledged in order to preserve at least once delivery guarantees.").
+				Default(1024),
+			service.NewIntField(ehiFieldPrefetchCount).
+				Description("The maximum number of events of each partition to prefetch from the event hub.").
+				Default(300).
+				Advanced(),
+			service.NewAutoRetryNacksToggleField(),
+			service.NewDurationField(ehiFieldCommitPeriod).
+				Description("The period of time between each checkpoint of the latest acknowledged events.").
+				Default("5s"),
+			service.NewDurationField(ehiFieldRebalancePeriod).
+				Description("The period of time between each attempt to claim partitions and renew the ownership of partitions already claimed.").
+				Default("10s").
+				Advanced(),
+			service.NewDurationField(ehiFieldLeasePeriod).
+				Description("The period of time after which a client that has failed to renew the ownership of a partition is assumed to be inactive, and its partitions are claimed by other clients. This must be greater than the `"+ehiFieldRebalancePeriod+"`.").
+				Default("1m").
+				Advanced(),
+		).
+		Example("Balanced consumption", "Consume events of an event hub with partitions balanced across any number of clients, storing checkpoints within a blob storage container.", `
+input:
+  azure_event_hubs:
+    connection_string: "${EVENT_HUBS_CONNECTION_STRING}"
+    event_hub: orders
+    consumer_group: benthos
+    storage_connection_string: "${STORAGE_CONNECTION_STRING}"
+    checkpoint_container: orders-checkpoints
+`)
+}
+
+func init() {
+	err := service.RegisterInput("azure_event_hubs", ehiSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			r, err := newAzureEventHubsReaderFromParsed(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksToggled(conf, r)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type ehAsyncMessage struct {
+	msg   *service.Message
+	ackFn service.AckFunc
+}
+
+// ehPartitionReceiver is implemented by both *azeventhubs.PartitionClient
+// and *azeventhubs.ProcessorPartitionClient.
+type ehPartitionReceiver interface {
+	ReceiveEvents(ctx context.Context, count int, options *azeventhubs.ReceiveEventsOptions) ([]*azeventhubs.ReceivedEventData, error)
+	Close(ctx context.Context) error
+}
+
+type ehUpdateCheckpointFn func(ctx context.Context, latestEvent *azeventhubs.ReceivedEventData, options *azeventhubs.UpdateCheckpointOptions) error
+
+// ehConnState holds the resources of a single session of consuming the event
+// hub, which are torn down as a unit when the connection is lost.
+type ehConnState struct {
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	lostOnce sync.Once
+	lost     chan struct{}
+}
+
+func (s *ehConnState) markLost() {
+	s.lostOnce.Do(func() {
+		close(s.lost)
+	})
+}
+
+type azureEventHubsReader struct {
+	eventHub        string
+	consumerGroup   string
+	startFromOldest bool
+	checkpointLimit int
+	prefetchCount   int
+	commitPeriod    time.Duration
+	rebalancePeriod time.Duration
+	leasePeriod     time.Duration
+
+	client          *azeventhubs.ConsumerClient
+	checkpointStore azeventhubs.CheckpointStore
+	log             *service.Logger
+
+	msgChan chan ehAsyncMessage
+
+	stateMut sync.Mutex
+	state    *ehConnState
+}
+
+func newAzureEventHubsReaderFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (r *azureEventHubsReader, err error) {
+	r = &azureEventHubsReader{
+		log:     mgr.Logger(),
+		msgChan: make(chan ehAsyncMessage),
+	}
+
+	var connectionString, namespace string
+	if connectionString, err = conf.FieldString(ehiFieldConnectionString); err != nil {
+		return
+	}
+	if namespace, err = conf.FieldString(ehiFieldNamespace); err != nil {
+		return
+	}
+	if connectionString == "" && namespace == "" {
+		return nil, fmt.Errorf("either field `%v` or `%v` must be set", ehiFieldConnectionString, ehiFieldNamespace)
+	}
+	if r.eventHub, err = conf.FieldString(ehiFieldEventHub); err != nil {
+		return
+	}
+	if r.consumerGroup, err = conf.FieldString(ehiFieldConsumerGroup); err != nil {
+		return
+	}
+	if r.startFromOldest, err = conf.FieldBool(ehiFieldStartFromOldest); err != nil {
+		return
+	}
+	if r.checkpointLimit, err = conf.FieldInt(ehiFieldCheckpointLimit); err != nil {
+		return
+	}
+	if r.checkpointLimit < 1 {
+		return nil, fmt.Errorf("field `%v` must be at least 1", ehiFieldCheckpointLimit)
+	}
+	if r.prefetchCount, err = conf.FieldInt(ehiFieldPrefetchCount); err != nil {
+		return
+	}
+	if r.prefetchCount < 1 {
+		return nil, fmt.Errorf("field `%v` must be at least 1", ehiFieldPrefetchCount)
+	}
+	if r.commitPeriod, err = conf.FieldDuration(ehiFieldCommitPeriod); err != nil {
+		return
+	}
+	if r.rebalancePeriod, err = conf.FieldDuration(ehiFieldRebalancePeriod); err != nil {
+		return
+	}
+	if r.leasePeriod, err = conf.FieldDuration(ehiFieldLeasePeriod); err != nil {
+		return
+	}
+	if r.leasePeriod <= r.rebalancePeriod {
+		return nil, fmt.Errorf("field `%v` must be greater than `%v`", ehiFieldLeasePeriod, ehiFieldRebalancePeriod)
+	}
+
+	if r.client, err = r.newClient(connectionString, namespace); err != nil {
+		return nil, err
+	}
+
+	var containerName string
+	if containerName, err = conf.FieldString(ehiFieldCheckpointContainer); err != nil {
+		return
+	}
+	if containerName != "" {
+		client, containerSASToken, err := blobStorageClientFromParsed(conf, containerName)
+		if err != nil {
+			return nil, err
+		}
+		if containerSASToken {
+			// when using a container SAS token, the container is already implicit
+			containerName = ""
+		}
+		if r.checkpointStore, err = checkpoints.NewBlobStore(client.ServiceClient().NewContainerClient(containerName), nil); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *azureEventHubsReader) newClient(connectionString, namespace string) (*azeventhubs.ConsumerClient, error) {
+	if connectionString != "" {
+		props, err := azeventhubs.ParseConnectionString(connectionString)
+		if err != nil {
+			return nil, err
+		}
+
+		// The client only accepts an event hub name when the connection string
+		// does not contain one.
+		var eventHub string
+		if props.EntityPath != nil {
+			if r.eventHub != "" && r.eventHub != *props.EntityPath {
+				return nil, fmt.Errorf("field `%v` does not match the EntityPath '%v' of the connection string", ehiFieldEventHub, *props.EntityPath)
+			}
+			r.eventHub = *props.EntityPath
+		} else if eventHub = r.eventHub; eventHub == "" {
+			return nil, fmt.Errorf("field `%v` must be set when the connection string does not contain an EntityPath", ehiFieldEventHub)
+		}
+		return azeventhubs.NewConsumerClientFromConnectionString(connectionString, eventHub, r.consumerGroup, nil)
+	}
+
+	if r.eventHub == "" {
+		return nil, fmt.Errorf("field `%v` must be set when using field `%v`", ehiFieldEventHub, ehiFieldNamespace)
+	}
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("error getting default Azure credentials: %v", err)
+	}
+	return azeventhubs.NewConsumerClient(namespace, r.eventHub, r.consumerGroup, cred, nil)
+}
+
+func (r *azureEventHubsReader) startPosition() azeventhubs.StartPosition {
+	if r.startFromOldest {
+		return azeventhubs.StartPosition{Earliest: to.Ptr(true)}
+	}
+	return azeventhubs.StartPosition{Latest: to.Ptr(true)}
+}
+
+func (r *azureEventHubsReader) ehMessage(event *azeventhubs.ReceivedEventData, partitionID string) *service.Message {
+	part := service.NewMessage(event.Body)
+	for k, v := range event.Properties {
+		part.MetaSetMut(k, v)
+	}
+	part.MetaSetMut("eventhub_name", r.eventHub)
+	part.MetaSetMut("eventhub_consumer_group", r.consumerGroup)
+	part.MetaSetMut("eventhub_partition_id", partitionID)
+	part.MetaSetMut("eventhub_offset", strconv.FormatInt(event.Offset, 10))
+	part.MetaSetMut("eventhub_sequence_number", event.SequenceNumber)
+	if event.PartitionKey != nil {
+		part.MetaSetMut("eventhub_partition_key", *event.PartitionKey)
+	}
+	if event.EnqueuedTime != nil {
+		part.MetaSetMut("eventhub_enqueued_time", event.EnqueuedTime.Format(time.RFC3339Nano))
+	}
+	return part
+}
+
+//------------------------------------------------------------------------------
+
+func (r *azureEventHubsReader) Connect(ctx context.Context) error {
+	r.stateMut.Lock()
+	defer r.stateMut.Unlock()
+
+	if r.state != nil {
+		return nil
+	}
+
+	loopCtx, cancel := context.WithCancel(context.Background())
+	s := &ehConnState{
+		cancel: cancel,
+		lost:   make(chan struct{}),
+	}
+
+	if r.checkpointStore != nil {
+		// A processor can only be run once, and therefore a new one is created
+		// for each connection.
+		processor, err := azeventhubs.NewProcessor(r.client, r.checkpointStore, &azeventhubs.ProcessorOptions{
+			UpdateInterval:              r.rebalancePeriod,
+			PartitionExpirationDuration: r.leasePeriod,
+			StartPositions:              azeventhubs.StartPositions{Default: r.startPosition()},
+			Prefetch:                    int32(r.prefetchCount),
+		})
+		if err != nil {
+			cancel()
+			return err
+		}
+
+		s.wg.Add(2)
+		go func() {
+			defer s.wg.Done()
+			if err := processor.Run(loopCtx); err != nil {
+				r.log.Errorf("Failed to balance partitions of event hub '%v': %v", r.eventHub, err)
+				s.markLost()
+			}
+		}()
+		go func() {
+			defer s.wg.Done()
+			for {
+				pc := processor.NextPartitionClient(loopCtx)
+				if pc == nil {
+					return
+				}
+				// Partitions that are released here are consumed again when the
+				// processor next finds that they are still owned by this client.
+				r.startConsumer(loopCtx, s, pc.PartitionID(), pc, pc.UpdateCheckpoint, false)
+			}
+		}()
+	} else {
+		props, err := r.client.GetEventHubProperties(ctx, nil)
+		if err != nil {
+			cancel()
+			return fmt.Errorf("failed to obtain partitions of event hub '%v': %w", r.eventHub, err)
+		}
+		for _, partitionID := range props.PartitionIDs {
+			pc, err := r.client.NewPartitionClient(partitionID, &azeventhubs.PartitionClientOptions{
+				StartPosition: r.startPosition(),
+				Prefetch:      int32(r.prefetchCount),
+			})
+			if err != nil {
+				cancel()
+				s.wg.Wait()
+				return err
+			}
+			r.startConsumer(loopCtx, s, partitionID, pc, nil, true)
+		}
+	}
+
+	r.state = s
+	r.log.Infof("Receiving Azure Event Hubs events from '%v'", r.eventHub)
+	return nil
+}
+
+// startConsumer consumes a partition in the background until the context is
+// cancelled or the partition fails. When lostOnErr is true a failure of the
+// partition marks the entire connection as lost.
+func (r *azureEventHubsReader) startConsumer(ctx context.Context, s *ehConnState, partitionID string, pc ehPartitionReceiver, updateCheckpoint ehUpdateCheckpointFn, lostOnErr bool) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		r.log.Debugf("Consuming event hub '%v' partition '%v'", r.eventHub, partitionID)
+		err := r.consumePartition(ctx, partitionID, pc, updateCheckpoint)
+		if err == nil {
+			r.log.Debugf("Stopped consuming event hub '%v' partition '%v'", r.eventHub, partitionID)
+			return
+		}
+
+		var ehErr *azeventhubs.Error
+		if errors.As(err, &ehErr) && ehErr.Code == azeventhubs.ErrorCodeOwnershipLost {
+			r.log.Debugf("Event hub '%v' partition '%v' has been claimed by another client", r.eventHub, partitionID)
+			return
+		}
+		r.log.Errorf("Stopped consuming event hub '%v' partition '%v': %v", r.eventHub, partitionID, err)
+		if lostOnErr {
+			s.markLost()
+		}
+	}()
+}
+
+func (r *azureEventHubsReader) consumePartition(ctx context.Context, partitionID string, pc ehPartitionReceiver, updateCheckpoint ehUpdateCheckpointFn) error {
+	defer func() {
+		closeCtx, done := context.WithTimeout(context.Background(), time.Second*5)
+		_ = pc.Close(closeCtx)
+		done()
+	}()
+
+	tracker := checkpoint.NewCapped[*azeventhubs.ReceivedEventData](int64(r.checkpointLimit))
+
+	pCtx, pDone := context.WithCancel(ctx)
+	defer pDone()
+
+	var commitWG sync.WaitGroup
+	if updateCheckpoint != nil {
+		commitWG.Add(1)
+		go func() {
+			defer commitWG.Done()
+			r.commitLoop(pCtx, partitionID, tracker, updateCheckpoint)
+		}()
+	}
+
+	err := r.receiveLoop(pCtx, partitionID, pc, tracker)
+	pDone()
+	commitWG.Wait()
+	return err
+}
+
+func (r *azureEventHubsReader) receiveLoop(ctx context.Context, partitionID string, pc ehPartitionReceiver, tracker *checkpoint.Capped[*azeventhubs.ReceivedEventData]) error {
+	for {
+		// Events are buffered by the prefetch of the client, and so receiving
+		// them one at a time avoids holding back events until a batch fills.
+		events, err := pc.ReceiveEvents(ctx, 1, nil)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		for _, event := range events {
+			resolveFn, err := tracker.Track(ctx, event, 1)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+
+			select {
+			case r.msgChan <- ehAsyncMessage{
+				msg: r.ehMessage(event, partitionID),
+				ackFn: func(context.Context, error) error {
+					resolveFn()
+					return nil
+				},
+			}:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+// commitLoop periodically stores the latest acknowledged event of a partition
+// as its checkpoint until the context is cancelled, at which point a final
+// checkpoint is stored.
+func (r *azureEventHubsReader) commitLoop(ctx context.Context, partitionID string, tracker *checkpoint.Capped[*azeventhubs.ReceivedEventData], updateCheckpoint ehUpdateCheckpointFn) {
+	var last *azeventhubs.ReceivedEventData
+	commit := func(ctx context.Context) error {
+		highest := tracker.Highest()
+		if highest == nil || *highest == last {
+			return nil
+		}
+		if err := updateCheckpoint(ctx, *highest, nil); err != nil {
+			return err
+		}
+		last = *highest
+		return nil
+	}
+
+	ticker := time.NewTicker(r.commitPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := commit(ctx); err != nil && ctx.Err() == nil {
+				r.log.Errorf("Failed to store checkpoint for event hub '%v' partition '%v': %v", r.eventHub, partitionID, err)
+			}
+		case <-ctx.Done():
+			finalCtx, done := context.WithTimeout(context.Background(), time.Second*5)
+			defer done()
+
+			if err := commit(finalCtx); err != nil {
+				r.log.Errorf("Failed to store final checkpoint for event hub '%v' partition '%v': %v", r.eventHub, partitionID, err)
+			}
+			return
+		}
+	}
+}
+
+func (r *azureEventHubsReader) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	r.stateMut.Lock()
+	s := r.state
+	r.stateMut.Unlock()
+
+	if s == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	select {
+	case m := <-r.msgChan:
+		return m.msg, m.ackFn, nil
+	case <-s.lost:
+		r.log.Warnf("Lost connection to event hub '%v'", r.eventHub)
+		r.disconnect(ctx, s)
+		return nil, nil, service.ErrNotConnected
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+func (r *azureEventHubsReader) disconnect(ctx context.Context, s *ehConnState) {
+	r.stateMut.Lock()
+	if r.state == s {
+		r.state = nil
+	}
+	r.stateMut.Unlock()
+
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+func (r *azureEventHubsReader) Close(ctx context.Context) error {
+	r.stateMut.Lock()
+	s := r.state
+	r.stateMut.Unlock()
+
+	if s != nil {
+		r.disconnect(ctx, s)
+	}
+	return r.client.Close(ctx)
+}
@@ -0,0 +1,198 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestEventHubsConfig(t *testing.T) {
+	pConf, err := ehiSpec().ParseYAML(`
+event_hub: foo
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newAzureEventHubsReaderFromParsed(pConf, service.MockResources())
+	require.ErrorContains(t, err, "namespace")
+
+	pConf, err = ehiSpec().ParseYAML(`
+connection_string: Endpoint=sb://foo.servicebus.windows.net/;SharedAccessKeyName=a;SharedAccessKey=b
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newAzureEventHubsReaderFromParsed(pConf, service.MockResources())
+	require.ErrorContains(t, err, "event_hub")
+
+	pConf, err = ehiSpec().ParseYAML(`
+connection_string: Endpoint=sb://foo.servicebus.windows.net/;SharedAccessKeyName=a;SharedAccessKey=b;EntityPath=bar
+event_hub: baz
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newAzureEventHubsReaderFromParsed(pConf, service.MockResources())
+	require.ErrorContains(t, err, "EntityPath")
+
+	pConf, err = ehiSpec().ParseYAML(`
+connection_string: Endpoint=sb://foo.servicebus.windows.net/;SharedAccessKeyName=a;SharedAccessKey=b;EntityPath=bar
+storage_connection_string: "UseDevelopmentStorage=true;"
+checkpoint_container: baz
+`, nil)
+	require.NoError(t, err)
+
+	r, err := newAzureEventHubsReaderFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, r.Close(context.Background())) })
+	assert.Equal(t, "bar", r.eventHub)
+	assert.Equal(t, "$Default", r.consumerGroup)
+	assert.NotNil(t, r.checkpointStore)
+
+	pConf, err = ehiSpec().ParseYAML(`
+connection_string: Endpoint=sb://foo.servicebus.windows.net/;SharedAccessKeyName=a;SharedAccessKey=b;EntityPath=bar
+rebalance_period: 1m
+lease_period: 30s
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newAzureEventHubsReaderFromParsed(pConf, service.MockResources())
+	require.ErrorContains(t, err, "lease_period")
+}
+
+func TestEventHubsMessage(t *testing.T) {
+	enqueued := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	r := &azureEventHubsReader{eventHub: "bar", consumerGroup: "$Default"}
+	part := r.ehMessage(&azeventhubs.ReceivedEventData{
+		EventData: azeventhubs.EventData{
+			Body:       []byte("hello world"),
+			Properties: map[string]any{"tenant": "acme"},
+		},
+		EnqueuedTime:   &enqueued,
+		PartitionKey:   to.Ptr("foo"),
+		Offset:         4096,
+		SequenceNumber: 12,
+	}, "2")
+
+	mBytes, err := part.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(mBytes))
+
+	meta := map[string]any{}
+	require.NoError(t, part.MetaWalkMut(func(key string, value any) error {
+		meta[key] = value
+		return nil
+	}))
+	assert.Equal(t, map[string]any{
+		"tenant":                   "acme",
+		"eventhub_name":            "bar",
+		"eventhub_consumer_group":  "$Default",
+		"eventhub_partition_id":    "2",
+		"eventhub_offset":          "4096",
+		"eventhub_sequence_number": int64(12),
+		"eventhub_partition_key":   "foo",
+		"eventhub_enqueued_time":   "2024-05-01T10:00:00Z",
+	}, meta)
+}
+
+type fakeEHReceiver struct {
+	events chan *azeventhubs.ReceivedEventData
+	closed chan struct{}
+}
+
+func (f *fakeEHReceiver) ReceiveEvents(ctx context.Context, count int, _ *azeventhubs.ReceiveEventsOptions) ([]*azeventhubs.ReceivedEventData, error) {
+	select {
+	case e := <-f.events:
+		return []*azeventhubs.ReceivedEventData{e}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (f *fakeEHReceiver) Close(context.Context) error {
+	close(f.closed)
+	return nil
+}
+
+func TestEventHubsConsumePartitionCheckpoints(t *testing.T) {
+	r := &azureEventHubsReader{
+		eventHub:        "bar",
+		consumerGroup:   "$Default",
+		checkpointLimit: 10,
+		commitPeriod:    time.Hour,
+		log:             service.MockResources().Logger(),
+		msgChan:         make(chan ehAsyncMessage),
+	}
+
+	pc := &fakeEHReceiver{
+		events: make(chan *azeventhubs.ReceivedEventData, 3),
+		closed: make(chan struct{}),
+	}
+	for i := int64(0); i < 3; i++ {
+		pc.events <- &azeventhubs.ReceivedEventData{Offset: i * 100, SequenceNumber: i}
+	}
+
+	var committed []int64
+	updateCheckpoint := func(_ context.Context, latest *azeventhubs.ReceivedEventData, _ *azeventhubs.UpdateCheckpointOptions) error {
+		committed = append(committed, latest.SequenceNumber)
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	consumeErr := make(chan error, 1)
+	go func() {
+		consumeErr <- r.consumePartition(ctx, "1", pc, updateCheckpoint)
+	}()
+
+	var msgs []ehAsyncMessage
+	for i := 0; i < 3; i++ {
+		select {
+		case m := <-r.msgChan:
+			msgs = append(msgs, m)
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out waiting for event")
+		}
+	}
+
+	offset, ok := msgs[2].msg.MetaGetMut("eventhub_offset")
+	require.True(t, ok)
+	assert.Equal(t, "200", offset)
+
+	// The third event is not checkpointed until the second is acknowledged.
+	require.NoError(t, msgs[0].ackFn(ctx, nil))
+	require.NoError(t, msgs[2].ackFn(ctx, nil))
+
+	cancel()
+	select {
+	case err := <-consumeErr:
+		require.NoError(t, err)
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for partition consumer")
+	}
+
+	assert.Equal(t, []int64{0}, committed)
+	select {
+	case <-pc.closed:
+	default:
+		t.Error("expected partition client to be closed")
+	}
+}
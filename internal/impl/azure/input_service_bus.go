@@ -0,0 +1,620 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	// Service Bus Input Fields
+	sbiFieldConnectionString      = "connection_string"
+	sbiFieldNamespace             = "namespace"
+	sbiFieldQueue                 = "queue"
+	sbiFieldTopic                 = "topic"
+	sbiFieldSubscription          = "subscription"
+	sbiFieldReceiveMode           = "receive_mode"
+	sbiFieldNackAction            = "nack_action"
+	sbiFieldSessionEnabled        = "session_enabled"
+	sbiFieldMaxConcurrentSessions = "max_concurrent_sessions"
+	sbiFieldSessionIdleTimeout    = "session_idle_timeout"
+	sbiFieldMaxMessages           = "max_messages"
+	sbiFieldLockRenewalPeriod     = "lock_renewal_period"
+)
+
+func sbiSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services", "Azure").
+		Version("4.31.0").
+		Summary(`Consumes messages from an Azure Service Bus queue or topic subscription.`).
+		Description(`
+Messages are consumed from either a `+"`"+sbiFieldQueue+"`"+` or the `+"`"+sbiFieldSubscription+"`"+` of a `+"`"+sbiFieldTopic+"`"+`, and exactly one of the two must be configured.
+
+== Authentication
+
+When a `+"`"+sbiFieldConnectionString+"`"+` is set it is used to authenticate with shared access signatures. Otherwise the fully qualified `+"`"+sbiFieldNamespace+"`"+` must be set, and credentials are obtained in the same way as the other Azure components, using the default Azure credential chain (environment variables, workload identity, managed identity, or the Azure CLI).
+
+== Delivery guarantees
+
+In the `+"`peek_lock`"+` receive mode messages are locked when received and are only settled once they have been processed. Acknowledged messages are completed. When `+"`auto_replay_nacks`"+` is `+"`false`"+` rejected messages are either abandoned, which makes them available for redelivery and increments their delivery count, or moved to the dead-letter queue of the entity with the error as the reason, depending on the `+"`"+sbiFieldNackAction+"`"+` field. Abandoned messages are moved to the dead-letter queue by Service Bus itself once their delivery count exceeds the maximum delivery count of the entity.
+
+The locks of messages that are still being processed are renewed periodically as determined by `+"`"+sbiFieldLockRenewalPeriod+"`"+`, which must be less than the lock duration of the entity.
+
+In the `+"`receive_and_delete`"+` receive mode messages are removed from the entity as soon as they are received, and therefore messages that are in flight when the process exits are lost.
+
+== Sessions
+
+When `+"`"+sbiFieldSessionEnabled+"`"+` is `+"`true`"+` the entity must be session aware, and messages are consumed from up to `+"`"+sbiFieldMaxConcurrentSessions+"`"+` sessions at a time. Messages of a session are received in the order in which they were sent, and a session is released once no messages have been received from it for the period of `+"`"+sbiFieldSessionIdleTimeout+"`"+` and all of its messages in flight have been settled. The session lock is renewed periodically in place of the locks of individual messages.
+
+In order to preserve the order of messages within a session all the way to the output, set `+"`"+sbiFieldMaxMessages+"`"+` to `+"`1`"+` and use an output with a `+"`max_in_flight`"+` of `+"`1`"+`.
+
+== Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- service_bus_message_id
+- service_bus_session_id
+- service_bus_delivery_count
+- service_bus_enqueued_time
+- service_bus_sequence_number
+- service_bus_content_type
+- service_bus_correlation_id
+- All application properties of the message
+`+"```"+`
+
+You can access these metadata fields using xref:configuration:interpolation.adoc#bloblang-queries[function interpolation].`).
+		Fields(
+			service.NewStringField(sbiFieldConnectionString).
+				Description("The connection string of the Service Bus namespace. When empty the `"+sbiFieldNamespace+"` field must be set.").
+				Example("Endpoint=sb://foo.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=bar").
+				Default("").
+				Secret(),
+			service.NewStringField(sbiFieldNamespace).
+				Description("The fully qualified namespace to connect to using the default Azure credential chain. This field is ignored when `"+sbiFieldConnectionString+"` is set.").
+				Example("foo.servicebus.windows.net").
+				Default(""),
+			service.NewStringField(sbiFieldQueue).
+				Description("The name of a queue to consume from.").
+				Default(""),
+			service.NewStringField(sbiFieldTopic).
+				Description("The name of a topic to consume from, which requires a `"+sbiFieldSubscription+"`.").
+				Default(""),
+			service.NewStringField(sbiFieldSubscription).
+				Description("The name of the subscription of the topic to consume from.").
+				Default(""),
+			service.NewStringEnumField(sbiFieldReceiveMode, "peek_lock", "receive_and_delete").
+				Description("The mode in which messages are received.").
+				Default("peek_lock"),
+			service.NewStringEnumField(sbiFieldNackAction, "abandon", "dead_letter").
+				Description("How rejected messages are settled when `auto_replay_nacks` is `false` and the receive mode is `peek_lock`.").
+				Default("abandon"),
+			service.NewBoolField(sbiFieldSessionEnabled).
+				Description("Whether to consume from the sessions of a session aware entity.").
+				Default(false),
+			service.NewIntField(sbiFieldMaxConcurrentSessions).
+				Description("The maximum number of sessions to consume from concurrently.").
+				Default(8).
+				Advanced(),
+			service.NewDurationField(sbiFieldSessionIdleTimeout).
+				Description("The period of time after which a session that has not yielded any messages is released.").
+				Default("10s").
+				Advanced(),
+			service.NewIntField(sbiFieldMaxMessages).
+				Description("The maximum number of messages to receive in a single request.").
+				Default(10).
+				Advanced(),
+			service.NewDurationField(sbiFieldLockRenewalPeriod).
+				Description("The period of time between each renewal of the lock of a message, or session, that is in flight. Set to `0s` in order to disable lock renewal.").
+				Default("30s").
+				Advanced(),
+			service.NewAutoRetryNacksToggleField(),
+		).
+		Example("Sessions", "Consume the messages of a session aware queue in order, authenticating with a managed identity.", `
+input:
+  azure_service_bus:
+    namespace: foo.servicebus.windows.net
+    queue: orders
+    session_enabled: true
+    max_messages: 1
+`)
+}
+
+func init() {
+	err := service.RegisterInput("azure_service_bus", sbiSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			r, err := newAzureServiceBusReaderFromParsed(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksToggled(conf, r)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// sbReceiver is the subset of the methods of both azservicebus.Receiver and
+// azservicebus.SessionReceiver used by this input.
+type sbReceiver interface {
+	ReceiveMessages(ctx context.Context, maxMessages int, options *azservicebus.ReceiveMessagesOptions) ([]*azservicebus.ReceivedMessage, error)
+	CompleteMessage(ctx context.Context, msg *azservicebus.ReceivedMessage, options *azservicebus.CompleteMessageOptions) error
+	AbandonMessage(ctx context.Context, msg *azservicebus.ReceivedMessage, options *azservicebus.AbandonMessageOptions) error
+	DeadLetterMessage(ctx context.Context, msg *azservicebus.ReceivedMessage, options *azservicebus.DeadLetterOptions) error
+	Close(ctx context.Context) error
+}
+
+type sbAsyncMessage struct {
+	msg   *service.Message
+	ackFn service.AckFunc
+}
+
+// sbConnState holds the resources of a single client of the namespace, which
+// are torn down as a unit when the connection is lost.
+type sbConnState struct {
+	client   *azservicebus.Client
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	lostOnce sync.Once
+	lost     chan struct{}
+}
+
+func (s *sbConnState) markLost() {
+	s.lostOnce.Do(func() {
+		close(s.lost)
+	})
+}
+
+type azureServiceBusReader struct {
+	connectionString      string
+	namespace             string
+	queue                 string
+	topic                 string
+	subscription          string
+	receiveMode           azservicebus.ReceiveMode
+	deadLetterNacks       bool
+	sessionEnabled        bool
+	maxConcurrentSessions int
+	sessionIdleTimeout    time.Duration
+	maxMessages           int
+	lockRenewalPeriod     time.Duration
+
+	log *service.Logger
+
+	msgChan chan sbAsyncMessage
+
+	stateMut sync.Mutex
+	state    *sbConnState
+}
+
+func newAzureServiceBusReaderFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (r *azureServiceBusReader, err error) {
+	r = &azureServiceBusReader{
+		log:     mgr.Logger(),
+		msgChan: make(chan sbAsyncMessage),
+	}
+
+	if r.connectionString, err = conf.FieldString(sbiFieldConnectionString); err != nil {
+		return
+	}
+	if r.namespace, err = conf.FieldString(sbiFieldNamespace); err != nil {
+		return
+	}
+	if r.connectionString == "" && r.namespace == "" {
+		return nil, fmt.Errorf("either field `%v` or `%v` must be set", sbiFieldConnectionString, sbiFieldNamespace)
+	}
+	if r.queue, err = conf.FieldString(sbiFieldQueue); err != nil {
+		return
+	}
+	if r.topic, err = conf.FieldString(sbiFieldTopic); err != nil {
+		return
+	}
+	if r.subscription, err = conf.FieldString(sbiFieldSubscription); err != nil {
+		return
+	}
+	if (r.queue == "") == (r.topic == "") {
+		return nil, fmt.Errorf("exactly one of the fields `%v` or `%v` must be set", sbiFieldQueue, sbiFieldTopic)
+	}
+	if (r.topic == "") != (r.subscription == "") {
+		return nil, fmt.Errorf("field `%v` must be set with, and only with, a `%v`", sbiFieldSubscription, sbiFieldTopic)
+	}
+
+	var receiveMode string
+	if receiveMode, err = conf.FieldString(sbiFieldReceiveMode); err != nil {
+		return
+	}
+	if receiveMode == "receive_and_delete" {
+		r.receiveMode = azservicebus.ReceiveModeReceiveAndDelete
+	} else {
+		r.receiveMode = azservicebus.ReceiveModePeekLock
+	}
+
+	var nackAction string
+	if nackAction, err = conf.FieldString(sbiFieldNackAction); err != nil {
+		return
+	}
+	r.deadLetterNacks = nackAction == "dead_letter"
+
+	if r.sessionEnabled, err = conf.FieldBool(sbiFieldSessionEnabled); err != nil {
+		return
+	}
+	if r.maxConcurrentSessions, err = conf.FieldInt(sbiFieldMaxConcurrentSessions); err != nil {
+		return
+	}
+	if r.maxConcurrentSessions < 1 {
+		return nil, fmt.Errorf("field `%v` must be at least 1", sbiFieldMaxConcurrentSessions)
+	}
+	if r.sessionIdleTimeout, err = conf.FieldDuration(sbiFieldSessionIdleTimeout); err != nil {
+		return
+	}
+	if r.sessionIdleTimeout <= 0 {
+		return nil, fmt.Errorf("field `%v` must be greater than zero", sbiFieldSessionIdleTimeout)
+	}
+	if r.maxMessages, err = conf.FieldInt(sbiFieldMaxMessages); err != nil {
+		return
+	}
+	if r.maxMessages < 1 {
+		return nil, fmt.Errorf("field `%v` must be at least 1", sbiFieldMaxMessages)
+	}
+	if r.lockRenewalPeriod, err = conf.FieldDuration(sbiFieldLockRenewalPeriod); err != nil {
+		return
+	}
+	return r, nil
+}
+
+func (r *azureServiceBusReader) entityName() string {
+	if r.queue != "" {
+		return r.queue
+	}
+	return r.topic + "/" + r.subscription
+}
+
+func (r *azureServiceBusReader) newClient() (*azservicebus.Client, error) {
+	if r.connectionString != "" {
+		return azservicebus.NewClientFromConnectionString(r.connectionString, nil)
+	}
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("error getting default Azure credentials: %v", err)
+	}
+	return azservicebus.NewClient(r.namespace, cred, nil)
+}
+
+func sbMessage(rm *azservicebus.ReceivedMessage) *service.Message {
+	part := service.NewMessage(rm.Body)
+	for k, v := range rm.ApplicationProperties {
+		part.MetaSetMut(k, v)
+	}
+	part.MetaSetMut("service_bus_message_id", rm.MessageID)
+	part.MetaSetMut("service_bus_delivery_count", int64(rm.DeliveryCount))
+	if rm.SessionID != nil {
+		part.MetaSetMut("service_bus_session_id", *rm.SessionID)
+	}
+	if rm.EnqueuedTime != nil {
+		part.MetaSetMut("service_bus_enqueued_time", rm.EnqueuedTime.Format(time.RFC3339Nano))
+	}
+	if rm.SequenceNumber != nil {
+		part.MetaSetMut("service_bus_sequence_number", *rm.SequenceNumber)
+	}
+	if rm.ContentType != nil {
+		part.MetaSetMut("service_bus_content_type", *rm.ContentType)
+	}
+	if rm.CorrelationID != nil {
+		part.MetaSetMut("service_bus_correlation_id", *rm.CorrelationID)
+	}
+	return part
+}
+
+//------------------------------------------------------------------------------
+
+func (r *azureServiceBusReader) Connect(ctx context.Context) error {
+	r.stateMut.Lock()
+	defer r.stateMut.Unlock()
+
+	if r.state != nil {
+		return nil
+	}
+
+	client, err := r.newClient()
+	if err != nil {
+		return err
+	}
+
+	loopCtx, cancel := context.WithCancel(context.Background())
+	s := &sbConnState{
+		client: client,
+		cancel: cancel,
+		lost:   make(chan struct{}),
+	}
+
+	if !r.sessionEnabled {
+		opts := &azservicebus.ReceiverOptions{ReceiveMode: r.receiveMode}
+
+		var receiver *azservicebus.Receiver
+		if r.queue != "" {
+			receiver, err = client.NewReceiverForQueue(r.queue, opts)
+		} else {
+			receiver, err = client.NewReceiverForSubscription(r.topic, r.subscription, opts)
+		}
+		if err != nil {
+			cancel()
+			_ = client.Close(ctx)
+			return err
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer receiver.Close(context.Background())
+
+			var inFlight sync.WaitGroup
+			err := r.receiveLoop(loopCtx, receiver, receiver.RenewMessageLock, 0, &inFlight)
+			if err != nil {
+				r.log.Errorf("Failed to receive messages from '%v': %v", r.entityName(), err)
+				s.markLost()
+			}
+		}()
+	} else {
+		for i := 0; i < r.maxConcurrentSessions; i++ {
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				r.sessionLoop(loopCtx, s)
+			}()
+		}
+	}
+
+	r.state = s
+	r.log.Infof("Receiving Azure Service Bus messages from '%v'", r.entityName())
+	return nil
+}
+
+func (r *azureServiceBusReader) acceptNextSession(ctx context.Context, client *azservicebus.Client) (*azservicebus.SessionReceiver, error) {
+	opts := &azservicebus.SessionReceiverOptions{ReceiveMode: r.receiveMode}
+	if r.queue != "" {
+		return client.AcceptNextSessionForQueue(ctx, r.queue, opts)
+	}
+	return client.AcceptNextSessionForSubscription(ctx, r.topic, r.subscription, opts)
+}
+
+// sessionLoop repeatedly accepts the next available session and consumes it
+// until it becomes idle.
+func (r *azureServiceBusReader) sessionLoop(ctx context.Context, s *sbConnState) {
+	for {
+		session, err := r.acceptNextSession(ctx, s.client)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			var sbErr *azservicebus.Error
+			if errors.As(err, &sbErr) && sbErr.Code == azservicebus.CodeTimeout {
+				// No sessions currently have messages available.
+				continue
+			}
+			r.log.Errorf("Failed to accept session of '%v': %v", r.entityName(), err)
+			s.markLost()
+			return
+		}
+
+		if err := r.consumeSession(ctx, session); err != nil {
+			r.log.Errorf("Failed to receive messages from session '%v': %v", session.SessionID(), err)
+			s.markLost()
+			return
+		}
+	}
+}
+
+func (r *azureServiceBusReader) consumeSession(ctx context.Context, session *azservicebus.SessionReceiver) error {
+	r.log.Debugf("Accepted session '%v'", session.SessionID())
+
+	sessCtx, sessDone := context.WithCancel(ctx)
+	defer sessDone()
+
+	if r.receiveMode == azservicebus.ReceiveModePeekLock && r.lockRenewalPeriod > 0 {
+		go r.renewLoop(sessCtx, func(ctx context.Context) error {
+			return session.RenewSessionLock(ctx, nil)
+		})
+	}
+
+	var inFlight sync.WaitGroup
+	err := r.receiveLoop(ctx, session, nil, r.sessionIdleTimeout, &inFlight)
+
+	// Messages in flight must be settled before the session is released, as
+	// settlement is tied to the session receiver.
+	settled := make(chan struct{})
+	go func() {
+		inFlight.Wait()
+		close(settled)
+	}()
+	select {
+	case <-settled:
+	case <-ctx.Done():
+	}
+	sessDone()
+
+	_ = session.Close(context.Background())
+	r.log.Debugf("Released session '%v'", session.SessionID())
+	return err
+}
+
+func (r *azureServiceBusReader) renewLoop(ctx context.Context, renew func(ctx context.Context) error) {
+	ticker := time.NewTicker(r.lockRenewalPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := renew(ctx); err != nil && ctx.Err() == nil {
+				r.log.Warnf("Failed to renew lock: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// receiveLoop receives messages from a receiver and dispatches them to Read
+// until the context is cancelled. When an idle timeout is provided the loop
+// also exits once no messages have been received within that period.
+func (r *azureServiceBusReader) receiveLoop(
+	ctx context.Context,
+	receiver sbReceiver,
+	renewMessageLock func(context.Context, *azservicebus.ReceivedMessage, *azservicebus.RenewMessageLockOptions) error,
+	idleTimeout time.Duration,
+	inFlight *sync.WaitGroup,
+) error {
+	for {
+		rctx, rdone := ctx, context.CancelFunc(func() {})
+		if idleTimeout > 0 {
+			rctx, rdone = context.WithTimeout(ctx, idleTimeout)
+		}
+		msgs, err := receiver.ReceiveMessages(rctx, r.maxMessages, nil)
+		idle := rctx.Err() != nil
+		rdone()
+
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil && !(idleTimeout > 0 && idle) {
+			return err
+		}
+		if len(msgs) == 0 && idleTimeout > 0 {
+			return nil
+		}
+
+		for _, rm := range msgs {
+			if !r.dispatch(ctx, receiver, renewMessageLock, rm, inFlight) {
+				return nil
+			}
+		}
+	}
+}
+
+func (r *azureServiceBusReader) dispatch(
+	ctx context.Context,
+	receiver sbReceiver,
+	renewMessageLock func(context.Context, *azservicebus.ReceivedMessage, *azservicebus.RenewMessageLockOptions) error,
+	rm *azservicebus.ReceivedMessage,
+	inFlight *sync.WaitGroup,
+) bool {
+	peekLock := r.receiveMode == azservicebus.ReceiveModePeekLock
+
+	renewCtx, renewDone := context.WithCancel(ctx)
+	if peekLock && renewMessageLock != nil && r.lockRenewalPeriod > 0 {
+		go r.renewLoop(renewCtx, func(ctx context.Context) error {
+			return renewMessageLock(ctx, rm, nil)
+		})
+	}
+
+	inFlight.Add(1)
+	var settleOnce sync.Once
+	done := func() {
+		settleOnce.Do(func() {
+			renewDone()
+			inFlight.Done()
+		})
+	}
+
+	select {
+	case r.msgChan <- sbAsyncMessage{
+		msg: sbMessage(rm),
+		ackFn: func(ctx context.Context, err error) error {
+			defer done()
+			if !peekLock {
+				return nil
+			}
+			if err == nil {
+				return receiver.CompleteMessage(ctx, rm, nil)
+			}
+			if r.deadLetterNacks {
+				reason := err.Error()
+				return receiver.DeadLetterMessage(ctx, rm, &azservicebus.DeadLetterOptions{
+					Reason: &reason,
+				})
+			}
+			return receiver.AbandonMessage(ctx, rm, nil)
+		},
+	}:
+		return true
+	case <-ctx.Done():
+		// The message was never delivered, its lock expires and it is
+		// redelivered.
+		done()
+		return false
+	}
+}
+
+func (r *azureServiceBusReader) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	r.stateMut.Lock()
+	s := r.state
+	r.stateMut.Unlock()
+
+	if s == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	select {
+	case m := <-r.msgChan:
+		return m.msg, m.ackFn, nil
+	case <-s.lost:
+		r.disconnect(ctx, s)
+		return nil, nil, service.ErrNotConnected
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+func (r *azureServiceBusReader) disconnect(ctx context.Context, s *sbConnState) {
+	r.stateMut.Lock()
+	if r.state == s {
+		r.state = nil
+	}
+	r.stateMut.Unlock()
+
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	_ = s.client.Close(ctx)
+}
+
+func (r *azureServiceBusReader) Close(ctx context.Context) error {
+	r.stateMut.Lock()
+	s := r.state
+	r.stateMut.Unlock()
+
+	if s != nil {
+		r.disconnect(ctx, s)
+	}
+	return nil
+}
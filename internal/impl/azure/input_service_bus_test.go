@@ -0,0 +1,239 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azure
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestServiceBusConfig(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		conf        string
+		errContains string
+	}{
+		{
+			name:        "no credentials",
+			conf:        `queue: foo`,
+			errContains: "namespace",
+		},
+		{
+			name: "queue and topic",
+			conf: `
+namespace: foo.servicebus.windows.net
+queue: foo
+topic: bar
+subscription: baz
+`,
+			errContains: "exactly one",
+		},
+		{
+			name: "topic without subscription",
+			conf: `
+namespace: foo.servicebus.windows.net
+topic: bar
+`,
+			errContains: "subscription",
+		},
+		{
+			name: "queue with subscription",
+			conf: `
+namespace: foo.servicebus.windows.net
+queue: foo
+subscription: baz
+`,
+			errContains: "subscription",
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			pConf, err := sbiSpec().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			_, err = newAzureServiceBusReaderFromParsed(pConf, service.MockResources())
+			require.ErrorContains(t, err, test.errContains)
+		})
+	}
+
+	pConf, err := sbiSpec().ParseYAML(`
+connection_string: Endpoint=sb://foo.servicebus.windows.net/;SharedAccessKeyName=a;SharedAccessKey=b
+topic: bar
+subscription: baz
+receive_mode: receive_and_delete
+`, nil)
+	require.NoError(t, err)
+
+	r, err := newAzureServiceBusReaderFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	assert.Equal(t, "bar/baz", r.entityName())
+	assert.Equal(t, azservicebus.ReceiveModeReceiveAndDelete, r.receiveMode)
+	assert.False(t, r.deadLetterNacks)
+}
+
+func TestServiceBusMessage(t *testing.T) {
+	enqueued := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	seq, sessionID, contentType := int64(42), "s1", "application/json"
+
+	msg := sbMessage(&azservicebus.ReceivedMessage{
+		Body:                  []byte(`{"id":1}`),
+		MessageID:             "m1",
+		SessionID:             &sessionID,
+		DeliveryCount:         3,
+		EnqueuedTime:          &enqueued,
+		SequenceNumber:        &seq,
+		ContentType:           &contentType,
+		ApplicationProperties: map[string]any{"tenant": "acme"},
+	})
+
+	b, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":1}`, string(b))
+
+	meta := map[string]any{}
+	require.NoError(t, msg.MetaWalkMut(func(k string, v any) error {
+		meta[k] = v
+		return nil
+	}))
+	assert.Equal(t, map[string]any{
+		"tenant":                      "acme",
+		"service_bus_message_id":      "m1",
+		"service_bus_session_id":      "s1",
+		"service_bus_delivery_count":  int64(3),
+		"service_bus_enqueued_time":   "2024-06-01T12:00:00Z",
+		"service_bus_sequence_number": int64(42),
+		"service_bus_content_type":    "application/json",
+	}, meta)
+}
+
+type mockSBReceiver struct {
+	mut     sync.Mutex
+	pending [][]*azservicebus.ReceivedMessage
+	settled map[string]string
+	reasons map[string]string
+}
+
+func (m *mockSBReceiver) ReceiveMessages(ctx context.Context, maxMessages int, options *azservicebus.ReceiveMessagesOptions) ([]*azservicebus.ReceivedMessage, error) {
+	m.mut.Lock()
+	if len(m.pending) > 0 {
+		msgs := m.pending[0]
+		m.pending = m.pending[1:]
+		m.mut.Unlock()
+		return msgs, nil
+	}
+	m.mut.Unlock()
+
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (m *mockSBReceiver) settle(rm *azservicebus.ReceivedMessage, how string) {
+	m.mut.Lock()
+	m.settled[rm.MessageID] = how
+	m.mut.Unlock()
+}
+
+func (m *mockSBReceiver) CompleteMessage(ctx context.Context, rm *azservicebus.ReceivedMessage, options *azservicebus.CompleteMessageOptions) error {
+	m.settle(rm, "complete")
+	return nil
+}
+
+func (m *mockSBReceiver) AbandonMessage(ctx context.Context, rm *azservicebus.ReceivedMessage, options *azservicebus.AbandonMessageOptions) error {
+	m.settle(rm, "abandon")
+	return nil
+}
+
+func (m *mockSBReceiver) DeadLetterMessage(ctx context.Context, rm *azservicebus.ReceivedMessage, options *azservicebus.DeadLetterOptions) error {
+	m.settle(rm, "dead_letter")
+	m.mut.Lock()
+	m.reasons[rm.MessageID] = *options.Reason
+	m.mut.Unlock()
+	return nil
+}
+
+func (m *mockSBReceiver) Close(ctx context.Context) error {
+	return nil
+}
+
+func TestServiceBusSettlement(t *testing.T) {
+	for _, test := range []struct {
+		nackAction string
+		expected   map[string]string
+	}{
+		{
+			nackAction: "abandon",
+			expected:   map[string]string{"a": "complete", "b": "abandon"},
+		},
+		{
+			nackAction: "dead_letter",
+			expected:   map[string]string{"a": "complete", "b": "dead_letter"},
+		},
+	} {
+		test := test
+		t.Run(test.nackAction, func(t *testing.T) {
+			conf, err := sbiSpec().ParseYAML(`
+namespace: foo.servicebus.windows.net
+queue: foo
+nack_action: `+test.nackAction+`
+`, nil)
+			require.NoError(t, err)
+
+			r, err := newAzureServiceBusReaderFromParsed(conf, service.MockResources())
+			require.NoError(t, err)
+
+			recv := &mockSBReceiver{
+				pending: [][]*azservicebus.ReceivedMessage{
+					{{MessageID: "a", Body: []byte("first")}, {MessageID: "b", Body: []byte("second")}},
+				},
+				settled: map[string]string{},
+				reasons: map[string]string{},
+			}
+
+			var inFlight sync.WaitGroup
+			loopErr := make(chan error, 1)
+			go func() {
+				loopErr <- r.receiveLoop(context.Background(), recv, nil, 50*time.Millisecond, &inFlight)
+			}()
+
+			first := <-r.msgChan
+			second := <-r.msgChan
+			require.NoError(t, first.ackFn(context.Background(), nil))
+			require.NoError(t, second.ackFn(context.Background(), errors.New("nope")))
+
+			select {
+			case err := <-loopErr:
+				require.NoError(t, err)
+			case <-time.After(time.Second):
+				t.Fatal("timed out waiting for idle session")
+			}
+			inFlight.Wait()
+
+			assert.Equal(t, test.expected, recv.settled)
+			if test.nackAction == "dead_letter" {
+				assert.Equal(t, "nope", recv.reasons["b"])
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bolt
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/Jeffail/shutdown"
+	"go.etcd.io/bbolt"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	bcFieldPath          = "path"
+	bcFieldBucket        = "bucket"
+	bcFieldDefaultTTL    = "default_ttl"
+	bcFieldSweepInterval = "sweep_interval"
+	bcFieldOpenTimeout   = "open_timeout"
+)
+
+func boltCacheConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.31.0").
+		Summary(`Stores key/value pairs in an embedded https://github.com/etcd-io/bbolt[bbolt^] database file, which persists items across restarts without depending on an external service.`).
+		Description(`
+Items are written to a single bucket of the database and the add command is atomic, which makes this cache suitable for deduplication within a single instance. The database file is locked whilst open and therefore cannot be shared between multiple processes, or between multiple cache resources within the same process.
+
+Items with a TTL are stored along with their expiry time and are no longer returned once it has passed. Expired items are removed from the database periodically by a background sweep according to the field ` + "`sweep_interval`" + `.`).
+		Field(service.NewStringField(bcFieldPath).
+			Description("The path of the database file, which is created if it does not already exist.").
+			Example("./cache.db")).
+		Field(service.NewStringField(bcFieldBucket).
+			Description("The bucket within the database to store items in.").
+			Default("benthos")).
+		Field(service.NewDurationField(bcFieldDefaultTTL).
+			Description("An optional default TTL to set for items, calculated from the moment the item is cached.").
+			Optional().
+			Example("5m").
+			Example("24h")).
+		Field(service.NewDurationField(bcFieldSweepInterval).
+			Description("The period of time between sweeps of the database for expired items.").
+			Default("1m").
+			Advanced()).
+		Field(service.NewDurationField(bcFieldOpenTimeout).
+			Description("The maximum period of time to wait for the lock on the database file when opening it.").
+			Default("5s").
+			Advanced())
+}
+
+func init() {
+	err := service.RegisterCache(
+		"bolt", boltCacheConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Cache, error) {
+			return newBoltCacheFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func newBoltCacheFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*boltCache, error) {
+	path, err := conf.FieldString(bcFieldPath)
+	if err != nil {
+		return nil, err
+	}
+	bucket, err := conf.FieldString(bcFieldBucket)
+	if err != nil {
+		return nil, err
+	}
+	if bucket == "" {
+		return nil, errors.New("bucket must not be empty")
+	}
+
+	var defaultTTL time.Duration
+	if conf.Contains(bcFieldDefaultTTL) {
+		if defaultTTL, err = conf.FieldDuration(bcFieldDefaultTTL); err != nil {
+			return nil, err
+		}
+	}
+
+	sweepInterval, err := conf.FieldDuration(bcFieldSweepInterval)
+	if err != nil {
+		return nil, err
+	}
+	openTimeout, err := conf.FieldDuration(bcFieldOpenTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return newBoltCache(mgr.Logger(), path, bucket, defaultTTL, sweepInterval, openTimeout)
+}
+
+//------------------------------------------------------------------------------
+
+type boltCache struct {
+	db         *bbolt.DB
+	bucket     []byte
+	defaultTTL time.Duration
+	log        *service.Logger
+	now        func() time.Time
+
+	shutSig *shutdown.Signaller
+}
+
+func newBoltCache(log *service.Logger, path, bucket string, defaultTTL, sweepInterval, openTimeout time.Duration) (*boltCache, error) {
+	db, err := bbolt.Open(path, 0o600, &bbolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, err
+	}
+
+	b := &boltCache{
+		db:         db,
+		bucket:     []byte(bucket),
+		defaultTTL: defaultTTL,
+		log:        log,
+		now:        time.Now,
+		shutSig:    shutdown.NewSignaller(),
+	}
+
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(b.bucket)
+		return err
+	}); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	go b.sweepLoop(sweepInterval)
+	return b, nil
+}
+
+// Items are stored with an eight byte prefix containing the unix nano
+// timestamp at which they expire, or zero when they do not expire.
+const expiryLen = 8
+
+func (b *boltCache) encode(value []byte, ttl *time.Duration) []byte {
+	t := b.defaultTTL
+	if ttl != nil {
+		t = *ttl
+	}
+
+	var expiresAt int64
+	if t > 0 {
+		expiresAt = b.now().Add(t).UnixNano()
+	}
+
+	v := make([]byte, expiryLen+len(value))
+	binary.BigEndian.PutUint64(v, uint64(expiresAt))
+	copy(v[expiryLen:], value)
+	return v
+}
+
+func (b *boltCache) expired(v []byte, now int64) bool {
+	if len(v) < expiryLen {
+		return true
+	}
+	expiresAt := int64(binary.BigEndian.Uint64(v))
+	return expiresAt > 0 && expiresAt <= now
+}
+
+func (b *boltCache) Get(ctx context.Context, key string) (value []byte, err error) {
+	err = b.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(b.bucket).Get([]byte(key))
+		if v == nil || b.expired(v, b.now().UnixNano()) {
+			return service.ErrKeyNotFound
+		}
+		// Values are only valid for the lifetime of the transaction.
+		value = make([]byte, len(v)-expiryLen)
+		copy(value, v[expiryLen:])
+		return nil
+	})
+	return
+}
+
+func (b *boltCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(b.bucket).Put([]byte(key), b.encode(value, ttl))
+	})
+}
+
+func (b *boltCache) Add(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(b.bucket)
+		if v := bucket.Get([]byte(key)); v != nil && !b.expired(v, b.now().UnixNano()) {
+			return service.ErrKeyAlreadyExists
+		}
+		return bucket.Put([]byte(key), b.encode(value, ttl))
+	})
+}
+
+func (b *boltCache) Delete(ctx context.Context, key string) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(b.bucket).Delete([]byte(key))
+	})
+}
+
+// sweep removes all expired items from the bucket and returns the number of
+// items removed.
+func (b *boltCache) sweep() (removed int, err error) {
+	err = b.db.Update(func(tx *bbolt.Tx) error {
+		now := b.now().UnixNano()
+		bucket := tx.Bucket(b.bucket)
+
+		var expiredKeys [][]byte
+		if err := bucket.ForEach(func(k, v []byte) error {
+			if b.expired(v, now) {
+				expiredKeys = append(expiredKeys, append([]byte(nil), k...))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		for _, k := range expiredKeys {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		removed = len(expiredKeys)
+		return nil
+	})
+	return
+}
+
+func (b *boltCache) sweepLoop(interval time.Duration) {
+	defer func() {
+		_ = b.db.Close()
+		b.shutSig.TriggerHasStopped()
+	}()
+
+	if interval <= 0 {
+		<-b.shutSig.HardStopChan()
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			removed, err := b.sweep()
+			if err != nil {
+				b.log.Errorf("Failed to sweep expired items: %v", err)
+			} else if removed > 0 {
+				b.log.Debugf("Removed %v expired items", removed)
+			}
+		case <-b.shutSig.HardStopChan():
+			return
+		}
+	}
+}
+
+func (b *boltCache) Close(ctx context.Context) error {
+	b.shutSig.TriggerHardStop()
+	select {
+	case <-b.shutSig.HasStoppedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bolt
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestBoltCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	ctx := context.Background()
+
+	conf, err := boltCacheConfig().ParseYAML(`path: `+path, nil)
+	require.NoError(t, err)
+
+	c, err := newBoltCacheFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+
+	_, err = c.Get(ctx, "foo")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+
+	require.NoError(t, c.Set(ctx, "foo", []byte("1"), nil))
+
+	res, err := c.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), res)
+
+	assert.Equal(t, service.ErrKeyAlreadyExists, c.Add(ctx, "foo", []byte("2"), nil))
+	require.NoError(t, c.Add(ctx, "bar", []byte("2"), nil))
+
+	require.NoError(t, c.Delete(ctx, "foo"))
+	_, err = c.Get(ctx, "foo")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+
+	require.NoError(t, c.Close(ctx))
+
+	// Items survive the database being reopened.
+	pConf, err := boltCacheConfig().ParseYAML(`path: `+path, nil)
+	require.NoError(t, err)
+
+	c, err = newBoltCacheFromConfig(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, c.Close(ctx))
+	})
+
+	res, err = c.Get(ctx, "bar")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("2"), res)
+}
+
+func TestBoltCacheTTL(t *testing.T) {
+	ctx := context.Background()
+
+	conf, err := boltCacheConfig().ParseYAML(`
+path: `+filepath.Join(t.TempDir(), "cache.db")+`
+default_ttl: 1m
+sweep_interval: 0s
+`, nil)
+	require.NoError(t, err)
+
+	c, err := newBoltCacheFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, c.Close(ctx))
+	})
+
+	now := time.Unix(1000, 0)
+	c.now = func() time.Time { return now }
+
+	longTTL := time.Hour
+	require.NoError(t, c.Set(ctx, "foo", []byte("1"), nil))
+	require.NoError(t, c.Set(ctx, "bar", []byte("2"), &longTTL))
+	require.NoError(t, c.Set(ctx, "baz", []byte("3"), nil))
+
+	now = now.Add(time.Minute * 2)
+
+	_, err = c.Get(ctx, "foo")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+
+	res, err := c.Get(ctx, "bar")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("2"), res)
+
+	// Expired items can be added again.
+	require.NoError(t, c.Add(ctx, "foo", []byte("4"), nil))
+
+	removed, err := c.sweep()
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	res, err = c.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("4"), res)
+}
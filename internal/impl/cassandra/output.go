@@ -15,6 +15,7 @@
 package cassandra
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -33,10 +34,16 @@ const (
 	coFieldQuery       = "query"
 	coFieldArgsMapping = "args_mapping"
 	coFieldConsistency = "consistency"
+	coFieldSerialCons  = "serial_consistency"
 	coFieldLoggedBatch = "logged_batch"
+	coFieldBatchMode   = "batch_mode"
 	coFieldBatching    = "batching"
 )
 
+// The minimum period between warnings about batches that span multiple
+// partitions.
+const coMultiPartitionWarnInterval = time.Minute
+
 func outputSpec() *service.ConfigSpec {
 	return service.NewConfigSpec().
 		Beta().
@@ -44,10 +51,16 @@ func outputSpec() *service.ConfigSpec {
 		Description(`
 Query arguments can be set using a bloblang array for the fields using the `+"`args_mapping`"+` field.
 
-When populating timestamp columns the value must either be a string in ISO 8601 format (2006-01-02T15:04:05Z07:00), or an integer representing unix time in seconds.`+service.OutputPerformanceDocs(true, true)).
+When populating timestamp columns the value must either be a string in ISO 8601 format (2006-01-02T15:04:05Z07:00), or an integer representing unix time in seconds.
+
+== Batches
+
+When a batch of messages is written, the queries of the messages are executed according to `+"`batch_mode`"+`. Grouping queries into a Cassandra `+"`BATCH`"+` statement is efficient when the queries of a batch write to the same partition, but a batch that spans many partitions places extra load on the coordinator node, in which case a warning is logged and it's usually more efficient to set `+"`batch_mode`"+` to `+"`none`"+` so that the queries of a batch are executed individually and routed to their replicas in parallel.
+
+Queries are prepared once per connection, and prepared statements are cached by their query, up to the number of statements specified by `+"`max_prepared_statements`"+`.`+service.OutputPerformanceDocs(true, true)).
 		Example(
 			"Basic Inserts",
-			"If we were to create a table with some basic columns with `CREATE TABLE foo.bar (id int primary key, content text, created_at timestamp);`, and were processing JSON documents of the form `{\"id\":\"342354354\",\"content\":\"hello world\",\"timestamp\":1605219406}` using logged batches, we could populate our table with the following config:",
+			"If we were to create a table with some basic columns with `CREATE TABLE foo.bar (id int primary key, content text, created_at timestamp);`, and were processing JSON documents of the form `{\"id\":\"342354354\",\"content\":\"hello world\",\"timestamp\":1605219406}`, we could populate our table with the following config:",
 			`
 output:
   cassandra:
@@ -90,13 +103,28 @@ output:
 				Optional(),
 			service.NewStringEnumField(coFieldConsistency,
 				"ANY", "ONE", "TWO", "THREE", "QUORUM", "ALL", "LOCAL_QUORUM", "EACH_QUORUM", "LOCAL_ONE").
-				Description("The consistency level to use.").
+				Description("The consistency level to use for each query or batch of queries.").
 				Advanced().
 				Default("QUORUM"),
+			service.NewStringEnumField(coFieldSerialCons, "SERIAL", "LOCAL_SERIAL").
+				Description("An optional serial consistency level to use for each query or batch of queries, which applies to conditional writes such as `INSERT ... IF NOT EXISTS`.").
+				Version("4.31.0").
+				Advanced().
+				Optional(),
+			service.NewStringAnnotatedEnumField(coFieldBatchMode, map[string]string{
+				"unlogged": "Execute the queries of a batch as an unlogged `BATCH` statement, which is applied atomically when the queries write to the same partition.",
+				"logged":   "Execute the queries of a batch as a logged `BATCH` statement, which guarantees that either all or none of the queries are eventually applied at the cost of additional writes to the batch log.",
+				"none":     "Execute the queries of a batch individually in parallel, which is efficient when the queries write to many different partitions.",
+			}).
+				Description("How the queries of a batch of messages are executed.").
+				Version("4.31.0").
+				Advanced().
+				Default("unlogged"),
 			service.NewBoolField(coFieldLoggedBatch).
-				Description("If enabled the driver will perform a logged batch. Disabling this prompts unlogged batches to be used instead, which are less efficient but necessary for alternative storages that do not support logged batches.").
+				Description("Deprecated in favour of `batch_mode`. When set this field overrides `batch_mode`, where `true` is equivalent to `logged` and `false` to `unlogged`.").
 				Advanced().
-				Default(true),
+				Deprecated().
+				Optional(),
 			service.NewOutputMaxInFlightField(),
 			service.NewBatchPolicyField(coFieldBatching),
 		)
@@ -126,8 +154,12 @@ type cassandraWriter struct {
 	query       string
 	clientConf  clientConf
 	argsMapping *bloblang.Executor
-	batchType   gocql.BatchType
+	batchMode   string
 	consistency gocql.Consistency
+	serialCons  gocql.SerialConsistency
+
+	lastMultiPartWarn time.Time
+	warnMut           sync.Mutex
 
 	session  *gocql.Session
 	connLock sync.RWMutex
@@ -152,9 +184,23 @@ func newCassandraWriter(conf *service.ParsedConfig, mgr *service.Resources) (c *
 		}
 	}
 
-	c.batchType = gocql.UnloggedBatch
-	if loggedBatch, _ := conf.FieldBool(coFieldLoggedBatch); loggedBatch {
-		c.batchType = gocql.LoggedBatch
+	if c.batchMode, err = conf.FieldString(coFieldBatchMode); err != nil {
+		return
+	}
+	if conf.Contains(coFieldLoggedBatch) {
+		var loggedBatch bool
+		if loggedBatch, err = conf.FieldBool(coFieldLoggedBatch); err != nil {
+			return
+		}
+		c.batchMode = "unlogged"
+		if loggedBatch {
+			c.batchMode = "logged"
+		}
+	}
+	switch c.batchMode {
+	case "unlogged", "logged", "none":
+	default:
+		return nil, fmt.Errorf("batch mode not recognised: %v", c.batchMode)
 	}
 
 	var consistencyStr string
@@ -165,6 +211,16 @@ func newCassandraWriter(conf *service.ParsedConfig, mgr *service.Resources) (c *
 		return nil, fmt.Errorf("parsing consistency: %w", err)
 	}
 
+	if conf.Contains(coFieldSerialCons) {
+		var serialStr string
+		if serialStr, err = conf.FieldString(coFieldSerialCons); err != nil {
+			return
+		}
+		if err = c.serialCons.UnmarshalText([]byte(serialStr)); err != nil {
+			return nil, fmt.Errorf("parsing serial consistency: %w", err)
+		}
+	}
+
 	return
 }
 
@@ -202,31 +258,115 @@ func (c *cassandraWriter) WriteBatch(ctx context.Context, batch service.MessageB
 	if len(batch) == 1 {
 		return c.writeRow(session, batch)
 	}
+	if c.batchMode == "none" {
+		return c.writeRows(session, batch)
+	}
 	return c.writeBatch(session, batch)
 }
 
+func (c *cassandraWriter) newQuery(session *gocql.Session, values []any) *gocql.Query {
+	q := session.Query(c.query, values...).Consistency(c.consistency)
+	if c.serialCons > 0 {
+		q = q.SerialConsistency(c.serialCons)
+	}
+	return q
+}
+
 func (c *cassandraWriter) writeRow(session *gocql.Session, b service.MessageBatch) error {
 	values, err := c.mapArgs(b, 0)
 	if err != nil {
 		return fmt.Errorf("parsing args: %w", err)
 	}
-	return session.Query(c.query, values...).Exec()
+	return c.newQuery(session, values).Exec()
+}
+
+func (c *cassandraWriter) writeRows(session *gocql.Session, b service.MessageBatch) error {
+	var batchErr *service.BatchError
+	var errMut sync.Mutex
+	setErr := func(i int, err error) {
+		errMut.Lock()
+		if batchErr == nil {
+			batchErr = service.NewBatchError(b, err)
+		}
+		batchErr.Failed(i, err)
+		errMut.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	for i := range b {
+		values, err := c.mapArgs(b, i)
+		if err != nil {
+			setErr(i, fmt.Errorf("parsing args: %w", err))
+			continue
+		}
+		wg.Add(1)
+		go func(i int, q *gocql.Query) {
+			defer wg.Done()
+			if err := q.Exec(); err != nil {
+				setErr(i, err)
+			}
+		}(i, c.newQuery(session, values))
+	}
+	wg.Wait()
+
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
 }
 
 func (c *cassandraWriter) writeBatch(session *gocql.Session, b service.MessageBatch) error {
-	batch := session.NewBatch(c.batchType)
+	batchType := gocql.UnloggedBatch
+	if c.batchMode == "logged" {
+		batchType = gocql.LoggedBatch
+	}
+	batch := session.NewBatch(batchType)
+	batch.SetConsistency(c.consistency)
+	if c.serialCons > 0 {
+		batch.SerialConsistency(c.serialCons)
+	}
 
+	var firstKey []byte
+	multiPartition := false
 	for i := range b {
 		values, err := c.mapArgs(b, i)
 		if err != nil {
 			return fmt.Errorf("parsing args for part: %d: %w", i, err)
 		}
 		batch.Query(c.query, values...)
+
+		if !multiPartition {
+			// The routing key information of a query is cached by the driver
+			// and therefore only obtained once per query.
+			q := session.Query(c.query, values...)
+			if key, err := q.GetRoutingKey(); err == nil && key != nil {
+				if i == 0 {
+					firstKey = key
+				} else if !bytes.Equal(firstKey, key) {
+					multiPartition = true
+				}
+			}
+			q.Release()
+		}
+	}
+	if multiPartition {
+		c.warnMultiPartition()
 	}
 
 	return session.ExecuteBatch(batch)
 }
 
+func (c *cassandraWriter) warnMultiPartition() {
+	c.warnMut.Lock()
+	defer c.warnMut.Unlock()
+
+	if time.Since(c.lastMultiPartWarn) < coMultiPartitionWarnInterval {
+		return
+	}
+	c.lastMultiPartWarn = time.Now()
+	c.log.Warnf("Writing a %v batch that spans multiple partitions, which places extra load on the coordinator node, consider setting batch_mode to none", c.batchMode)
+}
+
 func (c *cassandraWriter) mapArgs(b service.MessageBatch, index int) ([]any, error) {
 	if c.argsMapping != nil {
 		// We've got an "args_mapping" field, extract values from there.
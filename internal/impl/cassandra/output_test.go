@@ -0,0 +1,85 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cassandra
+
+import (
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestCassandraOutputBatchMode(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		conf string
+		mode string
+	}{
+		{name: "default", conf: ``, mode: "unlogged"},
+		{name: "explicit mode", conf: `batch_mode: none`, mode: "none"},
+		{name: "deprecated logged", conf: `logged_batch: true`, mode: "logged"},
+		{name: "deprecated unlogged", conf: "logged_batch: false\nbatch_mode: logged", mode: "unlogged"},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			pConf, err := outputSpec().ParseYAML(`
+addresses: [ localhost:9042 ]
+query: 'INSERT INTO foo.bar (id) VALUES (?)'
+serial_consistency: LOCAL_SERIAL
+`+test.conf, nil)
+			require.NoError(t, err)
+
+			w, err := newCassandraWriter(pConf, service.MockResources())
+			require.NoError(t, err)
+			assert.Equal(t, test.mode, w.batchMode)
+			assert.Equal(t, gocql.LocalSerial, w.serialCons)
+		})
+	}
+}
+
+func TestCassandraClientHostSelection(t *testing.T) {
+	pConf, err := outputSpec().ParseYAML(`
+addresses: [ localhost:9042 ]
+query: 'INSERT INTO foo.bar (id) VALUES (?)'
+token_aware_routing: true
+local_datacenter: dc1
+max_prepared_statements: 50
+`, nil)
+	require.NoError(t, err)
+
+	cConf, err := clientConfFromParsed(pConf)
+	require.NoError(t, err)
+
+	cluster, err := cConf.Create()
+	require.NoError(t, err)
+	assert.NotNil(t, cluster.PoolConfig.HostSelectionPolicy)
+	assert.Equal(t, 50, cluster.MaxPreparedStmts)
+
+	pConf, err = outputSpec().ParseYAML(`
+addresses: [ localhost:9042 ]
+query: 'INSERT INTO foo.bar (id) VALUES (?)'
+`, nil)
+	require.NoError(t, err)
+
+	cConf, err = clientConfFromParsed(pConf)
+	require.NoError(t, err)
+
+	cluster, err = cConf.Create()
+	require.NoError(t, err)
+	assert.Nil(t, cluster.PoolConfig.HostSelectionPolicy)
+}
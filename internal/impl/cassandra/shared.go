@@ -16,6 +16,7 @@ package cassandra
 
 import (
 	"crypto/tls"
+	"fmt"
 	"strings"
 	"time"
 
@@ -37,6 +38,10 @@ const (
 	cFieldBackoffInitInterval = "initial_interval"
 	cFieldBackoffMaxInterval  = "max_interval"
 	cFieldTimeout             = "timeout"
+	cFieldTokenAware          = "token_aware_routing"
+	cFieldShuffleReplicas     = "shuffle_replicas"
+	cFieldLocalDC             = "local_datacenter"
+	cFieldMaxPreparedStmts    = "max_prepared_statements"
 )
 
 func clientFields() []*service.ConfigField {
@@ -84,6 +89,26 @@ func clientFields() []*service.ConfigField {
 		service.NewDurationField(cFieldTimeout).
 			Description("The client connection timeout.").
 			Default("600ms"),
+		service.NewBoolField(cFieldTokenAware).
+			Description("Whether to route each query directly to a replica of the partition that it targets, which avoids an additional hop through a coordinator node. Requires host information from the system.peers table, and therefore has no effect when `disable_initial_host_lookup` is enabled.").
+			Version("4.31.0").
+			Advanced().
+			Default(false),
+		service.NewBoolField(cFieldShuffleReplicas).
+			Description("Whether to pick a replica at random for each query when `token_aware_routing` is enabled, rather than always the first replica of a partition, which spreads the load of hot partitions across their replicas.").
+			Version("4.31.0").
+			Advanced().
+			Default(false),
+		service.NewStringField(cFieldLocalDC).
+			Description("An optional datacenter to prefer when selecting nodes, in which case nodes of other datacenters are only used when no nodes of the local datacenter are available.").
+			Version("4.31.0").
+			Advanced().
+			Default(""),
+		service.NewIntField(cFieldMaxPreparedStmts).
+			Description("The maximum number of prepared statements to cache, where statements are cached by their query.").
+			Version("4.31.0").
+			Advanced().
+			Default(1000),
 	}
 }
 
@@ -99,6 +124,10 @@ type clientConf struct {
 	backoffInitInterval time.Duration
 	backoffMaxInterval  time.Duration
 	timeout             time.Duration
+	tokenAware          bool
+	shuffleReplicas     bool
+	localDC             string
+	maxPreparedStmts    int
 }
 
 func (c *clientConf) Create() (*gocql.ClusterConfig, error) {
@@ -125,6 +154,25 @@ func (c *clientConf) Create() (*gocql.ClusterConfig, error) {
 		Max:        c.backoffMaxInterval,
 	}
 
+	var hostPolicy gocql.HostSelectionPolicy
+	if c.localDC != "" {
+		hostPolicy = gocql.DCAwareRoundRobinPolicy(c.localDC)
+	}
+	if c.tokenAware {
+		if hostPolicy == nil {
+			hostPolicy = gocql.RoundRobinHostPolicy()
+		}
+		if c.shuffleReplicas {
+			hostPolicy = gocql.TokenAwareHostPolicy(hostPolicy, gocql.ShuffleReplicas())
+		} else {
+			hostPolicy = gocql.TokenAwareHostPolicy(hostPolicy)
+		}
+	}
+	if hostPolicy != nil {
+		conn.PoolConfig.HostSelectionPolicy = hostPolicy
+	}
+
+	conn.MaxPreparedStmts = c.maxPreparedStmts
 	conn.Timeout = c.timeout
 	return conn, nil
 }
@@ -164,5 +212,21 @@ func clientConfFromParsed(conf *service.ParsedConfig) (c clientConf, err error)
 	if c.timeout, err = conf.FieldDuration(cFieldTimeout); err != nil {
 		return
 	}
+	if c.tokenAware, err = conf.FieldBool(cFieldTokenAware); err != nil {
+		return
+	}
+	if c.shuffleReplicas, err = conf.FieldBool(cFieldShuffleReplicas); err != nil {
+		return
+	}
+	if c.localDC, err = conf.FieldString(cFieldLocalDC); err != nil {
+		return
+	}
+	if c.maxPreparedStmts, err = conf.FieldInt(cFieldMaxPreparedStmts); err != nil {
+		return
+	}
+	if c.maxPreparedStmts <= 0 {
+		err = fmt.Errorf("field %v must be greater than zero", cFieldMaxPreparedStmts)
+		return
+	}
 	return
 }
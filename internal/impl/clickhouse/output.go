@@ -0,0 +1,485 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	chFieldAddresses   = "addresses"
+	chFieldDatabase    = "database"
+	chFieldUsername    = "username"
+	chFieldPassword    = "password"
+	chFieldTable       = "table"
+	chFieldColumns     = "columns"
+	chFieldArgsMapping = "args_mapping"
+	chFieldAsyncInsert = "async_insert"
+	chFieldCompression = "compression"
+	chFieldDialTimeout = "dial_timeout"
+	chFieldTLS         = "tls"
+	chFieldMaxRetries  = "max_retries"
+	chFieldBackoff     = "backoff"
+	chFieldBatching    = "batching"
+)
+
+func clickhouseOutputConfig() *service.ConfigSpec {
+	backoffDefaults := backoff.NewExponentialBackOff()
+	backoffDefaults.InitialInterval = time.Second
+	backoffDefaults.MaxInterval = time.Second * 30
+	backoffDefaults.MaxElapsedTime = 0
+
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.31.0").
+		Summary("Inserts rows into a ClickHouse table using the native protocol.").
+		Description(`
+Each batch of messages is written as a single block using the native ClickHouse protocol, which is significantly more efficient than inserting rows with the `+"`sql_insert`"+` output. In order to get the most out of this output it is recommended to configure a xref:configuration:batching.adoc[batching policy] that results in large batches, ideally thousands of rows.
+
+When `+"`args_mapping`"+` is omitted the value of each column is taken from the field of the same name within the JSON document of each message, where missing fields are inserted as `+"`null`"+`, or the zero value of the column type for columns that are not nullable.
+
+== Type coercion
+
+The types of the target columns are read from the table when connecting, and values are converted into those types before they are inserted. For example, numbers within strings are parsed for numeric columns, objects and arrays are serialized as JSON for `+"`String`"+` columns, and `+"`Array`"+`, `+"`Map`"+`, `+"`Nullable`"+` and `+"`LowCardinality`"+` columns are converted recursively.
+
+Values for `+"`Date`"+`, `+"`DateTime`"+` and `+"`DateTime64`"+` columns can be either timestamps, strings in RFC 3339 format (or `+"`2006-01-02 15:04:05`"+` style), or numbers representing seconds since the unix epoch, which may contain a fractional component for `+"`DateTime64`"+` columns.
+
+== Retries
+
+Inserts that fail due to transient errors, such as network failures or a server that is temporarily overloaded, are retried according to `+"`max_retries`"+` and `+"`backoff`"+`. Any other error, such as a value that cannot be converted into the type of its column, results in the batch being rejected without retries.
+
+== Async inserts
+
+When `+"`async_insert`"+` is enabled rows are inserted using https://clickhouse.com/docs/en/optimize/asynchronous-inserts[asynchronous inserts^], where the server buffers rows from many inserts before flushing them to storage. This output always waits for the server to acknowledge that a buffer has been flushed before acknowledging a batch, and therefore delivery guarantees are preserved.`).
+		Field(service.NewStringListField(chFieldAddresses).
+			Description("A list of addresses of ClickHouse servers to connect to using the native protocol. If an item of the list contains commas it will be expanded into multiple addresses.").
+			Example([]string{"localhost:9000"}).
+			Example([]string{"clickhouse-01:9440", "clickhouse-02:9440"})).
+		Field(service.NewStringField(chFieldDatabase).
+			Description("The database containing the table to insert to.").
+			Default("default")).
+		Field(service.NewStringField(chFieldUsername).
+			Description("The username to authenticate with.").
+			Default("default")).
+		Field(service.NewStringField(chFieldPassword).
+			Description("The password to authenticate with.").
+			Default("").
+			Secret()).
+		Field(service.NewStringField(chFieldTable).
+			Description("The table to insert to.").
+			Example("events")).
+		Field(service.NewStringListField(chFieldColumns).
+			Description("A list of columns to insert.").
+			Example([]string{"id", "timestamp", "tags"})).
+		Field(service.NewBloblangField(chFieldArgsMapping).
+			Description("An optional xref:guides:bloblang/about.adoc[Bloblang mapping] which should evaluate to an array of values matching in size to the number of columns specified. When omitted the values are taken from the fields of each document that match the column names.").
+			Example("root = [ this.user.id, this.ts, this.tags ]").
+			Optional()).
+		Field(service.NewBoolField(chFieldAsyncInsert).
+			Description("Whether to insert rows using asynchronous inserts.").
+			Default(false).
+			Advanced()).
+		Field(service.NewStringEnumField(chFieldCompression, "none", "lz4", "zstd").
+			Description("The compression algorithm to use for blocks sent to the server.").
+			Default("lz4").
+			Advanced()).
+		Field(service.NewDurationField(chFieldDialTimeout).
+			Description("The maximum period to wait for a connection to be established.").
+			Default("10s").
+			Advanced()).
+		Field(service.NewTLSToggledField(chFieldTLS)).
+		Field(service.NewIntField(chFieldMaxRetries).
+			Description("The maximum number of retries of a batch that fails due to a transient error. Set to zero to disable retries.").
+			Default(3).
+			Advanced()).
+		Field(service.NewBackOffField(chFieldBackoff, false, backoffDefaults).
+			Description("Control time intervals between retry attempts.").
+			Advanced()).
+		Field(service.NewOutputMaxInFlightField()).
+		Field(service.NewBatchPolicyField(chFieldBatching)).
+		Example("Events", "Insert events into a table, converting the `timestamp` field into a `DateTime64` column and extracting a column from metadata.", `
+output:
+  clickhouse:
+    addresses: [ localhost:9000 ]
+    table: events
+    columns: [ id, timestamp, topic, tags ]
+    args_mapping: |
+      root = [
+        this.id,
+        this.timestamp,
+        meta("kafka_topic"),
+        this.tags,
+      ]
+    batching:
+      count: 10000
+      period: 5s
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"clickhouse", clickhouseOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if batchPolicy, err = conf.FieldBatchPolicy(chFieldBatching); err != nil {
+				return
+			}
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newClickhouseOutputFromConfig(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type clickhouseOutput struct {
+	opts        *clickhouse.Options
+	asyncInsert bool
+	database    string
+	table       string
+	columns     []string
+	argsMapping *bloblang.Executor
+	maxRetries  int
+	backoff     *backoff.ExponentialBackOff
+	insertQuery string
+
+	log *service.Logger
+
+	connMut  sync.RWMutex
+	conn     driver.Conn
+	coercers []coerceFn
+}
+
+func newClickhouseOutputFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*clickhouseOutput, error) {
+	c := &clickhouseOutput{
+		log:  mgr.Logger(),
+		opts: &clickhouse.Options{},
+	}
+
+	addresses, err := conf.FieldStringList(chFieldAddresses)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range addresses {
+		for _, s := range strings.Split(a, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				c.opts.Addr = append(c.opts.Addr, s)
+			}
+		}
+	}
+	if len(c.opts.Addr) == 0 {
+		return nil, errors.New("at least one address must be specified")
+	}
+
+	if c.database, err = conf.FieldString(chFieldDatabase); err != nil {
+		return nil, err
+	}
+	c.opts.Auth.Database = c.database
+	if c.opts.Auth.Username, err = conf.FieldString(chFieldUsername); err != nil {
+		return nil, err
+	}
+	if c.opts.Auth.Password, err = conf.FieldString(chFieldPassword); err != nil {
+		return nil, err
+	}
+
+	if c.table, err = conf.FieldString(chFieldTable); err != nil {
+		return nil, err
+	}
+	if c.columns, err = conf.FieldStringList(chFieldColumns); err != nil {
+		return nil, err
+	}
+	if len(c.columns) == 0 {
+		return nil, errors.New("at least one column must be specified")
+	}
+	if conf.Contains(chFieldArgsMapping) {
+		if c.argsMapping, err = conf.FieldBloblang(chFieldArgsMapping); err != nil {
+			return nil, err
+		}
+	}
+
+	quotedCols := make([]string, len(c.columns))
+	for i, col := range c.columns {
+		quotedCols[i] = quoteIdentifier(col)
+	}
+	c.insertQuery = fmt.Sprintf("INSERT INTO %v.%v (%v)",
+		quoteIdentifier(c.database), quoteIdentifier(c.table), strings.Join(quotedCols, ", "))
+
+	if c.asyncInsert, err = conf.FieldBool(chFieldAsyncInsert); err != nil {
+		return nil, err
+	}
+
+	compression, err := conf.FieldString(chFieldCompression)
+	if err != nil {
+		return nil, err
+	}
+	switch compression {
+	case "lz4":
+		c.opts.Compression = &clickhouse.Compression{Method: clickhouse.CompressionLZ4}
+	case "zstd":
+		c.opts.Compression = &clickhouse.Compression{Method: clickhouse.CompressionZSTD}
+	}
+
+	if c.opts.DialTimeout, err = conf.FieldDuration(chFieldDialTimeout); err != nil {
+		return nil, err
+	}
+
+	var tlsConf *tls.Config
+	var tlsEnabled bool
+	if tlsConf, tlsEnabled, err = conf.FieldTLSToggled(chFieldTLS); err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		c.opts.TLS = tlsConf
+	}
+
+	if c.maxRetries, err = conf.FieldInt(chFieldMaxRetries); err != nil {
+		return nil, err
+	}
+	if c.backoff, err = conf.FieldBackOff(chFieldBackoff); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func quoteIdentifier(s string) string {
+	return "`" + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), "`", "\\`") + "`"
+}
+
+func (c *clickhouseOutput) Connect(ctx context.Context) error {
+	c.connMut.Lock()
+	defer c.connMut.Unlock()
+
+	if c.conn != nil {
+		return nil
+	}
+
+	conn, err := clickhouse.Open(c.opts)
+	if err != nil {
+		return err
+	}
+	if err := conn.Ping(ctx); err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	coercers, err := c.readColumnTypes(ctx, conn)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	c.conn = conn
+	c.coercers = coercers
+	return nil
+}
+
+func (c *clickhouseOutput) readColumnTypes(ctx context.Context, conn driver.Conn) ([]coerceFn, error) {
+	rows, err := conn.Query(ctx, "SELECT name, type FROM system.columns WHERE database = ? AND table = ?", c.database, c.table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of table %v: %w", c.table, err)
+	}
+	defer rows.Close()
+
+	types := map[string]string{}
+	for rows.Next() {
+		var name, colType string
+		if err := rows.Scan(&name, &colType); err != nil {
+			return nil, err
+		}
+		types[name] = colType
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(types) == 0 {
+		return nil, fmt.Errorf("table %v.%v does not exist", c.database, c.table)
+	}
+
+	coercers := make([]coerceFn, len(c.columns))
+	for i, col := range c.columns {
+		colType, exists := types[col]
+		if !exists {
+			return nil, fmt.Errorf("column %v does not exist in table %v.%v", col, c.database, c.table)
+		}
+		if coercers[i], err = newCoerceFn(colType); err != nil {
+			return nil, fmt.Errorf("column %v: %w", col, err)
+		}
+	}
+	return coercers, nil
+}
+
+func (c *clickhouseOutput) rowValues(batch service.MessageBatch, i int) ([]any, error) {
+	if c.argsMapping == nil {
+		v, err := batch[i].AsStructured()
+		if err != nil {
+			return nil, err
+		}
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("expected object document, got %T", v)
+		}
+		args := make([]any, len(c.columns))
+		for j, col := range c.columns {
+			args[j] = obj[col]
+		}
+		return args, nil
+	}
+
+	resMsg, err := batch.BloblangQuery(i, c.argsMapping)
+	if err != nil {
+		return nil, err
+	}
+	iargs, err := resMsg.AsStructured()
+	if err != nil {
+		return nil, err
+	}
+	args, ok := iargs.([]any)
+	if !ok {
+		return nil, fmt.Errorf("mapping returned non-array result: %T", iargs)
+	}
+	if len(args) != len(c.columns) {
+		return nil, fmt.Errorf("mapping returned %v values, expected %v", len(args), len(c.columns))
+	}
+	return args, nil
+}
+
+func (c *clickhouseOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	c.connMut.RLock()
+	conn, coercers := c.conn, c.coercers
+	c.connMut.RUnlock()
+
+	if conn == nil {
+		return service.ErrNotConnected
+	}
+
+	rows := make([][]any, len(batch))
+	for i := range batch {
+		args, err := c.rowValues(batch, i)
+		if err != nil {
+			return err
+		}
+		for j, arg := range args {
+			if args[j], err = coercers[j](arg); err != nil {
+				return fmt.Errorf("column %v: %w", c.columns[j], err)
+			}
+		}
+		rows[i] = args
+	}
+
+	if c.asyncInsert {
+		ctx = clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+			"async_insert":          1,
+			"wait_for_async_insert": 1,
+		}))
+	}
+
+	boff := *c.backoff
+	boff.Reset()
+	for retries := 0; ; retries++ {
+		err := c.insert(ctx, conn, rows)
+		if err == nil {
+			return nil
+		}
+		if retries >= c.maxRetries || !isTransientErr(err) {
+			return err
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return err
+		}
+		c.log.Warnf("Retrying insert after transient error: %v", err)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (c *clickhouseOutput) insert(ctx context.Context, conn driver.Conn, rows [][]any) error {
+	b, err := conn.PrepareBatch(ctx, c.insertQuery)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := b.Append(row...); err != nil {
+			_ = b.Abort()
+			return err
+		}
+	}
+	return b.Send()
+}
+
+// Error codes of server exceptions that are worth retrying.
+var transientExceptionCodes = map[int32]struct{}{
+	159: {}, // TIMEOUT_EXCEEDED
+	202: {}, // TOO_MANY_SIMULTANEOUS_QUERIES
+	203: {}, // NO_FREE_CONNECTION
+	209: {}, // SOCKET_TIMEOUT
+	210: {}, // NETWORK_ERROR
+	242: {}, // TABLE_IS_READ_ONLY
+	252: {}, // TOO_MANY_PARTS
+	319: {}, // UNKNOWN_STATUS_OF_INSERT
+	425: {}, // SYSTEM_ERROR
+}
+
+func isTransientErr(err error) bool {
+	var exc *clickhouse.Exception
+	if errors.As(err, &exc) {
+		_, exists := transientExceptionCodes[exc.Code]
+		return exists
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+func (c *clickhouseOutput) Close(ctx context.Context) error {
+	c.connMut.Lock()
+	defer c.connMut.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
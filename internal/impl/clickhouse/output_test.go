@@ -0,0 +1,88 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestClickhouseOutputConfig(t *testing.T) {
+	pConf, err := clickhouseOutputConfig().ParseYAML(`
+addresses: [ "foo:9000,bar:9000" ]
+database: analytics
+table: events
+columns: [ id, ts ]
+compression: zstd
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newClickhouseOutputFromConfig(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"foo:9000", "bar:9000"}, out.opts.Addr)
+	assert.Equal(t, "analytics", out.opts.Auth.Database)
+	assert.Equal(t, clickhouse.CompressionZSTD, out.opts.Compression.Method)
+	assert.Equal(t, "INSERT INTO `analytics`.`events` (`id`, `ts`)", out.insertQuery)
+	assert.Equal(t, 3, out.maxRetries)
+}
+
+func TestClickhouseOutputRowValues(t *testing.T) {
+	pConf, err := clickhouseOutputConfig().ParseYAML(`
+addresses: [ localhost:9000 ]
+table: events
+columns: [ id, ts ]
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newClickhouseOutputFromConfig(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	batch := service.MessageBatch{service.NewMessage([]byte(`{"id":"foo","other":"bar"}`))}
+	args, err := out.rowValues(batch, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []any{"foo", nil}, args)
+
+	pConf, err = clickhouseOutputConfig().ParseYAML(`
+addresses: [ localhost:9000 ]
+table: events
+columns: [ id, ts ]
+args_mapping: 'root = [ this.id, meta("ts") ]'
+`, nil)
+	require.NoError(t, err)
+
+	out, err = newClickhouseOutputFromConfig(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	msg := service.NewMessage([]byte(`{"id":"foo"}`))
+	msg.MetaSetMut("ts", "2024-06-01")
+	args, err = out.rowValues(service.MessageBatch{msg}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []any{"foo", "2024-06-01"}, args)
+}
+
+func TestClickhouseTransientErrors(t *testing.T) {
+	assert.True(t, isTransientErr(io.EOF))
+	assert.True(t, isTransientErr(&clickhouse.Exception{Code: 252}))
+	assert.False(t, isTransientErr(&clickhouse.Exception{Code: 53}))
+	assert.False(t, isTransientErr(errors.New("nope")))
+}
@@ -0,0 +1,469 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/column"
+	"github.com/shopspring/decimal"
+)
+
+// coerceFn converts a value obtained from a structured message into a type
+// that the native client accepts for a given column type.
+type coerceFn func(v any) (any, error)
+
+// splitType separates a column type such as `Map(String, Array(Int64))` into
+// its name and top level arguments.
+func splitType(t string) (name string, args []string) {
+	t = strings.TrimSpace(t)
+	open := strings.IndexByte(t, '(')
+	if open == -1 || !strings.HasSuffix(t, ")") {
+		return t, nil
+	}
+
+	name = t[:open]
+	inner := t[open+1 : len(t)-1]
+
+	depth, start := 0, 0
+	var quoted bool
+	for i := 0; i < len(inner); i++ {
+		switch c := inner[i]; {
+		case c == '\'':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			args = append(args, strings.TrimSpace(inner[start:i]))
+			start = i + 1
+		}
+	}
+	args = append(args, strings.TrimSpace(inner[start:]))
+	return
+}
+
+func newCoerceFn(chType string) (coerceFn, error) {
+	name, args := splitType(chType)
+	switch name {
+	case "LowCardinality", "SimpleAggregateFunction":
+		if len(args) == 0 {
+			return nil, fmt.Errorf("unexpected type: %v", chType)
+		}
+		return newCoerceFn(args[len(args)-1])
+	case "Nullable":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("unexpected type: %v", chType)
+		}
+		inner, err := newCoerceFn(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return func(v any) (any, error) {
+			if v == nil {
+				return nil, nil
+			}
+			return inner(v)
+		}, nil
+	case "Array":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("unexpected type: %v", chType)
+		}
+		inner, err := newCoerceFn(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return func(v any) (any, error) {
+			if v == nil {
+				return []any{}, nil
+			}
+			arr, ok := v.([]any)
+			if !ok {
+				return nil, fmt.Errorf("expected array value, got %T", v)
+			}
+			res := make([]any, len(arr))
+			for i, e := range arr {
+				var err error
+				if res[i], err = inner(e); err != nil {
+					return nil, fmt.Errorf("index %v: %w", i, err)
+				}
+			}
+			return res, nil
+		}, nil
+	case "Map":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("unexpected type: %v", chType)
+		}
+		keyFn, err := newCoerceFn(args[0])
+		if err != nil {
+			return nil, err
+		}
+		valueFn, err := newCoerceFn(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return func(v any) (any, error) {
+			res := &orderedMap{}
+			if v == nil {
+				return res, nil
+			}
+			obj, ok := v.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("expected object value, got %T", v)
+			}
+			for k, e := range obj {
+				ck, err := keyFn(k)
+				if err != nil {
+					return nil, fmt.Errorf("key %v: %w", k, err)
+				}
+				ce, err := valueFn(e)
+				if err != nil {
+					return nil, fmt.Errorf("key %v: %w", k, err)
+				}
+				res.Put(ck, ce)
+			}
+			return res, nil
+		}, nil
+	}
+
+	// Scalar columns accept nil values as the zero value of the column type,
+	// which is what we want for fields that are missing from a document.
+	fn := scalarCoerceFn(name)
+	return func(v any) (any, error) {
+		if v == nil {
+			return nil, nil
+		}
+		return fn(v)
+	}, nil
+}
+
+func scalarCoerceFn(name string) coerceFn {
+	switch name {
+	case "Int8":
+		return intCoerceFn(8, func(i int64) any { return int8(i) })
+	case "Int16":
+		return intCoerceFn(16, func(i int64) any { return int16(i) })
+	case "Int32":
+		return intCoerceFn(32, func(i int64) any { return int32(i) })
+	case "Int64":
+		return intCoerceFn(64, func(i int64) any { return i })
+	case "UInt8":
+		return uintCoerceFn(8, func(i uint64) any { return uint8(i) })
+	case "UInt16":
+		return uintCoerceFn(16, func(i uint64) any { return uint16(i) })
+	case "UInt32":
+		return uintCoerceFn(32, func(i uint64) any { return uint32(i) })
+	case "UInt64":
+		return uintCoerceFn(64, func(i uint64) any { return i })
+	case "Int128", "Int256", "UInt128", "UInt256":
+		return toBigInt
+	case "Float32":
+		return func(v any) (any, error) {
+			f, err := toFloat64(v)
+			return float32(f), err
+		}
+	case "Float64":
+		return func(v any) (any, error) {
+			return toFloat64(v)
+		}
+	case "Decimal", "Decimal32", "Decimal64", "Decimal128", "Decimal256":
+		return toDecimal
+	case "Bool", "Boolean":
+		return toBool
+	case "String", "FixedString", "Enum8", "Enum16", "UUID", "IPv4", "IPv6":
+		return toString
+	case "Date", "Date32", "DateTime", "DateTime64":
+		return func(v any) (any, error) {
+			return toTime(v)
+		}
+	}
+	// Any other type is given to the client as is, which may still be able to
+	// convert it.
+	return func(v any) (any, error) {
+		return v, nil
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func toInt64(v any) (int64, error) {
+	switch t := v.(type) {
+	case int:
+		return int64(t), nil
+	case int8:
+		return int64(t), nil
+	case int16:
+		return int64(t), nil
+	case int32:
+		return int64(t), nil
+	case int64:
+		return t, nil
+	case uint8:
+		return int64(t), nil
+	case uint16:
+		return int64(t), nil
+	case uint32:
+		return int64(t), nil
+	case uint64:
+		if t > math.MaxInt64 {
+			return 0, fmt.Errorf("value %v overflows int64", t)
+		}
+		return int64(t), nil
+	case float32:
+		return toInt64(float64(t))
+	case float64:
+		if t != math.Trunc(t) || t > math.MaxInt64 || t < math.MinInt64 {
+			return 0, fmt.Errorf("value %v is not a valid integer", t)
+		}
+		return int64(t), nil
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i, nil
+		}
+		f, err := t.Float64()
+		if err != nil {
+			return 0, err
+		}
+		return toInt64(f)
+	case string:
+		return strconv.ParseInt(strings.TrimSpace(t), 10, 64)
+	case bool:
+		if t {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, fmt.Errorf("expected integer value, got %T", v)
+}
+
+func toUint64(v any) (uint64, error) {
+	switch t := v.(type) {
+	case uint64:
+		return t, nil
+	case json.Number:
+		if u, err := strconv.ParseUint(t.String(), 10, 64); err == nil {
+			return u, nil
+		}
+	case string:
+		return strconv.ParseUint(strings.TrimSpace(t), 10, 64)
+	}
+	i, err := toInt64(v)
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 {
+		return 0, fmt.Errorf("value %v is negative", i)
+	}
+	return uint64(i), nil
+}
+
+func intCoerceFn(bits int, conv func(int64) any) coerceFn {
+	maxV := int64(math.MaxInt64) >> (64 - bits)
+	minV := -maxV - 1
+	return func(v any) (any, error) {
+		i, err := toInt64(v)
+		if err != nil {
+			return nil, err
+		}
+		if i > maxV || i < minV {
+			return nil, fmt.Errorf("value %v overflows Int%v", i, bits)
+		}
+		return conv(i), nil
+	}
+}
+
+func uintCoerceFn(bits int, conv func(uint64) any) coerceFn {
+	maxV := uint64(math.MaxUint64) >> (64 - bits)
+	return func(v any) (any, error) {
+		u, err := toUint64(v)
+		if err != nil {
+			return nil, err
+		}
+		if u > maxV {
+			return nil, fmt.Errorf("value %v overflows UInt%v", u, bits)
+		}
+		return conv(u), nil
+	}
+}
+
+func toBigInt(v any) (any, error) {
+	var str string
+	switch t := v.(type) {
+	case *big.Int:
+		return t, nil
+	case json.Number:
+		str = t.String()
+	case string:
+		str = strings.TrimSpace(t)
+	default:
+		i, err := toInt64(v)
+		if err != nil {
+			return nil, err
+		}
+		return big.NewInt(i), nil
+	}
+	b, ok := new(big.Int).SetString(str, 10)
+	if !ok {
+		return nil, fmt.Errorf("value %v is not a valid integer", str)
+	}
+	return b, nil
+}
+
+func toFloat64(v any) (float64, error) {
+	switch t := v.(type) {
+	case float64:
+		return t, nil
+	case float32:
+		return float64(t), nil
+	case json.Number:
+		return t.Float64()
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(t), 64)
+	}
+	i, err := toInt64(v)
+	if err != nil {
+		return 0, fmt.Errorf("expected number value, got %T", v)
+	}
+	return float64(i), nil
+}
+
+func toDecimal(v any) (any, error) {
+	switch t := v.(type) {
+	case decimal.Decimal:
+		return t, nil
+	case json.Number:
+		return decimal.NewFromString(t.String())
+	case string:
+		return decimal.NewFromString(strings.TrimSpace(t))
+	case float64:
+		return decimal.NewFromFloat(t), nil
+	case float32:
+		return decimal.NewFromFloat32(t), nil
+	}
+	i, err := toInt64(v)
+	if err != nil {
+		return nil, fmt.Errorf("expected decimal value, got %T", v)
+	}
+	return decimal.NewFromInt(i), nil
+}
+
+func toBool(v any) (any, error) {
+	switch t := v.(type) {
+	case bool:
+		return t, nil
+	case string:
+		return strconv.ParseBool(strings.TrimSpace(t))
+	}
+	f, err := toFloat64(v)
+	if err != nil {
+		return nil, fmt.Errorf("expected boolean value, got %T", v)
+	}
+	return f != 0, nil
+}
+
+func toString(v any) (any, error) {
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case []byte:
+		return string(t), nil
+	case json.Number:
+		return t.String(), nil
+	case nil:
+		return "", nil
+	case time.Time:
+		return t.Format(time.RFC3339Nano), nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+func toTime(v any) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case string:
+		s := strings.TrimSpace(t)
+		for _, layout := range timeLayouts {
+			if ts, err := time.Parse(layout, s); err == nil {
+				return ts, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("value %q is not a recognised timestamp", s)
+	}
+	f, err := toFloat64(v)
+	if err != nil {
+		return time.Time{}, errors.New("expected timestamp string or unix seconds")
+	}
+	secs, frac := math.Modf(f)
+	return time.Unix(int64(secs), int64(math.Round(frac*1e9))).UTC(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// orderedMap allows us to write Map columns without knowing the concrete Go
+// type that the client would otherwise expect.
+type orderedMap struct {
+	keys   []any
+	values []any
+}
+
+var _ column.IterableOrderedMap = &orderedMap{}
+
+func (m *orderedMap) Put(key, value any) {
+	m.keys = append(m.keys, key)
+	m.values = append(m.values, value)
+}
+
+func (m *orderedMap) Iterator() column.MapIterator {
+	return &orderedMapIterator{m: m, i: -1}
+}
+
+type orderedMapIterator struct {
+	m *orderedMap
+	i int
+}
+
+func (i *orderedMapIterator) Next() bool {
+	i.i++
+	return i.i < len(i.m.keys)
+}
+
+func (i *orderedMapIterator) Key() any {
+	return i.m.keys[i.i]
+}
+
+func (i *orderedMapIterator) Value() any {
+	return i.m.values[i.i]
+}
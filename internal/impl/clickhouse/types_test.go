@@ -0,0 +1,125 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitType(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		name  string
+		args  []string
+	}{
+		{input: "String", name: "String"},
+		{input: "Nullable(Int64)", name: "Nullable", args: []string{"Int64"}},
+		{input: "DateTime64(3, 'UTC')", name: "DateTime64", args: []string{"3", "'UTC'"}},
+		{input: "Map(String, Array(Nullable(Int64)))", name: "Map", args: []string{"String", "Array(Nullable(Int64))"}},
+		{input: "Enum8('a,b' = 1, 'c' = 2)", name: "Enum8", args: []string{"'a,b' = 1", "'c' = 2"}},
+	} {
+		name, args := splitType(test.input)
+		assert.Equal(t, test.name, name, test.input)
+		assert.Equal(t, test.args, args, test.input)
+	}
+}
+
+func TestCoerceValues(t *testing.T) {
+	for _, test := range []struct {
+		chType string
+		input  any
+		output any
+	}{
+		{chType: "Int8", input: json.Number("12"), output: int8(12)},
+		{chType: "Int32", input: float64(-5), output: int32(-5)},
+		{chType: "Int64", input: "42", output: int64(42)},
+		{chType: "Int64", input: nil, output: nil},
+		{chType: "UInt16", input: true, output: uint16(1)},
+		{chType: "UInt64", input: json.Number("18446744073709551615"), output: uint64(18446744073709551615)},
+		{chType: "Int128", input: json.Number("170141183460469231731687303715884105727"), output: func() *big.Int {
+			b, _ := new(big.Int).SetString("170141183460469231731687303715884105727", 10)
+			return b
+		}()},
+		{chType: "Float32", input: "1.5", output: float32(1.5)},
+		{chType: "Float64", input: json.Number("2.25"), output: 2.25},
+		{chType: "Decimal(18, 4)", input: "10.1234", output: decimal.RequireFromString("10.1234")},
+		{chType: "Bool", input: "true", output: true},
+		{chType: "String", input: map[string]any{"a": "b"}, output: `{"a":"b"}`},
+		{chType: "LowCardinality(String)", input: json.Number("10"), output: "10"},
+		{chType: "Nullable(String)", input: nil, output: nil},
+		{chType: "Nullable(UInt8)", input: float64(3), output: uint8(3)},
+		{chType: "DateTime64(3)", input: "2024-06-01T10:00:00.123Z", output: time.Date(2024, 6, 1, 10, 0, 0, 123000000, time.UTC)},
+		{chType: "DateTime", input: "2024-06-01 10:00:00", output: time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)},
+		{chType: "DateTime64(3)", input: 1717236000.5, output: time.Date(2024, 6, 1, 10, 0, 0, 500000000, time.UTC)},
+		{chType: "Date", input: "2024-06-01", output: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{chType: "Array(Nullable(Int64))", input: []any{json.Number("1"), nil, "3"}, output: []any{int64(1), nil, int64(3)}},
+		{chType: "Array(Array(String))", input: []any{[]any{"a"}, []any{}}, output: []any{[]any{"a"}, []any{}}},
+		{chType: "Array(String)", input: nil, output: []any{}},
+		{chType: "Tuple(String, Int64)", input: []any{"a", int64(1)}, output: []any{"a", int64(1)}},
+	} {
+		fn, err := newCoerceFn(test.chType)
+		require.NoError(t, err, test.chType)
+
+		res, err := fn(test.input)
+		require.NoError(t, err, test.chType)
+		assert.Equal(t, test.output, res, test.chType)
+	}
+}
+
+func TestCoerceMap(t *testing.T) {
+	fn, err := newCoerceFn("Map(String, UInt32)")
+	require.NoError(t, err)
+
+	res, err := fn(map[string]any{"a": json.Number("1")})
+	require.NoError(t, err)
+
+	m, ok := res.(*orderedMap)
+	require.True(t, ok)
+
+	iter := m.Iterator()
+	require.True(t, iter.Next())
+	assert.Equal(t, "a", iter.Key())
+	assert.Equal(t, uint32(1), iter.Value())
+	assert.False(t, iter.Next())
+}
+
+func TestCoerceErrors(t *testing.T) {
+	for _, test := range []struct {
+		chType string
+		input  any
+	}{
+		{chType: "Int8", input: float64(300)},
+		{chType: "Int64", input: 1.5},
+		{chType: "UInt32", input: json.Number("-1")},
+		{chType: "Int64", input: "nope"},
+		{chType: "Bool", input: []any{}},
+		{chType: "DateTime64(3)", input: "yesterday"},
+		{chType: "Array(Int64)", input: "[1,2]"},
+		{chType: "Map(String, String)", input: []any{}},
+	} {
+		fn, err := newCoerceFn(test.chType)
+		require.NoError(t, err, test.chType)
+
+		_, err = fn(test.input)
+		assert.Error(t, err, test.chType)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+
+	sess "github.com/redpanda-data/connect/v4/internal/impl/aws"
+	"github.com/redpanda-data/connect/v4/internal/impl/crypto"
+)
+
+func init() {
+	crypto.AWSKMSDecryptFn = kmsDecrypt
+}
+
+type kmsDecryptRequest struct {
+	CiphertextBlob []byte `json:"CiphertextBlob"`
+}
+
+type kmsDecryptResponse struct {
+	Plaintext []byte `json:"Plaintext"`
+}
+
+type kmsError struct {
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+// kmsDecrypt calls the Decrypt operation of the AWS KMS JSON API directly,
+// which avoids depending on the full KMS client for a single operation.
+func kmsDecrypt(ctx context.Context, conf *service.ParsedConfig, ciphertext []byte) ([]byte, error) {
+	awsConf, err := sess.GetSession(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+	if awsConf.Region == "" {
+		return nil, errors.New("a region must be specified in order to use AWS KMS")
+	}
+
+	endpoint := fmt.Sprintf("https://kms.%v.amazonaws.com/", awsConf.Region)
+	if awsConf.BaseEndpoint != nil {
+		endpoint = *awsConf.BaseEndpoint
+	}
+
+	body, err := json.Marshal(kmsDecryptRequest{CiphertextBlob: ciphertext})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService.Decrypt")
+
+	creds, err := awsConf.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve credentials: %w", err)
+	}
+	payloadHash := sha256.Sum256(body)
+	if err := v4.NewSigner().SignHTTP(ctx, creds, req, hex.EncodeToString(payloadHash[:]), "kms", awsConf.Region, time.Now()); err != nil {
+		return nil, err
+	}
+
+	client := awsConf.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		var kErr kmsError
+		if err := json.Unmarshal(resBody, &kErr); err == nil && kErr.Type != "" {
+			return nil, fmt.Errorf("kms decrypt failed: %v: %v", kErr.Type, kErr.Message)
+		}
+		return nil, fmt.Errorf("kms decrypt failed with status %v", res.StatusCode)
+	}
+
+	var dRes kmsDecryptResponse
+	if err := json.Unmarshal(resBody, &dRes); err != nil {
+		return nil, fmt.Errorf("failed to parse kms response: %w", err)
+	}
+	return dRes.Plaintext, nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/impl/aws/config"
+)
+
+func TestKMSDecrypt(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "TrentService.Decrypt", r.Header.Get("X-Amz-Target"))
+		assert.Equal(t, "application/x-amz-json-1.1", r.Header.Get("Content-Type"))
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=foo/"), r.Header.Get("Authorization"))
+		assert.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/kms/aws4_request")
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		var req map[string]string
+		require.NoError(t, json.Unmarshal(body, &req))
+		if req["CiphertextBlob"] != "aGVsbG8=" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"InvalidCiphertextException","message":"nope"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"KeyId":"foo","Plaintext":"d29ybGQ="}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	conf, err := service.NewConfigSpec().Fields(config.SessionFields()...).ParseYAML(fmt.Sprintf(`
+region: eu-west-1
+endpoint: %v
+credentials:
+  id: foo
+  secret: bar
+`, srv.URL), nil)
+	require.NoError(t, err)
+
+	res, err := kmsDecrypt(context.Background(), conf, []byte("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "world", string(res))
+
+	_, err = kmsDecrypt(context.Background(), conf, []byte("nope"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "InvalidCiphertextException")
+}
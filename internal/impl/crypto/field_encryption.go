@@ -0,0 +1,381 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/impl/aws/config"
+)
+
+const (
+	feFieldFields = "fields"
+	feFieldKey    = "key"
+
+	feFieldKeyStatic      = "static"
+	feFieldKeyEnv         = "env"
+	feFieldKeyAWSKMS      = "aws_kms"
+	feFieldKeyGCPKMS      = "gcp_kms"
+	feFieldKeyCiphertext  = "ciphertext"
+	feFieldKeyGCPKeyName  = "key_name"
+	feFieldKeyGCPEndpoint = "endpoint"
+)
+
+// The maximum time spent obtaining a key from a key management service.
+const feKeyResolutionTimeout = time.Second * 30
+
+func notImportedAWSKMSFn(ctx context.Context, conf *service.ParsedConfig, ciphertext []byte) ([]byte, error) {
+	return nil, errors.New("unable to decrypt key with AWS KMS as this binary does not import components/aws")
+}
+
+func notImportedGCPKMSFn(ctx context.Context, conf *service.ParsedConfig, ciphertext []byte) ([]byte, error) {
+	return nil, errors.New("unable to decrypt key with GCP KMS as this binary does not import components/gcp")
+}
+
+// AWSKMSDecryptFn is populated with the child `aws` package when imported.
+var AWSKMSDecryptFn = notImportedAWSKMSFn
+
+// GCPKMSDecryptFn is populated with the child `gcp` package when imported.
+var GCPKMSDecryptFn = notImportedGCPKMSFn
+
+func fieldEncryptionFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewStringListField(feFieldFields).
+			Description("A list of xref:configuration:field_paths.adoc[dot separated paths] to the fields of each message to process.").
+			Example([]string{"user.email", "user.phone"}),
+		service.NewObjectField(feFieldKey,
+			service.NewStringField(feFieldKeyStatic).
+				Description("A base64 encoded key.").
+				Secret().
+				Optional(),
+			service.NewStringField(feFieldKeyEnv).
+				Description("The name of an environment variable containing a base64 encoded key.").
+				Optional(),
+			service.NewObjectField(feFieldKeyAWSKMS,
+				append([]*service.ConfigField{
+					service.NewStringField(feFieldKeyCiphertext).
+						Description("The base64 encoded key encrypted with an AWS KMS key, such as the `CiphertextBlob` returned by the `GenerateDataKey` operation."),
+				}, config.SessionFields()...)...,
+			).
+				Description("Decrypt a base64 encoded key with AWS KMS when the processor is created.").
+				Optional(),
+			service.NewObjectField(feFieldKeyGCPKMS,
+				service.NewStringField(feFieldKeyGCPKeyName).
+					Description("The resource name of the GCP KMS key that the key was encrypted with.").
+					Example("projects/my-project/locations/global/keyRings/my-ring/cryptoKeys/my-key"),
+				service.NewStringField(feFieldKeyCiphertext).
+					Description("The base64 encoded key encrypted with the GCP KMS key."),
+				service.NewURLField(feFieldKeyGCPEndpoint).
+					Description("The endpoint of the GCP KMS API.").
+					Default("https://cloudkms.googleapis.com").
+					Advanced(),
+			).
+				Description("Decrypt a base64 encoded key with GCP KMS when the processor is created, using the default credentials of the environment.").
+				Optional(),
+		).
+			Description("The source of the AES key, which must be 16, 24 or 32 bytes long in order to use AES-128, AES-192 or AES-256 respectively. Exactly one source must be specified."),
+	}
+}
+
+const fieldEncryptionFormatDocs = `
+== Format
+
+Each value is serialized as JSON and encrypted with AES-GCM using a random nonce generated for each value, and the result is stored in place of the value as a base64 encoded string of the nonce followed by the ciphertext. The path of each field is used as additional authenticated data, and therefore a value can only be decrypted from the same path that it was encrypted at, which prevents encrypted values from being swapped between fields.
+
+Fields that do not exist within a message are skipped.
+
+== Keys
+
+Keys can be provided directly, either within the config or via an environment variable, or as a data key that is encrypted with a key management service, in which case it is decrypted once when the processor is created. Envelope encryption with a key management service avoids storing plain keys within configs, and a data key can be generated with the AWS CLI using ` + "`aws kms generate-data-key --key-id <key> --key-spec AES_256`" + `, keeping the ` + "`CiphertextBlob`" + ` of the result.`
+
+func base64Key(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode key as base64: %w", err)
+	}
+	return key, nil
+}
+
+func fieldEncryptionKeyFromParsed(conf *service.ParsedConfig) ([]byte, error) {
+	var sources []string
+	for _, k := range []string{feFieldKeyStatic, feFieldKeyEnv, feFieldKeyAWSKMS, feFieldKeyGCPKMS} {
+		if conf.Contains(k) {
+			sources = append(sources, k)
+		}
+	}
+	if len(sources) != 1 {
+		return nil, fmt.Errorf("exactly one key source must be specified, found %v", len(sources))
+	}
+
+	ctx, done := context.WithTimeout(context.Background(), feKeyResolutionTimeout)
+	defer done()
+
+	switch sources[0] {
+	case feFieldKeyStatic:
+		s, err := conf.FieldString(feFieldKeyStatic)
+		if err != nil {
+			return nil, err
+		}
+		return base64Key(s)
+	case feFieldKeyEnv:
+		name, err := conf.FieldString(feFieldKeyEnv)
+		if err != nil {
+			return nil, err
+		}
+		s, exists := os.LookupEnv(name)
+		if !exists {
+			return nil, fmt.Errorf("environment variable %v is not set", name)
+		}
+		return base64Key(s)
+	case feFieldKeyAWSKMS:
+		kmsConf := conf.Namespace(feFieldKeyAWSKMS)
+		s, err := kmsConf.FieldString(feFieldKeyCiphertext)
+		if err != nil {
+			return nil, err
+		}
+		ciphertext, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode ciphertext as base64: %w", err)
+		}
+		return AWSKMSDecryptFn(ctx, kmsConf, ciphertext)
+	default:
+		kmsConf := conf.Namespace(feFieldKeyGCPKMS)
+		s, err := kmsConf.FieldString(feFieldKeyCiphertext)
+		if err != nil {
+			return nil, err
+		}
+		ciphertext, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode ciphertext as base64: %w", err)
+		}
+		return GCPKMSDecryptFn(ctx, kmsConf, ciphertext)
+	}
+}
+
+type fieldCipher struct {
+	fields []string
+	aead   cipher.AEAD
+}
+
+func fieldCipherFromParsed(conf *service.ParsedConfig) (*fieldCipher, error) {
+	c := &fieldCipher{}
+
+	var err error
+	if c.fields, err = conf.FieldStringList(feFieldFields); err != nil {
+		return nil, err
+	}
+	if len(c.fields) == 0 {
+		return nil, errors.New("at least one field must be specified")
+	}
+
+	key, err := fieldEncryptionKeyFromParsed(conf.Namespace(feFieldKey))
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if c.aead, err = cipher.NewGCM(block); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *fieldCipher) encrypt(path string, v any) (string, error) {
+	plaintext, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(plaintext)+c.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(c.aead.Seal(nonce, nonce, plaintext, []byte(path))), nil
+}
+
+func (c *fieldCipher) decrypt(path string, v any) (any, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected string value, got %T", v)
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode value as base64: %w", err)
+	}
+	if len(b) < c.aead.NonceSize() {
+		return nil, errors.New("value is too short to have been encrypted")
+	}
+
+	nonce, ciphertext := b[:c.aead.NonceSize()], b[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, []byte(path))
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(plaintext))
+	dec.UseNumber()
+
+	var res any
+	if err := dec.Decode(&res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *fieldCipher) process(msg *service.Message, fn func(path string, v any) (any, error)) (service.MessageBatch, error) {
+	v, err := msg.AsStructuredMut()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %w", err)
+	}
+
+	// All fields are processed before any are modified so that a message that
+	// fails is left unchanged.
+	doc := gabs.Wrap(v)
+	results := make([]any, len(c.fields))
+	found := make([]bool, len(c.fields))
+	for i, path := range c.fields {
+		if found[i] = doc.ExistsP(path); !found[i] {
+			continue
+		}
+		if results[i], err = fn(path, doc.Path(path).Data()); err != nil {
+			return nil, fmt.Errorf("field %v: %w", path, err)
+		}
+	}
+	for i, path := range c.fields {
+		if !found[i] {
+			continue
+		}
+		if _, err := doc.SetP(results[i], path); err != nil {
+			return nil, fmt.Errorf("field %v: %w", path, err)
+		}
+	}
+
+	msg.SetStructuredMut(doc.Data())
+	return service.MessageBatch{msg}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func encryptFieldsProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Encrypts the values of specific fields of messages with AES-GCM.").
+		Description(`
+Values can be decrypted with the `+"xref:components:processors/decrypt_fields.adoc[`decrypt_fields` processor]"+` configured with the same key. Messages that are not valid JSON are flagged as failed and can be handled using xref:configuration:error_handling.adoc[error handling methods].
+`+fieldEncryptionFormatDocs).
+		Fields(fieldEncryptionFields()...).
+		Example("Encrypt PII", "Encrypt the contact details of users with a data key that is encrypted with AWS KMS.", `
+pipeline:
+  processors:
+    - encrypt_fields:
+        fields: [ user.email, user.phone ]
+        key:
+          aws_kms:
+            ciphertext: ${DATA_KEY_CIPHERTEXT}
+            region: eu-west-1
+`)
+}
+
+func decryptFieldsProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Decrypts the values of specific fields of messages that were encrypted with the `encrypt_fields` processor.").
+		Description(`
+Messages that are not valid JSON, or that contain values that cannot be decrypted with the key, are flagged as failed and can be handled using xref:configuration:error_handling.adoc[error handling methods].
+`+fieldEncryptionFormatDocs).
+		Fields(fieldEncryptionFields()...).
+		Example("Decrypt PII", "Decrypt the contact details of users with a key from an environment variable.", `
+pipeline:
+  processors:
+    - decrypt_fields:
+        fields: [ user.email, user.phone ]
+        key:
+          env: PII_KEY
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"encrypt_fields", encryptFieldsProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			c, err := fieldCipherFromParsed(conf)
+			if err != nil {
+				return nil, err
+			}
+			return &encryptFieldsProc{c: c}, nil
+		})
+	if err != nil {
+		panic(err)
+	}
+
+	err = service.RegisterProcessor(
+		"decrypt_fields", decryptFieldsProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			c, err := fieldCipherFromParsed(conf)
+			if err != nil {
+				return nil, err
+			}
+			return &decryptFieldsProc{c: c}, nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type encryptFieldsProc struct {
+	c *fieldCipher
+}
+
+func (p *encryptFieldsProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	return p.c.process(msg, func(path string, v any) (any, error) {
+		return p.c.encrypt(path, v)
+	})
+}
+
+func (p *encryptFieldsProc) Close(ctx context.Context) error {
+	return nil
+}
+
+type decryptFieldsProc struct {
+	c *fieldCipher
+}
+
+func (p *decryptFieldsProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	return p.c.process(msg, p.c.decrypt)
+}
+
+func (p *decryptFieldsProc) Close(ctx context.Context) error {
+	return nil
+}
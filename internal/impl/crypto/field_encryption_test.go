@@ -0,0 +1,200 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crypto
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const testFieldKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=" // 32 bytes
+
+func TestFieldEncryptionRoundTrip(t *testing.T) {
+	conf, err := encryptFieldsProcConfig().ParseYAML(fmt.Sprintf(`
+fields: [ user.email, user.age, user.tags, missing.field ]
+key:
+  static: %v
+`, testFieldKey), nil)
+	require.NoError(t, err)
+
+	c, err := fieldCipherFromParsed(conf)
+	require.NoError(t, err)
+
+	enc := &encryptFieldsProc{c: c}
+	dec := &decryptFieldsProc{c: c}
+
+	input := `{"id":"foo","user":{"age":12345678901234567,"email":"foo@example.com","tags":["a",{"b":true}]}}`
+
+	res, err := enc.Process(context.Background(), service.NewMessage([]byte(input)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	encrypted, err := res[0].AsStructured()
+	require.NoError(t, err)
+	user := encrypted.(map[string]any)["user"].(map[string]any)
+	for _, k := range []string{"email", "age", "tags"} {
+		s, ok := user[k].(string)
+		require.True(t, ok, k)
+		_, err := base64.StdEncoding.DecodeString(s)
+		require.NoError(t, err, k)
+	}
+	assert.Equal(t, "foo", encrypted.(map[string]any)["id"])
+
+	// Encrypting the same value twice results in different ciphertexts due to
+	// the random nonce.
+	res2, err := enc.Process(context.Background(), service.NewMessage([]byte(input)))
+	require.NoError(t, err)
+	encrypted2, err := res2[0].AsStructured()
+	require.NoError(t, err)
+	assert.NotEqual(t, user["email"], encrypted2.(map[string]any)["user"].(map[string]any)["email"])
+
+	res, err = dec.Process(context.Background(), res[0])
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	mBytes, err := res[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, input, string(mBytes))
+	assert.Contains(t, string(mBytes), "12345678901234567")
+}
+
+func TestFieldEncryptionWrongPath(t *testing.T) {
+	key := fmt.Sprintf("key:\n  static: %v\n", testFieldKey)
+
+	conf, err := encryptFieldsProcConfig().ParseYAML("fields: [ a ]\n"+key, nil)
+	require.NoError(t, err)
+
+	encCipher, err := fieldCipherFromParsed(conf)
+	require.NoError(t, err)
+
+	conf, err = decryptFieldsProcConfig().ParseYAML("fields: [ b ]\n"+key, nil)
+	require.NoError(t, err)
+
+	decCipher, err := fieldCipherFromParsed(conf)
+	require.NoError(t, err)
+
+	enc := &encryptFieldsProc{c: encCipher}
+	dec := &decryptFieldsProc{c: decCipher}
+
+	res, err := enc.Process(context.Background(), service.NewMessage([]byte(`{"a":"secret"}`)))
+	require.NoError(t, err)
+
+	// Move the encrypted value to another field.
+	v, err := res[0].AsStructuredMut()
+	require.NoError(t, err)
+	obj := v.(map[string]any)
+	obj["b"] = obj["a"]
+	res[0].SetStructuredMut(obj)
+
+	_, err = dec.Process(context.Background(), res[0])
+	require.Error(t, err)
+}
+
+func TestFieldDecryptionErrors(t *testing.T) {
+	conf, err := decryptFieldsProcConfig().ParseYAML(fmt.Sprintf(`
+fields: [ a, b ]
+key:
+  static: %v
+`, testFieldKey), nil)
+	require.NoError(t, err)
+
+	c, err := fieldCipherFromParsed(conf)
+	require.NoError(t, err)
+
+	dec := &decryptFieldsProc{c: c}
+
+	for _, input := range []string{
+		`not json`,
+		`{"a":10}`,
+		`{"a":"not base64!"}`,
+		`{"a":"c2hvcnQ="}`,
+		`{"a":"MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="}`,
+	} {
+		msg := service.NewMessage([]byte(input))
+		_, err := dec.Process(context.Background(), msg)
+		require.Error(t, err, input)
+
+		// Failed messages are left unchanged.
+		mBytes, err := msg.AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, input, string(mBytes))
+	}
+}
+
+func TestFieldEncryptionKeySources(t *testing.T) {
+	t.Setenv("TEST_FIELD_KEY", testFieldKey)
+
+	conf, err := encryptFieldsProcConfig().ParseYAML(`
+fields: [ a ]
+key:
+  env: TEST_FIELD_KEY
+`, nil)
+	require.NoError(t, err)
+
+	_, err = fieldCipherFromParsed(conf)
+	require.NoError(t, err)
+
+	var kmsCiphertext []byte
+	AWSKMSDecryptFn = func(ctx context.Context, conf *service.ParsedConfig, ciphertext []byte) ([]byte, error) {
+		region, err := conf.FieldString("region")
+		require.NoError(t, err)
+		assert.Equal(t, "eu-west-1", region)
+		kmsCiphertext = ciphertext
+		return base64.StdEncoding.DecodeString(testFieldKey)
+	}
+	GCPKMSDecryptFn = func(ctx context.Context, conf *service.ParsedConfig, ciphertext []byte) ([]byte, error) {
+		return nil, errors.New("nope")
+	}
+	t.Cleanup(func() {
+		AWSKMSDecryptFn = notImportedAWSKMSFn
+		GCPKMSDecryptFn = notImportedGCPKMSFn
+	})
+
+	conf, err = encryptFieldsProcConfig().ParseYAML(`
+fields: [ a ]
+key:
+  aws_kms:
+    ciphertext: aGVsbG8=
+    region: eu-west-1
+`, nil)
+	require.NoError(t, err)
+
+	_, err = fieldCipherFromParsed(conf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(kmsCiphertext))
+
+	for _, confStr := range []string{
+		"fields: [ a ]\nkey: {}",
+		fmt.Sprintf("fields: [ a ]\nkey:\n  static: %v\n  env: TEST_FIELD_KEY", testFieldKey),
+		"fields: [ a ]\nkey:\n  static: c2hvcnQ=",
+		"fields: [ a ]\nkey:\n  env: TEST_FIELD_KEY_MISSING",
+		"fields: [ a ]\nkey:\n  gcp_kms:\n    key_name: foo\n    ciphertext: aGVsbG8=",
+		fmt.Sprintf("fields: []\nkey:\n  static: %v", testFieldKey),
+	} {
+		conf, err := encryptFieldsProcConfig().ParseYAML(confStr, nil)
+		require.NoError(t, err, confStr)
+
+		_, err = fieldCipherFromParsed(conf)
+		require.Error(t, err, confStr)
+	}
+}
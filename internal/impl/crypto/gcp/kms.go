@@ -0,0 +1,110 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"golang.org/x/oauth2/google"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/impl/crypto"
+)
+
+const kmsScope = "https://www.googleapis.com/auth/cloudkms"
+
+func init() {
+	crypto.GCPKMSDecryptFn = kmsDecrypt
+}
+
+// newHTTPClient creates a client authenticated with the default credentials of
+// the environment, and is replaced during tests.
+var newHTTPClient = func(ctx context.Context) (*http.Client, error) {
+	return google.DefaultClient(ctx, kmsScope)
+}
+
+type kmsDecryptRequest struct {
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+type kmsDecryptResponse struct {
+	Plaintext []byte `json:"plaintext"`
+}
+
+type kmsErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// kmsDecrypt calls the decrypt method of the GCP KMS REST API directly, which
+// avoids depending on the full KMS client for a single operation.
+func kmsDecrypt(ctx context.Context, conf *service.ParsedConfig, ciphertext []byte) ([]byte, error) {
+	keyName, err := conf.FieldString("key_name")
+	if err != nil {
+		return nil, err
+	}
+	endpoint, err := conf.FieldURL("endpoint")
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(kmsDecryptRequest{Ciphertext: ciphertext})
+	if err != nil {
+		return nil, err
+	}
+
+	reqURL := fmt.Sprintf("%v/v1/%v:decrypt", strings.TrimSuffix(endpoint.String(), "/"), strings.TrimPrefix(keyName, "/"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client, err := newHTTPClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain default credentials: %w", err)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		var kErr kmsErrorResponse
+		if err := json.Unmarshal(resBody, &kErr); err == nil && kErr.Error.Message != "" {
+			return nil, fmt.Errorf("kms decrypt failed: %v", kErr.Error.Message)
+		}
+		return nil, fmt.Errorf("kms decrypt failed with status %v", res.StatusCode)
+	}
+
+	var dRes kmsDecryptResponse
+	if err := json.Unmarshal(resBody, &dRes); err != nil {
+		return nil, fmt.Errorf("failed to parse kms response: %w", err)
+	}
+	return dRes.Plaintext, nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestKMSDecrypt(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/projects/foo/locations/global/keyRings/bar/cryptoKeys/baz:decrypt", r.URL.Path)
+
+		var req map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if req["ciphertext"] != "aGVsbG8=" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"code":400,"message":"Decryption failed"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"plaintext":"d29ybGQ="}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	newHTTPClient = func(ctx context.Context) (*http.Client, error) {
+		return srv.Client(), nil
+	}
+
+	conf, err := service.NewConfigSpec().
+		Field(service.NewStringField("key_name")).
+		Field(service.NewURLField("endpoint")).
+		ParseYAML(fmt.Sprintf(`
+key_name: projects/foo/locations/global/keyRings/bar/cryptoKeys/baz
+endpoint: %v
+`, srv.URL), nil)
+	require.NoError(t, err)
+
+	res, err := kmsDecrypt(context.Background(), conf, []byte("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "world", string(res))
+
+	_, err = kmsDecrypt(context.Background(), conf, []byte("nope"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Decryption failed")
+}
@@ -0,0 +1,120 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+)
+
+var hmacAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+var hmacSignatureDecoders = map[string]func(string) ([]byte, error){
+	"hex":    hex.DecodeString,
+	"base64": base64.StdEncoding.DecodeString,
+}
+
+func registerCompareHMACMethod() error {
+	spec := bloblang.NewPluginSpec().
+		Category("Encoding and Encryption").
+		Version("4.31.0").
+		Description("Checks whether a signature matches the HMAC of the input computed with a secret key, which is commonly used to verify the payloads of webhooks. The comparison is performed in constant time, and signatures that are missing the expected prefix or cannot be decoded do not match.").
+		Param(bloblang.NewStringParam("signature").Description("The signature to verify, usually obtained from a header of a request, e.g. `@X-Hub-Signature-256`.")).
+		Param(bloblang.NewStringParam("key").Description("The secret key used to compute the HMAC.")).
+		Param(bloblang.NewStringParam("algorithm").Description("The hash algorithm of the HMAC, one of `md5`, `sha1`, `sha256` or `sha512`.").Default("sha256")).
+		Param(bloblang.NewStringParam("prefix").Description("A prefix that the signature must begin with, which is removed before it is decoded, e.g. `sha256=`.").Default("")).
+		Param(bloblang.NewStringParam("encoding").Description("The encoding of the signature, either `hex` or `base64`.").Default("hex")).
+		Example("Verify the signature of a webhook, where the signature is in the format `sha256=<hex>`.", `root.valid = content().compare_hmac(signature: "sha256=60868fd70007967e1ee47fd9a06180c5260416c96a338be842e18fc998d4308e", key: "secret", prefix: "sha256=")`, [2]string{
+			`{"id":"foo"}`,
+			`{"valid":true}`,
+		}).
+		Example("", `root.valid = content().compare_hmac(signature: "sha256=60868fd70007967e1ee47fd9a06180c5260416c96a338be842e18fc998d4308e", key: "secret", prefix: "sha256=")`, [2]string{
+			`{"id":"bar"}`,
+			`{"valid":false}`,
+		}).
+		Example("Reject messages with an invalid base64 encoded SHA-1 signature, which can be combined with the `http_server` input in order to drop unauthenticated webhook requests.", `root = if !content().compare_hmac(signature: "Azdu5617v87umGYEOaTYsSUSKlo=", key: "secret", algorithm: "sha1", encoding: "base64") { throw("invalid signature") }`, [2]string{
+			`hello world`,
+			`hello world`,
+		})
+
+	return bloblang.RegisterMethodV2("compare_hmac", spec, func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+		signature, err := args.GetString("signature")
+		if err != nil {
+			return nil, err
+		}
+		key, err := args.GetString("key")
+		if err != nil {
+			return nil, err
+		}
+		algorithm, err := args.GetString("algorithm")
+		if err != nil {
+			return nil, err
+		}
+		prefix, err := args.GetString("prefix")
+		if err != nil {
+			return nil, err
+		}
+		encoding, err := args.GetString("encoding")
+		if err != nil {
+			return nil, err
+		}
+
+		hashFn, exists := hmacAlgorithms[algorithm]
+		if !exists {
+			return nil, fmt.Errorf("unrecognised hmac algorithm: %v", algorithm)
+		}
+		decodeFn, exists := hmacSignatureDecoders[encoding]
+		if !exists {
+			return nil, fmt.Errorf("unrecognised signature encoding: %v", encoding)
+		}
+
+		var expected []byte
+		if encoded, hasPrefix := strings.CutPrefix(signature, prefix); hasPrefix {
+			// An undecodable signature is treated the same as an incorrect
+			// one, as both are the result of a bad request.
+			expected, _ = decodeFn(encoded)
+		}
+
+		return bloblang.BytesMethod(func(b []byte) (any, error) {
+			if len(expected) == 0 {
+				return false, nil
+			}
+			mac := hmac.New(hashFn, []byte(key))
+			_, _ = mac.Write(b)
+			return hmac.Equal(mac.Sum(nil), expected), nil
+		}), nil
+	})
+}
+
+func init() {
+	if err := registerCompareHMACMethod(); err != nil {
+		panic(err)
+	}
+}
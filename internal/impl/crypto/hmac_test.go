@@ -0,0 +1,94 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+)
+
+func TestBloblangCompareHMAC(t *testing.T) {
+	testCases := []struct {
+		title    string
+		mapping  string
+		expected bool
+	}{
+		{
+			title:    "sha256 hex",
+			mapping:  `root = "hello world".compare_hmac(signature: "734cc62f32841568f45715aeb9f4d7891324e6d948e4c6c60c0621cdac48623a", key: "secret")`,
+			expected: true,
+		},
+		{
+			title:    "sha256 wrong key",
+			mapping:  `root = "hello world".compare_hmac(signature: "734cc62f32841568f45715aeb9f4d7891324e6d948e4c6c60c0621cdac48623a", key: "nope")`,
+			expected: false,
+		},
+		{
+			title:    "sha256 with prefix",
+			mapping:  `root = "hello world".compare_hmac(signature: "sha256=734cc62f32841568f45715aeb9f4d7891324e6d948e4c6c60c0621cdac48623a", key: "secret", prefix: "sha256=")`,
+			expected: true,
+		},
+		{
+			title:    "missing prefix",
+			mapping:  `root = "hello world".compare_hmac(signature: "734cc62f32841568f45715aeb9f4d7891324e6d948e4c6c60c0621cdac48623a", key: "secret", prefix: "sha256=")`,
+			expected: false,
+		},
+		{
+			title:    "sha1 base64",
+			mapping:  `root = "hello world".compare_hmac(signature: "Azdu5617v87umGYEOaTYsSUSKlo=", key: "secret", algorithm: "sha1", encoding: "base64")`,
+			expected: true,
+		},
+		{
+			title:    "sha512 hex",
+			mapping:  `root = "hello world".compare_hmac(signature: "6d32239b01dd1750557211629313d95e4f4fcb8ee517e443990ac1afc7562bfd74ffa6118387efd9e168ff86d1da5cef4a55edc63cc4ba289c4c3a8b4f7bdfc2", key: "secret", algorithm: "sha512")`,
+			expected: true,
+		},
+		{
+			title:    "undecodable signature",
+			mapping:  `root = "hello world".compare_hmac(signature: "not hex", key: "secret")`,
+			expected: false,
+		},
+		{
+			title:    "empty signature",
+			mapping:  `root = "hello world".compare_hmac(signature: "", key: "secret")`,
+			expected: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.title, func(t *testing.T) {
+			exe, err := bloblang.Parse(testCase.mapping)
+			require.NoError(t, err)
+
+			res, err := exe.Query(nil)
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected, res)
+		})
+	}
+}
+
+func TestBloblangCompareHMACBadParams(t *testing.T) {
+	for _, mapping := range []string{
+		`root = content().compare_hmac(signature: "", key: "secret", algorithm: "sha3")`,
+		`root = content().compare_hmac(signature: "", key: "secret", encoding: "base32")`,
+	} {
+		_, err := bloblang.Parse(mapping)
+		require.Error(t, err, mapping)
+	}
+}
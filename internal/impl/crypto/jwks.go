@@ -0,0 +1,232 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crypto
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+// The minimum period between refreshes of a key set caused by an unknown key
+// ID, which prevents tokens with bogus key IDs from hammering the endpoint.
+const jwksMinRefreshInterval = time.Second * 10
+
+// The maximum time spent obtaining a key set.
+const jwksTimeout = time.Second * 30
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func jwkDecodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func (k jwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := jwkDecodeInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode modulus: %w", err)
+		}
+		e, err := jwkDecodeInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode exponent: %w", err)
+		}
+		if !e.IsInt64() || e.Int64() > (1<<31-1) {
+			return nil, errors.New("exponent is too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("curve not supported: %v", k.Crv)
+		}
+		x, err := jwkDecodeInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode x coordinate: %w", err)
+		}
+		y, err := jwkDecodeInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode y coordinate: %w", err)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("curve not supported: %v", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode x coordinate: %w", err)
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("key type not supported: %v", k.Kty)
+}
+
+// jwksCache obtains a JSON Web Key Set from a URL and caches its keys, where
+// the set is refreshed periodically and whenever an unknown key is requested.
+type jwksCache struct {
+	url             string
+	refreshInterval time.Duration
+	minRefresh      time.Duration
+	client          *http.Client
+	log             *service.Logger
+	nowFn           func() time.Time
+
+	mut         sync.Mutex
+	keys        map[string]any
+	lastAttempt time.Time
+}
+
+func newJWKSCache(url string, refreshInterval time.Duration, log *service.Logger) *jwksCache {
+	return &jwksCache{
+		url:             url,
+		refreshInterval: refreshInterval,
+		minRefresh:      jwksMinRefreshInterval,
+		client:          &http.Client{Timeout: jwksTimeout},
+		log:             log,
+		nowFn:           time.Now,
+	}
+}
+
+// key returns the key of a given ID, which can be empty when the key set
+// contains only one key.
+func (c *jwksCache) key(ctx context.Context, kid string) (any, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	sinceAttempt := c.nowFn().Sub(c.lastAttempt)
+	refresh := c.keys == nil || sinceAttempt >= c.refreshInterval
+	if !refresh {
+		if _, exists := c.lookup(kid); !exists {
+			refresh = sinceAttempt >= c.minRefresh
+		}
+	}
+
+	if refresh {
+		c.lastAttempt = c.nowFn()
+		keys, err := c.fetch(ctx)
+		if err != nil {
+			if c.keys == nil {
+				return nil, fmt.Errorf("failed to obtain key set: %w", err)
+			}
+			if c.log != nil {
+				c.log.Warnf("Failed to refresh key set, continuing with cached keys: %v", err)
+			}
+		} else {
+			c.keys = keys
+		}
+	}
+
+	k, exists := c.lookup(kid)
+	if !exists {
+		if kid == "" {
+			return nil, errors.New("token does not specify a key ID and the key set contains more than one key")
+		}
+		return nil, fmt.Errorf("key %v not found within key set", kid)
+	}
+	return k, nil
+}
+
+func (c *jwksCache) lookup(kid string) (any, bool) {
+	if kid == "" && len(c.keys) == 1 {
+		for _, k := range c.keys {
+			return k, true
+		}
+	}
+	k, exists := c.keys[kid]
+	return k, exists
+}
+
+func (c *jwksCache) fetch(ctx context.Context) (map[string]any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return nil, fmt.Errorf("key set request returned status %v", res.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to parse key set: %w", err)
+	}
+
+	keys := make(map[string]any, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			if c.log != nil {
+				c.log.Debugf("Skipping key %v of key set: %v", k.Kid, err)
+			}
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("key set contains no supported signing keys")
+	}
+	return keys, nil
+}
@@ -0,0 +1,317 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crypto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	jpFieldOperator        = "operator"
+	jpFieldToken           = "token"
+	jpFieldTargetPath      = "target_path"
+	jpFieldKey             = "key"
+	jpFieldJWKSURL         = "jwks_url"
+	jpFieldJWKSRefresh     = "jwks_refresh_interval"
+	jpFieldAlgorithms      = "algorithms"
+	jpFieldIssuer          = "issuer"
+	jpFieldAudience        = "audience"
+	jpFieldLeeway          = "leeway"
+	jpFieldRequireExpiry   = "require_expiry"
+	jpOperatorDecode       = "decode"
+	jpOperatorVerify       = "verify"
+	jpDefaultTokenTemplate = "${! content() }"
+)
+
+func jwtProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Parsing", "Utility").
+		Version("4.31.0").
+		Summary("Decodes the claims of a JSON Web Token, and optionally verifies its signature and claims.").
+		Description(`
+The token of each message is obtained with the interpolated field `+"`token`"+`, where a `+"`Bearer `"+` prefix is removed when present, and the claims of the token are placed at `+"`target_path`"+`.
+
+The `+"`decode`"+` operator extracts the claims without verifying the token in any way, and must therefore only be used with tokens from a trusted source. The `+"`verify`"+` operator checks the signature of the token with either a static `+"`key`"+` or the keys of a JSON Web Key Set obtained from `+"`jwks_url`"+`, and validates the expiry, not before, issuer and audience claims of the token.
+
+Messages with a token that cannot be decoded or fails verification, including expired tokens, are flagged as failed so that they can be handled using xref:configuration:error_handling.adoc[error handling methods].
+
+== Key rotation
+
+A key set obtained from `+"`jwks_url`"+` is cached and refreshed periodically according to `+"`jwks_refresh_interval`"+`. When a token is signed with a key ID that is not within the cached key set it's refreshed immediately, although no more than once every ten seconds, which allows signing keys to be rotated without interruption.`).
+		Field(service.NewStringAnnotatedEnumField(jpFieldOperator, map[string]string{
+			jpOperatorDecode: "Decode the claims of a token without verifying it.",
+			jpOperatorVerify: "Verify the signature and claims of a token before decoding its claims.",
+		}).
+			Description("The operation to perform on each token.")).
+		Field(service.NewInterpolatedStringField(jpFieldToken).
+			Description("An interpolated string that resolves to the token of each message.").
+			Default(jpDefaultTokenTemplate).
+			Example(`${! @Authorization }`).
+			Example(`${! this.token }`)).
+		Field(service.NewStringField(jpFieldTargetPath).
+			Description("A xref:configuration:field_paths.adoc[dot separated path] at which to place the claims within the message. When empty the contents of the message are replaced with the claims.").
+			Default("").
+			Example("auth.claims")).
+		Field(service.NewStringField(jpFieldKey).
+			Description("A key to verify tokens with, which is either a PEM encoded RSA, ECDSA or Ed25519 public key, or an HMAC secret. Only relevant when the operator is `verify`, in which case either this field or `jwks_url` must be specified.").
+			Secret().
+			Optional()).
+		Field(service.NewURLField(jpFieldJWKSURL).
+			Description("A URL from which to obtain the JSON Web Key Set to verify tokens with. Only relevant when the operator is `verify`, in which case either this field or `key` must be specified.").
+			Example("https://example.auth0.com/.well-known/jwks.json").
+			Optional()).
+		Field(service.NewDurationField(jpFieldJWKSRefresh).
+			Description("The period after which a cached JSON Web Key Set is obtained again.").
+			Default("1h").
+			Advanced()).
+		Field(service.NewStringListField(jpFieldAlgorithms).
+			Description("An optional list of signing algorithms that tokens are allowed to use. When empty any algorithm supported by the key is allowed.").
+			Example([]string{"RS256"}).
+			Default([]string{}).
+			Advanced()).
+		Field(service.NewStringField(jpFieldIssuer).
+			Description("An optional issuer that the `iss` claim of tokens must match.").
+			Example("https://example.auth0.com/").
+			Optional()).
+		Field(service.NewStringField(jpFieldAudience).
+			Description("An optional audience that the `aud` claim of tokens must contain.").
+			Example("my-api").
+			Optional()).
+		Field(service.NewDurationField(jpFieldLeeway).
+			Description("A leeway to allow for clock skew when validating the expiry and not before claims of tokens.").
+			Default("0s").
+			Advanced()).
+		Field(service.NewBoolField(jpFieldRequireExpiry).
+			Description("Whether tokens without an `exp` claim are rejected.").
+			Default(false).
+			Advanced()).
+		LintRule(`
+root = match {
+  this.operator == "verify" && this.key.or("") == "" && this.jwks_url.or("") == "" => "either a key or a jwks_url must be specified when the operator is verify"
+  this.operator == "verify" && this.key.or("") != "" && this.jwks_url.or("") != "" => "a key and a jwks_url cannot both be specified"
+}`).
+		Example("Verify bearer tokens", "Verify the bearer tokens of HTTP requests against the key set of an identity provider, and add the claims of each token to the message.", `
+input:
+  http_server:
+    path: /events
+
+pipeline:
+  processors:
+    - jwt:
+        operator: verify
+        token: ${! @Authorization }
+        target_path: auth
+        jwks_url: https://example.auth0.com/.well-known/jwks.json
+        issuer: https://example.auth0.com/
+        audience: my-api
+    - switch:
+        - check: errored()
+          processors:
+            - log:
+                message: 'Rejected event: ${! error() }'
+            - mapping: root = deleted()
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"jwt", jwtProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return jwtProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type jwtProc struct {
+	token      *service.InterpolatedString
+	targetPath string
+
+	verify    bool
+	staticKey any
+	jwks      *jwksCache
+	parser    *jwt.Parser
+}
+
+func jwtProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*jwtProc, error) {
+	p := &jwtProc{}
+
+	operator, err := conf.FieldString(jpFieldOperator)
+	if err != nil {
+		return nil, err
+	}
+	if p.token, err = conf.FieldInterpolatedString(jpFieldToken); err != nil {
+		return nil, err
+	}
+	if p.targetPath, err = conf.FieldString(jpFieldTargetPath); err != nil {
+		return nil, err
+	}
+
+	switch operator {
+	case jpOperatorDecode:
+		p.parser = jwt.NewParser()
+		return p, nil
+	case jpOperatorVerify:
+		p.verify = true
+	default:
+		return nil, fmt.Errorf("operator not recognised: %v", operator)
+	}
+
+	if conf.Contains(jpFieldKey) {
+		keyStr, err := conf.FieldString(jpFieldKey)
+		if err != nil {
+			return nil, err
+		}
+		if p.staticKey, err = jwtVerificationKey(keyStr); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(jpFieldJWKSURL) {
+		if p.staticKey != nil {
+			return nil, errors.New("a key and a jwks_url cannot both be specified")
+		}
+		jwksURL, err := conf.FieldURL(jpFieldJWKSURL)
+		if err != nil {
+			return nil, err
+		}
+		refresh, err := conf.FieldDuration(jpFieldJWKSRefresh)
+		if err != nil {
+			return nil, err
+		}
+		p.jwks = newJWKSCache(jwksURL.String(), refresh, mgr.Logger())
+	}
+	if p.staticKey == nil && p.jwks == nil {
+		return nil, errors.New("either a key or a jwks_url must be specified when the operator is verify")
+	}
+
+	var opts []jwt.ParserOption
+
+	algs, err := conf.FieldStringList(jpFieldAlgorithms)
+	if err != nil {
+		return nil, err
+	}
+	if len(algs) > 0 {
+		opts = append(opts, jwt.WithValidMethods(algs))
+	}
+	if conf.Contains(jpFieldIssuer) {
+		iss, err := conf.FieldString(jpFieldIssuer)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, jwt.WithIssuer(iss))
+	}
+	if conf.Contains(jpFieldAudience) {
+		aud, err := conf.FieldString(jpFieldAudience)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, jwt.WithAudience(aud))
+	}
+	leeway, err := conf.FieldDuration(jpFieldLeeway)
+	if err != nil {
+		return nil, err
+	}
+	if leeway > 0 {
+		opts = append(opts, jwt.WithLeeway(leeway))
+	}
+	requireExpiry, err := conf.FieldBool(jpFieldRequireExpiry)
+	if err != nil {
+		return nil, err
+	}
+	if requireExpiry {
+		opts = append(opts, jwt.WithExpirationRequired())
+	}
+
+	p.parser = jwt.NewParser(opts...)
+	return p, nil
+}
+
+// jwtVerificationKey parses a PEM encoded public key, and otherwise treats the
+// key as an HMAC secret.
+func jwtVerificationKey(s string) (any, error) {
+	if !strings.HasPrefix(strings.TrimSpace(s), "-----BEGIN") {
+		return []byte(s), nil
+	}
+	if k, err := jwt.ParseRSAPublicKeyFromPEM([]byte(s)); err == nil {
+		return k, nil
+	}
+	if k, err := jwt.ParseECPublicKeyFromPEM([]byte(s)); err == nil {
+		return k, nil
+	}
+	if k, err := jwt.ParseEdPublicKeyFromPEM([]byte(s)); err == nil {
+		return k, nil
+	}
+	return nil, errors.New("failed to parse key as a PEM encoded RSA, ECDSA or Ed25519 public key")
+}
+
+func (p *jwtProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	tokenStr, err := p.token.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("token interpolation error: %w", err)
+	}
+	tokenStr = strings.TrimSpace(tokenStr)
+	if len(tokenStr) > 7 && strings.EqualFold(tokenStr[:7], "bearer ") {
+		tokenStr = strings.TrimSpace(tokenStr[7:])
+	}
+	if tokenStr == "" {
+		return nil, errors.New("token is empty")
+	}
+
+	claims := jwt.MapClaims{}
+	if p.verify {
+		_, err = p.parser.ParseWithClaims(tokenStr, claims, func(tok *jwt.Token) (any, error) {
+			if p.staticKey != nil {
+				return p.staticKey, nil
+			}
+			kid, _ := tok.Header["kid"].(string)
+			return p.jwks.key(ctx, kid)
+		})
+	} else {
+		_, _, err = p.parser.ParseUnverified(tokenStr, claims)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT: %w", err)
+	}
+
+	if p.targetPath == "" {
+		msg.SetStructuredMut(map[string]any(claims))
+		return service.MessageBatch{msg}, nil
+	}
+
+	v, err := msg.AsStructuredMut()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message: %w", err)
+	}
+	gObj := gabs.Wrap(v)
+	if _, err := gObj.SetP(map[string]any(claims), p.targetPath); err != nil {
+		return nil, fmt.Errorf("failed to set claims at path %v: %w", p.targetPath, err)
+	}
+	msg.SetStructuredMut(gObj.Data())
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *jwtProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,211 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crypto
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func signJWT(t testing.TB, method jwt.SigningMethod, key any, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	tok := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		tok.Header["kid"] = kid
+	}
+	s, err := tok.SignedString(key)
+	require.NoError(t, err)
+	return s
+}
+
+func processJWT(t testing.TB, proc *jwtProc, msg *service.Message) (string, error) {
+	t.Helper()
+
+	res, err := proc.Process(context.Background(), msg)
+	if err != nil {
+		return "", err
+	}
+	require.Len(t, res, 1)
+	mBytes, err := res[0].AsBytes()
+	require.NoError(t, err)
+	return string(mBytes), nil
+}
+
+func TestJWTProcDecode(t *testing.T) {
+	conf, err := jwtProcConfig().ParseYAML(`
+operator: decode
+token: ${! @Authorization }
+target_path: auth.claims
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := jwtProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	tok := signJWT(t, jwt.SigningMethodHS256, []byte("nope"), "", jwt.MapClaims{"sub": "foo", "exp": 1})
+
+	msg := service.NewMessage([]byte(`{"id":"a"}`))
+	msg.MetaSetMut("Authorization", "Bearer "+tok)
+
+	res, err := processJWT(t, proc, msg)
+	require.NoError(t, err)
+	assert.Equal(t, `{"auth":{"claims":{"exp":1,"sub":"foo"}},"id":"a"}`, res)
+
+	_, err = processJWT(t, proc, service.NewMessage([]byte(`{}`)))
+	require.Error(t, err)
+}
+
+func TestJWTProcVerifyStaticKey(t *testing.T) {
+	conf, err := jwtProcConfig().ParseYAML(`
+operator: verify
+key: dont-tell-anyone
+issuer: example
+audience: my-api
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := jwtProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	exp := time.Now().Add(time.Hour).Unix()
+
+	res, err := processJWT(t, proc, service.NewMessage([]byte(signJWT(t, jwt.SigningMethodHS256, []byte("dont-tell-anyone"), "", jwt.MapClaims{
+		"sub": "foo", "iss": "example", "aud": "my-api", "exp": exp,
+	}))))
+	require.NoError(t, err)
+	assert.Contains(t, res, `"sub":"foo"`)
+
+	for name, tok := range map[string]string{
+		"wrong key": signJWT(t, jwt.SigningMethodHS256, []byte("nope"), "", jwt.MapClaims{
+			"iss": "example", "aud": "my-api", "exp": exp,
+		}),
+		"expired": signJWT(t, jwt.SigningMethodHS256, []byte("dont-tell-anyone"), "", jwt.MapClaims{
+			"iss": "example", "aud": "my-api", "exp": time.Now().Add(-time.Hour).Unix(),
+		}),
+		"wrong issuer": signJWT(t, jwt.SigningMethodHS256, []byte("dont-tell-anyone"), "", jwt.MapClaims{
+			"iss": "other", "aud": "my-api", "exp": exp,
+		}),
+		"wrong audience": signJWT(t, jwt.SigningMethodHS256, []byte("dont-tell-anyone"), "", jwt.MapClaims{
+			"iss": "example", "aud": "other", "exp": exp,
+		}),
+		"not a token": "foo.bar.baz",
+	} {
+		_, err := processJWT(t, proc, service.NewMessage([]byte(tok)))
+		assert.Error(t, err, name)
+	}
+}
+
+type testJWKSServer struct {
+	mut      sync.Mutex
+	keys     map[string]*rsa.PrivateKey
+	requests atomic.Int32
+}
+
+func (s *testJWKSServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.requests.Add(1)
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	var keys []map[string]string
+	for kid, k := range s.keys {
+		keys = append(keys, map[string]string{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		})
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+}
+
+func TestJWTProcVerifyJWKSRotation(t *testing.T) {
+	keyA, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keyB, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwks := &testJWKSServer{keys: map[string]*rsa.PrivateKey{"a": keyA}}
+	srv := httptest.NewServer(jwks)
+	t.Cleanup(srv.Close)
+
+	conf, err := jwtProcConfig().ParseYAML(`
+operator: verify
+jwks_url: `+srv.URL+`
+algorithms: [ RS256 ]
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := jwtProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	res, err := processJWT(t, proc, service.NewMessage([]byte(signJWT(t, jwt.SigningMethodRS256, keyA, "a", jwt.MapClaims{"sub": "foo"}))))
+	require.NoError(t, err)
+	assert.Equal(t, `{"sub":"foo"}`, res)
+
+	_, err = processJWT(t, proc, service.NewMessage([]byte(signJWT(t, jwt.SigningMethodRS256, keyA, "a", jwt.MapClaims{"sub": "bar"}))))
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), jwks.requests.Load())
+
+	// Rotate the signing key, a token signed with the new key triggers a
+	// refresh once the minimum refresh interval has passed.
+	jwks.mut.Lock()
+	jwks.keys = map[string]*rsa.PrivateKey{"b": keyB}
+	jwks.mut.Unlock()
+
+	tokB := signJWT(t, jwt.SigningMethodRS256, keyB, "b", jwt.MapClaims{"sub": "baz"})
+
+	_, err = processJWT(t, proc, service.NewMessage([]byte(tokB)))
+	require.Error(t, err)
+	assert.Equal(t, int32(1), jwks.requests.Load())
+
+	proc.jwks.lastAttempt = time.Now().Add(-jwksMinRefreshInterval)
+
+	res, err = processJWT(t, proc, service.NewMessage([]byte(tokB)))
+	require.NoError(t, err)
+	assert.Equal(t, `{"sub":"baz"}`, res)
+	assert.Equal(t, int32(2), jwks.requests.Load())
+
+	_, err = processJWT(t, proc, service.NewMessage([]byte(signJWT(t, jwt.SigningMethodHS256, []byte("foo"), "b", jwt.MapClaims{}))))
+	require.Error(t, err)
+}
+
+func TestJWTProcBadConfig(t *testing.T) {
+	pConf, err := jwtProcConfig().ParseYAML(`
+operator: verify
+`, nil)
+	require.NoError(t, err)
+
+	_, err = jwtProcFromParsed(pConf, service.MockResources())
+	require.Error(t, err)
+}
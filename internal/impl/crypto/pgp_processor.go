@@ -0,0 +1,448 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	pgpFieldOperator       = "operator"
+	pgpFieldPublicKey      = "public_key"
+	pgpFieldPublicKeyFile  = "public_key_file"
+	pgpFieldPrivateKey     = "private_key"
+	pgpFieldPrivateKeyFile = "private_key_file"
+	pgpFieldPassphrase     = "passphrase"
+	pgpFieldSignature      = "signature"
+	pgpFieldArmor          = "armor"
+	pgpOperatorVerify      = "verify"
+	pgpOperatorDecrypt     = "decrypt"
+	pgpOperatorSign        = "sign"
+	pgpOperatorEncrypt     = "encrypt"
+)
+
+func pgpProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Parsing", "Utility").
+		Version("4.31.0").
+		Summary("Verifies, decrypts, signs or encrypts messages with OpenPGP keys, compatible with GnuPG.").
+		Description(`
+Keys can be provided either as strings or as paths to files, and can be in either the ASCII armored or the binary format. A public key field can contain multiple keys, in which case any of them is accepted when verifying signatures, and messages are encrypted for all of them.
+
+The `+"`verify`"+` operator checks the signature of each message against the public keys, and accepts inline signed messages (`+"`gpg --sign`"+`), in which case the contents of the message are replaced with the signed data, and clear signed messages (`+"`gpg --clearsign`"+`), in which case the contents are replaced with the signed text. When a `+"`signature`"+` is specified it's used as a detached signature (`+"`gpg --detach-sign`"+`) of the message, and the contents of the message are left unchanged.
+
+The `+"`decrypt`"+` operator decrypts each message with the private key. When a message is also signed and public keys are specified the signature is verified against them, otherwise the signature is ignored.
+
+The `+"`sign`"+` operator replaces each message with an inline signed message, and the `+"`encrypt`"+` operator replaces each message with a message encrypted for the public keys, which is also signed when a private key is specified.
+
+Messages that cannot be verified or decrypted, including messages with signatures from unknown keys, are flagged as failed and can be handled using xref:configuration:error_handling.adoc[error handling methods].
+
+== Metadata
+
+When a signature is verified by either the `+"`verify`"+` or `+"`decrypt`"+` operator the following metadata fields are added to the message:
+
+`+"```text"+`
+- pgp_signer_key_id
+- pgp_signer
+- pgp_signed_at
+`+"```"+`
+
+Where `+"`pgp_signer_key_id`"+` is the hex encoded ID of the signing key, `+"`pgp_signer`"+` is the primary identity of the signing key, and `+"`pgp_signed_at`"+` is the creation time of the signature in RFC 3339 format.`).
+		Field(service.NewStringAnnotatedEnumField(pgpFieldOperator, map[string]string{
+			pgpOperatorVerify:  "Verify the signature of each message.",
+			pgpOperatorDecrypt: "Decrypt each message, verifying its signature when present.",
+			pgpOperatorSign:    "Sign each message.",
+			pgpOperatorEncrypt: "Encrypt each message, signing it when a private key is specified.",
+		}).
+			Description("The operation to perform on each message.")).
+		Field(service.NewStringField(pgpFieldPublicKey).
+			Description("One or more public keys, required by the `verify` and `encrypt` operators.").
+			Optional()).
+		Field(service.NewStringField(pgpFieldPublicKeyFile).
+			Description("A path to a file containing one or more public keys, as an alternative to `public_key`.").
+			Example("./keys/partner.asc").
+			Optional()).
+		Field(service.NewStringField(pgpFieldPrivateKey).
+			Description("A private key, required by the `decrypt` and `sign` operators.").
+			Secret().
+			Optional()).
+		Field(service.NewStringField(pgpFieldPrivateKeyFile).
+			Description("A path to a file containing a private key, as an alternative to `private_key`.").
+			Example("./keys/private.asc").
+			Optional()).
+		Field(service.NewStringField(pgpFieldPassphrase).
+			Description("The passphrase of the private key, when it's encrypted.").
+			Secret().
+			Optional()).
+		Field(service.NewInterpolatedStringField(pgpFieldSignature).
+			Description("An optional detached signature of each message to verify, only relevant when the operator is `verify`.").
+			Example(`${! @signature }`).
+			Optional().
+			Advanced()).
+		Field(service.NewBoolField(pgpFieldArmor).
+			Description("Whether the messages produced by the `sign` and `encrypt` operators are ASCII armored.").
+			Default(true).
+			Advanced()).
+		Example("Verify partner feeds", "Verify that messages are signed by a partner, sending messages with invalid signatures to a dead letter queue.", `
+pipeline:
+  processors:
+    - pgp:
+        operator: verify
+        public_key_file: ./keys/partner.asc
+
+output:
+  switch:
+    cases:
+      - check: errored()
+        output:
+          file:
+            path: ./rejected.jsonl
+      - output:
+          stdout: {}
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"pgp", pgpProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return pgpProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type pgpProc struct {
+	operator  string
+	public    openpgp.EntityList
+	private   *openpgp.Entity
+	signature *service.InterpolatedString
+	armor     bool
+}
+
+// pgpKeyRing reads keys from either a string or file field, returning an
+// empty list when neither is set.
+func pgpKeyRing(conf *service.ParsedConfig, field, fileField string) (openpgp.EntityList, error) {
+	if conf.Contains(field) && conf.Contains(fileField) {
+		return nil, fmt.Errorf("%v and %v cannot both be specified", field, fileField)
+	}
+
+	var data []byte
+	switch {
+	case conf.Contains(field):
+		s, err := conf.FieldString(field)
+		if err != nil {
+			return nil, err
+		}
+		data = []byte(s)
+	case conf.Contains(fileField):
+		path, err := conf.FieldString(fileField)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, nil
+	}
+
+	var keys openpgp.EntityList
+	var err error
+	if pgpIsArmored(data) {
+		keys, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	} else {
+		keys, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %v: %w", field, err)
+	}
+	return keys, nil
+}
+
+func pgpIsArmored(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN PGP"))
+}
+
+func pgpProcFromParsed(conf *service.ParsedConfig) (*pgpProc, error) {
+	p := &pgpProc{}
+
+	var err error
+	if p.operator, err = conf.FieldString(pgpFieldOperator); err != nil {
+		return nil, err
+	}
+	if p.armor, err = conf.FieldBool(pgpFieldArmor); err != nil {
+		return nil, err
+	}
+	if p.public, err = pgpKeyRing(conf, pgpFieldPublicKey, pgpFieldPublicKeyFile); err != nil {
+		return nil, err
+	}
+
+	privateKeys, err := pgpKeyRing(conf, pgpFieldPrivateKey, pgpFieldPrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(privateKeys) > 1 {
+		return nil, errors.New("private_key must contain a single key")
+	}
+	if len(privateKeys) == 1 {
+		p.private = privateKeys[0]
+		if p.private.PrivateKey == nil {
+			return nil, errors.New("private_key does not contain a private key")
+		}
+		if p.private.PrivateKey.Encrypted {
+			if !conf.Contains(pgpFieldPassphrase) {
+				return nil, errors.New("a passphrase is required for the encrypted private key")
+			}
+			pass, err := conf.FieldString(pgpFieldPassphrase)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.private.DecryptPrivateKeys([]byte(pass)); err != nil {
+				return nil, fmt.Errorf("failed to decrypt private key: %w", err)
+			}
+		}
+	}
+
+	if conf.Contains(pgpFieldSignature) {
+		if p.operator != pgpOperatorVerify {
+			return nil, errors.New("a signature can only be specified when the operator is verify")
+		}
+		if p.signature, err = conf.FieldInterpolatedString(pgpFieldSignature); err != nil {
+			return nil, err
+		}
+	}
+
+	switch p.operator {
+	case pgpOperatorVerify, pgpOperatorEncrypt:
+		if len(p.public) == 0 {
+			return nil, fmt.Errorf("a public key is required when the operator is %v", p.operator)
+		}
+	case pgpOperatorDecrypt, pgpOperatorSign:
+		if p.private == nil {
+			return nil, fmt.Errorf("a private key is required when the operator is %v", p.operator)
+		}
+	default:
+		return nil, fmt.Errorf("operator not recognised: %v", p.operator)
+	}
+	return p, nil
+}
+
+// pgpIssuerKeyID returns the ID of the key that made a signature, which is the
+// signing subkey rather than the primary key when one is used.
+func pgpIssuerKeyID(signer *openpgp.Entity, sig *packet.Signature) uint64 {
+	if sig != nil && sig.IssuerKeyId != nil {
+		return *sig.IssuerKeyId
+	}
+	return signer.PrimaryKey.KeyId
+}
+
+func pgpSetSignerMeta(msg *service.Message, signer *openpgp.Entity, keyID uint64, sig *packet.Signature) {
+	msg.MetaSetMut("pgp_signer_key_id", fmt.Sprintf("%016X", keyID))
+	if signer != nil {
+		if id := signer.PrimaryIdentity(); id != nil {
+			msg.MetaSetMut("pgp_signer", id.Name)
+		}
+	}
+	if sig != nil {
+		msg.MetaSetMut("pgp_signed_at", sig.CreationTime.Format(time.RFC3339))
+	}
+}
+
+// readMessage reads an inline signed and/or encrypted message, verifying its
+// signature against the keyring when requireSig is true.
+func (p *pgpProc) readMessage(data []byte, keyring openpgp.KeyRing, requireSig bool) ([]byte, *openpgp.MessageDetails, error) {
+	var r io.Reader = bytes.NewReader(data)
+	if pgpIsArmored(data) {
+		block, err := armor.Decode(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decode armor: %w", err)
+		}
+		r = block.Body
+	}
+
+	md, err := openpgp.ReadMessage(r, keyring, nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := io.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !md.IsSigned {
+		if requireSig {
+			return nil, nil, errors.New("message is not signed")
+		}
+		return body, md, nil
+	}
+	if md.SignedBy == nil {
+		if requireSig {
+			return nil, nil, fmt.Errorf("message is signed by an unknown key %016X", md.SignedByKeyId)
+		}
+		return body, md, nil
+	}
+	if md.SignatureError != nil {
+		return nil, nil, fmt.Errorf("invalid signature: %w", md.SignatureError)
+	}
+	return body, md, nil
+}
+
+func (p *pgpProc) verify(msg *service.Message, data []byte) ([]byte, error) {
+	if p.signature != nil {
+		sigStr, err := p.signature.TryString(msg)
+		if err != nil {
+			return nil, fmt.Errorf("signature interpolation error: %w", err)
+		}
+		var sigReader io.Reader = bytes.NewReader([]byte(sigStr))
+		if pgpIsArmored([]byte(sigStr)) {
+			block, err := armor.Decode(sigReader)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode signature armor: %w", err)
+			}
+			sigReader = block.Body
+		}
+		sig, signer, err := openpgp.VerifyDetachedSignature(p.public, bytes.NewReader(data), sigReader, nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid signature: %w", err)
+		}
+		pgpSetSignerMeta(msg, signer, pgpIssuerKeyID(signer, sig), sig)
+		return data, nil
+	}
+
+	if block, _ := clearsign.Decode(data); block != nil {
+		signer, err := block.VerifySignature(p.public, nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid signature: %w", err)
+		}
+
+		// The signature body is consumed by the verification, and therefore
+		// the block is decoded again in order to read the signature details.
+		var sig *packet.Signature
+		if sigBlock, _ := clearsign.Decode(data); sigBlock != nil {
+			if pkt, err := packet.Read(sigBlock.ArmoredSignature.Body); err == nil {
+				sig, _ = pkt.(*packet.Signature)
+			}
+		}
+		pgpSetSignerMeta(msg, signer, pgpIssuerKeyID(signer, sig), sig)
+
+		// The line break preceding the signature is not part of the signed
+		// text.
+		return bytes.TrimSuffix(block.Plaintext, []byte("\n")), nil
+	}
+
+	body, md, err := p.readMessage(data, p.public, true)
+	if err != nil {
+		return nil, err
+	}
+	pgpSetSignerMeta(msg, md.SignedBy.Entity, md.SignedByKeyId, md.Signature)
+	return body, nil
+}
+
+func (p *pgpProc) decrypt(msg *service.Message, data []byte) ([]byte, error) {
+	keyring := append(openpgp.EntityList{p.private}, p.public...)
+	body, md, err := p.readMessage(data, keyring, false)
+	if err != nil {
+		return nil, err
+	}
+	if !md.IsEncrypted {
+		return nil, errors.New("message is not encrypted")
+	}
+	if md.IsSigned && len(p.public) > 0 {
+		if md.SignedBy == nil {
+			return nil, fmt.Errorf("message is signed by an unknown key %016X", md.SignedByKeyId)
+		}
+		pgpSetSignerMeta(msg, md.SignedBy.Entity, md.SignedByKeyId, md.Signature)
+	}
+	return body, nil
+}
+
+func (p *pgpProc) write(data []byte, fn func(w io.Writer) (io.WriteCloser, error)) ([]byte, error) {
+	var buf bytes.Buffer
+
+	var out io.Writer = &buf
+	var armorW io.WriteCloser
+	if p.armor {
+		var err error
+		if armorW, err = armor.Encode(&buf, "PGP MESSAGE", nil); err != nil {
+			return nil, err
+		}
+		out = armorW
+	}
+
+	w, err := fn(out)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	if armorW != nil {
+		if err := armorW.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func (p *pgpProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	data, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var res []byte
+	switch p.operator {
+	case pgpOperatorVerify:
+		res, err = p.verify(msg, data)
+	case pgpOperatorDecrypt:
+		res, err = p.decrypt(msg, data)
+	case pgpOperatorSign:
+		res, err = p.write(data, func(w io.Writer) (io.WriteCloser, error) {
+			return openpgp.Sign(w, p.private, nil, nil)
+		})
+	case pgpOperatorEncrypt:
+		res, err = p.write(data, func(w io.Writer) (io.WriteCloser, error) {
+			return openpgp.Encrypt(w, p.public, p.private, nil, nil)
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	msg.SetBytes(res)
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *pgpProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,304 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type pgpTestKey struct {
+	entity      *openpgp.Entity
+	publicFile  string
+	privateFile string
+}
+
+func newPGPTestKey(t testing.TB, name string) pgpTestKey {
+	t.Helper()
+
+	e, err := openpgp.NewEntity(name, "", strings.ToLower(name)+"@example.com", nil)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	k := pgpTestKey{
+		entity:      e,
+		publicFile:  filepath.Join(dir, "public.asc"),
+		privateFile: filepath.Join(dir, "private.gpg"),
+	}
+
+	var pubBuf bytes.Buffer
+	w, err := armor.Encode(&pubBuf, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, e.Serialize(w))
+	require.NoError(t, w.Close())
+	require.NoError(t, os.WriteFile(k.publicFile, pubBuf.Bytes(), 0o600))
+
+	// The private key is stored in the binary format.
+	var privBuf bytes.Buffer
+	require.NoError(t, e.SerializePrivate(&privBuf, nil))
+	require.NoError(t, os.WriteFile(k.privateFile, privBuf.Bytes(), 0o600))
+	return k
+}
+
+func pgpProcess(t testing.TB, proc *pgpProc, msg *service.Message) (*service.Message, error) {
+	t.Helper()
+
+	res, err := proc.Process(context.Background(), msg)
+	if err != nil {
+		return nil, err
+	}
+	require.Len(t, res, 1)
+	return res[0], nil
+}
+
+func pgpContent(t testing.TB, msg *service.Message) string {
+	t.Helper()
+
+	b, err := msg.AsBytes()
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestPGPSignVerify(t *testing.T) {
+	alice, mallory := newPGPTestKey(t, "Alice"), newPGPTestKey(t, "Mallory")
+
+	conf, err := pgpProcConfig().ParseYAML(fmt.Sprintf(`
+operator: sign
+private_key_file: %v
+`, alice.privateFile), nil)
+	require.NoError(t, err)
+
+	signer, err := pgpProcFromParsed(conf)
+	require.NoError(t, err)
+
+	pConf, err := pgpProcConfig().ParseYAML(fmt.Sprintf(`
+operator: verify
+public_key_file: %v
+`, alice.publicFile), nil)
+	require.NoError(t, err)
+
+	verifier, err := pgpProcFromParsed(pConf)
+	require.NoError(t, err)
+
+	signed, err := pgpProcess(t, signer, service.NewMessage([]byte("hello world")))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(pgpContent(t, signed), "-----BEGIN PGP MESSAGE-----"))
+
+	verified, err := pgpProcess(t, verifier, signed)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", pgpContent(t, verified))
+
+	keyID, _ := verified.MetaGet("pgp_signer_key_id")
+	assert.Equal(t, fmt.Sprintf("%016X", alice.entity.PrimaryKey.KeyId), keyID)
+	signerName, _ := verified.MetaGet("pgp_signer")
+	assert.Equal(t, "Alice <alice@example.com>", signerName)
+	_, exists := verified.MetaGet("pgp_signed_at")
+	assert.True(t, exists)
+
+	pConf, err = pgpProcConfig().ParseYAML(fmt.Sprintf(`
+operator: sign
+private_key_file: %v
+armor: false
+`, mallory.privateFile), nil)
+	require.NoError(t, err)
+
+	forger, err := pgpProcFromParsed(pConf)
+	require.NoError(t, err)
+
+	forged, err := pgpProcess(t, forger, service.NewMessage([]byte("hello world")))
+	require.NoError(t, err)
+
+	_, err = pgpProcess(t, verifier, forged)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown key")
+
+	_, err = pgpProcess(t, verifier, service.NewMessage([]byte("hello world")))
+	require.Error(t, err)
+}
+
+func TestPGPVerifyClearSigned(t *testing.T) {
+	alice := newPGPTestKey(t, "Alice")
+
+	var buf bytes.Buffer
+	w, err := clearsign.Encode(&buf, alice.entity.PrivateKey, nil)
+	require.NoError(t, err)
+	_, err = w.Write([]byte("hello world"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	pubKey, err := os.ReadFile(alice.publicFile)
+	require.NoError(t, err)
+
+	conf, err := pgpProcConfig().ParseYAML("operator: verify\npublic_key: |\n  "+
+		strings.ReplaceAll(strings.TrimSpace(string(pubKey)), "\n", "\n  "), nil)
+	require.NoError(t, err)
+
+	verifier, err := pgpProcFromParsed(conf)
+	require.NoError(t, err)
+
+	verified, err := pgpProcess(t, verifier, service.NewMessage(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", pgpContent(t, verified))
+	keyID, _ := verified.MetaGet("pgp_signer_key_id")
+	assert.Equal(t, fmt.Sprintf("%016X", alice.entity.PrimaryKey.KeyId), keyID)
+
+	tampered := bytes.Replace(buf.Bytes(), []byte("hello world"), []byte("hello there"), 1)
+	_, err = pgpProcess(t, verifier, service.NewMessage(tampered))
+	require.Error(t, err)
+}
+
+func TestPGPVerifyDetached(t *testing.T) {
+	alice := newPGPTestKey(t, "Alice")
+
+	var sig bytes.Buffer
+	require.NoError(t, openpgp.ArmoredDetachSign(&sig, alice.entity, strings.NewReader("hello world"), nil))
+
+	conf, err := pgpProcConfig().ParseYAML(fmt.Sprintf(`
+operator: verify
+public_key_file: %v
+signature: ${! @signature }
+`, alice.publicFile), nil)
+	require.NoError(t, err)
+
+	verifier, err := pgpProcFromParsed(conf)
+	require.NoError(t, err)
+
+	msg := service.NewMessage([]byte("hello world"))
+	msg.MetaSetMut("signature", sig.String())
+	verified, err := pgpProcess(t, verifier, msg)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", pgpContent(t, verified))
+	_, exists := verified.MetaGet("pgp_signed_at")
+	assert.True(t, exists)
+
+	msg = service.NewMessage([]byte("hello there"))
+	msg.MetaSetMut("signature", sig.String())
+	_, err = pgpProcess(t, verifier, msg)
+	require.Error(t, err)
+}
+
+func TestPGPEncryptDecrypt(t *testing.T) {
+	alice, bob := newPGPTestKey(t, "Alice"), newPGPTestKey(t, "Bob")
+
+	conf, err := pgpProcConfig().ParseYAML(fmt.Sprintf(`
+operator: encrypt
+public_key_file: %v
+private_key_file: %v
+`, bob.publicFile, alice.privateFile), nil)
+	require.NoError(t, err)
+
+	encrypter, err := pgpProcFromParsed(conf)
+	require.NoError(t, err)
+
+	encrypted, err := pgpProcess(t, encrypter, service.NewMessage([]byte("hello world")))
+	require.NoError(t, err)
+	assert.NotContains(t, pgpContent(t, encrypted), "hello world")
+
+	// Without public keys the signature is ignored.
+	pConf, err := pgpProcConfig().ParseYAML(fmt.Sprintf(`
+operator: decrypt
+private_key_file: %v
+`, bob.privateFile), nil)
+	require.NoError(t, err)
+
+	decrypter, err := pgpProcFromParsed(pConf)
+	require.NoError(t, err)
+
+	decrypted, err := pgpProcess(t, decrypter, encrypted.Copy())
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", pgpContent(t, decrypted))
+	_, exists := decrypted.MetaGet("pgp_signer_key_id")
+	assert.False(t, exists)
+
+	pConf, err = pgpProcConfig().ParseYAML(fmt.Sprintf(`
+operator: decrypt
+private_key_file: %v
+public_key_file: %v
+`, bob.privateFile, alice.publicFile), nil)
+	require.NoError(t, err)
+
+	verifyingDecrypter, err := pgpProcFromParsed(pConf)
+	require.NoError(t, err)
+
+	decrypted, err = pgpProcess(t, verifyingDecrypter, encrypted.Copy())
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", pgpContent(t, decrypted))
+	signerName, _ := decrypted.MetaGet("pgp_signer")
+	assert.Equal(t, "Alice <alice@example.com>", signerName)
+
+	pConf, err = pgpProcConfig().ParseYAML(fmt.Sprintf(`
+operator: decrypt
+private_key_file: %v
+`, alice.privateFile), nil)
+	require.NoError(t, err)
+
+	wrongDecrypter, err := pgpProcFromParsed(pConf)
+	require.NoError(t, err)
+
+	_, err = pgpProcess(t, wrongDecrypter, encrypted.Copy())
+	require.Error(t, err)
+}
+
+func TestPGPConfigErrors(t *testing.T) {
+	alice := newPGPTestKey(t, "Alice")
+
+	for _, test := range []struct {
+		conf   string
+		errStr string
+	}{
+		{
+			conf:   `operator: verify`,
+			errStr: "a public key is required when the operator is verify",
+		},
+		{
+			conf:   `operator: sign`,
+			errStr: "a private key is required when the operator is sign",
+		},
+		{
+			conf: fmt.Sprintf(`
+operator: decrypt
+private_key_file: %v
+signature: foo
+`, alice.privateFile),
+			errStr: "a signature can only be specified when the operator is verify",
+		},
+		{
+			conf: fmt.Sprintf(`
+operator: sign
+private_key_file: %v
+`, alice.publicFile),
+			errStr: "private_key does not contain a private key",
+		},
+	} {
+		pConf, err := pgpProcConfig().ParseYAML(test.conf, nil)
+		require.NoError(t, err)
+
+		_, err = pgpProcFromParsed(pConf)
+		require.EqualError(t, err, test.errStr, test.conf)
+	}
+}
@@ -0,0 +1,381 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcp
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery/storage/apiv1/storagepb"
+	"cloud.google.com/go/bigquery/storage/managedwriter/adapt"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// bqsSchema is the protobuf representation of a table schema used for
+// encoding rows for the Storage Write API.
+type bqsSchema struct {
+	table      *storagepb.TableSchema
+	desc       protoreflect.MessageDescriptor
+	descriptor *descriptorpb.DescriptorProto
+}
+
+// bqsStringTypes are column types that are encoded as strings rather than the
+// binary encodings chosen by the adapt package, as the Storage Write API
+// accepts both and strings can be produced from JSON documents directly.
+var bqsStringTypes = map[storagepb.TableFieldSchema_Type]struct{}{
+	storagepb.TableFieldSchema_NUMERIC:    {},
+	storagepb.TableFieldSchema_BIGNUMERIC: {},
+	storagepb.TableFieldSchema_DATETIME:   {},
+	storagepb.TableFieldSchema_TIME:       {},
+	storagepb.TableFieldSchema_INTERVAL:   {},
+	storagepb.TableFieldSchema_JSON:       {},
+}
+
+func bqsStringifyFields(fields []*storagepb.TableFieldSchema) []*storagepb.TableFieldSchema {
+	out := make([]*storagepb.TableFieldSchema, len(fields))
+	for i, f := range fields {
+		f = proto.Clone(f).(*storagepb.TableFieldSchema)
+		if _, isString := bqsStringTypes[f.Type]; isString {
+			f.Type = storagepb.TableFieldSchema_STRING
+		}
+		if f.Mode == storagepb.TableFieldSchema_MODE_UNSPECIFIED {
+			f.Mode = storagepb.TableFieldSchema_NULLABLE
+		}
+		if f.Type == storagepb.TableFieldSchema_STRUCT {
+			f.Fields = bqsStringifyFields(f.Fields)
+		}
+		out[i] = f
+	}
+	return out
+}
+
+func newBQSSchema(table *storagepb.TableSchema) (*bqsSchema, error) {
+	d, err := adapt.StorageSchemaToProto2Descriptor(&storagepb.TableSchema{
+		Fields: bqsStringifyFields(table.GetFields()),
+	}, "root")
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert table schema: %w", err)
+	}
+	desc, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("unexpected descriptor type %T", d)
+	}
+	dp, err := adapt.NormalizeDescriptor(desc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize descriptor: %w", err)
+	}
+	return &bqsSchema{table: table, desc: desc, descriptor: dp}, nil
+}
+
+// errBQSUnknownField is returned when a row contains a field that does not
+// exist within the schema, which might indicate that the schema has changed.
+var errBQSUnknownField = errors.New("unknown field")
+
+// encodeRow converts a structured row into the serialised protobuf form of the
+// schema.
+func (s *bqsSchema) encodeRow(row any, ignoreUnknown bool) ([]byte, error) {
+	obj, ok := row.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected an object, got %T", row)
+	}
+	msg := dynamicpb.NewMessage(s.desc)
+	if err := bqsSetFields(msg, s.table.GetFields(), obj, ignoreUnknown, ""); err != nil {
+		return nil, err
+	}
+	return proto.Marshal(msg)
+}
+
+func bqsSetFields(msg protoreflect.Message, fields []*storagepb.TableFieldSchema, obj map[string]any, ignoreUnknown bool, prefix string) error {
+	fds := msg.Descriptor().Fields()
+
+	matched := 0
+	for i, f := range fields {
+		v, exists := obj[f.GetName()]
+		if !exists {
+			// Column names are case insensitive.
+			for k, kv := range obj {
+				if strings.EqualFold(k, f.GetName()) {
+					v, exists = kv, true
+					break
+				}
+			}
+		}
+		if !exists {
+			continue
+		}
+		matched++
+		if v == nil {
+			continue
+		}
+
+		path := prefix + f.GetName()
+		fd := fds.Get(i)
+		if f.GetMode() == storagepb.TableFieldSchema_REPEATED {
+			arr, ok := v.([]any)
+			if !ok {
+				return fmt.Errorf("field %v: expected an array, got %T", path, v)
+			}
+			list := msg.Mutable(fd).List()
+			for j, e := range arr {
+				if f.GetType() == storagepb.TableFieldSchema_STRUCT {
+					elem := list.NewElement()
+					if err := bqsSetStruct(elem.Message(), f, e, ignoreUnknown, fmt.Sprintf("%v.%v.", path, j)); err != nil {
+						return err
+					}
+					list.Append(elem)
+					continue
+				}
+				pv, err := bqsScalar(f.GetType(), e)
+				if err != nil {
+					return fmt.Errorf("field %v.%v: %w", path, j, err)
+				}
+				list.Append(pv)
+			}
+			continue
+		}
+
+		if f.GetType() == storagepb.TableFieldSchema_STRUCT {
+			if err := bqsSetStruct(msg.Mutable(fd).Message(), f, v, ignoreUnknown, path+"."); err != nil {
+				return err
+			}
+			continue
+		}
+		pv, err := bqsScalar(f.GetType(), v)
+		if err != nil {
+			return fmt.Errorf("field %v: %w", path, err)
+		}
+		msg.Set(fd, pv)
+	}
+
+	if !ignoreUnknown && matched < len(obj) {
+		for k := range obj {
+			known := false
+			for _, f := range fields {
+				if strings.EqualFold(k, f.GetName()) {
+					known = true
+					break
+				}
+			}
+			if !known {
+				return fmt.Errorf("%w: %v%v", errBQSUnknownField, prefix, k)
+			}
+		}
+	}
+	return nil
+}
+
+func bqsSetStruct(msg protoreflect.Message, f *storagepb.TableFieldSchema, v any, ignoreUnknown bool, prefix string) error {
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return fmt.Errorf("field %v: expected an object, got %T", strings.TrimSuffix(prefix, "."), v)
+	}
+	return bqsSetFields(msg, f.GetFields(), obj, ignoreUnknown, prefix)
+}
+
+func bqsInt64(v any) (int64, error) {
+	switch t := v.(type) {
+	case int64:
+		return t, nil
+	case int:
+		return int64(t), nil
+	case uint64:
+		if t > math.MaxInt64 {
+			return 0, fmt.Errorf("value %v overflows a 64-bit integer", t)
+		}
+		return int64(t), nil
+	case float64:
+		if t != math.Trunc(t) {
+			return 0, fmt.Errorf("expected an integer, got %v", t)
+		}
+		return int64(t), nil
+	case json.Number:
+		return t.Int64()
+	case string:
+		return strconv.ParseInt(t, 10, 64)
+	}
+	return 0, fmt.Errorf("expected an integer, got %T", v)
+}
+
+func bqsFloat64(v any) (float64, error) {
+	switch t := v.(type) {
+	case float64:
+		return t, nil
+	case int64:
+		return float64(t), nil
+	case int:
+		return float64(t), nil
+	case uint64:
+		return float64(t), nil
+	case json.Number:
+		return t.Float64()
+	case string:
+		return strconv.ParseFloat(t, 64)
+	}
+	return 0, fmt.Errorf("expected a number, got %T", v)
+}
+
+func bqsString(v any) (string, error) {
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case json.Number:
+		return t.String(), nil
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), nil
+	case int64:
+		return strconv.FormatInt(t, 10), nil
+	case int:
+		return strconv.Itoa(t), nil
+	case uint64:
+		return strconv.FormatUint(t, 10), nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	}
+	return "", fmt.Errorf("expected a string, got %T", v)
+}
+
+var bqsEpoch = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// bqsScalar converts a value into the protobuf value of a column type.
+func bqsScalar(t storagepb.TableFieldSchema_Type, v any) (protoreflect.Value, error) {
+	switch t {
+	case storagepb.TableFieldSchema_STRING, storagepb.TableFieldSchema_GEOGRAPHY,
+		storagepb.TableFieldSchema_NUMERIC, storagepb.TableFieldSchema_BIGNUMERIC,
+		storagepb.TableFieldSchema_INTERVAL:
+		s, err := bqsString(v)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfString(s), nil
+
+	case storagepb.TableFieldSchema_JSON:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfString(string(b)), nil
+
+	case storagepb.TableFieldSchema_DATETIME:
+		if ts, ok := v.(time.Time); ok {
+			return protoreflect.ValueOfString(ts.Format("2006-01-02 15:04:05.999999")), nil
+		}
+		s, err := bqsString(v)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfString(s), nil
+
+	case storagepb.TableFieldSchema_TIME:
+		if ts, ok := v.(time.Time); ok {
+			return protoreflect.ValueOfString(ts.Format("15:04:05.999999")), nil
+		}
+		s, err := bqsString(v)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfString(s), nil
+
+	case storagepb.TableFieldSchema_INT64:
+		i, err := bqsInt64(v)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfInt64(i), nil
+
+	case storagepb.TableFieldSchema_DOUBLE:
+		f, err := bqsFloat64(v)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfFloat64(f), nil
+
+	case storagepb.TableFieldSchema_BOOL:
+		switch b := v.(type) {
+		case bool:
+			return protoreflect.ValueOfBool(b), nil
+		case string:
+			pb, err := strconv.ParseBool(b)
+			if err != nil {
+				return protoreflect.Value{}, err
+			}
+			return protoreflect.ValueOfBool(pb), nil
+		}
+		return protoreflect.Value{}, fmt.Errorf("expected a boolean, got %T", v)
+
+	case storagepb.TableFieldSchema_BYTES:
+		switch b := v.(type) {
+		case []byte:
+			return protoreflect.ValueOfBytes(b), nil
+		case string:
+			db, err := base64.StdEncoding.DecodeString(b)
+			if err != nil {
+				return protoreflect.Value{}, fmt.Errorf("expected a base64 encoded string: %w", err)
+			}
+			return protoreflect.ValueOfBytes(db), nil
+		}
+		return protoreflect.Value{}, fmt.Errorf("expected a base64 encoded string, got %T", v)
+
+	case storagepb.TableFieldSchema_TIMESTAMP:
+		switch ts := v.(type) {
+		case time.Time:
+			return protoreflect.ValueOfInt64(ts.UnixMicro()), nil
+		case string:
+			pt, err := time.Parse(time.RFC3339Nano, ts)
+			if err != nil {
+				return protoreflect.Value{}, err
+			}
+			return protoreflect.ValueOfInt64(pt.UnixMicro()), nil
+		}
+		i, err := bqsInt64(v)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("expected an RFC 3339 timestamp or microseconds since the epoch: %w", err)
+		}
+		return protoreflect.ValueOfInt64(i), nil
+
+	case storagepb.TableFieldSchema_DATE:
+		switch ts := v.(type) {
+		case time.Time:
+			return protoreflect.ValueOfInt32(int32(bqsDays(ts))), nil
+		case string:
+			pt, err := time.Parse(time.DateOnly, ts)
+			if err != nil {
+				return protoreflect.Value{}, err
+			}
+			return protoreflect.ValueOfInt32(int32(bqsDays(pt))), nil
+		}
+		i, err := bqsInt64(v)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("expected a date or days since the epoch: %w", err)
+		}
+		return protoreflect.ValueOfInt32(int32(i)), nil
+	}
+	return protoreflect.Value{}, fmt.Errorf("unsupported column type: %v", t)
+}
+
+// bqsDays returns the number of days since the epoch of the date of a
+// timestamp in its own location.
+func bqsDays(t time.Time) int64 {
+	d := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return int64(math.Floor(d.Sub(bqsEpoch).Hours() / 24))
+}
@@ -0,0 +1,597 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcp
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/bigquery/storage/apiv1/storagepb"
+	"cloud.google.com/go/bigquery/storage/managedwriter"
+	"cloud.google.com/go/bigquery/storage/managedwriter/adapt"
+	"github.com/googleapis/gax-go/v2/apierror"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	bqsFieldProject             = "project"
+	bqsFieldDataset             = "dataset"
+	bqsFieldTable               = "table"
+	bqsFieldStreamType          = "stream_type"
+	bqsFieldIgnoreUnknownFields = "ignore_unknown_fields"
+	bqsFieldMaxInFlight         = "max_in_flight"
+	bqsFieldBatching            = "batching"
+
+	bqsStreamDefault   = "default"
+	bqsStreamCommitted = "committed"
+	bqsStreamPending   = "pending"
+
+	// bqsSchemaRefreshPeriod is the minimum period between fetches of the
+	// table schema triggered by rows containing unknown fields.
+	bqsSchemaRefreshPeriod = time.Minute
+)
+
+func bigQueryStorageOutputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("GCP", "Services").
+		Version("4.31.0").
+		Summary(`Writes messages as rows to a Google Cloud BigQuery table using the Storage Write API.`).
+		Description(`
+Each message must be a JSON object, which is converted into a row using the schema of the target table, which must already exist. The schema is obtained when the output connects, and is updated whenever the Storage Write API reports that it has changed. Messages that contain fields not present in the schema also cause the schema to be refetched (at most once per minute) before they are rejected, unless the field `+"`ignore_unknown_fields`"+` is set, in which case those fields are dropped.
+
+== Credentials
+
+By default Redpanda Connect will use a shared credentials file when connecting to GCP services. You can find out more in xref:guides:cloud/gcp.adoc[].
+
+== Stream types
+
+The field `+"`stream_type`"+` determines which kind of write stream is used:
+
+- `+"`default`"+`: Rows are appended to the default stream of the table and are visible immediately. Delivery is at-least-once, and rows may be duplicated when writes are retried.
+- `+"`committed`"+`: Rows are appended to a dedicated stream at explicit offsets and are visible immediately. A failed batch that is retried is written at the same offset, and rows already written at that offset are not duplicated. This requires that batches are written in order, and therefore `+"`max_in_flight`"+` should be set to `+"`1`"+`. When a different batch is written after a failure the output moves to a new stream, as the outcome of the failed write is unknown.
+- `+"`pending`"+`: Each batch is written to a new pending stream, which is committed once the whole batch has been appended. A batch therefore becomes visible atomically, or not at all.
+
+== Column types
+
+Values are converted to the type of the column they are written to. `+"`TIMESTAMP`"+` columns accept RFC 3339 strings or integers of microseconds since the epoch, `+"`DATE`"+` columns accept strings of the form `+"`2006-01-02`"+` or integers of days since the epoch, and `+"`BYTES`"+` columns accept base64 encoded strings. `+"`NUMERIC`"+`, `+"`BIGNUMERIC`"+`, `+"`DATETIME`"+`, `+"`TIME`"+` and `+"`INTERVAL`"+` columns accept their canonical string representations, and values of `+"`JSON`"+` columns are serialised as JSON documents.
+
+== Errors
+
+Messages that cannot be converted into rows, and rows rejected by BigQuery, are reported as individual message errors, and the remaining rows of the batch are written. These errors can be handled with the patterns outlined in xref:configuration:error_handling.adoc[].`+service.OutputPerformanceDocs(true, true)).
+		Fields(
+			service.NewStringField(bqsFieldProject).
+				Description("The project ID of the dataset to insert data to. If not set, it will be inferred from the credentials or read from the GOOGLE_CLOUD_PROJECT environment variable.").
+				Default(""),
+			service.NewStringField(bqsFieldDataset).
+				Description("The BigQuery Dataset ID."),
+			service.NewStringField(bqsFieldTable).
+				Description("The table to insert messages to."),
+			service.NewStringEnumField(bqsFieldStreamType, bqsStreamDefault, bqsStreamCommitted, bqsStreamPending).
+				Description("The type of write stream to append rows to.").
+				Default(bqsStreamDefault),
+			service.NewBoolField(bqsFieldIgnoreUnknownFields).
+				Description("Whether fields of messages that do not exist within the table schema should be dropped rather than causing the message to be rejected.").
+				Advanced().
+				Default(false),
+			service.NewIntField(bqsFieldMaxInFlight).
+				Description("The maximum number of message batches to have in flight at a given time. Increase this to improve throughput. This should be `1` when the `committed` stream type is used.").
+				Default(64),
+			service.NewBatchPolicyField(bqsFieldBatching),
+		).
+		Example("Exactly-once writes", "Rows are written to a committed stream at explicit offsets, with a single batch in flight, so that retried batches are not duplicated.", `
+output:
+  gcp_bigquery_storage:
+    project: foo
+    dataset: bar
+    table: baz
+    stream_type: committed
+    max_in_flight: 1
+    batching:
+      count: 500
+      period: 1s
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"gcp_bigquery_storage", bigQueryStorageOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (output service.BatchOutput, batchPol service.BatchPolicy, maxInFlight int, err error) {
+			if batchPol, err = conf.FieldBatchPolicy(bqsFieldBatching); err != nil {
+				return
+			}
+			if maxInFlight, err = conf.FieldInt(bqsFieldMaxInFlight); err != nil {
+				return
+			}
+			output, err = bigQueryStorageOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// bqsStream is a write stream that rows are appended to.
+type bqsStream interface {
+	// appendRows appends rows to the stream at an offset, or without an
+	// offset when it is negative. The descriptor is the schema the rows are
+	// encoded with.
+	appendRows(ctx context.Context, rows [][]byte, offset int64, dp *descriptorpb.DescriptorProto) (*storagepb.AppendRowsResponse, error)
+
+	// commit finalises the stream, and for pending streams commits the rows
+	// appended to it.
+	commit(ctx context.Context) error
+
+	close() error
+}
+
+type bigQueryStorageOutput struct {
+	project       string
+	dataset       string
+	table         string
+	streamType    string
+	ignoreUnknown bool
+	log           *service.Logger
+
+	newStream   func(ctx context.Context, streamType string, dp *descriptorpb.DescriptorProto) (bqsStream, error)
+	fetchSchema func(ctx context.Context) (*storagepb.TableSchema, error)
+
+	client   *managedwriter.Client
+	bqClient *bigquery.Client
+
+	schemaMut     sync.RWMutex
+	schema        *bqsSchema
+	schemaFetched time.Time
+
+	// Writes to committed streams are serialised and guarded by writeMut.
+	writeMut sync.Mutex
+	stream   bqsStream
+	offset   int64
+
+	// The offset and hash of the rows of the last write to a committed
+	// stream that failed, which is reused when the same rows are retried.
+	failedOffset int64
+	failedHash   uint64
+	failed       bool
+}
+
+func bigQueryStorageOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*bigQueryStorageOutput, error) {
+	o := &bigQueryStorageOutput{log: mgr.Logger()}
+
+	var err error
+	if o.project, err = conf.FieldString(bqsFieldProject); err != nil {
+		return nil, err
+	}
+	if o.project == "" {
+		o.project = bigquery.DetectProjectID
+	}
+	if o.dataset, err = conf.FieldString(bqsFieldDataset); err != nil {
+		return nil, err
+	}
+	if o.table, err = conf.FieldString(bqsFieldTable); err != nil {
+		return nil, err
+	}
+	if o.streamType, err = conf.FieldString(bqsFieldStreamType); err != nil {
+		return nil, err
+	}
+	if o.ignoreUnknown, err = conf.FieldBool(bqsFieldIgnoreUnknownFields); err != nil {
+		return nil, err
+	}
+	if o.streamType == bqsStreamCommitted {
+		if maxInFlight, _ := conf.FieldInt(bqsFieldMaxInFlight); maxInFlight > 1 {
+			o.log.Warnf("Writes to committed streams are serialised, set max_in_flight to 1")
+		}
+	}
+
+	o.newStream = o.newManagedStream
+	o.fetchSchema = o.fetchTableSchema
+	return o, nil
+}
+
+func (o *bigQueryStorageOutput) Connect(ctx context.Context) (err error) {
+	o.writeMut.Lock()
+	defer o.writeMut.Unlock()
+
+	if o.client != nil {
+		return nil
+	}
+
+	if o.bqClient, err = bigquery.NewClient(context.Background(), o.project); err != nil {
+		return fmt.Errorf("error creating big query client: %w", err)
+	}
+	if o.client, err = managedwriter.NewClient(context.Background(), o.bqClient.Project()); err != nil {
+		_ = o.bqClient.Close()
+		o.bqClient = nil
+		return fmt.Errorf("error creating storage write client: %w", err)
+	}
+
+	if err = o.refreshSchema(ctx, true); err != nil {
+		_ = o.closeClients()
+		return err
+	}
+	return nil
+}
+
+func (o *bigQueryStorageOutput) closeClients() error {
+	var err error
+	if o.client != nil {
+		err = o.client.Close()
+		o.client = nil
+	}
+	if o.bqClient != nil {
+		if cErr := o.bqClient.Close(); err == nil {
+			err = cErr
+		}
+		o.bqClient = nil
+	}
+	return err
+}
+
+func (o *bigQueryStorageOutput) fetchTableSchema(ctx context.Context) (*storagepb.TableSchema, error) {
+	md, err := o.bqClient.Dataset(o.dataset).Table(o.table).Metadata(ctx)
+	if err != nil {
+		if hasStatusCode(err, 404) {
+			return nil, fmt.Errorf("table does not exist: %v.%v", o.dataset, o.table)
+		}
+		return nil, fmt.Errorf("error fetching table metadata: %w", err)
+	}
+	return adapt.BQSchemaToStorageTableSchema(md.Schema)
+}
+
+// refreshSchema fetches the schema of the table, unless it was last fetched
+// within the refresh period and force is false.
+func (o *bigQueryStorageOutput) refreshSchema(ctx context.Context, force bool) error {
+	o.schemaMut.Lock()
+	defer o.schemaMut.Unlock()
+
+	if !force && time.Since(o.schemaFetched) < bqsSchemaRefreshPeriod {
+		return nil
+	}
+	o.schemaFetched = time.Now()
+
+	ts, err := o.fetchSchema(ctx)
+	if err != nil {
+		return err
+	}
+	s, err := newBQSSchema(ts)
+	if err != nil {
+		return err
+	}
+	o.schema = s
+	return nil
+}
+
+func (o *bigQueryStorageOutput) setSchema(ts *storagepb.TableSchema) {
+	s, err := newBQSSchema(ts)
+	if err != nil {
+		o.log.Errorf("Failed to apply updated table schema: %v", err)
+		return
+	}
+	o.schemaMut.Lock()
+	o.schema = s
+	o.schemaMut.Unlock()
+	o.log.Infof("Table schema of %v.%v has been updated", o.dataset, o.table)
+}
+
+func (o *bigQueryStorageOutput) currentSchema() *bqsSchema {
+	o.schemaMut.RLock()
+	defer o.schemaMut.RUnlock()
+	return o.schema
+}
+
+//------------------------------------------------------------------------------
+
+type bqsManagedStream struct {
+	client     *managedwriter.Client
+	ms         *managedwriter.ManagedStream
+	streamType string
+	dp         *descriptorpb.DescriptorProto
+}
+
+func (o *bigQueryStorageOutput) newManagedStream(ctx context.Context, streamType string, dp *descriptorpb.DescriptorProto) (bqsStream, error) {
+	opts := []managedwriter.WriterOption{
+		managedwriter.WithDestinationTable(managedwriter.TableParentFromParts(o.bqClient.Project(), o.dataset, o.table)),
+		managedwriter.WithSchemaDescriptor(dp),
+	}
+	switch streamType {
+	case bqsStreamDefault:
+		opts = append(opts, managedwriter.WithType(managedwriter.DefaultStream), managedwriter.EnableWriteRetries(true))
+	case bqsStreamCommitted:
+		opts = append(opts, managedwriter.WithType(managedwriter.CommittedStream))
+	case bqsStreamPending:
+		opts = append(opts, managedwriter.WithType(managedwriter.PendingStream))
+	}
+	ms, err := o.client.NewManagedStream(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating write stream: %w", err)
+	}
+	return &bqsManagedStream{client: o.client, ms: ms, streamType: streamType, dp: dp}, nil
+}
+
+func (s *bqsManagedStream) appendRows(ctx context.Context, rows [][]byte, offset int64, dp *descriptorpb.DescriptorProto) (*storagepb.AppendRowsResponse, error) {
+	var opts []managedwriter.AppendOption
+	if offset >= 0 {
+		opts = append(opts, managedwriter.WithOffset(offset))
+	}
+	if dp != s.dp {
+		opts = append(opts, managedwriter.UpdateSchemaDescriptor(dp))
+		s.dp = dp
+	}
+	res, err := s.ms.AppendRows(ctx, rows, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return res.FullResponse(ctx)
+}
+
+func (s *bqsManagedStream) commit(ctx context.Context) error {
+	if s.streamType == bqsStreamDefault {
+		return nil
+	}
+	if _, err := s.ms.Finalize(ctx); err != nil {
+		return fmt.Errorf("error finalising write stream: %w", err)
+	}
+	if s.streamType != bqsStreamPending {
+		return nil
+	}
+	resp, err := s.client.BatchCommitWriteStreams(ctx, &storagepb.BatchCommitWriteStreamsRequest{
+		Parent:       managedwriter.TableParentFromStreamName(s.ms.StreamName()),
+		WriteStreams: []string{s.ms.StreamName()},
+	})
+	if err != nil {
+		return fmt.Errorf("error committing write stream: %w", err)
+	}
+	if serrs := resp.GetStreamErrors(); len(serrs) > 0 {
+		return fmt.Errorf("error committing write stream: %v", serrs[0].GetErrorMessage())
+	}
+	return nil
+}
+
+func (s *bqsManagedStream) close() error {
+	return s.ms.Close()
+}
+
+//------------------------------------------------------------------------------
+
+// bqsStorageErrorCode returns the storage error code carried by an append
+// error, if any.
+func bqsStorageErrorCode(err error) (storagepb.StorageError_StorageErrorCode, bool) {
+	var apiErr *apierror.APIError
+	if !errors.As(err, &apiErr) {
+		return 0, false
+	}
+	se := &storagepb.StorageError{}
+	if apiErr.Details().ExtractProtoMessage(se) != nil {
+		return 0, false
+	}
+	return se.GetCode(), true
+}
+
+func bqsRowsHash(rows [][]byte) uint64 {
+	h := fnv.New64a()
+	var lenBytes [8]byte
+	for _, r := range rows {
+		binary.BigEndian.PutUint64(lenBytes[:], uint64(len(r)))
+		_, _ = h.Write(lenBytes[:])
+		_, _ = h.Write(r)
+	}
+	return h.Sum64()
+}
+
+// encodeBatch converts each message of a batch into a row, returning the rows
+// and the indexes of the messages they belong to. Messages that could not be
+// converted are flagged within the returned batch error.
+func (o *bigQueryStorageOutput) encodeBatch(ctx context.Context, batch service.MessageBatch) (rows [][]byte, indexes []int, batchErr *service.BatchError) {
+	schema := o.currentSchema()
+	refreshed := false
+	for i, msg := range batch {
+		row, err := o.encodeMessage(schema, msg)
+		if errors.Is(err, errBQSUnknownField) && !refreshed {
+			// The schema might have gained new columns since it was last
+			// fetched.
+			refreshed = true
+			if rErr := o.refreshSchema(ctx, false); rErr != nil {
+				o.log.Errorf("Failed to refresh table schema: %v", rErr)
+			} else if newSchema := o.currentSchema(); newSchema != schema {
+				// Rows must all be encoded with the same schema.
+				return o.encodeBatch(ctx, batch)
+			}
+		}
+		if err != nil {
+			if batchErr == nil {
+				batchErr = service.NewBatchError(batch, errors.New("failed to convert messages into rows"))
+			}
+			batchErr.Failed(i, err)
+			continue
+		}
+		rows = append(rows, row)
+		indexes = append(indexes, i)
+	}
+	return
+}
+
+func (o *bigQueryStorageOutput) encodeMessage(schema *bqsSchema, msg *service.Message) ([]byte, error) {
+	v, err := msg.AsStructured()
+	if err != nil {
+		return nil, err
+	}
+	return schema.encodeRow(v, o.ignoreUnknown)
+}
+
+func (o *bigQueryStorageOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	if o.currentSchema() == nil {
+		return service.ErrNotConnected
+	}
+
+	rows, indexes, batchErr := o.encodeBatch(ctx, batch)
+	if len(rows) > 0 {
+		rowErrs, err := o.writeRows(ctx, rows)
+		if err != nil {
+			return err
+		}
+		for _, re := range rowErrs {
+			if batchErr == nil {
+				batchErr = service.NewBatchError(batch, errors.New("rows were rejected"))
+			}
+			batchErr.Failed(indexes[re.GetIndex()], fmt.Errorf("row rejected (%v): %v", re.GetCode(), re.GetMessage()))
+		}
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+// writeRows appends rows to a stream. When rows are rejected none of the rows
+// are written, and so the remaining rows are appended again and the rejected
+// rows returned.
+func (o *bigQueryStorageOutput) writeRows(ctx context.Context, rows [][]byte) ([]*storagepb.RowError, error) {
+	resp, err := o.appendRows(ctx, rows)
+	rowErrs := resp.GetRowErrors()
+	if len(rowErrs) == 0 {
+		return nil, err
+	}
+
+	rejected := make(map[int64]struct{}, len(rowErrs))
+	for _, re := range rowErrs {
+		rejected[re.GetIndex()] = struct{}{}
+	}
+	remaining := make([][]byte, 0, len(rows)-len(rejected))
+	remainingIndexes := make([]int64, 0, len(rows)-len(rejected))
+	for i, r := range rows {
+		if _, exists := rejected[int64(i)]; !exists {
+			remaining = append(remaining, r)
+			remainingIndexes = append(remainingIndexes, int64(i))
+		}
+	}
+	if len(remaining) == 0 {
+		return rowErrs, nil
+	}
+
+	if resp, err = o.appendRows(ctx, remaining); err != nil {
+		if len(resp.GetRowErrors()) > 0 {
+			return nil, fmt.Errorf("rows were rejected after retrying without the rows rejected previously: %w", err)
+		}
+		return nil, err
+	}
+	return rowErrs, nil
+}
+
+func (o *bigQueryStorageOutput) appendRows(ctx context.Context, rows [][]byte) (*storagepb.AppendRowsResponse, error) {
+	if o.streamType == bqsStreamPending {
+		return o.appendPending(ctx, rows)
+	}
+
+	o.writeMut.Lock()
+	defer o.writeMut.Unlock()
+
+	offset := int64(-1)
+	var hash uint64
+	if o.streamType == bqsStreamCommitted {
+		hash = bqsRowsHash(rows)
+		if o.failed {
+			o.failed = false
+			if o.failedHash == hash {
+				o.offset = o.failedOffset
+			} else if o.stream != nil {
+				// The outcome of the failed write is unknown, and therefore
+				// new rows cannot be written at its offset.
+				if err := o.stream.commit(ctx); err != nil {
+					o.log.Warnf("Failed to finalise write stream: %v", err)
+				}
+				_ = o.stream.close()
+				o.stream = nil
+			}
+		}
+		offset = o.offset
+	}
+
+	schema := o.currentSchema()
+	if o.stream == nil {
+		s, err := o.newStream(ctx, o.streamType, schema.descriptor)
+		if err != nil {
+			return nil, err
+		}
+		o.stream, o.offset = s, 0
+		if o.streamType == bqsStreamCommitted {
+			offset = 0
+		}
+	}
+
+	resp, err := o.stream.appendRows(ctx, rows, offset, schema.descriptor)
+	if ts := resp.GetUpdatedSchema(); ts != nil {
+		o.setSchema(ts)
+	}
+	if err != nil && o.streamType == bqsStreamCommitted {
+		if code, ok := bqsStorageErrorCode(err); ok && code == storagepb.StorageError_OFFSET_ALREADY_EXISTS {
+			// The rows were written by a previous attempt.
+			err = nil
+		}
+	}
+	if err != nil {
+		if o.streamType == bqsStreamCommitted && len(resp.GetRowErrors()) == 0 {
+			o.failed, o.failedOffset, o.failedHash = true, offset, hash
+		}
+		return resp, err
+	}
+	if o.streamType == bqsStreamCommitted {
+		o.offset = offset + int64(len(rows))
+	}
+	return resp, nil
+}
+
+func (o *bigQueryStorageOutput) appendPending(ctx context.Context, rows [][]byte) (*storagepb.AppendRowsResponse, error) {
+	schema := o.currentSchema()
+	s, err := o.newStream(ctx, bqsStreamPending, schema.descriptor)
+	if err != nil {
+		return nil, err
+	}
+	defer s.close()
+
+	resp, err := s.appendRows(ctx, rows, 0, schema.descriptor)
+	if ts := resp.GetUpdatedSchema(); ts != nil {
+		o.setSchema(ts)
+	}
+	if err != nil {
+		return resp, err
+	}
+	return resp, s.commit(ctx)
+}
+
+func (o *bigQueryStorageOutput) Close(ctx context.Context) error {
+	o.writeMut.Lock()
+	defer o.writeMut.Unlock()
+
+	if o.stream != nil {
+		if o.streamType == bqsStreamCommitted {
+			if err := o.stream.commit(ctx); err != nil {
+				o.log.Warnf("Failed to finalise write stream: %v", err)
+			}
+		}
+		_ = o.stream.close()
+		o.stream = nil
+	}
+	return o.closeClients()
+}
@@ -0,0 +1,466 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery/storage/apiv1/storagepb"
+	"github.com/googleapis/gax-go/v2/apierror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type fakeBQSAppend struct {
+	rows   [][]byte
+	offset int64
+	dp     *descriptorpb.DescriptorProto
+}
+
+type fakeBQSResult struct {
+	resp *storagepb.AppendRowsResponse
+	err  error
+}
+
+type fakeBQSStream struct {
+	id        int
+	appends   []fakeBQSAppend
+	results   []fakeBQSResult
+	committed bool
+	closed    bool
+}
+
+func (f *fakeBQSStream) appendRows(ctx context.Context, rows [][]byte, offset int64, dp *descriptorpb.DescriptorProto) (*storagepb.AppendRowsResponse, error) {
+	f.appends = append(f.appends, fakeBQSAppend{rows: rows, offset: offset, dp: dp})
+	if len(f.results) == 0 {
+		return &storagepb.AppendRowsResponse{}, nil
+	}
+	res := f.results[0]
+	f.results = f.results[1:]
+	return res.resp, res.err
+}
+
+func (f *fakeBQSStream) commit(ctx context.Context) error {
+	f.committed = true
+	return nil
+}
+
+func (f *fakeBQSStream) close() error {
+	f.closed = true
+	return nil
+}
+
+type fakeBQS struct {
+	schema  *storagepb.TableSchema
+	fetches int
+	streams []*fakeBQSStream
+
+	// Results assigned to the next stream created.
+	results []fakeBQSResult
+}
+
+// attach replaces the BigQuery clients of an output with the fake and loads
+// the table schema from it.
+func (f *fakeBQS) attach(t testing.TB, o *bigQueryStorageOutput) {
+	t.Helper()
+
+	o.fetchSchema = func(ctx context.Context) (*storagepb.TableSchema, error) {
+		f.fetches++
+		return f.schema, nil
+	}
+	o.newStream = func(ctx context.Context, streamType string, dp *descriptorpb.DescriptorProto) (bqsStream, error) {
+		s := &fakeBQSStream{id: len(f.streams), results: f.results}
+		f.results = nil
+		f.streams = append(f.streams, s)
+		return s, nil
+	}
+	require.NoError(t, o.refreshSchema(context.Background(), true))
+}
+
+func bqsBatch(docs ...string) (b service.MessageBatch) {
+	for _, d := range docs {
+		b = append(b, service.NewMessage([]byte(d)))
+	}
+	return
+}
+
+func bqsDecode(t testing.TB, o *bigQueryStorageOutput, row []byte) map[string]any {
+	t.Helper()
+
+	msg := dynamicpb.NewMessage(o.currentSchema().desc)
+	require.NoError(t, proto.Unmarshal(row, msg))
+
+	out := map[string]any{}
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsList():
+			var l []any
+			for i := 0; i < v.List().Len(); i++ {
+				l = append(l, v.List().Get(i).Interface())
+			}
+			out[string(fd.Name())] = l
+		case fd.Message() != nil:
+			sub := map[string]any{}
+			v.Message().Range(func(sfd protoreflect.FieldDescriptor, sv protoreflect.Value) bool {
+				sub[string(sfd.Name())] = sv.Interface()
+				return true
+			})
+			out[string(fd.Name())] = sub
+		default:
+			out[string(fd.Name())] = v.Interface()
+		}
+		return true
+	})
+	return out
+}
+
+func bqsTestSchema() *storagepb.TableSchema {
+	return &storagepb.TableSchema{
+		Fields: []*storagepb.TableFieldSchema{
+			{Name: "name", Type: storagepb.TableFieldSchema_STRING, Mode: storagepb.TableFieldSchema_REQUIRED},
+			{Name: "age", Type: storagepb.TableFieldSchema_INT64},
+		},
+	}
+}
+
+func TestBigQueryStorageRowConversion(t *testing.T) {
+	f := &fakeBQS{schema: &storagepb.TableSchema{
+		Fields: []*storagepb.TableFieldSchema{
+			{Name: "name", Type: storagepb.TableFieldSchema_STRING},
+			{Name: "age", Type: storagepb.TableFieldSchema_INT64},
+			{Name: "score", Type: storagepb.TableFieldSchema_DOUBLE},
+			{Name: "active", Type: storagepb.TableFieldSchema_BOOL},
+			{Name: "price", Type: storagepb.TableFieldSchema_NUMERIC},
+			{Name: "created", Type: storagepb.TableFieldSchema_TIMESTAMP},
+			{Name: "day", Type: storagepb.TableFieldSchema_DATE},
+			{Name: "raw", Type: storagepb.TableFieldSchema_BYTES},
+			{Name: "doc", Type: storagepb.TableFieldSchema_JSON},
+			{Name: "tags", Type: storagepb.TableFieldSchema_STRING, Mode: storagepb.TableFieldSchema_REPEATED},
+			{Name: "address", Type: storagepb.TableFieldSchema_STRUCT, Fields: []*storagepb.TableFieldSchema{
+				{Name: "city", Type: storagepb.TableFieldSchema_STRING},
+			}},
+		},
+	}}
+	conf, err := bigQueryStorageOutputConfig().ParseYAML(`
+dataset: foo
+table: bar
+`, nil)
+	require.NoError(t, err)
+
+	o, err := bigQueryStorageOutputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	f.attach(t, o)
+
+	require.NoError(t, o.WriteBatch(context.Background(), bqsBatch(`{
+  "name": "alice",
+  "AGE": 30,
+  "score": 1.5,
+  "active": true,
+  "price": 12.25,
+  "created": "2024-01-02T03:04:05.000006Z",
+  "day": "1970-01-11",
+  "raw": "aGVsbG8=",
+  "doc": {"a":[1,2]},
+  "tags": ["x","y"],
+  "address": {"city": "london"}
+}`)))
+
+	require.Len(t, f.streams, 1)
+	require.Len(t, f.streams[0].appends, 1)
+	assert.Equal(t, int64(-1), f.streams[0].appends[0].offset)
+	require.Len(t, f.streams[0].appends[0].rows, 1)
+
+	assert.Equal(t, map[string]any{
+		"name":    "alice",
+		"age":     int64(30),
+		"score":   1.5,
+		"active":  true,
+		"price":   "12.25",
+		"created": int64(1704164645000006),
+		"day":     int32(10),
+		"raw":     []byte("hello"),
+		"doc":     `{"a":[1,2]}`,
+		"tags":    []any{"x", "y"},
+		"address": map[string]any{"city": "london"},
+	}, bqsDecode(t, o, f.streams[0].appends[0].rows[0]))
+}
+
+func TestBigQueryStorageConversionErrors(t *testing.T) {
+	f := &fakeBQS{schema: bqsTestSchema()}
+	conf, err := bigQueryStorageOutputConfig().ParseYAML(`
+dataset: foo
+table: bar
+`, nil)
+	require.NoError(t, err)
+
+	o, err := bigQueryStorageOutputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	f.attach(t, o)
+
+	// Allow the schema to be refreshed immediately.
+	o.schemaFetched = time.Time{}
+
+	err = o.WriteBatch(context.Background(), bqsBatch(
+		`{"name":"a","age":1}`,
+		`{"name":"b","age":"nope"}`,
+		`{"name":"c","nope":true}`,
+		`not json`,
+	))
+	require.Error(t, err)
+
+	var bErr *service.BatchError
+	require.ErrorAs(t, err, &bErr)
+	assert.Equal(t, 3, bErr.IndexedErrors())
+
+	failed := map[int]string{}
+	bErr.WalkMessages(func(i int, m *service.Message, err error) bool {
+		if err != nil {
+			failed[i] = err.Error()
+		}
+		return true
+	})
+	assert.Len(t, failed, 3)
+	assert.Contains(t, failed[1], "field age")
+	assert.Contains(t, failed[2], "unknown field: nope")
+
+	// The unknown field triggered a refresh of the schema, which is limited
+	// to once per refresh period.
+	assert.Equal(t, 2, f.fetches)
+	require.Error(t, o.WriteBatch(context.Background(), bqsBatch(`{"name":"c","nope":true}`)))
+	assert.Equal(t, 2, f.fetches)
+
+	// Valid rows are still written.
+	require.Len(t, f.streams, 1)
+	require.Len(t, f.streams[0].appends, 1)
+	assert.Len(t, f.streams[0].appends[0].rows, 1)
+}
+
+func TestBigQueryStorageIgnoreUnknownFields(t *testing.T) {
+	f := &fakeBQS{schema: bqsTestSchema()}
+	conf, err := bigQueryStorageOutputConfig().ParseYAML(`
+dataset: foo
+table: bar
+ignore_unknown_fields: true
+`, nil)
+	require.NoError(t, err)
+
+	o, err := bigQueryStorageOutputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	f.attach(t, o)
+
+	require.NoError(t, o.WriteBatch(context.Background(), bqsBatch(`{"name":"a","nope":true}`)))
+	assert.Equal(t, map[string]any{"name": "a"}, bqsDecode(t, o, f.streams[0].appends[0].rows[0]))
+	assert.Equal(t, 1, f.fetches)
+}
+
+func TestBigQueryStorageRowErrors(t *testing.T) {
+	f := &fakeBQS{schema: bqsTestSchema()}
+	conf, err := bigQueryStorageOutputConfig().ParseYAML(`
+dataset: foo
+table: bar
+stream_type: committed
+max_in_flight: 1
+`, nil)
+	require.NoError(t, err)
+
+	o, err := bigQueryStorageOutputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	f.attach(t, o)
+
+	f.results = []fakeBQSResult{{
+		resp: &storagepb.AppendRowsResponse{
+			RowErrors: []*storagepb.RowError{
+				{Index: 1, Code: storagepb.RowError_FIELDS_ERROR, Message: "bad value"},
+			},
+		},
+		err: errors.New("rows were invalid"),
+	}}
+
+	err = o.WriteBatch(context.Background(), bqsBatch(
+		`{"name":"a"}`,
+		`{"age":"nope"}`,
+		`{"name":"b"}`,
+		`{"name":"c"}`,
+	))
+	require.Error(t, err)
+
+	var bErr *service.BatchError
+	require.ErrorAs(t, err, &bErr)
+
+	failed := map[int]string{}
+	bErr.WalkMessages(func(i int, m *service.Message, err error) bool {
+		if err != nil {
+			failed[i] = err.Error()
+		}
+		return true
+	})
+	require.Len(t, failed, 2)
+	assert.Contains(t, failed[1], "field age")
+	assert.Contains(t, failed[2], "bad value")
+
+	// The remaining rows are appended again at the same offset.
+	require.Len(t, f.streams, 1)
+	appends := f.streams[0].appends
+	require.Len(t, appends, 2)
+	assert.Len(t, appends[0].rows, 3)
+	assert.Equal(t, int64(0), appends[0].offset)
+	require.Len(t, appends[1].rows, 2)
+	assert.Equal(t, int64(0), appends[1].offset)
+	assert.Equal(t, "a", bqsDecode(t, o, appends[1].rows[0])["name"])
+	assert.Equal(t, "c", bqsDecode(t, o, appends[1].rows[1])["name"])
+	assert.Equal(t, int64(2), o.offset)
+}
+
+func TestBigQueryStorageCommittedOffsets(t *testing.T) {
+	tCtx := context.Background()
+
+	f := &fakeBQS{schema: bqsTestSchema()}
+	conf, err := bigQueryStorageOutputConfig().ParseYAML(`
+dataset: foo
+table: bar
+stream_type: committed
+max_in_flight: 1
+`, nil)
+	require.NoError(t, err)
+
+	o, err := bigQueryStorageOutputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	f.attach(t, o)
+
+	st, sErr := status.New(codes.AlreadyExists, "offset already exists").WithDetails(&storagepb.StorageError{
+		Code: storagepb.StorageError_OFFSET_ALREADY_EXISTS,
+	})
+	require.NoError(t, sErr)
+	existsErr, ok := apierror.FromError(st.Err())
+	require.True(t, ok)
+
+	f.results = []fakeBQSResult{
+		{},
+		{err: errors.New("connection lost")},
+		{err: existsErr},
+		{err: errors.New("connection lost")},
+	}
+
+	require.NoError(t, o.WriteBatch(tCtx, bqsBatch(`{"name":"a"}`, `{"name":"b"}`)))
+
+	// A failed write is retried at the same offset, and an existing offset
+	// means the rows were written previously.
+	require.Error(t, o.WriteBatch(tCtx, bqsBatch(`{"name":"c"}`)))
+	require.NoError(t, o.WriteBatch(tCtx, bqsBatch(`{"name":"c"}`)))
+
+	require.Len(t, f.streams, 1)
+	var offsets []int64
+	for _, a := range f.streams[0].appends {
+		offsets = append(offsets, a.offset)
+	}
+	assert.Equal(t, []int64{0, 2, 2}, offsets)
+	assert.Equal(t, int64(3), o.offset)
+
+	// Writing different rows after a failure moves to a new stream.
+	require.Error(t, o.WriteBatch(tCtx, bqsBatch(`{"name":"d"}`)))
+	require.NoError(t, o.WriteBatch(tCtx, bqsBatch(`{"name":"e"}`)))
+
+	require.Len(t, f.streams, 2)
+	assert.True(t, f.streams[0].committed)
+	assert.True(t, f.streams[0].closed)
+	require.Len(t, f.streams[1].appends, 1)
+	assert.Equal(t, int64(0), f.streams[1].appends[0].offset)
+	assert.Equal(t, int64(1), o.offset)
+
+	require.NoError(t, o.Close(tCtx))
+	assert.True(t, f.streams[1].committed)
+	assert.True(t, f.streams[1].closed)
+}
+
+func TestBigQueryStoragePendingStreams(t *testing.T) {
+	tCtx := context.Background()
+
+	f := &fakeBQS{schema: bqsTestSchema()}
+	conf, err := bigQueryStorageOutputConfig().ParseYAML(`
+dataset: foo
+table: bar
+stream_type: pending
+`, nil)
+	require.NoError(t, err)
+
+	o, err := bigQueryStorageOutputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	f.attach(t, o)
+
+	require.NoError(t, o.WriteBatch(tCtx, bqsBatch(`{"name":"a"}`, `{"name":"b"}`)))
+	require.NoError(t, o.WriteBatch(tCtx, bqsBatch(`{"name":"c"}`)))
+
+	require.Len(t, f.streams, 2)
+	for _, s := range f.streams {
+		require.Len(t, s.appends, 1)
+		assert.Equal(t, int64(0), s.appends[0].offset)
+		assert.True(t, s.committed)
+		assert.True(t, s.closed)
+	}
+}
+
+func TestBigQueryStorageSchemaUpdate(t *testing.T) {
+	tCtx := context.Background()
+
+	f := &fakeBQS{schema: bqsTestSchema()}
+	conf, err := bigQueryStorageOutputConfig().ParseYAML(`
+dataset: foo
+table: bar
+`, nil)
+	require.NoError(t, err)
+
+	o, err := bigQueryStorageOutputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	f.attach(t, o)
+
+	updated := bqsTestSchema()
+	updated.Fields = append(updated.Fields, &storagepb.TableFieldSchema{
+		Name: "email", Type: storagepb.TableFieldSchema_STRING,
+	})
+	f.results = []fakeBQSResult{{
+		resp: &storagepb.AppendRowsResponse{UpdatedSchema: updated},
+	}}
+
+	require.NoError(t, o.WriteBatch(tCtx, bqsBatch(`{"name":"a"}`)))
+	require.NoError(t, o.WriteBatch(tCtx, bqsBatch(`{"name":"b","email":"b@example.com"}`)))
+
+	require.Len(t, f.streams, 1)
+	appends := f.streams[0].appends
+	require.Len(t, appends, 2)
+	assert.NotSame(t, appends[0].dp, appends[1].dp)
+	assert.Equal(t, map[string]any{
+		"name":  "b",
+		"email": "b@example.com",
+	}, bqsDecode(t, o, appends[1].rows[0]))
+	assert.Equal(t, 1, f.fetches)
+}
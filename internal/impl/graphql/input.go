@@ -0,0 +1,460 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/checkpoint"
+	"github.com/Jeffail/gabs/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	gqlFieldURL             = "url"
+	gqlFieldQuery           = "query"
+	gqlFieldVariables       = "variables"
+	gqlFieldCursorVariable  = "cursor_variable"
+	gqlFieldNodesPath       = "nodes_path"
+	gqlFieldEndCursorPath   = "end_cursor_path"
+	gqlFieldHasNextPagePath = "has_next_page_path"
+	gqlFieldHeaders         = "headers"
+	gqlFieldPollInterval    = "poll_interval"
+	gqlFieldCache           = "cache"
+	gqlFieldCacheKey        = "cache_key"
+	gqlFieldTimeout         = "timeout"
+	gqlFieldTLS             = "tls"
+)
+
+func inputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Network").
+		Version("4.31.0").
+		Summary("Executes a GraphQL query against an endpoint, following cursor based pagination and emitting each node of the results as a message.").
+		Description(`
+Each page of results is emitted as a batch of messages, one for each node found at the path `+"`nodes_path`"+`. The cursor found at `+"`end_cursor_path`"+` is provided to the next query as the variable `+"`cursor_variable`"+`, and pages are requested until the value at `+"`has_next_page_path`"+` is false.
+
+Once all pages have been consumed the input shuts down, unless a `+"`poll_interval`"+` is set, in which case the query is executed again from the last cursor after the interval has passed in order to consume newly added nodes.
+
+== Checkpointing
+
+When a `+"`cache`"+` is configured the end cursor of each page is stored within it once the messages of that page, and all pages before it, have been acknowledged. The cursor is read from the cache when the input connects, allowing it to resume where it left off after a restart.
+
+== Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- graphql_cursor
+`+"```"+`
+
+Where `+"`graphql_cursor`"+` is the end cursor of the page that the node was part of.`).
+		Fields(
+			service.NewURLField(gqlFieldURL).
+				Description("The URL of the GraphQL endpoint.").
+				Example("https://api.example.com/graphql"),
+			service.NewStringField(gqlFieldQuery).
+				Description("The GraphQL query to execute, which should accept the cursor variable and select the page info of the connection being paginated.").
+				Example(`query Issues($cursor: String) {
+  issues(first: 100, after: $cursor) {
+    nodes { id title }
+    pageInfo { endCursor hasNextPage }
+  }
+}`),
+			service.NewBloblangField(gqlFieldVariables).
+				Description("An optional xref:guides:bloblang/about.adoc[Bloblang mapping] which should evaluate to an object of variables to provide with each query. The mapping is executed for each request, and the cursor variable is set after the mapping is applied.").
+				Example(`root.since = now().ts_sub_iso8601("P1D")`).
+				Optional(),
+			service.NewStringField(gqlFieldCursorVariable).
+				Description("The name of the query variable used for providing the cursor of the next page.").
+				Default("cursor"),
+			service.NewStringField(gqlFieldNodesPath).
+				Description("A dot path within the response that identifies the array of nodes to emit as messages.").
+				Example("data.issues.nodes"),
+			service.NewStringField(gqlFieldEndCursorPath).
+				Description("A dot path within the response that identifies the end cursor of the page.").
+				Example("data.issues.pageInfo.endCursor"),
+			service.NewStringField(gqlFieldHasNextPagePath).
+				Description("A dot path within the response that identifies whether another page of results is available.").
+				Example("data.issues.pageInfo.hasNextPage"),
+			service.NewInterpolatedStringMapField(gqlFieldHeaders).
+				Description("A map of headers to add to each request, which can be used for providing authentication.").
+				Example(map[string]any{
+					"Authorization": `Bearer ${! env("GRAPHQL_TOKEN") }`,
+				}).
+				Default(map[string]any{}),
+			service.NewDurationField(gqlFieldPollInterval).
+				Description("An optional period of time to wait after consuming all pages before executing the query again from the last cursor. When not set the input shuts down once all pages have been consumed.").
+				Example("5m").
+				Optional(),
+			service.NewStringField(gqlFieldCache).
+				Description("An optional cache resource used for storing the end cursor of the last page consumed, allowing polling to resume after a restart.").
+				Optional(),
+			service.NewStringField(gqlFieldCacheKey).
+				Description("The key identifier used when storing the cursor of the last page consumed.").
+				Default("graphql_cursor").
+				Advanced(),
+			service.NewDurationField(gqlFieldTimeout).
+				Description("The maximum period of time to wait for each request.").
+				Default("30s").
+				Advanced(),
+			service.NewTLSToggledField(gqlFieldTLS),
+			service.NewAutoRetryNacksToggleField(),
+		).
+		Example("Poll issues", "Consume all issues of a project and then check for new issues every five minutes, storing the cursor in a file so that a restart doesn't result in duplicates.", `
+input:
+  graphql:
+    url: https://api.example.com/graphql
+    query: |
+      query Issues($cursor: String) {
+        issues(first: 100, after: $cursor) {
+          nodes { id title createdAt }
+          pageInfo { endCursor hasNextPage }
+        }
+      }
+    nodes_path: data.issues.nodes
+    end_cursor_path: data.issues.pageInfo.endCursor
+    has_next_page_path: data.issues.pageInfo.hasNextPage
+    headers:
+      Authorization: Bearer ${! env("GRAPHQL_TOKEN") }
+    poll_interval: 5m
+    cache: cursors
+
+cache_resources:
+  - label: cursors
+    file:
+      directory: ./cursors
+`)
+}
+
+func init() {
+	err := service.RegisterBatchInput(
+		"graphql", inputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+			i, err := newInputFromConfig(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksBatchedToggled(conf, i)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type graphqlInput struct {
+	url            string
+	query          string
+	variables      *bloblang.Executor
+	cursorVariable string
+	nodesPath      string
+	endCursorPath  string
+	hasNextPath    string
+	headers        map[string]*service.InterpolatedString
+	pollInterval   time.Duration
+	cache          string
+	cacheKey       string
+
+	client       *http.Client
+	mgr          *service.Resources
+	log          *service.Logger
+	checkpointer *checkpoint.Capped[string]
+
+	stateMut  sync.Mutex
+	connected bool
+	cursor    string
+	exhausted bool
+}
+
+func newInputFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*graphqlInput, error) {
+	g := &graphqlInput{
+		mgr:          mgr,
+		log:          mgr.Logger(),
+		checkpointer: checkpoint.NewCapped[string](1024),
+	}
+
+	u, err := conf.FieldURL(gqlFieldURL)
+	if err != nil {
+		return nil, err
+	}
+	g.url = u.String()
+
+	if g.query, err = conf.FieldString(gqlFieldQuery); err != nil {
+		return nil, err
+	}
+	if conf.Contains(gqlFieldVariables) {
+		if g.variables, err = conf.FieldBloblang(gqlFieldVariables); err != nil {
+			return nil, err
+		}
+	}
+	if g.cursorVariable, err = conf.FieldString(gqlFieldCursorVariable); err != nil {
+		return nil, err
+	}
+	if g.nodesPath, err = conf.FieldString(gqlFieldNodesPath); err != nil {
+		return nil, err
+	}
+	if g.endCursorPath, err = conf.FieldString(gqlFieldEndCursorPath); err != nil {
+		return nil, err
+	}
+	if g.hasNextPath, err = conf.FieldString(gqlFieldHasNextPagePath); err != nil {
+		return nil, err
+	}
+	if g.headers, err = conf.FieldInterpolatedStringMap(gqlFieldHeaders); err != nil {
+		return nil, err
+	}
+	if conf.Contains(gqlFieldPollInterval) {
+		if g.pollInterval, err = conf.FieldDuration(gqlFieldPollInterval); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(gqlFieldCache) {
+		if g.cache, err = conf.FieldString(gqlFieldCache); err != nil {
+			return nil, err
+		}
+		if !mgr.HasCache(g.cache) {
+			return nil, fmt.Errorf("cache resource '%v' was not found", g.cache)
+		}
+	}
+	if g.cacheKey, err = conf.FieldString(gqlFieldCacheKey); err != nil {
+		return nil, err
+	}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(gqlFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := conf.FieldDuration(gqlFieldTimeout)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsEnabled {
+		transport.TLSClientConfig = tlsConf
+	}
+	g.client = &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}
+	return g, nil
+}
+
+func (g *graphqlInput) Connect(ctx context.Context) error {
+	g.stateMut.Lock()
+	defer g.stateMut.Unlock()
+	if g.connected || g.cache == "" {
+		g.connected = true
+		return nil
+	}
+
+	// Obtain the cursor of the last page we've already consumed.
+	var cursorBytes []byte
+	var cacheErr error
+	err := g.mgr.AccessCache(ctx, g.cache, func(c service.Cache) {
+		if cursorBytes, cacheErr = c.Get(ctx, g.cacheKey); errors.Is(cacheErr, service.ErrKeyNotFound) {
+			cacheErr = nil
+		}
+	})
+	if err == nil {
+		err = cacheErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to obtain last cursor: %w", err)
+	}
+
+	g.cursor = string(cursorBytes)
+	g.connected = true
+	return nil
+}
+
+type graphqlPage struct {
+	nodes       []any
+	endCursor   string
+	hasNextPage bool
+}
+
+func (g *graphqlInput) requestBody() ([]byte, error) {
+	variables := map[string]any{}
+	if g.variables != nil {
+		v, err := g.variables.Query(nil)
+		if err != nil {
+			return nil, fmt.Errorf("variables mapping failed: %w", err)
+		}
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("variables mapping returned non-object result: %T", v)
+		}
+		for k, v := range obj {
+			variables[k] = v
+		}
+	}
+	if g.cursor != "" {
+		variables[g.cursorVariable] = g.cursor
+	} else {
+		variables[g.cursorVariable] = nil
+	}
+	return json.Marshal(map[string]any{
+		"query":     g.query,
+		"variables": variables,
+	})
+}
+
+func (g *graphqlInput) fetchPage(ctx context.Context) (*graphqlPage, error) {
+	body, err := g.requestBody()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	headerMsg := service.NewMessage(nil)
+	for k, v := range g.headers {
+		hv, err := v.TryString(headerMsg)
+		if err != nil {
+			return nil, fmt.Errorf("header '%v' interpolation error: %w", k, err)
+		}
+		req.Header.Set(k, hv)
+	}
+
+	res, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	resBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("graphql request returned status %v: %s", res.StatusCode, strings.TrimSpace(string(resBytes)))
+	}
+
+	resObj, err := gabs.ParseJSON(resBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if errs, _ := resObj.S("errors").Data().([]any); len(errs) > 0 {
+		errBytes, _ := json.Marshal(errs)
+		return nil, fmt.Errorf("graphql query returned errors: %s", errBytes)
+	}
+
+	var page graphqlPage
+	switch t := resObj.Path(g.nodesPath).Data().(type) {
+	case []any:
+		page.nodes = t
+	case nil:
+	default:
+		return nil, fmt.Errorf("expected array at path '%v', got %T", g.nodesPath, t)
+	}
+	switch t := resObj.Path(g.endCursorPath).Data().(type) {
+	case string:
+		page.endCursor = t
+	case nil:
+	default:
+		return nil, fmt.Errorf("expected string at path '%v', got %T", g.endCursorPath, t)
+	}
+	switch t := resObj.Path(g.hasNextPath).Data().(type) {
+	case bool:
+		page.hasNextPage = t
+	case nil:
+	default:
+		return nil, fmt.Errorf("expected boolean at path '%v', got %T", g.hasNextPath, t)
+	}
+	return &page, nil
+}
+
+func (g *graphqlInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	g.stateMut.Lock()
+	defer g.stateMut.Unlock()
+	if !g.connected {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	for {
+		if g.exhausted {
+			if g.pollInterval <= 0 {
+				return nil, nil, service.ErrEndOfInput
+			}
+			select {
+			case <-time.After(g.pollInterval):
+			case <-ctx.Done():
+				return nil, nil, ctx.Err()
+			}
+			g.exhausted = false
+		}
+
+		page, err := g.fetchPage(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if page.endCursor != "" {
+			g.cursor = page.endCursor
+		}
+		g.exhausted = !page.hasNextPage
+		if len(page.nodes) == 0 {
+			continue
+		}
+
+		batch := make(service.MessageBatch, 0, len(page.nodes))
+		for _, n := range page.nodes {
+			msg := service.NewMessage(nil)
+			msg.SetStructuredMut(n)
+			msg.MetaSetMut("graphql_cursor", g.cursor)
+			batch = append(batch, msg)
+		}
+
+		release, err := g.checkpointer.Track(ctx, g.cursor, 1)
+		if err != nil {
+			return nil, nil, err
+		}
+		return batch, func(ctx context.Context, err error) error {
+			highest := release()
+			if highest == nil || g.cache == "" {
+				return nil
+			}
+			var setErr error
+			if err := g.mgr.AccessCache(ctx, g.cache, func(c service.Cache) {
+				setErr = c.Set(ctx, g.cacheKey, []byte(*highest), nil)
+			}); err != nil {
+				return err
+			}
+			return setErr
+		}, nil
+	}
+}
+
+func (g *graphqlInput) Close(ctx context.Context) error {
+	g.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,228 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type testGraphQLServer struct {
+	mut     sync.Mutex
+	pages   map[string][]string
+	next    map[string]string
+	cursors []any
+	headers []string
+}
+
+func (s *testGraphQLServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Query     string         `json:"query"`
+		Variables map[string]any `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.cursors = append(s.cursors, req.Variables["after"])
+	s.headers = append(s.headers, r.Header.Get("Authorization"))
+
+	cursor, _ := req.Variables["after"].(string)
+	var nodes []any
+	for _, id := range s.pages[cursor] {
+		nodes = append(nodes, map[string]any{"id": id})
+	}
+	endCursor, hasNext := s.next[cursor]
+
+	var endCursorV any
+	if endCursor != "" {
+		endCursorV = endCursor
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"data": map[string]any{
+			"items": map[string]any{
+				"nodes": nodes,
+				"pageInfo": map[string]any{
+					"endCursor":   endCursorV,
+					"hasNextPage": hasNext && s.pages[endCursor] != nil,
+				},
+			},
+		},
+	})
+}
+
+func readIDs(t testing.TB, batch service.MessageBatch) (ids []string) {
+	t.Helper()
+	for _, msg := range batch {
+		v, err := msg.AsStructured()
+		require.NoError(t, err)
+		ids = append(ids, v.(map[string]any)["id"].(string))
+	}
+	return
+}
+
+func TestGraphQLInputPagination(t *testing.T) {
+	srv := &testGraphQLServer{
+		pages: map[string][]string{
+			"":   {"a", "b"},
+			"c1": {"c", "d"},
+			"c2": {"e"},
+		},
+		next: map[string]string{
+			"":   "c1",
+			"c1": "c2",
+			"c2": "c3",
+		},
+	}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	ctx := context.Background()
+	conf, err := inputConfig().ParseYAML(fmt.Sprintf(`
+url: %v
+query: 'query Items($after: String) { items(after: $after) { nodes { id } pageInfo { endCursor hasNextPage } } }'
+cursor_variable: after
+nodes_path: data.items.nodes
+end_cursor_path: data.items.pageInfo.endCursor
+has_next_page_path: data.items.pageInfo.hasNextPage
+headers:
+  Authorization: 'Bearer meow'
+`, ts.URL), nil)
+	require.NoError(t, err)
+
+	i, err := newInputFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, i.Connect(ctx))
+
+	var ids []string
+	for {
+		batch, ackFn, err := i.ReadBatch(ctx)
+		if errors.Is(err, service.ErrEndOfInput) {
+			break
+		}
+		require.NoError(t, err)
+		ids = append(ids, readIDs(t, batch)...)
+		require.NoError(t, ackFn(ctx, nil))
+	}
+	require.NoError(t, i.Close(ctx))
+
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, ids)
+	assert.Equal(t, []any{nil, "c1", "c2"}, srv.cursors)
+	assert.Equal(t, []string{"Bearer meow", "Bearer meow", "Bearer meow"}, srv.headers)
+}
+
+func TestGraphQLInputCheckpointing(t *testing.T) {
+	srv := &testGraphQLServer{
+		pages: map[string][]string{
+			"":   {"a", "b"},
+			"c1": {"c"},
+		},
+		next: map[string]string{
+			"":   "c1",
+			"c1": "c2",
+		},
+	}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	ctx := context.Background()
+	mgr := service.MockResources(service.MockResourcesOptAddCache("foocache"))
+
+	conf, err := inputConfig().ParseYAML(fmt.Sprintf(`
+url: %v
+query: 'query Items($after: String) { items(after: $after) { nodes { id } pageInfo { endCursor hasNextPage } } }'
+cursor_variable: after
+nodes_path: data.items.nodes
+end_cursor_path: data.items.pageInfo.endCursor
+has_next_page_path: data.items.pageInfo.hasNextPage
+cache: foocache
+cache_key: foo
+`, ts.URL), nil)
+	require.NoError(t, err)
+
+	i, err := newInputFromConfig(conf, mgr)
+	require.NoError(t, err)
+	require.NoError(t, i.Connect(ctx))
+
+	batch, ackFn, err := i.ReadBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, readIDs(t, batch))
+	v, _ := batch[0].MetaGetMut("graphql_cursor")
+	assert.Equal(t, "c1", v)
+	require.NoError(t, ackFn(ctx, nil))
+
+	// Reconnecting resumes from the stored cursor.
+	i, err = newInputFromConfig(conf, mgr)
+	require.NoError(t, err)
+	require.NoError(t, i.Connect(ctx))
+
+	batch, ackFn, err = i.ReadBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c"}, readIDs(t, batch))
+	require.NoError(t, ackFn(ctx, nil))
+
+	_, _, err = i.ReadBatch(ctx)
+	require.ErrorIs(t, err, service.ErrEndOfInput)
+
+	require.NoError(t, mgr.AccessCache(ctx, "foocache", func(c service.Cache) {
+		b, err := c.Get(ctx, "foo")
+		require.NoError(t, err)
+		assert.Equal(t, "c2", string(b))
+	}))
+	assert.Equal(t, []any{nil, "c1"}, srv.cursors)
+}
+
+func TestGraphQLInputErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"errors":[{"message":"not allowed"}]}`))
+	}))
+	t.Cleanup(ts.Close)
+
+	ctx := context.Background()
+	conf, err := inputConfig().ParseYAML(fmt.Sprintf(`
+url: %v
+query: 'query Items($after: String) { items(after: $after) { nodes { id } pageInfo { endCursor hasNextPage } } }'
+cursor_variable: after
+nodes_path: data.items.nodes
+end_cursor_path: data.items.pageInfo.endCursor
+has_next_page_path: data.items.pageInfo.hasNextPage
+headers:
+  Authorization: 'Bearer meow'
+`, ts.URL), nil)
+	require.NoError(t, err)
+
+	i, err := newInputFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, i.Connect(ctx))
+
+	_, _, err = i.ReadBatch(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not allowed")
+}
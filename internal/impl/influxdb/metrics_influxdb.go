@@ -17,11 +17,13 @@ package influxdb
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
 	client "github.com/influxdata/influxdb1-client/v2"
 	"github.com/rcrowley/go-metrics"
 
@@ -44,19 +46,56 @@ const (
 	imFieldIncludeRuntime   = "runtime"
 	imFieldIncludeDebugGC   = "debug_gc"
 	imFieldTags             = "tags"
+	imFieldBatchSize        = "batch_size"
+	imFieldRetries          = "retries"
+	imFieldV2               = "v2"
+	imFieldV2Bucket         = "bucket"
+	imFieldV2Org            = "org"
+	imFieldV2Token          = "token"
 )
 
 func configSpec() *service.ConfigSpec {
+	retriesDefaults := backoff.NewExponentialBackOff()
+	retriesDefaults.InitialInterval = time.Millisecond * 500
+	retriesDefaults.MaxInterval = time.Second * 5
+	retriesDefaults.MaxElapsedTime = time.Second * 30
+
 	return service.NewConfigSpec().
 		Beta().
 		Version("3.36.0").
-		Summary(`Send metrics to InfluxDB 1.x using the `+"`/write`"+` endpoint.`).
-		Description(`See https://docs.influxdata.com/influxdb/v1.8/tools/api/#write-http-endpoint for further details on the write API.`).
+		Summary(`Send metrics to InfluxDB 1.x using the `+"`/write`"+` endpoint, or to InfluxDB 2.x using the `+"`/api/v2/write`"+` endpoint.`).
+		Description(`See https://docs.influxdata.com/influxdb/v1.8/tools/api/#write-http-endpoint for further details on the write API.
+
+== InfluxDB 2.x
+
+When the `+"`v2`"+` field is set metrics are written to the bucket and organization it specifies using the https://docs.influxdata.com/influxdb/v2/api/#operation/PostWrite[v2 write API^], authenticated with an API token, and the field `+"`db`"+` is ignored.
+
+== Measurements
+
+Each metric is written as a measurement of the same name, with its labels and the global `+"`tags`"+` added as tags. Counters are written with a single field `+"`count`"+`, gauges with a single field `+"`value`"+`, and timings with the fields `+"`count`, `min`, `max`, `mean`, `stddev`, `p50`, `p75`, `p95`, `p99`, `p999`, `1m.rate`, `5m.rate`, `15m.rate` and `mean.rate`"+`.
+
+== Batching and retries
+
+Metrics are written on each `+"`interval`"+` in batches of at most `+"`batch_size`"+` points, and batches that fail to be written are retried according to `+"`retries`"+`. Batches rejected by an InfluxDB 2.x endpoint with a client error other than a 429, such as an invalid token, are not retried.`).
 		Fields(
 			service.NewURLField(imFieldURL).
 				Description("A URL of the format `[https|http|udp]://host:port` to the InfluxDB host."),
 			service.NewStringField(imFieldDB).
-				Description("The name of the database to use."),
+				Description("The name of the database to use, this field is required unless `v2` is set.").
+				Default(""),
+			service.NewObjectField(imFieldV2,
+				service.NewStringField(imFieldV2Bucket).
+					Description("The bucket to write metrics to."),
+				service.NewStringField(imFieldV2Org).
+					Description("The name or ID of the organization that owns the bucket."),
+				service.NewStringField(imFieldV2Token).
+					Description("An API token with permission to write to the bucket.").
+					Secret().
+					Default(""),
+			).
+				Description("Write metrics to an InfluxDB 2.x endpoint.").
+				Version("4.31.0").
+				Optional(),
 			service.NewTLSToggledField(imFieldTLS), // TODO: V5 use non-toggled here
 			service.NewStringField(imFieldUsername).
 				Description("A username (when applicable).").
@@ -83,6 +122,15 @@ func configSpec() *service.ConfigSpec {
 				Description("A duration string indicating how often metrics should be flushed.").
 				Advanced().
 				Default("1m"),
+			service.NewIntField(imFieldBatchSize).
+				Description("The maximum number of points to write in a single request.").
+				Version("4.31.0").
+				Advanced().
+				Default(5000),
+			service.NewBackOffField(imFieldRetries, false, retriesDefaults).
+				Description("Determine time intervals and cut offs for retry attempts of failed writes.").
+				Version("4.31.0").
+				Advanced(),
 			service.NewDurationField(imFieldPingInterval).
 				Description("A duration string indicating how often to ping the host.").
 				Advanced().
@@ -111,7 +159,18 @@ func configSpec() *service.ConfigSpec {
 				Description("[any|one|quorum|all] sets write consistency when available.").
 				Advanced().
 				Optional(),
-		)
+		).
+		Example("InfluxDB 2.x", "Write metrics to a bucket of an InfluxDB 2.x instance.", `
+metrics:
+  influxdb:
+    url: http://localhost:8086
+    v2:
+      bucket: benthos
+      org: my-org
+      token: ${INFLUXDB_TOKEN}
+    tags:
+      hostname: ${HOSTNAME}
+`)
 }
 
 func init() {
@@ -126,9 +185,11 @@ func init() {
 }
 
 type influxDBMetrics struct {
-	client      client.Client
+	client      influxClient
 	clientConf  clientConf
 	batchConfig client.BatchPointsConfig
+	batchSize   int
+	backOff     *backoff.ExponentialBackOff
 
 	tags         map[string]string
 	interval     time.Duration
@@ -179,11 +240,20 @@ func fromParsed(conf *service.ParsedConfig, logger *service.Logger) (i *influxDB
 	if i.timeout, err = conf.FieldDuration(imFieldTimeout); err != nil {
 		return
 	}
+	if i.batchSize, err = conf.FieldInt(imFieldBatchSize); err != nil {
+		return
+	}
+	if i.batchSize < 1 {
+		return nil, fmt.Errorf("field %v must be greater than zero", imFieldBatchSize)
+	}
+	if i.backOff, err = conf.FieldBackOff(imFieldRetries); err != nil {
+		return
+	}
 
 	if i.clientConf, err = clientConfFromParsed(conf); err != nil {
 		return nil, err
 	}
-	if i.client, err = i.clientConf.build(); err != nil {
+	if i.client, err = i.clientConf.build(i.timeout); err != nil {
 		return nil, err
 	}
 
@@ -198,6 +268,9 @@ func fromParsed(conf *service.ParsedConfig, logger *service.Logger) (i *influxDB
 	if i.batchConfig.Database, err = conf.FieldString(imFieldDB); err != nil {
 		return
 	}
+	if !i.clientConf.v2 && i.batchConfig.Database == "" {
+		return nil, fmt.Errorf("field %v is required unless %v is set", imFieldDB, imFieldV2)
+	}
 	i.batchConfig.RetentionPolicy, _ = conf.FieldString(imFieldRetentionPolicy)
 	i.batchConfig.WriteConsistency, _ = conf.FieldString(imFieldWriteConsistency)
 
@@ -211,6 +284,12 @@ type clientConf struct {
 	tlsConf  *tls.Config
 	username string
 	password string
+
+	v2        bool
+	bucket    string
+	org       string
+	token     string
+	precision string
 }
 
 func clientConfFromParsed(conf *service.ParsedConfig) (c clientConf, err error) {
@@ -222,10 +301,28 @@ func clientConfFromParsed(conf *service.ParsedConfig) (c clientConf, err error)
 	if c.tlsConf, err = conf.FieldTLS(imFieldTLS); err != nil {
 		return
 	}
+	if c.precision, err = conf.FieldString(imFieldPrecision); err != nil {
+		return
+	}
+	if conf.Contains(imFieldV2) {
+		c.v2 = true
+		if c.bucket, err = conf.FieldString(imFieldV2, imFieldV2Bucket); err != nil {
+			return
+		}
+		if c.org, err = conf.FieldString(imFieldV2, imFieldV2Org); err != nil {
+			return
+		}
+		if c.token, err = conf.FieldString(imFieldV2, imFieldV2Token); err != nil {
+			return
+		}
+	}
 	return
 }
 
-func (conf clientConf) build() (c client.Client, err error) {
+func (conf clientConf) build(timeout time.Duration) (c influxClient, err error) {
+	if conf.v2 {
+		return newV2Client(conf, timeout)
+	}
 	if conf.u.Scheme == "https" {
 		c, err = client.NewHTTPClient(client.HTTPConfig{
 			Addr:      conf.u.String(),
@@ -259,14 +356,14 @@ func (i *influxDBMetrics) loop() {
 		case <-i.ctx.Done():
 			return
 		case <-ticker.C:
-			if err := i.publishRegistry(); err != nil {
+			if err := i.publishRegistry(i.ctx); err != nil {
 				i.log.Errorf("failed to send metrics data: %s", err)
 			}
 		case <-pingTicker.C:
 			_, _, err := i.client.Ping(i.timeout)
 			if err != nil {
 				i.log.Warnf("unable to ping influx endpoint: %s", err)
-				if tmpClient, err := i.clientConf.build(); err != nil {
+				if tmpClient, err := i.clientConf.build(i.timeout); err != nil {
 					i.log.Errorf("unable to recreate client: %s", err)
 				} else {
 					i.client = tmpClient
@@ -276,12 +373,9 @@ func (i *influxDBMetrics) loop() {
 	}
 }
 
-func (i *influxDBMetrics) publishRegistry() error {
-	points, err := client.NewBatchPoints(i.batchConfig)
-	if err != nil {
-		return fmt.Errorf("problem creating batch points for influx: %s", err)
-	}
+func (i *influxDBMetrics) publishRegistry(ctx context.Context) error {
 	now := time.Now()
+	var points []*client.Point
 	all := i.getAllMetrics()
 	for k, v := range all {
 		name, normalTags := decodeInfluxDBName(k)
@@ -298,11 +392,54 @@ func (i *influxDBMetrics) publishRegistry() error {
 		if err != nil {
 			i.log.Debugf("problem formatting metrics on %s: %s", name, err)
 		} else {
-			points.AddPoint(p)
+			points = append(points, p)
 		}
 	}
 
-	return i.client.Write(points)
+	for len(points) > 0 {
+		n := i.batchSize
+		if n > len(points) {
+			n = len(points)
+		}
+		batch, err := client.NewBatchPoints(i.batchConfig)
+		if err != nil {
+			return fmt.Errorf("problem creating batch points for influx: %s", err)
+		}
+		batch.AddPoints(points[:n])
+		if err := i.writeWithRetries(ctx, batch); err != nil {
+			return err
+		}
+		points = points[n:]
+	}
+	return nil
+}
+
+func (i *influxDBMetrics) writeWithRetries(ctx context.Context, batch client.BatchPoints) error {
+	boff := *i.backOff
+	boff.Reset()
+
+	for {
+		err := i.client.Write(batch)
+		if err == nil {
+			return nil
+		}
+
+		var wErr *v2WriteError
+		if errors.As(err, &wErr) && !wErr.retryable() {
+			return err
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return err
+		}
+		i.log.Debugf("failed to write metrics data, retrying: %s", err)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return err
+		}
+	}
 }
 
 func getMetricValues(i any) map[string]any {
@@ -403,8 +540,9 @@ func (i *influxDBMetrics) HandlerFunc() http.HandlerFunc {
 	return nil
 }
 
-func (i *influxDBMetrics) Close(context.Context) error {
-	if err := i.publishRegistry(); err != nil {
+func (i *influxDBMetrics) Close(ctx context.Context) error {
+	i.cancel()
+	if err := i.publishRegistry(ctx); err != nil {
 		i.log.Errorf("failed to send metrics data: %s", err)
 	}
 	i.client.Close()
@@ -0,0 +1,145 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package influxdb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	client "github.com/influxdata/influxdb1-client/v2"
+)
+
+// influxClient is the subset of the InfluxDB 1.x client used by the exporter,
+// which allows the v2 write API to be targeted with a separate implementation.
+type influxClient interface {
+	Ping(timeout time.Duration) (time.Duration, string, error)
+	Write(bp client.BatchPoints) error
+	Close() error
+}
+
+// v2WriteError is returned when the InfluxDB v2 write API responds with a
+// status other than 204.
+type v2WriteError struct {
+	status int
+	body   string
+}
+
+func (e *v2WriteError) Error() string {
+	if e.body == "" {
+		return fmt.Sprintf("influxdb write returned status %v", e.status)
+	}
+	return fmt.Sprintf("influxdb write returned status %v: %v", e.status, e.body)
+}
+
+// retryable returns false for client errors, which are caused by malformed
+// data or bad credentials and will never succeed when retried.
+func (e *v2WriteError) retryable() bool {
+	return e.status == http.StatusTooManyRequests || e.status >= 500
+}
+
+type v2Client struct {
+	writeURL string
+	pingURL  string
+	token    string
+	client   *http.Client
+}
+
+func newV2Client(conf clientConf, timeout time.Duration) (*v2Client, error) {
+	if conf.u.Scheme != "http" && conf.u.Scheme != "https" {
+		return nil, fmt.Errorf("protocol needs to be http or https when writing to InfluxDB v2 and is %s", conf.u.Scheme)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if conf.u.Scheme == "https" {
+		transport.TLSClientConfig = conf.tlsConf
+	}
+
+	base := strings.TrimSuffix(conf.u.String(), "/")
+
+	query := url.Values{}
+	query.Set("org", conf.org)
+	query.Set("bucket", conf.bucket)
+	query.Set("precision", conf.precision)
+
+	return &v2Client{
+		writeURL: base + "/api/v2/write?" + query.Encode(),
+		pingURL:  base + "/ping",
+		token:    conf.token,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+		},
+	}, nil
+}
+
+func (c *v2Client) Ping(timeout time.Duration) (time.Duration, string, error) {
+	start := time.Now()
+
+	req, err := http.NewRequest(http.MethodGet, c.pingURL, http.NoBody)
+	if err != nil {
+		return 0, "", err
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	_ = res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		return 0, "", fmt.Errorf("influxdb ping returned status %v", res.StatusCode)
+	}
+	return time.Since(start), res.Header.Get("X-Influxdb-Version"), nil
+}
+
+func (c *v2Client) Write(bp client.BatchPoints) error {
+	var buf bytes.Buffer
+	for _, p := range bp.Points() {
+		buf.WriteString(p.PrecisionString(bp.Precision()))
+		buf.WriteByte('\n')
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.writeURL, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Token "+c.token)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return &v2WriteError{status: res.StatusCode, body: strings.TrimSpace(string(body))}
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	return nil
+}
+
+func (c *v2Client) Close() error {
+	c.client.CloseIdleConnections()
+	return nil
+}
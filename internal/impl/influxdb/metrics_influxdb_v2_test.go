@@ -0,0 +1,187 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package influxdb
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type v2TestServer struct {
+	*httptest.Server
+
+	mut      sync.Mutex
+	requests []*http.Request
+	bodies   []string
+	statuses []int
+}
+
+func newV2TestServer(t testing.TB, statuses ...int) *v2TestServer {
+	t.Helper()
+
+	s := &v2TestServer{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		s.mut.Lock()
+		s.requests = append(s.requests, r)
+		s.bodies = append(s.bodies, string(body))
+		status := http.StatusNoContent
+		if len(s.statuses) > 0 {
+			status = s.statuses[0]
+			s.statuses = s.statuses[1:]
+		}
+		s.mut.Unlock()
+
+		if status != http.StatusNoContent {
+			http.Error(w, "nope", status)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *v2TestServer) lines() []string {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	var lines []string
+	for _, b := range s.bodies {
+		lines = append(lines, strings.Split(strings.TrimSpace(b), "\n")...)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func TestInfluxV2Write(t *testing.T) {
+	srv := newV2TestServer(t)
+
+	i := fromYAML(t, `
+url: %v
+precision: ms
+v2:
+  bucket: foo
+  org: bar
+  token: baz
+tags:
+  hostname: localhost
+`, srv.URL)
+
+	i.NewCounterCtor("counter", "label")("value").Incr(3)
+	i.NewGaugeCtor("gauge")().Set(5)
+
+	require.NoError(t, i.publishRegistry(context.Background()))
+
+	require.Len(t, srv.requests, 1)
+	req := srv.requests[0]
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "/api/v2/write", req.URL.Path)
+	assert.Equal(t, "foo", req.URL.Query().Get("bucket"))
+	assert.Equal(t, "bar", req.URL.Query().Get("org"))
+	assert.Equal(t, "ms", req.URL.Query().Get("precision"))
+	assert.Equal(t, "Token baz", req.Header.Get("Authorization"))
+
+	lines := srv.lines()
+	require.Len(t, lines, 2)
+	assert.Regexp(t, `^counter,hostname=localhost,label=value count=3i \d+$`, lines[0])
+	assert.Regexp(t, `^gauge,hostname=localhost value=5i \d+$`, lines[1])
+}
+
+func TestInfluxV2Batching(t *testing.T) {
+	srv := newV2TestServer(t)
+
+	i := fromYAML(t, `
+url: %v
+batch_size: 2
+v2:
+  bucket: foo
+  org: bar
+`, srv.URL)
+
+	for _, n := range []string{"a", "b", "c", "d", "e"} {
+		i.NewCounterCtor(n)().Incr(1)
+	}
+
+	require.NoError(t, i.publishRegistry(context.Background()))
+	require.Len(t, srv.requests, 3)
+	assert.Empty(t, srv.requests[0].Header.Get("Authorization"))
+	assert.Len(t, srv.lines(), 5)
+}
+
+func TestInfluxV2Retries(t *testing.T) {
+	srv := newV2TestServer(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+
+	i := fromYAML(t, `
+url: %v
+v2:
+  bucket: foo
+  org: bar
+retries:
+  initial_interval: 1ms
+  max_interval: 1ms
+`, srv.URL)
+
+	i.NewCounterCtor("counter")().Incr(1)
+
+	require.NoError(t, i.publishRegistry(context.Background()))
+	require.Len(t, srv.requests, 3)
+	assert.Len(t, srv.lines(), 3)
+}
+
+func TestInfluxV2NoRetryOnClientError(t *testing.T) {
+	srv := newV2TestServer(t, http.StatusUnauthorized)
+
+	i := fromYAML(t, `
+url: %v
+v2:
+  bucket: foo
+  org: bar
+retries:
+  initial_interval: 1ms
+  max_interval: 1ms
+`, srv.URL)
+
+	i.NewCounterCtor("counter")().Incr(1)
+
+	err := i.publishRegistry(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+	assert.Len(t, srv.requests, 1)
+}
+
+func TestInfluxConfigErrors(t *testing.T) {
+	for _, conf := range []string{
+		`url: http://localhost:8086`,
+		"url: http://localhost:8086\ndb: foo\nbatch_size: 0",
+		"url: udp://localhost:8086\nv2:\n  bucket: foo\n  org: bar",
+	} {
+		pConf, err := configSpec().ParseYAML(conf, nil)
+		require.NoError(t, err, conf)
+
+		_, err = fromParsed(pConf, nil)
+		require.Error(t, err, conf)
+	}
+}
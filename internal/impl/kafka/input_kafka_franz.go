@@ -17,13 +17,17 @@ package kafka
 import (
 	"context"
 	"crypto/tls"
+	"encoding/base64"
 	"errors"
+	"fmt"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/twmb/franz-go/pkg/kerr"
 	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
 	"github.com/twmb/franz-go/pkg/sasl"
 
 	"github.com/Jeffail/checkpoint"
@@ -57,6 +61,8 @@ This input adds the following metadata fields to each message:
 - kafka_tombstone_message
 - All record headers
 ` + "```" + `
+
+Record headers are added as metadata fields of the same name with their values converted to strings, which is lossy for headers with binary values. When ` + "`structured_headers`" + ` is set to ` + "`true`" + ` all headers are also added to the metadata field ` + "`kafka_headers`" + ` as an object, where the value of each header is its raw bytes, or the bytes encoded as a base64 string when ` + "`encode_binary_headers`" + ` is ` + "`true`" + `, and headers that appear multiple times within a record are represented as an array of all values in order.
 `).
 		Field(service.NewStringListField("seed_brokers").
 			Description("A list of broker addresses to connect to in order to establish connections. If an item of the list contains commas it will be expanded into multiple addresses.").
@@ -77,7 +83,7 @@ Finally, it's also possible to specify an explicit offset to consume from by add
 			Example([]string{"foo:0,bar:1,bar:3"}).
 			Example([]string{"foo:0-5"})).
 		Field(service.NewBoolField("regexp_topics").
-			Description("Whether listed topics should be interpreted as regular expression patterns for matching multiple topics. When topics are specified with explicit partitions this field must remain set to `false`.").
+			Description("Whether listed topics should be interpreted as regular expression patterns for matching multiple topics. Topics created after the input has started are consumed once they are discovered by a metadata refresh, the frequency of which is determined by `metadata_max_age`, and topics that are deleted are dropped from the set of consumed topics. When topics are specified with explicit partitions this field must remain set to `false`.").
 			Default(false)).
 		Field(service.NewStringField("consumer_group").
 			Description("An optional consumer group to consume as. When specified the partitions of specified topics are automatically distributed across consumers sharing a consumer group, and partition offsets are automatically committed and resumed under this name. Consumer groups are not supported when specifying explicit partitions to consume from in the `topics` field.").
@@ -94,11 +100,21 @@ Finally, it's also possible to specify an explicit offset to consume from by add
 			Description("Determines how many messages of the same partition can be processed in parallel before applying back pressure. When a message of a given offset is delivered to the output the offset is only allowed to be committed when all messages of prior offsets have also been delivered, this ensures at-least-once delivery guarantees. However, this mechanism also increases the likelihood of duplicates in the event of crashes or server faults, reducing the checkpoint limit will mitigate this.").
 			Default(1024).
 			Advanced()).
+		Field(service.NewStringField("rate_limit").
+			Description("An optional xref:components:rate_limits/about.adoc[`rate_limit`] resource to throttle the consumption of records by. Each record consumed accesses the rate limit once, and whilst the rate limit is exhausted fetching of all consumed topics is paused, and is resumed once the rate limit frees up. Records that were already fetched are consumed once the rate limit frees up and offsets are committed as normal, therefore no records are skipped.").
+			Default("").
+			Advanced().
+			Version("4.31.0")).
 		Field(service.NewAutoRetryNacksToggleField()).
 		Field(service.NewDurationField("commit_period").
 			Description("The period of time between each commit of the current partition offsets. Offsets are always committed during shutdown.").
 			Default("5s").
 			Advanced()).
+		Field(service.NewDurationField("metadata_max_age").
+			Description("The maximum age of cluster metadata before it is refreshed. This determines how quickly newly created topics are consumed when `regexp_topics` is enabled.").
+			Default("5m").
+			Advanced().
+			Version("4.31.0")).
 		Field(service.NewBoolField("start_from_oldest").
 			Description("Determines whether to consume from the oldest available offset, otherwise messages are consumed from the latest offset. The setting is applied when creating a new consumer group or the saved offset no longer exists.").
 			Default(true).
@@ -106,6 +122,16 @@ Finally, it's also possible to specify an explicit offset to consume from by add
 		Field(service.NewTLSToggledField("tls")).
 		Field(SASLFields()).
 		Field(service.NewBoolField("multi_header").Description("Decode headers into lists to allow handling of multiple values with the same key").Default(false).Advanced()).
+		Field(service.NewBoolField("structured_headers").
+			Description("Whether to add all record headers to the metadata field `kafka_headers` as an object that preserves the raw bytes of values and represents headers with multiple values as arrays.").
+			Default(false).
+			Advanced().
+			Version("4.31.0")).
+		Field(service.NewBoolField("encode_binary_headers").
+			Description("Whether header values within the `kafka_headers` metadata field are encoded as base64 strings rather than raw bytes, which allows them to be serialized safely, e.g. with `@kafka_headers.format_json()`. This field has no effect unless `structured_headers` is `true`.").
+			Default(false).
+			Advanced().
+			Version("4.31.0")).
 		Field(service.NewBatchPolicyField("batching").
 			Description("Allows you to configure a xref:configuration:batching.adoc[batching policy] that applies to individual topic partitions in order to batch messages together before flushing them for processing. Batching can be beneficial for performance as well as useful for windowed processing, and doing so this way preserves the ordering of topic partitions.").
 			Advanced()).
@@ -152,10 +178,14 @@ type franzKafkaReader struct {
 	tlsConf         *tls.Config
 	saslConfs       []sasl.Mechanism
 	checkpointLimit int
+	rateLimit       string
 	startFromOldest bool
 	commitPeriod    time.Duration
+	metadataMaxAge  time.Duration
 	regexPattern    bool
 	multiHeader     bool
+	structHeaders   bool
+	encodeHeaders   bool
 	batchPolicy     service.BatchPolicy
 
 	batchChan atomic.Value
@@ -237,10 +267,21 @@ func newFranzKafkaReaderFromConfig(conf *service.ParsedConfig, res *service.Reso
 		return nil, err
 	}
 
+	if f.rateLimit, err = conf.FieldString("rate_limit"); err != nil {
+		return nil, err
+	}
+	if f.rateLimit != "" && !res.HasRateLimit(f.rateLimit) {
+		return nil, fmt.Errorf("rate limit resource '%v' was not found", f.rateLimit)
+	}
+
 	if f.commitPeriod, err = conf.FieldDuration("commit_period"); err != nil {
 		return nil, err
 	}
 
+	if f.metadataMaxAge, err = conf.FieldDuration("metadata_max_age"); err != nil {
+		return nil, err
+	}
+
 	if f.batchPolicy, err = conf.FieldBatchPolicy("batching"); err != nil {
 		return nil, err
 	}
@@ -255,6 +296,12 @@ func newFranzKafkaReaderFromConfig(conf *service.ParsedConfig, res *service.Reso
 	if f.multiHeader, err = conf.FieldBool("multi_header"); err != nil {
 		return nil, err
 	}
+	if f.structHeaders, err = conf.FieldBool("structured_headers"); err != nil {
+		return nil, err
+	}
+	if f.encodeHeaders, err = conf.FieldBool("encode_binary_headers"); err != nil {
+		return nil, err
+	}
 	if f.saslConfs, err = SASLMechanismsFromConfig(conf); err != nil {
 		return nil, err
 	}
@@ -291,6 +338,9 @@ func (f *franzKafkaReader) recordToMessage(record *kgo.Record) *msgWithRecord {
 			msg.MetaSetMut(hdr.Key, string(hdr.Value))
 		}
 	}
+	if f.structHeaders {
+		msg.MetaSetMut("kafka_headers", f.structuredHeaders(record.Headers))
+	}
 
 	// The record lives on for checkpointing, but we don't need the contents
 	// going forward so discard these. This looked fine to me but could
@@ -304,6 +354,32 @@ func (f *franzKafkaReader) recordToMessage(record *kgo.Record) *msgWithRecord {
 	}
 }
 
+// structuredHeaders converts record headers into an object of header values,
+// where headers that appear more than once become arrays of their values.
+func (f *franzKafkaReader) structuredHeaders(hdrs []kgo.RecordHeader) map[string]any {
+	headers := make(map[string]any, len(hdrs))
+	for _, hdr := range hdrs {
+		var v any
+		if f.encodeHeaders {
+			v = base64.StdEncoding.EncodeToString(hdr.Value)
+		} else {
+			// Copy the value so that the message does not reference the
+			// buffer the record was decoded from.
+			v = append([]byte(nil), hdr.Value...)
+		}
+
+		switch existing := headers[hdr.Key].(type) {
+		case nil:
+			headers[hdr.Key] = v
+		case []any:
+			headers[hdr.Key] = append(existing, v)
+		default:
+			headers[hdr.Key] = []any{existing, v}
+		}
+	}
+	return headers
+}
+
 //------------------------------------------------------------------------------
 
 type partitionTracker struct {
@@ -630,6 +706,7 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 		kgo.ConsumerGroup(f.consumerGroup),
 		kgo.ClientID(f.clientID),
 		kgo.Rack(f.rackID),
+		kgo.MetadataMaxAge(f.metadataMaxAge),
 	}
 
 	if f.consumerGroup != "" {
@@ -677,6 +754,19 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 		closeCtx, done := f.shutSig.SoftStopCtx(context.Background())
 		defer done()
 
+		// Topics are tracked in order to pause all of them whilst the rate
+		// limit is exhausted, where topics consumed with a regular expression
+		// are only known once records are seen.
+		consumedTopics := map[string]struct{}{}
+		if !f.regexPattern {
+			for _, t := range f.topics {
+				consumedTopics[t] = struct{}{}
+			}
+		}
+		for t := range f.topicPartitions {
+			consumedTopics[t] = struct{}{}
+		}
+
 		for {
 			// Using a stall prevention context here because I've realised we
 			// might end up disabling literally all the partitions and topics
@@ -693,19 +783,40 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 				// forcing a reconnect.
 				nonTemporalErr := false
 
-				for _, kerr := range errs {
+				for _, fErr := range errs {
 					// TODO: The documentation from franz-go is top-tier, it
 					// should be straight forward to expand this to include more
 					// errors that are safe to disregard.
-					if errors.Is(kerr.Err, context.DeadlineExceeded) ||
-						errors.Is(kerr.Err, context.Canceled) {
+					if errors.Is(fErr.Err, context.DeadlineExceeded) ||
+						errors.Is(fErr.Err, context.Canceled) {
+						continue
+					}
+
+					// When consuming with a regular expression a matched topic
+					// can be deleted at any time, the client stops consuming
+					// it on the next metadata refresh and so we only need to
+					// drop our own tracking of its partitions. However, this
+					// error is also returned transiently whilst metadata
+					// propagates or partitions are reassigned, in which case
+					// the client continues to fetch the partition and its
+					// tracked offsets must be kept.
+					if f.regexPattern && errors.Is(fErr.Err, kerr.UnknownTopicOrPartition) {
+						exists, err := topicExists(closeCtx, cl, fErr.Topic)
+						if err != nil || exists {
+							f.log.Warnf("Kafka poll error on topic %v, partition %v: %v", fErr.Topic, fErr.Partition, fErr.Err)
+							continue
+						}
+						f.log.Warnf("Topic %v partition %v no longer exists, dropping it from the consumed topics", fErr.Topic, fErr.Partition)
+						checkpoints.removeTopicPartitions(closeCtx, map[string][]int32{
+							fErr.Topic: {fErr.Partition},
+						})
 						continue
 					}
 
 					nonTemporalErr = true
 
-					if !errors.Is(kerr.Err, kgo.ErrClientClosed) {
-						f.log.Errorf("Kafka poll error on topic %v, partition %v: %v", kerr.Topic, kerr.Partition, kerr.Err)
+					if !errors.Is(fErr.Err, kgo.ErrClientClosed) {
+						f.log.Errorf("Kafka poll error on topic %v, partition %v: %v", fErr.Topic, fErr.Partition, fErr.Err)
 					}
 				}
 
@@ -722,6 +833,12 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 			iter := fetches.RecordIter()
 			for !iter.Done() {
 				record := iter.Next()
+				if f.rateLimit != "" {
+					consumedTopics[record.Topic] = struct{}{}
+					if !f.waitForRateLimit(closeCtx, cl, consumedTopics) {
+						return
+					}
+				}
 				if checkpoints.addRecord(closeCtx, f.recordToMessage(record), f.checkpointLimit) {
 					pauseTopicPartitions[record.Topic] = append(pauseTopicPartitions[record.Topic], record.Partition)
 				}
@@ -747,6 +864,84 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 	return nil
 }
 
+// waitForRateLimit blocks until the rate limit grants access, pausing the
+// fetching of topics for as long as it is exhausted. Returns false if the
+// context is cancelled.
+// topicExists requests the metadata of a topic in order to confirm whether it
+// has been deleted.
+func topicExists(ctx context.Context, cl *kgo.Client, topic string) (bool, error) {
+	req := kmsg.NewPtrMetadataRequest()
+	reqTopic := kmsg.NewMetadataRequestTopic()
+	reqTopic.Topic = kmsg.StringPtr(topic)
+	req.Topics = append(req.Topics, reqTopic)
+
+	res, err := req.RequestWith(ctx, cl)
+	if err != nil {
+		return false, err
+	}
+	for _, t := range res.Topics {
+		if t.Topic == nil || *t.Topic != topic {
+			continue
+		}
+		if err := kerr.ErrorForCode(t.ErrorCode); err != nil {
+			if errors.Is(err, kerr.UnknownTopicOrPartition) {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	}
+	return false, fmt.Errorf("metadata response did not contain topic %v", topic)
+}
+
+func (f *franzKafkaReader) waitForRateLimit(ctx context.Context, cl *kgo.Client, topics map[string]struct{}) bool {
+	var (
+		pausedFetch bool
+		paused      []string
+	)
+	defer func() {
+		if pausedFetch {
+			cl.ResumeFetchTopics(paused...)
+			f.log.Debug("Rate limit freed up, resuming fetches")
+		}
+	}()
+
+	for {
+		var period time.Duration
+		var err error
+		if rerr := f.res.AccessRateLimit(ctx, f.rateLimit, func(rl service.RateLimit) {
+			period, err = rl.Access(ctx)
+		}); rerr != nil {
+			err = rerr
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		if err != nil {
+			f.log.Errorf("Rate limit error: %v", err)
+			period = time.Second
+		}
+		if period <= 0 {
+			return true
+		}
+
+		if !pausedFetch {
+			pausedFetch = true
+			for t := range topics {
+				paused = append(paused, t)
+			}
+			cl.PauseFetchTopics(paused...)
+			f.log.Debugf("Rate limit exhausted, pausing fetches for %v", period)
+		}
+
+		select {
+		case <-time.After(period):
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
 func (f *franzKafkaReader) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
 	batchChan := f.getBatchChan()
 	if batchChan == nil {
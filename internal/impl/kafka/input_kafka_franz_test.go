@@ -0,0 +1,216 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestKafkaFranzInputHeaders(t *testing.T) {
+	newRecord := func() *kgo.Record {
+		return &kgo.Record{
+			Topic:     "foo",
+			Value:     []byte("hello"),
+			Timestamp: time.Unix(100, 0),
+			Headers: []kgo.RecordHeader{
+				{Key: "schema", Value: []byte{0x00, 0xff, 0x10}},
+				{Key: "trace", Value: []byte("a")},
+				{Key: "trace", Value: []byte("b")},
+				{Key: "trace", Value: []byte("c")},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		conf     string
+		expected any
+	}{
+		{
+			name:     "flat by default",
+			expected: nil,
+		},
+		{
+			name: "structured raw bytes",
+			conf: `structured_headers: true`,
+			expected: map[string]any{
+				"schema": []byte{0x00, 0xff, 0x10},
+				"trace":  []any{[]byte("a"), []byte("b"), []byte("c")},
+			},
+		},
+		{
+			name: "structured base64",
+			conf: "structured_headers: true\nencode_binary_headers: true",
+			expected: map[string]any{
+				"schema": "AP8Q",
+				"trace":  []any{"YQ==", "Yg==", "Yw=="},
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			pConf, err := franzKafkaInputConfig().ParseYAML(fmt.Sprintf(`
+seed_brokers: [ localhost:9092 ]
+topics: [ foo ]
+consumer_group: cg
+%v
+`, test.conf), nil)
+			require.NoError(t, err)
+
+			rdr, err := newFranzKafkaReaderFromConfig(pConf, service.MockResources())
+			require.NoError(t, err)
+
+			msg := rdr.recordToMessage(newRecord()).msg
+
+			v, _ := msg.MetaGet("schema")
+			assert.Equal(t, "\x00\xff\x10", v)
+			v, _ = msg.MetaGet("trace")
+			assert.Equal(t, "c", v)
+
+			structured, exists := msg.MetaGetMut("kafka_headers")
+			if test.expected == nil {
+				assert.False(t, exists)
+				return
+			}
+			assert.Equal(t, test.expected, structured)
+		})
+	}
+}
+
+func TestKafkaFranzInputRateLimitPause(t *testing.T) {
+	var accesses int32
+	res := service.MockResources(service.MockResourcesOptAddRateLimit("foo", func(ctx context.Context) (time.Duration, error) {
+		if atomic.AddInt32(&accesses, 1) < 3 {
+			return 50 * time.Millisecond, nil
+		}
+		return 0, nil
+	}))
+
+	pConf, err := franzKafkaInputConfig().ParseYAML(`
+seed_brokers: [ localhost:9092 ]
+topics: [ foo ]
+consumer_group: cg
+rate_limit: foo
+`, nil)
+	require.NoError(t, err)
+
+	r, err := newFranzKafkaReaderFromConfig(pConf, res)
+	require.NoError(t, err)
+
+	cl, err := kgo.NewClient(kgo.SeedBrokers("localhost:9092"), kgo.ConsumeTopics("foo"))
+	require.NoError(t, err)
+	t.Cleanup(cl.Close)
+
+	topics := map[string]struct{}{"foo": {}, "bar": {}}
+
+	pausedDuring := make(chan []string, 1)
+	go func() {
+		time.Sleep(25 * time.Millisecond)
+		pausedDuring <- cl.PauseFetchTopics()
+	}()
+
+	require.True(t, r.waitForRateLimit(context.Background(), cl, topics))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&accesses))
+	assert.ElementsMatch(t, []string{"foo", "bar"}, <-pausedDuring)
+	assert.Empty(t, cl.PauseFetchTopics())
+
+	ctx, done := context.WithCancel(context.Background())
+	done()
+	atomic.StoreInt32(&accesses, 0)
+	assert.False(t, r.waitForRateLimit(ctx, cl, topics))
+	assert.Empty(t, cl.PauseFetchTopics())
+}
+
+func TestKafkaFranzInputRateLimitMissing(t *testing.T) {
+	pConf, err := franzKafkaInputConfig().ParseYAML(`
+seed_brokers: [ localhost:9092 ]
+topics: [ foo ]
+consumer_group: cg
+rate_limit: nope
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newFranzKafkaReaderFromConfig(pConf, service.MockResources())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "rate limit resource 'nope' was not found")
+}
+
+func TestKafkaFranzCheckpointRemoveTopicPartitions(t *testing.T) {
+	batchChan := make(chan batchWithAckFn, 10)
+
+	var committed []string
+	checkpoints := newCheckpointTracker(service.MockResources(), batchChan, func(r *kgo.Record) {
+		committed = append(committed, fmt.Sprintf("%v-%v", r.Topic, r.Offset))
+	}, service.BatchPolicy{})
+	t.Cleanup(checkpoints.close)
+
+	addRecord := func(topic string, offset int64) batchWithAckFn {
+		t.Helper()
+		checkpoints.addRecord(context.Background(), &msgWithRecord{
+			msg: service.NewMessage([]byte("hello")),
+			r:   &kgo.Record{Topic: topic, Partition: 0, Offset: offset},
+		}, 10)
+		select {
+		case b := <-batchChan:
+			return b
+		default:
+			t.Fatal("expected a batch")
+		}
+		return batchWithAckFn{}
+	}
+
+	// Leave the first record of each topic pending so that later records
+	// cannot be committed.
+	addRecord("foo", 0)
+	fooSecond := addRecord("foo", 1)
+	barFirst := addRecord("bar", 0)
+	barSecond := addRecord("bar", 1)
+
+	fooSecond.onAck()
+	barSecond.onAck()
+	assert.Empty(t, committed)
+	assert.True(t, checkpoints.pauseFetch("foo", 0, 2))
+	assert.True(t, checkpoints.pauseFetch("bar", 0, 2))
+
+	checkpoints.removeTopicPartitions(context.Background(), map[string][]int32{
+		"foo": {0},
+	})
+	assert.False(t, checkpoints.pauseFetch("foo", 0, 2))
+	assert.True(t, checkpoints.pauseFetch("bar", 0, 2))
+
+	// Records of the removed topic are no longer blocked by its old pending
+	// offsets.
+	addRecord("foo", 5).onAck()
+	assert.Equal(t, []string{"foo-5"}, committed)
+
+	// Whereas the other topic is still tracked from where it was.
+	addRecord("bar", 2).onAck()
+	assert.Equal(t, []string{"foo-5"}, committed)
+
+	barFirst.onAck()
+	assert.Equal(t, []string{"foo-5", "bar-2"}, committed)
+}
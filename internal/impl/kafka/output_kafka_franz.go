@@ -17,6 +17,7 @@ package kafka
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"math"
 	"strconv"
@@ -27,7 +28,10 @@ import (
 	"github.com/twmb/franz-go/pkg/kgo"
 	"github.com/twmb/franz-go/pkg/sasl"
 
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
 	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/expiry"
 )
 
 func franzKafkaOutputConfig() *service.ConfigSpec {
@@ -40,6 +44,8 @@ func franzKafkaOutputConfig() *service.ConfigSpec {
 Writes a batch of messages to Kafka brokers and waits for acknowledgement before propagating it back to the input.
 
 This output often out-performs the traditional ` + "`kafka`" + ` output as well as providing more useful logs and error messages.
+
+Messages with an expiry deadline in the metadata key ` + "`benthos_expires_at`" + ` that has passed are dropped rather than written, and the deadline of messages that are written is always added as a header. Kafka does not support a TTL for individual records, see the xref:components:processors/check_expiry.adoc[` + "`check_expiry`" + ` processor] for more information.
 `).
 		Field(service.NewStringListField("seed_brokers").
 			Description("A list of broker addresses to connect to in order to establish connections. If an item of the list contains commas it will be expanded into multiple addresses.").
@@ -51,17 +57,24 @@ This output often out-performs the traditional ` + "`kafka`" + ` output as well
 		Field(service.NewInterpolatedStringField("key").
 			Description("An optional key to populate for each message.").Optional()).
 		Field(service.NewStringAnnotatedEnumField("partitioner", map[string]string{
-			"murmur2_hash": "Kafka's default hash algorithm that uses a 32-bit murmur2 hash of the key to compute which partition the record will be on.",
+			"murmur2_hash": "Kafka's default hash algorithm that uses a 32-bit murmur2 hash of the key to compute which partition the record will be on. This is compatible with the default partitioner of the Java client, and messages without a key are partitioned in sticky batches.",
 			"round_robin":  "Round-robin's messages through all available partitions. This algorithm has lower throughput and causes higher CPU load on brokers, but can be useful if you want to ensure an even distribution of records to partitions.",
 			"least_backup": "Chooses the least backed up partition (the partition with the fewest amount of buffered records). Partitions are selected per batch.",
-			"manual":       "Manually select a partition for each message, requires the field `partition` to be specified.",
+			"manual":       "Manually select a partition for each message from the field `partition`, which defaults to the metadata key `kafka_partition`.",
+			"expression":   "Select a partition for each message with the Bloblang mapping `partition_expression`.",
 		}).
 			Description("Override the default murmur2 hashing partitioner.").
 			Advanced().Optional()).
 		Field(service.NewInterpolatedStringField("partition").
-			Description("An optional explicit partition to set for each message. This field is only relevant when the `partitioner` is set to `manual`. The provided interpolation string must be a valid integer.").
+			Description("An optional explicit partition to set for each message. This field is only relevant when the `partitioner` is set to `manual`, and when omitted the partition is read from the metadata key `kafka_partition`. The provided interpolation string must be a valid integer, and messages with a partition that does not exist within the topic are rejected.").
 			Example(`${! meta("partition") }`).
 			Optional()).
+		Field(service.NewBloblangField("partition_expression").
+			Description("A xref:guides:bloblang/about.adoc[Bloblang mapping] that results in the partition to set for each message. This field is only relevant when the `partitioner` is set to `expression`. The mapping must result in an integer, and messages with a partition that does not exist within the topic are rejected.").
+			Example(`root = this.tenant_id % 12`).
+			Version("4.31.0").
+			Advanced().
+			Optional()).
 		Field(service.NewStringField("client_id").
 			Description("An identifier for the client connection.").
 			Default("benthos").
@@ -74,6 +87,15 @@ This output often out-performs the traditional ` + "`kafka`" + ` output as well
 			Description("Enable the idempotent write producer option. This requires the `IDEMPOTENT_WRITE` permission on `CLUSTER` and can be disabled if this permission is not available.").
 			Default(true).
 			Advanced()).
+		Field(service.NewStringAnnotatedEnumField("produce_acks", map[string]string{
+			"all":    "Wait for all in-sync replicas to acknowledge each write, which provides the strongest durability guarantees.",
+			"leader": "Wait only for the partition leader to acknowledge each write. Messages are lost if the leader fails before they are replicated.",
+			"none":   "Do not wait for any acknowledgement from brokers. Messages are acknowledged as soon as they have been written to the connection, and are lost without an error if a broker fails to persist them for any reason.",
+		}).
+			Description("The number of acknowledgements required from brokers before a write is considered successful. Values other than `all` trade durability for throughput and implicitly disable `idempotent_write`, as idempotent writes require acknowledgements from all in-sync replicas. Only use `leader` or `none` for data where occasional loss is acceptable, such as non-critical telemetry.").
+			Default("all").
+			Version("4.31.0").
+			Advanced()).
 		Field(service.NewMetadataFilterField("metadata").
 			Description("Determine which (if any) metadata values should be added to messages as headers.").
 			Optional()).
@@ -104,12 +126,10 @@ This output often out-performs the traditional ` + "`kafka`" + ` output as well
 			Optional().
 			Advanced()).
 		LintRule(`
-root = if this.partitioner == "manual" {
-  if this.partition.or("") == "" {
-    "a partition must be specified when the partitioner is set to manual"
-  }
-} else if this.partition.or("") != "" {
-  "a partition cannot be specified unless the partitioner is set to manual"
+root = match {
+  this.partitioner != "manual" && this.partition.or("") != "" => "a partition cannot be specified unless the partitioner is set to manual"
+  this.partitioner == "expression" && this.partition_expression.or("") == "" => "a partition_expression must be specified when the partitioner is set to expression"
+  this.partitioner != "expression" && this.partition_expression.or("") != "" => "a partition_expression cannot be specified unless the partitioner is set to expression"
 }`)
 }
 
@@ -142,10 +162,12 @@ type franzKafkaWriter struct {
 	topic            *service.InterpolatedString
 	key              *service.InterpolatedString
 	partition        *service.InterpolatedString
+	partitionExpr    *bloblang.Executor
 	timestamp        *service.InterpolatedString
 	clientID         string
 	rackID           string
 	idempotentWrite  bool
+	produceAcks      string
 	tlsConf          *tls.Config
 	saslConfs        []sasl.Mechanism
 	metaFilter       *service.MetadataFilter
@@ -246,6 +268,16 @@ func newFranzKafkaWriterFromConfig(conf *service.ParsedConfig, log *service.Logg
 			f.partitioner = kgo.LeastBackupPartitioner()
 		case "manual":
 			f.partitioner = kgo.ManualPartitioner()
+			if f.partition == nil {
+				if f.partition, err = service.NewInterpolatedString(`${! @kafka_partition }`); err != nil {
+					return nil, err
+				}
+			}
+		case "expression":
+			f.partitioner = kgo.ManualPartitioner()
+			if f.partitionExpr, err = conf.FieldBloblang("partition_expression"); err != nil {
+				return nil, err
+			}
 		default:
 			return nil, fmt.Errorf("unknown partitioner: %v", partStr)
 		}
@@ -263,6 +295,19 @@ func newFranzKafkaWriterFromConfig(conf *service.ParsedConfig, log *service.Logg
 		return nil, err
 	}
 
+	if f.produceAcks, err = conf.FieldString("produce_acks"); err != nil {
+		return nil, err
+	}
+	switch f.produceAcks {
+	case "all":
+	case "leader", "none":
+		// Idempotent writes are only possible when all in-sync replicas
+		// acknowledge each write.
+		f.idempotentWrite = false
+	default:
+		return nil, fmt.Errorf("unknown produce_acks: %v", f.produceAcks)
+	}
+
 	if conf.Contains("metadata") {
 		if f.metaFilter, err = conf.FieldMetadataFilter("metadata"); err != nil {
 			return nil, err
@@ -315,6 +360,15 @@ func (f *franzKafkaWriter) Connect(ctx context.Context) error {
 	if !f.idempotentWrite {
 		clientOpts = append(clientOpts, kgo.DisableIdempotentWrite())
 	}
+	switch f.produceAcks {
+	case "leader":
+		clientOpts = append(clientOpts, kgo.RequiredAcks(kgo.LeaderAck()))
+	case "none":
+		// Produce requests are still issued synchronously, but the broker
+		// does not respond and so each record is considered successful as
+		// soon as it has been written.
+		clientOpts = append(clientOpts, kgo.RequiredAcks(kgo.NoAck()))
+	}
 	if len(f.compressionPrefs) > 0 {
 		clientOpts = append(clientOpts, kgo.ProducerBatchCompression(f.compressionPrefs...))
 	}
@@ -328,16 +382,12 @@ func (f *franzKafkaWriter) Connect(ctx context.Context) error {
 	return nil
 }
 
-func (f *franzKafkaWriter) WriteBatch(ctx context.Context, b service.MessageBatch) (err error) {
-	if f.client == nil {
-		return service.ErrNotConnected
-	}
-
-	records := make([]*kgo.Record, 0, len(b))
+func (f *franzKafkaWriter) recordsFromBatch(b service.MessageBatch) (records []*kgo.Record, err error) {
+	records = make([]*kgo.Record, 0, len(b))
 	for i, msg := range b {
 		var topic string
 		if topic, err = b.TryInterpolatedString(i, f.topic); err != nil {
-			return fmt.Errorf("topic interpolation error: %w", err)
+			return nil, fmt.Errorf("topic interpolation error: %w", err)
 		}
 
 		record := &kgo.Record{Topic: topic}
@@ -346,33 +396,62 @@ func (f *franzKafkaWriter) WriteBatch(ctx context.Context, b service.MessageBatc
 		}
 		if f.key != nil {
 			if record.Key, err = b.TryInterpolatedBytes(i, f.key); err != nil {
-				return fmt.Errorf("key interpolation error: %w", err)
+				return nil, fmt.Errorf("key interpolation error: %w", err)
 			}
 		}
 		if f.partition != nil {
 			partStr, err := b.TryInterpolatedString(i, f.partition)
 			if err != nil {
-				return fmt.Errorf("partition interpolation error: %w", err)
+				return nil, fmt.Errorf("partition interpolation error: %w", err)
+			}
+			partInt, err := strconv.ParseInt(partStr, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("partition parse error: %w", err)
+			}
+			record.Partition = int32(partInt)
+		}
+		if f.partitionExpr != nil {
+			partMsg, err := b.BloblangQuery(i, f.partitionExpr)
+			if err != nil {
+				return nil, fmt.Errorf("partition expression error: %w", err)
+			}
+			if partMsg == nil {
+				return nil, errors.New("partition expression error: mapping deleted the message")
+			}
+			partV, err := partMsg.AsStructured()
+			if err != nil {
+				return nil, fmt.Errorf("partition expression error: %w", err)
 			}
-			partInt, err := strconv.Atoi(partStr)
+			partInt, err := bloblang.ValueAsInt64(partV)
 			if err != nil {
-				return fmt.Errorf("partition parse error: %w", err)
+				return nil, fmt.Errorf("partition expression error: %w", err)
+			}
+			if partInt < 0 || partInt > math.MaxInt32 {
+				return nil, fmt.Errorf("partition expression error: partition %v is out of range", partInt)
 			}
 			record.Partition = int32(partInt)
 		}
+		var wroteExpiry bool
 		_ = f.metaFilter.Walk(msg, func(key, value string) error {
+			wroteExpiry = wroteExpiry || key == expiry.MetadataKey
 			record.Headers = append(record.Headers, kgo.RecordHeader{
 				Key:   key,
 				Value: []byte(value),
 			})
 			return nil
 		})
+		if v, exists := msg.MetaGet(expiry.MetadataKey); exists && !wroteExpiry {
+			record.Headers = append(record.Headers, kgo.RecordHeader{
+				Key:   expiry.MetadataKey,
+				Value: []byte(v),
+			})
+		}
 		if f.timestamp != nil {
 			if tsStr, err := b.TryInterpolatedString(i, f.timestamp); err != nil {
-				return fmt.Errorf("timestamp interpolation error: %w", err)
+				return nil, fmt.Errorf("timestamp interpolation error: %w", err)
 			} else {
 				if ts, err := strconv.ParseInt(tsStr, 10, 64); err != nil {
-					return fmt.Errorf("failed to parse timestamp: %w", err)
+					return nil, fmt.Errorf("failed to parse timestamp: %w", err)
 				} else {
 					record.Timestamp = time.Unix(ts, 0)
 				}
@@ -380,6 +459,22 @@ func (f *franzKafkaWriter) WriteBatch(ctx context.Context, b service.MessageBatc
 		}
 		records = append(records, record)
 	}
+	return
+}
+
+func (f *franzKafkaWriter) WriteBatch(ctx context.Context, b service.MessageBatch) (err error) {
+	if f.client == nil {
+		return service.ErrNotConnected
+	}
+
+	if b = expiry.FilterBatch(b, time.Now()); len(b) == 0 {
+		return nil
+	}
+
+	records, err := f.recordsFromBatch(b)
+	if err != nil {
+		return err
+	}
 
 	// TODO: This is very cool and allows us to easily return granular errors,
 	// so we should honor travis by doing it.
@@ -15,8 +15,11 @@
 package kafka
 
 import (
+	"context"
 	"testing"
 
+	"github.com/twmb/franz-go/pkg/kgo"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -55,7 +58,37 @@ kafka_franz:
   topic: foo
   partitioner: manual
 `,
-			errContains: "a partition must be specified when the partitioner is set to manual",
+		},
+		{
+			name: "expression partitioner with an expression",
+			conf: `
+kafka_franz:
+  seed_brokers: [ foo:1234 ]
+  topic: foo
+  partitioner: expression
+  partition_expression: 'root = this.tenant_id % 4'
+`,
+		},
+		{
+			name: "expression partitioner without an expression",
+			conf: `
+kafka_franz:
+  seed_brokers: [ foo:1234 ]
+  topic: foo
+  partitioner: expression
+`,
+			errContains: "a partition_expression must be specified when the partitioner is set to expression",
+		},
+		{
+			name: "expression without expression partitioner",
+			conf: `
+kafka_franz:
+  seed_brokers: [ foo:1234 ]
+  topic: foo
+  partitioner: manual
+  partition_expression: 'root = 1'
+`,
+			errContains: "a partition_expression cannot be specified unless the partitioner is set to expression",
 		},
 		{
 			name: "partition without manual partitioner",
@@ -82,3 +115,110 @@ kafka_franz:
 		})
 	}
 }
+
+func recordPartitions(records []*kgo.Record) (parts []int32) {
+	for _, r := range records {
+		parts = append(parts, r.Partition)
+	}
+	return
+}
+
+func TestKafkaFranzOutputManualPartitionFromMetadata(t *testing.T) {
+	conf, err := franzKafkaOutputConfig().ParseYAML(`
+seed_brokers: [ foo:1234 ]
+topic: foo
+partitioner: manual
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newFranzKafkaWriterFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	msgA := service.NewMessage([]byte("a"))
+	msgA.MetaSetMut("kafka_partition", "3")
+	msgB := service.NewMessage([]byte("b"))
+	msgB.MetaSetMut("kafka_partition", 1)
+
+	records, err := w.recordsFromBatch(service.MessageBatch{msgA, msgB})
+	require.NoError(t, err)
+	assert.Equal(t, []int32{3, 1}, recordPartitions(records))
+
+	_, err = w.recordsFromBatch(service.MessageBatch{service.NewMessage([]byte("c"))})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "partition parse error")
+}
+
+func TestKafkaFranzOutputExpressionPartition(t *testing.T) {
+	conf, err := franzKafkaOutputConfig().ParseYAML(`
+seed_brokers: [ foo:1234 ]
+topic: foo
+partitioner: expression
+partition_expression: 'root = this.tenant_id % 4'
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newFranzKafkaWriterFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	records, err := w.recordsFromBatch(service.MessageBatch{
+		service.NewMessage([]byte(`{"tenant_id":6}`)),
+		service.NewMessage([]byte(`{"tenant_id":9}`)),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int32{2, 1}, recordPartitions(records))
+
+	_, err = w.recordsFromBatch(service.MessageBatch{
+		service.NewMessage([]byte(`{"tenant_id":"nope"}`)),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "partition expression error")
+}
+
+func TestKafkaFranzOutputExpiryHeader(t *testing.T) {
+	conf, err := franzKafkaOutputConfig().ParseYAML(`
+seed_brokers: [ foo:1234 ]
+topic: foo
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newFranzKafkaWriterFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	msgA := service.NewMessage([]byte("a"))
+	msgA.MetaSetMut("benthos_expires_at", "2024-06-01T00:00:00Z")
+	msgA.MetaSetMut("foo", "bar")
+	msgB := service.NewMessage([]byte("b"))
+
+	records, err := w.recordsFromBatch(service.MessageBatch{msgA, msgB})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, []kgo.RecordHeader{
+		{Key: "benthos_expires_at", Value: []byte("2024-06-01T00:00:00Z")},
+	}, records[0].Headers)
+	assert.Empty(t, records[1].Headers)
+}
+
+func TestKafkaFranzOutputProduceAcks(t *testing.T) {
+	for _, acks := range []string{"all", "leader", "none"} {
+		acks := acks
+		t.Run(acks, func(t *testing.T) {
+			conf, err := franzKafkaOutputConfig().ParseYAML(`
+seed_brokers: [ foo:1234 ]
+topic: foo
+produce_acks: `+acks+`
+`, nil)
+			require.NoError(t, err)
+
+			w, err := newFranzKafkaWriterFromConfig(conf, nil)
+			require.NoError(t, err)
+
+			assert.Equal(t, acks, w.produceAcks)
+			assert.Equal(t, acks == "all", w.idempotentWrite)
+
+			// Non-idempotent acks are rejected by the client unless idempotent
+			// writes are disabled.
+			require.NoError(t, w.Connect(context.Background()))
+			require.NoError(t, w.Close(context.Background()))
+		})
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lang
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+)
+
+const businessCalendarDescription = "A calendar describing which days are not business days, either as an object or as the path of a YAML or JSON file containing the calendar. A calendar consists of a list of `weekend` days, which defaults to Saturday and Sunday, and a list of `holidays` as dates in the format `2006-01-02`. A calendar may also contain a map of `regions`, where each region can override the `weekend` and `holidays` of the calendar. When omitted Saturday and Sunday are considered non-business days and there are no holidays."
+
+func init() {
+	if err := registerAddBusinessDays(); err != nil {
+		panic(err)
+	}
+	if err := registerIsBusinessDay(); err != nil {
+		panic(err)
+	}
+}
+
+type businessCalendarConf struct {
+	Weekend  []string                        `yaml:"weekend"`
+	Holidays []string                        `yaml:"holidays"`
+	Regions  map[string]businessCalendarConf `yaml:"regions"`
+}
+
+// businessCalendar determines whether a given day is a business day.
+type businessCalendar struct {
+	weekend  [7]bool
+	holidays map[string]struct{}
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+func parseWeekday(s string) (time.Weekday, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for name, day := range weekdayNames {
+		if s == name || (len(s) == 3 && strings.HasPrefix(name, s)) {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("unrecognised weekday: %v", s)
+}
+
+func newBusinessCalendar(conf businessCalendarConf, region string) (*businessCalendar, error) {
+	if region != "" {
+		rConf, exists := conf.Regions[region]
+		if !exists {
+			return nil, fmt.Errorf("calendar region not found: %v", region)
+		}
+		if rConf.Weekend != nil {
+			conf.Weekend = rConf.Weekend
+		}
+		if rConf.Holidays != nil {
+			conf.Holidays = rConf.Holidays
+		}
+	}
+
+	c := &businessCalendar{holidays: make(map[string]struct{}, len(conf.Holidays))}
+	if conf.Weekend == nil {
+		conf.Weekend = []string{"saturday", "sunday"}
+	}
+	for _, d := range conf.Weekend {
+		day, err := parseWeekday(d)
+		if err != nil {
+			return nil, err
+		}
+		c.weekend[day] = true
+	}
+	if c.weekend == [7]bool{true, true, true, true, true, true, true} {
+		return nil, errors.New("calendar must contain at least one weekday that is not a weekend day")
+	}
+	for _, h := range conf.Holidays {
+		if _, err := time.Parse(time.DateOnly, h); err != nil {
+			return nil, fmt.Errorf("failed to parse holiday: %w", err)
+		}
+		c.holidays[h] = struct{}{}
+	}
+	return c, nil
+}
+
+// businessCalendarFromArgs parses the optional calendar and region arguments
+// of a method. Calendars provided as a path are read via the environment
+// importer.
+func businessCalendarFromArgs(args *bloblang.ParsedParams) (*businessCalendar, error) {
+	calV, err := args.Get("calendar")
+	if err != nil {
+		return nil, err
+	}
+	region, err := args.GetString("region")
+	if err != nil {
+		return nil, err
+	}
+
+	var calBytes []byte
+	switch t := calV.(type) {
+	case nil:
+	case string:
+		if calBytes, err = args.ImportFile(t); err != nil {
+			return nil, fmt.Errorf("failed to read calendar: %w", err)
+		}
+	case map[string]any:
+		if calBytes, err = json.Marshal(t); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("expected object or string calendar, got %T", calV)
+	}
+
+	var conf businessCalendarConf
+	if len(calBytes) > 0 {
+		if err := yaml.Unmarshal(calBytes, &conf); err != nil {
+			return nil, fmt.Errorf("failed to parse calendar: %w", err)
+		}
+	}
+	return newBusinessCalendar(conf, region)
+}
+
+func (c *businessCalendar) isBusinessDay(t time.Time) bool {
+	if c.weekend[t.Weekday()] {
+		return false
+	}
+	_, isHoliday := c.holidays[t.Format(time.DateOnly)]
+	return !isHoliday
+}
+
+// addBusinessDays moves a timestamp forwards, or backwards when n is
+// negative, by n business days, preserving the time of day.
+func (c *businessCalendar) addBusinessDays(t time.Time, n int64) time.Time {
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	for n > 0 {
+		t = t.AddDate(0, 0, step)
+		if c.isBusinessDay(t) {
+			n--
+		}
+	}
+	return t
+}
+
+func businessCalendarParams(spec *bloblang.PluginSpec) *bloblang.PluginSpec {
+	return spec.
+		Param(bloblang.NewAnyParam("calendar").Description(businessCalendarDescription).Optional()).
+		Param(bloblang.NewStringParam("region").Description("An optional region of the calendar to use.").Default(""))
+}
+
+func registerAddBusinessDays() error {
+	spec := bloblang.NewPluginSpec().
+		Beta().
+		Category("Timestamp Manipulation").
+		Version("4.31.0").
+		Description("Adds a number of business days to a timestamp, skipping weekend days and holidays of a calendar. A negative number of days moves the timestamp backwards. The time of day is preserved and days are determined in the timezone of the timestamp.").
+		Param(bloblang.NewInt64Param("n").Description("The number of business days to add."))
+	spec = businessCalendarParams(spec).
+		Example("Calculate a T+2 settlement date.",
+			`root.settles = this.traded.add_business_days(2, {"holidays":["2024-12-25","2024-12-26"]}).ts_format("2006-01-02")`,
+			[2]string{
+				`{"traded":"2024-12-24T15:04:05Z"}`,
+				`{"settles":"2024-12-30"}`,
+			},
+			[2]string{
+				`{"traded":"2024-12-20T15:04:05Z"}`,
+				`{"settles":"2024-12-24"}`,
+			}).
+		Example("The holidays of a calendar can be overridden per region.",
+			`root.settles = this.traded.add_business_days(n: 1, calendar: {"holidays":["2024-12-25"],"regions":{"uk":{"holidays":["2024-12-25","2024-12-26"]}}}, region: this.region).ts_format("2006-01-02")`,
+			[2]string{
+				`{"traded":"2024-12-24T15:04:05Z","region":""}`,
+				`{"settles":"2024-12-26"}`,
+			},
+			[2]string{
+				`{"traded":"2024-12-24T15:04:05Z","region":"uk"}`,
+				`{"settles":"2024-12-27"}`,
+			}).
+		ExampleNotTested("Calendars can be loaded from a YAML or JSON file, where relative paths are resolved from the location of the mapping.",
+			`root.settles = this.traded.add_business_days(2, "./calendars/nyse.yaml")`)
+
+	return bloblang.RegisterMethodV2("add_business_days", spec, func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+		n, err := args.GetInt64("n")
+		if err != nil {
+			return nil, err
+		}
+		cal, err := businessCalendarFromArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		return bloblang.TimestampMethod(func(t time.Time) (any, error) {
+			return cal.addBusinessDays(t, n), nil
+		}), nil
+	})
+}
+
+func registerIsBusinessDay() error {
+	spec := bloblang.NewPluginSpec().
+		Beta().
+		Category("Timestamp Manipulation").
+		Version("4.31.0").
+		Description("Returns whether a timestamp falls on a business day of a calendar, where days are determined in the timezone of the timestamp.")
+	spec = businessCalendarParams(spec).
+		Example("",
+			`root.business_day = this.date.is_business_day({"weekend":["friday","saturday"],"holidays":["2024-04-10"]})`,
+			[2]string{`{"date":"2024-04-07T12:00:00Z"}`, `{"business_day":true}`},
+			[2]string{`{"date":"2024-04-10T12:00:00Z"}`, `{"business_day":false}`},
+			[2]string{`{"date":"2024-04-12T12:00:00Z"}`, `{"business_day":false}`})
+
+	return bloblang.RegisterMethodV2("is_business_day", spec, func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+		cal, err := businessCalendarFromArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		return bloblang.TimestampMethod(func(t time.Time) (any, error) {
+			return cal.isBusinessDay(t), nil
+		}), nil
+	})
+}
@@ -0,0 +1,158 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lang
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+)
+
+func TestAddBusinessDays(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping string
+		input   string
+		output  string
+	}{
+		{
+			name:    "default calendar skips weekends",
+			mapping: `root = this.add_business_days(2)`,
+			input:   "2024-06-06T10:00:00Z",
+			output:  "2024-06-10T10:00:00Z",
+		},
+		{
+			name:    "negative days",
+			mapping: `root = this.add_business_days(-1)`,
+			input:   "2024-06-10T10:00:00Z",
+			output:  "2024-06-07T10:00:00Z",
+		},
+		{
+			name:    "zero days on a weekend",
+			mapping: `root = this.add_business_days(0)`,
+			input:   "2024-06-08T10:00:00Z",
+			output:  "2024-06-08T10:00:00Z",
+		},
+		{
+			name:    "holidays",
+			mapping: `root = this.add_business_days(2, {"holidays":["2024-12-25","2024-12-26"]})`,
+			input:   "2024-12-24T10:00:00Z",
+			output:  "2024-12-30T10:00:00Z",
+		},
+		{
+			name:    "custom weekend",
+			mapping: `root = this.add_business_days(1, {"weekend":["fri","sat"]})`,
+			input:   "2024-06-06T10:00:00Z",
+			output:  "2024-06-09T10:00:00Z",
+		},
+		{
+			name:    "region overrides holidays",
+			mapping: `root = this.add_business_days(1, {"holidays":["2024-12-25"],"regions":{"uk":{"holidays":["2024-12-26"]}}}, "uk")`,
+			input:   "2024-12-24T10:00:00Z",
+			output:  "2024-12-25T10:00:00Z",
+		},
+		{
+			name:    "days are determined in the timezone of the timestamp",
+			mapping: `root = this.add_business_days(1)`,
+			input:   "2024-06-07T23:30:00-05:00",
+			output:  "2024-06-10T23:30:00-05:00",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			exec, err := bloblang.Parse(test.mapping)
+			require.NoError(t, err)
+
+			res, err := exec.Query(test.input)
+			require.NoError(t, err)
+
+			ts, ok := res.(time.Time)
+			require.True(t, ok, "%T", res)
+			assert.Equal(t, test.output, ts.Format(time.RFC3339))
+		})
+	}
+}
+
+func TestIsBusinessDay(t *testing.T) {
+	exec, err := bloblang.Parse(`root = this.is_business_day({"holidays":["2024-12-25"]})`)
+	require.NoError(t, err)
+
+	for input, exp := range map[string]bool{
+		"2024-12-24T10:00:00Z": true,
+		"2024-12-25T10:00:00Z": false,
+		"2024-12-28T10:00:00Z": false,
+	} {
+		res, err := exec.Query(input)
+		require.NoError(t, err)
+		assert.Equal(t, exp, res, input)
+	}
+}
+
+func TestBusinessCalendarFromFile(t *testing.T) {
+	dir := t.TempDir()
+	calPath := filepath.Join(dir, "calendar.yaml")
+	require.NoError(t, os.WriteFile(calPath, []byte(`
+weekend: [ sunday ]
+holidays: [ 2024-06-10 ]
+regions:
+  east:
+    holidays: []
+`), 0o644))
+
+	exec, err := bloblang.Parse(`root = [ this.add_business_days(1, "` + calPath + `"), this.add_business_days(1, "` + calPath + `", "east") ]`)
+	require.NoError(t, err)
+
+	res, err := exec.Query("2024-06-07T10:00:00Z")
+	require.NoError(t, err)
+
+	arr, ok := res.([]any)
+	require.True(t, ok)
+	require.Len(t, arr, 2)
+	assert.Equal(t, "2024-06-08T10:00:00Z", arr[0].(time.Time).Format(time.RFC3339))
+	assert.Equal(t, "2024-06-08T10:00:00Z", arr[1].(time.Time).Format(time.RFC3339))
+
+	exec, err = bloblang.Parse(`root = this.add_business_days(2, "` + calPath + `")`)
+	require.NoError(t, err)
+	res, err = exec.Query("2024-06-07T10:00:00Z")
+	require.NoError(t, err)
+	assert.Equal(t, "2024-06-11T10:00:00Z", res.(time.Time).Format(time.RFC3339))
+
+	exec, err = bloblang.Parse(`root = this.add_business_days(2, "` + calPath + `", "east")`)
+	require.NoError(t, err)
+	res, err = exec.Query("2024-06-07T10:00:00Z")
+	require.NoError(t, err)
+	assert.Equal(t, "2024-06-10T10:00:00Z", res.(time.Time).Format(time.RFC3339))
+}
+
+func TestBusinessCalendarErrors(t *testing.T) {
+	for _, mapping := range []string{
+		`root = this.add_business_days(1, {"weekend":["funday"]})`,
+		`root = this.add_business_days(1, {"holidays":["christmas"]})`,
+		`root = this.add_business_days(1, {"weekend":["mon","tue","wed","thu","fri","sat","sun"]})`,
+		`root = this.add_business_days(1, {}, "nope")`,
+		`root = this.is_business_day(10)`,
+	} {
+		_, err := bloblang.Parse(mapping)
+		assert.Error(t, err, mapping)
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lang
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+)
+
+func init() {
+	if err := registerCheckedMethod("add_checked", "Adds a number to an integer", addInt64Checked,
+		`root.total = this.a.add_checked(this.b)`,
+		[2]string{`{"a":9223372036854775800,"b":7}`, `{"total":9223372036854775807}`},
+	); err != nil {
+		panic(err)
+	}
+	if err := registerCheckedMethod("sub_checked", "Subtracts a number from an integer", subInt64Checked,
+		`root.balance = this.balance.sub_checked(this.debit)`,
+		[2]string{`{"balance":100,"debit":250}`, `{"balance":-150}`},
+	); err != nil {
+		panic(err)
+	}
+	if err := registerCheckedMethod("mul_checked", "Multiplies an integer by a number", mulInt64Checked,
+		`root.cents = this.units.mul_checked(this.unit_cents).catch(-1)`,
+		[2]string{`{"units":3,"unit_cents":1999}`, `{"cents":5997}`},
+		[2]string{`{"units":4611686018427387904,"unit_cents":2}`, `{"cents":-1}`},
+	); err != nil {
+		panic(err)
+	}
+}
+
+// maxExactFloatInt is the largest magnitude of an integer that can be
+// represented exactly as a float64.
+const maxExactFloatInt = 1 << 53
+
+// exactInt64 converts a numeric value into an int64, returning an error rather
+// than truncating or rounding when the value cannot be represented exactly.
+func exactInt64(v any) (int64, error) {
+	switch t := v.(type) {
+	case int:
+		return int64(t), nil
+	case int8:
+		return int64(t), nil
+	case int16:
+		return int64(t), nil
+	case int32:
+		return int64(t), nil
+	case int64:
+		return t, nil
+	case uint:
+		return exactInt64(uint64(t))
+	case uint8:
+		return int64(t), nil
+	case uint16:
+		return int64(t), nil
+	case uint32:
+		return int64(t), nil
+	case uint64:
+		if t > math.MaxInt64 {
+			return 0, fmt.Errorf("integer overflow: %v exceeds the maximum of a signed 64-bit integer", t)
+		}
+		return int64(t), nil
+	case float32:
+		return exactInt64(float64(t))
+	case float64:
+		if t != math.Trunc(t) {
+			return 0, fmt.Errorf("expected an integer, got %v", t)
+		}
+		if math.Abs(t) > maxExactFloatInt {
+			return 0, fmt.Errorf("number %v cannot be represented exactly as an integer", t)
+		}
+		return int64(t), nil
+	case json.Number:
+		return t.Int64()
+	}
+	return 0, fmt.Errorf("expected number value, got %T", v)
+}
+
+func addInt64Checked(a, b int64) (int64, error) {
+	c := a + b
+	if (c > a) != (b > 0) {
+		return 0, fmt.Errorf("integer overflow: %v + %v", a, b)
+	}
+	return c, nil
+}
+
+func subInt64Checked(a, b int64) (int64, error) {
+	c := a - b
+	if (c < a) != (b > 0) {
+		return 0, fmt.Errorf("integer overflow: %v - %v", a, b)
+	}
+	return c, nil
+}
+
+func mulInt64Checked(a, b int64) (int64, error) {
+	if a == 0 || b == 0 {
+		return 0, nil
+	}
+	hi, lo := bits.Mul64(absInt64(a), absInt64(b))
+	limit := uint64(math.MaxInt64)
+	if (a < 0) != (b < 0) {
+		limit++
+	}
+	if hi != 0 || lo > limit {
+		return 0, fmt.Errorf("integer overflow: %v * %v", a, b)
+	}
+	return a * b, nil
+}
+
+// absInt64 returns the absolute value of i as an unsigned integer, which can
+// represent the absolute value of math.MinInt64.
+func absInt64(i int64) uint64 {
+	if i < 0 {
+		return uint64(-i)
+	}
+	return uint64(i)
+}
+
+func registerCheckedMethod(name, summary string, fn func(a, b int64) (int64, error), mapping string, examples ...[2]string) error {
+	spec := bloblang.NewPluginSpec().
+		Beta().
+		Category("Number Manipulation").
+		Version("4.31.0").
+		Description(summary+" and returns the result, or an error when the result overflows a signed 64-bit integer. Both values must be integers, and floating point values are only accepted when they are whole numbers that can be represented exactly, which prevents the loss of precision that occurs with regular arithmetic. The error can be handled with the `catch` method.").
+		Param(bloblang.NewAnyParam("value").Description("The integer operand.")).
+		Example("", mapping, examples...)
+
+	return bloblang.RegisterMethodV2(name, spec, func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+		v, err := args.Get("value")
+		if err != nil {
+			return nil, err
+		}
+		b, err := exactInt64(v)
+		if err != nil {
+			return nil, err
+		}
+		return func(v any) (any, error) {
+			a, err := exactInt64(v)
+			if err != nil {
+				return nil, err
+			}
+			return fn(a, b)
+		}, nil
+	})
+}
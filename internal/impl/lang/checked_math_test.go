@@ -0,0 +1,85 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lang
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+)
+
+func TestCheckedArithmetic(t *testing.T) {
+	tests := []struct {
+		mapping     string
+		input       any
+		output      any
+		errContains string
+	}{
+		{mapping: `root = 5.add_checked(7)`, output: int64(12)},
+		{mapping: `root = 9223372036854775806.add_checked(1)`, output: int64(math.MaxInt64)},
+		{mapping: `root = 9223372036854775807.add_checked(1)`, errContains: "integer overflow"},
+		{mapping: `root = this.add_checked(-2)`, input: int64(math.MinInt64 + 1), errContains: "integer overflow"},
+		{mapping: `root = 5.sub_checked(7)`, output: int64(-2)},
+		{mapping: `root = this.sub_checked(1)`, input: int64(math.MinInt64 + 1), output: int64(math.MinInt64)},
+		{mapping: `root = this.sub_checked(2)`, input: int64(math.MinInt64 + 1), errContains: "integer overflow"},
+		{mapping: `root = 9223372036854775807.sub_checked(-1)`, errContains: "integer overflow"},
+		{mapping: `root = -6.mul_checked(7)`, output: int64(-42)},
+		{mapping: `root = 0.mul_checked(9223372036854775807)`, output: int64(0)},
+		{mapping: `root = 4611686018427387904.mul_checked(-2)`, output: int64(math.MinInt64)},
+		{mapping: `root = 4611686018427387904.mul_checked(2)`, errContains: "integer overflow"},
+		{mapping: `root = 3037000500.mul_checked(3037000500)`, errContains: "integer overflow"},
+		{mapping: `root = 5.add_checked(1.5)`, errContains: "expected an integer"},
+		{mapping: `root = 1.5.add_checked(1)`, errContains: "expected an integer"},
+		{mapping: `root = 2.0.add_checked(1)`, output: int64(3)},
+		{mapping: `root = this.add_checked(1)`, input: 1e300, errContains: "cannot be represented exactly"},
+		{mapping: `root = "5".add_checked(1)`, errContains: "expected number value"},
+		{mapping: `root = 9223372036854775807.add_checked(1).catch(0)`, output: int64(0)},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(fmt.Sprintf("%v %v", test.mapping, test.input), func(t *testing.T) {
+			exec, err := bloblang.Parse(test.mapping)
+			if err == nil {
+				var res any
+				res, err = exec.Query(test.input)
+				if err == nil {
+					require.Empty(t, test.errContains)
+					assert.Equal(t, test.output, res)
+					return
+				}
+			}
+			require.NotEmpty(t, test.errContains, err)
+			require.ErrorContains(t, err, test.errContains)
+		})
+	}
+}
+
+func TestCheckedArithmeticDynamicArgs(t *testing.T) {
+	exec, err := bloblang.Parse(`root = this.a.mul_checked(this.b)`)
+	require.NoError(t, err)
+
+	res, err := exec.Query(map[string]any{"a": int64(-3), "b": uint64(5)})
+	require.NoError(t, err)
+	assert.Equal(t, int64(-15), res)
+
+	_, err = exec.Query(map[string]any{"a": int64(1), "b": uint64(math.MaxUint64)})
+	require.ErrorContains(t, err, "integer overflow")
+}
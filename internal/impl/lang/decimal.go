@@ -0,0 +1,328 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lang
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+)
+
+const decimalDescription = "Decimals are represented as strings in order to preserve their precision, and both numbers and strings are accepted as decimal values. Converting a decimal into a floating point number with the `number` method loses this precision."
+
+var decimalRoundingModes = []string{"half_even", "half_up", "half_down", "up", "down", "ceil", "floor"}
+
+func init() {
+	if err := registerDecimal(); err != nil {
+		panic(err)
+	}
+	for _, op := range []struct {
+		name, summary string
+		fn            func(a, b decimal.Decimal) decimal.Decimal
+		mapping       string
+		example       [2]string
+	}{
+		{
+			name:    "decimal_add",
+			summary: "Adds a decimal value to a decimal",
+			fn:      decimal.Decimal.Add,
+			mapping: `root.total = this.price.decimal_add(this.tax)`,
+			example: [2]string{`{"price":"0.1","tax":0.2}`, `{"total":"0.3"}`},
+		},
+		{
+			name:    "decimal_sub",
+			summary: "Subtracts a decimal value from a decimal",
+			fn:      decimal.Decimal.Sub,
+			mapping: `root.balance = this.balance.decimal_sub(this.debit)`,
+			example: [2]string{`{"balance":"100.10","debit":"0.35"}`, `{"balance":"99.75"}`},
+		},
+		{
+			name:    "decimal_mul",
+			summary: "Multiplies a decimal by a decimal value",
+			fn:      decimal.Decimal.Mul,
+			mapping: `root.total = this.price.decimal_mul(value: this.quantity, scale: 2)`,
+			example: [2]string{`{"price":"19.99","quantity":3}`, `{"total":"59.97"}`},
+		},
+	} {
+		if err := registerDecimalOp(op.name, op.summary, op.fn, op.mapping, op.example); err != nil {
+			panic(err)
+		}
+	}
+	if err := registerDecimalDiv(); err != nil {
+		panic(err)
+	}
+}
+
+// toDecimal converts a number or string into a decimal. Floating point values
+// are converted using the shortest representation that round trips to the same
+// value, and therefore 0.1 is converted exactly into 0.1.
+func toDecimal(v any) (decimal.Decimal, error) {
+	switch t := v.(type) {
+	case string:
+		return decimal.NewFromString(t)
+	case []byte:
+		return decimal.NewFromString(string(t))
+	case json.Number:
+		return decimal.NewFromString(t.String())
+	case float64:
+		return decimal.NewFromFloat(t), nil
+	case float32:
+		return decimal.NewFromFloat32(t), nil
+	case uint64:
+		return decimal.NewFromBigInt(new(big.Int).SetUint64(t), 0), nil
+	}
+	i, err := exactInt64(v)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("expected number or string value, got %T", v)
+	}
+	return decimal.NewFromInt(i), nil
+}
+
+func checkRoundingMode(mode string) error {
+	for _, m := range decimalRoundingModes {
+		if m == mode {
+			return nil
+		}
+	}
+	return fmt.Errorf("unrecognised rounding mode: %v", mode)
+}
+
+// quoRound divides d by d2 and rounds the quotient to scale decimal places
+// with a rounding mode.
+func quoRound(d, d2 decimal.Decimal, scale int32, mode string) (decimal.Decimal, error) {
+	if d2.IsZero() {
+		return decimal.Decimal{}, errors.New("division by zero")
+	}
+
+	// The quotient is truncated towards zero and the sign of the remainder
+	// follows the dividend, so the discarded fraction is r / d2.
+	q, r := d.QuoRem(d2, scale)
+	if r.IsZero() {
+		return q, nil
+	}
+	fracNeg := r.Sign() != d2.Sign()
+
+	ulp := decimal.New(1, -scale)
+	away := q.Add(ulp)
+	if fracNeg {
+		away = q.Sub(ulp)
+	}
+
+	// Compare the discarded fraction against half of a unit in the last place.
+	half := r.Abs().Mul(decimal.NewFromInt(2)).Cmp(d2.Abs().Mul(ulp))
+
+	switch mode {
+	case "down":
+		return q, nil
+	case "up":
+		return away, nil
+	case "ceil":
+		if !fracNeg {
+			return away, nil
+		}
+		return q, nil
+	case "floor":
+		if fracNeg {
+			return away, nil
+		}
+		return q, nil
+	case "half_up":
+		if half >= 0 {
+			return away, nil
+		}
+		return q, nil
+	case "half_down":
+		if half > 0 {
+			return away, nil
+		}
+		return q, nil
+	case "half_even":
+		if half > 0 || (half == 0 && q.Shift(scale).BigInt().Bit(0) == 1) {
+			return away, nil
+		}
+		return q, nil
+	}
+	return decimal.Decimal{}, fmt.Errorf("unrecognised rounding mode: %v", mode)
+}
+
+func decimalScaleParams(spec *bloblang.PluginSpec, scaleParam bloblang.ParamDefinition) *bloblang.PluginSpec {
+	return spec.
+		Param(scaleParam).
+		Param(bloblang.NewStringParam("rounding").
+			Description(fmt.Sprintf("The rounding mode used when reducing the number of decimal places, one of: %v. The mode `half_even`, also known as banker's rounding, rounds halves towards the nearest even digit, `half_up` and `half_down` round halves away from and towards zero respectively, `up` and `down` round away from and towards zero, and `ceil` and `floor` round towards positive and negative infinity.", decimalRoundingModes)).
+			Default("half_even"))
+}
+
+// decimalScaleFromArgs returns a function that rounds a decimal to the scale
+// and rounding mode provided as arguments, where a nil scale leaves decimals
+// unmodified.
+func decimalScaleFromArgs(args *bloblang.ParsedParams) (func(d decimal.Decimal) (string, error), error) {
+	scale, err := args.GetOptionalInt64("scale")
+	if err != nil {
+		return nil, err
+	}
+	mode, err := args.GetString("rounding")
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRoundingMode(mode); err != nil {
+		return nil, err
+	}
+	if scale == nil {
+		return func(d decimal.Decimal) (string, error) {
+			return d.String(), nil
+		}, nil
+	}
+	if *scale < 0 {
+		return nil, errors.New("scale must not be negative")
+	}
+	s := int32(*scale)
+	return func(d decimal.Decimal) (string, error) {
+		d, err := quoRound(d, decimal.NewFromInt(1), s, mode)
+		if err != nil {
+			return "", err
+		}
+		return d.StringFixed(s), nil
+	}, nil
+}
+
+func optionalScaleParam() bloblang.ParamDefinition {
+	return bloblang.NewInt64Param("scale").
+		Description("An optional number of decimal places to round the result to. When omitted the result is exact.").
+		Optional()
+}
+
+func registerDecimal() error {
+	spec := bloblang.NewPluginSpec().
+		Beta().
+		Category("Number Manipulation").
+		Version("4.31.0").
+		Description("Converts a number or string into an arbitrary precision decimal, optionally rounding it to a number of decimal places. " + decimalDescription)
+	spec = decimalScaleParams(spec, optionalScaleParam()).
+		Example("",
+			`root.amount = this.amount.decimal(2)`,
+			[2]string{`{"amount":"10.005"}`, `{"amount":"10.00"}`},
+			[2]string{`{"amount":10.015}`, `{"amount":"10.02"}`},
+			[2]string{`{"amount":7}`, `{"amount":"7.00"}`}).
+		Example("",
+			`root.amount = this.amount.decimal(scale: 0, rounding: "floor")`,
+			[2]string{`{"amount":"-2.5"}`, `{"amount":"-3"}`})
+
+	return bloblang.RegisterMethodV2("decimal", spec, func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+		format, err := decimalScaleFromArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		return func(v any) (any, error) {
+			d, err := toDecimal(v)
+			if err != nil {
+				return nil, err
+			}
+			return format(d)
+		}, nil
+	})
+}
+
+func registerDecimalOp(name, summary string, fn func(a, b decimal.Decimal) decimal.Decimal, mapping string, example [2]string) error {
+	spec := bloblang.NewPluginSpec().
+		Beta().
+		Category("Number Manipulation").
+		Version("4.31.0").
+		Description(summary + " using arbitrary precision decimal arithmetic, optionally rounding the result to a number of decimal places. " + decimalDescription).
+		Param(bloblang.NewAnyParam("value").Description("The decimal operand, either a number or a string."))
+	spec = decimalScaleParams(spec, optionalScaleParam()).
+		Example("", mapping, example)
+
+	return bloblang.RegisterMethodV2(name, spec, func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+		v, err := args.Get("value")
+		if err != nil {
+			return nil, err
+		}
+		b, err := toDecimal(v)
+		if err != nil {
+			return nil, err
+		}
+		format, err := decimalScaleFromArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		return func(v any) (any, error) {
+			a, err := toDecimal(v)
+			if err != nil {
+				return nil, err
+			}
+			return format(fn(a, b))
+		}, nil
+	})
+}
+
+func registerDecimalDiv() error {
+	spec := bloblang.NewPluginSpec().
+		Beta().
+		Category("Number Manipulation").
+		Version("4.31.0").
+		Description("Divides a decimal by a decimal value using arbitrary precision decimal arithmetic, rounding the result to a number of decimal places. An error is returned when dividing by zero. " + decimalDescription).
+		Param(bloblang.NewAnyParam("value").Description("The decimal divisor, either a number or a string."))
+	spec = decimalScaleParams(spec, bloblang.NewInt64Param("scale").
+		Description("The number of decimal places to round the result to.").
+		Default(16)).
+		Example("",
+			`root.share = this.total.decimal_div(value: 3, scale: 2)`,
+			[2]string{`{"total":"100"}`, `{"share":"33.33"}`}).
+		Example("",
+			`root.rate = this.a.decimal_div(value: this.b, scale: 1, rounding: "half_up")`,
+			[2]string{`{"a":"1","b":"4"}`, `{"rate":"0.3"}`})
+
+	return bloblang.RegisterMethodV2("decimal_div", spec, func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+		v, err := args.Get("value")
+		if err != nil {
+			return nil, err
+		}
+		b, err := toDecimal(v)
+		if err != nil {
+			return nil, err
+		}
+		scale, err := args.GetInt64("scale")
+		if err != nil {
+			return nil, err
+		}
+		if scale < 0 {
+			return nil, errors.New("scale must not be negative")
+		}
+		mode, err := args.GetString("rounding")
+		if err != nil {
+			return nil, err
+		}
+		if err := checkRoundingMode(mode); err != nil {
+			return nil, err
+		}
+		return func(v any) (any, error) {
+			a, err := toDecimal(v)
+			if err != nil {
+				return nil, err
+			}
+			q, err := quoRound(a, b, int32(scale), mode)
+			if err != nil {
+				return nil, err
+			}
+			return q.StringFixed(int32(scale)), nil
+		}, nil
+	})
+}
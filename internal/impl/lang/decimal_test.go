@@ -0,0 +1,102 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lang
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+)
+
+func TestDecimalMethods(t *testing.T) {
+	tests := []struct {
+		mapping     string
+		output      any
+		errContains string
+	}{
+		{mapping: `root = 0.1.decimal_add(0.2)`, output: "0.3"},
+		{mapping: `root = "0.1".decimal_add("0.2", 2)`, output: "0.30"},
+		{mapping: `root = "12345678901234567890.01".decimal_add("0.02")`, output: "12345678901234567890.03"},
+		{mapping: `root = "1".decimal_sub("0.9")`, output: "0.1"},
+		{mapping: `root = "1.005".decimal_mul(3)`, output: "3.015"},
+		{mapping: `root = "1.005".decimal_mul(3, 2)`, output: "3.02"},
+		{mapping: `root = "1.005".decimal_mul(3, 2, "down")`, output: "3.01"},
+		{mapping: `root = "2".decimal_div(3)`, output: "0.6666666666666667"},
+		{mapping: `root = "2".decimal_div(3, 2, "down")`, output: "0.66"},
+		{mapping: `root = "-2".decimal_div(3, 2, "ceil")`, output: "-0.66"},
+		{mapping: `root = "-2".decimal_div(3, 2, "floor")`, output: "-0.67"},
+		{mapping: `root = "10".decimal_div(4, 0)`, output: "2"},
+		{mapping: `root = "10".decimal_div(4, 0, "half_up")`, output: "3"},
+		{mapping: `root = "1".decimal_div(0)`, errContains: "division by zero"},
+		{mapping: `root = "2.5".decimal(0)`, output: "2"},
+		{mapping: `root = "3.5".decimal(0)`, output: "4"},
+		{mapping: `root = "-2.5".decimal(0, "half_up")`, output: "-3"},
+		{mapping: `root = "-2.5".decimal(0, "half_down")`, output: "-2"},
+		{mapping: `root = "2.01".decimal(0, "up")`, output: "3"},
+		{mapping: `root = "2.5".decimal()`, output: "2.5"},
+		{mapping: `root = 5.decimal(2)`, output: "5.00"},
+		{mapping: `root = "nope".decimal()`, errContains: "can't convert"},
+		{mapping: `root = true.decimal()`, errContains: "expected number or string"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.mapping, func(t *testing.T) {
+			exec, err := bloblang.Parse(test.mapping)
+			require.NoError(t, err)
+
+			res, err := exec.Query(nil)
+			if test.errContains != "" {
+				require.ErrorContains(t, err, test.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.output, res)
+		})
+	}
+}
+
+func TestDecimalBadArgs(t *testing.T) {
+	for _, mapping := range []string{
+		`root = this.decimal(2, "sideways")`,
+		`root = this.decimal(-1)`,
+		`root = this.decimal_div(2, -1)`,
+		`root = this.decimal_add("nope")`,
+	} {
+		_, err := bloblang.Parse(mapping)
+		assert.Error(t, err, mapping)
+	}
+}
+
+func TestQuoRoundHalfEven(t *testing.T) {
+	for in, exp := range map[string]string{
+		"0.125":  "0.12",
+		"0.135":  "0.14",
+		"-0.125": "-0.12",
+		"-0.135": "-0.14",
+		"0.1251": "0.13",
+	} {
+		d, err := decimal.NewFromString(in)
+		require.NoError(t, err)
+
+		q, err := quoRound(d, decimal.NewFromInt(1), 2, "half_even")
+		require.NoError(t, err)
+		assert.Equal(t, exp, q.StringFixed(2), in)
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lang
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+)
+
+const randomSeedDescription = "An optional seed for the random number generator. When the seed is a static value the generator is seeded once and produces the same sequence of values each time the mapping is created. When the seed is a dynamic query, such as a field of the input document, the generator is seeded on each invocation and the result is therefore determined by the value of the seed. Strings are hashed in order to obtain a seed. When omitted the generator is seeded randomly."
+
+func init() {
+	if err := registerRandomFloat(); err != nil {
+		panic(err)
+	}
+	if err := registerRandomChoice(); err != nil {
+		panic(err)
+	}
+}
+
+// seedToInt64 converts a seed argument of any scalar type into an integer,
+// hashing strings so that identifiers can be used as seeds.
+func seedToInt64(v any) (int64, error) {
+	switch t := v.(type) {
+	case int64:
+		return t, nil
+	case uint64:
+		return int64(t), nil
+	case float64:
+		return int64(t), nil
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i, nil
+		}
+		f, err := t.Float64()
+		return int64(f), err
+	case string:
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(t))
+		return int64(h.Sum64()), nil
+	case []byte:
+		h := fnv.New64a()
+		_, _ = h.Write(t)
+		return int64(h.Sum64()), nil
+	}
+	return 0, fmt.Errorf("expected number or string seed, got %T", v)
+}
+
+// lockedRand wraps a generator so that it can be shared across concurrent
+// executions of a mapping.
+type lockedRand struct {
+	mut sync.Mutex
+	r   *rand.Rand
+}
+
+func newLockedRandFromArgs(args *bloblang.ParsedParams) (*lockedRand, error) {
+	seedV, err := args.Get("seed")
+	if err != nil {
+		return nil, err
+	}
+
+	seed := time.Now().UnixNano()
+	if seedV != nil {
+		if seed, err = seedToInt64(seedV); err != nil {
+			return nil, err
+		}
+	}
+	return &lockedRand{r: rand.New(rand.NewSource(seed))}, nil
+}
+
+func (l *lockedRand) Float64() float64 {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	return l.r.Float64()
+}
+
+func (l *lockedRand) Intn(n int) int {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	return l.r.Intn(n)
+}
+
+func registerRandomFloat() error {
+	spec := bloblang.NewPluginSpec().
+		Beta().
+		Category("General").
+		Version("4.31.0").
+		Description("Generates a pseudo-random floating point number in the half-open interval [0.0,1.0).").
+		Param(bloblang.NewAnyParam("seed").Description(randomSeedDescription).Optional()).
+		Example("", `root.sample = random_float() < 0.1`).
+		Example("Seeding the generator with a field of the input document results in the same number for each document with that value, which is useful for deterministic sampling.", `root = if random_float(this.user_id) >= 0.1 { deleted() }`)
+
+	return bloblang.RegisterFunctionV2("random_float", spec, func(args *bloblang.ParsedParams) (bloblang.Function, error) {
+		r, err := newLockedRandFromArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		return func() (any, error) {
+			return r.Float64(), nil
+		}, nil
+	})
+}
+
+func registerRandomChoice() error {
+	spec := bloblang.NewPluginSpec().
+		Beta().
+		Category("General").
+		Version("4.31.0").
+		Description("Returns a pseudo-random element of an array.").
+		Param(bloblang.NewQueryParam("values", true).Description("An array to choose an element from.")).
+		Param(bloblang.NewAnyParam("seed").Description(randomSeedDescription).Optional()).
+		Example("", `root.colour = random_choice(["red", "green", "blue"])`).
+		Example("A seed can be provided in order to make the sequence of choices reproducible, which is useful for generating test data.", `root.region = random_choice(this.regions, 42)`)
+
+	return bloblang.RegisterAdvancedFunction("random_choice", spec, func(args *bloblang.ParsedParams) (bloblang.AdvancedFunction, error) {
+		valuesFn, err := args.GetQuery("values")
+		if err != nil {
+			return nil, err
+		}
+		r, err := newLockedRandFromArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx *bloblang.ExecContext) (any, error) {
+			v, err := ctx.Exec(valuesFn)
+			if err != nil {
+				return nil, err
+			}
+			arr, ok := v.([]any)
+			if !ok {
+				return nil, fmt.Errorf("expected array value, got %T", v)
+			}
+			if len(arr) == 0 {
+				return nil, errors.New("cannot choose an element from an empty array")
+			}
+			return arr[r.Intn(len(arr))], nil
+		}, nil
+	})
+}
@@ -0,0 +1,86 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lang
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+)
+
+func queryN(t testing.TB, mapping string, input any, n int) []any {
+	t.Helper()
+
+	ex, err := bloblang.Parse(mapping)
+	require.NoError(t, err)
+
+	var res []any
+	for i := 0; i < n; i++ {
+		v, err := ex.Query(input)
+		require.NoError(t, err)
+		res = append(res, v)
+	}
+	return res
+}
+
+func TestRandomFloat(t *testing.T) {
+	for _, v := range queryN(t, `root = random_float()`, nil, 100) {
+		f, ok := v.(float64)
+		require.True(t, ok)
+		assert.GreaterOrEqual(t, f, 0.0)
+		assert.Less(t, f, 1.0)
+	}
+
+	// A static seed produces the same sequence for each new mapping.
+	first := queryN(t, `root = random_float(10)`, nil, 5)
+	assert.Equal(t, first, queryN(t, `root = random_float(10)`, nil, 5))
+	assert.NotEqual(t, first[0], first[1])
+
+	// A dynamic seed produces the same value for the same input.
+	keyed := queryN(t, `root = random_float(this.id)`, map[string]any{"id": "foo"}, 3)
+	assert.Equal(t, keyed[0], keyed[1])
+	assert.Equal(t, keyed[0], keyed[2])
+	assert.NotEqual(t, keyed[0], queryN(t, `root = random_float(this.id)`, map[string]any{"id": "bar"}, 1)[0])
+}
+
+func TestRandomChoice(t *testing.T) {
+	values := []any{"a", "b", "c"}
+	for _, v := range queryN(t, `root = random_choice(["a", "b", "c"])`, nil, 20) {
+		assert.Contains(t, values, v)
+	}
+
+	// The values can be dynamic whilst the seed remains static.
+	input := map[string]any{"values": values}
+	first := queryN(t, `root = random_choice(this.values, 42)`, input, 20)
+	assert.Equal(t, first, queryN(t, `root = random_choice(this.values, 42)`, input, 20))
+
+	seen := map[any]struct{}{}
+	for _, v := range first {
+		seen[v] = struct{}{}
+	}
+	assert.Greater(t, len(seen), 1)
+
+	ex, err := bloblang.Parse(`root = random_choice(this.values)`)
+	require.NoError(t, err)
+
+	_, err = ex.Query(map[string]any{"values": []any{}})
+	require.Error(t, err)
+
+	_, err = ex.Query(map[string]any{"values": "nope"})
+	require.Error(t, err)
+}
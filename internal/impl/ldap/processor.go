@@ -0,0 +1,513 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	lpFieldURL          = "url"
+	lpFieldStartTLS     = "start_tls"
+	lpFieldTLS          = "tls"
+	lpFieldBindDN       = "bind_dn"
+	lpFieldBindPassword = "bind_password"
+	lpFieldBaseDN       = "base_dn"
+	lpFieldScope        = "scope"
+	lpFieldFilter       = "filter"
+	lpFieldArgsMapping  = "args_mapping"
+	lpFieldAttributes   = "attributes"
+	lpFieldTargetPath   = "target_path"
+	lpFieldNoMatch      = "no_match"
+	lpFieldCache        = "cache"
+	lpFieldCacheTTL     = "cache_ttl"
+	lpFieldTimeout      = "timeout"
+)
+
+func processorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.31.0").
+		Categories("Integration").
+		Summary("Enriches messages with the attributes of an entry found by searching an LDAP directory, such as Active Directory.").
+		Description(`
+For each message a search is performed beneath the `+"`base_dn`"+` with the `+"`filter`"+`, and the attributes of the first entry found are written as an object to the `+"`target_path`"+` of the message, along with the DN of the entry in the field `+"`dn`"+`. Attributes with a single value are written as a string, and attributes with multiple values are written as an array of strings.
+
+When no entry is found the message is passed on unchanged with `+"`no_match`"+` set to `+"`pass`"+`, and is otherwise flagged as failed so that it can be handled using xref:configuration:error_handling.adoc[error handling methods].
+
+== Filters
+
+Values from messages should be added to the filter with placeholders, where each `+"`?`"+` within the filter is replaced with an element of the array resulting from `+"`args_mapping`"+`. Placeholder values are escaped, such that they cannot change the structure of the filter. Values added to the filter with interpolation functions are not escaped.
+
+== Connections
+
+A connection to the server is opened when the first message is processed, bound with the `+"`bind_dn`"+` and `+"`bind_password`"+` of a service account when they are set, and is reused by subsequent searches. Connections to `+"`ldaps://`"+` URLs use TLS, and connections to `+"`ldap://`"+` URLs can be upgraded to TLS with `+"`start_tls`"+`. When a connection fails it is reopened by the next search.
+
+== Caching
+
+The results of searches, including searches that found no entries, can be stored in a `+"`cache`"+` in order to reduce the load on the directory, where the key of each result is the filter of the search. A `+"`cache_ttl`"+` can be set in order for changes to the directory to be picked up.`).
+		Fields(
+			service.NewStringField(lpFieldURL).
+				Description("The URL of the LDAP server.").
+				Example("ldaps://ldap.example.com:636").
+				Example("ldap://localhost:389"),
+			service.NewBoolField(lpFieldStartTLS).
+				Description("Whether to upgrade connections to `ldap://` URLs to TLS with the `StartTLS` operation.").
+				Default(false),
+			service.NewTLSField(lpFieldTLS).
+				Description("Custom TLS settings of the connection, which are used with `ldaps://` URLs or when `start_tls` is enabled."),
+			service.NewStringField(lpFieldBindDN).
+				Description("The DN of a service account to bind connections with. When empty the searches are performed anonymously.").
+				Example("cn=svc-connect,ou=services,dc=example,dc=com").
+				Default(""),
+			service.NewStringField(lpFieldBindPassword).
+				Description("The password of the service account.").
+				Secret().
+				Default(""),
+			service.NewStringField(lpFieldBaseDN).
+				Description("The DN beneath which entries are searched for.").
+				Example("ou=people,dc=example,dc=com"),
+			service.NewStringAnnotatedEnumField(lpFieldScope, map[string]string{
+				"base": "Only the entry of the base DN itself.",
+				"one":  "Only the immediate children of the base DN.",
+				"sub":  "All of the descendants of the base DN.",
+			}).
+				Description("The scope of the search.").
+				Default("sub"),
+			service.NewInterpolatedStringField(lpFieldFilter).
+				Description("The filter of the search, where each `?` is replaced with an escaped value from `args_mapping`.").
+				Example("(&(objectClass=user)(sAMAccountName=?))"),
+			service.NewBloblangField(lpFieldArgsMapping).
+				Description("An optional xref:guides:bloblang/about.adoc[Bloblang mapping] which should evaluate to an array of values matching in size to the number of placeholders in the field `filter`.").
+				Example("root = [ this.user.name ]").
+				Optional(),
+			service.NewStringListField(lpFieldAttributes).
+				Description("The attributes of the entry to write to the message. When empty all attributes are written.").
+				Example([]string{"mail", "department", "memberOf"}).
+				Default([]any{}),
+			service.NewStringField(lpFieldTargetPath).
+				Description("The xref:configuration:field_paths.adoc[dot separated path] to write the attributes of the entry to. When empty the attributes replace the contents of the message.").
+				Example("user.directory"),
+			service.NewStringAnnotatedEnumField(lpFieldNoMatch, map[string]string{
+				"pass":  "Pass the message on unchanged.",
+				"error": "Flag the message as failed.",
+			}).
+				Description("What to do with messages when no entry is found.").
+				Default("pass"),
+			service.NewStringField(lpFieldCache).
+				Description("An optional xref:components:caches/about.adoc[`cache` resource] to store the results of searches in.").
+				Optional(),
+			service.NewStringField(lpFieldCacheTTL).
+				Description("An optional TTL of cached results. Not all caches support per-key TTLs.").
+				Example("10m").
+				Optional().
+				Advanced(),
+			service.NewDurationField(lpFieldTimeout).
+				Description("The maximum period to wait for connections to be opened and for searches to complete.").
+				Default("5s").
+				Advanced(),
+		).
+		Example("User enrichment", "Add the email address, department and groups of the user of each event from Active Directory, caching results for ten minutes.", `
+pipeline:
+  processors:
+    - ldap:
+        url: ldaps://dc1.example.com:636
+        bind_dn: cn=svc-connect,ou=services,dc=example,dc=com
+        bind_password: ${LDAP_PASSWORD}
+        base_dn: ou=people,dc=example,dc=com
+        filter: (&(objectClass=user)(sAMAccountName=?))
+        args_mapping: root = [ this.user ]
+        attributes: [ mail, department, memberOf ]
+        target_path: directory
+        cache: ldap_results
+        cache_ttl: 10m
+
+cache_resources:
+  - label: ldap_results
+    memory: {}
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"ldap", processorSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return processorFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// searcher is the subset of an LDAP connection used for searches.
+type searcher interface {
+	Search(req *ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close() error
+}
+
+type processor struct {
+	url          string
+	startTLS     bool
+	tlsConf      *tls.Config
+	bindDN       string
+	bindPassword string
+	baseDN       string
+	scope        int
+	filter       *service.InterpolatedString
+	argsMapping  *bloblang.Executor
+	attributes   []string
+	targetPath   []string
+	noMatchError bool
+	cache        string
+	cacheTTL     *time.Duration
+	timeout      time.Duration
+
+	dial func() (searcher, error)
+
+	connMut sync.Mutex
+	conn    searcher
+
+	log *service.Logger
+	mgr *service.Resources
+}
+
+func processorFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*processor, error) {
+	p := &processor{log: mgr.Logger(), mgr: mgr}
+
+	var err error
+	if p.url, err = conf.FieldString(lpFieldURL); err != nil {
+		return nil, err
+	}
+	if p.startTLS, err = conf.FieldBool(lpFieldStartTLS); err != nil {
+		return nil, err
+	}
+	if p.startTLS && strings.HasPrefix(strings.ToLower(p.url), "ldaps://") {
+		return nil, errors.New("start_tls cannot be enabled with an ldaps:// url")
+	}
+	if p.tlsConf, err = conf.FieldTLS(lpFieldTLS); err != nil {
+		return nil, err
+	}
+	if p.bindDN, err = conf.FieldString(lpFieldBindDN); err != nil {
+		return nil, err
+	}
+	if p.bindPassword, err = conf.FieldString(lpFieldBindPassword); err != nil {
+		return nil, err
+	}
+	if p.baseDN, err = conf.FieldString(lpFieldBaseDN); err != nil {
+		return nil, err
+	}
+
+	scopeStr, err := conf.FieldString(lpFieldScope)
+	if err != nil {
+		return nil, err
+	}
+	switch scopeStr {
+	case "base":
+		p.scope = ldap.ScopeBaseObject
+	case "one":
+		p.scope = ldap.ScopeSingleLevel
+	default:
+		p.scope = ldap.ScopeWholeSubtree
+	}
+
+	if p.filter, err = conf.FieldInterpolatedString(lpFieldFilter); err != nil {
+		return nil, err
+	}
+	if conf.Contains(lpFieldArgsMapping) {
+		if p.argsMapping, err = conf.FieldBloblang(lpFieldArgsMapping); err != nil {
+			return nil, err
+		}
+	}
+	if p.attributes, err = conf.FieldStringList(lpFieldAttributes); err != nil {
+		return nil, err
+	}
+
+	targetPath, err := conf.FieldString(lpFieldTargetPath)
+	if err != nil {
+		return nil, err
+	}
+	if targetPath != "" {
+		p.targetPath = strings.Split(targetPath, ".")
+	}
+
+	noMatch, err := conf.FieldString(lpFieldNoMatch)
+	if err != nil {
+		return nil, err
+	}
+	p.noMatchError = noMatch == "error"
+
+	if conf.Contains(lpFieldCache) {
+		if p.cache, err = conf.FieldString(lpFieldCache); err != nil {
+			return nil, err
+		}
+		if !mgr.HasCache(p.cache) {
+			return nil, fmt.Errorf("cache resource '%v' was not found", p.cache)
+		}
+	}
+	if conf.Contains(lpFieldCacheTTL) {
+		ttlStr, err := conf.FieldString(lpFieldCacheTTL)
+		if err != nil {
+			return nil, err
+		}
+		ttl, err := time.ParseDuration(ttlStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse cache_ttl: %w", err)
+		}
+		p.cacheTTL = &ttl
+	}
+	if p.timeout, err = conf.FieldDuration(lpFieldTimeout); err != nil {
+		return nil, err
+	}
+
+	p.dial = p.dialConn
+	return p, nil
+}
+
+func (p *processor) dialConn() (searcher, error) {
+	conn, err := ldap.DialURL(p.url,
+		ldap.DialWithDialer(&net.Dialer{Timeout: p.timeout}),
+		ldap.DialWithTLSConfig(p.tlsConf))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(p.timeout)
+
+	if p.startTLS {
+		if err := conn.StartTLS(p.tlsConf); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("failed to start tls: %w", err)
+		}
+	}
+	if p.bindDN != "" {
+		if err := conn.Bind(p.bindDN, p.bindPassword); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("failed to bind: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// buildFilter resolves the filter of a message and replaces its placeholders
+// with the escaped arguments of the message.
+func (p *processor) buildFilter(msg *service.Message) (string, error) {
+	filter, err := p.filter.TryString(msg)
+	if err != nil {
+		return "", fmt.Errorf("filter interpolation error: %w", err)
+	}
+	if p.argsMapping == nil {
+		return filter, nil
+	}
+
+	resMsg, err := msg.BloblangQuery(p.argsMapping)
+	if err != nil {
+		return "", fmt.Errorf("args mapping error: %w", err)
+	}
+	iargs, err := resMsg.AsStructured()
+	if err != nil {
+		return "", fmt.Errorf("args mapping error: %w", err)
+	}
+	args, ok := iargs.([]any)
+	if !ok {
+		return "", fmt.Errorf("mapping returned non-array result: %T", iargs)
+	}
+
+	var b strings.Builder
+	for i, part := range strings.Split(filter, "?") {
+		if i > 0 {
+			if i > len(args) {
+				return "", fmt.Errorf("filter contains more placeholders than the %v arguments", len(args))
+			}
+			var arg string
+			switch t := args[i-1].(type) {
+			case string:
+				arg = t
+			default:
+				argBytes, err := json.Marshal(t)
+				if err != nil {
+					return "", err
+				}
+				arg = string(argBytes)
+			}
+			b.WriteString(ldap.EscapeFilter(arg))
+		}
+		b.WriteString(part)
+	}
+	if placeholders := strings.Count(filter, "?"); placeholders != len(args) {
+		return "", fmt.Errorf("filter contains %v placeholders but %v arguments were provided", placeholders, len(args))
+	}
+	return b.String(), nil
+}
+
+// search returns the attributes of the first entry found with a filter, or nil
+// when no entry was found.
+func (p *processor) search(filter string) (map[string]any, error) {
+	p.connMut.Lock()
+	defer p.connMut.Unlock()
+
+	if p.conn == nil {
+		conn, err := p.dial()
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect: %w", err)
+		}
+		p.conn = conn
+	}
+
+	res, err := p.conn.Search(ldap.NewSearchRequest(
+		p.baseDN, p.scope, ldap.NeverDerefAliases,
+		1, int(p.timeout.Seconds()), false,
+		filter, p.attributes, nil,
+	))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		if ldap.IsErrorWithCode(err, ldap.ErrorNetwork) {
+			_ = p.conn.Close()
+			p.conn = nil
+		}
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+	if res == nil || len(res.Entries) == 0 {
+		return nil, nil
+	}
+
+	entry := res.Entries[0]
+	attrs := map[string]any{"dn": entry.DN}
+	for _, a := range entry.Attributes {
+		if len(a.Values) == 1 {
+			attrs[a.Name] = a.Values[0]
+			continue
+		}
+		values := make([]any, len(a.Values))
+		for i, v := range a.Values {
+			values[i] = v
+		}
+		attrs[a.Name] = values
+	}
+	return attrs, nil
+}
+
+func (p *processor) cachedSearch(ctx context.Context, filter string) (map[string]any, error) {
+	if p.cache == "" {
+		return p.search(filter)
+	}
+
+	var cached []byte
+	var cacheErr error
+	if err := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		cached, cacheErr = c.Get(ctx, filter)
+	}); err != nil {
+		return nil, err
+	}
+	if cacheErr == nil {
+		var attrs map[string]any
+		if err := json.Unmarshal(cached, &attrs); err == nil {
+			return attrs, nil
+		}
+	} else if !errors.Is(cacheErr, service.ErrKeyNotFound) {
+		p.log.Warnf("Failed to obtain cached result: %v", cacheErr)
+	}
+
+	attrs, err := p.search(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Searches without results are cached as null.
+	attrsBytes, err := json.Marshal(attrs)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		cacheErr = c.Set(ctx, filter, attrsBytes, p.cacheTTL)
+	}); err != nil {
+		return nil, err
+	}
+	if cacheErr != nil {
+		p.log.Warnf("Failed to cache result: %v", cacheErr)
+	}
+	return attrs, nil
+}
+
+func (p *processor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	filter, err := p.buildFilter(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs, err := p.cachedSearch(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if attrs == nil {
+		if p.noMatchError {
+			return nil, fmt.Errorf("no entry found with filter %v", filter)
+		}
+		return service.MessageBatch{msg}, nil
+	}
+
+	if len(p.targetPath) == 0 {
+		msg.SetStructuredMut(attrs)
+		return service.MessageBatch{msg}, nil
+	}
+
+	root, err := msg.AsStructuredMut()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as structured: %w", err)
+	}
+	obj, ok := root.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected message to be an object, got %T", root)
+	}
+	setPath(obj, p.targetPath, attrs)
+	msg.SetStructuredMut(obj)
+	return service.MessageBatch{msg}, nil
+}
+
+func setPath(obj map[string]any, path []string, v any) {
+	for _, seg := range path[:len(path)-1] {
+		next, ok := obj[seg].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			obj[seg] = next
+		}
+		obj = next
+	}
+	obj[path[len(path)-1]] = v
+}
+
+func (p *processor) Close(ctx context.Context) error {
+	p.connMut.Lock()
+	defer p.connMut.Unlock()
+
+	if p.conn == nil {
+		return nil
+	}
+	err := p.conn.Close()
+	p.conn = nil
+	return err
+}
@@ -0,0 +1,289 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ldap
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type fakeSearcher struct {
+	entries  map[string][]*ldap.Entry
+	err      error
+	requests []*ldap.SearchRequest
+	closed   bool
+	dials    int
+}
+
+func (f *fakeSearcher) dial() (searcher, error) {
+	f.dials++
+	return f, nil
+}
+
+func (f *fakeSearcher) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	f.requests = append(f.requests, req)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &ldap.SearchResult{Entries: f.entries[req.Filter]}, nil
+}
+
+func (f *fakeSearcher) Close() error {
+	f.closed = true
+	return nil
+}
+
+func testEntries() map[string][]*ldap.Entry {
+	return map[string][]*ldap.Entry{
+		"(&(objectClass=user)(sAMAccountName=jdoe))": {
+			ldap.NewEntry("cn=John Doe,ou=people,dc=example,dc=com", map[string][]string{
+				"mail":     {"jdoe@example.com"},
+				"memberOf": {"cn=admins", "cn=users"},
+			}),
+		},
+	}
+}
+
+func processMsg(t testing.TB, p *processor, content string) (string, error) {
+	t.Helper()
+
+	res, err := p.Process(context.Background(), service.NewMessage([]byte(content)))
+	if err != nil {
+		return "", err
+	}
+	require.Len(t, res, 1)
+	b, err := res[0].AsBytes()
+	require.NoError(t, err)
+	return string(b), nil
+}
+
+func TestLDAPEnrichment(t *testing.T) {
+	s := &fakeSearcher{entries: testEntries()}
+	conf, err := processorSpec().ParseYAML(`
+url: ldap://localhost:389
+base_dn: ou=people,dc=example,dc=com
+filter: (&(objectClass=user)(sAMAccountName=?))
+args_mapping: root = [ this.user ]
+attributes: [ mail, memberOf ]
+target_path: directory.user
+`, nil)
+	require.NoError(t, err)
+
+	p, err := processorFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.NoError(t, err)
+
+	p.dial = s.dial
+
+	out, err := processMsg(t, p, `{"user":"jdoe"}`)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "user": "jdoe",
+  "directory": {
+    "user": {
+      "dn": "cn=John Doe,ou=people,dc=example,dc=com",
+      "mail": "jdoe@example.com",
+      "memberOf": ["cn=admins","cn=users"]
+    }
+  }
+}`, out)
+
+	// Entries that are not found leave the message unchanged.
+	out, err = processMsg(t, p, `{"user":"nobody"}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"user":"nobody"}`, out)
+
+	require.Len(t, s.requests, 2)
+	assert.Equal(t, "ou=people,dc=example,dc=com", s.requests[0].BaseDN)
+	assert.Equal(t, ldap.ScopeWholeSubtree, s.requests[0].Scope)
+	assert.Equal(t, []string{"mail", "memberOf"}, s.requests[0].Attributes)
+	assert.Equal(t, 1, s.dials)
+
+	require.NoError(t, p.Close(context.Background()))
+	assert.True(t, s.closed)
+}
+
+func TestLDAPFilterEscaping(t *testing.T) {
+	s := &fakeSearcher{}
+	conf, err := processorSpec().ParseYAML(`
+url: ldap://localhost:389
+base_dn: dc=example,dc=com
+filter: (&(uid=?)(ou=${! @ou })(employeeNumber=?))
+args_mapping: root = [ this.user, this.num ]
+target_path: ""
+`, nil)
+	require.NoError(t, err)
+
+	p, err := processorFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.NoError(t, err)
+
+	p.dial = s.dial
+
+	msg := service.NewMessage([]byte(`{"user":"*)(uid=*","num":5}`))
+	msg.MetaSetMut("ou", "eng")
+	_, err = p.Process(context.Background(), msg)
+	require.NoError(t, err)
+
+	require.Len(t, s.requests, 1)
+	assert.Equal(t, `(&(uid=\2a\29\28uid=\2a)(ou=eng)(employeeNumber=5))`, s.requests[0].Filter)
+
+	// Without an args mapping the filter is used as it is.
+	p.argsMapping = nil
+	p.filter, err = service.NewInterpolatedString("(uid=?)")
+	require.NoError(t, err)
+	_, err = processMsg(t, p, `{}`)
+	require.NoError(t, err)
+	assert.Equal(t, "(uid=?)", s.requests[1].Filter)
+}
+
+func TestLDAPPlaceholderMismatch(t *testing.T) {
+	conf, err := processorSpec().ParseYAML(`
+url: ldap://localhost:389
+base_dn: dc=example,dc=com
+filter: (uid=?)
+args_mapping: root = [ "a", "b" ]
+target_path: ""
+`, nil)
+	require.NoError(t, err)
+
+	p, err := processorFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.NoError(t, err)
+
+	p.dial = (&fakeSearcher{}).dial
+
+	_, err = processMsg(t, p, `{}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 placeholders but 2 arguments")
+}
+
+func TestLDAPNoMatchError(t *testing.T) {
+	conf, err := processorSpec().ParseYAML(`
+url: ldap://localhost:389
+base_dn: dc=example,dc=com
+filter: (uid=?)
+args_mapping: root = [ this.user ]
+target_path: directory
+no_match: error
+`, nil)
+	require.NoError(t, err)
+
+	p, err := processorFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.NoError(t, err)
+
+	p.dial = (&fakeSearcher{}).dial
+
+	_, err = processMsg(t, p, `{"user":"nobody"}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no entry found")
+}
+
+func TestLDAPCache(t *testing.T) {
+	s := &fakeSearcher{entries: testEntries()}
+	conf, err := processorSpec().ParseYAML(`
+url: ldap://localhost:389
+base_dn: ou=people,dc=example,dc=com
+filter: (&(objectClass=user)(sAMAccountName=?))
+args_mapping: root = [ this.user ]
+target_path: directory
+cache: foocache
+cache_ttl: 1m
+`, nil)
+	require.NoError(t, err)
+
+	p, err := processorFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.NoError(t, err)
+
+	p.dial = s.dial
+
+	for i := 0; i < 3; i++ {
+		out, err := processMsg(t, p, `{"user":"jdoe"}`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+  "user": "jdoe",
+  "directory": {
+    "dn": "cn=John Doe,ou=people,dc=example,dc=com",
+    "mail": "jdoe@example.com",
+    "memberOf": ["cn=admins","cn=users"]
+  }
+}`, out)
+
+		out, err = processMsg(t, p, `{"user":"nobody"}`)
+		require.NoError(t, err)
+		assert.Equal(t, `{"user":"nobody"}`, out)
+	}
+
+	// Both found and missing entries are cached.
+	assert.Len(t, s.requests, 2)
+}
+
+func TestLDAPReconnect(t *testing.T) {
+	s := &fakeSearcher{
+		entries: testEntries(),
+		err:     ldap.NewError(ldap.ErrorNetwork, errors.New("connection reset")),
+	}
+	conf, err := processorSpec().ParseYAML(`
+url: ldap://localhost:389
+base_dn: ou=people,dc=example,dc=com
+filter: (&(objectClass=user)(sAMAccountName=?))
+args_mapping: root = [ this.user ]
+target_path: directory
+`, nil)
+	require.NoError(t, err)
+
+	p, err := processorFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.NoError(t, err)
+
+	p.dial = s.dial
+
+	_, err = processMsg(t, p, `{"user":"jdoe"}`)
+	require.Error(t, err)
+	assert.True(t, s.closed)
+
+	s.err = nil
+	_, err = processMsg(t, p, `{"user":"jdoe"}`)
+	require.NoError(t, err)
+	assert.Equal(t, 2, s.dials)
+}
+
+func TestLDAPConfigErrors(t *testing.T) {
+	for _, conf := range []string{
+		`
+url: ldaps://localhost:636
+start_tls: true
+base_dn: dc=example,dc=com
+filter: (uid=foo)
+target_path: ""
+`,
+		`
+url: ldap://localhost:389
+base_dn: dc=example,dc=com
+filter: (uid=foo)
+target_path: ""
+cache: nope
+`,
+	} {
+		pConf, err := processorSpec().ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		_, err = processorFromParsed(pConf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+		require.Error(t, err)
+	}
+}
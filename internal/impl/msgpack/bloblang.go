@@ -48,11 +48,7 @@ func init() {
 				if err != nil {
 					return nil, err
 				}
-				var jObj any
-				if err := msgpack.Unmarshal(b, &jObj); err != nil {
-					return nil, err
-				}
-				return jObj, nil
+				return decodeMsgPack(b)
 			}, nil
 		},
 	); err != nil {
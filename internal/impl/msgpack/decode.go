@@ -0,0 +1,105 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package msgpack
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+)
+
+// timestampExtID is the extension type reserved by the MessagePack spec for
+// timestamps.
+const timestampExtID = -1
+
+// decodeMsgPack parses a MessagePack document into a structured value that can
+// be represented as JSON. Timestamps are converted into UTC times, map keys are
+// converted into strings, binary strings are kept as raw bytes and extension
+// types not known by the spec are converted into objects of the form
+// `{"type":<ext id>,"data":<bytes>}`.
+func decodeMsgPack(b []byte) (any, error) {
+	return decodeMsgPackValue(msgpack.NewDecoder(bytes.NewReader(b)))
+}
+
+func decodeMsgPackValue(d *msgpack.Decoder) (any, error) {
+	c, err := d.PeekCode()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case msgpcode.IsFixedMap(c) || c == msgpcode.Map16 || c == msgpcode.Map32:
+		n, err := d.DecodeMapLen()
+		if err != nil {
+			return nil, err
+		}
+		obj := make(map[string]any, n)
+		for i := 0; i < n; i++ {
+			k, err := d.DecodeInterface()
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeMsgPackValue(d)
+			if err != nil {
+				return nil, err
+			}
+			switch kt := k.(type) {
+			case string:
+				obj[kt] = v
+			case []byte:
+				obj[string(kt)] = v
+			default:
+				obj[fmt.Sprintf("%v", kt)] = v
+			}
+		}
+		return obj, nil
+	case msgpcode.IsFixedArray(c) || c == msgpcode.Array16 || c == msgpcode.Array32:
+		n, err := d.DecodeArrayLen()
+		if err != nil {
+			return nil, err
+		}
+		arr := make([]any, n)
+		for i := range arr {
+			if arr[i], err = decodeMsgPackValue(d); err != nil {
+				return nil, err
+			}
+		}
+		return arr, nil
+	case msgpcode.IsExt(c):
+		raw, err := d.DecodeRaw()
+		if err != nil {
+			return nil, err
+		}
+		extID, extLen, err := msgpack.NewDecoder(bytes.NewReader(raw)).DecodeExtHeader()
+		if err != nil {
+			return nil, err
+		}
+		if extID == timestampExtID {
+			var t time.Time
+			if err := msgpack.Unmarshal(raw, &t); err != nil {
+				return nil, err
+			}
+			return t.UTC(), nil
+		}
+		return map[string]any{
+			"type": int64(extID),
+			"data": raw[len(raw)-extLen:],
+		}, nil
+	}
+	return d.DecodeInterface()
+}
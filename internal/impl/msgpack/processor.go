@@ -28,6 +28,10 @@ func processorConfig() *service.ConfigSpec {
 		Beta().
 		Categories("Parsing").
 		Summary("Converts messages to or from the https://msgpack.org/[MessagePack^] format.").
+		Description(`
+When converting MessagePack to JSON binary strings are represented as base64 encoded strings, timestamps are represented as RFC 3339 strings in UTC and any other extension types are represented as an object of the form ` + "`" + `{"type":<ext id>,"data":"<base64 encoded bytes>"}` + "`" + `.
+
+Documents that fail to decode are flagged as errors and passed through unchanged, and can therefore be handled with xref:configuration:error_handling.adoc[error handling patterns].`).
 		Field(service.NewStringAnnotatedEnumField("operator", map[string]string{
 			"to_json":   "Convert MessagePack messages to JSON format",
 			"from_json": "Convert JSON messages to MessagePack format",
@@ -57,8 +61,8 @@ func strToMsgPackOperator(opStr string) (msgPackOperator, error) {
 				return nil, err
 			}
 
-			var jObj any
-			if err := msgpack.Unmarshal(mBytes, &jObj); err != nil {
+			jObj, err := decodeMsgPack(mBytes)
+			if err != nil {
 				return nil, fmt.Errorf("failed to convert MsgPack document to JSON: %v", err)
 			}
 
@@ -15,9 +15,11 @@
 package msgpack
 
 import (
+	"bytes"
 	"context"
 	b64 "encoding/base64"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -138,3 +140,61 @@ func TestMsgPackFromJson(t *testing.T) {
 		})
 	}
 }
+
+func TestMsgPackToJsonExtensions(t *testing.T) {
+	enc := func(fn func(e *msgpack.Encoder) error) []byte {
+		var buf bytes.Buffer
+		require.NoError(t, fn(msgpack.NewEncoder(&buf)))
+		return buf.Bytes()
+	}
+
+	input := enc(func(e *msgpack.Encoder) error {
+		if err := e.EncodeMapLen(3); err != nil {
+			return err
+		}
+		if err := e.EncodeString("bin"); err != nil {
+			return err
+		}
+		if err := e.EncodeBytes([]byte{0xff, 0x00, 'a'}); err != nil {
+			return err
+		}
+		if err := e.EncodeString("ts"); err != nil {
+			return err
+		}
+		if err := e.EncodeTime(time.Unix(1700000000, 500).In(time.FixedZone("foo", 3600))); err != nil {
+			return err
+		}
+		if err := e.EncodeString("ext"); err != nil {
+			return err
+		}
+		if err := e.EncodeExtHeader(5, 2); err != nil {
+			return err
+		}
+		_, err := e.Writer().Write([]byte{0x01, 0x02})
+		return err
+	})
+
+	proc, err := newProcessor("to_json")
+	require.NoError(t, err)
+
+	msgs, err := proc.Process(context.Background(), service.NewMessage(input))
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	act, err := msgs[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "bin": "/wBh",
+  "ts": "2023-11-14T22:13:20.0000005Z",
+  "ext": {"type": 5, "data": "AQI="}
+}`, string(act))
+}
+
+func TestMsgPackToJsonInvalid(t *testing.T) {
+	proc, err := newProcessor("to_json")
+	require.NoError(t, err)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte{0x82, 0xa1, 'a'}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to convert MsgPack document to JSON")
+}
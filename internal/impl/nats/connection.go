@@ -43,6 +43,19 @@ func connectionTailFields() []*service.ConfigField {
 	}
 }
 
+const connFieldLazyConnect = "lazy_connect"
+
+// lazyConnectField is used by components that would otherwise connect during
+// construction, which prevents a pipeline from starting whilst NATS is
+// unreachable.
+func lazyConnectField() *service.ConfigField {
+	return service.NewBoolField(connFieldLazyConnect).
+		Description("When `false` the connection to NATS is established when the component is created, and the pipeline fails to start if NATS is unreachable. When `true` the connection is instead deferred until the first message is processed, and messages are rejected with a retryable error until a connection is established.").
+		Default(false).
+		Advanced().
+		Version("4.31.0")
+}
+
 type connectionDetails struct {
 	label    string
 	logger   *service.Logger
@@ -38,6 +38,15 @@ NATS component, so that monitoring tools between NATS and Redpanda Connect can s
 `
 }
 
+func outputExpiryDescription() string {
+	return `
+
+== Expiry
+
+Messages with an expiry deadline in the metadata key ` + "`benthos_expires_at`" + ` that has passed are dropped rather than published, and the deadline of messages that are published is always added as a header. NATS does not support a TTL for individual messages, see the xref:components:processors/check_expiry.adoc[` + "`check_expiry`" + ` processor] for more information.
+`
+}
+
 func inputTracingDocs() *service.ConfigField {
 	return service.NewExtractTracingSpanMappingField().Version(tracingVersion)
 }
@@ -40,6 +40,12 @@ func natsJetStreamInputConfig() *service.ConfigSpec {
 
 In the case where a stream being consumed is mirrored from a different JetStream domain the stream cannot be resolved from the subject name alone, and so the stream name as well as the subject (if applicable) must both be specified.
 
+== Ordered consumers
+
+When ` + "`ordered_consumer`" + ` is enabled messages are consumed with an ephemeral ordered push consumer, which guarantees that messages are delivered in the order of the stream without redelivery. The client tracks the sequence of each message and when a gap is detected, such as after a missed heartbeat or a reconnect, the consumer is automatically recreated from the last sequence received and a warning is logged. Messages of an ordered consumer are not acknowledged, and so it is not possible to combine this option with ` + "`durable`, `queue` or `bind`" + `.
+
+This is useful for rebuilding state from a stream, in which case the pipeline should also process messages with a single thread in order to preserve the ordering downstream.
+
 == Metadata
 
 This input adds the following metadata fields to each message:
@@ -75,6 +81,10 @@ xref:configuration:interpolation.adoc#bloblang-queries[function interpolation].
 		Field(service.NewBoolField("bind").
 			Description("Indicates that the subscription should use an existing consumer.").
 			Optional()).
+		Field(service.NewBoolField("ordered_consumer").
+			Description("Consume with an ordered consumer, which delivers messages strictly in the order of the stream and is recreated when a gap is detected. Messages are not acknowledged when this is enabled.").
+			Default(false).
+			Version("4.31.0")).
 		Field(service.NewStringAnnotatedEnumField("deliver", map[string]string{
 			"all":              "Deliver all available messages.",
 			"last":             "Deliver starting with the last published messages.",
@@ -122,6 +132,7 @@ type jetStreamReader struct {
 	stream        string
 	bind          bool
 	pull          bool
+	ordered       bool
 	durable       string
 	ackWait       time.Duration
 	maxAckPending int
@@ -132,6 +143,10 @@ type jetStreamReader struct {
 	natsConn *nats.Conn
 	natsSub  *nats.Subscription
 
+	// The last consumer sequence read from an ordered consumer, used in order
+	// to report when the consumer has been recreated due to a gap.
+	lastConsumerSeq uint64
+
 	shutSig *shutdown.Signaller
 }
 
@@ -189,6 +204,13 @@ func newJetStreamReaderFromConfig(conf *service.ParsedConfig, mgr *service.Resou
 			return nil, err
 		}
 	}
+	if j.ordered, err = conf.FieldBool("ordered_consumer"); err != nil {
+		return nil, err
+	}
+	if j.ordered && (j.durable != "" || j.queue != "" || j.bind) {
+		return nil, errors.New("ordered_consumer cannot be combined with durable, queue or bind")
+	}
+
 	if j.bind {
 		if j.stream == "" && j.durable == "" {
 			return nil, errors.New("stream or durable is required, when bind is true")
@@ -266,6 +288,21 @@ func (j *jetStreamReader) Connect(ctx context.Context) (err error) {
 		j.pull = info.Config.DeliverSubject == ""
 	}
 
+	if j.ordered {
+		options := []nats.SubOpt{nats.OrderedConsumer(), j.deliverOpt}
+		if j.stream != "" {
+			options = append(options, nats.BindStream(j.stream))
+		}
+		if natsSub, err = jCtx.SubscribeSync(j.subject, options...); err != nil {
+			return err
+		}
+
+		j.natsConn = natsConn
+		j.natsSub = natsSub
+		j.lastConsumerSeq = 0
+		return nil
+	}
+
 	options := []nats.SubOpt{
 		nats.ManualAck(),
 	}
@@ -335,6 +372,9 @@ func (j *jetStreamReader) Read(ctx context.Context) (*service.Message, service.A
 			// TODO: Any errors need capturing here to signal a lost connection?
 			return nil, nil, err
 		}
+		if j.ordered {
+			return j.convertOrderedMessage(nmsg)
+		}
 		return convertMessage(nmsg)
 	}
 
@@ -373,6 +413,26 @@ func (j *jetStreamReader) Close(ctx context.Context) error {
 	return nil
 }
 
+// convertOrderedMessage converts a message from an ordered consumer, which
+// cannot be acknowledged, and logs a warning when the consumer sequence has
+// restarted, indicating that a gap was detected and the consumer recreated.
+func (j *jetStreamReader) convertOrderedMessage(m *nats.Msg) (*service.Message, service.AckFunc, error) {
+	if metadata, err := m.Metadata(); err == nil {
+		if j.lastConsumerSeq > 0 && metadata.Sequence.Consumer != j.lastConsumerSeq+1 {
+			j.log.Warnf("Gap detected by ordered consumer after consumer sequence %v, the consumer was recreated and resumed from stream sequence %v", j.lastConsumerSeq, metadata.Sequence.Stream)
+		}
+		j.lastConsumerSeq = metadata.Sequence.Consumer
+	}
+
+	msg, _, err := convertMessage(m)
+	if err != nil {
+		return nil, nil, err
+	}
+	return msg, func(ctx context.Context, res error) error {
+		return nil
+	}, nil
+}
+
 func convertMessage(m *nats.Msg) (*service.Message, service.AckFunc, error) {
 	msg := service.NewMessage(m.Data)
 	msg.MetaSet("nats_subject", m.Subject)
@@ -165,4 +165,34 @@ bind: false
 		_, err = newJetStreamReaderFromConfig(conf, service.MockResources())
 		require.Error(t, err)
 	})
+
+	t.Run("Ordered consumer", func(t *testing.T) {
+		inputConfig := `
+urls: [ url1 ]
+subject: testsubject
+ordered_consumer: true
+`
+
+		conf, err := spec.ParseYAML(inputConfig, env)
+		require.NoError(t, err)
+
+		e, err := newJetStreamReaderFromConfig(conf, service.MockResources())
+		require.NoError(t, err)
+		assert.True(t, e.ordered)
+	})
+
+	t.Run("Ordered consumer with durable", func(t *testing.T) {
+		inputConfig := `
+urls: [ url1 ]
+subject: testsubject
+durable: foo
+ordered_consumer: true
+`
+
+		conf, err := spec.ParseYAML(inputConfig, env)
+		require.NoError(t, err)
+
+		_, err = newJetStreamReaderFromConfig(conf, service.MockResources())
+		require.Error(t, err)
+	})
 }
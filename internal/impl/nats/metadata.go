@@ -15,9 +15,12 @@
 package nats
 
 import (
+	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
 
 	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/expiry"
 )
 
 const (
@@ -40,3 +43,13 @@ func newMessageFromKVEntry(entry jetstream.KeyValueEntry) *service.Message {
 
 	return msg
 }
+
+// addExpiryHeader writes the expiry deadline of a message as a header when it
+// hasn't already been added by a metadata filter.
+func addExpiryHeader(msg *service.Message, header nats.Header) {
+	v, exists := msg.MetaGet(expiry.MetadataKey)
+	if !exists || header.Get(expiry.MetadataKey) != "" {
+		return
+	}
+	header.Set(expiry.MetadataKey, v)
+}
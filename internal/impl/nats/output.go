@@ -19,10 +19,13 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/nats-io/nats.go"
 
 	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/expiry"
 )
 
 func natsOutputConfig() *service.ConfigSpec {
@@ -32,7 +35,7 @@ func natsOutputConfig() *service.ConfigSpec {
 		Summary("Publish to an NATS subject.").
 		Description(`This output will interpolate functions within the subject field, you can find a list of functions xref:configuration:interpolation.adoc#bloblang-queries[here].
 
-` + connectionNameDescription() + authDescription()).
+` + connectionNameDescription() + authDescription() + outputExpiryDescription()).
 		Fields(connectionHeadFields()...).
 		Field(service.NewInterpolatedStringField("subject").
 			Description("The subject to publish to.").
@@ -144,6 +147,11 @@ func (n *natsWriter) Write(context context.Context, msg *service.Message) error
 		return service.ErrNotConnected
 	}
 
+	if expiry.Expired(msg, time.Now()) {
+		n.log.Debug("Dropping expired message")
+		return nil
+	}
+
 	subject, err := n.subjectStr.TryString(msg)
 	if err != nil {
 		return fmt.Errorf("subject interpolation error: %w", err)
@@ -170,6 +178,7 @@ func (n *natsWriter) Write(context context.Context, msg *service.Message) error
 			nMsg.Header.Add(key, value)
 			return nil
 		})
+		addExpiryHeader(msg, nMsg.Header)
 	}
 
 	if err = conn.PublishMsg(nMsg); errors.Is(err, nats.ErrConnectionClosed) {
@@ -17,13 +17,17 @@ package nats
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/nats-io/nats.go"
 
 	"github.com/Jeffail/shutdown"
 
 	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/expiry"
 )
 
 func natsJetStreamOutputConfig() *service.ConfigSpec {
@@ -32,7 +36,12 @@ func natsJetStreamOutputConfig() *service.ConfigSpec {
 		Categories("Services").
 		Version("3.46.0").
 		Summary("Write messages to a NATS JetStream subject.").
-		Description(connectionNameDescription() + authDescription()).
+		Description(`
+== Deduplication
+
+When the field ` + "`deduplicate_id`" + ` is set its value is added to each message as the header ` + "`Nats-Msg-Id`" + `, and JetStream discards any message published with an ID that was already seen within the duplicate window of the stream. Using an ID derived from the message, such as a key or an offset from the input, therefore makes publishes idempotent when a message is retried after a publish that actually succeeded.
+
+After a successful publish the metadata fields ` + "`nats_stream`" + ` and ` + "`nats_sequence_stream`" + ` are set on the message from the publish acknowledgement, along with ` + "`nats_duplicate`" + ` which is ` + "`true`" + ` when the message was discarded as a duplicate. These can be observed by outputs that follow this one within a xref:components:outputs/broker.adoc#fan_out_sequential[` + "`fan_out_sequential`" + ` broker].` + connectionNameDescription() + authDescription() + outputExpiryDescription()).
 		Fields(connectionHeadFields()...).
 		Field(service.NewInterpolatedStringField("subject").
 			Description("A subject to write to.").
@@ -49,6 +58,12 @@ func natsJetStreamOutputConfig() *service.ConfigSpec {
 		Field(service.NewMetadataFilterField("metadata").
 			Description("Determine which (if any) metadata values should be added to messages as headers.").
 			Optional()).
+		Field(service.NewInterpolatedStringField("deduplicate_id").
+			Description("An optional ID to set as the `Nats-Msg-Id` header of each message, which JetStream uses in order to discard duplicate messages published within the duplicate window of the stream. Messages where the ID resolves to an empty string are published without one.").
+			Example(`${! @kafka_topic }-${! @kafka_partition }-${! @kafka_offset }`).
+			Example(`${! this.id }`).
+			Optional().
+			Version("4.31.0")).
 		Field(service.NewOutputMaxInFlightField().Default(1024)).
 		Fields(connectionTailFields()...).
 		Field(outputTracingDocs())
@@ -82,6 +97,7 @@ type jetStreamOutput struct {
 	subjectStr    *service.InterpolatedString
 	headers       map[string]*service.InterpolatedString
 	metaFilter    *service.MetadataFilter
+	dedupeID      *service.InterpolatedString
 
 	log *service.Logger
 
@@ -120,6 +136,12 @@ func newJetStreamWriterFromConfig(conf *service.ParsedConfig, mgr *service.Resou
 			return nil, err
 		}
 	}
+
+	if conf.Contains("deduplicate_id") {
+		if j.dedupeID, err = conf.FieldInterpolatedString("deduplicate_id"); err != nil {
+			return nil, err
+		}
+	}
 	return &j, nil
 }
 
@@ -176,6 +198,11 @@ func (j *jetStreamOutput) Write(ctx context.Context, msg *service.Message) error
 		return service.ErrNotConnected
 	}
 
+	if expiry.Expired(msg, time.Now()) {
+		j.log.Debug("Dropping expired message")
+		return nil
+	}
+
 	subject, err := j.subjectStr.TryString(msg)
 	if err != nil {
 		return fmt.Errorf(`failed string interpolation on field "subject": %w`, err)
@@ -200,9 +227,27 @@ func (j *jetStreamOutput) Write(ctx context.Context, msg *service.Message) error
 		jsmsg.Header.Add(key, value)
 		return nil
 	})
+	addExpiryHeader(msg, jsmsg.Header)
 
-	_, err = jCtx.PublishMsg(jsmsg)
-	return err
+	if j.dedupeID != nil {
+		id, err := j.dedupeID.TryString(msg)
+		if err != nil {
+			return fmt.Errorf(`failed string interpolation on field "deduplicate_id": %w`, err)
+		}
+		if id != "" {
+			jsmsg.Header.Set(nats.MsgIdHdr, id)
+		}
+	}
+
+	ack, err := jCtx.PublishMsg(jsmsg)
+	if err != nil {
+		return err
+	}
+
+	msg.MetaSetMut("nats_stream", ack.Stream)
+	msg.MetaSetMut("nats_sequence_stream", strconv.FormatUint(ack.Sequence, 10))
+	msg.MetaSetMut("nats_duplicate", strconv.FormatBool(ack.Duplicate))
+	return nil
 }
 
 func (j *jetStreamOutput) Close(ctx context.Context) error {
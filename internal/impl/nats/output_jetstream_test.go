@@ -69,6 +69,29 @@ auth:
 		assert.Equal(t, "test auth inline user NKey Seed", e.connDetails.authConf.UserNkeySeed)
 	})
 
+	t.Run("Deduplicate ID", func(t *testing.T) {
+		outputConfig := `
+urls: [ url1 ]
+subject: testsubject
+deduplicate_id: ${! @kafka_topic }-${! @kafka_offset }
+`
+
+		conf, err := spec.ParseYAML(outputConfig, env)
+		require.NoError(t, err)
+
+		e, err := newJetStreamWriterFromConfig(conf, service.MockResources())
+		require.NoError(t, err)
+		require.NotNil(t, e.dedupeID)
+
+		msg := service.NewMessage(nil)
+		msg.MetaSet("kafka_topic", "foo")
+		msg.MetaSet("kafka_offset", "42")
+
+		id, err := e.dedupeID.TryString(msg)
+		require.NoError(t, err)
+		assert.Equal(t, "foo-42", id)
+	})
+
 	t.Run("Missing user_nkey_seed", func(t *testing.T) {
 		inputConfig := `
 urls: [ url1, url2 ]
@@ -120,6 +120,7 @@ This processor adds the following metadata fields to each message, depending on
 			service.NewDurationField(kvpFieldTimeout).
 				Description("The maximum period to wait on an operation before aborting and returning an error.").
 				Advanced().Default("5s"),
+			lazyConnectField(),
 		}...)...).
 		LintRule(`root = match {
       ["get_revision", "update"].contains(this.operation) && !this.exists("revision") => [ "'revision' must be set when operation is '" + this.operation + "'" ],
@@ -191,6 +192,14 @@ func newKVProcessor(conf *service.ParsedConfig, mgr *service.Resources) (*kvProc
 		return nil, err
 	}
 
+	lazy, err := conf.FieldBool(connFieldLazyConnect)
+	if err != nil {
+		return nil, err
+	}
+	if lazy {
+		return p, nil
+	}
+
 	err = p.Connect(context.Background())
 	return p, err
 }
@@ -211,6 +220,18 @@ func (p *kvProcessor) Process(ctx context.Context, msg *service.Message) (servic
 	kv := p.kv
 	p.connMut.Unlock()
 
+	if kv == nil {
+		if err := p.Connect(ctx); err != nil {
+			return nil, fmt.Errorf("%w: %v", service.ErrNotConnected, err)
+		}
+		p.connMut.Lock()
+		kv = p.kv
+		p.connMut.Unlock()
+		if kv == nil {
+			return nil, service.ErrNotConnected
+		}
+	}
+
 	key, err := p.key.TryString(msg)
 	if err != nil {
 		return nil, err
@@ -385,7 +406,9 @@ func (p *kvProcessor) Connect(ctx context.Context) (err error) {
 		if err != nil {
 			if p.natsConn != nil {
 				p.natsConn.Close()
+				p.natsConn = nil
 			}
+			p.kv = nil
 		}
 	}()
 
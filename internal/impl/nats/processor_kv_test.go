@@ -0,0 +1,65 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nats
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestKVProcessorLazyConnect(t *testing.T) {
+	spec := natsKVProcessorConfig()
+	env := service.NewEnvironment()
+
+	t.Run("Fails fast by default", func(t *testing.T) {
+		conf, err := spec.ParseYAML(`
+urls: [ nats://127.0.0.1:1 ]
+bucket: foo
+operation: get
+key: bar
+`, env)
+		require.NoError(t, err)
+
+		_, err = newKVProcessor(conf, service.MockResources())
+		require.Error(t, err)
+	})
+
+	t.Run("Defers connection when lazy", func(t *testing.T) {
+		conf, err := spec.ParseYAML(`
+urls: [ nats://127.0.0.1:1 ]
+bucket: foo
+operation: get
+key: bar
+lazy_connect: true
+`, env)
+		require.NoError(t, err)
+
+		p, err := newKVProcessor(conf, service.MockResources())
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_ = p.Close(context.Background())
+		})
+
+		_, err = p.Process(context.Background(), service.NewMessage([]byte("hello")))
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, service.ErrNotConnected), err)
+	})
+}
@@ -73,6 +73,7 @@ You can access these metadata fields using xref:configuration:interpolation.adoc
 			Description("A duration string is a possibly signed sequence of decimal numbers, each with optional fraction and a unit suffix, such as 300ms, -1.5h or 2h45m. Valid time units are ns, us (or µs), ms, s, m, h.").
 			Optional().
 			Default("3s")).
+		Field(lazyConnectField()).
 		Fields(connectionTailFields()...)
 }
 
@@ -128,6 +129,14 @@ func newRequestReplyProcessor(conf *service.ParsedConfig, mgr *service.Resources
 		return nil, err
 	}
 
+	lazy, err := conf.FieldBool(connFieldLazyConnect)
+	if err != nil {
+		return nil, err
+	}
+	if lazy {
+		return p, nil
+	}
+
 	err = p.connect(context.Background())
 	return p, err
 }
@@ -160,9 +169,23 @@ func (r *requestReplyProcessor) connect(ctx context.Context) (err error) {
 }
 
 func (r *requestReplyProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	r.connMut.RLock()
+	connected := r.natsConn != nil
+	r.connMut.RUnlock()
+
+	if !connected {
+		if err := r.connect(ctx); err != nil {
+			return nil, fmt.Errorf("%w: %v", service.ErrNotConnected, err)
+		}
+	}
+
 	r.connMut.RLock()
 	defer r.connMut.RUnlock()
 
+	if r.natsConn == nil {
+		return nil, service.ErrNotConnected
+	}
+
 	subject, err := r.subject.TryString(msg)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,297 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgresql
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/jackc/pgtype"
+)
+
+// lsn is a position within the write-ahead log of a database.
+type lsn uint64
+
+func (l lsn) String() string {
+	return fmt.Sprintf("%X/%X", uint32(l>>32), uint32(l))
+}
+
+func parseLSN(s string) (lsn, error) {
+	var hi, lo uint32
+	if _, err := fmt.Sscanf(s, "%X/%X", &hi, &lo); err != nil {
+		return 0, fmt.Errorf("failed to parse lsn %q: %w", s, err)
+	}
+	return lsn(uint64(hi)<<32 | uint64(lo)), nil
+}
+
+//------------------------------------------------------------------------------
+
+// The following types describe the messages of the pgoutput logical decoding
+// plugin that are relevant to the input, as documented at:
+// https://www.postgresql.org/docs/current/protocol-logicalrep-message-formats.html
+
+type pgRelationColumn struct {
+	key      bool
+	name     string
+	dataType uint32
+}
+
+type pgRelation struct {
+	id        uint32
+	namespace string
+	name      string
+	columns   []pgRelationColumn
+}
+
+const (
+	pgTupleNull      = 'n'
+	pgTupleUnchanged = 'u'
+	pgTupleText      = 't'
+	pgTupleBinary    = 'b'
+)
+
+type pgTupleColumn struct {
+	kind byte
+	data []byte
+}
+
+type pgBegin struct{}
+
+type pgCommit struct {
+	endLSN lsn
+}
+
+type pgChange struct {
+	operation  string
+	relationID uint32
+	keysOnly   bool
+	tuple      []pgTupleColumn
+}
+
+type pgoutputReader struct {
+	buf []byte
+	err error
+}
+
+func (r *pgoutputReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.buf) < n {
+		r.err = errors.New("message is too short")
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *pgoutputReader) byte() byte {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *pgoutputReader) uint16() uint16 {
+	if b := r.next(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *pgoutputReader) uint32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *pgoutputReader) uint64() uint64 {
+	if b := r.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (r *pgoutputReader) string() string {
+	if r.err != nil {
+		return ""
+	}
+	for i, c := range r.buf {
+		if c == 0 {
+			s := string(r.buf[:i])
+			r.buf = r.buf[i+1:]
+			return s
+		}
+	}
+	r.err = errors.New("string is not terminated")
+	return ""
+}
+
+func (r *pgoutputReader) tuple() []pgTupleColumn {
+	n := int(r.uint16())
+	cols := make([]pgTupleColumn, 0, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		col := pgTupleColumn{kind: r.byte()}
+		switch col.kind {
+		case pgTupleNull, pgTupleUnchanged:
+		case pgTupleText, pgTupleBinary:
+			col.data = r.next(int(r.uint32()))
+		default:
+			r.err = fmt.Errorf("unknown tuple column type %q", col.kind)
+		}
+		cols = append(cols, col)
+	}
+	return cols
+}
+
+// parsePgoutput parses a message of the pgoutput plugin, returning nil for
+// message types that aren't used.
+func parsePgoutput(data []byte) (any, error) {
+	r := &pgoutputReader{buf: data}
+
+	var msg any
+	switch msgType := r.byte(); msgType {
+	case 'B':
+		msg = pgBegin{}
+	case 'C':
+		_ = r.byte()   // Flags
+		_ = r.uint64() // Commit LSN
+		msg = pgCommit{endLSN: lsn(r.uint64())}
+	case 'R':
+		rel := &pgRelation{
+			id:        r.uint32(),
+			namespace: r.string(),
+			name:      r.string(),
+		}
+		_ = r.byte() // Replica identity
+		n := int(r.uint16())
+		for i := 0; i < n && r.err == nil; i++ {
+			col := pgRelationColumn{key: r.byte()&1 == 1}
+			col.name = r.string()
+			col.dataType = r.uint32()
+			_ = r.uint32() // Type modifier
+			rel.columns = append(rel.columns, col)
+		}
+		msg = rel
+	case 'I':
+		c := pgChange{operation: "insert", relationID: r.uint32()}
+		if tupleType := r.byte(); r.err == nil && tupleType != 'N' {
+			return nil, fmt.Errorf("unexpected insert tuple type %q", tupleType)
+		}
+		c.tuple = r.tuple()
+		msg = c
+	case 'U':
+		c := pgChange{operation: "update", relationID: r.uint32()}
+		tupleType := r.byte()
+		if tupleType == 'K' || tupleType == 'O' {
+			_ = r.tuple()
+			tupleType = r.byte()
+		}
+		if r.err == nil && tupleType != 'N' {
+			return nil, fmt.Errorf("unexpected update tuple type %q", tupleType)
+		}
+		c.tuple = r.tuple()
+		msg = c
+	case 'D':
+		c := pgChange{operation: "delete", relationID: r.uint32()}
+		c.keysOnly = r.byte() == 'K'
+		c.tuple = r.tuple()
+		msg = c
+	default:
+		return nil, nil
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return msg, nil
+}
+
+//------------------------------------------------------------------------------
+
+// decodeTextValue converts a value in the text format of Postgres into a
+// structured value. Numbers, booleans and JSON documents are converted into
+// their natural equivalents and all other types are represented as strings.
+func decodeTextValue(oid uint32, data []byte) (any, error) {
+	str := string(data)
+	switch oid {
+	case pgtype.BoolOID:
+		return str == "t", nil
+	case pgtype.Int2OID, pgtype.Int4OID, pgtype.Int8OID, pgtype.OIDOID:
+		i, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return i, nil
+	case pgtype.Float4OID, pgtype.Float8OID:
+		f, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return nil, err
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return str, nil
+		}
+		return f, nil
+	case pgtype.NumericOID:
+		// Special values such as NaN and Infinity are not valid JSON numbers.
+		if !json.Valid(data) {
+			return str, nil
+		}
+		return json.Number(str), nil
+	case pgtype.JSONOID, pgtype.JSONBOID:
+		var v any
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+	return str, nil
+}
+
+// tupleToMap converts the columns of a tuple into an object keyed by column
+// names. Unchanged TOAST values are omitted as their values are not sent, and
+// when keysOnly is true only the columns of the replica identity are included.
+func tupleToMap(rel *pgRelation, tuple []pgTupleColumn, keysOnly bool) (map[string]any, error) {
+	if len(tuple) > len(rel.columns) {
+		return nil, fmt.Errorf("tuple contains %v columns but relation %v has %v", len(tuple), rel.name, len(rel.columns))
+	}
+
+	row := make(map[string]any, len(tuple))
+	for i, col := range tuple {
+		relCol := rel.columns[i]
+		if keysOnly && !relCol.key {
+			continue
+		}
+
+		switch col.kind {
+		case pgTupleNull:
+			row[relCol.name] = nil
+		case pgTupleText:
+			v, err := decodeTextValue(relCol.dataType, col.data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode column %v: %w", relCol.name, err)
+			}
+			row[relCol.name] = v
+		case pgTupleBinary:
+			return nil, fmt.Errorf("column %v is in an unsupported binary format", relCol.name)
+		}
+	}
+	return row, nil
+}
@@ -0,0 +1,205 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgresql
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/jackc/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pgoutputBuilder []byte
+
+func (b pgoutputBuilder) byte(v byte) pgoutputBuilder {
+	return append(b, v)
+}
+
+func (b pgoutputBuilder) uint16(v uint16) pgoutputBuilder {
+	return binary.BigEndian.AppendUint16(b, v)
+}
+
+func (b pgoutputBuilder) uint32(v uint32) pgoutputBuilder {
+	return binary.BigEndian.AppendUint32(b, v)
+}
+
+func (b pgoutputBuilder) uint64(v uint64) pgoutputBuilder {
+	return binary.BigEndian.AppendUint64(b, v)
+}
+
+func (b pgoutputBuilder) string(v string) pgoutputBuilder {
+	return append(append(b, v...), 0)
+}
+
+// tuple appends a tuple where nil values are null, and the value "~" is an
+// unchanged TOAST value.
+func (b pgoutputBuilder) tuple(values ...any) pgoutputBuilder {
+	b = b.uint16(uint16(len(values)))
+	for _, v := range values {
+		switch t := v.(type) {
+		case nil:
+			b = b.byte('n')
+		case string:
+			if t == "~" {
+				b = b.byte('u')
+				continue
+			}
+			b = append(b.byte('t').uint32(uint32(len(t))), t...)
+		}
+	}
+	return b
+}
+
+func testRelation() *pgRelation {
+	return &pgRelation{
+		id:        16384,
+		namespace: "public",
+		name:      "orders",
+		columns: []pgRelationColumn{
+			{key: true, name: "id", dataType: pgtype.Int4OID},
+			{name: "total", dataType: pgtype.NumericOID},
+			{name: "paid", dataType: pgtype.BoolOID},
+			{name: "meta", dataType: pgtype.JSONBOID},
+			{name: "note", dataType: pgtype.TextOID},
+		},
+	}
+}
+
+func TestPgoutputParseRelation(t *testing.T) {
+	b := pgoutputBuilder{}.byte('R').uint32(16384).string("public").string("orders").byte('d').uint16(5)
+	for _, c := range testRelation().columns {
+		var flags byte
+		if c.key {
+			flags = 1
+		}
+		b = b.byte(flags).string(c.name).uint32(c.dataType).uint32(0xFFFFFFFF)
+	}
+
+	msg, err := parsePgoutput(b)
+	require.NoError(t, err)
+	assert.Equal(t, testRelation(), msg)
+}
+
+func TestPgoutputParseChanges(t *testing.T) {
+	rel := testRelation()
+
+	tests := []struct {
+		name      string
+		data      pgoutputBuilder
+		operation string
+		row       map[string]any
+	}{
+		{
+			name:      "insert",
+			data:      pgoutputBuilder{}.byte('I').uint32(16384).byte('N').tuple("1", "10.50", "t", `{"a":[1]}`, nil),
+			operation: "insert",
+			row: map[string]any{
+				"id":    int64(1),
+				"total": json.Number("10.50"),
+				"paid":  true,
+				"meta":  map[string]any{"a": []any{1.0}},
+				"note":  nil,
+			},
+		},
+		{
+			name:      "update with unchanged toast",
+			data:      pgoutputBuilder{}.byte('U').uint32(16384).byte('N').tuple("1", "NaN", "f", "~", "hello"),
+			operation: "update",
+			row: map[string]any{
+				"id":    int64(1),
+				"total": "NaN",
+				"paid":  false,
+				"note":  "hello",
+			},
+		},
+		{
+			name:      "update with old key",
+			data:      pgoutputBuilder{}.byte('U').uint32(16384).byte('K').tuple("1", nil, nil, nil, nil).byte('N').tuple("2", "1", "t", "null", "x"),
+			operation: "update",
+			row: map[string]any{
+				"id":    int64(2),
+				"total": json.Number("1"),
+				"paid":  true,
+				"meta":  nil,
+				"note":  "x",
+			},
+		},
+		{
+			name:      "delete by key",
+			data:      pgoutputBuilder{}.byte('D').uint32(16384).byte('K').tuple("3", nil, nil, nil, nil),
+			operation: "delete",
+			row:       map[string]any{"id": int64(3)},
+		},
+		{
+			name:      "delete full",
+			data:      pgoutputBuilder{}.byte('D').uint32(16384).byte('O').tuple("3", "2", "f", "{}", "gone"),
+			operation: "delete",
+			row: map[string]any{
+				"id":    int64(3),
+				"total": json.Number("2"),
+				"paid":  false,
+				"meta":  map[string]any{},
+				"note":  "gone",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			msg, err := parsePgoutput(test.data)
+			require.NoError(t, err)
+
+			change, ok := msg.(pgChange)
+			require.True(t, ok, "%T", msg)
+			assert.Equal(t, test.operation, change.operation)
+			assert.Equal(t, uint32(16384), change.relationID)
+
+			row, err := tupleToMap(rel, change.tuple, change.keysOnly)
+			require.NoError(t, err)
+			assert.Equal(t, test.row, row)
+		})
+	}
+}
+
+func TestPgoutputParseTransaction(t *testing.T) {
+	msg, err := parsePgoutput(pgoutputBuilder{}.byte('B').uint64(100).uint64(0).uint32(5))
+	require.NoError(t, err)
+	assert.Equal(t, pgBegin{}, msg)
+
+	msg, err = parsePgoutput(pgoutputBuilder{}.byte('C').byte(0).uint64(100).uint64(0x1_00000020).uint64(0))
+	require.NoError(t, err)
+	assert.Equal(t, pgCommit{endLSN: 0x1_00000020}, msg)
+
+	msg, err = parsePgoutput(pgoutputBuilder{}.byte('Y').uint32(1).string("public").string("foo"))
+	require.NoError(t, err)
+	assert.Nil(t, msg)
+
+	_, err = parsePgoutput(pgoutputBuilder{}.byte('I').uint32(1).byte('N').uint16(1).byte('t').uint32(10))
+	require.Error(t, err)
+}
+
+func TestLSN(t *testing.T) {
+	pos, err := parseLSN("16/B374D848")
+	require.NoError(t, err)
+	assert.Equal(t, lsn(0x16_B374D848), pos)
+	assert.Equal(t, "16/B374D848", pos.String())
+
+	_, err = parseLSN("nope")
+	require.Error(t, err)
+}
@@ -0,0 +1,109 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgresql
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgproto3/v2"
+)
+
+// Identifiers of the messages exchanged within the CopyBoth sub-protocol of a
+// replication connection, as documented at:
+// https://www.postgresql.org/docs/current/protocol-replication.html
+const (
+	replKeepaliveID     = 'k'
+	replXLogDataID      = 'w'
+	replStatusUpdateID  = 'r'
+	replKeepaliveLength = 17
+	replXLogHeaderSize  = 24
+)
+
+var pgEpoch = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// startReplication issues the START_REPLICATION command and waits for the
+// database to switch the connection into CopyBoth mode.
+func startReplication(ctx context.Context, conn *pgconn.PgConn, slot string, start lsn, pluginArgs []string) error {
+	sql := fmt.Sprintf("START_REPLICATION SLOT %v LOGICAL %v", slot, start)
+	if len(pluginArgs) > 0 {
+		sql += " (" + strings.Join(pluginArgs, ", ") + ")"
+	}
+
+	buf, err := (&pgproto3.Query{String: sql}).Encode(nil)
+	if err != nil {
+		return err
+	}
+	if err := conn.SendBytes(ctx, buf); err != nil {
+		return err
+	}
+
+	for {
+		msg, err := conn.ReceiveMessage(ctx)
+		if err != nil {
+			return err
+		}
+		switch t := msg.(type) {
+		case *pgproto3.NoticeResponse:
+		case *pgproto3.ErrorResponse:
+			return pgconn.ErrorResponseToPgError(t)
+		case *pgproto3.CopyBothResponse:
+			return nil
+		default:
+			return fmt.Errorf("unexpected response to START_REPLICATION: %T", msg)
+		}
+	}
+}
+
+// sendStandbyStatus reports the position up to which changes have been
+// processed, allowing the database to advance the replication slot.
+func sendStandbyStatus(ctx context.Context, conn *pgconn.PgConn, pos lsn) error {
+	data := make([]byte, 34)
+	data[0] = replStatusUpdateID
+	binary.BigEndian.PutUint64(data[1:], uint64(pos))  // Written
+	binary.BigEndian.PutUint64(data[9:], uint64(pos))  // Flushed
+	binary.BigEndian.PutUint64(data[17:], uint64(pos)) // Applied
+	binary.BigEndian.PutUint64(data[25:], uint64(time.Since(pgEpoch).Microseconds()))
+	data[33] = 0
+
+	buf, err := (&pgproto3.CopyData{Data: data}).Encode(nil)
+	if err != nil {
+		return err
+	}
+	return conn.SendBytes(ctx, buf)
+}
+
+// parseKeepalive returns whether a primary keepalive message requests an
+// immediate status update.
+func parseKeepalive(data []byte) (bool, error) {
+	if len(data) != replKeepaliveLength {
+		return false, fmt.Errorf("keepalive message must be %v bytes, got %v", replKeepaliveLength, len(data))
+	}
+	return data[16] != 0, nil
+}
+
+// parseXLogData returns the starting position and the contents of a WAL data
+// message.
+func parseXLogData(data []byte) (lsn, []byte, error) {
+	if len(data) < replXLogHeaderSize {
+		return 0, nil, errors.New("wal data message is too short")
+	}
+	return lsn(binary.BigEndian.Uint64(data)), data[replXLogHeaderSize:], nil
+}
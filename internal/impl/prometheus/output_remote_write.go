@@ -0,0 +1,417 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/retries"
+)
+
+const (
+	prwFieldURL               = "url"
+	prwFieldName              = "name"
+	prwFieldValue             = "value"
+	prwFieldTimestamp         = "timestamp"
+	prwFieldLabels            = "labels"
+	prwFieldBasicAuth         = "basic_auth"
+	prwFieldBasicAuthUsername = "username"
+	prwFieldBasicAuthPassword = "password"
+	prwFieldBearerToken       = "bearer_token"
+	prwFieldTLS               = "tls"
+	prwFieldTimeout           = "timeout"
+	prwFieldMaxInFlight       = "max_in_flight"
+	prwFieldBatching          = "batching"
+)
+
+func remoteWriteOutputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.31.0").
+		Summary("Writes each message as a sample to a Prometheus compatible database using the https://prometheus.io/docs/concepts/remote_write_spec/[remote write protocol^].").
+		Description(`
+The metric name, value, timestamp and labels of each sample are resolved from each message with the fields `+"`name`, `value`, `timestamp` and `labels`"+`. Labels that resolve to an empty string are omitted. Samples of a batch are grouped into a time series for each unique combination of name and labels, and sent in a single snappy compressed protobuf request.
+
+Requests that fail with a 5xx or 429 status code are retried according to the `+"`max_retries`"+` and `+"`backoff`"+` fields, whereas other failures are returned immediately as they will not succeed when retried. Note that most databases reject samples that are out of order for a series, and so ordering should be preserved by keeping `+"`max_in_flight`"+` at one.
+
+Set the field `+"`max_in_flight`"+` to a higher value in order to send multiple requests in parallel.`).
+		Fields(
+			service.NewURLField(prwFieldURL).
+				Description("The URL of the remote write endpoint.").
+				Example("http://localhost:9090/api/v1/write"),
+			service.NewInterpolatedStringField(prwFieldName).
+				Description("The name of the metric of each sample.").
+				Example(`${! this.name }`),
+			service.NewInterpolatedStringField(prwFieldValue).
+				Description("The value of each sample, which must resolve to a number.").
+				Example(`${! this.value }`),
+			service.NewInterpolatedStringField(prwFieldTimestamp).
+				Description("An optional timestamp of each sample, which must resolve to either an integer of milliseconds since the unix epoch or an RFC 3339 timestamp. When empty the time at which the sample is written is used.").
+				Example(`${! this.timestamp_ms }`).
+				Example(`${! @timestamp }`).
+				Default(""),
+			service.NewInterpolatedStringMapField(prwFieldLabels).
+				Description("A map of labels to add to each sample.").
+				Example(map[string]any{
+					"host": `${! this.host }`,
+					"job":  "benthos",
+				}).
+				Default(map[string]any{}),
+			service.NewObjectField(prwFieldBasicAuth,
+				service.NewStringField(prwFieldBasicAuthUsername).
+					Description("The Basic Authentication username.").
+					Default(""),
+				service.NewStringField(prwFieldBasicAuthPassword).
+					Description("The Basic Authentication password.").
+					Secret().
+					Default(""),
+			).Description("Optional Basic Authentication credentials, which are used when the username is set.").
+				Advanced(),
+			service.NewStringField(prwFieldBearerToken).
+				Description("An optional bearer token to add to the authorization header of requests.").
+				Secret().
+				Default("").
+				Advanced(),
+			service.NewTLSToggledField(prwFieldTLS),
+			service.NewDurationField(prwFieldTimeout).
+				Description("The maximum period of time to wait for each request.").
+				Default("10s").
+				Advanced(),
+			service.NewIntField(prwFieldMaxInFlight).
+				Description("The maximum number of requests to have in flight at a given time.").
+				Default(1),
+			service.NewBatchPolicyField(prwFieldBatching),
+		).
+		Fields(retries.CommonRetryBackOffFields(3, "500ms", "10s", "1m")...).
+		Example("Metrics from JSON events", "Convert JSON documents describing measurements into samples.", `
+output:
+  prometheus_remote_write:
+    url: http://localhost:9090/api/v1/write
+    name: ${! this.metric }
+    value: ${! this.value }
+    timestamp: ${! this.ts.ts_unix_milli() }
+    labels:
+      host: ${! this.host }
+      region: ${! this.region }
+    batching:
+      count: 500
+      period: 5s
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"prometheus_remote_write", remoteWriteOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if batchPolicy, err = conf.FieldBatchPolicy(prwFieldBatching); err != nil {
+				return
+			}
+			if maxInFlight, err = conf.FieldInt(prwFieldMaxInFlight); err != nil {
+				return
+			}
+			out, err = newRemoteWriteOutputFromConfig(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type remoteWriteOutput struct {
+	url         string
+	name        *service.InterpolatedString
+	value       *service.InterpolatedString
+	timestamp   *service.InterpolatedString
+	labels      map[string]*service.InterpolatedString
+	username    string
+	password    string
+	bearerToken string
+	backoffCtor func() backoff.BackOff
+
+	client *http.Client
+	log    *service.Logger
+	now    func() time.Time
+}
+
+func newRemoteWriteOutputFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*remoteWriteOutput, error) {
+	r := &remoteWriteOutput{
+		log: mgr.Logger(),
+		now: time.Now,
+	}
+
+	u, err := conf.FieldURL(prwFieldURL)
+	if err != nil {
+		return nil, err
+	}
+	r.url = u.String()
+
+	if r.name, err = conf.FieldInterpolatedString(prwFieldName); err != nil {
+		return nil, err
+	}
+	if r.value, err = conf.FieldInterpolatedString(prwFieldValue); err != nil {
+		return nil, err
+	}
+	if r.timestamp, err = conf.FieldInterpolatedString(prwFieldTimestamp); err != nil {
+		return nil, err
+	}
+	if r.labels, err = conf.FieldInterpolatedStringMap(prwFieldLabels); err != nil {
+		return nil, err
+	}
+	for k := range r.labels {
+		if k == "__name__" {
+			return nil, errors.New("the label __name__ is reserved for the metric name, use the name field instead")
+		}
+	}
+
+	if r.username, err = conf.FieldString(prwFieldBasicAuth, prwFieldBasicAuthUsername); err != nil {
+		return nil, err
+	}
+	if r.password, err = conf.FieldString(prwFieldBasicAuth, prwFieldBasicAuthPassword); err != nil {
+		return nil, err
+	}
+	if r.bearerToken, err = conf.FieldString(prwFieldBearerToken); err != nil {
+		return nil, err
+	}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(prwFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := conf.FieldDuration(prwFieldTimeout)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsEnabled {
+		transport.TLSClientConfig = tlsConf
+	}
+	r.client = &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}
+
+	if r.backoffCtor, err = retries.CommonRetryBackOffCtorFromParsed(conf); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *remoteWriteOutput) Connect(ctx context.Context) error {
+	return nil
+}
+
+type remoteWriteLabel struct {
+	name, value string
+}
+
+type remoteWriteSample struct {
+	value     float64
+	timestamp int64
+}
+
+type remoteWriteSeries struct {
+	labels  []remoteWriteLabel
+	samples []remoteWriteSample
+}
+
+func (r *remoteWriteOutput) parseTimestamp(tStr string) (int64, error) {
+	if tStr == "" {
+		return r.now().UnixMilli(), nil
+	}
+	if ms, err := strconv.ParseInt(tStr, 10, 64); err == nil {
+		return ms, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, tStr)
+	if err != nil {
+		return 0, fmt.Errorf("expected unix milliseconds or an RFC 3339 timestamp: %w", err)
+	}
+	return t.UnixMilli(), nil
+}
+
+// seriesFromBatch resolves a sample from each message and groups them into a
+// time series for each unique set of labels.
+func (r *remoteWriteOutput) seriesFromBatch(batch service.MessageBatch) ([]*remoteWriteSeries, error) {
+	var series []*remoteWriteSeries
+	seriesByKey := map[string]*remoteWriteSeries{}
+
+	for i := range batch {
+		name, err := batch.TryInterpolatedString(i, r.name)
+		if err != nil {
+			return nil, fmt.Errorf("name interpolation error: %w", err)
+		}
+		if name == "" {
+			return nil, fmt.Errorf("message %v resolved an empty metric name", i)
+		}
+
+		valueStr, err := batch.TryInterpolatedString(i, r.value)
+		if err != nil {
+			return nil, fmt.Errorf("value interpolation error: %w", err)
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(valueStr), 64)
+		if err != nil {
+			return nil, fmt.Errorf("message %v resolved a non-numeric value: %w", i, err)
+		}
+
+		tStr, err := batch.TryInterpolatedString(i, r.timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("timestamp interpolation error: %w", err)
+		}
+		timestamp, err := r.parseTimestamp(tStr)
+		if err != nil {
+			return nil, fmt.Errorf("message %v resolved an invalid timestamp: %w", i, err)
+		}
+
+		labels := []remoteWriteLabel{{name: "__name__", value: name}}
+		for k, v := range r.labels {
+			lValue, err := batch.TryInterpolatedString(i, v)
+			if err != nil {
+				return nil, fmt.Errorf("label %v interpolation error: %w", k, err)
+			}
+			if lValue != "" {
+				labels = append(labels, remoteWriteLabel{name: k, value: lValue})
+			}
+		}
+		sort.Slice(labels, func(i, j int) bool {
+			return labels[i].name < labels[j].name
+		})
+
+		var keyBuilder strings.Builder
+		for _, l := range labels {
+			keyBuilder.WriteString(l.name)
+			keyBuilder.WriteByte(0xff)
+			keyBuilder.WriteString(l.value)
+			keyBuilder.WriteByte(0xff)
+		}
+		key := keyBuilder.String()
+
+		s, exists := seriesByKey[key]
+		if !exists {
+			s = &remoteWriteSeries{labels: labels}
+			seriesByKey[key] = s
+			series = append(series, s)
+		}
+		s.samples = append(s.samples, remoteWriteSample{value: value, timestamp: timestamp})
+	}
+
+	for _, s := range series {
+		sort.SliceStable(s.samples, func(i, j int) bool {
+			return s.samples[i].timestamp < s.samples[j].timestamp
+		})
+	}
+	return series, nil
+}
+
+// encodeWriteRequest encodes a prometheus.WriteRequest protobuf message.
+func encodeWriteRequest(series []*remoteWriteSeries) []byte {
+	var req []byte
+	for _, s := range series {
+		var ts []byte
+		for _, l := range s.labels {
+			var lb []byte
+			lb = protowire.AppendTag(lb, 1, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.name)
+			lb = protowire.AppendTag(lb, 2, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.value)
+
+			ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, lb)
+		}
+		for _, sample := range s.samples {
+			var sb []byte
+			sb = protowire.AppendTag(sb, 1, protowire.Fixed64Type)
+			sb = protowire.AppendFixed64(sb, math.Float64bits(sample.value))
+			sb = protowire.AppendTag(sb, 2, protowire.VarintType)
+			sb = protowire.AppendVarint(sb, uint64(sample.timestamp))
+
+			ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, sb)
+		}
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendBytes(req, ts)
+	}
+	return req
+}
+
+func (r *remoteWriteOutput) send(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return backoff.Permanent(err)
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "Benthos")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if r.username != "" {
+		req.SetBasicAuth(r.username, r.password)
+	} else if r.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+r.bearerToken)
+	}
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return nil
+	}
+
+	resBody, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	err = fmt.Errorf("remote write returned status %v: %s", res.StatusCode, bytes.TrimSpace(resBody))
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500 {
+		return err
+	}
+	return backoff.Permanent(err)
+}
+
+func (r *remoteWriteOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	series, err := r.seriesFromBatch(batch)
+	if err != nil {
+		return err
+	}
+	body := snappy.Encode(nil, encodeWriteRequest(series))
+
+	return backoff.RetryNotify(func() error {
+		return r.send(ctx, body)
+	}, backoff.WithContext(r.backoffCtor(), ctx), func(err error, wait time.Duration) {
+		r.log.Warnf("Failed to send samples, retrying in %v: %v", wait, err)
+	})
+}
+
+func (r *remoteWriteOutput) Close(ctx context.Context) error {
+	r.client.CloseIdleConnections()
+	return nil
+}
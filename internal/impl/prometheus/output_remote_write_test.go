@@ -0,0 +1,213 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+// decodeWriteRequest decodes a prometheus.WriteRequest protobuf message into
+// a readable form of one string per series.
+func decodeWriteRequest(t testing.TB, b []byte) []string {
+	t.Helper()
+
+	consumeFields := func(b []byte, fn func(num protowire.Number, typ protowire.Type, b []byte) int) {
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			require.GreaterOrEqual(t, n, 0)
+			b = b[n:]
+			n = fn(num, typ, b)
+			require.GreaterOrEqual(t, n, 0)
+			b = b[n:]
+		}
+	}
+
+	var series []string
+	consumeFields(b, func(_ protowire.Number, _ protowire.Type, b []byte) int {
+		tsBytes, n := protowire.ConsumeBytes(b)
+		var str string
+		consumeFields(tsBytes, func(num protowire.Number, _ protowire.Type, b []byte) int {
+			fieldBytes, n := protowire.ConsumeBytes(b)
+			var parts []any
+			consumeFields(fieldBytes, func(num protowire.Number, typ protowire.Type, b []byte) int {
+				switch typ {
+				case protowire.BytesType:
+					v, n := protowire.ConsumeString(b)
+					parts = append(parts, v)
+					return n
+				case protowire.Fixed64Type:
+					v, n := protowire.ConsumeFixed64(b)
+					parts = append(parts, math.Float64frombits(v))
+					return n
+				default:
+					v, n := protowire.ConsumeVarint(b)
+					parts = append(parts, int64(v))
+					return n
+				}
+			})
+			if num == 1 {
+				str += fmt.Sprintf("%v=%v ", parts[0], parts[1])
+			} else {
+				str += fmt.Sprintf("%v@%v ", parts[0], parts[1])
+			}
+			return n
+		})
+		series = append(series, str)
+		return n
+	})
+	return series
+}
+
+func TestRemoteWriteOutput(t *testing.T) {
+	var reqMut sync.Mutex
+	var reqs [][]string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "0.1.0", r.Header.Get("X-Prometheus-Remote-Write-Version"))
+		assert.Equal(t, "Bearer meow", r.Header.Get("Authorization"))
+
+		compressed, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		body, err := snappy.Decode(nil, compressed)
+		require.NoError(t, err)
+
+		reqMut.Lock()
+		reqs = append(reqs, decodeWriteRequest(t, body))
+		reqMut.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(ts.Close)
+
+	conf, err := remoteWriteOutputConfig().ParseYAML(fmt.Sprintf(`
+url: %v
+name: ${! this.name }
+value: ${! this.value }
+timestamp: ${! this.ts }
+bearer_token: meow
+labels:
+  host: ${! this.host }
+  region: ${! this.region.or("") }
+`, ts.URL), nil)
+	require.NoError(t, err)
+
+	out, err := newRemoteWriteOutputFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+
+	out.now = func() time.Time {
+		return time.UnixMilli(5000)
+	}
+
+	var batch service.MessageBatch
+	for _, doc := range []string{
+		`{"name":"cpu","value":0.5,"ts":2000,"host":"a"}`,
+		`{"name":"cpu","value":0.7,"ts":1000,"host":"a"}`,
+		`{"name":"cpu","value":1,"ts":"1970-01-01T00:00:03Z","host":"b","region":"eu"}`,
+		`{"name":"mem","value":"42","ts":"","host":"a"}`,
+	} {
+		batch = append(batch, service.NewMessage([]byte(doc)))
+	}
+	require.NoError(t, out.WriteBatch(context.Background(), batch))
+
+	assert.Equal(t, [][]string{{
+		"__name__=cpu host=a 0.7@1000 0.5@2000 ",
+		"__name__=cpu host=b region=eu 1@3000 ",
+		"__name__=mem host=a 42@5000 ",
+	}}, reqs)
+}
+
+func TestRemoteWriteOutputRetries(t *testing.T) {
+	var reqMut sync.Mutex
+	var statuses []int
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqMut.Lock()
+		defer reqMut.Unlock()
+
+		status := http.StatusNoContent
+		if len(statuses) < 2 {
+			status = http.StatusServiceUnavailable
+		}
+		if r.URL.Path == "/bad" {
+			status = http.StatusBadRequest
+		}
+		statuses = append(statuses, status)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(ts.Close)
+
+	conf := `
+url: %v
+name: foo
+value: ${! content() }
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+`
+	batch := service.MessageBatch{service.NewMessage([]byte(`1`))}
+
+	pConf, err := remoteWriteOutputConfig().ParseYAML(fmt.Sprintf(conf, ts.URL), nil)
+	require.NoError(t, err)
+
+	out, err := newRemoteWriteOutputFromConfig(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	require.NoError(t, out.WriteBatch(context.Background(), batch))
+	assert.Equal(t, []int{503, 503, 204}, statuses)
+
+	statuses = nil
+	pConf, err = remoteWriteOutputConfig().ParseYAML(fmt.Sprintf(conf, ts.URL+"/bad"), nil)
+	require.NoError(t, err)
+
+	out, err = newRemoteWriteOutputFromConfig(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	err = out.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 400")
+	assert.Equal(t, []int{400}, statuses)
+}
+
+func TestRemoteWriteOutputBadValue(t *testing.T) {
+	conf, err := remoteWriteOutputConfig().ParseYAML(`
+url: http://localhost:1234
+name: foo
+value: ${! content() }
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newRemoteWriteOutputFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+
+	err = out.WriteBatch(context.Background(), service.MessageBatch{service.NewMessage([]byte(`nope`))})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "non-numeric value")
+}
@@ -0,0 +1,269 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	bubFieldCheck          = "check"
+	bubFieldIncludeTrigger = "include_trigger"
+)
+
+func batchUntilBufferConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Accumulates messages into a batch until a message arrives for which a Bloblang query returns `true`, at which point the batch is closed and flushed downstream.").
+		Description(`
+This buffer is useful for grouping an ordered stream of messages into batches along logical boundaries, such as a sentinel message that marks the end of a transaction. The `+"`check`"+` query is executed against each message individually as it arrives, and when it returns `+"`true`"+` the current batch is closed.
+
+The message that triggers the check is added to the end of the batch it closes by default. When `+"`include_trigger`"+` is set to `+"`false`"+` the triggering message is instead used as the first message of the next batch, which is useful when the sentinel marks the beginning of a transaction rather than the end.
+
+When the input ends any remaining messages are flushed as a final batch.
+
+== Delivery guarantees
+
+Messages are not acknowledged at the input level until the batch they were added to has been successfully delivered downstream. This means delivery guarantees are preserved, but it also means that the input must be able to have at least as many messages in flight as the largest expected batch, otherwise the pipeline will stall waiting for acknowledgements that can never arrive.
+
+There is no upper bound on the size of a batch, and therefore a stream that never triggers the check will accumulate messages in memory until the input ends.`).
+		Field(service.NewBloblangField(bubFieldCheck).
+			Description("A Bloblang query that should return a boolean value indicating whether a message should close the current batch.").
+			Examples(`this.type == "end_of_transaction"`, `@kafka_key == "EOT"`)).
+		Field(service.NewBoolField(bubFieldIncludeTrigger).
+			Description("Whether the message that triggers the check should be the last message of the batch it closes. When `false` the message is instead the first message of the next batch.").
+			Default(true)).
+		Example("Transaction boundaries", "Group an ordered stream of change events into transactions, where each transaction ends with a commit event.", `
+input:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topics: [ changes ]
+    consumer_group: benthos
+
+buffer:
+  batch_until:
+    check: this.op == "commit"
+
+pipeline:
+  processors:
+    - archive:
+        format: json_array
+`)
+}
+
+func init() {
+	err := service.RegisterBatchBuffer(
+		"batch_until", batchUntilBufferConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchBuffer, error) {
+			return newBatchUntilBufferFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func newBatchUntilBufferFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*batchUntilBuffer, error) {
+	check, err := conf.FieldBloblang(bubFieldCheck)
+	if err != nil {
+		return nil, err
+	}
+	includeTrigger, err := conf.FieldBool(bubFieldIncludeTrigger)
+	if err != nil {
+		return nil, err
+	}
+	return newBatchUntilBuffer(check, includeTrigger), nil
+}
+
+//------------------------------------------------------------------------------
+
+// inputAck tracks the acknowledgement of a single batch written to the buffer,
+// the messages of which may be spread across any number of output batches.
+type inputAck struct {
+	aFn     service.AckFunc
+	pending int
+	err     error
+}
+
+type untilBatch struct {
+	msgs service.MessageBatch
+	acks []*inputAck
+}
+
+func (u *untilBatch) add(msg *service.Message, ack *inputAck) {
+	if len(u.acks) == 0 || u.acks[len(u.acks)-1] != ack {
+		ack.pending++
+		u.acks = append(u.acks, ack)
+	}
+	u.msgs = append(u.msgs, msg)
+}
+
+type batchUntilBuffer struct {
+	check          *bloblang.Executor
+	includeTrigger bool
+
+	cond       *sync.Cond
+	current    *untilBatch
+	ready      []*untilBatch
+	endOfInput bool
+	closed     bool
+}
+
+func newBatchUntilBuffer(check *bloblang.Executor, includeTrigger bool) *batchUntilBuffer {
+	return &batchUntilBuffer{
+		check:          check,
+		includeTrigger: includeTrigger,
+		cond:           sync.NewCond(&sync.Mutex{}),
+		current:        &untilBatch{},
+	}
+}
+
+// flush moves the current batch into the ready queue. Must be called whilst
+// holding the lock.
+func (b *batchUntilBuffer) flush() {
+	if len(b.current.msgs) == 0 {
+		return
+	}
+	b.ready = append(b.ready, b.current)
+	b.current = &untilBatch{}
+}
+
+func (b *batchUntilBuffer) WriteBatch(ctx context.Context, batch service.MessageBatch, aFn service.AckFunc) error {
+	// Run all checks up front so that a failure doesn't leave part of the
+	// batch within the buffer.
+	triggers := make([]bool, len(batch))
+	for i := range batch {
+		resMsg, err := batch.BloblangQuery(i, b.check)
+		if err != nil {
+			return fmt.Errorf("check failed: %w", err)
+		}
+		if resMsg == nil {
+			return errors.New("check mapping deleted the message")
+		}
+		v, err := resMsg.AsStructured()
+		if err != nil {
+			return fmt.Errorf("check failed: %w", err)
+		}
+		var isBool bool
+		if triggers[i], isBool = v.(bool); !isBool {
+			return fmt.Errorf("check returned non-boolean value: %T", v)
+		}
+	}
+
+	b.cond.L.Lock()
+	defer b.cond.L.Unlock()
+
+	if b.closed {
+		return service.ErrEndOfBuffer
+	}
+
+	ack := &inputAck{aFn: aFn}
+	for i, msg := range batch {
+		if !triggers[i] {
+			b.current.add(msg, ack)
+			continue
+		}
+		if b.includeTrigger {
+			b.current.add(msg, ack)
+			b.flush()
+		} else {
+			b.flush()
+			b.current.add(msg, ack)
+		}
+	}
+
+	b.cond.Broadcast()
+	return nil
+}
+
+func (b *batchUntilBuffer) ackFnFor(u *untilBatch) service.AckFunc {
+	var once sync.Once
+	return func(ctx context.Context, err error) (ackErr error) {
+		once.Do(func() {
+			b.cond.L.Lock()
+			var toCall []*inputAck
+			for _, ack := range u.acks {
+				if err != nil && ack.err == nil {
+					ack.err = err
+				}
+				if ack.pending--; ack.pending == 0 {
+					toCall = append(toCall, ack)
+				}
+			}
+			b.cond.L.Unlock()
+
+			for _, ack := range toCall {
+				if aErr := ack.aFn(ctx, ack.err); aErr != nil {
+					ackErr = aErr
+				}
+			}
+		})
+		return
+	}
+}
+
+func (b *batchUntilBuffer) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	ctx, done := context.WithCancel(ctx)
+	defer done()
+
+	go func() {
+		<-ctx.Done()
+		b.cond.Broadcast()
+	}()
+
+	b.cond.L.Lock()
+	defer b.cond.L.Unlock()
+
+	for len(b.ready) == 0 {
+		if b.closed || b.endOfInput {
+			return nil, nil, service.ErrEndOfBuffer
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		b.cond.Wait()
+	}
+
+	next := b.ready[0]
+	b.ready[0] = nil
+	b.ready = b.ready[1:]
+	return next.msgs, b.ackFnFor(next), nil
+}
+
+func (b *batchUntilBuffer) EndOfInput() {
+	go func() {
+		b.cond.L.Lock()
+		defer b.cond.L.Unlock()
+
+		b.flush()
+		b.endOfInput = true
+		b.cond.Broadcast()
+	}()
+}
+
+func (b *batchUntilBuffer) Close(ctx context.Context) error {
+	b.cond.L.Lock()
+	b.closed = true
+	b.cond.Broadcast()
+	b.cond.L.Unlock()
+	return nil
+}
@@ -0,0 +1,204 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func batchContents(t testing.TB, b service.MessageBatch) []string {
+	t.Helper()
+
+	var res []string
+	for _, m := range b {
+		mBytes, err := m.AsBytes()
+		require.NoError(t, err)
+		res = append(res, string(mBytes))
+	}
+	return res
+}
+
+func TestBatchUntilBufferInclusive(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	conf, err := batchUntilBufferConfig().ParseYAML(`check: content() == "end"`, nil)
+	require.NoError(t, err)
+
+	buf, err := newBatchUntilBufferFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+
+	var acked []error
+	ackFn := func(ctx context.Context, err error) error {
+		acked = append(acked, err)
+		return nil
+	}
+
+	require.NoError(t, buf.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte("a")),
+		service.NewMessage([]byte("b")),
+	}, ackFn))
+	require.NoError(t, buf.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte("end")),
+		service.NewMessage([]byte("c")),
+	}, ackFn))
+
+	b, aFn, err := buf.ReadBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "end"}, batchContents(t, b))
+
+	require.NoError(t, aFn(ctx, nil))
+	assert.Equal(t, []error{nil}, acked, "second input batch is still pending")
+
+	buf.EndOfInput()
+
+	b, aFn, err = buf.ReadBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c"}, batchContents(t, b))
+
+	require.NoError(t, aFn(ctx, nil))
+	assert.Equal(t, []error{nil, nil}, acked)
+
+	_, _, err = buf.ReadBatch(ctx)
+	require.ErrorIs(t, err, service.ErrEndOfBuffer)
+}
+
+func TestBatchUntilBufferExclusive(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	conf, err := batchUntilBufferConfig().ParseYAML(`
+check: this.begin == true
+include_trigger: false
+`, nil)
+	require.NoError(t, err)
+
+	buf, err := newBatchUntilBufferFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+
+	ackFn := func(ctx context.Context, err error) error { return nil }
+	for _, s := range []string{
+		`{"begin":true,"id":1}`,
+		`{"id":2}`,
+		`{"begin":true,"id":3}`,
+		`{"id":4}`,
+		`{"begin":true,"id":5}`,
+	} {
+		require.NoError(t, buf.WriteBatch(ctx, service.MessageBatch{
+			service.NewMessage([]byte(s)),
+		}, ackFn))
+	}
+
+	b, _, err := buf.ReadBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{"begin":true,"id":1}`, `{"id":2}`}, batchContents(t, b))
+
+	b, _, err = buf.ReadBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{"begin":true,"id":3}`, `{"id":4}`}, batchContents(t, b))
+
+	buf.EndOfInput()
+
+	b, _, err = buf.ReadBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{"begin":true,"id":5}`}, batchContents(t, b))
+
+	_, _, err = buf.ReadBatch(ctx)
+	require.ErrorIs(t, err, service.ErrEndOfBuffer)
+}
+
+func TestBatchUntilBufferNackPropagates(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	conf, err := batchUntilBufferConfig().ParseYAML(`check: content() == "end"`, nil)
+	require.NoError(t, err)
+
+	buf, err := newBatchUntilBufferFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+
+	var acked []error
+	require.NoError(t, buf.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte("a")),
+		service.NewMessage([]byte("end")),
+		service.NewMessage([]byte("b")),
+		service.NewMessage([]byte("end")),
+	}, func(ctx context.Context, err error) error {
+		acked = append(acked, err)
+		return nil
+	}))
+
+	_, aFnOne, err := buf.ReadBatch(ctx)
+	require.NoError(t, err)
+
+	_, aFnTwo, err := buf.ReadBatch(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, aFnOne(ctx, errors.New("nope")))
+	assert.Empty(t, acked)
+
+	require.NoError(t, aFnTwo(ctx, nil))
+	require.Len(t, acked, 1)
+	require.EqualError(t, acked[0], "nope")
+}
+
+func TestBatchUntilBufferCheckErrors(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	conf, err := batchUntilBufferConfig().ParseYAML(`check: this.end`, nil)
+	require.NoError(t, err)
+
+	buf, err := newBatchUntilBufferFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+
+	require.Error(t, buf.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"end":false}`)),
+		service.NewMessage([]byte(`not json`)),
+	}, func(ctx context.Context, err error) error { return nil }))
+
+	require.Error(t, buf.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"end":"nah"}`)),
+	}, func(ctx context.Context, err error) error { return nil }))
+
+	buf.EndOfInput()
+
+	_, _, err = buf.ReadBatch(ctx)
+	require.ErrorIs(t, err, service.ErrEndOfBuffer)
+}
+
+func TestBatchUntilBufferReadCancelled(t *testing.T) {
+	conf, err := batchUntilBufferConfig().ParseYAML(`check: content() == "end"`, nil)
+	require.NoError(t, err)
+
+	buf, err := newBatchUntilBufferFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer done()
+
+	_, _, err = buf.ReadBatch(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	require.NoError(t, buf.Close(context.Background()))
+}
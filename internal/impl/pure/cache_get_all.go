@@ -0,0 +1,66 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"sync"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type cacheGetResult struct {
+	value []byte
+	err   error
+}
+
+// cacheGetAll obtains each unique key from a cache, with no more than
+// parallelism requests in flight at any given time.
+func cacheGetAll(
+	ctx context.Context,
+	accessCache func(ctx context.Context, fn func(c service.Cache)) error,
+	parallelism int,
+	keys []string,
+) (map[string]cacheGetResult, error) {
+	results := make(map[string]cacheGetResult, len(keys))
+
+	var resMut sync.Mutex
+	if err := accessCache(ctx, func(c service.Cache) {
+		keyChan := make(chan string)
+
+		var wg sync.WaitGroup
+		for i := 0; i < parallelism && i < len(keys); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for k := range keyChan {
+					v, err := c.Get(ctx, k)
+					resMut.Lock()
+					results[k] = cacheGetResult{value: v, err: err}
+					resMut.Unlock()
+				}
+			}()
+		}
+
+		for _, k := range keys {
+			keyChan <- k
+		}
+		close(keyChan)
+		wg.Wait()
+	}); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,154 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+// fakeLocale contains the data used to generate fake values that are
+// realistic for a given locale. Patterns are expanded by replacing each `#`
+// with a random digit and each `@` with a random upper case letter.
+type fakeLocale struct {
+	country        string
+	firstNames     []string
+	lastNames      []string
+	streets        []string
+	cities         []string
+	postcode       string
+	phone          string
+	numberFirst    bool
+	postcodeFirst  bool
+	streetNumberTo int
+}
+
+var fakeEmailDomains = []string{"example.com", "example.net", "example.org"}
+
+var fakeLocales = map[string]fakeLocale{
+	"en_US": {
+		country: "United States",
+		firstNames: []string{
+			"James", "Mary", "Robert", "Patricia", "John", "Jennifer", "Michael", "Linda", "David", "Elizabeth",
+			"William", "Barbara", "Richard", "Susan", "Joseph", "Jessica", "Thomas", "Sarah", "Charles", "Karen",
+		},
+		lastNames: []string{
+			"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis", "Rodriguez", "Martinez",
+			"Hernandez", "Lopez", "Gonzalez", "Wilson", "Anderson", "Thomas", "Taylor", "Moore", "Jackson", "Martin",
+		},
+		streets: []string{
+			"Main Street", "Oak Avenue", "Maple Drive", "Cedar Lane", "Pine Street", "Elm Street", "Washington Avenue",
+			"Lake Road", "Hill Street", "Park Avenue", "Sunset Boulevard", "Church Street", "Highland Drive", "Walnut Court",
+		},
+		cities: []string{
+			"Springfield", "Riverside", "Franklin", "Greenville", "Bristol", "Clinton", "Fairview", "Salem", "Madison",
+			"Georgetown", "Arlington", "Ashland", "Dover", "Oxford", "Jackson", "Burlington",
+		},
+		postcode:       "#####",
+		phone:          "+1 ###-555-####",
+		numberFirst:    true,
+		streetNumberTo: 9999,
+	},
+	"en_GB": {
+		country: "United Kingdom",
+		firstNames: []string{
+			"Oliver", "Olivia", "George", "Amelia", "Harry", "Isla", "Noah", "Ava", "Jack", "Emily",
+			"Charlie", "Sophia", "Leo", "Grace", "Jacob", "Lily", "Freddie", "Freya", "Alfie", "Poppy",
+		},
+		lastNames: []string{
+			"Smith", "Jones", "Taylor", "Brown", "Williams", "Wilson", "Johnson", "Davies", "Robinson", "Wright",
+			"Thompson", "Evans", "Walker", "White", "Roberts", "Green", "Hall", "Wood", "Jackson", "Clarke",
+		},
+		streets: []string{
+			"High Street", "Station Road", "Church Lane", "Victoria Road", "Green Lane", "Manor Road", "Park Road",
+			"Queen Street", "Mill Lane", "Kings Road", "The Crescent", "New Road", "School Lane", "North Street",
+		},
+		cities: []string{
+			"London", "Manchester", "Birmingham", "Leeds", "Bristol", "Sheffield", "Liverpool", "Nottingham", "Leicester",
+			"Cardiff", "Edinburgh", "Glasgow", "Oxford", "Cambridge", "York", "Bath",
+		},
+		postcode:       "@@# #@@",
+		phone:          "+44 7700 900###",
+		numberFirst:    true,
+		streetNumberTo: 250,
+	},
+	"de_DE": {
+		country: "Deutschland",
+		firstNames: []string{
+			"Lukas", "Anna", "Leon", "Lea", "Finn", "Hannah", "Jonas", "Mia", "Paul", "Emma",
+			"Felix", "Sophie", "Maximilian", "Marie", "Elias", "Lena", "Noah", "Johanna", "Ben", "Clara",
+		},
+		lastNames: []string{
+			"Müller", "Schmidt", "Schneider", "Fischer", "Weber", "Meyer", "Wagner", "Becker", "Schulz", "Hoffmann",
+			"Schäfer", "Koch", "Bauer", "Richter", "Klein", "Wolf", "Schröder", "Neumann", "Schwarz", "Zimmermann",
+		},
+		streets: []string{
+			"Hauptstraße", "Schulstraße", "Gartenstraße", "Bahnhofstraße", "Dorfstraße", "Bergstraße", "Birkenweg",
+			"Lindenstraße", "Kirchstraße", "Waldstraße", "Ringstraße", "Schillerstraße", "Goethestraße", "Am Markt",
+		},
+		cities: []string{
+			"Berlin", "Hamburg", "München", "Köln", "Frankfurt am Main", "Stuttgart", "Düsseldorf", "Leipzig", "Dortmund",
+			"Essen", "Bremen", "Dresden", "Hannover", "Nürnberg", "Freiburg", "Heidelberg",
+		},
+		postcode:       "#####",
+		phone:          "+49 15# ########",
+		postcodeFirst:  true,
+		streetNumberTo: 150,
+	},
+	"fr_FR": {
+		country: "France",
+		firstNames: []string{
+			"Gabriel", "Louise", "Léo", "Ambre", "Raphaël", "Jade", "Louis", "Emma", "Arthur", "Alice",
+			"Jules", "Rose", "Adam", "Chloé", "Lucas", "Léa", "Hugo", "Inès", "Nathan", "Camille",
+		},
+		lastNames: []string{
+			"Martin", "Bernard", "Thomas", "Petit", "Robert", "Richard", "Durand", "Dubois", "Moreau", "Laurent",
+			"Simon", "Michel", "Lefèvre", "Leroy", "Roux", "David", "Bertrand", "Morel", "Fournier", "Girard",
+		},
+		streets: []string{
+			"rue de la Paix", "rue Victor Hugo", "avenue de la République", "rue de l'Église", "place de la Mairie",
+			"rue du Moulin", "boulevard Pasteur", "rue Jean Jaurès", "rue des Écoles", "avenue Foch", "rue de la Gare",
+			"chemin des Vignes", "rue Nationale", "allée des Tilleuls",
+		},
+		cities: []string{
+			"Paris", "Marseille", "Lyon", "Toulouse", "Nice", "Nantes", "Strasbourg", "Montpellier", "Bordeaux", "Lille",
+			"Rennes", "Reims", "Grenoble", "Dijon", "Angers", "Tours",
+		},
+		postcode:       "#####",
+		phone:          "+33 6 ## ## ## ##",
+		numberFirst:    true,
+		postcodeFirst:  true,
+		streetNumberTo: 200,
+	},
+	"es_ES": {
+		country: "España",
+		firstNames: []string{
+			"Hugo", "Lucía", "Martín", "Sofía", "Lucas", "Martina", "Mateo", "María", "Leo", "Julia",
+			"Daniel", "Paula", "Alejandro", "Valeria", "Pablo", "Emma", "Manuel", "Daniela", "Álvaro", "Carla",
+		},
+		lastNames: []string{
+			"García", "Rodríguez", "González", "Fernández", "López", "Martínez", "Sánchez", "Pérez", "Gómez", "Martín",
+			"Jiménez", "Ruiz", "Hernández", "Díaz", "Moreno", "Muñoz", "Álvarez", "Romero", "Alonso", "Gutiérrez",
+		},
+		streets: []string{
+			"Calle Mayor", "Calle Real", "Avenida de la Constitución", "Plaza de España", "Calle del Sol", "Calle Nueva",
+			"Calle de la Iglesia", "Paseo del Prado", "Calle San José", "Avenida de Andalucía", "Calle del Carmen",
+			"Calle Luna", "Ronda de Toledo", "Camino Viejo",
+		},
+		cities: []string{
+			"Madrid", "Barcelona", "Valencia", "Sevilla", "Zaragoza", "Málaga", "Murcia", "Palma", "Bilbao", "Alicante",
+			"Córdoba", "Valladolid", "Vigo", "Gijón", "Granada", "Salamanca",
+		},
+		postcode:       "#####",
+		phone:          "+34 6## ### ###",
+		postcodeFirst:  true,
+		streetNumberTo: 150,
+	},
+}
@@ -0,0 +1,362 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	usiFieldPath        = "path"
+	usiFieldPermissions = "permissions"
+	usiFieldScanner     = "scanner"
+)
+
+func unixSocketInputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Network").
+		Version("4.31.0").
+		Summary("Creates a Unix domain socket server and consumes data from any connections made to it.").
+		Description(`
+The socket file is created when the input connects with the configured permissions, and is removed when the input is closed. When a file already exists at the path it's removed only when it's a socket that no other process is listening on, which allows the input to recover from a previous process that exited without removing its socket, otherwise the input fails to connect.
+
+The data of each connection is consumed with a xref:components:scanners/about.adoc[scanner], which determines how the data is divided into messages. The `+"`length_prefixed`"+` and `+"`netstring`"+` scanners are useful for data where messages might contain line breaks.
+
+Since data from a connection cannot be consumed again, messages that are rejected downstream are retried indefinitely until they're delivered.
+
+== Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- unix_socket_path
+- unix_socket_peer_pid
+- unix_socket_peer_uid
+- unix_socket_peer_gid
+`+"```"+`
+
+The credentials of the peer process are obtained when a connection is established and are only added on Linux.
+
+You can access these metadata fields using xref:configuration:interpolation.adoc#bloblang-queries[function interpolation].`).
+		Field(service.NewStringField(usiFieldPath).
+			Description("The path of the socket file to create.").
+			Example("/var/run/connect/ingest.sock")).
+		Field(service.NewStringField(usiFieldPermissions).
+			Description("The file permissions of the socket file in octal notation, which determine the users able to connect to it.").
+			Default("0600").
+			Example("0660")).
+		Field(service.NewScannerField(usiFieldScanner).
+			Description("The xref:components:scanners/about.adoc[scanner] by which the data of each connection is consumed.").
+			Default(map[string]any{"lines": map[string]any{}})).
+		Example("Length prefixed frames", "Consume frames prefixed with their length as a big endian 32-bit integer from local processes of the same group.", `
+input:
+  unix_socket:
+    path: /var/run/connect/ingest.sock
+    permissions: "0660"
+    scanner:
+      length_prefixed:
+        length_bytes: 4
+`)
+}
+
+func init() {
+	err := service.RegisterBatchInput("unix_socket", unixSocketInputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+			i, err := unixSocketInputFromParsed(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksBatched(i), nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+var errUnixSocketPeerCredsUnsupported = errors.New("peer credentials are not supported on this platform")
+
+type unixSocketCreds struct {
+	pid, uid, gid int
+}
+
+type unixSocketBatch struct {
+	batch service.MessageBatch
+	ackFn service.AckFunc
+}
+
+type unixSocketInput struct {
+	path        string
+	permissions os.FileMode
+	scanner     *service.OwnedScannerCreator
+	log         *service.Logger
+
+	mut      sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+
+	batches chan unixSocketBatch
+	closing chan struct{}
+	closeMu sync.Once
+}
+
+func unixSocketInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*unixSocketInput, error) {
+	u := &unixSocketInput{
+		log:     mgr.Logger(),
+		conns:   map[net.Conn]struct{}{},
+		batches: make(chan unixSocketBatch),
+		closing: make(chan struct{}),
+	}
+
+	var err error
+	if u.path, err = conf.FieldString(usiFieldPath); err != nil {
+		return nil, err
+	}
+	if u.path == "" {
+		return nil, errors.New("a path must be specified")
+	}
+
+	permStr, err := conf.FieldString(usiFieldPermissions)
+	if err != nil {
+		return nil, err
+	}
+	perm, err := strconv.ParseUint(permStr, 8, 32)
+	if err != nil || perm > 0o777 {
+		return nil, fmt.Errorf("invalid permissions %q, expected an octal file mode such as 0660", permStr)
+	}
+	u.permissions = os.FileMode(perm)
+
+	if u.scanner, err = conf.FieldScanner(usiFieldScanner); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// removeStaleSocket removes a socket file left behind by a process that is no
+// longer listening on it.
+func (u *unixSocketInput) removeStaleSocket() error {
+	info, err := os.Lstat(u.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("file %v already exists and is not a socket", u.path)
+	}
+
+	conn, err := net.DialTimeout("unix", u.path, time.Second)
+	if err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("socket %v is already in use", u.path)
+	}
+
+	u.log.Infof("Removing stale socket file %v", u.path)
+	return os.Remove(u.path)
+}
+
+func (u *unixSocketInput) Connect(ctx context.Context) error {
+	u.mut.Lock()
+	defer u.mut.Unlock()
+
+	if u.listener != nil {
+		return nil
+	}
+	select {
+	case <-u.closing:
+		return service.ErrEndOfInput
+	default:
+	}
+
+	if err := u.removeStaleSocket(); err != nil {
+		return err
+	}
+
+	l, err := net.Listen("unix", u.path)
+	if err != nil {
+		return err
+	}
+	// The listener removes the socket file itself when closed.
+	l.(*net.UnixListener).SetUnlinkOnClose(true)
+
+	if err := os.Chmod(u.path, u.permissions); err != nil {
+		_ = l.Close()
+		return fmt.Errorf("failed to set permissions of socket file: %w", err)
+	}
+
+	u.listener = l
+	u.wg.Add(1)
+	go u.acceptLoop(l)
+
+	u.log.Infof("Receiving unix socket messages from path: %v", u.path)
+	return nil
+}
+
+func (u *unixSocketInput) acceptLoop(l net.Listener) {
+	defer u.wg.Done()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			select {
+			case <-u.closing:
+				return
+			default:
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			u.log.Errorf("Failed to accept unix socket connection: %v", err)
+			select {
+			case <-time.After(time.Second):
+			case <-u.closing:
+				return
+			}
+			continue
+		}
+
+		u.mut.Lock()
+		u.conns[conn] = struct{}{}
+		u.mut.Unlock()
+
+		u.wg.Add(1)
+		go u.consumeConn(conn)
+	}
+}
+
+func (u *unixSocketInput) consumeConn(conn net.Conn) {
+	defer u.wg.Done()
+	defer func() {
+		u.mut.Lock()
+		delete(u.conns, conn)
+		u.mut.Unlock()
+		_ = conn.Close()
+	}()
+
+	meta := map[string]string{
+		"unix_socket_path": u.path,
+	}
+	if uc, ok := conn.(*net.UnixConn); ok {
+		if creds, err := unixSocketPeerCreds(uc); err == nil {
+			meta["unix_socket_peer_pid"] = strconv.Itoa(creds.pid)
+			meta["unix_socket_peer_uid"] = strconv.Itoa(creds.uid)
+			meta["unix_socket_peer_gid"] = strconv.Itoa(creds.gid)
+		} else if !errors.Is(err, errUnixSocketPeerCredsUnsupported) {
+			u.log.Warnf("Failed to obtain unix socket peer credentials: %v", err)
+		}
+	}
+
+	details := service.NewScannerSourceDetails()
+	details.SetName(u.path)
+
+	scanner, err := u.scanner.Create(conn, func(context.Context, error) error {
+		return nil
+	}, details)
+	if err != nil {
+		u.log.Errorf("Failed to create scanner for unix socket connection: %v", err)
+		return
+	}
+	defer scanner.Close(context.Background())
+
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	go func() {
+		select {
+		case <-u.closing:
+			done()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		batch, ackFn, err := scanner.NextBatch(ctx)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) && ctx.Err() == nil {
+				u.log.Errorf("Failed to read unix socket connection: %v", err)
+			}
+			return
+		}
+
+		for _, msg := range batch {
+			for k, v := range meta {
+				msg.MetaSetMut(k, v)
+			}
+		}
+
+		select {
+		case u.batches <- unixSocketBatch{batch: batch, ackFn: ackFn}:
+		case <-u.closing:
+			return
+		}
+	}
+}
+
+func (u *unixSocketInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	u.mut.Lock()
+	connected := u.listener != nil
+	u.mut.Unlock()
+	if !connected {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	select {
+	case b := <-u.batches:
+		return b.batch, b.ackFn, nil
+	case <-u.closing:
+		return nil, nil, service.ErrEndOfInput
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+func (u *unixSocketInput) Close(ctx context.Context) error {
+	u.closeMu.Do(func() {
+		close(u.closing)
+	})
+
+	u.mut.Lock()
+	var err error
+	if u.listener != nil {
+		err = u.listener.Close()
+	}
+	for conn := range u.conns {
+		_ = conn.Close()
+	}
+	u.mut.Unlock()
+
+	waitDone := make(chan struct{})
+	go func() {
+		u.wg.Wait()
+		close(waitDone)
+	}()
+	select {
+	case <-waitDone:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return err
+}
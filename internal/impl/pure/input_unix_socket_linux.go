@@ -0,0 +1,43 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"net"
+	"syscall"
+)
+
+func unixSocketPeerCreds(conn *net.UnixConn) (unixSocketCreds, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return unixSocketCreds{}, err
+	}
+
+	var ucred *syscall.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		ucred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return unixSocketCreds{}, err
+	}
+	if credErr != nil {
+		return unixSocketCreds{}, credErr
+	}
+	return unixSocketCreds{
+		pid: int(ucred.Pid),
+		uid: int(ucred.Uid),
+		gid: int(ucred.Gid),
+	}, nil
+}
@@ -0,0 +1,152 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestUnixSocketInputNetstring(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.sock")
+
+	conf, err := unixSocketInputConfig().ParseYAML(`
+path: `+path+`
+permissions: "0640"
+scanner:
+  netstring: {}
+`, nil)
+	require.NoError(t, err)
+
+	i, err := unixSocketInputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	require.NoError(t, i.Connect(ctx))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+
+	_, err = conn.Write([]byte("3:foo,7:bar\nbaz,"))
+	require.NoError(t, err)
+
+	var res []string
+	for len(res) < 2 {
+		batch, ackFn, err := i.ReadBatch(ctx)
+		require.NoError(t, err)
+		require.NoError(t, ackFn(ctx, nil))
+		res = append(res, batchContents(t, batch)...)
+
+		for _, msg := range batch {
+			v, _ := msg.MetaGet("unix_socket_path")
+			assert.Equal(t, path, v)
+			if runtime.GOOS == "linux" {
+				v, _ = msg.MetaGet("unix_socket_peer_uid")
+				assert.Equal(t, strconv.Itoa(os.Getuid()), v)
+				v, _ = msg.MetaGet("unix_socket_peer_pid")
+				assert.Equal(t, strconv.Itoa(os.Getpid()), v)
+			}
+		}
+	}
+	assert.Equal(t, []string{"foo", "bar\nbaz"}, res)
+
+	require.NoError(t, conn.Close())
+	require.NoError(t, i.Close(ctx))
+
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "socket file should be removed")
+
+	_, _, err = i.ReadBatch(ctx)
+	assert.Error(t, err)
+}
+
+func TestUnixSocketInputStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.sock")
+
+	// Leave a socket file behind without anything listening on it.
+	l, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, l.Close())
+
+	_, err = os.Stat(path)
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	conf, err := unixSocketInputConfig().ParseYAML(`path: `+path, nil)
+	require.NoError(t, err)
+
+	i, err := unixSocketInputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	require.NoError(t, i.Connect(ctx))
+
+	// A socket that is in use is not removed.
+	pConf, err := unixSocketInputConfig().ParseYAML(`path: `+path, nil)
+	require.NoError(t, err)
+
+	other, err := unixSocketInputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	err = other.Connect(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already in use")
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("hello world\n"))
+	require.NoError(t, err)
+
+	batch, _, err := i.ReadBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hello world"}, batchContents(t, batch))
+
+	require.NoError(t, conn.Close())
+	require.NoError(t, i.Close(ctx))
+}
+
+func TestUnixSocketInputNotASocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.sock")
+	require.NoError(t, os.WriteFile(path, []byte("foo"), 0o600))
+
+	conf, err := unixSocketInputConfig().ParseYAML(`path: `+path, nil)
+	require.NoError(t, err)
+
+	i, err := unixSocketInputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	err = i.Connect(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not a socket")
+}
@@ -0,0 +1,609 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Jeffail/checkpoint"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	welFieldChannels        = "channels"
+	welFieldQuery           = "query"
+	welFieldStartFrom       = "start_from"
+	welFieldBookmarkCache   = "bookmark_cache"
+	welFieldBatchSize       = "batch_size"
+	welFieldCheckpointLimit = "checkpoint_limit"
+)
+
+func windowsEventLogInputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Local").
+		Version("4.31.0").
+		Summary("Subscribes to channels of the Windows Event Log and consumes their events as structured JSON documents.").
+		Description(`
+Each channel is subscribed to with the Windows Event Log API, and the events received are rendered as XML and parsed into a JSON document of the following form:
+
+`+"```json"+`
+{
+  "provider": "Service Control Manager",
+  "provider_guid": "{555908d1-a6d7-4695-8e1e-26931d2012f4}",
+  "event_id": 7036,
+  "version": 0,
+  "level": 4,
+  "level_name": "Information",
+  "task": 0,
+  "opcode": 0,
+  "keywords": "0x8080000000000000",
+  "time_created": "2024-05-01T10:00:00.1234567Z",
+  "record_id": 12345,
+  "channel": "System",
+  "computer": "host.example.com",
+  "process_id": 624,
+  "thread_id": 7180,
+  "event_data": {
+    "param1": "Windows Update",
+    "param2": "running"
+  }
+}
+`+"```"+`
+
+The `+"`event_data`"+` field is an object when every data item of the event is named, and an array of values otherwise. Events that carry `+"`UserData`"+` instead have its elements converted into a `+"`user_data`"+` object. Events that cannot be parsed are emitted as their raw XML and flagged with an error, which can be handled with xref:configuration:error_handling.adoc[error handling].
+
+This input is only supported on Windows, and fails to connect on other platforms.
+
+== Bookmarks
+
+The position of each channel is tracked with an event log bookmark that only advances once the events before it have been acknowledged. When a `+"`"+welFieldBookmarkCache+"`"+` is configured the bookmarks are stored within it, keyed by channel name, which allows the input to continue from where it left off upon restart. Channels without a stored bookmark are consumed from the position set by `+"`"+welFieldStartFrom+"`"+`.
+
+== Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- windows_event_log_channel
+- windows_event_log_provider
+- windows_event_log_event_id
+- windows_event_log_level
+- windows_event_log_record_id
+- windows_event_log_computer
+`+"```"+`
+
+The level is added as its name, e.g. `+"`Error`"+`.
+
+You can access these metadata fields using xref:configuration:interpolation.adoc#bloblang-queries[function interpolation].`).
+		Field(service.NewStringListField(welFieldChannels).
+			Description("The channels to subscribe to, which can be any of the standard channels or the path of a custom channel.").
+			Example([]string{"System", "Application"}).
+			Example([]string{"Security", "Microsoft-Windows-Sysmon/Operational"})).
+		Field(service.NewStringField(welFieldQuery).
+			Description("An XPath query that selects the events of each channel to consume.").
+			Default("*").
+			Example("*[System[(Level=1 or Level=2 or Level=3)]]").
+			Example("*[System[(EventID=4624)]]")).
+		Field(service.NewStringEnumField(welFieldStartFrom, "newest", "oldest").
+			Description("Where to start consuming a channel that has no stored bookmark, either `newest` in order to only consume events written after the input connects, or `oldest` in order to consume every event held by the channel.").
+			Default("newest")).
+		Field(service.NewStringField(welFieldBookmarkCache).
+			Description("A xref:components:caches/about.adoc[cache resource] to use for storing the bookmark of each channel, this allows the input to continue from where it left off upon restart.").
+			Optional()).
+		Field(service.NewIntField(welFieldBatchSize).
+			Description("The maximum number of events of a channel to consume as a batch.").
+			Default(100).
+			Advanced()).
+		Field(service.NewIntField(welFieldCheckpointLimit).
+			Description("The maximum number of events of a channel that can be pending acknowledgement at a given time, after which consumption of the channel pauses until events are acknowledged.").
+			Default(1024).
+			Advanced()).
+		Field(service.NewAutoRetryNacksToggleField()).
+		Example("System errors", "Consume errors and critical events of the system and application channels, continuing from the last acknowledged events upon restart.", `
+input:
+  windows_event_log:
+    channels: [ System, Application ]
+    query: "*[System[(Level=1 or Level=2)]]"
+    bookmark_cache: bookmarks
+
+cache_resources:
+  - label: bookmarks
+    file:
+      directory: ./bookmarks
+`)
+}
+
+func init() {
+	err := service.RegisterBatchInput("windows_event_log", windowsEventLogInputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+			i, err := windowsEventLogInputFromParsed(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksBatchedToggled(conf, i)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+var errWindowsEventLogUnsupported = errors.New("the windows event log is only supported on windows")
+
+// eventLogRecord is an event received from a subscription along with the
+// bookmark of the subscription after the event.
+type eventLogRecord struct {
+	xml      []byte
+	bookmark []byte
+}
+
+// eventLogSubscription is a subscription to a channel of the event log.
+type eventLogSubscription interface {
+	// next blocks until at least one event is available and returns up to max
+	// events.
+	next(ctx context.Context, max int) ([]eventLogRecord, error)
+	close() error
+}
+
+type eventLogSubscribeFn func(channel, query string, bookmark []byte, fromOldest bool) (eventLogSubscription, error)
+
+type windowsEventLogBatch struct {
+	batch service.MessageBatch
+	ackFn service.AckFunc
+}
+
+type windowsEventLogInput struct {
+	channels        []string
+	query           string
+	fromOldest      bool
+	bookmarkCache   string
+	batchSize       int
+	checkpointLimit int
+	subscribe       eventLogSubscribeFn
+
+	mgr *service.Resources
+	log *service.Logger
+
+	mut       sync.Mutex
+	subs      []eventLogSubscription
+	bookmarks map[string][]byte
+	stopSubs  context.CancelFunc
+
+	batches chan windowsEventLogBatch
+	errs    chan error
+	closing chan struct{}
+	closeMu sync.Once
+}
+
+func windowsEventLogInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*windowsEventLogInput, error) {
+	w := &windowsEventLogInput{
+		subscribe: newEventLogSubscription,
+		mgr:       mgr,
+		log:       mgr.Logger(),
+		bookmarks: map[string][]byte{},
+		batches:   make(chan windowsEventLogBatch),
+		closing:   make(chan struct{}),
+	}
+
+	var err error
+	if w.channels, err = conf.FieldStringList(welFieldChannels); err != nil {
+		return nil, err
+	}
+	if len(w.channels) == 0 {
+		return nil, errors.New("at least one channel must be specified")
+	}
+	if w.query, err = conf.FieldString(welFieldQuery); err != nil {
+		return nil, err
+	}
+
+	var startFrom string
+	if startFrom, err = conf.FieldString(welFieldStartFrom); err != nil {
+		return nil, err
+	}
+	w.fromOldest = startFrom == "oldest"
+
+	if conf.Contains(welFieldBookmarkCache) {
+		if w.bookmarkCache, err = conf.FieldString(welFieldBookmarkCache); err != nil {
+			return nil, err
+		}
+		if !mgr.HasCache(w.bookmarkCache) {
+			return nil, fmt.Errorf("cache resource '%v' was not found", w.bookmarkCache)
+		}
+	}
+
+	if w.batchSize, err = conf.FieldInt(welFieldBatchSize); err != nil {
+		return nil, err
+	}
+	if w.batchSize < 1 {
+		return nil, fmt.Errorf("batch size must be greater than zero, got %v", w.batchSize)
+	}
+	if w.checkpointLimit, err = conf.FieldInt(welFieldCheckpointLimit); err != nil {
+		return nil, err
+	}
+	if w.checkpointLimit < w.batchSize {
+		return nil, fmt.Errorf("checkpoint limit must be at least the batch size of %v, got %v", w.batchSize, w.checkpointLimit)
+	}
+	return w, nil
+}
+
+func (w *windowsEventLogInput) loadBookmark(ctx context.Context, channel string) []byte {
+	w.mut.Lock()
+	bookmark := w.bookmarks[channel]
+	w.mut.Unlock()
+	if bookmark != nil || w.bookmarkCache == "" {
+		return bookmark
+	}
+
+	if err := w.mgr.AccessCache(ctx, w.bookmarkCache, func(c service.Cache) {
+		var cErr error
+		if bookmark, cErr = c.Get(ctx, channel); cErr != nil && !errors.Is(cErr, service.ErrKeyNotFound) {
+			w.log.Errorf("Failed to obtain bookmark of channel %v: %v", channel, cErr)
+		}
+	}); err != nil {
+		w.log.Errorf("Failed to access bookmark cache: %v", err)
+	}
+	return bookmark
+}
+
+func (w *windowsEventLogInput) storeBookmark(ctx context.Context, channel string, bookmark []byte) error {
+	w.mut.Lock()
+	w.bookmarks[channel] = bookmark
+	w.mut.Unlock()
+	if w.bookmarkCache == "" {
+		return nil
+	}
+
+	var setErr error
+	if err := w.mgr.AccessCache(ctx, w.bookmarkCache, func(c service.Cache) {
+		setErr = c.Set(ctx, channel, bookmark, nil)
+	}); err != nil {
+		return err
+	}
+	return setErr
+}
+
+func (w *windowsEventLogInput) Connect(ctx context.Context) error {
+	w.mut.Lock()
+	connected := w.subs != nil
+	w.mut.Unlock()
+	if connected {
+		return nil
+	}
+
+	subs := make([]eventLogSubscription, 0, len(w.channels))
+	for _, channel := range w.channels {
+		sub, err := w.subscribe(channel, w.query, w.loadBookmark(ctx, channel), w.fromOldest)
+		if err != nil {
+			for _, s := range subs {
+				_ = s.close()
+			}
+			return fmt.Errorf("failed to subscribe to channel %v: %w", channel, err)
+		}
+		subs = append(subs, sub)
+	}
+
+	subCtx, stopSubs := context.WithCancel(context.Background())
+	errs := make(chan error, len(subs))
+
+	w.mut.Lock()
+	w.subs, w.stopSubs, w.errs = subs, stopSubs, errs
+	w.mut.Unlock()
+
+	for i, sub := range subs {
+		go w.loop(subCtx, w.channels[i], sub, errs)
+	}
+	return nil
+}
+
+func (w *windowsEventLogInput) loop(ctx context.Context, channel string, sub eventLogSubscription, errs chan<- error) {
+	checkpointer := checkpoint.NewCapped[[]byte](int64(w.checkpointLimit))
+	for {
+		records, err := sub.next(ctx, w.batchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				errs <- fmt.Errorf("channel %v: %w", channel, err)
+			}
+			return
+		}
+		if len(records) == 0 {
+			continue
+		}
+
+		batch := make(service.MessageBatch, 0, len(records))
+		for _, r := range records {
+			batch = append(batch, windowsEventLogMessage(channel, r.xml))
+		}
+
+		releaseFn, err := checkpointer.Track(ctx, records[len(records)-1].bookmark, int64(len(records)))
+		if err != nil {
+			return
+		}
+
+		ackFn := func(ctx context.Context, err error) error {
+			bookmark := releaseFn()
+			if bookmark == nil {
+				return nil
+			}
+			return w.storeBookmark(ctx, channel, *bookmark)
+		}
+
+		select {
+		case w.batches <- windowsEventLogBatch{batch: batch, ackFn: ackFn}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (w *windowsEventLogInput) disconnect() {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	if w.stopSubs != nil {
+		w.stopSubs()
+	}
+	for _, sub := range w.subs {
+		if err := sub.close(); err != nil {
+			w.log.Errorf("Failed to close subscription: %v", err)
+		}
+	}
+	w.subs, w.stopSubs, w.errs = nil, nil, nil
+}
+
+func (w *windowsEventLogInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	w.mut.Lock()
+	errs := w.errs
+	w.mut.Unlock()
+	if errs == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	select {
+	case b := <-w.batches:
+		return b.batch, b.ackFn, nil
+	case err := <-errs:
+		w.log.Errorf("Subscription failed: %v", err)
+		w.disconnect()
+		return nil, nil, service.ErrNotConnected
+	case <-w.closing:
+		return nil, nil, service.ErrEndOfInput
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+func (w *windowsEventLogInput) Close(ctx context.Context) error {
+	w.closeMu.Do(func() {
+		close(w.closing)
+	})
+	w.disconnect()
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type eventLogXML struct {
+	System struct {
+		Provider struct {
+			Name string `xml:"Name,attr"`
+			GUID string `xml:"Guid,attr"`
+		} `xml:"Provider"`
+		EventID struct {
+			Value      string `xml:",chardata"`
+			Qualifiers string `xml:"Qualifiers,attr"`
+		} `xml:"EventID"`
+		Version     int    `xml:"Version"`
+		Level       int    `xml:"Level"`
+		Task        int    `xml:"Task"`
+		Opcode      int    `xml:"Opcode"`
+		Keywords    string `xml:"Keywords"`
+		TimeCreated struct {
+			SystemTime string `xml:"SystemTime,attr"`
+		} `xml:"TimeCreated"`
+		EventRecordID uint64 `xml:"EventRecordID"`
+		Correlation   struct {
+			ActivityID string `xml:"ActivityID,attr"`
+		} `xml:"Correlation"`
+		Execution struct {
+			ProcessID uint32 `xml:"ProcessID,attr"`
+			ThreadID  uint32 `xml:"ThreadID,attr"`
+		} `xml:"Execution"`
+		Channel  string `xml:"Channel"`
+		Computer string `xml:"Computer"`
+		Security struct {
+			UserID string `xml:"UserID,attr"`
+		} `xml:"Security"`
+	} `xml:"System"`
+	EventData *struct {
+		Data []struct {
+			Name  string `xml:"Name,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"Data"`
+	} `xml:"EventData"`
+	UserData *struct {
+		Inner []byte `xml:",innerxml"`
+	} `xml:"UserData"`
+}
+
+func eventLogLevelName(level int) string {
+	switch level {
+	case 1:
+		return "Critical"
+	case 2:
+		return "Error"
+	case 3:
+		return "Warning"
+	case 5:
+		return "Verbose"
+	}
+	return "Information"
+}
+
+// parseEventLogXML parses an event rendered as XML into a structured
+// document.
+func parseEventLogXML(raw []byte) (map[string]any, error) {
+	var e eventLogXML
+	if err := xml.Unmarshal(raw, &e); err != nil {
+		return nil, err
+	}
+
+	eventID, err := strconv.ParseUint(strings.TrimSpace(e.System.EventID.Value), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid event id: %w", err)
+	}
+
+	doc := map[string]any{
+		"provider":   e.System.Provider.Name,
+		"event_id":   eventID,
+		"version":    e.System.Version,
+		"level":      e.System.Level,
+		"level_name": eventLogLevelName(e.System.Level),
+		"task":       e.System.Task,
+		"opcode":     e.System.Opcode,
+		"keywords":   e.System.Keywords,
+		"record_id":  e.System.EventRecordID,
+		"channel":    e.System.Channel,
+		"computer":   e.System.Computer,
+		"process_id": e.System.Execution.ProcessID,
+		"thread_id":  e.System.Execution.ThreadID,
+	}
+	if e.System.Provider.GUID != "" {
+		doc["provider_guid"] = e.System.Provider.GUID
+	}
+	if e.System.EventID.Qualifiers != "" {
+		doc["qualifiers"] = e.System.EventID.Qualifiers
+	}
+	if t := e.System.TimeCreated.SystemTime; t != "" {
+		doc["time_created"] = t
+	}
+	if e.System.Correlation.ActivityID != "" {
+		doc["activity_id"] = e.System.Correlation.ActivityID
+	}
+	if e.System.Security.UserID != "" {
+		doc["user_id"] = e.System.Security.UserID
+	}
+
+	if e.EventData != nil && len(e.EventData.Data) > 0 {
+		named := true
+		for _, d := range e.EventData.Data {
+			if d.Name == "" {
+				named = false
+				break
+			}
+		}
+		if named {
+			data := make(map[string]any, len(e.EventData.Data))
+			for _, d := range e.EventData.Data {
+				data[d.Name] = d.Value
+			}
+			doc["event_data"] = data
+		} else {
+			data := make([]any, 0, len(e.EventData.Data))
+			for _, d := range e.EventData.Data {
+				data = append(data, d.Value)
+			}
+			doc["event_data"] = data
+		}
+	}
+
+	if e.UserData != nil {
+		userData, err := eventLogXMLElements(xml.NewDecoder(bytes.NewReader(e.UserData.Inner)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid user data: %w", err)
+		}
+		if m, ok := userData.(map[string]any); ok {
+			doc["user_data"] = m
+		}
+	}
+	return doc, nil
+}
+
+// eventLogXMLElements converts the elements read from the decoder until the end
+// of the enclosing element into an object keyed by element name, elements that
+// occur multiple times are collected into an array. When no elements are read
+// the character data is returned instead.
+func eventLogXMLElements(dec *xml.Decoder) (any, error) {
+	var (
+		elements map[string]any
+		text     strings.Builder
+	)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			v, err := eventLogXMLElements(dec)
+			if err != nil {
+				return nil, err
+			}
+			if elements == nil {
+				elements = map[string]any{}
+			}
+			name := t.Name.Local
+			switch existing := elements[name].(type) {
+			case nil:
+				elements[name] = v
+			case []any:
+				elements[name] = append(existing, v)
+			default:
+				elements[name] = []any{existing, v}
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if elements != nil {
+				return elements, nil
+			}
+			return strings.TrimSpace(text.String()), nil
+		}
+	}
+	if elements != nil {
+		return elements, nil
+	}
+	return strings.TrimSpace(text.String()), nil
+}
+
+func windowsEventLogMessage(channel string, raw []byte) *service.Message {
+	doc, err := parseEventLogXML(raw)
+	if err != nil {
+		msg := service.NewMessage(raw)
+		msg.MetaSetMut("windows_event_log_channel", channel)
+		msg.SetError(fmt.Errorf("failed to parse event: %w", err))
+		return msg
+	}
+
+	msg := service.NewMessage(nil)
+	msg.SetStructuredMut(doc)
+	msg.MetaSetMut("windows_event_log_channel", channel)
+	msg.MetaSetMut("windows_event_log_provider", doc["provider"])
+	msg.MetaSetMut("windows_event_log_event_id", strconv.FormatUint(doc["event_id"].(uint64), 10))
+	msg.MetaSetMut("windows_event_log_level", doc["level_name"])
+	msg.MetaSetMut("windows_event_log_record_id", strconv.FormatUint(doc["record_id"].(uint64), 10))
+	msg.MetaSetMut("windows_event_log_computer", doc["computer"])
+	return msg
+}
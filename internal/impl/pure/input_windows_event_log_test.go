@@ -0,0 +1,355 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const testEventLogXML = `<Event xmlns='http://schemas.microsoft.com/win/2004/08/events/event'>
+  <System>
+    <Provider Name='Service Control Manager' Guid='{555908d1-a6d7-4695-8e1e-26931d2012f4}' EventSourceName='Service Control Manager'/>
+    <EventID Qualifiers='16384'>7036</EventID>
+    <Version>0</Version>
+    <Level>4</Level>
+    <Task>0</Task>
+    <Opcode>0</Opcode>
+    <Keywords>0x8080000000000000</Keywords>
+    <TimeCreated SystemTime='2024-05-01T10:00:00.1234567Z'/>
+    <EventRecordID>%d</EventRecordID>
+    <Correlation/>
+    <Execution ProcessID='624' ThreadID='7180'/>
+    <Channel>System</Channel>
+    <Computer>host.example.com</Computer>
+    <Security/>
+  </System>
+  <EventData>
+    <Data Name='param1'>Windows Update</Data>
+    <Data Name='param2'>running</Data>
+  </EventData>
+</Event>`
+
+type fakeEventLogSubscription struct {
+	channel  string
+	bookmark []byte
+	records  chan eventLogRecord
+	err      chan error
+}
+
+func (f *fakeEventLogSubscription) next(ctx context.Context, max int) ([]eventLogRecord, error) {
+	select {
+	case r := <-f.records:
+		return []eventLogRecord{r}, nil
+	case err := <-f.err:
+		return nil, err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (f *fakeEventLogSubscription) close() error {
+	return nil
+}
+
+type fakeEventLog struct {
+	mut  sync.Mutex
+	subs []*fakeEventLogSubscription
+}
+
+func (f *fakeEventLog) subscribe(channel, query string, bookmark []byte, fromOldest bool) (eventLogSubscription, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	sub := &fakeEventLogSubscription{
+		channel:  channel,
+		bookmark: bookmark,
+		records:  make(chan eventLogRecord, 10),
+		err:      make(chan error, 1),
+	}
+	f.subs = append(f.subs, sub)
+	return sub, nil
+}
+
+func (f *fakeEventLog) sub(t testing.TB, i int) *fakeEventLogSubscription {
+	t.Helper()
+
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	require.Greater(t, len(f.subs), i)
+	return f.subs[i]
+}
+
+func TestWindowsEventLogParseXML(t *testing.T) {
+	doc, err := parseEventLogXML([]byte(fmt.Sprintf(testEventLogXML, 12345)))
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]any{
+		"provider":      "Service Control Manager",
+		"provider_guid": "{555908d1-a6d7-4695-8e1e-26931d2012f4}",
+		"event_id":      uint64(7036),
+		"qualifiers":    "16384",
+		"version":       0,
+		"level":         4,
+		"level_name":    "Information",
+		"task":          0,
+		"opcode":        0,
+		"keywords":      "0x8080000000000000",
+		"time_created":  "2024-05-01T10:00:00.1234567Z",
+		"record_id":     uint64(12345),
+		"channel":       "System",
+		"computer":      "host.example.com",
+		"process_id":    uint32(624),
+		"thread_id":     uint32(7180),
+		"event_data": map[string]any{
+			"param1": "Windows Update",
+			"param2": "running",
+		},
+	}, doc)
+}
+
+func TestWindowsEventLogParseXMLUnnamedData(t *testing.T) {
+	doc, err := parseEventLogXML([]byte(`<Event xmlns='http://schemas.microsoft.com/win/2004/08/events/event'>
+  <System>
+    <Provider Name='Application Error'/>
+    <EventID>1000</EventID>
+    <Level>2</Level>
+    <EventRecordID>7</EventRecordID>
+    <Channel>Application</Channel>
+    <Security UserID='S-1-5-18'/>
+  </System>
+  <EventData>
+    <Data>app.exe</Data>
+    <Data>1.0.0.0</Data>
+  </EventData>
+</Event>`))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Error", doc["level_name"])
+	assert.Equal(t, "S-1-5-18", doc["user_id"])
+	assert.Equal(t, []any{"app.exe", "1.0.0.0"}, doc["event_data"])
+}
+
+func TestWindowsEventLogParseXMLUserData(t *testing.T) {
+	doc, err := parseEventLogXML([]byte(`<Event xmlns='http://schemas.microsoft.com/win/2004/08/events/event'>
+  <System>
+    <Provider Name='Microsoft-Windows-Eventlog'/>
+    <EventID>1102</EventID>
+    <Level>4</Level>
+    <EventRecordID>8</EventRecordID>
+    <Channel>Security</Channel>
+  </System>
+  <UserData>
+    <LogFileCleared xmlns='http://manifests.microsoft.com/win/2004/08/windows/eventlog'>
+      <SubjectUserSid>S-1-5-21-1</SubjectUserSid>
+      <SubjectUserName>admin</SubjectUserName>
+      <Privilege>SeSecurityPrivilege</Privilege>
+      <Privilege>SeBackupPrivilege</Privilege>
+    </LogFileCleared>
+  </UserData>
+</Event>`))
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]any{
+		"LogFileCleared": map[string]any{
+			"SubjectUserSid":  "S-1-5-21-1",
+			"SubjectUserName": "admin",
+			"Privilege":       []any{"SeSecurityPrivilege", "SeBackupPrivilege"},
+		},
+	}, doc["user_data"])
+	assert.NotContains(t, doc, "event_data")
+}
+
+func TestWindowsEventLogParseXMLInvalid(t *testing.T) {
+	_, err := parseEventLogXML([]byte(`<Event><System><EventID>nope</EventID></System></Event>`))
+	require.Error(t, err)
+
+	_, err = parseEventLogXML([]byte(`not xml`))
+	require.Error(t, err)
+}
+
+func TestWindowsEventLogInputBookmarks(t *testing.T) {
+	res := service.MockResources(service.MockResourcesOptAddCache("foocache"))
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	require.NoError(t, res.AccessCache(ctx, "foocache", func(c service.Cache) {
+		require.NoError(t, c.Set(ctx, "Application", []byte("app-bookmark"), nil))
+	}))
+
+	conf, err := windowsEventLogInputConfig().ParseYAML(`
+channels: [ System, Application ]
+bookmark_cache: foocache
+`, nil)
+	require.NoError(t, err)
+
+	i, err := windowsEventLogInputFromParsed(conf, res)
+	require.NoError(t, err)
+
+	fake := &fakeEventLog{}
+	i.subscribe = fake.subscribe
+
+	require.NoError(t, i.Connect(ctx))
+
+	sys, app := fake.sub(t, 0), fake.sub(t, 1)
+	assert.Equal(t, "System", sys.channel)
+	assert.Nil(t, sys.bookmark)
+	assert.Equal(t, "Application", app.channel)
+	assert.Equal(t, []byte("app-bookmark"), app.bookmark)
+
+	sys.records <- eventLogRecord{xml: []byte(fmt.Sprintf(testEventLogXML, 1)), bookmark: []byte("sys-1")}
+	sys.records <- eventLogRecord{xml: []byte(fmt.Sprintf(testEventLogXML, 2)), bookmark: []byte("sys-2")}
+
+	batch1, ackFn1, err := i.ReadBatch(ctx)
+	require.NoError(t, err)
+	require.Len(t, batch1, 1)
+
+	batch2, ackFn2, err := i.ReadBatch(ctx)
+	require.NoError(t, err)
+	require.Len(t, batch2, 1)
+
+	for k, v := range map[string]string{
+		"windows_event_log_channel":   "System",
+		"windows_event_log_provider":  "Service Control Manager",
+		"windows_event_log_event_id":  "7036",
+		"windows_event_log_level":     "Information",
+		"windows_event_log_record_id": "2",
+		"windows_event_log_computer":  "host.example.com",
+	} {
+		mv, ok := batch2[0].MetaGet(k)
+		assert.True(t, ok, k)
+		assert.Equal(t, v, mv, k)
+	}
+
+	cachedBookmark := func() string {
+		var v []byte
+		require.NoError(t, res.AccessCache(ctx, "foocache", func(c service.Cache) {
+			var err error
+			if v, err = c.Get(ctx, "System"); errors.Is(err, service.ErrKeyNotFound) {
+				err = nil
+			}
+			require.NoError(t, err)
+		}))
+		return string(v)
+	}
+
+	// The bookmark only advances once every event before it is acknowledged.
+	require.NoError(t, ackFn2(ctx, nil))
+	assert.Equal(t, "", cachedBookmark())
+
+	require.NoError(t, ackFn1(ctx, nil))
+	assert.Equal(t, "sys-2", cachedBookmark())
+
+	require.NoError(t, i.Close(ctx))
+}
+
+func TestWindowsEventLogInputReconnect(t *testing.T) {
+	conf, err := windowsEventLogInputConfig().ParseYAML(`
+channels: [ System ]
+`, nil)
+	require.NoError(t, err)
+
+	i, err := windowsEventLogInputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	fake := &fakeEventLog{}
+	i.subscribe = fake.subscribe
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	require.NoError(t, i.Connect(ctx))
+
+	sub := fake.sub(t, 0)
+	sub.records <- eventLogRecord{xml: []byte(fmt.Sprintf(testEventLogXML, 1)), bookmark: []byte("sys-1")}
+
+	batch, ackFn, err := i.ReadBatch(ctx)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	require.NoError(t, ackFn(ctx, nil))
+
+	sub.err <- errors.New("channel was cleared")
+
+	_, _, err = i.ReadBatch(ctx)
+	require.ErrorIs(t, err, service.ErrNotConnected)
+
+	// Without a cache the input resumes from the last acknowledged bookmark.
+	require.NoError(t, i.Connect(ctx))
+	assert.Equal(t, []byte("sys-1"), fake.sub(t, 1).bookmark)
+
+	require.NoError(t, i.Close(ctx))
+
+	_, _, err = i.ReadBatch(ctx)
+	require.Error(t, err)
+}
+
+func TestWindowsEventLogInputParseError(t *testing.T) {
+	conf, err := windowsEventLogInputConfig().ParseYAML(`
+channels: [ Application ]
+`, nil)
+	require.NoError(t, err)
+
+	i, err := windowsEventLogInputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	fake := &fakeEventLog{}
+	i.subscribe = fake.subscribe
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	require.NoError(t, i.Connect(ctx))
+	fake.sub(t, 0).records <- eventLogRecord{xml: []byte(`not xml`), bookmark: []byte("app-1")}
+
+	batch, _, err := i.ReadBatch(ctx)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	b, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "not xml", string(b))
+	assert.Error(t, batch[0].GetError())
+
+	require.NoError(t, i.Close(ctx))
+}
+
+func TestWindowsEventLogInputConfigErrors(t *testing.T) {
+	for _, conf := range []string{
+		`channels: []`,
+		`channels: [ System ]
+bookmark_cache: nope`,
+		`channels: [ System ]
+batch_size: 0`,
+		`channels: [ System ]
+batch_size: 100
+checkpoint_limit: 10`,
+	} {
+		pConf, err := windowsEventLogInputConfig().ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		_, err = windowsEventLogInputFromParsed(pConf, service.MockResources())
+		assert.Error(t, err, conf)
+	}
+}
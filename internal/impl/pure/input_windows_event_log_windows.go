@@ -0,0 +1,189 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build windows
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modWevtapi            = windows.NewLazySystemDLL("wevtapi.dll")
+	procEvtSubscribe      = modWevtapi.NewProc("EvtSubscribe")
+	procEvtNext           = modWevtapi.NewProc("EvtNext")
+	procEvtRender         = modWevtapi.NewProc("EvtRender")
+	procEvtCreateBookmark = modWevtapi.NewProc("EvtCreateBookmark")
+	procEvtUpdateBookmark = modWevtapi.NewProc("EvtUpdateBookmark")
+	procEvtClose          = modWevtapi.NewProc("EvtClose")
+)
+
+const (
+	evtSubscribeToFutureEvents      = 1
+	evtSubscribeStartAtOldestRecord = 2
+	evtSubscribeStartAfterBookmark  = 3
+
+	evtRenderEventXML = 1
+	evtRenderBookmark = 2
+
+	// The interval at which the signal event is polled, which bounds the time
+	// taken to observe a cancelled context or a missed signal.
+	evtWaitMillis = 500
+)
+
+type winEventLogSubscription struct {
+	signal   windows.Handle
+	sub      uintptr
+	bookmark uintptr
+}
+
+func newEventLogSubscription(channel, query string, bookmark []byte, fromOldest bool) (eventLogSubscription, error) {
+	channelPtr, err := windows.UTF16PtrFromString(channel)
+	if err != nil {
+		return nil, err
+	}
+	queryPtr, err := windows.UTF16PtrFromString(query)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &winEventLogSubscription{}
+	if s.signal, err = windows.CreateEvent(nil, 1, 1, nil); err != nil {
+		return nil, fmt.Errorf("CreateEvent: %w", err)
+	}
+
+	var bookmarkXML *uint16
+	if len(bookmark) > 0 {
+		if bookmarkXML, err = windows.UTF16PtrFromString(string(bookmark)); err != nil {
+			_ = s.close()
+			return nil, err
+		}
+	}
+	if s.bookmark, _, err = procEvtCreateBookmark.Call(uintptr(unsafe.Pointer(bookmarkXML))); s.bookmark == 0 {
+		_ = s.close()
+		return nil, fmt.Errorf("EvtCreateBookmark: %w", err)
+	}
+
+	var flags uintptr = evtSubscribeToFutureEvents
+	var subBookmark uintptr
+	switch {
+	case len(bookmark) > 0:
+		flags, subBookmark = evtSubscribeStartAfterBookmark, s.bookmark
+	case fromOldest:
+		flags = evtSubscribeStartAtOldestRecord
+	}
+
+	if s.sub, _, err = procEvtSubscribe.Call(
+		0, uintptr(s.signal),
+		uintptr(unsafe.Pointer(channelPtr)), uintptr(unsafe.Pointer(queryPtr)),
+		subBookmark, 0, 0, flags,
+	); s.sub == 0 {
+		_ = s.close()
+		return nil, fmt.Errorf("EvtSubscribe: %w", err)
+	}
+	return s, nil
+}
+
+func (s *winEventLogSubscription) next(ctx context.Context, max int) ([]eventLogRecord, error) {
+	handles := make([]uintptr, max)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var returned uint32
+		ok, _, err := procEvtNext.Call(
+			s.sub, uintptr(max), uintptr(unsafe.Pointer(&handles[0])),
+			uintptr(windows.INFINITE), 0, uintptr(unsafe.Pointer(&returned)),
+		)
+		if ok != 0 {
+			return s.render(handles[:returned])
+		}
+		if !errors.Is(err, windows.ERROR_NO_MORE_ITEMS) {
+			return nil, fmt.Errorf("EvtNext: %w", err)
+		}
+
+		if err := windows.ResetEvent(s.signal); err != nil {
+			return nil, fmt.Errorf("ResetEvent: %w", err)
+		}
+		if _, err := windows.WaitForSingleObject(s.signal, evtWaitMillis); err != nil {
+			return nil, fmt.Errorf("WaitForSingleObject: %w", err)
+		}
+	}
+}
+
+func (s *winEventLogSubscription) render(handles []uintptr) ([]eventLogRecord, error) {
+	defer func() {
+		for _, h := range handles {
+			_, _, _ = procEvtClose.Call(h)
+		}
+	}()
+
+	records := make([]eventLogRecord, 0, len(handles))
+	for _, h := range handles {
+		raw, err := evtRender(h, evtRenderEventXML)
+		if err != nil {
+			return nil, err
+		}
+		if ok, _, err := procEvtUpdateBookmark.Call(s.bookmark, h); ok == 0 {
+			return nil, fmt.Errorf("EvtUpdateBookmark: %w", err)
+		}
+		bookmark, err := evtRender(s.bookmark, evtRenderBookmark)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, eventLogRecord{xml: raw, bookmark: bookmark})
+	}
+	return records, nil
+}
+
+func evtRender(h uintptr, flags uintptr) ([]byte, error) {
+	var used, props uint32
+	ok, _, err := procEvtRender.Call(0, h, flags, 0, 0, uintptr(unsafe.Pointer(&used)), uintptr(unsafe.Pointer(&props)))
+	if ok == 0 && !errors.Is(err, windows.ERROR_INSUFFICIENT_BUFFER) {
+		return nil, fmt.Errorf("EvtRender: %w", err)
+	}
+
+	buf := make([]uint16, used/2+1)
+	if ok, _, err = procEvtRender.Call(
+		0, h, flags, uintptr(len(buf)*2), uintptr(unsafe.Pointer(&buf[0])),
+		uintptr(unsafe.Pointer(&used)), uintptr(unsafe.Pointer(&props)),
+	); ok == 0 {
+		return nil, fmt.Errorf("EvtRender: %w", err)
+	}
+	return []byte(windows.UTF16ToString(buf)), nil
+}
+
+func (s *winEventLogSubscription) close() error {
+	if s.sub != 0 {
+		_, _, _ = procEvtClose.Call(s.sub)
+		s.sub = 0
+	}
+	if s.bookmark != 0 {
+		_, _, _ = procEvtClose.Call(s.bookmark)
+		s.bookmark = 0
+	}
+	if s.signal != 0 {
+		err := windows.CloseHandle(s.signal)
+		s.signal = 0
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// pathNode is a tree of paths, where a node with end set marks the end of a
+// path.
+type pathNode struct {
+	end      bool
+	children map[string]*pathNode
+}
+
+func (n *pathNode) child(key string) *pathNode {
+	if n == nil {
+		return nil
+	}
+	if c, exists := n.children[key]; exists {
+		return c
+	}
+	return n.children["*"]
+}
+
+// parsePaths parses dot separated paths into a tree, where a segment `*`
+// matches any object key or array index.
+func parsePaths(paths []string) (*pathNode, error) {
+	root := &pathNode{}
+	for _, p := range paths {
+		if p == "" {
+			return nil, errors.New("paths must not be empty")
+		}
+		node := root
+		for _, seg := range strings.Split(p, ".") {
+			if seg == "" {
+				return nil, fmt.Errorf("path %q contains an empty segment", p)
+			}
+			if node.children == nil {
+				node.children = map[string]*pathNode{}
+			}
+			next, exists := node.children[seg]
+			if !exists {
+				next = &pathNode{}
+				node.children[seg] = next
+			}
+			node = next
+		}
+		node.end = true
+	}
+	return root, nil
+}
@@ -0,0 +1,435 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Number types, named after their libphonenumber equivalents.
+const (
+	phoneTypeFixedLine         = "fixed_line"
+	phoneTypeMobile            = "mobile"
+	phoneTypeFixedLineOrMobile = "fixed_line_or_mobile"
+	phoneTypeTollFree          = "toll_free"
+	phoneTypePremiumRate       = "premium_rate"
+	phoneTypeSharedCost        = "shared_cost"
+	phoneTypeVoIP              = "voip"
+	phoneTypePersonalNumber    = "personal_number"
+	phoneTypePager             = "pager"
+	phoneTypeUAN               = "uan"
+	phoneTypeUnknown           = "unknown"
+)
+
+// The maximum number of digits of an E.164 number, including the country
+// calling code.
+const phoneMaxDigits = 15
+
+// The minimum number of digits of a national significant number that is
+// accepted for regions without numbering rules.
+const phoneMinNationalDigits = 4
+
+// phoneTypeRule matches national significant numbers of a given type, where
+// each prefix may contain the wildcard X to match any digit.
+type phoneTypeRule struct {
+	kind     string
+	prefixes []string
+	lengths  []int
+}
+
+func (r phoneTypeRule) matches(nsn string) bool {
+	lengthMatched := false
+	for _, l := range r.lengths {
+		if len(nsn) == l {
+			lengthMatched = true
+			break
+		}
+	}
+	if !lengthMatched {
+		return false
+	}
+	for _, p := range r.prefixes {
+		if len(p) > len(nsn) {
+			continue
+		}
+		matched := true
+		for i := 0; i < len(p); i++ {
+			if p[i] != 'X' && p[i] != nsn[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func phoneLengthRange(min, max int) []int {
+	var lengths []int
+	for i := min; i <= max; i++ {
+		lengths = append(lengths, i)
+	}
+	return lengths
+}
+
+// Numbering rules of regions that are validated and classified precisely,
+// where rules are evaluated in order. Numbers of other regions are only
+// checked for a plausible length.
+var phoneNANPRules = []phoneTypeRule{
+	{phoneTypeTollFree, []string{"800", "833", "844", "855", "866", "877", "888"}, []int{10}},
+	{phoneTypePremiumRate, []string{"900"}, []int{10}},
+	{phoneTypeFixedLineOrMobile, []string{"2", "3", "4", "5", "6", "7", "8", "9"}, []int{10}},
+}
+
+var phoneRegionRules = map[string][]phoneTypeRule{
+	"AU": {
+		{phoneTypeTollFree, []string{"180"}, []int{7, 10}},
+		{phoneTypeSharedCost, []string{"13"}, []int{6, 10}},
+		{phoneTypePremiumRate, []string{"19"}, []int{8, 10}},
+		{phoneTypeMobile, []string{"4"}, []int{9}},
+		{phoneTypeFixedLine, []string{"2", "3", "7", "8"}, []int{9}},
+	},
+	"BR": {
+		{phoneTypeTollFree, []string{"800"}, []int{10, 11}},
+		{phoneTypePremiumRate, []string{"900"}, []int{10, 11}},
+		{phoneTypeMobile, []string{"XX9"}, []int{11}},
+		{phoneTypeFixedLine, []string{"XX2", "XX3", "XX4", "XX5"}, []int{10}},
+	},
+	"CN": {
+		{phoneTypeTollFree, []string{"800"}, []int{10}},
+		{phoneTypeUAN, []string{"400"}, []int{10}},
+		{phoneTypeMobile, []string{"13", "14", "15", "16", "17", "18", "19"}, []int{11}},
+		{phoneTypeFixedLine, []string{"10", "2"}, []int{10}},
+		{phoneTypeFixedLine, []string{"3", "4", "5", "6", "7", "8", "9"}, []int{9, 10, 11}},
+	},
+	"DE": {
+		{phoneTypeMobile, []string{"15"}, []int{11}},
+		{phoneTypeMobile, []string{"16", "17"}, []int{10, 11}},
+		{phoneTypeTollFree, []string{"800"}, []int{10}},
+		{phoneTypePremiumRate, []string{"900"}, []int{10}},
+		{phoneTypeFixedLine, []string{"2", "3", "4", "5", "6", "7", "8", "9"}, phoneLengthRange(6, 11)},
+	},
+	"ES": {
+		{phoneTypeTollFree, []string{"800", "900"}, []int{9}},
+		{phoneTypePremiumRate, []string{"803", "806", "807", "905"}, []int{9}},
+		{phoneTypeSharedCost, []string{"901", "902"}, []int{9}},
+		{phoneTypeMobile, []string{"6", "71", "72", "73", "74"}, []int{9}},
+		{phoneTypeFixedLine, []string{"8", "9"}, []int{9}},
+	},
+	"FR": {
+		{phoneTypeTollFree, []string{"80"}, []int{9}},
+		{phoneTypeSharedCost, []string{"81", "82"}, []int{9}},
+		{phoneTypePremiumRate, []string{"89"}, []int{9}},
+		{phoneTypeMobile, []string{"6", "7"}, []int{9}},
+		{phoneTypeVoIP, []string{"9"}, []int{9}},
+		{phoneTypeFixedLine, []string{"1", "2", "3", "4", "5"}, []int{9}},
+	},
+	"GB": {
+		{phoneTypeMobile, []string{"7624", "71", "72", "73", "74", "75", "77", "78", "79"}, []int{10}},
+		{phoneTypePager, []string{"76"}, []int{10}},
+		{phoneTypePersonalNumber, []string{"70"}, []int{10}},
+		{phoneTypeTollFree, []string{"800"}, []int{9, 10}},
+		{phoneTypeTollFree, []string{"808"}, []int{10}},
+		{phoneTypeSharedCost, []string{"84", "87"}, []int{10}},
+		{phoneTypePremiumRate, []string{"9"}, []int{10}},
+		{phoneTypeVoIP, []string{"56"}, []int{10}},
+		{phoneTypeUAN, []string{"3", "55"}, []int{10}},
+		{phoneTypeFixedLine, []string{"1"}, []int{9, 10}},
+		{phoneTypeFixedLine, []string{"2"}, []int{10}},
+	},
+	"IE": {
+		{phoneTypeTollFree, []string{"1800"}, []int{10}},
+		{phoneTypePremiumRate, []string{"15"}, []int{10}},
+		{phoneTypeMobile, []string{"83", "85", "86", "87", "89"}, []int{9}},
+		{phoneTypeFixedLine, []string{"1", "2", "4", "5", "6", "7", "9"}, []int{7, 8, 9}},
+	},
+	"IN": {
+		{phoneTypeTollFree, []string{"1800"}, phoneLengthRange(10, 13)},
+		{phoneTypeMobile, []string{"6", "7", "8", "9"}, []int{10}},
+		{phoneTypeFixedLine, []string{"1", "2", "3", "4", "5"}, []int{10}},
+	},
+	"IT": {
+		{phoneTypeTollFree, []string{"800", "803"}, []int{6, 9}},
+		{phoneTypePremiumRate, []string{"89"}, phoneLengthRange(6, 10)},
+		{phoneTypeMobile, []string{"3"}, []int{9, 10}},
+		{phoneTypeFixedLine, []string{"0"}, phoneLengthRange(6, 11)},
+	},
+	"JP": {
+		{phoneTypeTollFree, []string{"120"}, []int{9}},
+		{phoneTypeTollFree, []string{"800"}, []int{10}},
+		{phoneTypeMobile, []string{"70", "80", "90"}, []int{10}},
+		{phoneTypeVoIP, []string{"50"}, []int{10}},
+		{phoneTypePager, []string{"20"}, []int{10}},
+		{phoneTypeFixedLine, []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"}, []int{9}},
+	},
+	"MX": {
+		{phoneTypeTollFree, []string{"800"}, []int{10}},
+		{phoneTypePremiumRate, []string{"900"}, []int{10}},
+		{phoneTypeFixedLineOrMobile, []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"}, []int{10}},
+	},
+	"NL": {
+		{phoneTypeTollFree, []string{"800"}, phoneLengthRange(7, 10)},
+		{phoneTypePremiumRate, []string{"90"}, phoneLengthRange(7, 10)},
+		{phoneTypeVoIP, []string{"85", "91"}, []int{9}},
+		{phoneTypeMobile, []string{"6"}, []int{9}},
+		{phoneTypeFixedLine, []string{"1", "2", "3", "4", "5", "7"}, []int{9}},
+	},
+}
+
+// Regions that share a country calling code with another, primary, region.
+// Numbers of the North American Numbering Plan are attributed to a region by
+// their area code.
+var phoneNANPAreaCodes = map[string]string{
+	"204": "CA", "226": "CA", "236": "CA", "249": "CA", "250": "CA", "257": "CA",
+	"263": "CA", "289": "CA", "306": "CA", "343": "CA", "354": "CA", "365": "CA",
+	"367": "CA", "368": "CA", "382": "CA", "403": "CA", "416": "CA", "418": "CA",
+	"428": "CA", "431": "CA", "437": "CA", "438": "CA", "450": "CA", "460": "CA",
+	"468": "CA", "474": "CA", "506": "CA", "514": "CA", "519": "CA", "548": "CA",
+	"579": "CA", "581": "CA", "584": "CA", "587": "CA", "604": "CA", "613": "CA",
+	"639": "CA", "647": "CA", "672": "CA", "683": "CA", "709": "CA", "742": "CA",
+	"753": "CA", "778": "CA", "780": "CA", "782": "CA", "807": "CA", "819": "CA",
+	"825": "CA", "867": "CA", "873": "CA", "879": "CA", "902": "CA", "905": "CA",
+
+	"242": "BS", "246": "BB", "264": "AI", "268": "AG", "284": "VG", "340": "VI",
+	"345": "KY", "441": "BM", "473": "GD", "649": "TC", "658": "JM", "876": "JM",
+	"664": "MS", "670": "MP", "671": "GU", "684": "AS", "721": "SX", "758": "LC",
+	"767": "DM", "784": "VC", "787": "PR", "939": "PR", "809": "DO", "829": "DO",
+	"849": "DO", "868": "TT", "869": "KN",
+}
+
+// The country calling code of each region.
+var phoneRegionCodes = map[string]string{
+	"AD": "376", "AE": "971", "AF": "93", "AG": "1", "AI": "1", "AL": "355",
+	"AM": "374", "AO": "244", "AR": "54", "AS": "1", "AT": "43", "AU": "61",
+	"AW": "297", "AX": "358", "AZ": "994", "BA": "387", "BB": "1", "BD": "880",
+	"BE": "32", "BF": "226", "BG": "359", "BH": "973", "BI": "257", "BJ": "229",
+	"BL": "590", "BM": "1", "BN": "673", "BO": "591", "BQ": "599", "BR": "55",
+	"BS": "1", "BT": "975", "BW": "267", "BY": "375", "BZ": "501", "CA": "1",
+	"CC": "61", "CD": "243", "CF": "236", "CG": "242", "CH": "41", "CI": "225",
+	"CK": "682", "CL": "56", "CM": "237", "CN": "86", "CO": "57", "CR": "506",
+	"CU": "53", "CV": "238", "CW": "599", "CX": "61", "CY": "357", "CZ": "420",
+	"DE": "49", "DJ": "253", "DK": "45", "DM": "1", "DO": "1", "DZ": "213",
+	"EC": "593", "EE": "372", "EG": "20", "EH": "212", "ER": "291", "ES": "34",
+	"ET": "251", "FI": "358", "FJ": "679", "FK": "500", "FM": "691", "FO": "298",
+	"FR": "33", "GA": "241", "GB": "44", "GD": "1", "GE": "995", "GF": "594",
+	"GG": "44", "GH": "233", "GI": "350", "GL": "299", "GM": "220", "GN": "224",
+	"GP": "590", "GQ": "240", "GR": "30", "GT": "502", "GU": "1", "GW": "245",
+	"GY": "592", "HK": "852", "HN": "504", "HR": "385", "HT": "509", "HU": "36",
+	"ID": "62", "IE": "353", "IL": "972", "IM": "44", "IN": "91", "IO": "246",
+	"IQ": "964", "IR": "98", "IS": "354", "IT": "39", "JE": "44", "JM": "1",
+	"JO": "962", "JP": "81", "KE": "254", "KG": "996", "KH": "855", "KI": "686",
+	"KM": "269", "KN": "1", "KP": "850", "KR": "82", "KW": "965", "KY": "1",
+	"KZ": "7", "LA": "856", "LB": "961", "LC": "1", "LI": "423", "LK": "94",
+	"LR": "231", "LS": "266", "LT": "370", "LU": "352", "LV": "371", "LY": "218",
+	"MA": "212", "MC": "377", "MD": "373", "ME": "382", "MF": "590", "MG": "261",
+	"MH": "692", "MK": "389", "ML": "223", "MM": "95", "MN": "976", "MO": "853",
+	"MP": "1", "MQ": "596", "MR": "222", "MS": "1", "MT": "356", "MU": "230",
+	"MV": "960", "MW": "265", "MX": "52", "MY": "60", "MZ": "258", "NA": "264",
+	"NC": "687", "NE": "227", "NF": "672", "NG": "234", "NI": "505", "NL": "31",
+	"NO": "47", "NP": "977", "NR": "674", "NU": "683", "NZ": "64", "OM": "968",
+	"PA": "507", "PE": "51", "PF": "689", "PG": "675", "PH": "63", "PK": "92",
+	"PL": "48", "PM": "508", "PR": "1", "PS": "970", "PT": "351", "PW": "680",
+	"PY": "595", "QA": "974", "RE": "262", "RO": "40", "RS": "381", "RU": "7",
+	"RW": "250", "SA": "966", "SB": "677", "SC": "248", "SD": "249", "SE": "46",
+	"SG": "65", "SH": "290", "SI": "386", "SJ": "47", "SK": "421", "SL": "232",
+	"SM": "378", "SN": "221", "SO": "252", "SR": "597", "SS": "211", "ST": "239",
+	"SV": "503", "SX": "1", "SY": "963", "SZ": "268", "TC": "1", "TD": "235",
+	"TG": "228", "TH": "66", "TJ": "992", "TK": "690", "TL": "670", "TM": "993",
+	"TN": "216", "TO": "676", "TR": "90", "TT": "1", "TV": "688", "TW": "886",
+	"TZ": "255", "UA": "380", "UG": "256", "US": "1", "UY": "598", "UZ": "998",
+	"VA": "39", "VC": "1", "VE": "58", "VG": "1", "VI": "1", "VN": "84",
+	"VU": "678", "WF": "681", "WS": "685", "XK": "383", "YE": "967", "YT": "262",
+	"ZA": "27", "ZM": "260", "ZW": "263",
+}
+
+// The primary region of country calling codes shared by several regions.
+var phonePrimaryRegions = map[string]string{
+	"1": "US", "7": "RU", "39": "IT", "44": "GB", "47": "NO", "61": "AU",
+	"212": "MA", "262": "RE", "358": "FI", "590": "GP", "599": "CW",
+}
+
+// The national trunk prefix of regions where it isn't 0, with an empty prefix
+// for regions without one.
+var phoneTrunkPrefixes = map[string]string{
+	"BH": "", "CL": "", "CY": "", "CZ": "", "DK": "", "EE": "", "ES": "",
+	"GR": "", "HK": "", "IS": "", "IT": "", "KW": "", "LU": "", "LV": "",
+	"MC": "", "MO": "", "MT": "", "MX": "", "NO": "", "OM": "", "PL": "",
+	"PT": "", "QA": "", "SG": "", "SM": "", "VA": "",
+	"BY": "8", "KZ": "8", "LT": "8", "RU": "8", "HU": "06",
+}
+
+// The international dialling prefix of regions where it isn't 00.
+var phoneIntlPrefixes = map[string]string{
+	"AU": "0011", "JP": "010",
+}
+
+// phoneCodeRegions maps each country calling code to its primary region.
+var phoneCodeRegions = func() map[string]string {
+	m := map[string]string{}
+	for region, code := range phoneRegionCodes {
+		if _, shared := phonePrimaryRegions[code]; !shared {
+			m[code] = region
+		}
+	}
+	for code, region := range phonePrimaryRegions {
+		m[code] = region
+	}
+	return m
+}()
+
+func phoneTrunkPrefix(region string) string {
+	if p, exists := phoneTrunkPrefixes[region]; exists {
+		return p
+	}
+	if phoneRegionCodes[region] == "1" {
+		return "1"
+	}
+	return "0"
+}
+
+func phoneIntlPrefix(region string) string {
+	if p, exists := phoneIntlPrefixes[region]; exists {
+		return p
+	}
+	if phoneRegionCodes[region] == "1" {
+		return "011"
+	}
+	return "00"
+}
+
+func phoneRules(region string) []phoneTypeRule {
+	if phoneRegionCodes[region] == "1" {
+		return phoneNANPRules
+	}
+	return phoneRegionRules[region]
+}
+
+var errPhoneInvalid = errors.New("phone number is invalid")
+
+type phoneNumber struct {
+	code   string
+	nsn    string
+	region string
+	kind   string
+}
+
+func (p phoneNumber) e164() string {
+	return "+" + p.code + p.nsn
+}
+
+// classify returns the type of a national significant number within a region,
+// or false when the number isn't valid for the region.
+func phoneClassify(region, nsn string) (string, bool) {
+	rules := phoneRules(region)
+	if rules == nil {
+		code := phoneRegionCodes[region]
+		if len(nsn) < phoneMinNationalDigits || len(code)+len(nsn) > phoneMaxDigits {
+			return "", false
+		}
+		return phoneTypeUnknown, true
+	}
+	for _, r := range rules {
+		if r.matches(nsn) {
+			return r.kind, true
+		}
+	}
+	return "", false
+}
+
+// parsePhoneNumber parses a phone number written either in international
+// format, or in the national format of a default region.
+func parsePhoneNumber(s, defaultRegion string) (phoneNumber, error) {
+	s = strings.TrimSpace(s)
+
+	var digits strings.Builder
+	plus := false
+	for i, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			digits.WriteRune(c)
+		case c == '+' && i == 0:
+			plus = true
+		case c == ' ' || c == '-' || c == '.' || c == '(' || c == ')' || c == '/':
+		default:
+			return phoneNumber{}, fmt.Errorf("%w: unexpected character %q", errPhoneInvalid, c)
+		}
+	}
+
+	num := digits.String()
+	if num == "" {
+		return phoneNumber{}, fmt.Errorf("%w: no digits found", errPhoneInvalid)
+	}
+
+	if !plus && defaultRegion != "" {
+		if p := phoneIntlPrefix(defaultRegion); strings.HasPrefix(num, p) {
+			plus, num = true, num[len(p):]
+		}
+	}
+
+	var p phoneNumber
+	if plus {
+		for l := 1; l <= 3 && l < len(num); l++ {
+			if region, exists := phoneCodeRegions[num[:l]]; exists {
+				p.code, p.region, p.nsn = num[:l], region, num[l:]
+				break
+			}
+		}
+		if p.code == "" {
+			return phoneNumber{}, fmt.Errorf("%w: unknown country calling code", errPhoneInvalid)
+		}
+	} else {
+		if defaultRegion == "" {
+			return phoneNumber{}, fmt.Errorf("%w: number is not in international format and no default region is set", errPhoneInvalid)
+		}
+		p.code, p.region, p.nsn = phoneRegionCodes[defaultRegion], defaultRegion, num
+	}
+
+	// Numbers in national format are usually written with a trunk prefix,
+	// and numbers in international format sometimes are, e.g. +44 (0)20 7946
+	// 0000.
+	candidates := []string{p.nsn}
+	if trunk := phoneTrunkPrefix(p.region); trunk != "" && strings.HasPrefix(p.nsn, trunk) {
+		if plus {
+			candidates = append(candidates, p.nsn[len(trunk):])
+		} else {
+			candidates = []string{p.nsn[len(trunk):], p.nsn}
+		}
+	}
+
+	var kind string
+	valid := false
+	for _, nsn := range candidates {
+		if kind, valid = phoneClassify(p.region, nsn); valid {
+			p.nsn = nsn
+			break
+		}
+	}
+	if !valid {
+		return phoneNumber{}, fmt.Errorf("%w for region %v", errPhoneInvalid, p.region)
+	}
+	if p.code == "1" {
+		if region, exists := phoneNANPAreaCodes[p.nsn[:3]]; exists {
+			p.region = region
+		} else {
+			p.region = "US"
+		}
+	}
+	p.kind = kind
+	return p, nil
+}
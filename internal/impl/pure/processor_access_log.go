@@ -0,0 +1,241 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	alFieldFormat = "format"
+)
+
+var accessLogPresets = map[string]string{
+	"common":   `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent`,
+	"combined": `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent "$http_referer" "$http_user_agent"`,
+}
+
+func accessLogProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Parsing").
+		Version("4.31.0").
+		Summary("Parses Apache and Nginx access log lines into structured fields.").
+		Description(`
+The format is either one of the presets `+"`common`"+` or `+"`combined`"+`, which are the common and combined log formats written by both Apache and Nginx by default, or a custom layout written in the https://nginx.org/en/docs/http/ngx_http_log_module.html#log_format[Nginx `+"`log_format`"+` syntax^], where variables are prefixed with `+"`$`"+` and everything else is matched literally.
+
+The following variables are parsed into fields with the following names:
+
+|===
+| Variable | Field | Type
+
+| `+"`$remote_addr`"+` | `+"`ip`"+` | string
+| `+"`$remote_user`"+` | `+"`user`"+` | string
+| `+"`$time_local`"+`, `+"`$time_iso8601`"+` | `+"`timestamp`"+` | string (RFC 3339)
+| `+"`$request`"+` | `+"`method`"+`, `+"`path`"+`, `+"`protocol`"+` | string
+| `+"`$status`"+` | `+"`status`"+` | number
+| `+"`$body_bytes_sent`"+`, `+"`$bytes_sent`"+` | `+"`bytes`"+` | number
+| `+"`$request_time`"+` | `+"`request_time`"+` | number
+| `+"`$http_referer`"+` | `+"`referrer`"+` | string
+| `+"`$http_user_agent`"+` | `+"`user_agent`"+` | string
+|===
+
+Any other variable is parsed into a string field of the same name without the `+"`$`"+`, and a value of `+"`-`"+` for any variable is omitted from the result. Each message is replaced with an object of the parsed fields, and messages that do not match the format are left unchanged and flagged as failed, and can be handled using xref:configuration:error_handling.adoc[error handling].`).
+		Field(service.NewStringField(alFieldFormat).
+			Description("The format of log lines, either a preset or a custom layout.").
+			Default("combined").
+			Example("common").
+			Example(`$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent $request_time "$http_x_forwarded_for"`)).
+		Example("Nginx logs", "Parse Nginx access logs tailed from a file, dropping lines that cannot be parsed.", `
+input:
+  file:
+    paths: [ /var/log/nginx/access.log ]
+    scanner:
+      lines: {}
+
+pipeline:
+  processors:
+    - access_log:
+        format: combined
+    - mapping: 'root = if errored() { deleted() }'
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"access_log", accessLogProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return accessLogProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type accessLogProc struct {
+	re        *regexp.Regexp
+	variables []string
+}
+
+func accessLogProcFromParsed(conf *service.ParsedConfig) (*accessLogProc, error) {
+	format, err := conf.FieldString(alFieldFormat)
+	if err != nil {
+		return nil, err
+	}
+	if preset, exists := accessLogPresets[format]; exists {
+		format = preset
+	}
+	return newAccessLogProc(format)
+}
+
+var accessLogVarRegexp = regexp.MustCompile(`\$[a-zA-Z_][a-zA-Z0-9_]*`)
+
+// newAccessLogProc compiles a layout into an expression where each variable
+// captures everything up to the literal character that follows it. Quoted
+// variables may also contain escaped quotes.
+func newAccessLogProc(format string) (*accessLogProc, error) {
+	locs := accessLogVarRegexp.FindAllStringIndex(format, -1)
+	if len(locs) == 0 {
+		return nil, errors.New("format must contain at least one variable")
+	}
+
+	p := &accessLogProc{}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+
+	last := 0
+	for i, loc := range locs {
+		if i > 0 && loc[0] == last {
+			return nil, fmt.Errorf("variables %v and %v must be separated by literal text", format[locs[i-1][0]:locs[i-1][1]], format[loc[0]:loc[1]])
+		}
+		expr.WriteString(regexp.QuoteMeta(format[last:loc[0]]))
+		last = loc[1]
+
+		p.variables = append(p.variables, format[loc[0]+1:loc[1]])
+		if last == len(format) {
+			expr.WriteString("(.*)")
+			continue
+		}
+
+		next := format[last]
+		quoted := next == '"' && loc[0] > 0 && format[loc[0]-1] == '"'
+		switch {
+		case quoted:
+			expr.WriteString(`((?:[^"\\]|\\.)*)`)
+		default:
+			expr.WriteString("([^" + regexp.QuoteMeta(string(next)) + "]*)")
+		}
+	}
+	expr.WriteString(regexp.QuoteMeta(format[last:]))
+	expr.WriteString("$")
+
+	var err error
+	if p.re, err = regexp.Compile(expr.String()); err != nil {
+		return nil, fmt.Errorf("failed to compile format: %w", err)
+	}
+	return p, nil
+}
+
+func (p *accessLogProc) parse(line string) (map[string]any, error) {
+	matches := p.re.FindStringSubmatch(line)
+	if matches == nil {
+		return nil, errors.New("line does not match the access log format")
+	}
+
+	fields := map[string]any{}
+	for i, name := range p.variables {
+		v := matches[i+1]
+		if v == "-" || v == "" {
+			continue
+		}
+		switch name {
+		case "remote_addr":
+			fields["ip"] = v
+		case "remote_user":
+			fields["user"] = v
+		case "time_local", "time_iso8601":
+			layout := "02/Jan/2006:15:04:05 -0700"
+			if name == "time_iso8601" {
+				layout = time.RFC3339
+			}
+			t, err := time.Parse(layout, v)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %v: %w", name, err)
+			}
+			fields["timestamp"] = t.Format(time.RFC3339)
+		case "request":
+			parts := strings.Split(v, " ")
+			if len(parts) != 3 {
+				return nil, fmt.Errorf("failed to parse request: %q", v)
+			}
+			fields["method"] = parts[0]
+			fields["path"] = parts[1]
+			fields["protocol"] = parts[2]
+		case "status":
+			status, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse status: %w", err)
+			}
+			fields["status"] = status
+		case "body_bytes_sent", "bytes_sent":
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %v: %w", name, err)
+			}
+			fields["bytes"] = n
+		case "request_time":
+			d, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse request_time: %w", err)
+			}
+			fields["request_time"] = d
+		case "http_referer":
+			fields["referrer"] = v
+		case "http_user_agent":
+			fields["user_agent"] = v
+		default:
+			fields[name] = v
+		}
+	}
+	return fields, nil
+}
+
+func (p *accessLogProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	mBytes, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := p.parse(strings.TrimRight(string(mBytes), "\r\n"))
+	if err != nil {
+		return nil, err
+	}
+
+	msg.SetStructuredMut(fields)
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *accessLogProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestAccessLogFormats(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		input    string
+		expected string
+	}{
+		{
+			name:     "common",
+			format:   "common",
+			input:    `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`,
+			expected: `{"ip":"127.0.0.1","user":"frank","timestamp":"2000-10-10T13:55:36-07:00","method":"GET","path":"/apache_pb.gif","protocol":"HTTP/1.0","status":200,"bytes":2326}`,
+		},
+		{
+			name:     "combined",
+			format:   "combined",
+			input:    `10.1.2.3 - - [01/Jun/2024:12:00:00 +0000] "POST /api/v1/items?x=1 HTTP/1.1" 201 - "https://example.com/" "Mozilla/5.0 (X11; Linux x86_64) \"quoted\""` + "\n",
+			expected: `{"ip":"10.1.2.3","timestamp":"2024-06-01T12:00:00Z","method":"POST","path":"/api/v1/items?x=1","protocol":"HTTP/1.1","status":201,"referrer":"https://example.com/","user_agent":"Mozilla/5.0 (X11; Linux x86_64) \\\"quoted\\\""}`,
+		},
+		{
+			name:     "custom",
+			format:   `$remote_addr [$time_iso8601] "$request" $status $bytes_sent $request_time "$http_x_forwarded_for"`,
+			input:    `::1 [2024-06-01T12:00:00+02:00] "GET / HTTP/2.0" 304 0 0.013 "203.0.113.7, 10.0.0.1"`,
+			expected: `{"ip":"::1","timestamp":"2024-06-01T12:00:00+02:00","method":"GET","path":"/","protocol":"HTTP/2.0","status":304,"bytes":0,"request_time":0.013,"http_x_forwarded_for":"203.0.113.7, 10.0.0.1"}`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := accessLogProcConfig().ParseYAML(`format: '`+test.format+`'`, nil)
+			require.NoError(t, err)
+
+			proc, err := accessLogProcFromParsed(conf)
+			require.NoError(t, err)
+
+			batch, err := proc.Process(context.Background(), service.NewMessage([]byte(test.input)))
+			require.NoError(t, err)
+			require.Len(t, batch, 1)
+
+			mBytes, err := batch[0].AsBytes()
+			require.NoError(t, err)
+			assert.JSONEq(t, test.expected, string(mBytes))
+		})
+	}
+}
+
+func TestAccessLogErrors(t *testing.T) {
+	conf, err := accessLogProcConfig().ParseYAML(`{}`, nil)
+	require.NoError(t, err)
+
+	proc, err := accessLogProcFromParsed(conf)
+	require.NoError(t, err)
+
+	for _, input := range []string{
+		`not an access log`,
+		`127.0.0.1 - - [yesterday] "GET / HTTP/1.1" 200 1 "-" "-"`,
+		`127.0.0.1 - - [01/Jun/2024:12:00:00 +0000] "GET / HTTP/1.1" OK 1 "-" "-"`,
+		`127.0.0.1 - - [01/Jun/2024:12:00:00 +0000] "\x16\x03\x01" 400 1 "-" "-"`,
+	} {
+		_, err := proc.Process(context.Background(), service.NewMessage([]byte(input)))
+		assert.Error(t, err, input)
+	}
+
+	_, err = newAccessLogProc(`no variables`)
+	require.Error(t, err)
+
+	_, err = newAccessLogProc(`$status$bytes_sent`)
+	require.Error(t, err)
+}
@@ -0,0 +1,305 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	acFieldProcessors       = "processors"
+	acFieldInitialLimit     = "initial_limit"
+	acFieldMinLimit         = "min_limit"
+	acFieldMaxLimit         = "max_limit"
+	acFieldBackoffRatio     = "backoff_ratio"
+	acFieldLatencyThreshold = "latency_threshold"
+)
+
+func adaptiveConcurrencyProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Composition").
+		Version("4.31.0").
+		Summary("Executes a list of child processors on the messages of a batch concurrently, with a limit on concurrency that adapts to the latency and errors of the child processors.").
+		Description(`
+Each message of a batch is processed by the child `+"`processors`"+` concurrently, with the number of messages being processed at any given time held below a limit. The limit is adjusted with the additive increase, multiplicative decrease (AIMD) algorithm: each successful execution while the limit is in use increases the limit by one divided by the limit, which increases it by approximately one for every round of executions, and each failed execution multiplies the limit by `+"`backoff_ratio`"+`. The limit therefore grows steadily while a downstream system copes with the load, and backs off quickly once it does not, without the need to tune a fixed limit.
+
+An execution fails when a child processor returns an error or flags a message as failed, or when it takes longer than `+"`latency_threshold`"+` when set. Only one decrease is applied for the failures of executions that were started before the previous decrease, in order to avoid collapsing the limit when many concurrent executions fail at once.
+
+The results of the child processors are returned in the order of the messages of the batch, and child processors are allowed to filter or split messages. Since child processors are executed concurrently they must be safe for concurrent use, which is the case for all standard processors.
+
+== Sharing the limit
+
+Each instance of this processor has its own limit, and when a pipeline has multiple threads each thread has its own instance. In order to limit the concurrency of all threads together define this processor as a xref:configuration:resources.adoc[processor resource], and reference it with the `+"xref:components:processors/resource.adoc[`resource` processor]"+`.
+
+== Metrics
+
+This processor emits a gauge `+"`adaptive_concurrency_limit`"+` with the current limit rounded down, and a gauge `+"`adaptive_concurrency_in_flight`"+` with the number of messages being processed.`).
+		Field(service.NewProcessorListField(acFieldProcessors).
+			Description("A list of processors to execute on each message.")).
+		Field(service.NewIntField(acFieldInitialLimit).
+			Description("The limit of concurrent executions to start with.").
+			Default(4)).
+		Field(service.NewIntField(acFieldMinLimit).
+			Description("The minimum limit of concurrent executions.").
+			Default(1).
+			Advanced()).
+		Field(service.NewIntField(acFieldMaxLimit).
+			Description("The maximum limit of concurrent executions.").
+			Default(100).
+			Advanced()).
+		Field(service.NewFloatField(acFieldBackoffRatio).
+			Description("The ratio, greater than zero and less than one, that the limit is multiplied by when an execution fails.").
+			Default(0.5).
+			Advanced()).
+		Field(service.NewDurationField(acFieldLatencyThreshold).
+			Description("An optional duration after which an execution is considered to have failed even when it succeeds, which reduces the limit when the latency of a downstream system rises.").
+			Example("500ms").
+			Optional()).
+		Example("Enrichment from a variable capacity API", "Enrich documents from an HTTP API as quickly as it allows, backing off when requests take longer than a second or fail, with the limit shared by all pipeline threads.", `
+pipeline:
+  threads: 4
+  processors:
+    - resource: enrich
+
+processor_resources:
+  - label: enrich
+    adaptive_concurrency:
+      initial_limit: 8
+      latency_threshold: 1s
+      processors:
+        - branch:
+            request_map: 'root.id = this.user_id'
+            processors:
+              - http:
+                  url: http://localhost:8080/users
+                  verb: POST
+            result_map: 'root.user = this'
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"adaptive_concurrency", adaptiveConcurrencyProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return adaptiveConcurrencyProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// acSlot is held by an execution, and records the state of the limiter when
+// the execution started.
+type acSlot struct {
+	start     time.Time
+	epoch     uint64
+	saturated bool
+}
+
+type adaptiveConcurrencyProc struct {
+	processors       []*service.OwnedProcessor
+	minLimit         float64
+	maxLimit         float64
+	backoffRatio     float64
+	latencyThreshold time.Duration
+
+	mLimit    *service.MetricGauge
+	mInFlight *service.MetricGauge
+
+	mut      sync.Mutex
+	limit    float64
+	inFlight int
+	epoch    uint64
+	released chan struct{}
+
+	now func() time.Time
+}
+
+func adaptiveConcurrencyProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*adaptiveConcurrencyProc, error) {
+	p := &adaptiveConcurrencyProc{
+		mLimit:    mgr.Metrics().NewGauge("adaptive_concurrency_limit"),
+		mInFlight: mgr.Metrics().NewGauge("adaptive_concurrency_in_flight"),
+		released:  make(chan struct{}),
+		now:       time.Now,
+	}
+
+	var err error
+	if p.processors, err = conf.FieldProcessorList(acFieldProcessors); err != nil {
+		return nil, err
+	}
+
+	initialLimit, err := conf.FieldInt(acFieldInitialLimit)
+	if err != nil {
+		return nil, err
+	}
+	minLimit, err := conf.FieldInt(acFieldMinLimit)
+	if err != nil {
+		return nil, err
+	}
+	maxLimit, err := conf.FieldInt(acFieldMaxLimit)
+	if err != nil {
+		return nil, err
+	}
+	if minLimit < 1 {
+		return nil, fmt.Errorf("field %v must be at least one", acFieldMinLimit)
+	}
+	if maxLimit < minLimit {
+		return nil, fmt.Errorf("field %v must be at least %v", acFieldMaxLimit, acFieldMinLimit)
+	}
+	if initialLimit < minLimit || initialLimit > maxLimit {
+		return nil, fmt.Errorf("field %v must be between %v and %v", acFieldInitialLimit, acFieldMinLimit, acFieldMaxLimit)
+	}
+	p.limit, p.minLimit, p.maxLimit = float64(initialLimit), float64(minLimit), float64(maxLimit)
+
+	if p.backoffRatio, err = conf.FieldFloat(acFieldBackoffRatio); err != nil {
+		return nil, err
+	}
+	if p.backoffRatio <= 0 || p.backoffRatio >= 1 {
+		return nil, fmt.Errorf("field %v must be greater than zero and less than one", acFieldBackoffRatio)
+	}
+	if conf.Contains(acFieldLatencyThreshold) {
+		if p.latencyThreshold, err = conf.FieldDuration(acFieldLatencyThreshold); err != nil {
+			return nil, err
+		}
+	}
+
+	p.mLimit.Set(int64(p.limit))
+	return p, nil
+}
+
+// acquire blocks until the number of executions in flight is below the limit.
+func (p *adaptiveConcurrencyProc) acquire(ctx context.Context) (acSlot, error) {
+	for {
+		p.mut.Lock()
+		if float64(p.inFlight) < math.Floor(p.limit) {
+			p.inFlight++
+			slot := acSlot{
+				start:     p.now(),
+				epoch:     p.epoch,
+				saturated: float64(p.inFlight)*2 >= math.Floor(p.limit),
+			}
+			p.mInFlight.Set(int64(p.inFlight))
+			p.mut.Unlock()
+			return slot, nil
+		}
+		released := p.released
+		p.mut.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return acSlot{}, ctx.Err()
+		}
+	}
+}
+
+// release ends an execution and adjusts the limit according to its outcome.
+func (p *adaptiveConcurrencyProc) release(slot acSlot, failed bool) {
+	if !failed && p.latencyThreshold > 0 && p.now().Sub(slot.start) > p.latencyThreshold {
+		failed = true
+	}
+
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	p.inFlight--
+	p.mInFlight.Set(int64(p.inFlight))
+
+	switch {
+	case failed && slot.epoch == p.epoch:
+		p.limit = math.Max(p.minLimit, p.limit*p.backoffRatio)
+		p.epoch++
+	case !failed && slot.saturated:
+		p.limit = math.Min(p.maxLimit, p.limit+1/p.limit)
+	}
+	p.mLimit.Set(int64(p.limit))
+
+	close(p.released)
+	p.released = make(chan struct{})
+}
+
+func (p *adaptiveConcurrencyProc) execute(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	slot, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Errors that a message already carries are kept by the child processors
+	// and must not be attributed to them, and therefore only errors that
+	// differ from it count as failures.
+	priorErr := msg.GetError()
+
+	batches, err := service.ExecuteProcessors(ctx, p.processors, service.MessageBatch{msg})
+
+	failed := err != nil
+	var res service.MessageBatch
+	for _, b := range batches {
+		for _, m := range b {
+			if mErr := m.GetError(); mErr != nil && (priorErr == nil || !errors.Is(mErr, priorErr)) {
+				failed = true
+			}
+			res = append(res, m)
+		}
+	}
+	p.release(slot, failed)
+	return res, err
+}
+
+func (p *adaptiveConcurrencyProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	results := make([]service.MessageBatch, len(batch))
+	errs := make([]error, len(batch))
+
+	var wg sync.WaitGroup
+	for i, msg := range batch {
+		wg.Add(1)
+		go func(i int, msg *service.Message) {
+			defer wg.Done()
+			results[i], errs[i] = p.execute(ctx, msg)
+		}(i, msg)
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	var out service.MessageBatch
+	for _, r := range results {
+		out = append(out, r...)
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return []service.MessageBatch{out}, nil
+}
+
+func (p *adaptiveConcurrencyProc) Close(ctx context.Context) error {
+	for _, proc := range p.processors {
+		if err := proc.Close(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
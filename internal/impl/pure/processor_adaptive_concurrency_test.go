@@ -0,0 +1,300 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestAdaptiveConcurrencyOrdering(t *testing.T) {
+	conf, err := adaptiveConcurrencyProcConfig().ParseYAML(`
+processors:
+  - mapping: |
+      root = if this.drop { deleted() } else if this.split { [ this.id, this.id ] } else { this.id }
+  - unarchive:
+      format: json_array
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := adaptiveConcurrencyProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, proc.Close(context.Background())) })
+
+	var batch service.MessageBatch
+	for i := 0; i < 10; i++ {
+		batch = append(batch, service.NewMessage([]byte(fmt.Sprintf(
+			`{"id":%v,"drop":%v,"split":%v}`, i, i%3 == 0, i%4 == 0,
+		))))
+	}
+
+	res, err := proc.ProcessBatch(context.Background(), batch)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	var contents []string
+	for _, msg := range res[0] {
+		mBytes, err := msg.AsBytes()
+		require.NoError(t, err)
+		contents = append(contents, string(mBytes))
+	}
+	assert.Equal(t, []string{"1", "2", "4", "4", "5", "7", "8", "8"}, contents)
+}
+
+func TestAdaptiveConcurrencyBounded(t *testing.T) {
+	conf, err := adaptiveConcurrencyProcConfig().ParseYAML(`
+initial_limit: 2
+min_limit: 2
+max_limit: 2
+processors:
+  - sleep:
+      duration: 100ms
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := adaptiveConcurrencyProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, proc.Close(context.Background())) })
+
+	var batch service.MessageBatch
+	for i := 0; i < 8; i++ {
+		batch = append(batch, service.NewMessage([]byte("foo")))
+	}
+
+	start := time.Now()
+	res, err := proc.ProcessBatch(context.Background(), batch)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Len(t, res[0], 8)
+
+	// Eight messages two at a time is four rounds.
+	elapsed := time.Since(start)
+	assert.GreaterOrEqual(t, elapsed, 400*time.Millisecond)
+	assert.Less(t, elapsed, 800*time.Millisecond)
+}
+
+func TestAdaptiveConcurrencyIncrease(t *testing.T) {
+	conf, err := adaptiveConcurrencyProcConfig().ParseYAML(`
+initial_limit: 4
+max_limit: 6
+processors:
+  - mapping: 'root = content()'
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := adaptiveConcurrencyProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, proc.Close(context.Background())) })
+
+	// Saturated successes increase the limit by roughly one per round, the
+	// first of these executions started with the limit mostly unused.
+	var slots []acSlot
+	for i := 0; i < 4; i++ {
+		slot, err := proc.acquire(context.Background())
+		require.NoError(t, err)
+		slots = append(slots, slot)
+	}
+	for _, slot := range slots {
+		proc.release(slot, false)
+	}
+	assert.InDelta(t, 4.7, proc.limit, 0.01)
+
+	for i := 0; i < 100; i++ {
+		slot, err := proc.acquire(context.Background())
+		require.NoError(t, err)
+		slot.saturated = true
+		proc.release(slot, false)
+	}
+	assert.Equal(t, 6.0, proc.limit)
+
+	// Successes while the limit is mostly unused leave it unchanged.
+	proc.limit = 5
+	slot, err := proc.acquire(context.Background())
+	require.NoError(t, err)
+	proc.release(slot, false)
+	assert.Equal(t, 5.0, proc.limit)
+}
+
+func TestAdaptiveConcurrencyDecrease(t *testing.T) {
+	conf, err := adaptiveConcurrencyProcConfig().ParseYAML(`
+initial_limit: 16
+min_limit: 3
+processors:
+  - mapping: 'root = if this.fail { throw("nope") } else { this }'
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := adaptiveConcurrencyProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, proc.Close(context.Background())) })
+
+	// Concurrent failures only decrease the limit once.
+	var slots []acSlot
+	for i := 0; i < 8; i++ {
+		slot, err := proc.acquire(context.Background())
+		require.NoError(t, err)
+		slots = append(slots, slot)
+	}
+	for _, slot := range slots {
+		proc.release(slot, true)
+	}
+	assert.Equal(t, 8.0, proc.limit)
+
+	// Failures in sequence decrease it each time, down to the minimum.
+	for i := 0; i < 3; i++ {
+		res, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+			service.NewMessage([]byte(`{"fail":true}`)),
+		})
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+		require.Len(t, res[0], 1)
+		assert.Error(t, res[0][0].GetError())
+	}
+	assert.Equal(t, 3.0, proc.limit)
+	assert.Equal(t, 0, proc.inFlight)
+}
+
+func TestAdaptiveConcurrencyIgnoresPriorErrors(t *testing.T) {
+	conf, err := adaptiveConcurrencyProcConfig().ParseYAML(`
+initial_limit: 8
+processors:
+  - mapping: 'root = content()'
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := adaptiveConcurrencyProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, proc.Close(context.Background())) })
+
+	// Messages that failed before reaching the processor do not decrease the
+	// limit.
+	for i := 0; i < 3; i++ {
+		msg := service.NewMessage([]byte(`{"id":1}`))
+		msg.SetError(errors.New("upstream failure"))
+
+		res, err := proc.ProcessBatch(context.Background(), service.MessageBatch{msg})
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+		require.Len(t, res[0], 1)
+		assert.EqualError(t, res[0][0].GetError(), "upstream failure")
+	}
+	assert.Equal(t, 8.0, proc.limit)
+}
+
+func TestAdaptiveConcurrencyNewErrorOnPriorError(t *testing.T) {
+	conf, err := adaptiveConcurrencyProcConfig().ParseYAML(`
+initial_limit: 8
+processors:
+  - mapping: 'root = throw("nope")'
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := adaptiveConcurrencyProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, proc.Close(context.Background())) })
+
+	// A child error replacing the error a message arrived with decreases the
+	// limit.
+	msg := service.NewMessage([]byte(`{"id":1}`))
+	msg.SetError(errors.New("upstream failure"))
+
+	res, err := proc.ProcessBatch(context.Background(), service.MessageBatch{msg})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 1)
+	require.Error(t, res[0][0].GetError())
+	assert.NotEqual(t, "upstream failure", res[0][0].GetError().Error())
+	assert.Equal(t, 4.0, proc.limit)
+}
+
+func TestAdaptiveConcurrencyLatencyThreshold(t *testing.T) {
+	conf, err := adaptiveConcurrencyProcConfig().ParseYAML(`
+initial_limit: 8
+latency_threshold: 1s
+processors:
+  - mapping: 'root = content()'
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := adaptiveConcurrencyProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, proc.Close(context.Background())) })
+
+	now := time.Unix(1000, 0)
+	proc.now = func() time.Time { return now }
+
+	slot, err := proc.acquire(context.Background())
+	require.NoError(t, err)
+	now = now.Add(500 * time.Millisecond)
+	proc.release(slot, false)
+	assert.Equal(t, 8.0, proc.limit)
+
+	slot, err = proc.acquire(context.Background())
+	require.NoError(t, err)
+	now = now.Add(2 * time.Second)
+	proc.release(slot, false)
+	assert.Equal(t, 4.0, proc.limit)
+}
+
+func TestAdaptiveConcurrencyCancelled(t *testing.T) {
+	conf, err := adaptiveConcurrencyProcConfig().ParseYAML(`
+initial_limit: 1
+processors:
+  - mapping: 'root = content()'
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := adaptiveConcurrencyProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, proc.Close(context.Background())) })
+
+	slot, err := proc.acquire(context.Background())
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer done()
+
+	_, err = proc.ProcessBatch(ctx, service.MessageBatch{service.NewMessage([]byte("foo"))})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	proc.release(slot, false)
+	assert.Equal(t, 0, proc.inFlight)
+}
+
+func TestAdaptiveConcurrencyBadConfig(t *testing.T) {
+	for _, conf := range []string{
+		`initial_limit: 0`,
+		`min_limit: 0`,
+		`max_limit: 2`,
+		`initial_limit: 4
+max_limit: 2`,
+		`backoff_ratio: 1`,
+		`backoff_ratio: 0`,
+	} {
+		pConf, err := adaptiveConcurrencyProcConfig().ParseYAML(conf+"\nprocessors: []", nil)
+		require.NoError(t, err)
+
+		_, err = adaptiveConcurrencyProcFromParsed(pConf, service.MockResources())
+		assert.Error(t, err, conf)
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	asFieldTargetRate = "target_rate"
+	asFieldInterval   = "interval"
+	asFieldSmoothing  = "smoothing"
+	asFieldMinRatio   = "min_ratio"
+)
+
+func adaptiveSampleProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Randomly drops messages with a probability that adapts to the rate of incoming messages, in order to hold the rate of messages that pass through at a target.").
+		Description(`
+Unlike sampling with a fixed ratio, all messages pass through while the rate of incoming messages is below `+"`target_rate`"+`, and messages are sampled increasingly aggressively as the rate rises above it, which protects downstream systems during spikes in volume.
+
+The rate of incoming messages is measured over each `+"`interval`"+`, and at the end of each interval the ratio of messages that pass through is recalculated as the target rate divided by the measured rate, smoothed with previous measurements according to `+"`smoothing`"+`. Since no measurement exists until the end of the first interval, all messages pass through during it. Each message is then either passed through or dropped independently at random, and therefore this processor is a head-based sampler, it does not consider the contents of messages or which messages belong together.
+
+The rate is measured per instance of this processor, and therefore when a pipeline has multiple threads the target rate applies to each thread separately.
+
+== Metrics
+
+This processor emits a gauge `+"`adaptive_sample_ratio`"+` with the current ratio of messages that pass through, from 0 to 1.`).
+		Field(service.NewFloatField(asFieldTargetRate).
+			Description("The target rate of messages per second that pass through.").
+			Example(100).
+			Example(2.5)).
+		Field(service.NewDurationField(asFieldInterval).
+			Description("The period over which the rate of incoming messages is measured before the sample ratio is recalculated.").
+			Default("1s").
+			Advanced()).
+		Field(service.NewFloatField(asFieldSmoothing).
+			Description("The weight from 0 to 1 given to the most recent measurement of the incoming rate, with the remaining weight given to previous measurements. Higher values react to changes in volume more quickly, lower values result in a steadier sample ratio.").
+			Default(0.5).
+			Advanced()).
+		Field(service.NewFloatField(asFieldMinRatio).
+			Description("A minimum ratio from 0 to 1 of messages that pass through regardless of the incoming rate, which ensures that some data is always retained.").
+			Default(0.0).
+			Advanced()).
+		Example("Protect a tracing backend", "Forward at most roughly 500 spans per second, sampling only when volume rises above that.", `
+pipeline:
+  processors:
+    - adaptive_sample:
+        target_rate: 500
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"adaptive_sample", adaptiveSampleProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return adaptiveSampleProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type adaptiveSampleProc struct {
+	targetRate float64
+	interval   time.Duration
+	smoothing  float64
+	minRatio   float64
+
+	mRatio *service.MetricGauge
+
+	mut         sync.Mutex
+	windowStart time.Time
+	count       int64
+	rate        float64
+	measured    bool
+	ratio       float64
+
+	now  func() time.Time
+	rand func() float64
+}
+
+func adaptiveSampleProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*adaptiveSampleProc, error) {
+	p := &adaptiveSampleProc{
+		mRatio: mgr.Metrics().NewGauge("adaptive_sample_ratio"),
+		ratio:  1,
+		now:    time.Now,
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())).Float64,
+	}
+
+	var err error
+	if p.targetRate, err = conf.FieldFloat(asFieldTargetRate); err != nil {
+		return nil, err
+	}
+	if p.targetRate <= 0 {
+		return nil, fmt.Errorf("field %v must be greater than zero", asFieldTargetRate)
+	}
+	if p.interval, err = conf.FieldDuration(asFieldInterval); err != nil {
+		return nil, err
+	}
+	if p.interval <= 0 {
+		return nil, fmt.Errorf("field %v must be greater than zero", asFieldInterval)
+	}
+	if p.smoothing, err = conf.FieldFloat(asFieldSmoothing); err != nil {
+		return nil, err
+	}
+	if p.smoothing <= 0 || p.smoothing > 1 {
+		return nil, fmt.Errorf("field %v must be greater than zero and at most one", asFieldSmoothing)
+	}
+	if p.minRatio, err = conf.FieldFloat(asFieldMinRatio); err != nil {
+		return nil, err
+	}
+	if p.minRatio < 0 || p.minRatio > 1 {
+		return nil, fmt.Errorf("field %v must be between zero and one", asFieldMinRatio)
+	}
+
+	p.windowStart = p.now()
+	p.mRatio.SetFloat64(p.ratio)
+	return p, nil
+}
+
+// recalculate ends the current measurement window when it has elapsed and
+// derives a new sample ratio from the rate measured within it. Must be called
+// whilst holding the mutex.
+func (p *adaptiveSampleProc) recalculate(now time.Time) {
+	elapsed := now.Sub(p.windowStart)
+	if elapsed < p.interval {
+		return
+	}
+
+	rate := float64(p.count) / elapsed.Seconds()
+	if p.measured {
+		p.rate = p.smoothing*rate + (1-p.smoothing)*p.rate
+	} else {
+		p.rate = rate
+		p.measured = true
+	}
+
+	p.ratio = 1
+	if p.rate > p.targetRate {
+		p.ratio = p.targetRate / p.rate
+	}
+	if p.ratio < p.minRatio {
+		p.ratio = p.minRatio
+	}
+	p.mRatio.SetFloat64(p.ratio)
+
+	p.windowStart = now
+	p.count = 0
+}
+
+func (p *adaptiveSampleProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	p.recalculate(p.now())
+	p.count++
+
+	if p.ratio < 1 && p.rand() >= p.ratio {
+		return nil, nil
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *adaptiveSampleProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+// cyclingRand cycles through evenly distributed values so that exactly the
+// sample ratio of messages pass through.
+func cyclingRand() func() float64 {
+	var i int
+	return func() float64 {
+		i++
+		return float64(i%100) / 100
+	}
+}
+
+// sendOverSecond sends n messages spread evenly over one second and returns
+// the number that passed through.
+func sendOverSecond(t testing.TB, proc *adaptiveSampleProc, now *time.Time, n int) (passed int) {
+	t.Helper()
+
+	step := time.Second / time.Duration(n)
+	for i := 0; i < n; i++ {
+		res, err := proc.Process(context.Background(), service.NewMessage([]byte("hello")))
+		require.NoError(t, err)
+		passed += len(res)
+		*now = now.Add(step)
+	}
+	return
+}
+
+func TestAdaptiveSampleBelowTarget(t *testing.T) {
+	now := time.Unix(1000, 0)
+	conf, err := adaptiveSampleProcConfig().ParseYAML(`target_rate: 100`, nil)
+	require.NoError(t, err)
+
+	proc, err := adaptiveSampleProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	proc.now = func() time.Time { return now }
+	proc.windowStart = now
+	proc.rand = cyclingRand()
+
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, 50, sendOverSecond(t, proc, &now, 50))
+	}
+	assert.Equal(t, 1.0, proc.ratio)
+}
+
+func TestAdaptiveSampleSpike(t *testing.T) {
+	now := time.Unix(1000, 0)
+	conf, err := adaptiveSampleProcConfig().ParseYAML(`target_rate: 100`, nil)
+	require.NoError(t, err)
+
+	proc, err := adaptiveSampleProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	proc.now = func() time.Time { return now }
+	proc.windowStart = now
+	proc.rand = cyclingRand()
+
+	// Nothing is measured during the first interval.
+	assert.Equal(t, 1000, sendOverSecond(t, proc, &now, 1000))
+
+	assert.Equal(t, 100, sendOverSecond(t, proc, &now, 1000))
+	assert.InDelta(t, 0.1, proc.ratio, 0.001)
+
+	// Volume drops, the measured rate is smoothed with the previous one.
+	sendOverSecond(t, proc, &now, 100)
+	assert.InDelta(t, 0.1, proc.ratio, 0.001)
+
+	sendOverSecond(t, proc, &now, 100)
+	assert.InDelta(t, 100.0/550.0, proc.ratio, 0.001)
+
+	for i := 0; i < 10; i++ {
+		sendOverSecond(t, proc, &now, 50)
+	}
+	assert.Equal(t, 1.0, proc.ratio)
+	assert.Equal(t, 50, sendOverSecond(t, proc, &now, 50))
+}
+
+func TestAdaptiveSampleMinRatio(t *testing.T) {
+	now := time.Unix(1000, 0)
+	conf, err := adaptiveSampleProcConfig().ParseYAML(`
+target_rate: 10
+min_ratio: 0.2
+smoothing: 1
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := adaptiveSampleProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	proc.now = func() time.Time { return now }
+	proc.windowStart = now
+	proc.rand = cyclingRand()
+
+	sendOverSecond(t, proc, &now, 1000)
+	assert.Equal(t, 200, sendOverSecond(t, proc, &now, 1000))
+	assert.Equal(t, 0.2, proc.ratio)
+}
+
+func TestAdaptiveSampleBadConfig(t *testing.T) {
+	for _, conf := range []string{
+		`target_rate: 0`,
+		"target_rate: 10\ninterval: 0s",
+		"target_rate: 10\nsmoothing: 0",
+		"target_rate: 10\nsmoothing: 1.5",
+		"target_rate: 10\nmin_ratio: -1",
+	} {
+		pConf, err := adaptiveSampleProcConfig().ParseYAML(conf, nil)
+		require.NoError(t, err, conf)
+
+		_, err = adaptiveSampleProcFromParsed(pConf, service.MockResources())
+		require.Error(t, err, conf)
+	}
+}
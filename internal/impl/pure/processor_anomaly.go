@@ -0,0 +1,271 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	anFieldKey        = "key"
+	anFieldValue      = "value"
+	anFieldWindow     = "window"
+	anFieldMinSamples = "min_samples"
+	anFieldThreshold  = "threshold"
+	anFieldCache      = "cache"
+	anFieldTTL        = "ttl"
+)
+
+func anomalyProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Flags messages with values that are outliers compared to the recent values of their key, using a rolling z-score with the state of each key stored in a cache.").
+		Description(`
+The z-score of a value is the number of standard deviations that it lies from the mean of the previous `+"`window`"+` values of its key. A message is flagged as an anomaly when the absolute z-score of its value exceeds the `+"`threshold`"+`. After it has been scored each value is added to the window of its key, including values flagged as anomalies.
+
+The metadata field `+"`anomaly`"+` is set on each message to a boolean indicating whether it is an anomaly, and the field `+"`anomaly_score`"+` is set to its z-score. Until a key has at least `+"`min_samples`"+` previous values it is warming up, its messages are never flagged, and `+"`anomaly_score`"+` is not set. When the previous values of a key are all identical any different value is flagged as an anomaly with an infinite score.
+
+Messages where the value cannot be parsed as a number are flagged as failed so that they can be handled using xref:configuration:error_handling.adoc[error handling methods], and do not affect the statistics of their key.
+
+== State
+
+The state of each key is limited to the last `+"`window`"+` values and is stored in the cache. Keys that are no longer seen can be evicted by setting a `+"`ttl`"+`, or by using a cache that limits its size such as `+"xref:components:caches/ristretto.adoc[`ristretto`]"+`, where an evicted key warms up again.
+
+A value is scored against the window of its key read from the cache, and the window with the value added is then written back. Values of the same key that are scored by multiple pipeline threads at the same time are scored against the same window and only the last write is kept, and so the other values are missing from the statistics that later values are scored against. The values of a key must therefore be scored by a single pipeline thread, which can be achieved by partitioning messages by key upstream.`).
+		Field(service.NewInterpolatedStringField(anFieldKey).
+			Description("An interpolated string that resolves to the key of each message.").
+			Example(`${! this.host }`)).
+		Field(service.NewInterpolatedStringField(anFieldValue).
+			Description("An interpolated string that resolves to the numeric value of each message.").
+			Example(`${! this.latency_ms }`)).
+		Field(service.NewIntField(anFieldWindow).
+			Description("The number of previous values of each key to calculate the mean and standard deviation from.").
+			Default(100)).
+		Field(service.NewIntField(anFieldMinSamples).
+			Description("The minimum number of previous values of a key required before its messages can be flagged. Must be at least 2 and no greater than `window`.").
+			Default(10).
+			Advanced()).
+		Field(service.NewFloatField(anFieldThreshold).
+			Description("The absolute z-score above which a value is flagged as an anomaly.").
+			Default(3.0)).
+		Field(service.NewStringField(anFieldCache).
+			Description("The xref:components:caches/about.adoc[`cache` resource] to store the state of each key in.")).
+		Field(service.NewStringField(anFieldTTL).
+			Description("An optional TTL to set for the state of each key, after which the key warms up again. Not all caches support per-key TTLs.").
+			Example("1h").
+			Optional().
+			Advanced()).
+		Example("Latency spikes", "Route requests with an anomalous latency for their host to a separate topic.", `
+pipeline:
+  processors:
+    - anomaly:
+        key: ${! this.host }
+        value: ${! this.latency_ms }
+        window: 200
+        threshold: 3.5
+        cache: latencies
+
+output:
+  switch:
+    cases:
+      - check: '@anomaly'
+        output:
+          kafka_franz:
+            seed_brokers: [ localhost:9092 ]
+            topic: latency_anomalies
+      - output:
+          drop: {}
+
+cache_resources:
+  - label: latencies
+    memory: {}
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"anomaly", anomalyProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return anomalyProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type anomalyProc struct {
+	key        *service.InterpolatedString
+	value      *service.InterpolatedString
+	window     int
+	minSamples int
+	threshold  float64
+	cache      string
+	ttl        *time.Duration
+
+	mgr *service.Resources
+}
+
+func anomalyProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*anomalyProc, error) {
+	p := &anomalyProc{mgr: mgr}
+
+	var err error
+	if p.key, err = conf.FieldInterpolatedString(anFieldKey); err != nil {
+		return nil, err
+	}
+	if p.value, err = conf.FieldInterpolatedString(anFieldValue); err != nil {
+		return nil, err
+	}
+	if p.window, err = conf.FieldInt(anFieldWindow); err != nil {
+		return nil, err
+	}
+	if p.minSamples, err = conf.FieldInt(anFieldMinSamples); err != nil {
+		return nil, err
+	}
+	if p.minSamples < 2 || p.minSamples > p.window {
+		return nil, errors.New("min_samples must be at least 2 and no greater than window")
+	}
+	if p.threshold, err = conf.FieldFloat(anFieldThreshold); err != nil {
+		return nil, err
+	}
+	if p.threshold <= 0 {
+		return nil, errors.New("threshold must be greater than zero")
+	}
+	if p.cache, err = conf.FieldString(anFieldCache); err != nil {
+		return nil, err
+	}
+	if !mgr.HasCache(p.cache) {
+		return nil, fmt.Errorf("cache resource '%v' was not found", p.cache)
+	}
+	if conf.Contains(anFieldTTL) {
+		ttlStr, err := conf.FieldString(anFieldTTL)
+		if err != nil {
+			return nil, err
+		}
+		ttl, err := time.ParseDuration(ttlStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ttl: %w", err)
+		}
+		p.ttl = &ttl
+	}
+	return p, nil
+}
+
+// anomalyState is the state of a key as it is stored within the cache.
+type anomalyState struct {
+	Values []float64 `json:"values"`
+}
+
+// zScore returns the z-score of v against the values of the state, and false
+// when there are not yet enough values to calculate a meaningful score.
+func (p *anomalyProc) zScore(state *anomalyState, v float64) (float64, bool) {
+	n := len(state.Values)
+	if n < p.minSamples {
+		return 0, false
+	}
+
+	var sum float64
+	for _, x := range state.Values {
+		sum += x
+	}
+	mean := sum / float64(n)
+
+	var sqDiffs float64
+	for _, x := range state.Values {
+		sqDiffs += (x - mean) * (x - mean)
+	}
+	stdDev := math.Sqrt(sqDiffs / float64(n))
+
+	if stdDev == 0 {
+		if v == mean {
+			return 0, true
+		}
+		return math.Inf(int(math.Copysign(1, v-mean))), true
+	}
+	return (v - mean) / stdDev, true
+}
+
+func (p *anomalyProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	key, err := p.key.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("key interpolation error: %w", err)
+	}
+	valueStr, err := p.value.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("value interpolation error: %w", err)
+	}
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse value %q as a number", valueStr)
+	}
+
+	var state anomalyState
+	var cacheErr error
+	if err := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		stateBytes, err := c.Get(ctx, key)
+		if err != nil {
+			if !errors.Is(err, service.ErrKeyNotFound) {
+				cacheErr = err
+			}
+			return
+		}
+		if err := json.Unmarshal(stateBytes, &state); err != nil {
+			cacheErr = fmt.Errorf("failed to parse state: %w", err)
+		}
+	}); err != nil {
+		return nil, err
+	}
+	if cacheErr != nil {
+		return nil, fmt.Errorf("failed to obtain state of %v: %w", key, cacheErr)
+	}
+
+	score, scored := p.zScore(&state, value)
+
+	state.Values = append(state.Values, value)
+	if len(state.Values) > p.window {
+		state.Values = state.Values[len(state.Values)-p.window:]
+	}
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		cacheErr = c.Set(ctx, key, stateBytes, p.ttl)
+	}); err != nil {
+		return nil, err
+	}
+	if cacheErr != nil {
+		return nil, fmt.Errorf("failed to store state of %v: %w", key, cacheErr)
+	}
+
+	msg.MetaSetMut("anomaly", scored && math.Abs(score) > p.threshold)
+	if scored {
+		msg.MetaSetMut("anomaly_score", score)
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *anomalyProc) Close(ctx context.Context) error {
+	return nil
+}
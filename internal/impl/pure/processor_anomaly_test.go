@@ -0,0 +1,153 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type anomalyResult struct {
+	flagged bool
+	score   any
+}
+
+func anomalyResults(t testing.TB, proc *anomalyProc, contents ...string) []anomalyResult {
+	t.Helper()
+
+	var results []anomalyResult
+	for _, c := range contents {
+		res, err := proc.Process(context.Background(), service.NewMessage([]byte(c)))
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+
+		flagged, exists := res[0].MetaGetMut("anomaly")
+		require.True(t, exists)
+		r := anomalyResult{flagged: flagged.(bool)}
+		if score, exists := res[0].MetaGetMut("anomaly_score"); exists {
+			r.score = score
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+func TestAnomalyZScore(t *testing.T) {
+	conf, err := anomalyProcConfig().ParseYAML(`
+key: ${! this.id }
+value: ${! this.v }
+window: 4
+min_samples: 4
+threshold: 2
+cache: foocache
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := anomalyProcFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.NoError(t, err)
+
+	// Once warmed up the window of a is [10,12,10,12], which has a mean of 11
+	// and a standard deviation of 1.
+	assert.Equal(t, []anomalyResult{
+		{flagged: false},
+		{flagged: false},
+		{flagged: false},
+		{flagged: false},
+		{flagged: false},
+		{flagged: true, score: 3.0},
+	}, anomalyResults(t, proc,
+		`{"id":"a","v":10}`,
+		`{"id":"a","v":12}`,
+		`{"id":"b","v":1000}`,
+		`{"id":"a","v":10}`,
+		`{"id":"a","v":12}`,
+		`{"id":"a","v":14}`,
+	))
+
+	// The window of a is now [12,10,12,14].
+	res := anomalyResults(t, proc, `{"id":"a","v":12}`)
+	assert.False(t, res[0].flagged)
+	assert.InDelta(t, 0.0, res[0].score, 0.0001)
+}
+
+func TestAnomalyConstantHistory(t *testing.T) {
+	conf, err := anomalyProcConfig().ParseYAML(`
+key: ${! this.id }
+value: ${! this.v }
+min_samples: 2
+cache: foocache
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := anomalyProcFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.NoError(t, err)
+
+	assert.Equal(t, []anomalyResult{
+		{flagged: false},
+		{flagged: false},
+		{flagged: false, score: 0.0},
+		{flagged: true, score: math.Inf(-1)},
+	}, anomalyResults(t, proc,
+		`{"id":"a","v":5}`,
+		`{"id":"a","v":5}`,
+		`{"id":"a","v":5}`,
+		`{"id":"a","v":4}`,
+	))
+}
+
+func TestAnomalyBadValue(t *testing.T) {
+	conf, err := anomalyProcConfig().ParseYAML(`
+key: ${! this.id }
+value: ${! this.v }
+min_samples: 2
+cache: foocache
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := anomalyProcFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.NoError(t, err)
+
+	anomalyResults(t, proc, `{"id":"a","v":5}`, `{"id":"a","v":5}`)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"id":"a","v":"nope"}`)))
+	require.Error(t, err)
+
+	// The failed message did not affect the state of the key.
+	assert.Equal(t, []anomalyResult{{flagged: false, score: 0.0}}, anomalyResults(t, proc, `{"id":"a","v":5}`))
+}
+
+func TestAnomalyBadConfig(t *testing.T) {
+	for _, conf := range []string{
+		"min_samples: 1",
+		"window: 5\nmin_samples: 6",
+		"threshold: 0",
+	} {
+		pConf, err := anomalyProcConfig().ParseYAML(`
+key: foo
+value: ${! this.v }
+cache: foocache
+`+conf, nil)
+		require.NoError(t, err)
+
+		_, err = anomalyProcFromParsed(pConf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+		require.Error(t, err, conf)
+	}
+}
@@ -0,0 +1,436 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	bdFieldKey               = "key"
+	bdFieldExpectedItems     = "expected_items"
+	bdFieldFalsePositiveRate = "false_positive_rate"
+	bdFieldWindow            = "window"
+	bdFieldCache             = "cache"
+	bdFieldCacheKey          = "cache_key"
+	bdFieldDropOnErr         = "drop_on_err"
+)
+
+func bloomDedupeProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Deduplicates messages by storing a key for each message within an in-memory Bloom filter, dropping messages with keys that have probably been seen before.").
+		Description(`
+This processor is an alternative to the `+"xref:components:processors/dedupe.adoc[`dedupe` processor]"+` for streams where the number of unique keys is far too large to track exactly. A Bloom filter represents the set of seen keys with a fixed amount of memory, determined by `+"`expected_items`"+` and `+"`false_positive_rate`"+`, at the cost of occasionally reporting that a key has been seen when it has not.
+
+== False positives
+
+A false positive means that a message with a key that has never been seen before is dropped as a duplicate. The probability of this happening stays close to `+"`false_positive_rate`"+` as long as no more than `+"`expected_items`"+` unique keys have been added to the filter, but grows rapidly beyond that, and therefore `+"`expected_items`"+` should be set to the largest number of unique keys expected within the deduplication period. Lowering `+"`false_positive_rate`"+` reduces the chance of losing messages but increases the memory used, as a rough guide the filter requires around 1.2 bytes per expected item at a rate of 0.01, and around 1.8 bytes at a rate of 0.001. False negatives are not possible, a key that has been added to the filter will always be detected as a duplicate until the filter is discarded.
+
+== Windowing
+
+Keys cannot be removed from a Bloom filter, and therefore without a `+"`window`"+` the filter fills up forever. When a `+"`window`"+` is set two filters are kept, keys are checked against both but only added to the newest, and each time the window elapses the oldest filter is discarded and replaced with an empty one. This means a key is remembered for at least the duration of `+"`window`"+` and at most twice that duration, and `+"`expected_items`"+` should account for the number of unique keys seen within a single window. Both filters are allocated up front, so the memory used is doubled.
+
+== Persistence
+
+By default the filters only exist in memory and are lost when the pipeline restarts. When a `+"`cache`"+` is configured the filters are loaded from the cache when the first batch is processed, and written back to it each time the filters are rotated and when the processor is closed. Since the filters are stored as a single value the cache must support values of that size, and any keys added since the last write are lost when the process exits abruptly.
+
+== Batches
+
+This processor acts across all messages of a batch, and therefore duplicates within the same batch are also removed. When a message is dropped it is acknowledged immediately.`).
+		Field(service.NewInterpolatedStringField(bdFieldKey).
+			Description("An interpolated string that should resolve to a key that uniquely identifies a message.").
+			Examples(`${! meta("kafka_key") }`, `${! content().hash("xxhash64") }`)).
+		Field(service.NewIntField(bdFieldExpectedItems).
+			Description("The number of unique keys expected to be added to a filter, which determines its size.").
+			Default(1000000)).
+		Field(service.NewFloatField(bdFieldFalsePositiveRate).
+			Description("The target probability of a new key being reported as already seen once the filter holds `expected_items` keys, must be greater than 0 and less than 1.").
+			Default(0.01)).
+		Field(service.NewDurationField(bdFieldWindow).
+			Description("An optional period after which the filters are rotated, causing keys to be forgotten after somewhere between one and two windows.").
+			Example("1h").
+			Optional()).
+		Field(service.NewStringField(bdFieldCache).
+			Description("An optional xref:components:caches/about.adoc[`cache` resource] to persist the filters to.").
+			Optional().
+			Advanced()).
+		Field(service.NewStringField(bdFieldCacheKey).
+			Description("The key under which the filters are stored within the `cache`.").
+			Default("bloom_dedupe").
+			Advanced()).
+		Field(service.NewBoolField(bdFieldDropOnErr).
+			Description("Whether messages should be dropped when the key could not be resolved. When `false` such messages are kept and flagged as failed.").
+			Default(true).
+			Advanced()).
+		Example("Deduplicate billions of events", "Drop events with an ID that has been seen within the last day or so, tolerating a one in a million chance of dropping a unique event.", `
+pipeline:
+  processors:
+    - bloom_dedupe:
+        key: ${! json("event_id") }
+        expected_items: 500000000
+        false_positive_rate: 0.000001
+        window: 24h
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"bloom_dedupe", bloomDedupeProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return bloomDedupeProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// bloomFilter is a fixed size Bloom filter where the bit positions of a key
+// are derived from two independent hashes using the Kirsch-Mitzenmacher
+// technique.
+type bloomFilter struct {
+	m    uint64
+	k    uint64
+	bits []uint64
+}
+
+func newBloomFilter(expectedItems int, falsePositiveRate float64) *bloomFilter {
+	n := float64(expectedItems)
+	m := math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	k := math.Round(m / n * math.Ln2)
+	if k < 1 {
+		k = 1
+	}
+	return newBloomFilterSized(uint64(m), uint64(k))
+}
+
+func newBloomFilterSized(m, k uint64) *bloomFilter {
+	if m < 64 {
+		m = 64
+	}
+	return &bloomFilter{
+		m:    m,
+		k:    k,
+		bits: make([]uint64, (m+63)/64),
+	}
+}
+
+func bloomHashes(key []byte) (h1, h2 uint64) {
+	h1 = xxhash.Sum64(key)
+	f := fnv.New64a()
+	_, _ = f.Write(key)
+	// Ensure the second hash is odd so that it never collapses every position
+	// onto the first.
+	h2 = f.Sum64() | 1
+	return
+}
+
+func (b *bloomFilter) test(h1, h2 uint64) bool {
+	for i := uint64(0); i < b.k; i++ {
+		pos := (h1 + i*h2) % b.m
+		if b.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *bloomFilter) add(h1, h2 uint64) {
+	for i := uint64(0); i < b.k; i++ {
+		pos := (h1 + i*h2) % b.m
+		b.bits[pos/64] |= 1 << (pos % 64)
+	}
+}
+
+func (b *bloomFilter) reset() {
+	clear(b.bits)
+}
+
+//------------------------------------------------------------------------------
+
+type bloomDedupeProc struct {
+	key       *service.InterpolatedString
+	window    time.Duration
+	dropOnErr bool
+
+	cache    string
+	cacheKey string
+	mgr      *service.Resources
+	log      *service.Logger
+
+	mut       sync.Mutex
+	loaded    bool
+	current   *bloomFilter
+	previous  *bloomFilter
+	rotatedAt time.Time
+	now       func() time.Time
+}
+
+func bloomDedupeProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*bloomDedupeProc, error) {
+	p := &bloomDedupeProc{
+		mgr: mgr,
+		log: mgr.Logger(),
+		now: time.Now,
+	}
+
+	var err error
+	if p.key, err = conf.FieldInterpolatedString(bdFieldKey); err != nil {
+		return nil, err
+	}
+
+	expectedItems, err := conf.FieldInt(bdFieldExpectedItems)
+	if err != nil {
+		return nil, err
+	}
+	if expectedItems < 1 {
+		return nil, fmt.Errorf("field `%v` must be at least 1", bdFieldExpectedItems)
+	}
+	fpRate, err := conf.FieldFloat(bdFieldFalsePositiveRate)
+	if err != nil {
+		return nil, err
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		return nil, fmt.Errorf("field `%v` must be greater than 0 and less than 1", bdFieldFalsePositiveRate)
+	}
+
+	if conf.Contains(bdFieldWindow) {
+		if p.window, err = conf.FieldDuration(bdFieldWindow); err != nil {
+			return nil, err
+		}
+		if p.window <= 0 {
+			return nil, fmt.Errorf("field `%v` must be greater than zero", bdFieldWindow)
+		}
+	}
+
+	if conf.Contains(bdFieldCache) {
+		if p.cache, err = conf.FieldString(bdFieldCache); err != nil {
+			return nil, err
+		}
+		if !mgr.HasCache(p.cache) {
+			return nil, fmt.Errorf("cache resource '%v' was not found", p.cache)
+		}
+	}
+	if p.cacheKey, err = conf.FieldString(bdFieldCacheKey); err != nil {
+		return nil, err
+	}
+	if p.dropOnErr, err = conf.FieldBool(bdFieldDropOnErr); err != nil {
+		return nil, err
+	}
+
+	p.current = newBloomFilter(expectedItems, fpRate)
+	if p.window > 0 {
+		p.previous = newBloomFilterSized(p.current.m, p.current.k)
+	}
+	p.rotatedAt = p.now()
+	return p, nil
+}
+
+//------------------------------------------------------------------------------
+
+// The persisted state is a small header followed by the bits of the current
+// filter and, when windowed, the bits of the previous filter.
+const bloomStateHeaderLen = 8 * 4
+
+func (p *bloomDedupeProc) marshalState() []byte {
+	nFilters := uint64(1)
+	if p.previous != nil {
+		nFilters = 2
+	}
+	b := make([]byte, bloomStateHeaderLen, bloomStateHeaderLen+int(nFilters)*len(p.current.bits)*8)
+	binary.BigEndian.PutUint64(b[0:], p.current.m)
+	binary.BigEndian.PutUint64(b[8:], p.current.k)
+	binary.BigEndian.PutUint64(b[16:], nFilters)
+	binary.BigEndian.PutUint64(b[24:], uint64(p.rotatedAt.UnixNano()))
+	for _, f := range []*bloomFilter{p.current, p.previous} {
+		if f == nil {
+			continue
+		}
+		for _, w := range f.bits {
+			b = binary.BigEndian.AppendUint64(b, w)
+		}
+	}
+	return b
+}
+
+func (p *bloomDedupeProc) unmarshalState(b []byte) error {
+	if len(b) < bloomStateHeaderLen {
+		return errors.New("state is too short")
+	}
+	m, k := binary.BigEndian.Uint64(b[0:]), binary.BigEndian.Uint64(b[8:])
+	nFilters := binary.BigEndian.Uint64(b[16:])
+	if m != p.current.m || k != p.current.k {
+		return fmt.Errorf("stored filters (m=%v, k=%v) do not match the configured size (m=%v, k=%v)", m, k, p.current.m, p.current.k)
+	}
+	if nFilters != 1 && nFilters != 2 {
+		return fmt.Errorf("unexpected number of stored filters: %v", nFilters)
+	}
+
+	words := len(p.current.bits)
+	if len(b) != bloomStateHeaderLen+int(nFilters)*words*8 {
+		return errors.New("state does not match the expected length")
+	}
+	rest := b[bloomStateHeaderLen:]
+	readInto := func(f *bloomFilter) {
+		for i := range f.bits {
+			f.bits[i] = binary.BigEndian.Uint64(rest[i*8:])
+		}
+		rest = rest[words*8:]
+	}
+
+	readInto(p.current)
+	if nFilters == 2 {
+		if p.previous != nil {
+			readInto(p.previous)
+		} else {
+			// Windowing has since been disabled, keep the keys of both
+			// filters within the one we have.
+			prev := newBloomFilterSized(m, k)
+			readInto(prev)
+			for i, w := range prev.bits {
+				p.current.bits[i] |= w
+			}
+		}
+	}
+	p.rotatedAt = time.Unix(0, int64(binary.BigEndian.Uint64(b[24:])))
+	return nil
+}
+
+func (p *bloomDedupeProc) load(ctx context.Context) error {
+	var state []byte
+	var getErr error
+	if err := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		state, getErr = c.Get(ctx, p.cacheKey)
+	}); err != nil {
+		return err
+	}
+	if getErr != nil {
+		if errors.Is(getErr, service.ErrKeyNotFound) {
+			return nil
+		}
+		return getErr
+	}
+	return p.unmarshalState(state)
+}
+
+func (p *bloomDedupeProc) store(ctx context.Context) error {
+	state := p.marshalState()
+	var setErr error
+	if err := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		setErr = c.Set(ctx, p.cacheKey, state, nil)
+	}); err != nil {
+		return err
+	}
+	return setErr
+}
+
+// rotate discards the previous filter once the window has elapsed, and clears
+// both filters when it has elapsed twice over. Must be called whilst holding
+// the lock, and returns true if the filters were changed.
+func (p *bloomDedupeProc) rotate() bool {
+	if p.window <= 0 {
+		return false
+	}
+	elapsed := p.now().Sub(p.rotatedAt)
+	if elapsed < p.window {
+		return false
+	}
+	if elapsed >= 2*p.window {
+		p.current.reset()
+		p.previous.reset()
+	} else {
+		p.previous.reset()
+		p.previous, p.current = p.current, p.previous
+	}
+	p.rotatedAt = p.now()
+	return true
+}
+
+func (p *bloomDedupeProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	keys := make([][]byte, len(batch))
+	keyErrs := make([]error, len(batch))
+	for i := range batch {
+		keys[i], keyErrs[i] = batch.TryInterpolatedBytes(i, p.key)
+	}
+
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if p.cache != "" && !p.loaded {
+		if err := p.load(ctx); err != nil {
+			return nil, fmt.Errorf("failed to load filters from cache: %w", err)
+		}
+		p.loaded = true
+	}
+
+	if p.rotate() && p.cache != "" {
+		if err := p.store(ctx); err != nil {
+			p.log.Errorf("Failed to store filters in cache: %v", err)
+		}
+	}
+
+	newBatch := make(service.MessageBatch, 0, len(batch))
+	for i, msg := range batch {
+		if keyErrs[i] != nil {
+			if p.dropOnErr {
+				p.log.Debugf("Dropping message due to key interpolation error: %v", keyErrs[i])
+				continue
+			}
+			msg.SetError(fmt.Errorf("key interpolation error: %w", keyErrs[i]))
+			newBatch = append(newBatch, msg)
+			continue
+		}
+
+		h1, h2 := bloomHashes(keys[i])
+		if p.current.test(h1, h2) || (p.previous != nil && p.previous.test(h1, h2)) {
+			continue
+		}
+		p.current.add(h1, h2)
+		newBatch = append(newBatch, msg)
+	}
+
+	if len(newBatch) == 0 {
+		return nil, nil
+	}
+	return []service.MessageBatch{newBatch}, nil
+}
+
+func (p *bloomDedupeProc) Close(ctx context.Context) error {
+	if p.cache == "" {
+		return nil
+	}
+
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if !p.loaded {
+		return nil
+	}
+	return p.store(ctx)
+}
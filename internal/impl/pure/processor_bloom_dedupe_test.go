@@ -0,0 +1,194 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func bloomDedupeBatch(t testing.TB, proc *bloomDedupeProc, contents ...string) []string {
+	t.Helper()
+
+	var batch service.MessageBatch
+	for _, c := range contents {
+		batch = append(batch, service.NewMessage([]byte(c)))
+	}
+	res, err := proc.ProcessBatch(context.Background(), batch)
+	require.NoError(t, err)
+	if len(res) == 0 {
+		return nil
+	}
+	require.Len(t, res, 1)
+	return batchContents(t, res[0])
+}
+
+func TestBloomDedupe(t *testing.T) {
+	conf, err := bloomDedupeProcConfig().ParseYAML(`
+key: ${! content() }
+expected_items: 1000
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := bloomDedupeProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"a", "b", "c"}, bloomDedupeBatch(t, proc, "a", "b", "a", "c", "b"))
+	assert.Equal(t, []string{"d"}, bloomDedupeBatch(t, proc, "c", "d", "a"))
+	assert.Nil(t, bloomDedupeBatch(t, proc, "a", "b", "c", "d"))
+}
+
+func TestBloomDedupeFalsePositiveRate(t *testing.T) {
+	f := newBloomFilter(10000, 0.01)
+	for i := 0; i < 10000; i++ {
+		f.add(bloomHashes([]byte(fmt.Sprintf("in-%v", i))))
+	}
+	for i := 0; i < 10000; i++ {
+		require.True(t, f.test(bloomHashes([]byte(fmt.Sprintf("in-%v", i)))))
+	}
+
+	var falsePositives int
+	for i := 0; i < 10000; i++ {
+		if f.test(bloomHashes([]byte(fmt.Sprintf("out-%v", i)))) {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, 200)
+}
+
+func TestBloomDedupeWindow(t *testing.T) {
+	conf, err := bloomDedupeProcConfig().ParseYAML(`
+key: ${! content() }
+expected_items: 1000
+window: 1m
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := bloomDedupeProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	now := time.Now()
+	proc.now = func() time.Time { return now }
+	proc.rotatedAt = now
+
+	assert.Equal(t, []string{"a"}, bloomDedupeBatch(t, proc, "a"))
+
+	// After one window the key is still remembered by the previous filter.
+	now = now.Add(time.Minute)
+	assert.Equal(t, []string{"b"}, bloomDedupeBatch(t, proc, "a", "b"))
+
+	// After another window the first key has been forgotten.
+	now = now.Add(time.Minute)
+	assert.Equal(t, []string{"a"}, bloomDedupeBatch(t, proc, "a", "b"))
+
+	// After two windows of inactivity everything is forgotten.
+	now = now.Add(3 * time.Minute)
+	assert.Equal(t, []string{"a", "b"}, bloomDedupeBatch(t, proc, "a", "b"))
+}
+
+func TestBloomDedupeKeyError(t *testing.T) {
+	conf, err := bloomDedupeProcConfig().ParseYAML(`
+key: ${! json("id") }
+expected_items: 1000
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := bloomDedupeProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{`{"id":"a"}`}, bloomDedupeBatch(t, proc, `{"id":"a"}`, `not json`))
+
+	pConf, err := bloomDedupeProcConfig().ParseYAML(`
+key: ${! json("id") }
+expected_items: 1000
+drop_on_err: false
+`, nil)
+	require.NoError(t, err)
+
+	proc, err = bloomDedupeProcFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	res, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`not json`)),
+	})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 1)
+	assert.Error(t, res[0][0].GetError())
+}
+
+func TestBloomDedupePersisted(t *testing.T) {
+	mgr := service.MockResources(service.MockResourcesOptAddCache("foocache"))
+	conf := `
+key: ${! content() }
+expected_items: 1000
+window: 1h
+cache: foocache
+`
+
+	pConf, err := bloomDedupeProcConfig().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	proc, err := bloomDedupeProcFromParsed(pConf, mgr)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"a", "b"}, bloomDedupeBatch(t, proc, "a", "b"))
+	require.NoError(t, proc.Close(context.Background()))
+
+	pConf, err = bloomDedupeProcConfig().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	proc, err = bloomDedupeProcFromParsed(pConf, mgr)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"c"}, bloomDedupeBatch(t, proc, "a", "b", "c"))
+	require.NoError(t, proc.Close(context.Background()))
+
+	// A differently sized filter can't make use of the stored state.
+	pConf, err = bloomDedupeProcConfig().ParseYAML(`
+key: ${! content() }
+expected_items: 5000
+cache: foocache
+`, nil)
+	require.NoError(t, err)
+
+	proc, err = bloomDedupeProcFromParsed(pConf, mgr)
+	require.NoError(t, err)
+
+	_, err = proc.ProcessBatch(context.Background(), service.MessageBatch{service.NewMessage([]byte("a"))})
+	require.Error(t, err)
+}
+
+func TestBloomDedupeBadConfig(t *testing.T) {
+	for _, conf := range []string{
+		"key: foo\nexpected_items: 0",
+		"key: foo\nfalse_positive_rate: 0",
+		"key: foo\nfalse_positive_rate: 1.5",
+		"key: foo\ncache: nope",
+	} {
+		pConf, err := bloomDedupeProcConfig().ParseYAML(conf, nil)
+		require.NoError(t, err, conf)
+
+		_, err = bloomDedupeProcFromParsed(pConf, service.MockResources())
+		require.Error(t, err, conf)
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Jeffail/gabs/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	cmgFieldResource    = "resource"
+	cmgFieldKey         = "key"
+	cmgFieldTargetPath  = "target_path"
+	cmgFieldParallelism = "parallelism"
+)
+
+func cacheMultiGetProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Integration").
+		Version("4.31.0").
+		Summary("Obtains the values of keys from a cache resource for an entire batch of messages at once, and writes each value to the message it was obtained for.").
+		Description(`
+This processor is an alternative to the `+"`get`"+` operator of the `+"xref:components:processors/cache.adoc[`cache` processor]"+` for enriching large batches of messages. The key of each message in a batch is resolved up front, duplicate keys are only requested once, and the requests are performed concurrently up to the limit set by `+"`parallelism`"+`. This significantly reduces the latency of a batch when the cache is a remote service.
+
+When `+"`target_path`"+` is set the value is written to that path within the JSON document of the message, where values that are valid JSON are inserted as structured data and all other values are inserted as strings. Otherwise the contents of the message are replaced with the value.
+
+Messages with keys that do not exist within the cache are left unchanged, and messages with keys that could not be obtained due to any other error are flagged as failed and can be handled using xref:configuration:error_handling.adoc[error handling methods].`).
+		Field(service.NewStringField(cmgFieldResource).
+			Description("The xref:components:caches/about.adoc[`cache` resource] to obtain values from.")).
+		Field(service.NewInterpolatedStringField(cmgFieldKey).
+			Description("A key to obtain for each message.").
+			Example(`${! json("user_id") }`)).
+		Field(service.NewStringField(cmgFieldTargetPath).
+			Description("An optional xref:configuration:field_paths.adoc[dot separated path] to write the value of each key to within its message.").
+			Example("user.profile").
+			Optional()).
+		Field(service.NewIntField(cmgFieldParallelism).
+			Description("The maximum number of keys to obtain from the cache concurrently.").
+			Default(10).
+			Advanced()).
+		Example("Enrich users", "Add a user profile from a Redis cache to each message of a batch.", `
+pipeline:
+  processors:
+    - cache_multi_get:
+        resource: profiles
+        key: ${! json("user_id") }
+        target_path: user.profile
+
+cache_resources:
+  - label: profiles
+    redis:
+      url: redis://localhost:6379
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"cache_multi_get", cacheMultiGetProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return cacheMultiGetProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type cacheMultiGetProc struct {
+	resource    string
+	key         *service.InterpolatedString
+	targetPath  string
+	parallelism int
+
+	accessCache func(ctx context.Context, fn func(c service.Cache)) error
+}
+
+func cacheMultiGetProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*cacheMultiGetProc, error) {
+	p := &cacheMultiGetProc{}
+
+	var err error
+	if p.resource, err = conf.FieldString(cmgFieldResource); err != nil {
+		return nil, err
+	}
+	if !mgr.HasCache(p.resource) {
+		return nil, fmt.Errorf("cache resource '%v' was not found", p.resource)
+	}
+	p.accessCache = func(ctx context.Context, fn func(c service.Cache)) error {
+		return mgr.AccessCache(ctx, p.resource, fn)
+	}
+	if p.key, err = conf.FieldInterpolatedString(cmgFieldKey); err != nil {
+		return nil, err
+	}
+	if conf.Contains(cmgFieldTargetPath) {
+		if p.targetPath, err = conf.FieldString(cmgFieldTargetPath); err != nil {
+			return nil, err
+		}
+	}
+	if p.parallelism, err = conf.FieldInt(cmgFieldParallelism); err != nil {
+		return nil, err
+	}
+	if p.parallelism < 1 {
+		return nil, errors.New("parallelism must be at least 1")
+	}
+	return p, nil
+}
+
+func (p *cacheMultiGetProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	msgKeys := make([]string, len(batch))
+	keyErrs := make([]error, len(batch))
+
+	var uniqueKeys []string
+	seen := map[string]struct{}{}
+	for i := range batch {
+		if msgKeys[i], keyErrs[i] = batch.TryInterpolatedString(i, p.key); keyErrs[i] != nil {
+			continue
+		}
+		if _, exists := seen[msgKeys[i]]; !exists {
+			seen[msgKeys[i]] = struct{}{}
+			uniqueKeys = append(uniqueKeys, msgKeys[i])
+		}
+	}
+
+	results, err := cacheGetAll(ctx, p.accessCache, p.parallelism, uniqueKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, msg := range batch {
+		if keyErrs[i] != nil {
+			msg.SetError(fmt.Errorf("key interpolation error: %w", keyErrs[i]))
+			continue
+		}
+
+		res := results[msgKeys[i]]
+		if errors.Is(res.err, service.ErrKeyNotFound) {
+			continue
+		}
+		if res.err != nil {
+			msg.SetError(res.err)
+			continue
+		}
+
+		if p.targetPath == "" {
+			msg.SetBytes(res.value)
+			continue
+		}
+		if err := p.setTarget(msg, res.value); err != nil {
+			msg.SetError(err)
+		}
+	}
+	return []service.MessageBatch{batch}, nil
+}
+
+func (p *cacheMultiGetProc) setTarget(msg *service.Message, value []byte) error {
+	var v any
+	if err := json.Unmarshal(value, &v); err != nil {
+		v = string(value)
+	}
+
+	root, err := msg.AsStructuredMut()
+	if err != nil {
+		return fmt.Errorf("failed to parse message as JSON: %w", err)
+	}
+
+	gObj := gabs.Wrap(root)
+	if _, err := gObj.SetP(v, p.targetPath); err != nil {
+		return fmt.Errorf("failed to set target path: %w", err)
+	}
+	msg.SetStructuredMut(gObj.Data())
+	return nil
+}
+
+func (p *cacheMultiGetProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,161 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type countingCache struct {
+	mut    sync.Mutex
+	values map[string][]byte
+	gets   map[string]int
+}
+
+func (c *countingCache) Get(ctx context.Context, key string) ([]byte, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.gets[key]++
+	if key == "broken" {
+		return nil, errors.New("cache is broken")
+	}
+	v, exists := c.values[key]
+	if !exists {
+		return nil, service.ErrKeyNotFound
+	}
+	return v, nil
+}
+
+func (c *countingCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	return errors.New("not implemented")
+}
+
+func (c *countingCache) Add(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	return errors.New("not implemented")
+}
+
+func (c *countingCache) Delete(ctx context.Context, key string) error {
+	return errors.New("not implemented")
+}
+
+func (c *countingCache) Close(ctx context.Context) error {
+	return nil
+}
+
+func TestCacheMultiGetTargetPath(t *testing.T) {
+	conf, err := cacheMultiGetProcConfig().ParseYAML(`
+resource: foocache
+key: ${! json("id") }
+target_path: result.value
+parallelism: 2
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := cacheMultiGetProcFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.NoError(t, err)
+
+	c := &countingCache{
+		values: map[string][]byte{
+			"a": []byte(`{"name":"foo"}`),
+			"b": []byte(`bar`),
+		},
+		gets: map[string]int{},
+	}
+	proc.accessCache = func(ctx context.Context, fn func(c service.Cache)) error {
+		fn(c)
+		return nil
+	}
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a"}`)),
+		service.NewMessage([]byte(`{"id":"b"}`)),
+		service.NewMessage([]byte(`{"id":"c"}`)),
+		service.NewMessage([]byte(`{"id":"a"}`)),
+	}
+
+	res, err := proc.ProcessBatch(context.Background(), batch)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	assert.Equal(t, []string{
+		`{"id":"a","result":{"value":{"name":"foo"}}}`,
+		`{"id":"b","result":{"value":"bar"}}`,
+		`{"id":"c"}`,
+		`{"id":"a","result":{"value":{"name":"foo"}}}`,
+	}, batchContents(t, res[0]))
+	for _, m := range res[0] {
+		assert.NoError(t, m.GetError())
+	}
+
+	// Each unique key is only obtained once.
+	assert.Equal(t, map[string]int{"a": 1, "b": 1, "c": 1}, c.gets)
+}
+
+func TestCacheMultiGetReplace(t *testing.T) {
+	conf, err := cacheMultiGetProcConfig().ParseYAML(`
+resource: foocache
+key: ${! content() }
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := cacheMultiGetProcFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.NoError(t, err)
+
+	c := &countingCache{
+		values: map[string][]byte{
+			"a": []byte(`{"name":"foo"}`),
+			"b": []byte(`bar`),
+		},
+		gets: map[string]int{},
+	}
+	proc.accessCache = func(ctx context.Context, fn func(c service.Cache)) error {
+		fn(c)
+		return nil
+	}
+
+	res, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`a`)),
+		service.NewMessage([]byte(`nope`)),
+		service.NewMessage([]byte(`broken`)),
+	})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, []string{`{"name":"foo"}`, `nope`, `broken`}, batchContents(t, res[0]))
+
+	assert.NoError(t, res[0][0].GetError())
+	assert.NoError(t, res[0][1].GetError())
+	assert.EqualError(t, res[0][2].GetError(), "cache is broken")
+}
+
+func TestCacheMultiGetMissingResource(t *testing.T) {
+	pConf, err := cacheMultiGetProcConfig().ParseYAML(`
+resource: nope
+key: ${! content() }
+`, nil)
+	require.NoError(t, err)
+
+	_, err = cacheMultiGetProcFromParsed(pConf, service.MockResources())
+	require.Error(t, err)
+}
@@ -0,0 +1,281 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func canonicalizeJSONProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Parsing").
+		Version("4.31.0").
+		Summary("Rewrites JSON documents in the canonical form defined by https://www.rfc-editor.org/rfc/rfc8785[RFC 8785^] (JSON Canonicalization Scheme), so that equivalent documents are always serialized to identical bytes.").
+		Description(`
+Canonical documents are suitable as the input of hashes and signatures, since any two documents that represent the same data produce the same output regardless of the system that serialized them. The canonical form is compact, with object keys sorted recursively by their UTF-16 code units, strings escaped only where required, and numbers serialized in the shortest form that represents the same IEEE 754 double precision value, in the same way as the JavaScript function `+"`JSON.stringify`"+`.
+
+Since all numbers are treated as double precision values, integers with a magnitude greater than 2^53 lose precision, and should be represented as strings within documents that need to be canonicalized.
+
+Messages that are not valid JSON documents, contain duplicate object keys, or contain numbers that cannot be represented as a double precision value, are flagged as failed and can be handled using xref:configuration:error_handling.adoc[error handling methods].`).
+		Example("Signing payloads", "Compute a stable hash of a document without modifying it by canonicalizing a copy within a branch.", `
+pipeline:
+  processors:
+    - branch:
+        processors:
+          - canonicalize_json: {}
+        result_map: 'meta content_hash = content().hash("sha256").encode("hex")'
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"canonicalize_json", canonicalizeJSONProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return &canonicalizeJSONProc{}, nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type canonicalizeJSONProc struct{}
+
+func (p *canonicalizeJSONProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	mBytes, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	res, err := canonicalizeJSON(mBytes)
+	if err != nil {
+		return nil, err
+	}
+	msg.SetBytes(res)
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *canonicalizeJSONProc) Close(ctx context.Context) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// canonicalizeJSON parses a JSON document and serializes it according to RFC
+// 8785. The document is parsed token by token rather than into a map so that
+// duplicate keys can be detected, and numbers are kept as their original
+// representation until they are serialized.
+func canonicalizeJSON(b []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var buf bytes.Buffer
+	if err := jcsWriteValue(&buf, dec); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected data after the end of the JSON document")
+	}
+	return buf.Bytes(), nil
+}
+
+func jcsWriteValue(buf *bytes.Buffer, dec *json.Decoder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return errors.New("unexpected end of JSON document")
+		}
+		return err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			return jcsWriteObject(buf, dec)
+		case '[':
+			return jcsWriteArray(buf, dec)
+		}
+		return fmt.Errorf("unexpected delimiter: %v", t)
+	case string:
+		jcsWriteString(buf, t)
+	case json.Number:
+		s, err := jcsFormatNumber(t)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(s)
+	case bool:
+		if t {
+			buf.WriteString("true")
+		} else {
+			buf.WriteString("false")
+		}
+	case nil:
+		buf.WriteString("null")
+	default:
+		return fmt.Errorf("unexpected token: %v", t)
+	}
+	return nil
+}
+
+func jcsWriteArray(buf *bytes.Buffer, dec *json.Decoder) error {
+	buf.WriteByte('[')
+	for i := 0; dec.More(); i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := jcsWriteValue(buf, dec); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	buf.WriteByte(']')
+	return nil
+}
+
+type jcsMember struct {
+	key     string
+	keyUTF  []uint16
+	encoded []byte
+}
+
+func jcsWriteObject(buf *bytes.Buffer, dec *json.Decoder) error {
+	var members []jcsMember
+	seen := map[string]struct{}{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return fmt.Errorf("unexpected object key: %v", tok)
+		}
+		if _, exists := seen[key]; exists {
+			return fmt.Errorf("duplicate object key: %v", key)
+		}
+		seen[key] = struct{}{}
+
+		var valueBuf bytes.Buffer
+		if err := jcsWriteValue(&valueBuf, dec); err != nil {
+			return err
+		}
+		members = append(members, jcsMember{
+			key:     key,
+			keyUTF:  utf16.Encode([]rune(key)),
+			encoded: valueBuf.Bytes(),
+		})
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		a, b := members[i].keyUTF, members[j].keyUTF
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+
+	buf.WriteByte('{')
+	for i, m := range members {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		jcsWriteString(buf, m.key)
+		buf.WriteByte(':')
+		buf.Write(m.encoded)
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+func jcsWriteString(buf *bytes.Buffer, s string) {
+	const hex = "0123456789abcdef"
+
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hex[r>>4])
+				buf.WriteByte(hex[r&0xF])
+			} else {
+				var runeBuf [utf8.UTFMax]byte
+				n := utf8.EncodeRune(runeBuf[:], r)
+				buf.Write(runeBuf[:n])
+			}
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// jcsFormatNumber serializes a number in the same way as the ECMAScript
+// Number.prototype.toString method, as required by RFC 8785.
+func jcsFormatNumber(n json.Number) (string, error) {
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return "", fmt.Errorf("number cannot be represented as a double precision value: %v", n)
+	}
+	if f == 0 {
+		return "0", nil
+	}
+
+	abs := math.Abs(f)
+	if abs >= 1e-6 && abs < 1e21 {
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	}
+
+	// Go produces exponents with at least two digits, e.g. 1e-07, whereas
+	// ECMAScript uses the minimum number of digits.
+	s := strconv.FormatFloat(f, 'e', -1, 64)
+	mantissa, exp, _ := strings.Cut(s, "e")
+	sign := exp[:1]
+	exp = strings.TrimLeft(exp[1:], "0")
+	return mantissa + "e" + sign + exp, nil
+}
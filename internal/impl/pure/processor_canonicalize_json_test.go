@@ -0,0 +1,125 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestCanonicalizeJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{
+			name: "rfc example",
+			input: `{
+  "numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+  "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+  "literals": [null, true, false]
+}`,
+			output: `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`,
+		},
+		{
+			name: "rfc sorting",
+			input: `{
+  "\u20ac": "Euro Sign",
+  "\r": "Carriage Return",
+  "\ufb33": "Hebrew Letter Dalet With Dagesh",
+  "1": "One",
+  "\ud83d\ude00": "Emoji: Grinning Face",
+  "\u0080": "Control",
+  "\u00f6": "Latin Small Letter O With Diaeresis"
+}`,
+			output: "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"\u00f6\":\"Latin Small Letter O With Diaeresis\",\"\u20ac\":\"Euro Sign\",\"\U0001F600\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}",
+		},
+		{
+			name:   "nested",
+			input:  `{"b":[{"z":1,"a":2}],"a":{"d":{},"c":[]}}`,
+			output: `{"a":{"c":[],"d":{}},"b":[{"a":2,"z":1}]}`,
+		},
+		{
+			name:   "html characters are not escaped",
+			input:  `"<a href=\"x\">&</a>\u2028"`,
+			output: "\"<a href=\\\"x\\\">&</a>\u2028\"",
+		},
+	}
+
+	proc := &canonicalizeJSONProc{}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			res, err := proc.Process(context.Background(), service.NewMessage([]byte(test.input)))
+			require.NoError(t, err)
+			require.Len(t, res, 1)
+
+			mBytes, err := res[0].AsBytes()
+			require.NoError(t, err)
+			assert.Equal(t, test.output, string(mBytes))
+		})
+	}
+}
+
+func TestCanonicalizeJSONNumbers(t *testing.T) {
+	for input, exp := range map[string]string{
+		"0":                      "0",
+		"-0":                     "0",
+		"-0.0":                   "0",
+		"5e-324":                 "5e-324",
+		"1.7976931348623157e308": "1.7976931348623157e+308",
+		"9007199254740992":       "9007199254740992",
+		"-9007199254740992":      "-9007199254740992",
+		"295147905179352830000":  "295147905179352830000",
+		"1e21":                   "1e+21",
+		"1e23":                   "1e+23",
+		"999999999999999999999":  "1e+21",
+		"0.000001":               "0.000001",
+		"0.0000001":              "1e-7",
+		"-1.5e-7":                "-1.5e-7",
+		"333333333.3333332":      "333333333.3333332",
+		"100":                    "100",
+		"1.0":                    "1",
+	} {
+		res, err := jcsFormatNumber(json.Number(input))
+		require.NoError(t, err, input)
+		assert.Equal(t, exp, res, input)
+	}
+
+	_, err := jcsFormatNumber(json.Number("1e400"))
+	require.Error(t, err)
+}
+
+func TestCanonicalizeJSONErrors(t *testing.T) {
+	proc := &canonicalizeJSONProc{}
+	for _, input := range []string{
+		`not json`,
+		`{"a":1,"a":2}`,
+		`{"a":1e999}`,
+		`{"a":1} {"b":2}`,
+		`{"a":`,
+		``,
+	} {
+		_, err := proc.Process(context.Background(), service.NewMessage([]byte(input)))
+		require.Error(t, err, input)
+	}
+}
@@ -0,0 +1,403 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	cdFieldKey       = "key"
+	cdFieldValue     = "value"
+	cdFieldWindow    = "window"
+	cdFieldCache     = "cache"
+	cdFieldPrecision = "precision"
+)
+
+func cardinalityProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Estimates the number of distinct values of each key within tumbling windows using HyperLogLog sketches stored in a cache, and emits the estimate of each window once it closes.").
+		Description(`
+Messages are counted by adding their `+"`value`"+` to the https://en.wikipedia.org/wiki/HyperLogLog[HyperLogLog^] sketch of their key and window, and are then dropped. Windows are aligned to the Unix epoch and assigned by the time messages are processed. Once a window has closed a message is emitted for each key counted within it:
+
+`+"```json"+`
+{"key":"example.com","window_start":"2024-01-02T03:04:00Z","window_end":"2024-01-02T03:05:00Z","count":1834}
+`+"```"+`
+
+Closed windows are detected when a batch is processed, and therefore their results are emitted along with the results of the next batch to arrive after they closed.
+
+== Accuracy
+
+The memory used by each sketch is 2 to the power of `+"`precision`"+` bytes, and the standard error of the estimates is approximately 1.04 divided by the square root of that number, which for the default precision of 14 is 16KiB and 0.81%. Small counts are estimated with linear counting and are usually exact.
+
+== Multiple instances
+
+Sketches are stored in the cache under the key and the start of their window, with a TTL of two windows, and each batch is merged into the stored sketch. When multiple instances of the processor share a cache, such as a `+"xref:components:caches/redis.adoc[`redis`]"+` cache, their sketches are merged and the count of each window covers all of the instances. Each instance emits the count of the merged sketch when a window closes, and so the count emitted last for a key and window is the most complete.
+
+A sketch is read and written by separate cache operations, and therefore instances that update the same sketch at the same time can lose values of each other.`).
+		Field(service.NewInterpolatedStringField(cdFieldKey).
+			Description("An interpolated string that resolves to the key to count distinct values of.").
+			Example(`${! this.site }`)).
+		Field(service.NewInterpolatedStringField(cdFieldValue).
+			Description("An interpolated string that resolves to the value to count.").
+			Example(`${! this.visitor_id }`)).
+		Field(service.NewDurationField(cdFieldWindow).
+			Description("The period of each tumbling window.").
+			Example("1m").
+			Example("1h")).
+		Field(service.NewStringField(cdFieldCache).
+			Description("The xref:components:caches/about.adoc[`cache` resource] to store sketches in.")).
+		Field(service.NewIntField(cdFieldPrecision).
+			Description("The precision of sketches between 4 and 18, where higher precisions are more accurate and use more memory. All instances sharing a cache must use the same precision.").
+			Default(14).
+			Advanced()).
+		Example("Unique visitors", "Count the unique visitors of each site every minute.", `
+pipeline:
+  processors:
+    - cardinality:
+        key: ${! this.site }
+        value: ${! this.visitor_id }
+        window: 1m
+        cache: sketches
+
+cache_resources:
+  - label: sketches
+    redis:
+      url: redis://localhost:6379
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"cardinality", cardinalityProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return cardinalityProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// hyperLogLog is a HyperLogLog sketch with a register for each of the 2^p
+// buckets of 64 bit hashes.
+type hyperLogLog struct {
+	p         uint8
+	registers []uint8
+}
+
+const hllVersion = 1
+
+func newHyperLogLog(p uint8) *hyperLogLog {
+	return &hyperLogLog{p: p, registers: make([]uint8, 1<<p)}
+}
+
+func (h *hyperLogLog) add(hash uint64) {
+	idx := hash >> (64 - h.p)
+	// The rank is the position of the first set bit of the remaining bits,
+	// which are padded with a set bit to cap it.
+	w := hash<<h.p | 1<<(h.p-1)
+	if rank := uint8(bits.LeadingZeros64(w)) + 1; rank > h.registers[idx] {
+		h.registers[idx] = rank
+	}
+}
+
+func (h *hyperLogLog) merge(o *hyperLogLog) error {
+	if o.p != h.p {
+		return fmt.Errorf("cannot merge a sketch of precision %v with a sketch of precision %v", o.p, h.p)
+	}
+	for i, r := range o.registers {
+		if r > h.registers[i] {
+			h.registers[i] = r
+		}
+	}
+	return nil
+}
+
+func (h *hyperLogLog) estimate() uint64 {
+	m := float64(len(h.registers))
+
+	var sum float64
+	zeros := 0
+	for _, r := range h.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	var alpha float64
+	switch len(h.registers) {
+	case 16:
+		alpha = 0.673
+	case 32:
+		alpha = 0.697
+	case 64:
+		alpha = 0.709
+	default:
+		alpha = 0.7213 / (1 + 1.079/m)
+	}
+
+	e := alpha * m * m / sum
+	if e <= 2.5*m && zeros > 0 {
+		e = m * math.Log(m/float64(zeros))
+	}
+	return uint64(math.Round(e))
+}
+
+func (h *hyperLogLog) marshal() []byte {
+	b := make([]byte, 2+len(h.registers))
+	b[0], b[1] = hllVersion, h.p
+	copy(b[2:], h.registers)
+	return b
+}
+
+func unmarshalHyperLogLog(b []byte) (*hyperLogLog, error) {
+	if len(b) < 2 || b[0] != hllVersion {
+		return nil, errors.New("unrecognised sketch encoding")
+	}
+	p := b[1]
+	if p < 4 || p > 18 || len(b) != 2+(1<<p) {
+		return nil, errors.New("malformed sketch")
+	}
+	h := newHyperLogLog(p)
+	copy(h.registers, b[2:])
+	return h, nil
+}
+
+//------------------------------------------------------------------------------
+
+type cardinalityWindow struct {
+	key   string
+	start time.Time
+}
+
+type cardinalityProc struct {
+	key       *service.InterpolatedString
+	value     *service.InterpolatedString
+	window    time.Duration
+	cache     string
+	precision uint8
+
+	// The windows of each key counted by this instance that are yet to close,
+	// indexed by their cache key.
+	openMut sync.Mutex
+	open    map[string]cardinalityWindow
+
+	now func() time.Time
+	mgr *service.Resources
+}
+
+func cardinalityProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*cardinalityProc, error) {
+	p := &cardinalityProc{
+		open: map[string]cardinalityWindow{},
+		now:  time.Now,
+		mgr:  mgr,
+	}
+
+	var err error
+	if p.key, err = conf.FieldInterpolatedString(cdFieldKey); err != nil {
+		return nil, err
+	}
+	if p.value, err = conf.FieldInterpolatedString(cdFieldValue); err != nil {
+		return nil, err
+	}
+	if p.window, err = conf.FieldDuration(cdFieldWindow); err != nil {
+		return nil, err
+	}
+	if p.window <= 0 {
+		return nil, errors.New("window must be greater than zero")
+	}
+	if p.cache, err = conf.FieldString(cdFieldCache); err != nil {
+		return nil, err
+	}
+	if !mgr.HasCache(p.cache) {
+		return nil, fmt.Errorf("cache resource '%v' was not found", p.cache)
+	}
+	precision, err := conf.FieldInt(cdFieldPrecision)
+	if err != nil {
+		return nil, err
+	}
+	if precision < 4 || precision > 18 {
+		return nil, errors.New("precision must be between 4 and 18")
+	}
+	p.precision = uint8(precision)
+	return p, nil
+}
+
+func cardinalityCacheKey(key string, start time.Time) string {
+	return fmt.Sprintf("%v:%v", key, start.UnixNano())
+}
+
+func (p *cardinalityProc) getSketch(ctx context.Context, cacheKey string) (*hyperLogLog, error) {
+	var sketch *hyperLogLog
+	var cacheErr error
+	if err := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		sketchBytes, err := c.Get(ctx, cacheKey)
+		if err != nil {
+			if !errors.Is(err, service.ErrKeyNotFound) {
+				cacheErr = err
+			}
+			return
+		}
+		sketch, cacheErr = unmarshalHyperLogLog(sketchBytes)
+	}); err != nil {
+		return nil, err
+	}
+	if cacheErr != nil {
+		return nil, fmt.Errorf("failed to obtain sketch %v: %w", cacheKey, cacheErr)
+	}
+	return sketch, nil
+}
+
+// mergeSketch merges a sketch into the sketch stored in the cache.
+func (p *cardinalityProc) mergeSketch(ctx context.Context, cacheKey string, sketch *hyperLogLog) error {
+	stored, err := p.getSketch(ctx, cacheKey)
+	if err != nil {
+		return err
+	}
+	if stored != nil {
+		if err := sketch.merge(stored); err != nil {
+			return err
+		}
+	}
+
+	ttl := 2 * p.window
+	var cacheErr error
+	if err := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		cacheErr = c.Set(ctx, cacheKey, sketch.marshal(), &ttl)
+	}); err != nil {
+		return err
+	}
+	if cacheErr != nil {
+		return fmt.Errorf("failed to store sketch %v: %w", cacheKey, cacheErr)
+	}
+	return nil
+}
+
+// closeWindows returns a result message for each open window that has
+// closed.
+func (p *cardinalityProc) closeWindows(ctx context.Context, now time.Time) (service.MessageBatch, error) {
+	p.openMut.Lock()
+	var closed []string
+	for cacheKey, w := range p.open {
+		if !w.start.Add(p.window).After(now) {
+			closed = append(closed, cacheKey)
+		}
+	}
+	p.openMut.Unlock()
+
+	// Results are emitted in a stable order.
+	sort.Strings(closed)
+
+	var out service.MessageBatch
+	for _, cacheKey := range closed {
+		p.openMut.Lock()
+		w := p.open[cacheKey]
+		p.openMut.Unlock()
+
+		sketch, err := p.getSketch(ctx, cacheKey)
+		if err != nil {
+			return out, err
+		}
+		var count uint64
+		if sketch != nil {
+			count = sketch.estimate()
+		}
+
+		msg := service.NewMessage(nil)
+		msg.SetStructuredMut(map[string]any{
+			"key":          w.key,
+			"window_start": w.start.UTC().Format(time.RFC3339Nano),
+			"window_end":   w.start.Add(p.window).UTC().Format(time.RFC3339Nano),
+			"count":        count,
+		})
+		out = append(out, msg)
+
+		p.openMut.Lock()
+		delete(p.open, cacheKey)
+		p.openMut.Unlock()
+	}
+	return out, nil
+}
+
+func (p *cardinalityProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	now := p.now()
+
+	out, err := p.closeWindows(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	start := now.Truncate(p.window)
+	sketches := map[string]*hyperLogLog{}
+	var keys []string
+	for _, msg := range batch {
+		key, err := p.key.TryString(msg)
+		if err != nil {
+			msg.SetError(fmt.Errorf("key interpolation error: %w", err))
+			out = append(out, msg)
+			continue
+		}
+		value, err := p.value.TryString(msg)
+		if err != nil {
+			msg.SetError(fmt.Errorf("value interpolation error: %w", err))
+			out = append(out, msg)
+			continue
+		}
+
+		sketch, exists := sketches[key]
+		if !exists {
+			sketch = newHyperLogLog(p.precision)
+			sketches[key] = sketch
+			keys = append(keys, key)
+		}
+		sketch.add(xxhash.Sum64String(value))
+	}
+
+	for _, key := range keys {
+		cacheKey := cardinalityCacheKey(key, start)
+		if err := p.mergeSketch(ctx, cacheKey, sketches[key]); err != nil {
+			return nil, err
+		}
+		p.openMut.Lock()
+		p.open[cacheKey] = cardinalityWindow{key: key, start: start}
+		p.openMut.Unlock()
+	}
+
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return []service.MessageBatch{out}, nil
+}
+
+func (p *cardinalityProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,219 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestHyperLogLogAccuracy(t *testing.T) {
+	for _, n := range []int{0, 1, 100, 10000, 200000} {
+		h := newHyperLogLog(14)
+		for i := 0; i < n; i++ {
+			// Duplicates do not affect the estimate.
+			h.add(xxhash.Sum64String(fmt.Sprintf("value-%v", i)))
+			h.add(xxhash.Sum64String(fmt.Sprintf("value-%v", i)))
+		}
+		est := float64(h.estimate())
+		assert.InDelta(t, float64(n), est, math.Max(1, float64(n)*0.03), "n = %v", n)
+	}
+}
+
+func TestHyperLogLogMergeAndMarshal(t *testing.T) {
+	a, b, both := newHyperLogLog(12), newHyperLogLog(12), newHyperLogLog(12)
+	for i := 0; i < 5000; i++ {
+		hash := xxhash.Sum64String(fmt.Sprintf("value-%v", i))
+		if i%2 == 0 {
+			a.add(hash)
+		} else {
+			b.add(hash)
+		}
+		both.add(hash)
+	}
+
+	decoded, err := unmarshalHyperLogLog(a.marshal())
+	require.NoError(t, err)
+	require.NoError(t, decoded.merge(b))
+	assert.Equal(t, both.registers, decoded.registers)
+
+	require.Error(t, decoded.merge(newHyperLogLog(10)))
+
+	_, err = unmarshalHyperLogLog([]byte{1, 12, 0})
+	require.Error(t, err)
+	_, err = unmarshalHyperLogLog([]byte("nope"))
+	require.Error(t, err)
+}
+
+type cardinalityResult struct {
+	Key         string `json:"key"`
+	WindowStart string `json:"window_start"`
+	WindowEnd   string `json:"window_end"`
+	Count       int    `json:"count"`
+}
+
+func cardinalityResults(t testing.TB, proc *cardinalityProc, contents ...string) []cardinalityResult {
+	t.Helper()
+
+	var batch service.MessageBatch
+	for _, c := range contents {
+		batch = append(batch, service.NewMessage([]byte(c)))
+	}
+	batches, err := proc.ProcessBatch(context.Background(), batch)
+	require.NoError(t, err)
+
+	var results []cardinalityResult
+	for _, b := range batches {
+		for _, m := range b {
+			require.NoError(t, m.GetError())
+			mBytes, err := m.AsBytes()
+			require.NoError(t, err)
+
+			var r cardinalityResult
+			require.NoError(t, json.Unmarshal(mBytes, &r))
+			results = append(results, r)
+		}
+	}
+	return results
+}
+
+const cardinalityTestConf = `
+key: ${! this.site }
+value: ${! this.visitor }
+window: 1m
+cache: foocache
+`
+
+func TestCardinalityWindows(t *testing.T) {
+	conf, err := cardinalityProcConfig().ParseYAML(cardinalityTestConf, nil)
+	require.NoError(t, err)
+
+	proc, err := cardinalityProcFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 2, 3, 4, 10, 0, time.UTC)
+	proc.now = func() time.Time { return now }
+
+	assert.Empty(t, cardinalityResults(t, proc,
+		`{"site":"a","visitor":"1"}`,
+		`{"site":"a","visitor":"2"}`,
+		`{"site":"b","visitor":"1"}`,
+	))
+
+	now = now.Add(30 * time.Second)
+	assert.Empty(t, cardinalityResults(t, proc,
+		`{"site":"a","visitor":"1"}`,
+		`{"site":"a","visitor":"3"}`,
+	))
+
+	// The first window has closed, and the message of the next window is
+	// counted separately.
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, []cardinalityResult{
+		{Key: "a", WindowStart: "2024-01-02T03:04:00Z", WindowEnd: "2024-01-02T03:05:00Z", Count: 3},
+		{Key: "b", WindowStart: "2024-01-02T03:04:00Z", WindowEnd: "2024-01-02T03:05:00Z", Count: 1},
+	}, cardinalityResults(t, proc, `{"site":"a","visitor":"1"}`))
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, []cardinalityResult{
+		{Key: "a", WindowStart: "2024-01-02T03:05:00Z", WindowEnd: "2024-01-02T03:06:00Z", Count: 1},
+	}, cardinalityResults(t, proc))
+
+	assert.Empty(t, cardinalityResults(t, proc))
+}
+
+func TestCardinalitySharedCache(t *testing.T) {
+	mgr := service.MockResources(service.MockResourcesOptAddCache("foocache"))
+	conf, err := cardinalityProcConfig().ParseYAML(cardinalityTestConf, nil)
+	require.NoError(t, err)
+
+	procA, err := cardinalityProcFromParsed(conf, mgr)
+	require.NoError(t, err)
+
+	nowA := time.Date(2024, 1, 2, 3, 4, 10, 0, time.UTC)
+	procA.now = func() time.Time { return nowA }
+
+	pConf, err := cardinalityProcConfig().ParseYAML(cardinalityTestConf, nil)
+	require.NoError(t, err)
+
+	procB, err := cardinalityProcFromParsed(pConf, mgr)
+	require.NoError(t, err)
+
+	nowB := time.Date(2024, 1, 2, 3, 4, 10, 0, time.UTC)
+	procB.now = func() time.Time { return nowB }
+
+	assert.Empty(t, cardinalityResults(t, procA, `{"site":"a","visitor":"1"}`, `{"site":"a","visitor":"2"}`))
+	assert.Empty(t, cardinalityResults(t, procB, `{"site":"a","visitor":"2"}`, `{"site":"a","visitor":"3"}`))
+
+	nowA = nowA.Add(time.Minute)
+	nowB = nowB.Add(time.Minute)
+
+	// Both instances emit the count of the merged sketch.
+	expected := []cardinalityResult{
+		{Key: "a", WindowStart: "2024-01-02T03:04:00Z", WindowEnd: "2024-01-02T03:05:00Z", Count: 3},
+	}
+	assert.Equal(t, expected, cardinalityResults(t, procA))
+	assert.Equal(t, expected, cardinalityResults(t, procB))
+}
+
+func TestCardinalityInterpolationErrors(t *testing.T) {
+	conf, err := cardinalityProcConfig().ParseYAML(cardinalityTestConf, nil)
+	require.NoError(t, err)
+
+	proc, err := cardinalityProcFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 2, 3, 4, 10, 0, time.UTC)
+	proc.now = func() time.Time { return now }
+
+	batches, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`not json`)),
+		service.NewMessage([]byte(`{"site":"a","visitor":"1"}`)),
+	})
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	require.Len(t, batches[0], 1)
+	require.Error(t, batches[0][0].GetError())
+	assert.Contains(t, batches[0][0].GetError().Error(), "key interpolation error")
+}
+
+func TestCardinalityConfigErrors(t *testing.T) {
+	for _, conf := range []string{
+		cardinalityTestConf + "precision: 3\n",
+		cardinalityTestConf + "precision: 19\n",
+		`
+key: ${! this.site }
+value: ${! this.visitor }
+window: 1m
+cache: nope
+`,
+	} {
+		pConf, err := cardinalityProcConfig().ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		_, err = cardinalityProcFromParsed(pConf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+		require.Error(t, err)
+	}
+}
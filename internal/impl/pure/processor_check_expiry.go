@@ -0,0 +1,140 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/expiry"
+)
+
+const (
+	ceFieldTTL = "ttl"
+)
+
+func checkExpiryProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Drops messages that have passed their expiry deadline.").
+		Description(`
+Messages carry an expiry deadline in the metadata key `+"`"+expiry.MetadataKey+"`"+`, which is either an RFC 3339 timestamp or a unix timestamp in seconds. Messages with a deadline that has passed are dropped by this processor, messages without a deadline or with a deadline in the future are passed through unchanged, and messages with a deadline that cannot be parsed are flagged as failed.
+
+When the field `+"`ttl`"+` is set messages that do not yet have a deadline are given one of the current time plus the TTL, and therefore this processor can be placed within the processors of an input in order to start the clock, and again anywhere further along a pipeline in order to drop messages that ran out of time.
+
+=== Propagation
+
+Since the deadline is metadata it is carried along with messages by any output that writes metadata as headers or attributes, and is restored by the matching input of the next hop. In addition the outputs `+"`aws_sqs`"+`, `+"`kafka_franz`"+`, `+"`nats`"+` and `+"`nats_jetstream`"+` drop messages that have expired by the time they are written, and always write the deadline along with the message regardless of their metadata filters. None of these services support a TTL for individual messages natively, and therefore messages that expire whilst waiting to be consumed are only dropped by the next hop.`).
+		Field(service.NewDurationField(ceFieldTTL).
+			Description("An optional TTL to give messages that do not already have a deadline.").
+			Optional().
+			Example("30s").
+			Example("1h")).
+		Example("Deadline aware processing", "Give each message ten seconds to make it through a pipeline, dropping any that take longer either before an expensive enrichment or before being written.", `
+input:
+  http_server:
+    path: /jobs
+  processors:
+    - check_expiry:
+        ttl: 10s
+
+pipeline:
+  processors:
+    - check_expiry: {}
+    - branch:
+        request_map: 'root = this.user_id'
+        processors:
+          - http:
+              url: http://users/lookup
+              verb: POST
+        result_map: 'root.user = this'
+    - check_expiry: {}
+
+output:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topic: jobs
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"check_expiry", checkExpiryProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return checkExpiryProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type checkExpiryProc struct {
+	ttl time.Duration
+	log *service.Logger
+
+	now func() time.Time
+}
+
+func checkExpiryProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*checkExpiryProc, error) {
+	p := &checkExpiryProc{
+		log: mgr.Logger(),
+		now: time.Now,
+	}
+	if conf.Contains(ceFieldTTL) {
+		var err error
+		if p.ttl, err = conf.FieldDuration(ceFieldTTL); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (p *checkExpiryProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	now := p.now()
+
+	kept := make(service.MessageBatch, 0, len(batch))
+	for _, msg := range batch {
+		deadline, exists, err := expiry.FromMessage(msg, expiry.MetadataKey)
+		if err != nil {
+			msg.SetError(err)
+			kept = append(kept, msg)
+			continue
+		}
+		if !exists {
+			if p.ttl > 0 {
+				msg.MetaSetMut(expiry.MetadataKey, expiry.Format(now.Add(p.ttl)))
+			}
+			kept = append(kept, msg)
+			continue
+		}
+		if !deadline.After(now) {
+			p.log.Debugf("Dropping message that expired at %v", expiry.Format(deadline))
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	if len(kept) == 0 {
+		return nil, nil
+	}
+	return []service.MessageBatch{kept}, nil
+}
+
+func (p *checkExpiryProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestCheckExpiryDrops(t *testing.T) {
+	conf, err := checkExpiryProcConfig().ParseYAML(`{}`, nil)
+	require.NoError(t, err)
+
+	proc, err := checkExpiryProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	proc.now = func() time.Time { return time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC) }
+
+	newMsg := func(content, deadline string) *service.Message {
+		msg := service.NewMessage([]byte(content))
+		if deadline != "" {
+			msg.MetaSetMut("benthos_expires_at", deadline)
+		}
+		return msg
+	}
+
+	batches, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		newMsg("a", ""),
+		newMsg("b", "2024-06-01T11:59:59Z"),
+		newMsg("c", "2024-06-01T12:00:01Z"),
+		newMsg("d", "1717243200"),
+		newMsg("e", "soon"),
+	})
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	assert.Equal(t, []string{"a", "c", "e"}, batchContents(t, batches[0]))
+
+	_, exists := batches[0][0].MetaGet("benthos_expires_at")
+	assert.False(t, exists)
+	require.NoError(t, batches[0][1].GetError())
+	require.Error(t, batches[0][2].GetError())
+	assert.Contains(t, batches[0][2].GetError().Error(), "benthos_expires_at")
+
+	batches, err = proc.ProcessBatch(context.Background(), service.MessageBatch{
+		newMsg("f", "2024-06-01T11:00:00Z"),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, batches)
+}
+
+func TestCheckExpiryTTL(t *testing.T) {
+	conf, err := checkExpiryProcConfig().ParseYAML(`ttl: 30s`, nil)
+	require.NoError(t, err)
+
+	proc, err := checkExpiryProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	proc.now = func() time.Time { return now }
+
+	msgA := service.NewMessage([]byte("a"))
+	msgB := service.NewMessage([]byte("b"))
+	msgB.MetaSetMut("benthos_expires_at", "2024-06-01T12:00:05Z")
+
+	batches, err := proc.ProcessBatch(context.Background(), service.MessageBatch{msgA, msgB})
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	require.Len(t, batches[0], 2)
+
+	v, _ := batches[0][0].MetaGet("benthos_expires_at")
+	assert.Equal(t, "2024-06-01T12:00:30Z", v)
+	v, _ = batches[0][1].MetaGet("benthos_expires_at")
+	assert.Equal(t, "2024-06-01T12:00:05Z", v)
+
+	// The deadline of the first message passes and the second message is
+	// dropped.
+	now = now.Add(10 * time.Second)
+	batches, err = proc.ProcessBatch(context.Background(), batches[0])
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	assert.Equal(t, []string{"a"}, batchContents(t, batches[0]))
+}
@@ -0,0 +1,276 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	ccFieldOperator     = "operator"
+	ccFieldResource     = "resource"
+	ccFieldKey          = "key"
+	ccFieldThreshold    = "threshold"
+	ccFieldTTL          = "ttl"
+	ccOperatorStore     = "store"
+	ccOperatorRetrieve  = "retrieve"
+	ccReferenceField    = "claim_check"
+	ccDefaultKeyPattern = `${! uuid_v4() }`
+)
+
+func claimCheckProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Integration").
+		Version("4.31.0").
+		Summary("Implements the claim check pattern, where large message payloads are stored in a cache resource and replaced with a reference, which is used to retrieve them again further downstream.").
+		Description(`
+Storing large payloads outside of a message broker keeps the messages that flow through the broker small, and the `+"xref:components:caches/aws_s3.adoc[`aws_s3`]"+` and `+"xref:components:caches/gcp_cloud_storage.adoc[`gcp_cloud_storage`]"+` caches make suitable stores. The `+"`store`"+` operator writes the contents of each message larger than `+"`threshold`"+` bytes to the cache under `+"`key`"+`, and replaces the contents with a reference of the following form:
+
+`+"```json"+`
+{"claim_check":{"key":"8f8c1e2a-6a3b-4bbf-9c6c-8a0b6c8f4e1d","size":1048576,"sha256":"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"}}
+`+"```"+`
+
+Metadata is not stored and stays with the message. The `+"`retrieve`"+` operator replaces the contents of messages that are references with the payload obtained from the cache, after verifying its size and checksum. Messages that are not references pass through unchanged, and therefore both operators can be applied to streams that contain a mixture of small and large messages.
+
+Payloads are not removed from the cache when they're retrieved, as a message may be delivered more than once. Instead, a `+"`ttl`"+` can be set, or the cache can be configured to expire payloads, for example with an S3 lifecycle rule.
+
+Messages where a payload cannot be stored or retrieved, or where a retrieved payload does not match its reference, are flagged as failed and can be handled using xref:configuration:error_handling.adoc[error handling methods].`).
+		Field(service.NewStringAnnotatedEnumField(ccFieldOperator, map[string]string{
+			ccOperatorStore:    "Store the contents of large messages and replace them with references.",
+			ccOperatorRetrieve: "Replace references with the contents that they refer to.",
+		}).
+			Description("The operation to perform on each message.")).
+		Field(service.NewStringField(ccFieldResource).
+			Description("The xref:components:caches/about.adoc[`cache` resource] to store payloads in.")).
+		Field(service.NewInterpolatedStringField(ccFieldKey).
+			Description("The key to store each payload under, only relevant when the operator is `store`. Keys must be unique to each payload.").
+			Default(ccDefaultKeyPattern).
+			Example(`claims/${! timestamp_unix_nano() }-${! uuid_v4() }`)).
+		Field(service.NewIntField(ccFieldThreshold).
+			Description("The size in bytes that the contents of a message must exceed in order to be stored, only relevant when the operator is `store`.").
+			Default(262144)).
+		Field(service.NewStringField(ccFieldTTL).
+			Description("An optional TTL to set for stored payloads. Not all caches support per-key TTLs.").
+			Example("72h").
+			Optional().
+			Advanced()).
+		Example("Storing large payloads", "Store payloads larger than the SQS message size limit in S3 before sending messages to a queue.", `
+pipeline:
+  processors:
+    - claim_check:
+        operator: store
+        resource: claims
+
+output:
+  aws_sqs:
+    url: https://sqs.us-east-1.amazonaws.com/123456789012/documents
+
+cache_resources:
+  - label: claims
+    aws_s3:
+      bucket: document-claims
+`).
+		Example("Retrieving large payloads", "Retrieve payloads that were stored in S3 when consuming messages from a queue.", `
+input:
+  aws_sqs:
+    url: https://sqs.us-east-1.amazonaws.com/123456789012/documents
+
+pipeline:
+  processors:
+    - claim_check:
+        operator: retrieve
+        resource: claims
+
+cache_resources:
+  - label: claims
+    aws_s3:
+      bucket: document-claims
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"claim_check", claimCheckProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return claimCheckProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type claimCheckRef struct {
+	Key    string `json:"key"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+type claimCheckProc struct {
+	store     bool
+	resource  string
+	key       *service.InterpolatedString
+	threshold int
+	ttl       *time.Duration
+
+	mgr *service.Resources
+}
+
+func claimCheckProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*claimCheckProc, error) {
+	p := &claimCheckProc{mgr: mgr}
+
+	operator, err := conf.FieldString(ccFieldOperator)
+	if err != nil {
+		return nil, err
+	}
+	switch operator {
+	case ccOperatorStore:
+		p.store = true
+	case ccOperatorRetrieve:
+	default:
+		return nil, fmt.Errorf("operator not recognised: %v", operator)
+	}
+
+	if p.resource, err = conf.FieldString(ccFieldResource); err != nil {
+		return nil, err
+	}
+	if !mgr.HasCache(p.resource) {
+		return nil, fmt.Errorf("cache resource '%v' was not found", p.resource)
+	}
+	if p.key, err = conf.FieldInterpolatedString(ccFieldKey); err != nil {
+		return nil, err
+	}
+	if p.threshold, err = conf.FieldInt(ccFieldThreshold); err != nil {
+		return nil, err
+	}
+	if conf.Contains(ccFieldTTL) {
+		ttlStr, err := conf.FieldString(ccFieldTTL)
+		if err != nil {
+			return nil, err
+		}
+		ttl, err := time.ParseDuration(ttlStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ttl: %w", err)
+		}
+		p.ttl = &ttl
+	}
+	return p, nil
+}
+
+func claimCheckChecksum(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// claimCheckParseRef returns the reference within the contents of a message,
+// or nil when the contents are not a reference.
+func claimCheckParseRef(b []byte) *claimCheckRef {
+	var wrapper map[string]*claimCheckRef
+	if err := json.Unmarshal(b, &wrapper); err != nil || len(wrapper) != 1 {
+		return nil
+	}
+	ref := wrapper[ccReferenceField]
+	if ref == nil || ref.Key == "" {
+		return nil
+	}
+	return ref
+}
+
+func (p *claimCheckProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	content, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	if p.store {
+		return p.storePayload(ctx, msg, content)
+	}
+	return p.retrievePayload(ctx, msg, content)
+}
+
+func (p *claimCheckProc) storePayload(ctx context.Context, msg *service.Message, content []byte) (service.MessageBatch, error) {
+	if len(content) <= p.threshold {
+		return service.MessageBatch{msg}, nil
+	}
+
+	key, err := p.key.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("key interpolation error: %w", err)
+	}
+	if key == "" {
+		return nil, errors.New("key resolved to an empty string")
+	}
+
+	var cacheErr error
+	if err := p.mgr.AccessCache(ctx, p.resource, func(c service.Cache) {
+		cacheErr = c.Set(ctx, key, content, p.ttl)
+	}); err != nil {
+		return nil, err
+	}
+	if cacheErr != nil {
+		return nil, fmt.Errorf("failed to store payload %v: %w", key, cacheErr)
+	}
+
+	refBytes, err := json.Marshal(map[string]claimCheckRef{
+		ccReferenceField: {
+			Key:    key,
+			Size:   len(content),
+			SHA256: claimCheckChecksum(content),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	msg.SetBytes(refBytes)
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *claimCheckProc) retrievePayload(ctx context.Context, msg *service.Message, content []byte) (service.MessageBatch, error) {
+	ref := claimCheckParseRef(content)
+	if ref == nil {
+		return service.MessageBatch{msg}, nil
+	}
+
+	var payload []byte
+	var cacheErr error
+	if err := p.mgr.AccessCache(ctx, p.resource, func(c service.Cache) {
+		payload, cacheErr = c.Get(ctx, ref.Key)
+	}); err != nil {
+		return nil, err
+	}
+	if cacheErr != nil {
+		return nil, fmt.Errorf("failed to retrieve payload %v: %w", ref.Key, cacheErr)
+	}
+
+	if len(payload) != ref.Size {
+		return nil, fmt.Errorf("payload %v has a size of %v bytes, expected %v", ref.Key, len(payload), ref.Size)
+	}
+	if ref.SHA256 != "" && claimCheckChecksum(payload) != ref.SHA256 {
+		return nil, fmt.Errorf("payload %v does not match its checksum", ref.Key)
+	}
+	msg.SetBytes(payload)
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *claimCheckProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func claimCheckProcess(t testing.TB, proc *claimCheckProc, content string) string {
+	t.Helper()
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(content)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	b, err := res[0].AsBytes()
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestClaimCheckRoundTrip(t *testing.T) {
+	mgr := service.MockResources(service.MockResourcesOptAddCache("claims"))
+
+	conf, err := claimCheckProcConfig().ParseYAML(`
+operator: store
+resource: claims
+key: ${! json("id") }
+threshold: 20
+`, nil)
+	require.NoError(t, err)
+
+	store, err := claimCheckProcFromParsed(conf, mgr)
+	require.NoError(t, err)
+
+	pConf, err := claimCheckProcConfig().ParseYAML(`
+operator: retrieve
+resource: claims
+`, nil)
+	require.NoError(t, err)
+
+	retrieve, err := claimCheckProcFromParsed(pConf, mgr)
+	require.NoError(t, err)
+
+	small := `{"id":"a"}`
+	assert.Equal(t, small, claimCheckProcess(t, store, small))
+
+	large := `{"id":"b","data":"` + strings.Repeat("x", 100) + `"}`
+	ref := claimCheckProcess(t, store, large)
+	assert.Equal(t, `{"claim_check":{"key":"b","size":120,"sha256":"`+claimCheckChecksum([]byte(large))+`"}}`, ref)
+
+	assert.Equal(t, large, claimCheckProcess(t, retrieve, ref))
+	assert.Equal(t, small, claimCheckProcess(t, retrieve, small))
+	assert.Equal(t, `{"claim_check":"nope"}`, claimCheckProcess(t, retrieve, `{"claim_check":"nope"}`))
+}
+
+func TestClaimCheckRetrieveErrors(t *testing.T) {
+	mgr := service.MockResources(service.MockResourcesOptAddCache("claims"))
+	require.NoError(t, mgr.AccessCache(context.Background(), "claims", func(c service.Cache) {
+		require.NoError(t, c.Set(context.Background(), "tampered", []byte("hello world"), nil))
+	}))
+
+	conf, err := claimCheckProcConfig().ParseYAML(`
+operator: retrieve
+resource: claims
+`, nil)
+	require.NoError(t, err)
+
+	retrieve, err := claimCheckProcFromParsed(conf, mgr)
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		ref    string
+		errStr string
+	}{
+		{
+			ref:    `{"claim_check":{"key":"missing","size":5}}`,
+			errStr: "failed to retrieve payload missing",
+		},
+		{
+			ref:    `{"claim_check":{"key":"tampered","size":5}}`,
+			errStr: "payload tampered has a size of 11 bytes, expected 5",
+		},
+		{
+			ref:    `{"claim_check":{"key":"tampered","size":11,"sha256":"nope"}}`,
+			errStr: "payload tampered does not match its checksum",
+		},
+	} {
+		_, err := retrieve.Process(context.Background(), service.NewMessage([]byte(test.ref)))
+		require.Error(t, err, test.ref)
+		assert.Contains(t, err.Error(), test.errStr)
+	}
+}
@@ -0,0 +1,299 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	coFieldSchema         = "schema"
+	coFieldSchemaType     = "type"
+	coFieldSchemaNullable = "nullable"
+	coFieldSchemaFormat   = "format"
+)
+
+func coerceProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Parsing").
+		Version("4.31.0").
+		Summary("Converts the fields of structured messages to the types of a declared schema.").
+		Description(`
+Each field of the schema is converted to its declared type, where strings are parsed, numbers are converted between integers and floats where this is lossless, and values that already have the declared type are left unchanged. Strings are trimmed of whitespace before being parsed, and booleans are parsed from the values accepted by https://pkg.go.dev/strconv#ParseBool[ParseBool^].
+
+Fields that are missing, `+"`null`"+` or an empty string are set to `+"`null`"+` when the field is nullable, and are otherwise considered uncoercible, with the exception of empty strings for fields of type `+"`string`"+`.
+
+Messages with any field that cannot be coerced are left unchanged and flagged as failed with an error naming each offending field, and can be handled using xref:configuration:error_handling.adoc[error handling].`).
+		Field(service.NewObjectMapField(coFieldSchema,
+			service.NewStringAnnotatedEnumField(coFieldSchemaType, map[string]string{
+				"string":    "A string, where numbers and booleans are formatted.",
+				"int":       "A 64-bit integer.",
+				"float":     "A 64-bit floating point number.",
+				"bool":      "A boolean.",
+				"timestamp": "A timestamp, parsed from strings with `format` or from numbers as unix seconds.",
+			}).Description("The type of the field."),
+			service.NewBoolField(coFieldSchemaNullable).
+				Description("Whether the field may be missing, `null` or an empty string, in which case it is set to `null`.").
+				Default(false),
+			service.NewStringField(coFieldSchemaFormat).
+				Description("The https://pkg.go.dev/time#pkg-constants[layout^] used to parse timestamps from strings.").
+				Default(time.RFC3339Nano).
+				Example("2006-01-02").
+				Example("02/01/2006 15:04:05"),
+		).Description("A map of xref:configuration:field_paths.adoc[dot separated paths] to the field declarations of the schema.")).
+		Example("CSV rows", "Coerce the columns of CSV rows, which are always parsed as strings, to their types.", `
+input:
+  file:
+    paths: [ ./orders.csv ]
+    scanner:
+      csv: {}
+
+pipeline:
+  processors:
+    - coerce:
+        schema:
+          id:
+            type: int
+          price:
+            type: float
+          gift:
+            type: bool
+            nullable: true
+          ordered_at:
+            type: timestamp
+            format: "2006-01-02 15:04:05"
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"coerce", coerceProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return coerceProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type coerceField struct {
+	path     string
+	kind     string
+	nullable bool
+	format   string
+}
+
+type coerceProc struct {
+	fields []coerceField
+}
+
+func coerceProcFromParsed(conf *service.ParsedConfig) (*coerceProc, error) {
+	schema, err := conf.FieldObjectMap(coFieldSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &coerceProc{}
+	for path, fConf := range schema {
+		f := coerceField{path: path}
+		if f.kind, err = fConf.FieldString(coFieldSchemaType); err != nil {
+			return nil, err
+		}
+		if f.nullable, err = fConf.FieldBool(coFieldSchemaNullable); err != nil {
+			return nil, err
+		}
+		if f.format, err = fConf.FieldString(coFieldSchemaFormat); err != nil {
+			return nil, err
+		}
+		p.fields = append(p.fields, f)
+	}
+	if len(p.fields) == 0 {
+		return nil, errors.New("schema must declare at least one field")
+	}
+	sort.Slice(p.fields, func(i, j int) bool {
+		return p.fields[i].path < p.fields[j].path
+	})
+	return p, nil
+}
+
+func (f coerceField) coerce(v any) (any, error) {
+	if s, ok := v.(string); ok && f.kind != "string" {
+		v = strings.TrimSpace(s)
+	}
+	if v == nil || v == "" {
+		if f.nullable {
+			return nil, nil
+		}
+		if v == "" && f.kind == "string" {
+			return v, nil
+		}
+		return nil, errors.New("value is empty and the field is not nullable")
+	}
+
+	switch f.kind {
+	case "string":
+		switch t := v.(type) {
+		case string:
+			return t, nil
+		case json.Number:
+			return t.String(), nil
+		case bool, int, int64, uint64:
+			return fmt.Sprintf("%v", t), nil
+		case float64:
+			return strconv.FormatFloat(t, 'f', -1, 64), nil
+		}
+	case "int":
+		switch t := v.(type) {
+		case string:
+			return strconv.ParseInt(t, 10, 64)
+		case json.Number:
+			if i, err := t.Int64(); err == nil {
+				return i, nil
+			}
+			fv, err := t.Float64()
+			if err != nil {
+				return nil, err
+			}
+			return coerceWholeFloat(fv)
+		case int:
+			return int64(t), nil
+		case int64:
+			return t, nil
+		case uint64:
+			if t > math.MaxInt64 {
+				return nil, fmt.Errorf("value %v overflows an int", t)
+			}
+			return int64(t), nil
+		case float64:
+			return coerceWholeFloat(t)
+		}
+	case "float":
+		switch t := v.(type) {
+		case string:
+			return strconv.ParseFloat(t, 64)
+		case json.Number:
+			return t.Float64()
+		case int:
+			return float64(t), nil
+		case int64:
+			return float64(t), nil
+		case uint64:
+			return float64(t), nil
+		case float64:
+			return t, nil
+		}
+	case "bool":
+		switch t := v.(type) {
+		case string:
+			return strconv.ParseBool(t)
+		case bool:
+			return t, nil
+		}
+	case "timestamp":
+		switch t := v.(type) {
+		case string:
+			return time.Parse(f.format, t)
+		case time.Time:
+			return t, nil
+		default:
+			secs, err := f.numberAsFloat(v)
+			if err != nil {
+				return nil, err
+			}
+			whole, frac := math.Modf(secs)
+			return time.Unix(int64(whole), int64(frac*1e9)).UTC(), nil
+		}
+	default:
+		return nil, fmt.Errorf("type not recognised: %v", f.kind)
+	}
+	return nil, fmt.Errorf("cannot coerce %T to %v", v, f.kind)
+}
+
+func coerceWholeFloat(f float64) (int64, error) {
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, fmt.Errorf("value %v is not an integer", f)
+	}
+	return int64(f), nil
+}
+
+func (f coerceField) numberAsFloat(v any) (float64, error) {
+	switch t := v.(type) {
+	case json.Number:
+		return t.Float64()
+	case int:
+		return float64(t), nil
+	case int64:
+		return float64(t), nil
+	case uint64:
+		return float64(t), nil
+	case float64:
+		return t, nil
+	}
+	return 0, fmt.Errorf("cannot coerce %T to %v", v, f.kind)
+}
+
+func (p *coerceProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	v, err := msg.AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message: %w", err)
+	}
+
+	gObj := gabs.Wrap(v)
+
+	values := make([]any, len(p.fields))
+	var errs []string
+	for i, f := range p.fields {
+		var current any
+		if gObj.ExistsP(f.path) {
+			current = gObj.Path(f.path).Data()
+		}
+		if values[i], err = f.coerce(current); err != nil {
+			errs = append(errs, fmt.Sprintf("field %v: %v", f.path, err))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("failed to coerce %v", strings.Join(errs, ", "))
+	}
+
+	mv, err := msg.AsStructuredMut()
+	if err != nil {
+		return nil, err
+	}
+	gObj = gabs.Wrap(mv)
+	for i, f := range p.fields {
+		if _, err := gObj.SetP(values[i], f.path); err != nil {
+			return nil, fmt.Errorf("field %v: %w", f.path, err)
+		}
+	}
+	msg.SetStructuredMut(gObj.Data())
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *coerceProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const coerceTestConf = `
+schema:
+  id:
+    type: int
+  price:
+    type: float
+  gift:
+    type: bool
+    nullable: true
+  ordered_at:
+    type: timestamp
+    format: "2006-01-02 15:04:05"
+  shipped_at:
+    type: timestamp
+    nullable: true
+  customer.ref:
+    type: string
+`
+
+func TestCoerceSuccess(t *testing.T) {
+	conf, err := coerceProcConfig().ParseYAML(coerceTestConf, nil)
+	require.NoError(t, err)
+
+	proc, err := coerceProcFromParsed(conf)
+	require.NoError(t, err)
+
+	msg := service.NewMessage([]byte(`{
+  "id": " 42 ",
+  "price": "9.99",
+  "gift": "",
+  "ordered_at": "2024-06-01 12:30:00",
+  "shipped_at": 1717245000,
+  "customer": {"ref": 1234, "name": "foo"},
+  "extra": "untouched"
+}`))
+
+	batch, err := proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	v, err := batch[0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"id":         int64(42),
+		"price":      9.99,
+		"gift":       nil,
+		"ordered_at": time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC),
+		"shipped_at": time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC),
+		"customer":   map[string]any{"ref": "1234", "name": "foo"},
+		"extra":      "untouched",
+	}, v)
+}
+
+func TestCoerceErrors(t *testing.T) {
+	conf, err := coerceProcConfig().ParseYAML(coerceTestConf, nil)
+	require.NoError(t, err)
+
+	proc, err := coerceProcFromParsed(conf)
+	require.NoError(t, err)
+
+	input := `{"id":"4.2","price":"cheap","gift":"yes","ordered_at":"2024-06-01","customer":{"ref":"a"}}`
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(input)))
+	require.Error(t, err)
+	for _, field := range []string{"field id", "field price", "field gift", "field ordered_at"} {
+		assert.Contains(t, err.Error(), field)
+	}
+	assert.NotContains(t, err.Error(), "field customer.ref")
+	assert.NotContains(t, err.Error(), "field shipped_at")
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"price":1,"ordered_at":"2024-06-01 12:30:00","customer":{"ref":"a"}}`)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "field id: value is empty and the field is not nullable")
+}
+
+func TestCoerceNumbers(t *testing.T) {
+	conf, err := coerceProcConfig().ParseYAML(`
+schema:
+  a:
+    type: int
+  b:
+    type: float
+  c:
+    type: string
+  d:
+    type: bool
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := coerceProcFromParsed(conf)
+	require.NoError(t, err)
+
+	batch, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"a":3.0,"b":7,"c":1.5,"d":true}`)))
+	require.NoError(t, err)
+
+	mBytes, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a":3,"b":7,"c":"1.5","d":true}`, string(mBytes))
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"a":3.5,"b":7,"c":"x","d":true}`)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "field a: value 3.5 is not an integer")
+}
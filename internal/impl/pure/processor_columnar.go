@@ -0,0 +1,259 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	tcolFieldColumns = "columns"
+
+	fcolFieldDropNulls = "drop_nulls"
+)
+
+func toColumnarProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Transposes a batch of JSON objects into a single message of the form `{\"column\":[values...]}`, with an array of values for each column.").
+		Description(`
+The keys of each object are the columns of the result, and the values of a column are ordered the same as the messages of the batch. The objects of a batch do not need to share the same keys, each column contains a `+"`null`"+` for every object that lacks it, and therefore all columns have the same length as the number of objects transposed. Only the top level keys of each object are columns, nested values are kept as they are.
+
+The metadata of the resulting message is copied from the first message of the batch. Messages that are not JSON objects are flagged as failed and passed through unchanged alongside the result, and can be handled using xref:configuration:error_handling.adoc[error handling methods].
+
+This processor is the inverse of the `+"xref:components:processors/from_columnar.adoc[`from_columnar` processor]"+`, and operates on a batch of messages, which are best formed with a xref:configuration:batching.adoc[batching policy].`).
+		Field(service.NewStringListField(tcolFieldColumns).
+			Description("An optional list of columns, when set only these columns are transposed and each of them is present in the result even when no object of the batch has it.").
+			Example([]string{"id", "price", "quantity"}).
+			Optional()).
+		Example("Vectorized inference", "Transpose batches of a hundred records into a single columnar document.", `
+input:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topics: [ features ]
+    consumer_group: inference
+    batching:
+      count: 100
+      period: 1s
+
+pipeline:
+  processors:
+    - to_columnar:
+        columns: [ id, age, income ]
+`)
+}
+
+func fromColumnarProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Transposes a JSON object of the form `{\"column\":[values...]}` into a message for each row, where each row is an object of the values at its index.").
+		Description(`
+Every value of the object must be an array, and all arrays must have the same length, which is the number of messages produced. The metadata of the original message is copied onto each message produced, and an object without any columns, or with empty columns, results in the message being dropped.
+
+Messages that are not valid JSON, or that do not match the expected form, are flagged as failed and can be handled using xref:configuration:error_handling.adoc[error handling methods].
+
+This processor is the inverse of the `+"xref:components:processors/to_columnar.adoc[`to_columnar` processor]"+`.`).
+		Field(service.NewBoolField(fcolFieldDropNulls).
+			Description("Whether to omit columns with a `null` value from rows, which reverses the filling of missing columns by the `to_columnar` processor.").
+			Default(false)).
+		Example("Inference results", "Split the results of a vectorized inference service into a message per record.", `
+pipeline:
+  processors:
+    - http:
+        url: http://localhost:8080/predict
+        verb: POST
+    - from_columnar:
+        drop_nulls: true
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"to_columnar", toColumnarProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return toColumnarProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+
+	err = service.RegisterProcessor(
+		"from_columnar", fromColumnarProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return fromColumnarProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type toColumnarProc struct {
+	columns []string
+}
+
+func toColumnarProcFromParsed(conf *service.ParsedConfig) (*toColumnarProc, error) {
+	p := &toColumnarProc{}
+	if conf.Contains(tcolFieldColumns) {
+		columns, err := conf.FieldStringList(tcolFieldColumns)
+		if err != nil {
+			return nil, err
+		}
+		if len(columns) > 0 {
+			p.columns = columns
+		}
+	}
+	return p, nil
+}
+
+func (p *toColumnarProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	var (
+		rows   []map[string]any
+		first  *service.Message
+		failed service.MessageBatch
+	)
+	for _, msg := range batch {
+		v, err := msg.AsStructured()
+		if err != nil {
+			msg.SetError(fmt.Errorf("failed to parse message as JSON: %w", err))
+			failed = append(failed, msg)
+			continue
+		}
+		obj, ok := v.(map[string]any)
+		if !ok {
+			msg.SetError(fmt.Errorf("expected object value, got %T", v))
+			failed = append(failed, msg)
+			continue
+		}
+		if first == nil {
+			first = msg
+		}
+		rows = append(rows, obj)
+	}
+
+	columns := p.columns
+	if columns == nil {
+		seen := map[string]struct{}{}
+		for _, row := range rows {
+			for k := range row {
+				if _, exists := seen[k]; !exists {
+					seen[k] = struct{}{}
+					columns = append(columns, k)
+				}
+			}
+		}
+		sort.Strings(columns)
+	}
+
+	var res service.MessageBatch
+	if first != nil {
+		doc := make(map[string]any, len(columns))
+		for _, c := range columns {
+			values := make([]any, len(rows))
+			for i, row := range rows {
+				values[i] = row[c]
+			}
+			doc[c] = values
+		}
+
+		out := first.Copy()
+		out.SetStructuredMut(doc)
+		res = append(res, out)
+	}
+	res = append(res, failed...)
+	if len(res) == 0 {
+		return nil, nil
+	}
+	return []service.MessageBatch{res}, nil
+}
+
+func (p *toColumnarProc) Close(ctx context.Context) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type fromColumnarProc struct {
+	dropNulls bool
+}
+
+func fromColumnarProcFromParsed(conf *service.ParsedConfig) (*fromColumnarProc, error) {
+	p := &fromColumnarProc{}
+
+	var err error
+	if p.dropNulls, err = conf.FieldBool(fcolFieldDropNulls); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *fromColumnarProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	v, err := msg.AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %w", err)
+	}
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected object value, got %T", v)
+	}
+
+	columns := make(map[string][]any, len(obj))
+	length := -1
+	for k, cv := range obj {
+		values, ok := cv.([]any)
+		if !ok {
+			return nil, fmt.Errorf("expected array value for column %v, got %T", k, cv)
+		}
+		if length == -1 {
+			length = len(values)
+		} else if len(values) != length {
+			return nil, errors.New("columns must all have the same length")
+		}
+		columns[k] = values
+	}
+	if length <= 0 {
+		return nil, nil
+	}
+
+	batch := make(service.MessageBatch, 0, length)
+	for i := 0; i < length; i++ {
+		row := make(map[string]any, len(columns))
+		for k, values := range columns {
+			if values[i] == nil && p.dropNulls {
+				continue
+			}
+			row[k] = values[i]
+		}
+
+		part := msg.Copy()
+		part.SetStructured(row)
+		batch = append(batch, part)
+	}
+	return batch, nil
+}
+
+func (p *fromColumnarProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,207 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestToColumnar(t *testing.T) {
+	conf, err := toColumnarProcConfig().ParseYAML(`{}`, nil)
+	require.NoError(t, err)
+
+	proc, err := toColumnarProcFromParsed(conf)
+	require.NoError(t, err)
+
+	first := service.NewMessage([]byte(`{"id":1,"name":"foo","tags":["a"]}`))
+	first.MetaSetMut("source", "first")
+
+	res, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		first,
+		service.NewMessage([]byte(`{"id":2,"price":1.5}`)),
+		service.NewMessage([]byte(`{"id":3,"name":"baz"}`)),
+	})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 1)
+
+	mBytes, err := res[0][0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "id": [1, 2, 3],
+  "name": ["foo", null, "baz"],
+  "price": [null, 1.5, null],
+  "tags": [["a"], null, null]
+}`, string(mBytes))
+
+	v, _ := res[0][0].MetaGet("source")
+	assert.Equal(t, "first", v)
+}
+
+func TestToColumnarColumns(t *testing.T) {
+	conf, err := toColumnarProcConfig().ParseYAML(`
+columns: [ id, missing ]
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := toColumnarProcFromParsed(conf)
+	require.NoError(t, err)
+
+	res, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1,"name":"foo"}`)),
+		service.NewMessage([]byte(`{"id":2}`)),
+	})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 1)
+
+	mBytes, err := res[0][0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":[1,2],"missing":[null,null]}`, string(mBytes))
+}
+
+func TestToColumnarNotObjects(t *testing.T) {
+	conf, err := toColumnarProcConfig().ParseYAML(`{}`, nil)
+	require.NoError(t, err)
+
+	proc, err := toColumnarProcFromParsed(conf)
+	require.NoError(t, err)
+
+	res, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1}`)),
+		service.NewMessage([]byte(`[1,2]`)),
+		service.NewMessage([]byte(`not json`)),
+	})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 3)
+
+	mBytes, err := res[0][0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":[1]}`, string(mBytes))
+	require.NoError(t, res[0][0].GetError())
+
+	for _, msg := range res[0][1:] {
+		assert.Error(t, msg.GetError())
+	}
+}
+
+func TestFromColumnar(t *testing.T) {
+	conf, err := fromColumnarProcConfig().ParseYAML(`{}`, nil)
+	require.NoError(t, err)
+
+	proc, err := fromColumnarProcFromParsed(conf)
+	require.NoError(t, err)
+
+	msg := service.NewMessage([]byte(`{"id":[1,2,3],"name":["foo",null,"baz"]}`))
+	msg.MetaSetMut("source", "columns")
+
+	res, err := proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, res, 3)
+
+	for i, exp := range []string{
+		`{"id":1,"name":"foo"}`,
+		`{"id":2,"name":null}`,
+		`{"id":3,"name":"baz"}`,
+	} {
+		mBytes, err := res[i].AsBytes()
+		require.NoError(t, err)
+		assert.JSONEq(t, exp, string(mBytes))
+
+		v, _ := res[i].MetaGet("source")
+		assert.Equal(t, "columns", v)
+	}
+}
+
+func TestColumnarRoundTrip(t *testing.T) {
+	pConf, err := toColumnarProcConfig().ParseYAML(`{}`, nil)
+	require.NoError(t, err)
+
+	to, err := toColumnarProcFromParsed(pConf)
+	require.NoError(t, err)
+
+	conf, err := fromColumnarProcConfig().ParseYAML(`
+drop_nulls: true
+`, nil)
+	require.NoError(t, err)
+
+	from, err := fromColumnarProcFromParsed(conf)
+	require.NoError(t, err)
+
+	rows := []string{
+		`{"id":1,"name":"foo"}`,
+		`{"id":2,"price":1.5}`,
+		`{"id":3,"nested":{"a":[1,2]}}`,
+	}
+
+	var batch service.MessageBatch
+	for _, r := range rows {
+		batch = append(batch, service.NewMessage([]byte(r)))
+	}
+
+	res, err := to.ProcessBatch(context.Background(), batch)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 1)
+
+	out, err := from.Process(context.Background(), res[0][0])
+	require.NoError(t, err)
+	require.Len(t, out, len(rows))
+
+	for i, exp := range rows {
+		mBytes, err := out[i].AsBytes()
+		require.NoError(t, err)
+		assert.JSONEq(t, exp, string(mBytes))
+	}
+}
+
+func TestFromColumnarEmpty(t *testing.T) {
+	conf, err := fromColumnarProcConfig().ParseYAML(`{}`, nil)
+	require.NoError(t, err)
+
+	proc, err := fromColumnarProcFromParsed(conf)
+	require.NoError(t, err)
+
+	for _, doc := range []string{`{}`, `{"id":[],"name":[]}`} {
+		res, err := proc.Process(context.Background(), service.NewMessage([]byte(doc)))
+		require.NoError(t, err)
+		assert.Empty(t, res, doc)
+	}
+}
+
+func TestFromColumnarErrors(t *testing.T) {
+	conf, err := fromColumnarProcConfig().ParseYAML(`{}`, nil)
+	require.NoError(t, err)
+
+	proc, err := fromColumnarProcFromParsed(conf)
+	require.NoError(t, err)
+
+	for _, doc := range []string{
+		`not json`,
+		`[1,2]`,
+		`{"id":[1,2],"name":"foo"}`,
+		`{"id":[1,2],"name":["foo"]}`,
+	} {
+		_, err := proc.Process(context.Background(), service.NewMessage([]byte(doc)))
+		assert.Error(t, err, doc)
+	}
+}
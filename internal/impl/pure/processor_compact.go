@@ -0,0 +1,214 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	cmpFieldKey            = "key"
+	cmpFieldMerge          = "merge"
+	cmpFieldKeepTombstones = "keep_tombstones"
+)
+
+func compactProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Compacts a batch so that it contains only the latest message of each key, in the style of Kafka log compaction.").
+		Description(`
+This processor is useful for change data capture streams where an entity is updated several times within a short period, and only its latest state needs to be written downstream. Only messages within the same batch are compacted, and therefore it's usually preceded by a form of xref:configuration:batching.adoc[batching].
+
+The messages that remain keep their order relative to each other, where the position of each key is that of its last message within the batch.
+
+== Merging
+
+When `+"`merge`"+` is `+"`true`"+` the messages of each key are merged in order instead of only keeping the last one, where the fields of JSON objects are merged recursively and any other value of a later message replaces that of an earlier one. This is useful when messages contain partial updates of an entity. The merged message has the metadata of the last message of the key.
+
+== Tombstones
+
+A message with an empty body or a body of `+"`null`"+` is a tombstone, which indicates that the entity of the key was deleted. When merging, a tombstone discards the state merged so far, so that the messages of the key that follow it are merged into an empty state. When the last message of a key is a tombstone it's kept only when `+"`keep_tombstones`"+` is `+"`true`"+`, otherwise the key is removed from the batch entirely.
+
+Messages where the key cannot be resolved are left in the batch without being compacted, and are flagged as failed so that they can be handled using xref:configuration:error_handling.adoc[error handling methods]. Messages removed from the batch are acknowledged once the remaining messages are delivered.`).
+		Field(service.NewInterpolatedStringField(cmpFieldKey).
+			Description("An interpolated string that resolves to the key of each message.").
+			Examples(`${! meta("kafka_key") }`, `${! this.id }`)).
+		Field(service.NewBoolField(cmpFieldMerge).
+			Description("Whether to merge the messages of each key rather than keep only the last one.").
+			Default(false)).
+		Field(service.NewBoolField(cmpFieldKeepTombstones).
+			Description("Whether to keep the tombstone of a key when it's the last message of the key.").
+			Default(true)).
+		Example("CDC latest state", "Collapse the changes to each row within batches of a CDC stream into the latest state of the row.", `
+input:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topics: [ cdc.public.users ]
+    consumer_group: users_sink
+    batching:
+      count: 1000
+      period: 1s
+
+pipeline:
+  processors:
+    - compact:
+        key: ${! meta("kafka_key") }
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"compact", compactProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return compactProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type compactProc struct {
+	key            *service.InterpolatedString
+	merge          bool
+	keepTombstones bool
+}
+
+func compactProcFromParsed(conf *service.ParsedConfig) (*compactProc, error) {
+	p := &compactProc{}
+
+	var err error
+	if p.key, err = conf.FieldInterpolatedString(cmpFieldKey); err != nil {
+		return nil, err
+	}
+	if p.merge, err = conf.FieldBool(cmpFieldMerge); err != nil {
+		return nil, err
+	}
+	if p.keepTombstones, err = conf.FieldBool(cmpFieldKeepTombstones); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func isTombstone(msg *service.Message) bool {
+	mBytes, err := msg.AsBytes()
+	if err != nil {
+		return false
+	}
+	mBytes = bytes.TrimSpace(mBytes)
+	return len(mBytes) == 0 || bytes.Equal(mBytes, []byte("null"))
+}
+
+// compactMerge merges src into dst recursively where both are objects, and
+// otherwise returns src.
+func compactMerge(dst, src any) any {
+	dstObj, dstIsObj := dst.(map[string]any)
+	srcObj, srcIsObj := src.(map[string]any)
+	if !dstIsObj || !srcIsObj {
+		return src
+	}
+	for k, v := range srcObj {
+		dstObj[k] = compactMerge(dstObj[k], v)
+	}
+	return dstObj
+}
+
+type compactState struct {
+	msg   *service.Message
+	value any
+	index int
+}
+
+func (p *compactProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	if len(batch) == 0 {
+		return nil, nil
+	}
+
+	// The surviving message of each index, which is either the last message of
+	// a key or a message that could not be keyed.
+	survivors := make([]*service.Message, len(batch))
+	states := map[string]*compactState{}
+
+	for i, msg := range batch {
+		key, err := batch.TryInterpolatedString(i, p.key)
+		if err != nil {
+			msg.SetError(fmt.Errorf("key interpolation error: %w", err))
+			survivors[i] = msg
+			continue
+		}
+
+		state, exists := states[key]
+		if !exists {
+			state = &compactState{}
+			states[key] = state
+		} else {
+			survivors[state.index] = nil
+		}
+		state.index = i
+
+		if !p.merge || isTombstone(msg) {
+			state.msg, state.value = msg, nil
+			survivors[i] = msg
+			continue
+		}
+
+		v, err := msg.AsStructuredMut()
+		if err != nil {
+			msg.SetError(fmt.Errorf("failed to parse message for merging: %w", err))
+			state.msg, state.value = msg, nil
+			survivors[i] = msg
+			continue
+		}
+		if state.value == nil {
+			state.value = v
+		} else {
+			state.value = compactMerge(state.value, v)
+		}
+		state.msg = msg
+		survivors[i] = msg
+	}
+
+	if p.merge {
+		for _, state := range states {
+			if state.value != nil {
+				state.msg.SetStructuredMut(state.value)
+			}
+		}
+	}
+
+	out := make(service.MessageBatch, 0, len(states))
+	for _, msg := range survivors {
+		if msg == nil {
+			continue
+		}
+		if !p.keepTombstones && msg.GetError() == nil && isTombstone(msg) {
+			continue
+		}
+		out = append(out, msg)
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return []service.MessageBatch{out}, nil
+}
+
+func (p *compactProc) Close(ctx context.Context) error {
+	return nil
+}
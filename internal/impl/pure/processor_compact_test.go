@@ -0,0 +1,168 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func compactBatch(contents ...string) service.MessageBatch {
+	var b service.MessageBatch
+	for _, c := range contents {
+		b = append(b, service.NewMessage([]byte(c)))
+	}
+	return b
+}
+
+func TestCompactReplace(t *testing.T) {
+	conf, err := compactProcConfig().ParseYAML(`
+key: ${! this.id }
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := compactProcFromParsed(conf)
+	require.NoError(t, err)
+
+	res, err := proc.ProcessBatch(context.Background(), compactBatch(
+		`{"id":"a","v":1}`,
+		`{"id":"b","v":1}`,
+		`{"id":"a","v":2}`,
+		`{"id":"c","v":1}`,
+		`{"id":"b","v":2}`,
+	))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, []string{
+		`{"id":"a","v":2}`,
+		`{"id":"c","v":1}`,
+		`{"id":"b","v":2}`,
+	}, batchContents(t, res[0]))
+}
+
+func TestCompactMerge(t *testing.T) {
+	conf, err := compactProcConfig().ParseYAML(`
+key: ${! this.id }
+merge: true
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := compactProcFromParsed(conf)
+	require.NoError(t, err)
+
+	batch := compactBatch(
+		`{"id":"a","name":"foo","address":{"city":"london","street":"x"}}`,
+		`{"id":"b","name":"bar"}`,
+		`{"id":"a","address":{"street":"y"},"tags":["c"]}`,
+	)
+	batch[2].MetaSetMut("version", "2")
+
+	res, err := proc.ProcessBatch(context.Background(), batch)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, []string{
+		`{"id":"b","name":"bar"}`,
+		`{"address":{"city":"london","street":"y"},"id":"a","name":"foo","tags":["c"]}`,
+	}, batchContents(t, res[0]))
+
+	v, ok := res[0][1].MetaGetMut("version")
+	require.True(t, ok)
+	assert.Equal(t, "2", v)
+}
+
+func TestCompactTombstones(t *testing.T) {
+	batch := func() service.MessageBatch {
+		b := compactBatch(
+			`{"id":"a","name":"foo"}`,
+			`null`,
+			`{"id":"b","name":"bar"}`,
+			``,
+			`{"id":"b","age":10}`,
+		)
+		b[1].MetaSetMut("id", "a")
+		b[3].MetaSetMut("id", "b")
+		return b
+	}
+
+	for _, test := range []struct {
+		name   string
+		conf   string
+		output []string
+	}{
+		{
+			name: "keep tombstones",
+			conf: `
+key: ${! @id | this.id }
+`,
+			output: []string{`null`, `{"id":"b","age":10}`},
+		},
+		{
+			name: "drop tombstones",
+			conf: `
+key: ${! @id | this.id }
+keep_tombstones: false
+`,
+			output: []string{`{"id":"b","age":10}`},
+		},
+		{
+			name: "merge resets on tombstone",
+			conf: `
+key: ${! @id | this.id }
+merge: true
+`,
+			output: []string{`null`, `{"age":10,"id":"b"}`},
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			pConf, err := compactProcConfig().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			proc, err := compactProcFromParsed(pConf)
+			require.NoError(t, err)
+
+			res, err := proc.ProcessBatch(context.Background(), batch())
+			require.NoError(t, err)
+			require.Len(t, res, 1)
+			assert.Equal(t, test.output, batchContents(t, res[0]))
+		})
+	}
+}
+
+func TestCompactKeyError(t *testing.T) {
+	conf, err := compactProcConfig().ParseYAML(`
+key: ${! this.id }
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := compactProcFromParsed(conf)
+	require.NoError(t, err)
+
+	res, err := proc.ProcessBatch(context.Background(), compactBatch(
+		`{"id":"a","v":1}`,
+		`not json`,
+		`{"id":"a","v":2}`,
+	))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, []string{`not json`, `{"id":"a","v":2}`}, batchContents(t, res[0]))
+	assert.Error(t, res[0][0].GetError())
+	assert.NoError(t, res[0][1].GetError())
+}
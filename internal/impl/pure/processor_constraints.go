@@ -0,0 +1,345 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	cnFieldConstraints = "constraints"
+	cnFieldField       = "field"
+	cnFieldNotNull     = "not_null"
+	cnFieldUnique      = "unique"
+	cnFieldPattern     = "pattern"
+	cnFieldMin         = "min"
+	cnFieldMax         = "max"
+	cnFieldEnum        = "enum"
+	cnFieldOnViolation = "on_violation"
+)
+
+func constraintsProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Validates the fields of each message of a batch against a list of declarative constraints.").
+		Description(`
+Each entry of `+"`constraints`"+` identifies a field of the message and any combination of the following constraints:
+
+- `+"`not_null`"+`: The field must be present and must not be `+"`null`"+`.
+- `+"`unique`"+`: The value of the field must not be shared with any other message of the same batch.
+- `+"`pattern`"+`: The field must be a string that matches a regular expression.
+- `+"`min`"+` and `+"`max`"+`: The field must be a number within an inclusive range.
+- `+"`enum`"+`: The field must be one of a list of values.
+
+With the exception of `+"`not_null`"+` the constraints of a field that is missing or `+"`null`"+` are not checked. Numbers and other non-string values are converted into their JSON representation when they are checked with `+"`unique`"+` or `+"`enum`"+`. When a value is shared by several messages of a batch all of those messages violate the `+"`unique`"+` constraint.
+
+Rows of CSV data can be validated by first parsing them into objects, for example with the `+"xref:components:scanners/csv.adoc[`csv` scanner]"+` or the `+"`parse_csv`"+` Bloblang method.
+
+The contents of messages are not modified. The metadata field `+"`constraint_violations`"+` is set on each message that violates one or more constraints to an array of objects, each containing the `+"`field`"+` and the `+"`constraint`"+` that was violated. Messages that cannot be parsed as JSON are flagged as failed.`).
+		Field(service.NewObjectListField(cnFieldConstraints,
+			service.NewStringField(cnFieldField).
+				Description("A xref:configuration:field_paths.adoc[dot separated path] of the field to check."),
+			service.NewBoolField(cnFieldNotNull).
+				Description("Whether the field must be present and not null.").
+				Default(false),
+			service.NewBoolField(cnFieldUnique).
+				Description("Whether the value of the field must be unique within the batch.").
+				Default(false),
+			service.NewStringField(cnFieldPattern).
+				Description("A regular expression that the field must match.").
+				Optional(),
+			service.NewFloatField(cnFieldMin).
+				Description("The minimum value of the field.").
+				Optional(),
+			service.NewFloatField(cnFieldMax).
+				Description("The maximum value of the field.").
+				Optional(),
+			service.NewStringListField(cnFieldEnum).
+				Description("A list of allowed values of the field.").
+				Optional(),
+		).
+			Description("The constraints to check for each message.")).
+		Field(service.NewStringAnnotatedEnumField(cnFieldOnViolation, map[string]string{
+			"error":    "Flag messages that violate constraints as failed, in which case they can be handled using xref:configuration:error_handling.adoc[error handling methods].",
+			"annotate": "Only add the `constraint_violations` metadata field to messages that violate constraints.",
+		}).
+			Description("Determines how messages that violate constraints are handled.").
+			Default("error")).
+		Example("Warehouse loading", "Validate rows of a CSV file before they are inserted into a table, and write rows that violate constraints to a separate file.", `
+input:
+  file:
+    paths: [ ./users.csv ]
+    scanner:
+      csv: {}
+
+pipeline:
+  processors:
+    - constraints:
+        constraints:
+          - field: id
+            not_null: true
+            unique: true
+          - field: email
+            pattern: '^[^@]+@[^@]+$'
+          - field: age
+            min: 0
+            max: 150
+          - field: country
+            enum: [ GB, US, DE ]
+
+output:
+  switch:
+    cases:
+      - check: errored()
+        output:
+          file:
+            path: ./rejected.jsonl
+          processors:
+            - mutation: 'meta violations = @constraint_violations.format_json(no_indent: true)'
+            - catch: []
+      - output:
+          sql_insert:
+            driver: postgres
+            dsn: postgres://localhost:5432/warehouse
+            table: users
+            columns: [ id, email, age, country ]
+            args_mapping: root = [ this.id, this.email, this.age, this.country ]
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"constraints", constraintsProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return constraintsProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type fieldConstraint struct {
+	field    string
+	notNull  bool
+	unique   bool
+	pattern  *regexp.Regexp
+	min, max *float64
+	enum     map[string]struct{}
+}
+
+type constraintsProc struct {
+	constraints []fieldConstraint
+	flag        bool
+}
+
+func constraintsProcFromParsed(conf *service.ParsedConfig) (*constraintsProc, error) {
+	p := &constraintsProc{}
+
+	cConfs, err := conf.FieldObjectList(cnFieldConstraints)
+	if err != nil {
+		return nil, err
+	}
+	if len(cConfs) == 0 {
+		return nil, errors.New("at least one constraint must be specified")
+	}
+
+	for _, cConf := range cConfs {
+		var c fieldConstraint
+		if c.field, err = cConf.FieldString(cnFieldField); err != nil {
+			return nil, err
+		}
+		if c.notNull, err = cConf.FieldBool(cnFieldNotNull); err != nil {
+			return nil, err
+		}
+		if c.unique, err = cConf.FieldBool(cnFieldUnique); err != nil {
+			return nil, err
+		}
+		if cConf.Contains(cnFieldPattern) {
+			pattern, err := cConf.FieldString(cnFieldPattern)
+			if err != nil {
+				return nil, err
+			}
+			if c.pattern, err = regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("field %v: failed to compile pattern: %w", c.field, err)
+			}
+		}
+		if cConf.Contains(cnFieldMin) {
+			v, err := cConf.FieldFloat(cnFieldMin)
+			if err != nil {
+				return nil, err
+			}
+			c.min = &v
+		}
+		if cConf.Contains(cnFieldMax) {
+			v, err := cConf.FieldFloat(cnFieldMax)
+			if err != nil {
+				return nil, err
+			}
+			c.max = &v
+		}
+		if c.min != nil && c.max != nil && *c.min > *c.max {
+			return nil, fmt.Errorf("field %v: min must not be greater than max", c.field)
+		}
+		if cConf.Contains(cnFieldEnum) {
+			values, err := cConf.FieldStringList(cnFieldEnum)
+			if err != nil {
+				return nil, err
+			}
+			if len(values) > 0 {
+				c.enum = make(map[string]struct{}, len(values))
+				for _, v := range values {
+					c.enum[v] = struct{}{}
+				}
+			}
+		}
+		p.constraints = append(p.constraints, c)
+	}
+
+	onViolation, err := conf.FieldString(cnFieldOnViolation)
+	if err != nil {
+		return nil, err
+	}
+	p.flag = onViolation == "error"
+	return p, nil
+}
+
+// constraintValueString converts a value into a string for the purpose of
+// comparing it with other values.
+func constraintValueString(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	vBytes, _ := json.Marshal(v)
+	return string(vBytes)
+}
+
+type constraintViolation struct {
+	field      string
+	constraint string
+}
+
+// check returns the names of the constraints violated by a value, excluding
+// uniqueness which can only be determined across a batch.
+func (c *fieldConstraint) check(v any) (violated []string) {
+	if v == nil {
+		if c.notNull {
+			violated = append(violated, cnFieldNotNull)
+		}
+		return
+	}
+	if c.pattern != nil {
+		if s, ok := v.(string); !ok || !c.pattern.MatchString(s) {
+			violated = append(violated, cnFieldPattern)
+		}
+	}
+	if c.min != nil || c.max != nil {
+		f, ok := dataQualityNumber(v)
+		if !ok || (c.min != nil && f < *c.min) {
+			violated = append(violated, cnFieldMin)
+		} else if c.max != nil && f > *c.max {
+			violated = append(violated, cnFieldMax)
+		}
+	}
+	if c.enum != nil {
+		if _, exists := c.enum[constraintValueString(v)]; !exists {
+			violated = append(violated, cnFieldEnum)
+		}
+	}
+	return
+}
+
+func (p *constraintsProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	violations := make([][]constraintViolation, len(batch))
+	parseErrs := make([]error, len(batch))
+
+	// For each unique constraint the indexes of messages for each value.
+	uniqueValues := make([]map[string][]int, len(p.constraints))
+	for i := range p.constraints {
+		if p.constraints[i].unique {
+			uniqueValues[i] = map[string][]int{}
+		}
+	}
+
+	for i, msg := range batch {
+		root, err := msg.AsStructured()
+		if err != nil {
+			parseErrs[i] = fmt.Errorf("failed to parse message as JSON: %w", err)
+			continue
+		}
+		gObj := gabs.Wrap(root)
+		for j := range p.constraints {
+			c := &p.constraints[j]
+			v := gObj.Path(c.field).Data()
+			for _, name := range c.check(v) {
+				violations[i] = append(violations[i], constraintViolation{field: c.field, constraint: name})
+			}
+			if c.unique && v != nil {
+				k := constraintValueString(v)
+				uniqueValues[j][k] = append(uniqueValues[j][k], i)
+			}
+		}
+	}
+
+	for j, values := range uniqueValues {
+		for _, indexes := range values {
+			if len(indexes) < 2 {
+				continue
+			}
+			for _, i := range indexes {
+				violations[i] = append(violations[i], constraintViolation{field: p.constraints[j].field, constraint: cnFieldUnique})
+			}
+		}
+	}
+
+	for i, msg := range batch {
+		if parseErrs[i] != nil {
+			msg.SetError(parseErrs[i])
+			continue
+		}
+		if len(violations[i]) == 0 {
+			continue
+		}
+
+		violationsMeta := make([]any, 0, len(violations[i]))
+		descriptions := make([]string, 0, len(violations[i]))
+		for _, v := range violations[i] {
+			violationsMeta = append(violationsMeta, map[string]any{
+				"field":      v.field,
+				"constraint": v.constraint,
+			})
+			descriptions = append(descriptions, fmt.Sprintf("%v (%v)", v.field, v.constraint))
+		}
+		msg.MetaSetMut("constraint_violations", violationsMeta)
+		if p.flag {
+			msg.SetError(fmt.Errorf("constraint violations: %v", strings.Join(descriptions, ", ")))
+		}
+	}
+	return []service.MessageBatch{batch}, nil
+}
+
+func (p *constraintsProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestConstraintsViolations(t *testing.T) {
+	conf, err := constraintsProcConfig().ParseYAML(`
+constraints:
+  - field: id
+    not_null: true
+    unique: true
+  - field: email
+    pattern: '^[^@]+@[^@]+$'
+  - field: age
+    min: 0
+    max: 150
+  - field: country
+    enum: [ GB, US, "1" ]
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := constraintsProcFromParsed(conf)
+	require.NoError(t, err)
+
+	res, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1,"email":"a@b","age":30,"country":"GB"}`)),
+		service.NewMessage([]byte(`{"id":2,"email":"nope","age":-1,"country":"FR"}`)),
+		service.NewMessage([]byte(`{"id":2,"age":200,"country":1}`)),
+		service.NewMessage([]byte(`{"email":10,"age":"old"}`)),
+		service.NewMessage([]byte(`not json`)),
+	})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 5)
+
+	assert.NoError(t, res[0][0].GetError())
+	_, exists := res[0][0].MetaGetMut("constraint_violations")
+	assert.False(t, exists)
+
+	assert.EqualError(t, res[0][1].GetError(), "constraint violations: email (pattern), age (min), country (enum), id (unique)")
+	v, exists := res[0][1].MetaGetMut("constraint_violations")
+	require.True(t, exists)
+	assert.Equal(t, []any{
+		map[string]any{"field": "email", "constraint": "pattern"},
+		map[string]any{"field": "age", "constraint": "min"},
+		map[string]any{"field": "country", "constraint": "enum"},
+		map[string]any{"field": "id", "constraint": "unique"},
+	}, v)
+
+	assert.EqualError(t, res[0][2].GetError(), "constraint violations: age (max), id (unique)")
+	assert.EqualError(t, res[0][3].GetError(), "constraint violations: id (not_null), email (pattern), age (min)")
+	assert.ErrorContains(t, res[0][4].GetError(), "failed to parse message as JSON")
+
+	// Message contents are unchanged.
+	assert.Equal(t, []string{
+		`{"id":1,"email":"a@b","age":30,"country":"GB"}`,
+		`{"id":2,"email":"nope","age":-1,"country":"FR"}`,
+		`{"id":2,"age":200,"country":1}`,
+		`{"email":10,"age":"old"}`,
+		`not json`,
+	}, batchContents(t, res[0]))
+}
+
+func TestConstraintsAnnotate(t *testing.T) {
+	conf, err := constraintsProcConfig().ParseYAML(`
+constraints:
+  - field: user.name
+    unique: true
+on_violation: annotate
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := constraintsProcFromParsed(conf)
+	require.NoError(t, err)
+
+	res, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"user":{"name":"foo"}}`)),
+		service.NewMessage([]byte(`{"user":{"name":"bar"}}`)),
+		service.NewMessage([]byte(`{"user":{"name":"foo"}}`)),
+		service.NewMessage([]byte(`{"user":{}}`)),
+		service.NewMessage([]byte(`{"user":{}}`)),
+	})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	var violated []bool
+	for _, m := range res[0] {
+		assert.NoError(t, m.GetError())
+		_, exists := m.MetaGetMut("constraint_violations")
+		violated = append(violated, exists)
+	}
+	assert.Equal(t, []bool{true, false, true, false, false}, violated)
+}
+
+func TestConstraintsBadConfig(t *testing.T) {
+	for _, conf := range []string{
+		`constraints: []`,
+		`
+constraints:
+  - field: foo
+    pattern: '('
+`,
+		`
+constraints:
+  - field: foo
+    min: 10
+    max: 1
+`,
+	} {
+		pConf, err := constraintsProcConfig().ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		_, err = constraintsProcFromParsed(pConf)
+		assert.Error(t, err, conf)
+	}
+}
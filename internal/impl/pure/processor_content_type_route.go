@@ -0,0 +1,265 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"unicode/utf8"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	ctrFieldMetadataKey = "metadata_key"
+	ctrFieldPeekBytes   = "peek_bytes"
+	ctrFieldRoutes      = "routes"
+
+	ctrTypeJSON   = "json"
+	ctrTypeXML    = "xml"
+	ctrTypeCSV    = "csv"
+	ctrTypeText   = "text"
+	ctrTypeBinary = "binary"
+)
+
+var ctrTypes = []string{ctrTypeJSON, ctrTypeXML, ctrTypeCSV, ctrTypeText, ctrTypeBinary}
+
+func contentTypeRouteProcConfig() *service.ConfigSpec {
+	routeFields := make([]*service.ConfigField, 0, len(ctrTypes))
+	for _, t := range ctrTypes {
+		routeFields = append(routeFields, service.NewProcessorListField(t).
+			Description("Processors to apply to messages detected as `"+t+"`.").
+			Optional())
+	}
+
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Detects whether the contents of each message are JSON, XML, CSV, plain text or binary, writes the type to metadata and optionally applies processors specific to the type.").
+		Description(`
+Detection only inspects the first `+"`peek_bytes`"+` bytes of each message and does not parse the message, which makes it cheap but also means that a message detected as JSON or XML might still fail to parse. After leading whitespace and a byte order mark are skipped, the type of a message is:
+
+- `+"`binary`"+`: When the bytes contain a null byte, are not valid UTF-8, or contain a significant proportion of control characters.
+- `+"`json`"+`: When the first character is `+"`{`"+` or `+"`[`"+`.
+- `+"`xml`"+`: When the first character is `+"`<`"+`.
+- `+"`csv`"+`: When the first two lines contain the same non-zero number of commas, semicolons, tabs or pipes outside of quotes.
+- `+"`text`"+`: Otherwise, including empty messages.
+
+== Routes
+
+When processors are configured for a type within `+"`routes`"+` they are applied to the messages of that type. In order to preserve the ordering of a batch each consecutive run of messages of the same type is processed as a separate batch, and the results are emitted as a single batch in their original order. Messages of types without processors pass through unchanged.`).
+		Field(service.NewStringField(ctrFieldMetadataKey).
+			Description("The metadata key to store the detected type in.").
+			Default("payload_type")).
+		Field(service.NewIntField(ctrFieldPeekBytes).
+			Description("The maximum number of bytes of each message to inspect.").
+			Default(512).
+			Advanced()).
+		Field(service.NewObjectField(ctrFieldRoutes, routeFields...).
+			Description("Processors to apply to messages of each type.")).
+		Example("Mixed payloads", "Parse the plain text lines of a stream into structured logs, whilst passing JSON logs through as they are, and dropping binary data.", `
+pipeline:
+  processors:
+    - content_type_route:
+        routes:
+          text:
+            - grok:
+                expressions: [ '%{COMMONAPACHELOG}' ]
+          binary:
+            - mapping: root = deleted()
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"content_type_route", contentTypeRouteProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return contentTypeRouteProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type contentTypeRouteProc struct {
+	metaKey   string
+	peekBytes int
+	routes    map[string][]*service.OwnedProcessor
+}
+
+func contentTypeRouteProcFromParsed(conf *service.ParsedConfig) (*contentTypeRouteProc, error) {
+	p := &contentTypeRouteProc{
+		routes: map[string][]*service.OwnedProcessor{},
+	}
+
+	var err error
+	if p.metaKey, err = conf.FieldString(ctrFieldMetadataKey); err != nil {
+		return nil, err
+	}
+	if p.peekBytes, err = conf.FieldInt(ctrFieldPeekBytes); err != nil {
+		return nil, err
+	}
+	if p.peekBytes < 1 {
+		return nil, errors.New("peek_bytes must be greater than zero")
+	}
+	for _, t := range ctrTypes {
+		if !conf.Contains(ctrFieldRoutes, t) {
+			continue
+		}
+		procs, err := conf.FieldProcessorList(ctrFieldRoutes, t)
+		if err != nil {
+			return nil, err
+		}
+		if len(procs) > 0 {
+			p.routes[t] = procs
+		}
+	}
+	return p, nil
+}
+
+// csvDelimiterCounts returns the number of occurrences of each delimiter of a
+// line outside of quotes.
+func csvDelimiterCounts(line []byte) map[byte]int {
+	counts := map[byte]int{}
+	inQuotes := false
+	for _, b := range line {
+		switch b {
+		case '"':
+			inQuotes = !inQuotes
+		case ',', ';', '\t', '|':
+			if !inQuotes {
+				counts[b]++
+			}
+		}
+	}
+	return counts
+}
+
+func looksLikeCSV(peek []byte) bool {
+	first, rest, found := bytes.Cut(peek, []byte("\n"))
+	if !found {
+		return false
+	}
+	second, _, _ := bytes.Cut(rest, []byte("\n"))
+
+	firstCounts := csvDelimiterCounts(bytes.TrimSuffix(first, []byte("\r")))
+	secondCounts := csvDelimiterCounts(bytes.TrimSuffix(second, []byte("\r")))
+	for d, n := range firstCounts {
+		if n > 0 && secondCounts[d] == n {
+			return true
+		}
+	}
+	return false
+}
+
+// detectPayloadType determines the type of a payload from its leading bytes.
+func detectPayloadType(peek []byte, truncated bool) string {
+	peek = bytes.TrimPrefix(peek, []byte("\xef\xbb\xbf"))
+
+	if truncated {
+		// Drop a rune that may have been cut off by the peek.
+		for i := 0; i < utf8.UTFMax && len(peek) > 0; i++ {
+			if r, _ := utf8.DecodeLastRune(peek); r != utf8.RuneError {
+				break
+			}
+			peek = peek[:len(peek)-1]
+		}
+	}
+	if bytes.IndexByte(peek, 0) >= 0 || !utf8.Valid(peek) {
+		return ctrTypeBinary
+	}
+	control := 0
+	for _, b := range peek {
+		if b < 0x20 && b != '\n' && b != '\r' && b != '\t' && b != '\f' {
+			control++
+		}
+	}
+	if len(peek) > 0 && control*10 > len(peek) {
+		return ctrTypeBinary
+	}
+
+	trimmed := bytes.TrimLeft(peek, " \t\r\n")
+	if len(trimmed) == 0 {
+		return ctrTypeText
+	}
+	switch trimmed[0] {
+	case '{', '[':
+		return ctrTypeJSON
+	case '<':
+		return ctrTypeXML
+	}
+	if looksLikeCSV(trimmed) {
+		return ctrTypeCSV
+	}
+	return ctrTypeText
+}
+
+func (p *contentTypeRouteProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	types := make([]string, len(batch))
+	for i, msg := range batch {
+		mBytes, err := msg.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		peek, truncated := mBytes, false
+		if len(peek) > p.peekBytes {
+			peek, truncated = peek[:p.peekBytes], true
+		}
+		types[i] = detectPayloadType(peek, truncated)
+		msg.MetaSetMut(p.metaKey, types[i])
+	}
+	if len(p.routes) == 0 {
+		return []service.MessageBatch{batch}, nil
+	}
+
+	var out service.MessageBatch
+	for start := 0; start < len(batch); {
+		end := start + 1
+		for end < len(batch) && types[end] == types[start] {
+			end++
+		}
+
+		run := batch[start:end]
+		if procs, exists := p.routes[types[start]]; exists {
+			results, err := service.ExecuteProcessors(ctx, procs, run)
+			if err != nil {
+				return nil, err
+			}
+			for _, r := range results {
+				out = append(out, r...)
+			}
+		} else {
+			out = append(out, run...)
+		}
+		start = end
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return []service.MessageBatch{out}, nil
+}
+
+func (p *contentTypeRouteProc) Close(ctx context.Context) error {
+	for _, procs := range p.routes {
+		for _, proc := range procs {
+			if err := proc.Close(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
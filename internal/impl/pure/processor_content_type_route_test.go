@@ -0,0 +1,114 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+
+	_ "github.com/redpanda-data/benthos/v4/public/components/pure"
+)
+
+func TestContentTypeDetection(t *testing.T) {
+	for _, test := range []struct {
+		input     string
+		truncated bool
+		exp       string
+	}{
+		{input: `{"foo":"bar"}`, exp: "json"},
+		{input: "\xef\xbb\xbf  \n[1,2", exp: "json"},
+		{input: `<?xml version="1.0"?><foo/>`, exp: "xml"},
+		{input: "<root><a>b</a></root>", exp: "xml"},
+		{input: "id,name\n1,foo\n2,bar", exp: "csv"},
+		{input: "id;name;\"a;b\"\r\n1;foo;x\r\n", exp: "csv"},
+		{input: "a\tb\n1\t2", exp: "csv"},
+		{input: "hello, world", exp: "text"},
+		{input: "hello, world\nhow are you", exp: "text"},
+		{input: "", exp: "text"},
+		{input: "plain old text", exp: "text"},
+		{input: "foo\x00bar", exp: "binary"},
+		{input: "\xff\xfe\xfd", exp: "binary"},
+		{input: "\x01\x02\x03\x04abc", exp: "binary"},
+		{input: "caf\xc3", truncated: true, exp: "text"},
+		{input: "caf\xc3", exp: "binary"},
+	} {
+		assert.Equal(t, test.exp, detectPayloadType([]byte(test.input), test.truncated), test.input)
+	}
+}
+
+func TestContentTypeRouteTagging(t *testing.T) {
+	conf, err := contentTypeRouteProcConfig().ParseYAML(`
+metadata_key: kind
+peek_bytes: 4
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := contentTypeRouteProcFromParsed(conf)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = proc.Close(context.Background()) })
+
+	res, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"a":"b"}`)),
+		service.NewMessage([]byte(`hello world`)),
+		service.NewMessage([]byte("ab\x00c")),
+	})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	var kinds []string
+	for _, m := range res[0] {
+		k, _ := m.MetaGet("kind")
+		kinds = append(kinds, k)
+	}
+	assert.Equal(t, []string{"json", "text", "binary"}, kinds)
+}
+
+func TestContentTypeRouteProcessors(t *testing.T) {
+	conf, err := contentTypeRouteProcConfig().ParseYAML(`
+routes:
+  text:
+    - mapping: 'root.text = content().string()'
+  binary:
+    - mapping: 'root = deleted()'
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := contentTypeRouteProcFromParsed(conf)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = proc.Close(context.Background()) })
+
+	res, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"a":1}`)),
+		service.NewMessage([]byte(`foo`)),
+		service.NewMessage([]byte(`bar`)),
+		service.NewMessage([]byte("\x00\x01")),
+		service.NewMessage([]byte(`{"b":2}`)),
+		service.NewMessage([]byte(`baz`)),
+	})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, []string{
+		`{"a":1}`,
+		`{"text":"foo"}`,
+		`{"text":"bar"}`,
+		`{"b":2}`,
+		`{"text":"baz"}`,
+	}, batchContents(t, res[0]))
+}
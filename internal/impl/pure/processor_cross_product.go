@@ -0,0 +1,189 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Jeffail/gabs/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	cpFieldFields          = "fields"
+	cpFieldFieldPath       = "path"
+	cpFieldFieldValues     = "values"
+	cpFieldMaxCombinations = "max_combinations"
+)
+
+func crossProductProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Expands a message into one message for each combination of the elements of multiple arrays, also known as the cartesian product.").
+		Description(`
+For each entry of `+"`fields`"+` the Bloblang query `+"`values`"+` is executed against the original message and must return an array. A message is then emitted for each combination of one element from each array, where the contents of the message is a copy of the original message with the chosen element of each field set at its `+"`path`"+`. All metadata of the original message is copied onto each message produced, and the position of each combination is added to the metadata field `+"`cross_product_index`"+`.
+
+Combinations are emitted in order of the fields, where the elements of the last field change the fastest. When any of the arrays is empty no combinations exist and the message is dropped.
+
+The number of combinations is the product of the lengths of all arrays, which grows very quickly, and therefore messages that would produce more than `+"`max_combinations`"+` messages are flagged as failed instead, along with messages that are not valid JSON or where a query does not return an array. Failed messages can be handled using xref:configuration:error_handling.adoc[error handling methods].`).
+		Field(service.NewObjectListField(cpFieldFields,
+			service.NewStringField(cpFieldFieldPath).
+				Description("A xref:configuration:field_paths.adoc[dot separated path] to set the chosen element at within each message produced, which can be the same path as the array itself."),
+			service.NewBloblangField(cpFieldFieldValues).
+				Description("A Bloblang query that returns an array of elements to choose from."),
+		).
+			Description("The fields to produce combinations of.")).
+		Field(service.NewIntField(cpFieldMaxCombinations).
+			Description("The maximum number of messages that a single message may be expanded into.").
+			Default(1000)).
+		Example("Notification variants", "Generate a notification for each combination of channel and language from a single template.", `
+pipeline:
+  processors:
+    - cross_product:
+        fields:
+          - path: channel
+            values: this.channels
+          - path: language
+            values: this.languages
+    - mapping: |
+        root = this.without("channels", "languages")
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"cross_product", crossProductProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return crossProductProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type crossProductField struct {
+	path   string
+	values *bloblang.Executor
+}
+
+type crossProductProc struct {
+	fields          []crossProductField
+	maxCombinations int
+}
+
+func crossProductProcFromParsed(conf *service.ParsedConfig) (*crossProductProc, error) {
+	p := &crossProductProc{}
+
+	fieldConfs, err := conf.FieldObjectList(cpFieldFields)
+	if err != nil {
+		return nil, err
+	}
+	if len(fieldConfs) == 0 {
+		return nil, errors.New("at least one field must be specified")
+	}
+	for _, fConf := range fieldConfs {
+		var f crossProductField
+		if f.path, err = fConf.FieldString(cpFieldFieldPath); err != nil {
+			return nil, err
+		}
+		if f.values, err = fConf.FieldBloblang(cpFieldFieldValues); err != nil {
+			return nil, err
+		}
+		p.fields = append(p.fields, f)
+	}
+
+	if p.maxCombinations, err = conf.FieldInt(cpFieldMaxCombinations); err != nil {
+		return nil, err
+	}
+	if p.maxCombinations < 1 {
+		return nil, fmt.Errorf("field %v must be greater than zero", cpFieldMaxCombinations)
+	}
+	return p, nil
+}
+
+func (p *crossProductProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	if _, err := msg.AsStructured(); err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %w", err)
+	}
+
+	arrays := make([][]any, len(p.fields))
+	total := 1
+	for i, f := range p.fields {
+		resMsg, err := msg.BloblangQuery(f.values)
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %w", f.path, err)
+		}
+		if resMsg == nil {
+			return nil, fmt.Errorf("field %v: query deleted the value", f.path)
+		}
+		v, err := resMsg.AsStructured()
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %w", f.path, err)
+		}
+		arr, ok := v.([]any)
+		if !ok {
+			return nil, fmt.Errorf("field %v: expected array value, got %T", f.path, v)
+		}
+		if len(arr) == 0 {
+			return nil, nil
+		}
+		if len(arr) > p.maxCombinations/total {
+			return nil, fmt.Errorf("message would produce more than the maximum of %v combinations", p.maxCombinations)
+		}
+		total *= len(arr)
+		arrays[i] = arr
+	}
+
+	batch := make(service.MessageBatch, 0, total)
+	indexes := make([]int, len(arrays))
+	for n := 0; n < total; n++ {
+		part := msg.Copy()
+		v, err := part.AsStructuredMut()
+		if err != nil {
+			return nil, err
+		}
+		doc := gabs.Wrap(v)
+		for i, f := range p.fields {
+			if _, err := doc.SetP(arrays[i][indexes[i]], f.path); err != nil {
+				return nil, fmt.Errorf("field %v: %w", f.path, err)
+			}
+		}
+		// Elements are shared between the messages produced and so the result
+		// is set immutably in order for later mutations to copy it.
+		part.SetStructured(doc.Data())
+		part.MetaSetMut("cross_product_index", n)
+		batch = append(batch, part)
+
+		// Advance the indexes like an odometer, with the last field changing
+		// the fastest.
+		for i := len(indexes) - 1; i >= 0; i-- {
+			if indexes[i]++; indexes[i] < len(arrays[i]) {
+				break
+			}
+			indexes[i] = 0
+		}
+	}
+	return batch, nil
+}
+
+func (p *crossProductProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,149 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestCrossProduct(t *testing.T) {
+	conf, err := crossProductProcConfig().ParseYAML(`
+fields:
+  - path: variant.color
+    values: this.colors
+  - path: variant.size
+    values: this.sizes
+  - path: tag
+    values: '[ "x" ]'
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := crossProductProcFromParsed(conf)
+	require.NoError(t, err)
+
+	msg := service.NewMessage([]byte(`{"colors":["red","blue"],"sizes":["S","M","L"]}`))
+	msg.MetaSetMut("foo", "bar")
+
+	res, err := proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`{"colors":["red","blue"],"sizes":["S","M","L"],"tag":"x","variant":{"color":"red","size":"S"}}`,
+		`{"colors":["red","blue"],"sizes":["S","M","L"],"tag":"x","variant":{"color":"red","size":"M"}}`,
+		`{"colors":["red","blue"],"sizes":["S","M","L"],"tag":"x","variant":{"color":"red","size":"L"}}`,
+		`{"colors":["red","blue"],"sizes":["S","M","L"],"tag":"x","variant":{"color":"blue","size":"S"}}`,
+		`{"colors":["red","blue"],"sizes":["S","M","L"],"tag":"x","variant":{"color":"blue","size":"M"}}`,
+		`{"colors":["red","blue"],"sizes":["S","M","L"],"tag":"x","variant":{"color":"blue","size":"L"}}`,
+	}, batchContents(t, res))
+
+	for i, m := range res {
+		v, _ := m.MetaGet("foo")
+		assert.Equal(t, "bar", v)
+		idx, _ := m.MetaGetMut("cross_product_index")
+		assert.Equal(t, i, idx)
+	}
+
+	// The original message is left unchanged.
+	mBytes, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"colors":["red","blue"],"sizes":["S","M","L"]}`, string(mBytes))
+}
+
+func TestCrossProductReplaceArray(t *testing.T) {
+	conf, err := crossProductProcConfig().ParseYAML(`
+fields:
+  - path: user
+    values: this.user
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := crossProductProcFromParsed(conf)
+	require.NoError(t, err)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"user":[{"id":1},{"id":2}]}`)))
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{"user":{"id":1}}`, `{"user":{"id":2}}`}, batchContents(t, res))
+
+	// Mutating one message must not affect the others.
+	v, err := res[0].AsStructuredMut()
+	require.NoError(t, err)
+	v.(map[string]any)["user"].(map[string]any)["id"] = 10
+	assert.Equal(t, []string{`{"user":{"id":10}}`, `{"user":{"id":2}}`}, batchContents(t, res))
+}
+
+func TestCrossProductEmpty(t *testing.T) {
+	conf, err := crossProductProcConfig().ParseYAML(`
+fields:
+  - path: a
+    values: this.a
+  - path: b
+    values: this.b
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := crossProductProcFromParsed(conf)
+	require.NoError(t, err)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"a":[1,2],"b":[]}`)))
+	require.NoError(t, err)
+	assert.Empty(t, res)
+}
+
+func TestCrossProductErrors(t *testing.T) {
+	conf, err := crossProductProcConfig().ParseYAML(`
+fields:
+  - path: a
+    values: this.a
+  - path: b
+    values: this.b
+max_combinations: 6
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := crossProductProcFromParsed(conf)
+	require.NoError(t, err)
+
+	for _, input := range []string{
+		`not json`,
+		`{"a":[1,2],"b":"nope"}`,
+		`{"a":[1,2]}`,
+		`{"a":[1,2,3],"b":[1,2,3]}`,
+	} {
+		_, err := proc.Process(context.Background(), service.NewMessage([]byte(input)))
+		require.Error(t, err, input)
+	}
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"a":[1,2,3],"b":[1,2]}`)))
+	require.NoError(t, err)
+	assert.Len(t, res, 6)
+}
+
+func TestCrossProductBadConfig(t *testing.T) {
+	for _, conf := range []string{
+		`fields: []`,
+		"fields: [ { path: a, values: this.a } ]\nmax_combinations: 0",
+	} {
+		pConf, err := crossProductProcConfig().ParseYAML(conf, nil)
+		require.NoError(t, err, conf)
+
+		_, err = crossProductProcFromParsed(pConf)
+		require.Error(t, err, conf)
+	}
+}
@@ -0,0 +1,337 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	dqFieldRules         = "rules"
+	dqFieldRuleName      = "name"
+	dqFieldRuleRequired  = "required"
+	dqFieldRuleCheck     = "check"
+	dqFieldRuleRange     = "range"
+	dqFieldRuleRangePath = "path"
+	dqFieldRuleRangeMin  = "min"
+	dqFieldRuleRangeMax  = "max"
+	dqFieldRuleWeight    = "weight"
+	dqFieldThreshold     = "threshold"
+)
+
+func dataQualityProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Scores the quality of each message against a set of rules.").
+		Description(`
+Each rule is one of the following kinds:
+
+- `+"`required`"+`: A completeness rule that passes when each of a list of paths is present and is neither `+"`null`"+` nor an empty string.
+- `+"`check`"+`: A validity rule that passes when a Bloblang query returns `+"`true`"+`. Queries that fail, for example because a field is missing, or that return anything other than a boolean do not pass.
+- `+"`range`"+`: A rule that passes when the value of a path is a number within an inclusive minimum and maximum.
+
+The score of a message is the sum of the weights of the rules that pass divided by the sum of the weights of all rules, which is a number between 0 and 1. The contents of messages are not changed.
+
+== Metadata
+
+This processor adds the following metadata fields to each message:
+
+`+"```text"+`
+- data_quality_score
+- data_quality_failed
+`+"```"+`
+
+Where `+"`data_quality_failed`"+` is a comma separated list of the names of the rules that did not pass, in the order of the rules, and is empty when all rules pass.
+
+== Quarantine
+
+When a `+"`threshold`"+` is set messages with a score below it are flagged as failed, and can be routed to a separate output using xref:configuration:error_handling.adoc[error handling], for example with a `+"`switch`"+` output as shown in the examples.`).
+		Field(service.NewObjectListField(dqFieldRules,
+			service.NewStringField(dqFieldRuleName).
+				Description("A unique name of the rule."),
+			service.NewStringListField(dqFieldRuleRequired).
+				Description("A list of xref:configuration:field_paths.adoc[dot separated paths] that must be present.").
+				Optional(),
+			service.NewBloblangField(dqFieldRuleCheck).
+				Description("A Bloblang query that must return `true`.").
+				Optional(),
+			service.NewObjectField(dqFieldRuleRange,
+				service.NewStringField(dqFieldRuleRangePath).
+					Description("A xref:configuration:field_paths.adoc[dot separated path] to a number."),
+				service.NewFloatField(dqFieldRuleRangeMin).
+					Description("The minimum value of the number.").
+					Optional(),
+				service.NewFloatField(dqFieldRuleRangeMax).
+					Description("The maximum value of the number.").
+					Optional(),
+			).
+				Description("A number that must be within a range.").
+				Optional(),
+			service.NewFloatField(dqFieldRuleWeight).
+				Description("The weight of the rule within the score.").
+				Default(1.0),
+		).
+			Description("The rules to score messages against, where each rule must have exactly one of `required`, `check` or `range`.")).
+		Field(service.NewFloatField(dqFieldThreshold).
+			Description("An optional minimum score, below which messages are flagged as failed.").
+			Optional().
+			Example(0.8)).
+		Example("Quarantine orders", "Score orders and route those scoring below 0.75 to a quarantine topic along with the rules they failed.", `
+pipeline:
+  processors:
+    - data_quality:
+        threshold: 0.75
+        rules:
+          - name: complete
+            required: [ id, customer.email, items ]
+            weight: 2
+          - name: valid_email
+            check: 'this.customer.email.re_match("^[^@]+@[^@]+$")'
+          - name: sane_total
+            range:
+              path: total
+              min: 0
+              max: 100000
+
+output:
+  switch:
+    cases:
+      - check: errored()
+        output:
+          kafka_franz:
+            seed_brokers: [ localhost:9092 ]
+            topic: orders_quarantine
+          processors:
+            - catch: []
+      - output:
+          kafka_franz:
+            seed_brokers: [ localhost:9092 ]
+            topic: orders
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"data_quality", dataQualityProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return dataQualityProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type dataQualityRule struct {
+	name   string
+	weight float64
+
+	required []string
+	check    *bloblang.Executor
+
+	rangePath string
+	min, max  *float64
+}
+
+type dataQualityProc struct {
+	rules       []dataQualityRule
+	totalWeight float64
+	threshold   *float64
+}
+
+func dataQualityProcFromParsed(conf *service.ParsedConfig) (*dataQualityProc, error) {
+	p := &dataQualityProc{}
+
+	ruleConfs, err := conf.FieldObjectList(dqFieldRules)
+	if err != nil {
+		return nil, err
+	}
+	if len(ruleConfs) == 0 {
+		return nil, errors.New("at least one rule must be specified")
+	}
+
+	names := map[string]struct{}{}
+	for _, rConf := range ruleConfs {
+		var r dataQualityRule
+		if r.name, err = rConf.FieldString(dqFieldRuleName); err != nil {
+			return nil, err
+		}
+		if _, exists := names[r.name]; exists {
+			return nil, fmt.Errorf("rule name %v is not unique", r.name)
+		}
+		names[r.name] = struct{}{}
+
+		if r.weight, err = rConf.FieldFloat(dqFieldRuleWeight); err != nil {
+			return nil, err
+		}
+		if r.weight < 0 {
+			return nil, fmt.Errorf("rule %v: weight must not be negative", r.name)
+		}
+
+		kinds := 0
+		if rConf.Contains(dqFieldRuleRequired) {
+			if r.required, err = rConf.FieldStringList(dqFieldRuleRequired); err != nil {
+				return nil, err
+			}
+			if len(r.required) > 0 {
+				kinds++
+			}
+		}
+		if rConf.Contains(dqFieldRuleCheck) {
+			kinds++
+			if r.check, err = rConf.FieldBloblang(dqFieldRuleCheck); err != nil {
+				return nil, err
+			}
+		}
+		if rConf.Contains(dqFieldRuleRange, dqFieldRuleRangePath) {
+			kinds++
+			rangeConf := rConf.Namespace(dqFieldRuleRange)
+			if r.rangePath, err = rangeConf.FieldString(dqFieldRuleRangePath); err != nil {
+				return nil, err
+			}
+			if rangeConf.Contains(dqFieldRuleRangeMin) {
+				v, err := rangeConf.FieldFloat(dqFieldRuleRangeMin)
+				if err != nil {
+					return nil, err
+				}
+				r.min = &v
+			}
+			if rangeConf.Contains(dqFieldRuleRangeMax) {
+				v, err := rangeConf.FieldFloat(dqFieldRuleRangeMax)
+				if err != nil {
+					return nil, err
+				}
+				r.max = &v
+			}
+		}
+		if kinds != 1 {
+			return nil, fmt.Errorf("rule %v must have exactly one of %v, %v or %v", r.name, dqFieldRuleRequired, dqFieldRuleCheck, dqFieldRuleRange)
+		}
+
+		p.totalWeight += r.weight
+		p.rules = append(p.rules, r)
+	}
+	if p.totalWeight == 0 {
+		return nil, errors.New("the weights of all rules must not be zero")
+	}
+
+	if conf.Contains(dqFieldThreshold) {
+		t, err := conf.FieldFloat(dqFieldThreshold)
+		if err != nil {
+			return nil, err
+		}
+		p.threshold = &t
+	}
+	return p, nil
+}
+
+func (r *dataQualityRule) passes(msg *service.Message, gObj *gabs.Container) bool {
+	switch {
+	case r.check != nil:
+		resMsg, err := msg.BloblangQuery(r.check)
+		if err != nil || resMsg == nil {
+			return false
+		}
+		v, err := resMsg.AsStructured()
+		if err != nil {
+			return false
+		}
+		b, _ := v.(bool)
+		return b
+	case r.rangePath != "":
+		if gObj == nil || !gObj.ExistsP(r.rangePath) {
+			return false
+		}
+		f, ok := dataQualityNumber(gObj.Path(r.rangePath).Data())
+		if !ok {
+			return false
+		}
+		return (r.min == nil || f >= *r.min) && (r.max == nil || f <= *r.max)
+	default:
+		if gObj == nil {
+			return false
+		}
+		for _, path := range r.required {
+			if !gObj.ExistsP(path) {
+				return false
+			}
+			if v := gObj.Path(path).Data(); v == nil || v == "" {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func dataQualityNumber(v any) (float64, bool) {
+	switch t := v.(type) {
+	case json.Number:
+		f, err := t.Float64()
+		return f, err == nil
+	case float64:
+		return t, true
+	case int:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case uint64:
+		return float64(t), true
+	}
+	return 0, false
+}
+
+func (p *dataQualityProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	var gObj *gabs.Container
+	if v, err := msg.AsStructured(); err == nil {
+		gObj = gabs.Wrap(v)
+	}
+
+	var (
+		passed float64
+		failed []string
+	)
+	for i := range p.rules {
+		r := &p.rules[i]
+		if r.passes(msg, gObj) {
+			passed += r.weight
+		} else {
+			failed = append(failed, r.name)
+		}
+	}
+
+	score := passed / p.totalWeight
+	msg.MetaSetMut("data_quality_score", strconv.FormatFloat(score, 'f', -1, 64))
+	msg.MetaSetMut("data_quality_failed", strings.Join(failed, ","))
+
+	if p.threshold != nil && score < *p.threshold {
+		return nil, fmt.Errorf("data quality score %v is below the threshold of %v, failed rules: %v", strconv.FormatFloat(score, 'f', -1, 64), *p.threshold, strings.Join(failed, ", "))
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *dataQualityProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const dataQualityTestConf = `
+rules:
+  - name: complete
+    required: [ id, customer.email ]
+    weight: 2
+  - name: valid_email
+    check: 'this.customer.email.contains("@")'
+  - name: sane_total
+    range:
+      path: total
+      min: 0
+      max: 100
+`
+
+func TestDataQualityScores(t *testing.T) {
+	conf, err := dataQualityProcConfig().ParseYAML(dataQualityTestConf, nil)
+	require.NoError(t, err)
+
+	proc, err := dataQualityProcFromParsed(conf)
+	require.NoError(t, err)
+
+	tests := []struct {
+		input  string
+		score  string
+		failed string
+	}{
+		{input: `{"id":1,"customer":{"email":"a@b"},"total":50}`, score: "1", failed: ""},
+		{input: `{"id":1,"customer":{"email":"nope"},"total":100}`, score: "0.75", failed: "valid_email"},
+		{input: `{"id":1,"customer":{"email":""},"total":-1}`, score: "0", failed: "complete,valid_email,sane_total"},
+		{input: `{"customer":{"email":"a@b"},"total":"5"}`, score: "0.25", failed: "complete,sane_total"},
+		{input: `not json`, score: "0", failed: "complete,valid_email,sane_total"},
+	}
+
+	for _, test := range tests {
+		batch, err := proc.Process(context.Background(), service.NewMessage([]byte(test.input)))
+		require.NoError(t, err, test.input)
+		require.Len(t, batch, 1)
+
+		score, _ := batch[0].MetaGet("data_quality_score")
+		assert.Equal(t, test.score, score, test.input)
+		failed, _ := batch[0].MetaGet("data_quality_failed")
+		assert.Equal(t, test.failed, failed, test.input)
+
+		mBytes, err := batch[0].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, test.input, string(mBytes))
+	}
+}
+
+func TestDataQualityThreshold(t *testing.T) {
+	conf, err := dataQualityProcConfig().ParseYAML(dataQualityTestConf+`
+threshold: 0.75
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := dataQualityProcFromParsed(conf)
+	require.NoError(t, err)
+
+	batch, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"id":1,"customer":{"email":"nope"},"total":5}`)))
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	msg := service.NewMessage([]byte(`{"id":1,"customer":{"email":"nope"},"total":500}`))
+	_, err = proc.Process(context.Background(), msg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed rules: valid_email, sane_total")
+
+	score, _ := msg.MetaGet("data_quality_score")
+	assert.Equal(t, "0.5", score)
+}
+
+func TestDataQualityConfigErrors(t *testing.T) {
+	for _, conf := range []string{
+		`rules: []`,
+		`rules: [ { name: a } ]`,
+		`rules: [ { name: a, required: [ foo ], check: 'true' } ]`,
+		`rules: [ { name: a, required: [ foo ] }, { name: a, check: 'true' } ]`,
+		`rules: [ { name: a, required: [ foo ], weight: 0 } ]`,
+	} {
+		pConf, err := dataQualityProcConfig().ParseYAML(conf, nil)
+		require.NoError(t, err, conf)
+
+		_, err = dataQualityProcFromParsed(pConf)
+		assert.Error(t, err, conf)
+	}
+}
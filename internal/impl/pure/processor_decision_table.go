@@ -0,0 +1,511 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	dtFieldPath           = "path"
+	dtFieldInputs         = "inputs"
+	dtFieldTargetPath     = "target_path"
+	dtFieldNoMatch        = "no_match"
+	dtFieldReloadInterval = "reload_interval"
+)
+
+func decisionTableProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Mapping").
+		Version("4.31.0").
+		Summary("Evaluates each message against the rows of a decision table loaded from a CSV file, and applies the results of the first matching row to the message.").
+		Description(`
+A decision table is a CSV file with a header row, where the columns named in `+"`inputs`"+` are condition columns and all other columns are result columns. Rows are evaluated from top to bottom, and a row matches a message when the value of every condition column of the message satisfies the condition of the row. The results of the first matching row are then written to the message, and the metadata field `+"`decision_table_row`"+` is set to the number of the row, where the first row after the header is row 1.
+
+== Conditions
+
+Each cell of a condition column contains one of the following conditions:
+
+|===
+| Condition | Matches
+
+| Empty, `+"`*`"+` or `+"`-`"+`
+| Any value.
+
+| `+"`gold`"+` or `+"`=gold`"+`
+| Values equal to `+"`gold`"+`. When both the cell and the value are numbers they are compared numerically, and therefore `+"`5`"+` matches `+"`5.0`"+`.
+
+| `+"`!=gold`"+`
+| Values not equal to `+"`gold`"+`.
+
+| `+"`gold*`"+`
+| Values matching a glob pattern, where `+"`*`"+` matches any sequence of characters and `+"`?`"+` matches a single character.
+
+| `+"`>10`"+`, `+"`>=10`"+`, `+"`<10`"+`, `+"`<=10`"+`
+| Numeric values that satisfy the comparison.
+
+| `+"`[10..20]`"+`, `+"`(10..20)`"+`, `+"`[10..20)`"+`
+| Numeric values within a range, where square brackets include the bound and parentheses exclude it.
+|===
+
+== Results
+
+Each result column is written to the message at the xref:configuration:field_paths.adoc[dot separated path] of its name, prefixed by `+"`target_path`"+` when it is set. Cells that contain valid JSON, such as numbers and booleans, are written as structured values and all other cells are written as strings. Empty result cells are not written.
+
+== Reloading
+
+The file is checked for changes every `+"`reload_interval`"+`, and when its modification time or size has changed the table is loaded again, which allows the table to be edited while the pipeline is running. When a modified table fails to load the error is logged and the previous table continues to be used.`).
+		Field(service.NewStringField(dtFieldPath).
+			Description("The path of the CSV file containing the decision table.").
+			Example("./rules/discounts.csv")).
+		Field(service.NewInterpolatedStringMapField(dtFieldInputs).
+			Description("A map of condition column names to interpolated strings that resolve to the value of each message to compare against the conditions of the column. Every condition column of the table must be listed.").
+			Example(map[string]any{
+				"tier":    `${! this.customer.tier }`,
+				"country": `${! this.address.country }`,
+				"total":   `${! this.total }`,
+			})).
+		Field(service.NewStringField(dtFieldTargetPath).
+			Description("An optional xref:configuration:field_paths.adoc[dot separated path] under which the results are written.").
+			Example("decision").
+			Default("")).
+		Field(service.NewStringAnnotatedEnumField(dtFieldNoMatch, map[string]string{
+			"pass":  "Leave the message unchanged.",
+			"error": "Flag the message as failed so that it can be handled using xref:configuration:error_handling.adoc[error handling methods].",
+		}).
+			Description("What to do with messages that do not match any row of the table.").
+			Default("pass")).
+		Field(service.NewDurationField(dtFieldReloadInterval).
+			Description("The period of time between checks for changes to the file. Set to `0s` in order to disable reloading.").
+			Default("30s").
+			Advanced()).
+		Example("Discount rules", "Apply a discount maintained in a spreadsheet, which is exported as a CSV file with the header `tier,country,total,discount,reason`.", `
+pipeline:
+  processors:
+    - decision_table:
+        path: ./rules/discounts.csv
+        inputs:
+          tier: ${! this.customer.tier }
+          country: ${! this.address.country }
+          total: ${! this.total }
+        target_path: pricing
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"decision_table", decisionTableProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return decisionTableProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type dtConditionKind int
+
+const (
+	dtAny dtConditionKind = iota
+	dtEqual
+	dtNotEqual
+	dtGlob
+	dtRange
+)
+
+// dtCondition is a parsed cell of a condition column.
+type dtCondition struct {
+	kind  dtConditionKind
+	value string
+
+	num   float64
+	isNum bool
+
+	lo, hi       float64
+	hasLo, hasHi bool
+	loInc, hiInc bool
+}
+
+func parseDTNumber(s string) (float64, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, fmt.Errorf("expected a number, got %q", s)
+	}
+	return f, nil
+}
+
+func parseDTCondition(cell string) (c dtCondition, err error) {
+	cell = strings.TrimSpace(cell)
+
+	switch {
+	case cell == "" || cell == "*" || cell == "-":
+		return dtCondition{kind: dtAny}, nil
+	case strings.HasPrefix(cell, "!="):
+		c.kind, c.value = dtNotEqual, strings.TrimSpace(cell[2:])
+	case strings.HasPrefix(cell, ">="):
+		c.kind, c.hasLo, c.loInc = dtRange, true, true
+		c.lo, err = parseDTNumber(cell[2:])
+		return
+	case strings.HasPrefix(cell, "<="):
+		c.kind, c.hasHi, c.hiInc = dtRange, true, true
+		c.hi, err = parseDTNumber(cell[2:])
+		return
+	case strings.HasPrefix(cell, ">"):
+		c.kind, c.hasLo = dtRange, true
+		c.lo, err = parseDTNumber(cell[1:])
+		return
+	case strings.HasPrefix(cell, "<"):
+		c.kind, c.hasHi = dtRange, true
+		c.hi, err = parseDTNumber(cell[1:])
+		return
+	case (cell[0] == '[' || cell[0] == '(') && strings.Contains(cell, ".."):
+		last := cell[len(cell)-1]
+		if last != ']' && last != ')' {
+			return c, fmt.Errorf("range %q must end with ] or )", cell)
+		}
+		lo, hi, _ := strings.Cut(cell[1:len(cell)-1], "..")
+		c.kind, c.hasLo, c.hasHi = dtRange, true, true
+		c.loInc, c.hiInc = cell[0] == '[', last == ']'
+		if c.lo, err = parseDTNumber(lo); err != nil {
+			return
+		}
+		if c.hi, err = parseDTNumber(hi); err != nil {
+			return
+		}
+		if c.lo > c.hi {
+			return c, fmt.Errorf("range %q has a lower bound greater than its upper bound", cell)
+		}
+		return
+	case strings.HasPrefix(cell, "="):
+		c.kind, c.value = dtEqual, strings.TrimSpace(cell[1:])
+	case strings.ContainsAny(cell, "*?"):
+		if _, err := path.Match(cell, ""); err != nil {
+			return c, fmt.Errorf("invalid pattern %q: %w", cell, err)
+		}
+		return dtCondition{kind: dtGlob, value: cell}, nil
+	default:
+		c.kind, c.value = dtEqual, cell
+	}
+
+	if f, err := strconv.ParseFloat(c.value, 64); err == nil {
+		c.num, c.isNum = f, true
+	}
+	return c, nil
+}
+
+func (c dtCondition) equals(v string) bool {
+	if c.isNum {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f == c.num
+		}
+	}
+	return v == c.value
+}
+
+func (c dtCondition) matches(v string) bool {
+	switch c.kind {
+	case dtAny:
+		return true
+	case dtEqual:
+		return c.equals(v)
+	case dtNotEqual:
+		return !c.equals(v)
+	case dtGlob:
+		matched, _ := path.Match(c.value, v)
+		return matched
+	case dtRange:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return false
+		}
+		if c.hasLo && (f < c.lo || (!c.loInc && f == c.lo)) {
+			return false
+		}
+		if c.hasHi && (f > c.hi || (!c.hiInc && f == c.hi)) {
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+type dtResult struct {
+	value any
+	set   bool
+}
+
+type dtRow struct {
+	conditions []dtCondition
+	results    []dtResult
+}
+
+// decisionTable is a parsed table where the conditions of each row are
+// ordered by inputColumns and the results by resultColumns.
+type decisionTable struct {
+	inputColumns  []string
+	resultColumns []string
+	rows          []dtRow
+}
+
+func parseDecisionTable(data []byte, inputs map[string]*service.InterpolatedString) (*decisionTable, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.TrimLeadingSpace = true
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("table is missing a header row")
+	}
+
+	t := &decisionTable{}
+	var inputIndexes, resultIndexes []int
+	seen := map[string]struct{}{}
+	for i, name := range records[0] {
+		name = strings.TrimSpace(name)
+		if _, exists := seen[name]; exists {
+			return nil, fmt.Errorf("duplicate column: %v", name)
+		}
+		seen[name] = struct{}{}
+		if _, isInput := inputs[name]; isInput {
+			t.inputColumns = append(t.inputColumns, name)
+			inputIndexes = append(inputIndexes, i)
+		} else {
+			t.resultColumns = append(t.resultColumns, name)
+			resultIndexes = append(resultIndexes, i)
+		}
+	}
+	for name := range inputs {
+		if _, exists := seen[name]; !exists {
+			return nil, fmt.Errorf("input column %v not found in table", name)
+		}
+	}
+	if len(t.resultColumns) == 0 {
+		return nil, errors.New("table must contain at least one result column")
+	}
+
+	for i, record := range records[1:] {
+		row := dtRow{
+			conditions: make([]dtCondition, len(inputIndexes)),
+			results:    make([]dtResult, len(resultIndexes)),
+		}
+		for j, idx := range inputIndexes {
+			if row.conditions[j], err = parseDTCondition(record[idx]); err != nil {
+				return nil, fmt.Errorf("row %v column %v: %w", i+1, t.inputColumns[j], err)
+			}
+		}
+		for j, idx := range resultIndexes {
+			cell := strings.TrimSpace(record[idx])
+			if cell == "" {
+				continue
+			}
+			var v any
+			if err := json.Unmarshal([]byte(cell), &v); err != nil {
+				v = cell
+			}
+			row.results[j] = dtResult{value: v, set: true}
+		}
+		t.rows = append(t.rows, row)
+	}
+	return t, nil
+}
+
+// match returns the index of the first row that matches the values of the
+// input columns, or -1 if no rows match.
+func (t *decisionTable) match(values []string) int {
+rows:
+	for i, row := range t.rows {
+		for j, c := range row.conditions {
+			if !c.matches(values[j]) {
+				continue rows
+			}
+		}
+		return i
+	}
+	return -1
+}
+
+//------------------------------------------------------------------------------
+
+type decisionTableProc struct {
+	path           string
+	inputs         map[string]*service.InterpolatedString
+	targetPath     string
+	errOnNoMatch   bool
+	reloadInterval time.Duration
+
+	mgr *service.Resources
+	log *service.Logger
+
+	tableMut sync.RWMutex
+	table    *decisionTable
+	modTime  time.Time
+	size     int64
+
+	reloadMut sync.Mutex
+	lastCheck time.Time
+}
+
+func decisionTableProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*decisionTableProc, error) {
+	p := &decisionTableProc{mgr: mgr, log: mgr.Logger()}
+
+	var err error
+	if p.path, err = conf.FieldString(dtFieldPath); err != nil {
+		return nil, err
+	}
+	if p.inputs, err = conf.FieldInterpolatedStringMap(dtFieldInputs); err != nil {
+		return nil, err
+	}
+	if p.targetPath, err = conf.FieldString(dtFieldTargetPath); err != nil {
+		return nil, err
+	}
+	noMatch, err := conf.FieldString(dtFieldNoMatch)
+	if err != nil {
+		return nil, err
+	}
+	p.errOnNoMatch = noMatch == "error"
+	if p.reloadInterval, err = conf.FieldDuration(dtFieldReloadInterval); err != nil {
+		return nil, err
+	}
+
+	if err := p.load(); err != nil {
+		return nil, fmt.Errorf("failed to load decision table: %w", err)
+	}
+	p.lastCheck = time.Now()
+	return p, nil
+}
+
+func (p *decisionTableProc) load() error {
+	info, err := p.mgr.FS().Stat(p.path)
+	if err != nil {
+		return err
+	}
+	data, err := service.ReadFile(p.mgr.FS(), p.path)
+	if err != nil {
+		return err
+	}
+	table, err := parseDecisionTable(data, p.inputs)
+	if err != nil {
+		return err
+	}
+
+	p.tableMut.Lock()
+	p.table, p.modTime, p.size = table, info.ModTime(), info.Size()
+	p.tableMut.Unlock()
+	return nil
+}
+
+// maybeReload loads the table again when the reload interval has passed since
+// the last check and the file has changed. Only one caller performs the check
+// at a time, and others continue with the current table.
+func (p *decisionTableProc) maybeReload() {
+	if p.reloadInterval <= 0 || !p.reloadMut.TryLock() {
+		return
+	}
+	defer p.reloadMut.Unlock()
+
+	if time.Since(p.lastCheck) < p.reloadInterval {
+		return
+	}
+	p.lastCheck = time.Now()
+
+	info, err := p.mgr.FS().Stat(p.path)
+	if err != nil {
+		p.log.Errorf("Failed to check decision table for changes: %v", err)
+		return
+	}
+
+	p.tableMut.RLock()
+	changed := !info.ModTime().Equal(p.modTime) || info.Size() != p.size
+	p.tableMut.RUnlock()
+	if !changed {
+		return
+	}
+
+	if err := p.load(); err != nil {
+		p.log.Errorf("Failed to reload decision table, continuing with the previous table: %v", err)
+		return
+	}
+	p.log.Infof("Reloaded decision table from %v", p.path)
+}
+
+func (p *decisionTableProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	p.maybeReload()
+
+	p.tableMut.RLock()
+	table := p.table
+	p.tableMut.RUnlock()
+
+	values := make([]string, len(table.inputColumns))
+	for i, col := range table.inputColumns {
+		v, err := p.inputs[col].TryString(msg)
+		if err != nil {
+			return nil, fmt.Errorf("input %v interpolation error: %w", col, err)
+		}
+		values[i] = v
+	}
+
+	rowIdx := table.match(values)
+	if rowIdx < 0 {
+		if p.errOnNoMatch {
+			return nil, errors.New("message did not match any row of the decision table")
+		}
+		return service.MessageBatch{msg}, nil
+	}
+
+	root, err := msg.AsStructuredMut()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %w", err)
+	}
+	gObj := gabs.Wrap(root)
+	for i, col := range table.resultColumns {
+		res := table.rows[rowIdx].results[i]
+		if !res.set {
+			continue
+		}
+		resPath := col
+		if p.targetPath != "" {
+			resPath = p.targetPath + "." + col
+		}
+		if _, err := gObj.SetP(res.value, resPath); err != nil {
+			return nil, fmt.Errorf("failed to set result %v: %w", col, err)
+		}
+	}
+	msg.SetStructuredMut(gObj.Data())
+	msg.MetaSetMut("decision_table_row", int64(rowIdx+1))
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *decisionTableProc) Close(ctx context.Context) error {
+	return nil
+}
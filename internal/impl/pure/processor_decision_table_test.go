@@ -0,0 +1,235 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const testDecisionTable = `tier,country,total,discount,reason
+gold,*,>=100,0.2,gold
+gold,,[0..100),0.1,
+silver,UK,>50,0.05,"silver, large"
+!=bronze,U*,(10..50],0.02,any
+*,*,*,0,none
+`
+
+func writeDecisionTable(t testing.TB, path, contents string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+}
+
+func TestDecisionTableConditions(t *testing.T) {
+	for _, test := range []struct {
+		cell    string
+		matches []string
+		misses  []string
+	}{
+		{cell: "", matches: []string{"", "a"}},
+		{cell: "*", matches: []string{"", "a"}},
+		{cell: "gold", matches: []string{"gold"}, misses: []string{"Gold", "golden"}},
+		{cell: "=5", matches: []string{"5", "5.0"}, misses: []string{"6", "five"}},
+		{cell: "!=gold", matches: []string{"silver", ""}, misses: []string{"gold"}},
+		{cell: "gold*", matches: []string{"gold", "golden"}, misses: []string{"silver"}},
+		{cell: "G?", matches: []string{"GB"}, misses: []string{"GBR"}},
+		{cell: ">10", matches: []string{"10.5"}, misses: []string{"10", "9", "nope"}},
+		{cell: ">=10", matches: []string{"10"}, misses: []string{"9.99"}},
+		{cell: "<10", matches: []string{"-3"}, misses: []string{"10"}},
+		{cell: "<=10", matches: []string{"10"}, misses: []string{"10.01"}},
+		{cell: "[10..20]", matches: []string{"10", "20"}, misses: []string{"9", "21"}},
+		{cell: "(10..20)", matches: []string{"15"}, misses: []string{"10", "20"}},
+		{cell: "[10..20)", matches: []string{"10"}, misses: []string{"20"}},
+	} {
+		c, err := parseDTCondition(test.cell)
+		require.NoError(t, err, test.cell)
+		for _, v := range test.matches {
+			assert.True(t, c.matches(v), "%q should match %q", test.cell, v)
+		}
+		for _, v := range test.misses {
+			assert.False(t, c.matches(v), "%q should not match %q", test.cell, v)
+		}
+	}
+
+	for _, cell := range []string{">ten", "[1..2", "[5..1]", "[a..2]", "[ab*"} {
+		_, err := parseDTCondition(cell)
+		assert.Error(t, err, cell)
+	}
+}
+
+func TestDecisionTable(t *testing.T) {
+	tablePath := filepath.Join(t.TempDir(), "table.csv")
+	writeDecisionTable(t, tablePath, testDecisionTable)
+
+	conf, err := decisionTableProcConfig().ParseYAML(`
+path: `+tablePath+`
+inputs:
+  tier: ${! this.tier }
+  country: ${! this.country }
+  total: ${! this.total }
+target_path: pricing
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := decisionTableProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		input  string
+		output string
+		row    int64
+	}{
+		{
+			input:  `{"tier":"gold","country":"FR","total":150}`,
+			output: `{"country":"FR","pricing":{"discount":0.2,"reason":"gold"},"tier":"gold","total":150}`,
+			row:    1,
+		},
+		{
+			input:  `{"tier":"gold","country":"FR","total":50}`,
+			output: `{"country":"FR","pricing":{"discount":0.1},"tier":"gold","total":50}`,
+			row:    2,
+		},
+		{
+			input:  `{"tier":"silver","country":"US","total":20}`,
+			output: `{"country":"US","pricing":{"discount":0.02,"reason":"any"},"tier":"silver","total":20}`,
+			row:    4,
+		},
+		{
+			input:  `{"tier":"bronze","country":"US","total":20}`,
+			output: `{"country":"US","pricing":{"discount":0,"reason":"none"},"tier":"bronze","total":20}`,
+			row:    5,
+		},
+	} {
+		res, err := proc.Process(context.Background(), service.NewMessage([]byte(test.input)))
+		require.NoError(t, err, test.input)
+		require.Len(t, res, 1)
+
+		b, err := res[0].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, test.output, string(b), test.input)
+
+		row, exists := res[0].MetaGetMut("decision_table_row")
+		require.True(t, exists)
+		assert.Equal(t, test.row, row)
+	}
+}
+
+func TestDecisionTableNoMatch(t *testing.T) {
+	tablePath := filepath.Join(t.TempDir(), "table.csv")
+	writeDecisionTable(t, tablePath, "tier,discount\ngold,0.2\n")
+
+	conf, err := decisionTableProcConfig().ParseYAML(`
+path: `+tablePath+`
+inputs:
+  tier: ${! this.tier }
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := decisionTableProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"tier":"silver"}`)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	b, err := res[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"tier":"silver"}`, string(b))
+	_, exists := res[0].MetaGetMut("decision_table_row")
+	assert.False(t, exists)
+
+	pConf, err := decisionTableProcConfig().ParseYAML(`
+path: `+tablePath+`
+inputs:
+  tier: ${! this.tier }
+no_match: error
+`, nil)
+	require.NoError(t, err)
+
+	proc, err = decisionTableProcFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"tier":"silver"}`)))
+	require.ErrorContains(t, err, "did not match")
+}
+
+func TestDecisionTableReload(t *testing.T) {
+	tablePath := filepath.Join(t.TempDir(), "table.csv")
+	writeDecisionTable(t, tablePath, "tier,discount\ngold,0.2\n")
+
+	conf, err := decisionTableProcConfig().ParseYAML(`
+path: `+tablePath+`
+inputs:
+  tier: ${! this.tier }
+reload_interval: 1ms
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := decisionTableProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	discount := func() any {
+		t.Helper()
+		res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"tier":"gold"}`)))
+		require.NoError(t, err)
+		v, err := res[0].AsStructured()
+		require.NoError(t, err)
+		return v.(map[string]any)["discount"]
+	}
+	assert.Equal(t, 0.2, discount())
+
+	writeDecisionTable(t, tablePath, "tier,discount\ngold,0.25\n")
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(tablePath, future, future))
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, 0.25, discount())
+
+	// A broken table is ignored and the previous table is kept.
+	writeDecisionTable(t, tablePath, "tier,discount\n>nope,1\n")
+	future = future.Add(time.Minute)
+	require.NoError(t, os.Chtimes(tablePath, future, future))
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, 0.25, discount())
+}
+
+func TestDecisionTableBadTables(t *testing.T) {
+	for _, table := range []string{
+		"",
+		"tier\ngold\n",
+		"tier,tier,discount\ngold,gold,1\n",
+		"country,discount\nUK,1\n",
+		"tier,discount\n>nope,1\n",
+	} {
+		tablePath := filepath.Join(t.TempDir(), "table.csv")
+		writeDecisionTable(t, tablePath, table)
+
+		pConf, err := decisionTableProcConfig().ParseYAML(`
+path: `+tablePath+`
+inputs:
+  tier: ${! this.tier }
+`, nil)
+		require.NoError(t, err)
+
+		_, err = decisionTableProcFromParsed(pConf, service.MockResources())
+		assert.Error(t, err, table)
+	}
+}
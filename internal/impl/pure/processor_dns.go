@@ -0,0 +1,364 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	dnsFieldOperator     = "operator"
+	dnsFieldRecordType   = "record_type"
+	dnsFieldValue        = "value"
+	dnsFieldTargetPath   = "target_path"
+	dnsFieldTimeout      = "timeout"
+	dnsFieldResolver     = "resolver"
+	dnsFieldCacheTTL     = "cache_ttl"
+	dnsFieldCacheSize    = "cache_size"
+	dnsFieldEmptyOnError = "empty_on_error"
+)
+
+func dnsProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Integration").
+		Version("4.31.0").
+		Summary("Resolves DNS records of hostnames or reverse lookups of IP addresses and writes the results to a field of each message.").
+		Description(`
+The `+"`lookup`"+` operator resolves records of the type `+"`record_type`"+` for the hostname resolved by `+"`value`"+`, and the `+"`reverse`"+` operator resolves the names of the IP address resolved by `+"`value`"+`. The results are written to `+"`target_path`"+` in the following formats:
+
+|===
+| Record type | Result
+
+| `+"`A`"+`, `+"`AAAA`"+`
+| An array of IP addresses.
+
+| `+"`MX`"+`
+| An array of objects with the fields `+"`host`"+` and `+"`pref`"+`.
+
+| `+"`TXT`"+`
+| An array of strings.
+
+| `+"`CNAME`"+`
+| A string.
+
+| `+"`reverse`"+`
+| An array of names.
+|===
+
+Trailing dots are removed from all names.
+
+== Caching
+
+Results are cached in memory for the period specified by `+"`cache_ttl`"+` in order to avoid sending a request for each message, which also applies to lookups of names that do not exist. The system resolver does not expose the TTLs of records, and therefore `+"`cache_ttl`"+` should be set no higher than the lowest TTL of the records being resolved when stale results are a concern. Lookups that fail for any other reason, such as a timeout, are not cached.
+
+== Error handling
+
+Messages where the lookup fails are flagged as failed and can be handled using xref:configuration:error_handling.adoc[error handling methods]. Alternatively, when `+"`empty_on_error`"+` is set an empty result is written to `+"`target_path`"+` instead.`).
+		Field(service.NewStringEnumField(dnsFieldOperator, "lookup", "reverse").
+			Description("The operation to perform.").
+			Default("lookup")).
+		Field(service.NewStringEnumField(dnsFieldRecordType, "A", "AAAA", "MX", "TXT", "CNAME").
+			Description("The type of records to resolve with the `lookup` operator.").
+			Default("A")).
+		Field(service.NewInterpolatedStringField(dnsFieldValue).
+			Description("The hostname or IP address to resolve for each message.").
+			Example(`${! this.host }`).
+			Example(`${! meta("client_ip") }`)).
+		Field(service.NewStringField(dnsFieldTargetPath).
+			Description("A xref:configuration:field_paths.adoc[dot separated path] to write the results to.").
+			Example("dns.addresses")).
+		Field(service.NewDurationField(dnsFieldTimeout).
+			Description("The maximum period to wait for each lookup.").
+			Default("5s")).
+		Field(service.NewStringField(dnsFieldResolver).
+			Description("An optional address of a DNS server to send requests to instead of the system resolver.").
+			Example("8.8.8.8:53").
+			Optional()).
+		Field(service.NewDurationField(dnsFieldCacheTTL).
+			Description("The period to cache the results of each lookup for, set to `0s` in order to disable caching.").
+			Default("5m").
+			Advanced()).
+		Field(service.NewIntField(dnsFieldCacheSize).
+			Description("The maximum number of results to cache.").
+			Default(10000).
+			Advanced()).
+		Field(service.NewBoolField(dnsFieldEmptyOnError).
+			Description("Whether to write an empty result to the target path when a lookup fails instead of flagging the message as failed.").
+			Default(false)).
+		Example("Reverse lookups", "Add the hostnames of client IP addresses to access logs.", `
+pipeline:
+  processors:
+    - dns:
+        operator: reverse
+        value: ${! this.client_ip }
+        target_path: client_hosts
+        empty_on_error: true
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"dns", dnsProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return dnsProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type dnsResolver interface {
+	LookupIP(ctx context.Context, network, host string) ([]net.IP, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupCNAME(ctx context.Context, host string) (string, error)
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
+type dnsCacheEntry struct {
+	value   any
+	err     error
+	expires time.Time
+}
+
+type dnsProc struct {
+	reverse      bool
+	recordType   string
+	value        *service.InterpolatedString
+	targetPath   string
+	timeout      time.Duration
+	emptyOnError bool
+	resolver     dnsResolver
+
+	cacheTTL  time.Duration
+	cacheSize int
+	cacheMut  sync.Mutex
+	cache     map[string]dnsCacheEntry
+}
+
+func dnsProcFromParsed(conf *service.ParsedConfig) (*dnsProc, error) {
+	p := &dnsProc{
+		resolver: net.DefaultResolver,
+		cache:    map[string]dnsCacheEntry{},
+	}
+
+	operator, err := conf.FieldString(dnsFieldOperator)
+	if err != nil {
+		return nil, err
+	}
+	p.reverse = operator == "reverse"
+	if p.recordType, err = conf.FieldString(dnsFieldRecordType); err != nil {
+		return nil, err
+	}
+	if p.value, err = conf.FieldInterpolatedString(dnsFieldValue); err != nil {
+		return nil, err
+	}
+	if p.targetPath, err = conf.FieldString(dnsFieldTargetPath); err != nil {
+		return nil, err
+	}
+	if p.timeout, err = conf.FieldDuration(dnsFieldTimeout); err != nil {
+		return nil, err
+	}
+	if p.emptyOnError, err = conf.FieldBool(dnsFieldEmptyOnError); err != nil {
+		return nil, err
+	}
+	if p.cacheTTL, err = conf.FieldDuration(dnsFieldCacheTTL); err != nil {
+		return nil, err
+	}
+	if p.cacheSize, err = conf.FieldInt(dnsFieldCacheSize); err != nil {
+		return nil, err
+	}
+
+	if conf.Contains(dnsFieldResolver) {
+		addr, err := conf.FieldString(dnsFieldResolver)
+		if err != nil {
+			return nil, err
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return nil, fmt.Errorf("invalid resolver address: %w", err)
+		}
+		p.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, addr)
+			},
+		}
+	}
+	return p, nil
+}
+
+func dnsTrimName(name string) string {
+	return strings.TrimSuffix(name, ".")
+}
+
+// lookup performs the configured lookup and returns the results in a form
+// that is safe to cache, which is converted to structured data by
+// dnsStructured.
+func (p *dnsProc) lookup(ctx context.Context, value string) (any, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	if p.reverse {
+		names, err := p.resolver.LookupAddr(ctx, value)
+		if err != nil {
+			return nil, err
+		}
+		for i, n := range names {
+			names[i] = dnsTrimName(n)
+		}
+		return names, nil
+	}
+
+	switch p.recordType {
+	case "A", "AAAA":
+		network := "ip4"
+		if p.recordType == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := p.resolver.LookupIP(ctx, network, value)
+		if err != nil {
+			return nil, err
+		}
+		addrs := make([]string, len(ips))
+		for i, ip := range ips {
+			addrs[i] = ip.String()
+		}
+		return addrs, nil
+	case "MX":
+		return p.resolver.LookupMX(ctx, value)
+	case "TXT":
+		return p.resolver.LookupTXT(ctx, value)
+	case "CNAME":
+		cname, err := p.resolver.LookupCNAME(ctx, value)
+		if err != nil {
+			return nil, err
+		}
+		return dnsTrimName(cname), nil
+	}
+	return nil, fmt.Errorf("record type not recognised: %v", p.recordType)
+}
+
+func (p *dnsProc) emptyResult() any {
+	if !p.reverse && p.recordType == "CNAME" {
+		return ""
+	}
+	return []any{}
+}
+
+func dnsStructured(v any) any {
+	switch t := v.(type) {
+	case []string:
+		arr := make([]any, len(t))
+		for i, s := range t {
+			arr[i] = s
+		}
+		return arr
+	case []*net.MX:
+		arr := make([]any, len(t))
+		for i, mx := range t {
+			arr[i] = map[string]any{
+				"host": dnsTrimName(mx.Host),
+				"pref": int64(mx.Pref),
+			}
+		}
+		return arr
+	}
+	return v
+}
+
+func (p *dnsProc) cachedLookup(ctx context.Context, value string) (any, error) {
+	if p.cacheTTL <= 0 {
+		return p.lookup(ctx, value)
+	}
+
+	now := time.Now()
+	p.cacheMut.Lock()
+	entry, exists := p.cache[value]
+	p.cacheMut.Unlock()
+	if exists && now.Before(entry.expires) {
+		return entry.value, entry.err
+	}
+
+	res, err := p.lookup(ctx, value)
+	var dnsErr *net.DNSError
+	if err != nil && !(errors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+		return nil, err
+	}
+
+	p.cacheMut.Lock()
+	if len(p.cache) >= p.cacheSize {
+		for k, e := range p.cache {
+			if now.After(e.expires) {
+				delete(p.cache, k)
+			}
+		}
+		// When every entry is still live an arbitrary one is evicted.
+		for k := range p.cache {
+			if len(p.cache) < p.cacheSize {
+				break
+			}
+			delete(p.cache, k)
+		}
+	}
+	if p.cacheSize > 0 {
+		p.cache[value] = dnsCacheEntry{value: res, err: err, expires: now.Add(p.cacheTTL)}
+	}
+	p.cacheMut.Unlock()
+	return res, err
+}
+
+func (p *dnsProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	value, err := p.value.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("value interpolation error: %w", err)
+	}
+
+	var result any
+	if res, err := p.cachedLookup(ctx, value); err == nil {
+		result = dnsStructured(res)
+	} else if p.emptyOnError {
+		result = p.emptyResult()
+	} else {
+		return nil, err
+	}
+
+	root, err := msg.AsStructuredMut()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %w", err)
+	}
+
+	gObj := gabs.Wrap(root)
+	if _, err := gObj.SetP(result, p.targetPath); err != nil {
+		return nil, fmt.Errorf("failed to set target path: %w", err)
+	}
+	msg.SetStructuredMut(gObj.Data())
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *dnsProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,221 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type fakeDNSResolver struct {
+	mut     sync.Mutex
+	lookups map[string]int
+}
+
+func (f *fakeDNSResolver) count(name string) error {
+	f.mut.Lock()
+	f.lookups[name]++
+	f.mut.Unlock()
+
+	switch name {
+	case "missing.example.com":
+		return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	case "broken.example.com":
+		return errors.New("server misbehaving")
+	}
+	return nil
+}
+
+func (f *fakeDNSResolver) LookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	if err := f.count(host); err != nil {
+		return nil, err
+	}
+	if network == "ip6" {
+		return []net.IP{net.ParseIP("2001:db8::1")}, nil
+	}
+	return []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("192.0.2.2")}, nil
+}
+
+func (f *fakeDNSResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	if err := f.count(name); err != nil {
+		return nil, err
+	}
+	return []*net.MX{{Host: "mx1.example.com.", Pref: 10}}, nil
+}
+
+func (f *fakeDNSResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if err := f.count(name); err != nil {
+		return nil, err
+	}
+	return []string{"v=spf1 -all"}, nil
+}
+
+func (f *fakeDNSResolver) LookupCNAME(ctx context.Context, host string) (string, error) {
+	if err := f.count(host); err != nil {
+		return "", err
+	}
+	return "target.example.com.", nil
+}
+
+func (f *fakeDNSResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	if err := f.count(addr); err != nil {
+		return nil, err
+	}
+	return []string{"host.example.com."}, nil
+}
+
+func dnsProcess(t testing.TB, proc *dnsProc, content string) (string, error) {
+	t.Helper()
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(content)))
+	if err != nil {
+		return "", err
+	}
+	require.Len(t, res, 1)
+
+	b, err := res[0].AsBytes()
+	require.NoError(t, err)
+	return string(b), nil
+}
+
+func TestDNSRecordTypes(t *testing.T) {
+	for _, test := range []struct {
+		conf     string
+		expected string
+	}{
+		{
+			conf:     `record_type: A`,
+			expected: `{"host":"example.com","res":["192.0.2.1","192.0.2.2"]}`,
+		},
+		{
+			conf:     `record_type: AAAA`,
+			expected: `{"host":"example.com","res":["2001:db8::1"]}`,
+		},
+		{
+			conf:     `record_type: MX`,
+			expected: `{"host":"example.com","res":[{"host":"mx1.example.com","pref":10}]}`,
+		},
+		{
+			conf:     `record_type: TXT`,
+			expected: `{"host":"example.com","res":["v=spf1 -all"]}`,
+		},
+		{
+			conf:     `record_type: CNAME`,
+			expected: `{"host":"example.com","res":"target.example.com"}`,
+		},
+		{
+			conf:     `operator: reverse`,
+			expected: `{"host":"example.com","res":["host.example.com"]}`,
+		},
+	} {
+		test := test
+		t.Run(test.conf, func(t *testing.T) {
+			pConf, err := dnsProcConfig().ParseYAML(test.conf+`
+value: ${! this.host }
+target_path: res
+`, nil)
+			require.NoError(t, err)
+
+			proc, err := dnsProcFromParsed(pConf)
+			require.NoError(t, err)
+
+			proc.resolver = &fakeDNSResolver{lookups: map[string]int{}}
+
+			res, err := dnsProcess(t, proc, `{"host":"example.com"}`)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, res)
+		})
+	}
+}
+
+func TestDNSCache(t *testing.T) {
+	conf, err := dnsProcConfig().ParseYAML(`
+value: ${! this.host }
+target_path: res
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := dnsProcFromParsed(conf)
+	require.NoError(t, err)
+
+	r := &fakeDNSResolver{lookups: map[string]int{}}
+	proc.resolver = r
+
+	for i := 0; i < 3; i++ {
+		_, err := dnsProcess(t, proc, `{"host":"example.com"}`)
+		require.NoError(t, err)
+
+		_, err = dnsProcess(t, proc, `{"host":"missing.example.com"}`)
+		require.Error(t, err)
+
+		_, err = dnsProcess(t, proc, `{"host":"broken.example.com"}`)
+		require.Error(t, err)
+	}
+
+	// Names that do not exist are cached, other failures are not.
+	assert.Equal(t, map[string]int{
+		"example.com":         1,
+		"missing.example.com": 1,
+		"broken.example.com":  3,
+	}, r.lookups)
+}
+
+func TestDNSCacheSize(t *testing.T) {
+	conf, err := dnsProcConfig().ParseYAML(`
+value: ${! this.host }
+target_path: res
+cache_size: 1
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := dnsProcFromParsed(conf)
+	require.NoError(t, err)
+
+	r := &fakeDNSResolver{lookups: map[string]int{}}
+	proc.resolver = r
+
+	for _, host := range []string{"a.example.com", "b.example.com", "a.example.com"} {
+		_, err := dnsProcess(t, proc, `{"host":"`+host+`"}`)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, map[string]int{"a.example.com": 2, "b.example.com": 1}, r.lookups)
+	assert.Len(t, proc.cache, 1)
+}
+
+func TestDNSEmptyOnError(t *testing.T) {
+	conf, err := dnsProcConfig().ParseYAML(`
+value: ${! this.host }
+target_path: res
+empty_on_error: true
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := dnsProcFromParsed(conf)
+	require.NoError(t, err)
+
+	proc.resolver = &fakeDNSResolver{lookups: map[string]int{}}
+
+	res, err := dnsProcess(t, proc, `{"host":"broken.example.com"}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"host":"broken.example.com","res":[]}`, res)
+}
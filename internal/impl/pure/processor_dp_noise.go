@@ -0,0 +1,239 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	dpnFieldFields      = "fields"
+	dpnFieldMechanism   = "mechanism"
+	dpnFieldEpsilon     = "epsilon"
+	dpnFieldDelta       = "delta"
+	dpnFieldSensitivity = "sensitivity"
+	dpnFieldMin         = "min"
+	dpnFieldMax         = "max"
+	dpnFieldSeed        = "seed"
+)
+
+func dpNoiseProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Mapping").
+		Version("4.31.0").
+		Summary("Adds random noise calibrated for differential privacy to numeric fields of messages.").
+		Description(`
+This processor is intended for releasing aggregated metrics, such as counts or sums calculated over a window, without exposing their exact values. The amount of noise added to each field is determined by the `+"`epsilon`"+` privacy budget and the `+"`sensitivity`"+` of the field, which is the maximum amount that the value of the field can change by when the data of a single individual is added or removed.
+
+The `+"`laplace`"+` mechanism adds noise drawn from a Laplace distribution with a scale of `+"`sensitivity / epsilon`"+`, and provides epsilon-differential privacy. The `+"`gaussian`"+` mechanism adds noise drawn from a normal distribution with a standard deviation of `+"`sensitivity * sqrt(2 * ln(1.25 / delta)) / epsilon`"+`, and provides (epsilon, delta)-differential privacy for values of `+"`epsilon`"+` below one.
+
+When `+"`min`"+` or `+"`max`"+` are set the noisy values are clamped to those bounds, which keeps them plausible (counts never become negative, for example) without affecting the privacy guarantee. Fields that do not exist within a message are ignored, and messages where a field is not a number are flagged as failed and can be handled using xref:configuration:error_handling.adoc[error handling methods].
+
+Each release of a value spends from the privacy budget, and therefore the same underlying data should not be released repeatedly with independent noise.`).
+		Field(service.NewStringListField(dpnFieldFields).
+			Description("A list of xref:configuration:field_paths.adoc[dot separated paths] of numeric fields to add noise to.").
+			Example([]string{"count", "stats.total_spend"})).
+		Field(service.NewStringEnumField(dpnFieldMechanism, "laplace", "gaussian").
+			Description("The distribution to draw noise from.").
+			Default("laplace")).
+		Field(service.NewFloatField(dpnFieldEpsilon).
+			Description("The privacy budget of each release, where lower values add more noise.").
+			Example(0.5)).
+		Field(service.NewFloatField(dpnFieldDelta).
+			Description("The probability of the privacy guarantee being broken, which only applies to the `gaussian` mechanism.").
+			Default(1e-5)).
+		Field(service.NewFloatField(dpnFieldSensitivity).
+			Description("The maximum amount that any field can change by due to the data of a single individual.").
+			Default(1.0)).
+		Field(service.NewFloatField(dpnFieldMin).
+			Description("An optional lower bound to clamp noisy values to.").
+			Optional()).
+		Field(service.NewFloatField(dpnFieldMax).
+			Description("An optional upper bound to clamp noisy values to.").
+			Optional()).
+		Field(service.NewIntField(dpnFieldSeed).
+			Description("An optional seed for the random number generator, which makes the noise added deterministic and should only be used for testing.").
+			Optional().
+			Advanced()).
+		Example("Noisy counts", "Release the number of visitors per page calculated over a window, with noise added to protect individual visitors.", `
+pipeline:
+  processors:
+    - dp_noise:
+        fields: [ visitors ]
+        epsilon: 0.5
+        sensitivity: 1
+        min: 0
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"dp_noise", dpNoiseProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return dpNoiseProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type dpNoiseProc struct {
+	fields   []string
+	gaussian bool
+	// The scale of the Laplace distribution, or the standard deviation of the
+	// normal distribution.
+	scale    float64
+	min, max *float64
+
+	rngMut sync.Mutex
+	rng    *rand.Rand
+}
+
+func dpNoiseProcFromParsed(conf *service.ParsedConfig) (*dpNoiseProc, error) {
+	p := &dpNoiseProc{}
+
+	var err error
+	if p.fields, err = conf.FieldStringList(dpnFieldFields); err != nil {
+		return nil, err
+	}
+	if len(p.fields) == 0 {
+		return nil, errors.New("at least one field must be specified")
+	}
+
+	mechanism, err := conf.FieldString(dpnFieldMechanism)
+	if err != nil {
+		return nil, err
+	}
+	p.gaussian = mechanism == "gaussian"
+
+	epsilon, err := conf.FieldFloat(dpnFieldEpsilon)
+	if err != nil {
+		return nil, err
+	}
+	if epsilon <= 0 {
+		return nil, errors.New("epsilon must be greater than zero")
+	}
+	sensitivity, err := conf.FieldFloat(dpnFieldSensitivity)
+	if err != nil {
+		return nil, err
+	}
+	if sensitivity <= 0 {
+		return nil, errors.New("sensitivity must be greater than zero")
+	}
+
+	if p.gaussian {
+		delta, err := conf.FieldFloat(dpnFieldDelta)
+		if err != nil {
+			return nil, err
+		}
+		if delta <= 0 || delta >= 1 {
+			return nil, errors.New("delta must be between zero and one")
+		}
+		p.scale = sensitivity * math.Sqrt(2*math.Log(1.25/delta)) / epsilon
+	} else {
+		p.scale = sensitivity / epsilon
+	}
+
+	for _, bound := range []struct {
+		field  string
+		target **float64
+	}{
+		{dpnFieldMin, &p.min},
+		{dpnFieldMax, &p.max},
+	} {
+		if !conf.Contains(bound.field) {
+			continue
+		}
+		v, err := conf.FieldFloat(bound.field)
+		if err != nil {
+			return nil, err
+		}
+		*bound.target = &v
+	}
+	if p.min != nil && p.max != nil && *p.min > *p.max {
+		return nil, errors.New("min must not be greater than max")
+	}
+
+	seed := time.Now().UnixNano()
+	if conf.Contains(dpnFieldSeed) {
+		s, err := conf.FieldInt(dpnFieldSeed)
+		if err != nil {
+			return nil, err
+		}
+		seed = int64(s)
+	}
+	p.rng = rand.New(rand.NewSource(seed))
+	return p, nil
+}
+
+// noise draws a value from the configured distribution, which is centred on
+// zero.
+func (p *dpNoiseProc) noise() float64 {
+	p.rngMut.Lock()
+	defer p.rngMut.Unlock()
+
+	if p.gaussian {
+		return p.rng.NormFloat64() * p.scale
+	}
+	// The difference of two exponentially distributed values follows a
+	// Laplace distribution.
+	return (p.rng.ExpFloat64() - p.rng.ExpFloat64()) * p.scale
+}
+
+func (p *dpNoiseProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	root, err := msg.AsStructuredMut()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %w", err)
+	}
+
+	gObj := gabs.Wrap(root)
+	for _, path := range p.fields {
+		if !gObj.ExistsP(path) {
+			continue
+		}
+		v, ok := dataQualityNumber(gObj.Path(path).Data())
+		if !ok {
+			return nil, fmt.Errorf("field %v is not a number", path)
+		}
+
+		v += p.noise()
+		if p.min != nil && v < *p.min {
+			v = *p.min
+		}
+		if p.max != nil && v > *p.max {
+			v = *p.max
+		}
+		if _, err := gObj.SetP(v, path); err != nil {
+			return nil, fmt.Errorf("failed to set field %v: %w", path, err)
+		}
+	}
+	msg.SetStructuredMut(gObj.Data())
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *dpNoiseProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func dpNoiseProcess(t testing.TB, proc *dpNoiseProc, content string) map[string]any {
+	t.Helper()
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(content)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	v, err := res[0].AsStructured()
+	require.NoError(t, err)
+	return v.(map[string]any)
+}
+
+func TestDPNoiseSeeded(t *testing.T) {
+	conf, err := dpNoiseProcConfig().ParseYAML(`
+fields: [ count, nested.total ]
+epsilon: 0.5
+seed: 42
+`, nil)
+	require.NoError(t, err)
+
+	procA, err := dpNoiseProcFromParsed(conf)
+	require.NoError(t, err)
+
+	procB, err := dpNoiseProcFromParsed(conf)
+	require.NoError(t, err)
+
+	input := `{"count":100,"nested":{"total":2000},"name":"foo"}`
+	resA := dpNoiseProcess(t, procA, input)
+	assert.Equal(t, resA, dpNoiseProcess(t, procB, input))
+
+	assert.Equal(t, "foo", resA["name"])
+	assert.NotEqual(t, 100.0, resA["count"])
+	assert.NotEqual(t, 2000.0, resA["nested"].(map[string]any)["total"])
+}
+
+func TestDPNoiseDistributions(t *testing.T) {
+	for _, test := range []struct {
+		mechanism string
+		// The expected mean absolute deviation of the noise.
+		expected float64
+	}{
+		{mechanism: "laplace", expected: 2},
+		{mechanism: "gaussian", expected: 2 * math.Sqrt(2*math.Log(1.25/1e-5)) * math.Sqrt(2/math.Pi)},
+	} {
+		test := test
+		t.Run(test.mechanism, func(t *testing.T) {
+			conf, err := dpNoiseProcConfig().ParseYAML(`
+fields: [ v ]
+mechanism: `+test.mechanism+`
+epsilon: 0.5
+sensitivity: 1
+seed: 1
+`, nil)
+			require.NoError(t, err)
+
+			proc, err := dpNoiseProcFromParsed(conf)
+			require.NoError(t, err)
+
+			var total float64
+			n := 20000
+			for i := 0; i < n; i++ {
+				total += math.Abs(dpNoiseProcess(t, proc, `{"v":0}`)["v"].(float64))
+			}
+			assert.InEpsilon(t, test.expected, total/float64(n), 0.05)
+		})
+	}
+}
+
+func TestDPNoiseClamp(t *testing.T) {
+	conf, err := dpNoiseProcConfig().ParseYAML(`
+fields: [ v ]
+epsilon: 0.01
+min: 0
+max: 10
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := dpNoiseProcFromParsed(conf)
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		v := dpNoiseProcess(t, proc, `{"v":5}`)["v"].(float64)
+		assert.GreaterOrEqual(t, v, 0.0)
+		assert.LessOrEqual(t, v, 10.0)
+	}
+}
+
+func TestDPNoiseFieldErrors(t *testing.T) {
+	conf, err := dpNoiseProcConfig().ParseYAML(`
+fields: [ v ]
+epsilon: 1
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := dpNoiseProcFromParsed(conf)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]any{"w": "foo"}, dpNoiseProcess(t, proc, `{"w":"foo"}`))
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"v":"nope"}`)))
+	require.EqualError(t, err, "field v is not a number")
+
+	pConf, err := dpNoiseProcConfig().ParseYAML(`
+fields: [ v ]
+epsilon: 1
+min: 10
+max: 0
+`, nil)
+	require.NoError(t, err)
+	_, err = dpNoiseProcFromParsed(pConf)
+	require.EqualError(t, err, "min must not be greater than max")
+}
@@ -0,0 +1,201 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	dsFieldTimestampField  = "timestamp_field"
+	dsFieldTimestampFormat = "timestamp_format"
+	dsFieldMaxAge          = "max_age"
+	dsFieldAction          = "action"
+	dsFieldOnMissing       = "on_missing"
+)
+
+func dropStaleProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Removes messages with a timestamp that is older than a maximum age relative to the current time.").
+		Description(`
+This processor is useful for skipping a backlog of stale data that accumulated during an outage, or during a catch-up, so that only fresh data is processed.
+
+The timestamp of each message is obtained by executing the Bloblang query `+"`timestamp_field`"+`, which may return a timestamp, a string in the format `+"`timestamp_format`"+`, or a number of seconds since the unix epoch.
+
+When `+"`action`"+` is `+"`drop`"+` stale messages are removed from the pipeline and acknowledged, otherwise they are flagged as failed and can be handled using xref:configuration:error_handling.adoc[error handling methods], such as routing them to a dead letter queue.`).
+		Field(service.NewBloblangField(dsFieldTimestampField).
+			Description("A Bloblang query that returns the timestamp of a message.").
+			Examples(`this.created_at`, `@kafka_timestamp_unix`, `meta("event_time")`)).
+		Field(service.NewStringField(dsFieldTimestampFormat).
+			Description("The format of timestamps obtained as strings, expressed as a Go time layout.").
+			Default(time.RFC3339Nano).
+			Example("2006-01-02 15:04:05").
+			Advanced()).
+		Field(service.NewDurationField(dsFieldMaxAge).
+			Description("The maximum age of a message, messages with a timestamp older than this are considered stale.").
+			Example("1h").
+			Example("30s")).
+		Field(service.NewStringAnnotatedEnumField(dsFieldAction, map[string]string{
+			"drop":  "Remove stale messages from the pipeline.",
+			"error": "Flag stale messages as failed.",
+		}).
+			Description("The action to take on stale messages.").
+			Default("drop")).
+		Field(service.NewStringAnnotatedEnumField(dsFieldOnMissing, map[string]string{
+			"pass":  "Process the message as if it were fresh.",
+			"error": "Flag the message as failed.",
+		}).
+			Description("The action to take on messages where the timestamp is missing or cannot be parsed.").
+			Default("pass")).
+		Example("Skip backlog", "Drop Kafka messages that were produced more than an hour ago.", `
+pipeline:
+  processors:
+    - drop_stale:
+        timestamp_field: '@kafka_timestamp_unix'
+        max_age: 1h
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"drop_stale", dropStaleProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return dropStaleProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type dropStaleProc struct {
+	timestamp    *bloblang.Executor
+	format       string
+	maxAge       time.Duration
+	errorOnStale bool
+	errorOnMiss  bool
+
+	log *service.Logger
+	now func() time.Time
+}
+
+func dropStaleProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*dropStaleProc, error) {
+	p := &dropStaleProc{
+		log: mgr.Logger(),
+		now: time.Now,
+	}
+
+	var err error
+	if p.timestamp, err = conf.FieldBloblang(dsFieldTimestampField); err != nil {
+		return nil, err
+	}
+	if p.format, err = conf.FieldString(dsFieldTimestampFormat); err != nil {
+		return nil, err
+	}
+	if p.maxAge, err = conf.FieldDuration(dsFieldMaxAge); err != nil {
+		return nil, err
+	}
+
+	action, err := conf.FieldString(dsFieldAction)
+	if err != nil {
+		return nil, err
+	}
+	p.errorOnStale = action == "error"
+
+	onMissing, err := conf.FieldString(dsFieldOnMissing)
+	if err != nil {
+		return nil, err
+	}
+	p.errorOnMiss = onMissing == "error"
+	return p, nil
+}
+
+func (p *dropStaleProc) timestampOf(msg *service.Message) (time.Time, error) {
+	resMsg, err := msg.BloblangQuery(p.timestamp)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if resMsg == nil {
+		return time.Time{}, errors.New("timestamp query deleted the message")
+	}
+	// Strings are not valid JSON documents and so we fall back to the raw
+	// bytes of the result.
+	v, err := resMsg.AsStructured()
+	if err != nil {
+		b, err := resMsg.AsBytes()
+		if err != nil {
+			return time.Time{}, err
+		}
+		v = string(b)
+	}
+
+	var secs float64
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case string:
+		return time.Parse(p.format, strings.TrimSpace(t))
+	case json.Number:
+		if secs, err = t.Float64(); err != nil {
+			return time.Time{}, err
+		}
+	case float64:
+		secs = t
+	case int64:
+		secs = float64(t)
+	case uint64:
+		secs = float64(t)
+	case nil:
+		return time.Time{}, errors.New("timestamp is null")
+	default:
+		return time.Time{}, fmt.Errorf("expected timestamp value, got %T", v)
+	}
+	whole, frac := math.Modf(secs)
+	return time.Unix(int64(whole), int64(frac*1e9)), nil
+}
+
+func (p *dropStaleProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	ts, err := p.timestampOf(msg)
+	if err != nil {
+		if p.errorOnMiss {
+			return nil, fmt.Errorf("failed to obtain timestamp: %w", err)
+		}
+		p.log.Debugf("Passing message with missing timestamp: %v", err)
+		return service.MessageBatch{msg}, nil
+	}
+
+	if age := p.now().Sub(ts); age > p.maxAge {
+		if p.errorOnStale {
+			return nil, fmt.Errorf("message is stale: age %v exceeds %v", age, p.maxAge)
+		}
+		return nil, nil
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *dropStaleProc) Close(ctx context.Context) error {
+	return nil
+}
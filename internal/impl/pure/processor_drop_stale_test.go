@@ -0,0 +1,97 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestDropStaleDrop(t *testing.T) {
+	conf, err := dropStaleProcConfig().ParseYAML(`
+timestamp_field: this.ts
+max_age: 1h
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := dropStaleProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	proc.now = func() time.Time { return now }
+
+	for _, test := range []struct {
+		input string
+		kept  bool
+	}{
+		{input: `{"ts":"2024-06-01T11:30:00Z"}`, kept: true},
+		{input: `{"ts":"2024-06-01T10:30:00Z"}`, kept: false},
+		{input: `{"ts":1717241400}`, kept: true},
+		{input: `{"ts":1717237800.5}`, kept: false},
+		{input: `{"ts":"not a timestamp"}`, kept: true},
+		{input: `{"other":"field"}`, kept: true},
+	} {
+		res, err := proc.Process(context.Background(), service.NewMessage([]byte(test.input)))
+		require.NoError(t, err, test.input)
+		if test.kept {
+			assert.Len(t, res, 1, test.input)
+		} else {
+			assert.Empty(t, res, test.input)
+		}
+	}
+}
+
+func TestDropStaleErrors(t *testing.T) {
+	conf, err := dropStaleProcConfig().ParseYAML(`
+timestamp_field: meta("ts")
+timestamp_format: "2006-01-02 15:04:05"
+max_age: 10m
+action: error
+on_missing: error
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := dropStaleProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	proc.now = func() time.Time { return now }
+
+	msg := service.NewMessage(nil)
+	msg.MetaSetMut("ts", "2024-06-01 11:55:00")
+	res, err := proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+	assert.Len(t, res, 1)
+
+	msg = service.NewMessage(nil)
+	msg.MetaSetMut("ts", "2024-06-01 11:45:00")
+	_, err = proc.Process(context.Background(), msg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stale")
+
+	msg = service.NewMessage(nil)
+	msg.MetaSetMut("ts", "2024-06-01T11:55:00Z")
+	_, err = proc.Process(context.Background(), msg)
+	require.Error(t, err)
+
+	_, err = proc.Process(context.Background(), service.NewMessage(nil))
+	require.Error(t, err)
+}
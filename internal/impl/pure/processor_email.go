@@ -0,0 +1,355 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/Jeffail/gabs/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	emFieldField        = "field"
+	emFieldOperator     = "operator"
+	emFieldOnInvalid    = "on_invalid"
+	emFieldLowercase    = "lowercase_local_part"
+	emFieldStripPlus    = "strip_plus"
+	emFieldGmail        = "gmail"
+	emFieldCheckMX      = "check_mx"
+	emFieldMXTimeout    = "mx_timeout"
+	emFieldMXCacheTTL   = "mx_cache_ttl"
+	emOperatorValidate  = "validate"
+	emOperatorNormalize = "normalize"
+)
+
+func emailProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Parsing").
+		Version("4.31.0").
+		Summary("Validates an email address within each message, and optionally normalizes it to a canonical form.").
+		Description(`
+Addresses are validated against the addr-spec syntax of RFC 5322, where display names and comments are not accepted, the local part must be no longer than 64 characters, the domain must consist of at least two valid labels, and the whole address must be no longer than 254 characters.
+
+The `+"`normalize`"+` operator replaces each valid address with its canonical form, where the domain is always lowercased and the local part is transformed according to `+"`lowercase_local_part`"+`, `+"`strip_plus`"+` and `+"`gmail`"+`. The `+"`validate`"+` operator leaves addresses unchanged.
+
+== MX checks
+
+When `+"`check_mx`"+` is `+"`true`"+` an address is only valid when its domain is able to receive email, which is when it has MX records, or when it has no MX records but resolves to an address. The result of each check is cached for `+"`mx_cache_ttl`"+`. Messages where a check cannot be performed, for example due to a timeout, are flagged as failed regardless of `+"`on_invalid`"+`.
+
+== Metadata
+
+This processor adds the following metadata fields to each message:
+
+`+"```text"+`
+- email_valid
+- email_domain
+`+"```"+`
+
+Where `+"`email_domain`"+` is the lowercased domain of a valid address.`).
+		Field(service.NewStringField(emFieldField).
+			Description("A xref:configuration:field_paths.adoc[dot separated path] to the email address of each message.").
+			Example("contact.email")).
+		Field(service.NewStringAnnotatedEnumField(emFieldOperator, map[string]string{
+			emOperatorValidate:  "Validate each address, leaving it unchanged.",
+			emOperatorNormalize: "Validate each address and replace it with its canonical form.",
+		}).
+			Description("The operation to perform on each address.").
+			Default(emOperatorNormalize)).
+		Field(service.NewStringAnnotatedEnumField(emFieldOnInvalid, map[string]string{
+			"error": "Flag the message as failed so that it can be handled using xref:configuration:error_handling.adoc[error handling methods].",
+			"flag":  "Leave the address unchanged and set the metadata field `email_valid` to `false`.",
+		}).
+			Description("What to do with messages where the address is invalid.").
+			Default("error")).
+		Field(service.NewBoolField(emFieldLowercase).
+			Description("Whether to lowercase the local part of addresses when normalizing. Local parts are case sensitive by specification, although they're treated as case insensitive by almost all providers.").
+			Default(true)).
+		Field(service.NewBoolField(emFieldStripPlus).
+			Description("Whether to remove the sub-address from the local part of addresses when normalizing, which is everything from the first `+`, such that `jane+news@example.com` becomes `jane@example.com`.").
+			Default(false)).
+		Field(service.NewBoolField(emFieldGmail).
+			Description("Whether to remove dots and sub-addresses from the local part of Gmail addresses when normalizing, which Gmail ignores, and to replace the domain `googlemail.com` with `gmail.com`.").
+			Default(false)).
+		Field(service.NewBoolField(emFieldCheckMX).
+			Description("Whether to check that the domain of each address is able to receive email.").
+			Default(false)).
+		Field(service.NewDurationField(emFieldMXTimeout).
+			Description("The maximum period to wait for each MX check.").
+			Default("5s").
+			Advanced()).
+		Field(service.NewDurationField(emFieldMXCacheTTL).
+			Description("The period to cache the result of the MX check of each domain for.").
+			Default("1h").
+			Advanced()).
+		Example("Deduplicate contacts", "Normalize the email addresses of contacts so that they can be deduplicated, dropping contacts where the address is invalid.", `
+pipeline:
+  processors:
+    - email:
+        field: contact.email
+        strip_plus: true
+        gmail: true
+        check_mx: true
+        on_invalid: flag
+    - mapping: |
+        root = if @email_valid == "false" { deleted() }
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"email", emailProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return emailProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type emailMXEntry struct {
+	ok      bool
+	expires time.Time
+}
+
+type emailProc struct {
+	path        string
+	normalize   bool
+	flagInvalid bool
+	lowercase   bool
+	stripPlus   bool
+	gmail       bool
+
+	checkMX    bool
+	mxTimeout  time.Duration
+	mxCacheTTL time.Duration
+	resolver   dnsResolver
+	mxMut      sync.Mutex
+	mxCache    map[string]emailMXEntry
+}
+
+func emailProcFromParsed(conf *service.ParsedConfig) (*emailProc, error) {
+	p := &emailProc{
+		resolver: net.DefaultResolver,
+		mxCache:  map[string]emailMXEntry{},
+	}
+
+	var err error
+	if p.path, err = conf.FieldString(emFieldField); err != nil {
+		return nil, err
+	}
+
+	operator, err := conf.FieldString(emFieldOperator)
+	if err != nil {
+		return nil, err
+	}
+	switch operator {
+	case emOperatorValidate:
+	case emOperatorNormalize:
+		p.normalize = true
+	default:
+		return nil, fmt.Errorf("operator not recognised: %v", operator)
+	}
+
+	onInvalid, err := conf.FieldString(emFieldOnInvalid)
+	if err != nil {
+		return nil, err
+	}
+	switch onInvalid {
+	case "error":
+	case "flag":
+		p.flagInvalid = true
+	default:
+		return nil, fmt.Errorf("on_invalid action not recognised: %v", onInvalid)
+	}
+
+	if p.lowercase, err = conf.FieldBool(emFieldLowercase); err != nil {
+		return nil, err
+	}
+	if p.stripPlus, err = conf.FieldBool(emFieldStripPlus); err != nil {
+		return nil, err
+	}
+	if p.gmail, err = conf.FieldBool(emFieldGmail); err != nil {
+		return nil, err
+	}
+	if p.checkMX, err = conf.FieldBool(emFieldCheckMX); err != nil {
+		return nil, err
+	}
+	if p.mxTimeout, err = conf.FieldDuration(emFieldMXTimeout); err != nil {
+		return nil, err
+	}
+	if p.mxCacheTTL, err = conf.FieldDuration(emFieldMXCacheTTL); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func emailValidDomain(domain string) bool {
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, l := range labels {
+		if l == "" || len(l) > 63 || l[0] == '-' || l[len(l)-1] == '-' {
+			return false
+		}
+		for _, r := range l {
+			if r != '-' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				return false
+			}
+		}
+	}
+	// A top level domain is never entirely numeric.
+	return strings.IndexFunc(labels[len(labels)-1], func(r rune) bool {
+		return !unicode.IsDigit(r)
+	}) >= 0
+}
+
+// parseEmail validates an address and returns its local part and lowercased
+// domain.
+func parseEmail(s string) (local, domain string, err error) {
+	if len(s) > 254 {
+		return "", "", errors.New("address is too long")
+	}
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Name != "" || addr.Address != s {
+		return "", "", fmt.Errorf("invalid address syntax: %q", s)
+	}
+
+	at := strings.LastIndexByte(s, '@')
+	local, domain = s[:at], strings.ToLower(s[at+1:])
+	if len(local) > 64 {
+		return "", "", errors.New("local part is too long")
+	}
+	if !emailValidDomain(domain) {
+		return "", "", fmt.Errorf("invalid domain: %v", domain)
+	}
+	return local, domain, nil
+}
+
+func (p *emailProc) canonical(local, domain string) string {
+	if p.gmail && (domain == "gmail.com" || domain == "googlemail.com") {
+		domain = "gmail.com"
+		if i := strings.IndexByte(local, '+'); i >= 0 {
+			local = local[:i]
+		}
+		local = strings.ReplaceAll(local, ".", "")
+	}
+	if p.stripPlus {
+		if i := strings.IndexByte(local, '+'); i > 0 {
+			local = local[:i]
+		}
+	}
+	if p.lowercase {
+		local = strings.ToLower(local)
+	}
+	return local + "@" + domain
+}
+
+// acceptsMail checks whether a domain has MX records, or resolves to an
+// address in their absence, in which case it receives mail implicitly.
+func (p *emailProc) acceptsMail(ctx context.Context, domain string) (bool, error) {
+	p.mxMut.Lock()
+	entry, exists := p.mxCache[domain]
+	p.mxMut.Unlock()
+	if exists && time.Now().Before(entry.expires) {
+		return entry.ok, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.mxTimeout)
+	defer cancel()
+
+	isNotFound := func(err error) bool {
+		var dnsErr *net.DNSError
+		return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+	}
+
+	mxs, err := p.resolver.LookupMX(ctx, domain)
+	if err != nil && !isNotFound(err) {
+		return false, fmt.Errorf("failed to check MX records of %v: %w", domain, err)
+	}
+	ok := len(mxs) > 0
+	if !ok {
+		ips, err := p.resolver.LookupIP(ctx, "ip", domain)
+		if err != nil && !isNotFound(err) {
+			return false, fmt.Errorf("failed to resolve %v: %w", domain, err)
+		}
+		ok = len(ips) > 0
+	}
+
+	p.mxMut.Lock()
+	p.mxCache[domain] = emailMXEntry{ok: ok, expires: time.Now().Add(p.mxCacheTTL)}
+	p.mxMut.Unlock()
+	return ok, nil
+}
+
+func (p *emailProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	v, err := msg.AsStructuredMut()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message: %w", err)
+	}
+
+	gObj := gabs.Wrap(v)
+	if !gObj.ExistsP(p.path) {
+		return nil, fmt.Errorf("field %v not found", p.path)
+	}
+	raw, ok := gObj.Path(p.path).Data().(string)
+	if !ok {
+		return nil, fmt.Errorf("field %v is not a string, got %T", p.path, gObj.Path(p.path).Data())
+	}
+
+	local, domain, err := parseEmail(strings.TrimSpace(raw))
+	if err == nil && p.checkMX {
+		accepts, mxErr := p.acceptsMail(ctx, domain)
+		if mxErr != nil {
+			return nil, mxErr
+		}
+		if !accepts {
+			err = fmt.Errorf("domain %v does not accept email", domain)
+		}
+	}
+	if err != nil {
+		if p.flagInvalid {
+			msg.MetaSetMut("email_valid", "false")
+			return service.MessageBatch{msg}, nil
+		}
+		return nil, err
+	}
+
+	if p.normalize {
+		if _, err := gObj.SetP(p.canonical(local, domain), p.path); err != nil {
+			return nil, err
+		}
+		msg.SetStructuredMut(gObj.Data())
+	}
+
+	msg.MetaSetMut("email_valid", "true")
+	msg.MetaSetMut("email_domain", domain)
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *emailProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,182 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestParseEmail(t *testing.T) {
+	for _, input := range []string{
+		"jane@example.com",
+		"Jane.Doe+news@Example.COM",
+		"o'brien@sub.example.co.uk",
+		"user@xn--bcher-kva.example",
+	} {
+		_, _, err := parseEmail(input)
+		assert.NoError(t, err, input)
+	}
+
+	for _, input := range []string{
+		"",
+		"jane",
+		"jane@",
+		"@example.com",
+		"jane@localhost",
+		"jane@example..com",
+		"jane@-example.com",
+		"jane@example.123",
+		"jane doe@example.com",
+		"Jane <jane@example.com>",
+		"jane@@example.com",
+		"jane.@example.com",
+		"a@[192.0.2.1]",
+	} {
+		_, _, err := parseEmail(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestEmailNormalize(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		conf     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "defaults",
+			input:    " Jane.Doe+news@Example.COM ",
+			expected: "jane.doe+news@example.com",
+		},
+		{
+			name:     "case sensitive",
+			conf:     "lowercase_local_part: false",
+			input:    "Jane.Doe@Example.COM",
+			expected: "Jane.Doe@example.com",
+		},
+		{
+			name:     "strip plus",
+			conf:     "strip_plus: true",
+			input:    "jane.doe+news@example.com",
+			expected: "jane.doe@example.com",
+		},
+		{
+			name:     "gmail",
+			conf:     "gmail: true",
+			input:    "Jane.Doe+news@GoogleMail.com",
+			expected: "janedoe@gmail.com",
+		},
+		{
+			name:     "gmail only applies to gmail",
+			conf:     "gmail: true",
+			input:    "jane.doe+news@example.com",
+			expected: "jane.doe+news@example.com",
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			pConf, err := emailProcConfig().ParseYAML("field: email\n"+test.conf, nil)
+			require.NoError(t, err)
+
+			proc, err := emailProcFromParsed(pConf)
+			require.NoError(t, err)
+
+			res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"email":"`+test.input+`"}`)))
+			require.NoError(t, err)
+			require.Len(t, res, 1)
+			assert.Equal(t, []string{`{"email":"` + test.expected + `"}`}, batchContents(t, res))
+
+			valid, _ := res[0].MetaGet("email_valid")
+			assert.Equal(t, "true", valid)
+		})
+	}
+}
+
+func TestEmailValidateInvalid(t *testing.T) {
+	conf, err := emailProcConfig().ParseYAML(`
+field: email
+operator: validate
+on_invalid: flag
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := emailProcFromParsed(conf)
+	require.NoError(t, err)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"email":"Jane@Example.com"}`)))
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{"email":"Jane@Example.com"}`}, batchContents(t, res))
+	domain, _ := res[0].MetaGet("email_domain")
+	assert.Equal(t, "example.com", domain)
+
+	res, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"email":"nope"}`)))
+	require.NoError(t, err)
+	valid, _ := res[0].MetaGet("email_valid")
+	assert.Equal(t, "false", valid)
+
+	pConf, err := emailProcConfig().ParseYAML(`field: email`, nil)
+	require.NoError(t, err)
+
+	proc, err = emailProcFromParsed(pConf)
+	require.NoError(t, err)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"email":"nope"}`)))
+	require.Error(t, err)
+}
+
+func TestEmailCheckMX(t *testing.T) {
+	conf, err := emailProcConfig().ParseYAML(`
+field: email
+check_mx: true
+on_invalid: flag
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := emailProcFromParsed(conf)
+	require.NoError(t, err)
+
+	r := &fakeDNSResolver{lookups: map[string]int{}}
+	proc.resolver = r
+
+	for i := 0; i < 2; i++ {
+		res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"email":"jane@example.com"}`)))
+		require.NoError(t, err)
+		valid, _ := res[0].MetaGet("email_valid")
+		assert.Equal(t, "true", valid)
+
+		res, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"email":"jane@missing.example.com"}`)))
+		require.NoError(t, err)
+		valid, _ = res[0].MetaGet("email_valid")
+		assert.Equal(t, "false", valid)
+
+		_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"email":"jane@broken.example.com"}`)))
+		require.Error(t, err)
+	}
+
+	// Results are cached, except for failed checks. Domains without MX records
+	// are also resolved to addresses.
+	assert.Equal(t, map[string]int{
+		"example.com":         1,
+		"missing.example.com": 2,
+		"broken.example.com":  2,
+	}, r.lookups)
+}
@@ -0,0 +1,260 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/unicode"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	encFieldFrom           = "from"
+	encFieldTo             = "to"
+	encFieldOnInvalid      = "on_invalid"
+	encFieldDetectFallback = "detect_fallback"
+
+	encDetect = "detect"
+
+	encOnInvalidReplace = "replace"
+	encOnInvalidStrict  = "strict"
+)
+
+func encodingProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Parsing").
+		Version("4.31.0").
+		Summary("Converts the contents of messages from one character encoding to another.").
+		Description(`
+Character encodings are identified by either their https://www.iana.org/assignments/character-sets/character-sets.xhtml[IANA name^] or a https://encoding.spec.whatwg.org/#names-and-labels[WHATWG label^], case insensitively, which covers common encodings such as `+"`utf-8`, `utf-16le`, `iso-8859-1`, `windows-1252`, `shift_jis`, `euc-jp`, `gbk`, `big5` and `euc-kr`"+`.
+
+== Detection
+
+When `+"`from`"+` is set to `+"`detect`"+` the source encoding of each message is determined by a byte order mark when one is present, otherwise messages that are valid UTF-8 are assumed to be UTF-8, and all other messages are assumed to be encoded with `+"`detect_fallback`"+`. Detection is therefore only able to distinguish Unicode encodings from a single legacy encoding, and when the legacy encodings of your data vary it's better to determine the encoding of each message yourself and use a `+"xref:components:processors/switch.adoc[`switch` processor]"+` with multiple `+"`encoding`"+` processors. The encoding that was detected is added to the metadata field `+"`encoding_detected`"+`.
+
+A byte order mark at the beginning of a message is always removed when decoding from a Unicode encoding.
+
+== Invalid data
+
+With `+"`on_invalid`"+` set to `+"`replace`"+` byte sequences that are invalid in the source encoding are replaced with the Unicode replacement character, and characters that cannot be represented in the target encoding are replaced with a substitute character of that encoding (usually `+"`?`"+` or `+"`\\x1A`"+`).
+
+With `+"`on_invalid`"+` set to `+"`strict`"+` messages containing either are left unchanged and flagged as failed, and can be handled using xref:configuration:error_handling.adoc[error handling methods]. Decoders of legacy encodings do not distinguish invalid data from a literal replacement character, and therefore in strict mode a message that already contains the Unicode replacement character is also rejected unless it is being decoded from UTF-8.`).
+		Field(service.NewStringField(encFieldFrom).
+			Description("The encoding of the messages, or `detect` in order to detect it for each message.").
+			Examples("iso-8859-1", "shift_jis", "detect")).
+		Field(service.NewStringField(encFieldTo).
+			Description("The encoding to convert messages to.").
+			Default("utf-8")).
+		Field(service.NewStringAnnotatedEnumField(encFieldOnInvalid, map[string]string{
+			encOnInvalidReplace: "Replace invalid and unrepresentable characters.",
+			encOnInvalidStrict:  "Flag messages that contain invalid or unrepresentable characters as failed.",
+		}).
+			Description("How to handle byte sequences that are invalid in the source encoding and characters that cannot be represented in the target encoding.").
+			Default(encOnInvalidReplace)).
+		Field(service.NewStringField(encFieldDetectFallback).
+			Description("The encoding assumed when `from` is `detect` and a message is neither valid UTF-8 nor starts with a byte order mark.").
+			Default("windows-1252").
+			Advanced()).
+		Example("Shift-JIS to UTF-8", "Convert Shift-JIS encoded files to UTF-8, rejecting any files that aren't valid Shift-JIS.", `
+pipeline:
+  processors:
+    - encoding:
+        from: shift_jis
+        on_invalid: strict
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"encoding", encodingProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return encodingProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type namedEncoding struct {
+	name string
+	enc  encoding.Encoding
+}
+
+func lookupEncoding(name string) (namedEncoding, error) {
+	name = strings.TrimSpace(name)
+	if enc, err := ianaindex.IANA.Encoding(name); err == nil && enc != nil {
+		return namedEncoding{name: strings.ToLower(name), enc: enc}, nil
+	}
+	if enc, err := htmlindex.Get(name); err == nil {
+		return namedEncoding{name: strings.ToLower(name), enc: enc}, nil
+	}
+	return namedEncoding{}, fmt.Errorf("unrecognised character encoding: %v", name)
+}
+
+func isUTF8(enc encoding.Encoding) bool {
+	return enc == unicode.UTF8 || enc == unicode.UTF8BOM || enc == encoding.Nop
+}
+
+type encodingProc struct {
+	from     *namedEncoding
+	fallback namedEncoding
+	to       namedEncoding
+	strict   bool
+}
+
+func encodingProcFromParsed(conf *service.ParsedConfig) (*encodingProc, error) {
+	p := &encodingProc{}
+
+	fromStr, err := conf.FieldString(encFieldFrom)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(fromStr, encDetect) {
+		from, err := lookupEncoding(fromStr)
+		if err != nil {
+			return nil, err
+		}
+		p.from = &from
+	}
+
+	toStr, err := conf.FieldString(encFieldTo)
+	if err != nil {
+		return nil, err
+	}
+	if p.to, err = lookupEncoding(toStr); err != nil {
+		return nil, err
+	}
+
+	fallbackStr, err := conf.FieldString(encFieldDetectFallback)
+	if err != nil {
+		return nil, err
+	}
+	if p.fallback, err = lookupEncoding(fallbackStr); err != nil {
+		return nil, err
+	}
+
+	onInvalid, err := conf.FieldString(encFieldOnInvalid)
+	if err != nil {
+		return nil, err
+	}
+	p.strict = onInvalid == encOnInvalidStrict
+	return p, nil
+}
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+func (p *encodingProc) detect(b []byte) namedEncoding {
+	switch {
+	case bytes.HasPrefix(b, bomUTF8):
+		return namedEncoding{name: "utf-8", enc: unicode.UTF8}
+	case bytes.HasPrefix(b, bomUTF16LE):
+		return namedEncoding{name: "utf-16le", enc: unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)}
+	case bytes.HasPrefix(b, bomUTF16BE):
+		return namedEncoding{name: "utf-16be", enc: unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)}
+	case utf8.Valid(b):
+		return namedEncoding{name: "utf-8", enc: unicode.UTF8}
+	}
+	return p.fallback
+}
+
+var errEncodingInvalidInput = errors.New("message contains byte sequences that are invalid in the source encoding")
+
+func (p *encodingProc) decode(from namedEncoding, b []byte) ([]byte, error) {
+	if isUTF8(from.enc) {
+		b = bytes.TrimPrefix(b, bomUTF8)
+		if p.strict && !utf8.Valid(b) {
+			return nil, errEncodingInvalidInput
+		}
+		if !p.strict {
+			b = bytes.ToValidUTF8(b, []byte(string(utf8.RuneError)))
+		}
+		return b, nil
+	}
+
+	decoded, err := from.enc.NewDecoder().Bytes(b)
+	if err != nil {
+		return nil, err
+	}
+	// Decoders of Unicode encodings do not remove a byte order mark unless it
+	// was requested explicitly.
+	decoded = bytes.TrimPrefix(decoded, bomUTF8)
+	if p.strict && bytes.ContainsRune(decoded, utf8.RuneError) {
+		return nil, errEncodingInvalidInput
+	}
+	return decoded, nil
+}
+
+func (p *encodingProc) encode(b []byte) ([]byte, error) {
+	if isUTF8(p.to.enc) {
+		return b, nil
+	}
+	encoder := p.to.enc.NewEncoder()
+	if !p.strict {
+		encoder = encoding.ReplaceUnsupported(encoder)
+	}
+	res, err := encoder.Bytes(b)
+	if err != nil {
+		return nil, fmt.Errorf("message contains characters that cannot be represented in %v: %w", p.to.name, err)
+	}
+	return res, nil
+}
+
+func (p *encodingProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	mBytes, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var from namedEncoding
+	if p.from != nil {
+		from = *p.from
+	} else {
+		from = p.detect(mBytes)
+		msg.MetaSetMut("encoding_detected", from.name)
+	}
+
+	decoded, err := p.decode(from, mBytes)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := p.encode(decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	msg.SetBytes(encoded)
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *encodingProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestEncodingProcessor(t *testing.T) {
+	tests := []struct {
+		name   string
+		conf   string
+		input  []byte
+		output []byte
+		meta   string
+		errs   bool
+	}{
+		{
+			name:   "latin1 to utf8",
+			conf:   `from: iso-8859-1`,
+			input:  []byte("caf\xe9 na\xefve"),
+			output: []byte("café naïve"),
+		},
+		{
+			name:   "shift jis to utf8",
+			conf:   `from: shift_jis`,
+			input:  []byte{0x93, 0xfa, 0x96, 0x7b},
+			output: []byte("日本"),
+		},
+		{
+			name:   "utf8 to latin1",
+			conf:   "from: utf-8\nto: latin1",
+			input:  []byte("café"),
+			output: []byte("caf\xe9"),
+		},
+		{
+			name:   "unrepresentable replaced",
+			conf:   "from: utf-8\nto: iso-8859-1",
+			input:  []byte("日本"),
+			output: []byte("\x1a\x1a"),
+		},
+		{
+			name:  "unrepresentable strict",
+			conf:  "from: utf-8\nto: iso-8859-1\non_invalid: strict",
+			input: []byte("日本"),
+			errs:  true,
+		},
+		{
+			name:   "invalid shift jis replaced",
+			conf:   `from: shift_jis`,
+			input:  []byte{0x93, 0xfa, 0x85},
+			output: []byte("日�"),
+		},
+		{
+			name:  "invalid shift jis strict",
+			conf:  "from: shift_jis\non_invalid: strict",
+			input: []byte{0x93, 0xfa, 0x85},
+			errs:  true,
+		},
+		{
+			name:   "invalid utf8 replaced",
+			conf:   `from: utf-8`,
+			input:  []byte("foo\xffbar"),
+			output: []byte("foo�bar"),
+		},
+		{
+			name:  "invalid utf8 strict",
+			conf:  "from: utf-8\non_invalid: strict",
+			input: []byte("foo\xffbar"),
+			errs:  true,
+		},
+		{
+			name:   "utf16 with bom",
+			conf:   `from: utf-16le`,
+			input:  []byte{0xff, 0xfe, 'h', 0, 'i', 0},
+			output: []byte("hi"),
+		},
+		{
+			name:   "detect utf8",
+			conf:   `from: detect`,
+			input:  []byte("\xef\xbb\xbfcafé"),
+			output: []byte("café"),
+			meta:   "utf-8",
+		},
+		{
+			name:   "detect utf16be",
+			conf:   `from: detect`,
+			input:  []byte{0xfe, 0xff, 0, 'h', 0, 'i'},
+			output: []byte("hi"),
+			meta:   "utf-16be",
+		},
+		{
+			name:   "detect fallback",
+			conf:   `from: detect`,
+			input:  []byte("caf\xe9"),
+			output: []byte("café"),
+			meta:   "windows-1252",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			pConf, err := encodingProcConfig().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			proc, err := encodingProcFromParsed(pConf)
+			require.NoError(t, err)
+
+			res, err := proc.Process(context.Background(), service.NewMessage(test.input))
+			if test.errs {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, res, 1)
+
+			mBytes, err := res[0].AsBytes()
+			require.NoError(t, err)
+			assert.Equal(t, string(test.output), string(mBytes))
+
+			if test.meta != "" {
+				v, _ := res[0].MetaGet("encoding_detected")
+				assert.Equal(t, test.meta, v)
+			}
+		})
+	}
+}
+
+func TestEncodingProcessorBadConfig(t *testing.T) {
+	for _, conf := range []string{
+		`from: nope`,
+		"from: utf-8\nto: nope",
+		"from: detect\ndetect_fallback: nope",
+	} {
+		pConf, err := encodingProcConfig().ParseYAML(conf, nil)
+		require.NoError(t, err, conf)
+
+		_, err = encodingProcFromParsed(pConf)
+		require.Error(t, err, conf)
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	ecFieldMetadataKey   = "metadata_key"
+	ecFieldRetryCountKey = "retry_count_key"
+	ecFieldComponent     = "component"
+	ecFieldPath          = "path"
+)
+
+func errorContextProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Attaches structured context about the error of a failed message as metadata, intended for use within a `catch` block before messages are sent to a dead letter queue.").
+		Description(`
+For each message flagged with an error an object is added to the metadata key `+"`metadata_key`"+` of the following form:
+
+`+"```json"+`
+{
+  "error": "the error message",
+  "component": "the label of the failed component",
+  "path": "the location of this processor",
+  "timestamp": "2024-06-20T10:00:00.123Z",
+  "retry_count": 1
+}
+`+"```"+`
+
+The retry count is also maintained as a string in the metadata key `+"`retry_count_key`"+`, which is incremented each time a message passes through this processor. Since metadata keys are usually preserved as headers by outputs and inputs this allows the count to survive messages being reprocessed from a dead letter queue.
+
+Messages that are not flagged with an error are passed through unchanged.
+
+The label of the component that failed is not recorded along with the error, and therefore the field `+"`component`"+` must be configured in order to populate it, usually with the label of the processors wrapped by the `+"`try`"+` that the `+"`catch`"+` handles. When the field `+"`path`"+` is not set the label of this processor is used instead.`).
+		Field(service.NewStringField(ecFieldMetadataKey).
+			Description("The metadata key to store the error context in.").
+			Default("error_context")).
+		Field(service.NewStringField(ecFieldRetryCountKey).
+			Description("The metadata key to maintain the retry count of messages in.").
+			Default("error_retry_count")).
+		Field(service.NewInterpolatedStringField(ecFieldComponent).
+			Description("The label of the component that failed.").
+			Example("enrich_users").
+			Example(`${! @failed_step }`).
+			Default("")).
+		Field(service.NewStringField(ecFieldPath).
+			Description("An identifier of where within the config this processor resides, such as the name of the stream and pipeline.").
+			Example("orders.pipeline").
+			Default("")).
+		Example("Dead letter queue envelopes", "Wrap messages that fail to be enriched within an envelope describing the error before writing them to a dead letter topic.", `
+pipeline:
+  processors:
+    - try:
+        - label: enrich_users
+          http:
+            url: http://localhost:8080/users
+            verb: POST
+    - catch:
+        - error_context:
+            component: enrich_users
+            path: orders.pipeline
+        - mapping: |
+            root.context = @error_context
+            root.payload = content().string()
+
+output:
+  switch:
+    cases:
+      - check: '@error_context != null'
+        output:
+          kafka_franz:
+            seed_brokers: [ localhost:9092 ]
+            topic: orders_dlq
+      - output:
+          kafka_franz:
+            seed_brokers: [ localhost:9092 ]
+            topic: orders_enriched
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"error_context", errorContextProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return errorContextProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type errorContextProc struct {
+	metadataKey   string
+	retryCountKey string
+	component     *service.InterpolatedString
+	path          string
+	now           func() time.Time
+}
+
+func errorContextProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*errorContextProc, error) {
+	p := &errorContextProc{
+		now: time.Now,
+	}
+
+	var err error
+	if p.metadataKey, err = conf.FieldString(ecFieldMetadataKey); err != nil {
+		return nil, err
+	}
+	if p.retryCountKey, err = conf.FieldString(ecFieldRetryCountKey); err != nil {
+		return nil, err
+	}
+	if p.component, err = conf.FieldInterpolatedString(ecFieldComponent); err != nil {
+		return nil, err
+	}
+	if p.path, err = conf.FieldString(ecFieldPath); err != nil {
+		return nil, err
+	}
+	if p.path == "" {
+		p.path = mgr.Label()
+	}
+	return p, nil
+}
+
+func (p *errorContextProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	procErr := msg.GetError()
+	if procErr == nil {
+		return service.MessageBatch{msg}, nil
+	}
+
+	var retryCount int64
+	if v, exists := msg.MetaGet(p.retryCountKey); exists && v != "" {
+		var err error
+		if retryCount, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return nil, fmt.Errorf("failed to parse retry count from metadata key %v: %w", p.retryCountKey, err)
+		}
+	}
+	retryCount++
+
+	component, err := p.component.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("component interpolation error: %w", err)
+	}
+
+	msg.MetaSetMut(p.metadataKey, map[string]any{
+		"error":       procErr.Error(),
+		"component":   component,
+		"path":        p.path,
+		"timestamp":   p.now().UTC().Format(time.RFC3339Nano),
+		"retry_count": retryCount,
+	})
+	msg.MetaSetMut(p.retryCountKey, strconv.FormatInt(retryCount, 10))
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *errorContextProc) Close(ctx context.Context) error {
+	return nil
+}
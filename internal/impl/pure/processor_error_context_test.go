@@ -0,0 +1,109 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestErrorContext(t *testing.T) {
+	conf, err := errorContextProcConfig().ParseYAML(`
+component: ${! @step }
+path: orders.pipeline
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := errorContextProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	proc.now = func() time.Time {
+		return time.Date(2024, 6, 20, 10, 0, 0, 0, time.UTC)
+	}
+
+	msg := service.NewMessage([]byte(`hello world`))
+	msg.MetaSetMut("step", "enrich_users")
+	msg.SetError(errors.New("connection refused"))
+
+	for i := int64(1); i <= 2; i++ {
+		batch, err := proc.Process(context.Background(), msg)
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		msg = batch[0]
+
+		v, exists := msg.MetaGetMut("error_context")
+		require.True(t, exists)
+		assert.Equal(t, map[string]any{
+			"error":       "connection refused",
+			"component":   "enrich_users",
+			"path":        "orders.pipeline",
+			"timestamp":   "2024-06-20T10:00:00Z",
+			"retry_count": i,
+		}, v)
+
+		count, _ := msg.MetaGet("error_retry_count")
+		assert.Equal(t, strconv.FormatInt(i, 10), count)
+		assert.EqualError(t, msg.GetError(), "connection refused")
+	}
+}
+
+func TestErrorContextNoError(t *testing.T) {
+	conf, err := errorContextProcConfig().ParseYAML(`{}`, nil)
+	require.NoError(t, err)
+
+	proc, err := errorContextProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	proc.now = func() time.Time {
+		return time.Date(2024, 6, 20, 10, 0, 0, 0, time.UTC)
+	}
+
+	batch, err := proc.Process(context.Background(), service.NewMessage([]byte(`hello world`)))
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	_, exists := batch[0].MetaGetMut("error_context")
+	assert.False(t, exists)
+	_, exists = batch[0].MetaGetMut("error_retry_count")
+	assert.False(t, exists)
+}
+
+func TestErrorContextBadRetryCount(t *testing.T) {
+	conf, err := errorContextProcConfig().ParseYAML(`{}`, nil)
+	require.NoError(t, err)
+
+	proc, err := errorContextProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	proc.now = func() time.Time {
+		return time.Date(2024, 6, 20, 10, 0, 0, 0, time.UTC)
+	}
+
+	msg := service.NewMessage([]byte(`hello world`))
+	msg.MetaSetMut("error_retry_count", "nope")
+	msg.SetError(errors.New("meow"))
+
+	_, err = proc.Process(context.Background(), msg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse retry count")
+}
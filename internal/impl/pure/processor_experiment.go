@@ -0,0 +1,219 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	expFieldVariants      = "variants"
+	expFieldVariantName   = "name"
+	expFieldVariantWeight = "weight"
+	expFieldKey           = "key"
+	expFieldSalt          = "salt"
+	expFieldMetadataKey   = "metadata_key"
+)
+
+func experimentProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Assigns each message to one of a list of weighted variants and writes the name of the variant to a metadata field.").
+		Description(`
+The probability of a message being assigned to a variant is its weight divided by the sum of the weights of all variants. The contents of messages are not modified, and the variant can be used by subsequent components, for example with a `+"xref:components:outputs/switch.adoc[`switch` output]"+` or within analytics.
+
+== Sticky assignment
+
+When a `+"`key`"+` is set the variant of each message is derived from a hash of its key, and therefore messages with the same key are always assigned the same variant, across restarts and across all instances of a pipeline with the same configuration. Messages where the key resolves to an empty string are assigned randomly.
+
+The `+"`salt`"+` is hashed along with each key, and can be set to a unique value for each experiment in order for the assignments of different experiments to be independent of each other. Changing the salt, or the weights of variants, reassigns keys.`).
+		Field(service.NewObjectListField(expFieldVariants,
+			service.NewStringField(expFieldVariantName).
+				Description("The name of the variant."),
+			service.NewFloatField(expFieldVariantWeight).
+				Description("The weight of the variant.").
+				Default(1.0),
+		).
+			Description("The variants to assign messages to.")).
+		Field(service.NewInterpolatedStringField(expFieldKey).
+			Description("An optional key that messages are assigned a variant by.").
+			Example(`${! this.user_id }`).
+			Optional()).
+		Field(service.NewStringField(expFieldSalt).
+			Description("A value hashed along with each key.").
+			Default("").
+			Example("checkout_redesign_2024")).
+		Field(service.NewStringField(expFieldMetadataKey).
+			Description("The metadata key to store the name of the variant in.").
+			Default("variant")).
+		Example("Canary routing", "Send ten percent of users to a new version of a service, where all requests of a user are sent to the same version.", `
+pipeline:
+  processors:
+    - experiment:
+        key: ${! this.user_id }
+        salt: scoring_v2
+        variants:
+          - name: control
+            weight: 90
+          - name: canary
+            weight: 10
+
+output:
+  switch:
+    cases:
+      - check: '@variant == "canary"'
+        output:
+          http_client:
+            url: http://scoring-v2:8080/score
+      - output:
+          http_client:
+            url: http://scoring:8080/score
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"experiment", experimentProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return experimentProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type experimentProc struct {
+	names []string
+	// The cumulative weights of variants normalised to the interval [0, 1].
+	bounds  []float64
+	key     *service.InterpolatedString
+	salt    string
+	metaKey string
+
+	rngMut sync.Mutex
+	rng    *rand.Rand
+}
+
+func experimentProcFromParsed(conf *service.ParsedConfig) (*experimentProc, error) {
+	p := &experimentProc{
+		rng: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	vConfs, err := conf.FieldObjectList(expFieldVariants)
+	if err != nil {
+		return nil, err
+	}
+	if len(vConfs) == 0 {
+		return nil, errors.New("at least one variant must be specified")
+	}
+
+	var total float64
+	seen := map[string]struct{}{}
+	for _, vConf := range vConfs {
+		name, err := vConf.FieldString(expFieldVariantName)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := seen[name]; exists {
+			return nil, fmt.Errorf("variant name %v is not unique", name)
+		}
+		seen[name] = struct{}{}
+
+		weight, err := vConf.FieldFloat(expFieldVariantWeight)
+		if err != nil {
+			return nil, err
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("variant %v: weight must not be negative", name)
+		}
+		total += weight
+		p.names = append(p.names, name)
+		p.bounds = append(p.bounds, total)
+	}
+	if total == 0 {
+		return nil, errors.New("the weights of all variants must not be zero")
+	}
+	for i := range p.bounds {
+		p.bounds[i] /= total
+	}
+
+	if conf.Contains(expFieldKey) {
+		if p.key, err = conf.FieldInterpolatedString(expFieldKey); err != nil {
+			return nil, err
+		}
+	}
+	if p.salt, err = conf.FieldString(expFieldSalt); err != nil {
+		return nil, err
+	}
+	if p.metaKey, err = conf.FieldString(expFieldMetadataKey); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// keyPosition maps a key onto the interval [0, 1) deterministically.
+func (p *experimentProc) keyPosition(key string) float64 {
+	d := xxhash.New()
+	_, _ = d.WriteString(p.salt)
+	_, _ = d.Write([]byte{0})
+	_, _ = d.WriteString(key)
+	return float64(d.Sum64()>>11) / (1 << 53)
+}
+
+func (p *experimentProc) variant(pos float64) string {
+	for i, b := range p.bounds {
+		if pos < b {
+			return p.names[i]
+		}
+	}
+	// Guards against rounding errors of the final bound.
+	return p.names[len(p.names)-1]
+}
+
+func (p *experimentProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	var key string
+	if p.key != nil {
+		var err error
+		if key, err = p.key.TryString(msg); err != nil {
+			return nil, fmt.Errorf("key interpolation error: %w", err)
+		}
+	}
+
+	var pos float64
+	if key != "" {
+		pos = p.keyPosition(key)
+	} else {
+		p.rngMut.Lock()
+		pos = p.rng.Float64()
+		p.rngMut.Unlock()
+	}
+	msg.MetaSetMut(p.metaKey, p.variant(pos))
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *experimentProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,144 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func experimentVariant(t testing.TB, proc *experimentProc, content string) string {
+	t.Helper()
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(content)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	v, exists := res[0].MetaGet("variant")
+	require.True(t, exists)
+	return v
+}
+
+func TestExperimentWeights(t *testing.T) {
+	conf, err := experimentProcConfig().ParseYAML(`
+variants:
+  - name: a
+    weight: 75
+  - name: b
+    weight: 25
+  - name: never
+    weight: 0
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := experimentProcFromParsed(conf)
+	require.NoError(t, err)
+
+	counts := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		counts[experimentVariant(t, proc, "{}")]++
+	}
+	assert.Zero(t, counts["never"])
+	assert.InDelta(t, 3000, counts["a"], 250)
+	assert.InDelta(t, 1000, counts["b"], 250)
+}
+
+func TestExperimentSticky(t *testing.T) {
+	conf := `
+key: ${! this.user }
+salt: foo
+variants:
+  - name: a
+  - name: b
+`
+	pConf, err := experimentProcConfig().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	procA, err := experimentProcFromParsed(pConf)
+	require.NoError(t, err)
+
+	pConf, err = experimentProcConfig().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	procB, err := experimentProcFromParsed(pConf)
+	require.NoError(t, err)
+
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		doc := fmt.Sprintf(`{"user":"user-%v"}`, i)
+		v := experimentVariant(t, procA, doc)
+		assert.Equal(t, v, experimentVariant(t, procA, doc))
+		assert.Equal(t, v, experimentVariant(t, procB, doc))
+		counts[v]++
+	}
+	assert.InDelta(t, 500, counts["a"], 100)
+	assert.InDelta(t, 500, counts["b"], 100)
+
+	// A different salt results in independent assignments.
+	pConf, err = experimentProcConfig().ParseYAML(`
+key: ${! this.user }
+salt: bar
+variants:
+  - name: a
+  - name: b
+`, nil)
+	require.NoError(t, err)
+
+	procC, err := experimentProcFromParsed(pConf)
+	require.NoError(t, err)
+
+	differ := 0
+	for i := 0; i < 1000; i++ {
+		doc := fmt.Sprintf(`{"user":"user-%v"}`, i)
+		if experimentVariant(t, procA, doc) != experimentVariant(t, procC, doc) {
+			differ++
+		}
+	}
+	assert.InDelta(t, 500, differ, 100)
+}
+
+func TestExperimentBadConfig(t *testing.T) {
+	for _, conf := range []string{
+		`variants: []`,
+		`
+variants:
+  - name: a
+  - name: a
+`,
+		`
+variants:
+  - name: a
+    weight: 0
+`,
+		`
+variants:
+  - name: a
+    weight: -1
+  - name: b
+`,
+	} {
+		pConf, err := experimentProcConfig().ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		_, err = experimentProcFromParsed(pConf)
+		assert.Error(t, err, conf)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/Jeffail/gabs/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	empFieldPath        = "path"
+	empFieldKeyMetadata = "key_metadata"
+	empFieldKeyField    = "key_field"
+)
+
+func explodeMapProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Splits a JSON object into multiple messages, one for each key of the object, where the contents of each message is the value of the key.").
+		Description(`
+This processor is useful for consuming documents shaped as an object keyed by an identifier, such as `+"`{\"id1\":{...},\"id2\":{...}}`"+`, which is common in API responses. Arrays can instead be split with the `+"xref:components:processors/unarchive.adoc[`unarchive` processor]"+` using the `+"`json_array`"+` format.
+
+The key of each entry is stored within the metadata of the message it produces, and can optionally be added to the value itself with the field `+"`key_field`"+`. All metadata of the original message is copied onto each message produced, and messages are emitted in lexicographical order of their keys in order to keep the output deterministic.
+
+An object without any keys results in the message being dropped. Messages that are not valid JSON, or where the target of `+"`path`"+` is not an object, are flagged as failed and can be handled using xref:configuration:error_handling.adoc[error handling methods].`).
+		Field(service.NewStringField(empFieldPath).
+			Description("A xref:configuration:field_paths.adoc[dot separated path] to the object to explode. When empty the entire document is exploded.").
+			Example("data.users").
+			Default("")).
+		Field(service.NewStringField(empFieldKeyMetadata).
+			Description("The metadata key to store the key of each entry in.").
+			Default("explode_key")).
+		Field(service.NewStringField(empFieldKeyField).
+			Description("An optional field to add the key of each entry to within its value, in which case all values of the object must themselves be objects.").
+			Example("id").
+			Optional()).
+		Example("API responses", "Produce a message for each user of an API response shaped as `{\"users\":{\"u1\":{\"name\":\"foo\"},\"u2\":{\"name\":\"bar\"}}}`, with the ID of each user added to the document.", `
+pipeline:
+  processors:
+    - explode_map:
+        path: users
+        key_field: id
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"explode_map", explodeMapProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return explodeMapProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type explodeMapProc struct {
+	path        string
+	keyMetadata string
+	keyField    string
+}
+
+func explodeMapProcFromParsed(conf *service.ParsedConfig) (*explodeMapProc, error) {
+	p := &explodeMapProc{}
+
+	var err error
+	if p.path, err = conf.FieldString(empFieldPath); err != nil {
+		return nil, err
+	}
+	if p.keyMetadata, err = conf.FieldString(empFieldKeyMetadata); err != nil {
+		return nil, err
+	}
+	if conf.Contains(empFieldKeyField) {
+		if p.keyField, err = conf.FieldString(empFieldKeyField); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (p *explodeMapProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	v, err := msg.AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %w", err)
+	}
+
+	target := v
+	if p.path != "" {
+		if target = gabs.Wrap(v).Path(p.path).Data(); target == nil {
+			return nil, fmt.Errorf("path %v does not exist", p.path)
+		}
+	}
+
+	obj, ok := target.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected object value, got %T", target)
+	}
+
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	batch := make(service.MessageBatch, 0, len(keys))
+	for _, k := range keys {
+		value := obj[k]
+		if p.keyField != "" {
+			valueObj, isObj := value.(map[string]any)
+			if !isObj {
+				return nil, fmt.Errorf("expected object value for key %v, got %T", k, value)
+			}
+			// The value is shared with the original message and so the key is
+			// added to a shallow copy.
+			newObj := make(map[string]any, len(valueObj)+1)
+			for vk, vv := range valueObj {
+				newObj[vk] = vv
+			}
+			newObj[p.keyField] = k
+			value = newObj
+		}
+
+		part := msg.Copy()
+		part.SetStructured(value)
+		part.MetaSetMut(p.keyMetadata, k)
+		batch = append(batch, part)
+	}
+	return batch, nil
+}
+
+func (p *explodeMapProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestExplodeMapRoot(t *testing.T) {
+	conf, err := explodeMapProcConfig().ParseYAML(`{}`, nil)
+	require.NoError(t, err)
+
+	proc, err := explodeMapProcFromParsed(conf)
+	require.NoError(t, err)
+
+	msg := service.NewMessage([]byte(`{"b":{"name":"bar"},"a":{"name":"foo"},"c":"baz"}`))
+	msg.MetaSetMut("source", "api")
+
+	res, err := proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{`{"name":"foo"}`, `{"name":"bar"}`, `"baz"`}, batchContents(t, res))
+	for i, k := range []string{"a", "b", "c"} {
+		v, _ := res[i].MetaGet("explode_key")
+		assert.Equal(t, k, v)
+		v, _ = res[i].MetaGet("source")
+		assert.Equal(t, "api", v)
+	}
+}
+
+func TestExplodeMapPathWithKeyField(t *testing.T) {
+	conf, err := explodeMapProcConfig().ParseYAML(`
+path: data.users
+key_metadata: user_id
+key_field: id
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := explodeMapProcFromParsed(conf)
+	require.NoError(t, err)
+
+	input := `{"data":{"users":{"u2":{"name":"bar"},"u1":{"name":"foo"}}}}`
+	msg := service.NewMessage([]byte(input))
+
+	res, err := proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{`{"id":"u1","name":"foo"}`, `{"id":"u2","name":"bar"}`}, batchContents(t, res))
+	v, _ := res[1].MetaGet("user_id")
+	assert.Equal(t, "u2", v)
+
+	// The original message must not be modified.
+	mBytes, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, input, string(mBytes))
+}
+
+func TestExplodeMapEmpty(t *testing.T) {
+	conf, err := explodeMapProcConfig().ParseYAML(`{}`, nil)
+	require.NoError(t, err)
+
+	proc, err := explodeMapProcFromParsed(conf)
+	require.NoError(t, err)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{}`)))
+	require.NoError(t, err)
+	assert.Empty(t, res)
+}
+
+func TestExplodeMapErrors(t *testing.T) {
+	conf, err := explodeMapProcConfig().ParseYAML(`
+path: users
+key_field: id
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := explodeMapProcFromParsed(conf)
+	require.NoError(t, err)
+
+	for _, input := range []string{
+		`not json`,
+		`{"foo":{}}`,
+		`{"users":["a","b"]}`,
+		`{"users":{"a":"b"}}`,
+	} {
+		_, err := proc.Process(context.Background(), service.NewMessage([]byte(input)))
+		require.Error(t, err, input)
+	}
+}
@@ -0,0 +1,433 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/Jeffail/gabs/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	fakeFieldFields         = "fields"
+	fakeFieldLocale         = "locale"
+	fakeFieldSeed           = "seed"
+	fakeFieldPreserveFormat = "preserve_format"
+)
+
+type fakeGenerator func(r *rand.Rand, l *fakeLocale) string
+
+var fakeGenerators = map[string]fakeGenerator{
+	"first_name": func(r *rand.Rand, l *fakeLocale) string {
+		return fakePick(r, l.firstNames)
+	},
+	"last_name": func(r *rand.Rand, l *fakeLocale) string {
+		return fakePick(r, l.lastNames)
+	},
+	"name": func(r *rand.Rand, l *fakeLocale) string {
+		return fakePick(r, l.firstNames) + " " + fakePick(r, l.lastNames)
+	},
+	"username": fakeUsername,
+	"email": func(r *rand.Rand, l *fakeLocale) string {
+		return fakeUsername(r, l) + "@" + fakePick(r, fakeEmailDomains)
+	},
+	"phone_number": func(r *rand.Rand, l *fakeLocale) string {
+		return fakePattern(r, l.phone)
+	},
+	"street_address": fakeStreetAddress,
+	"city": func(r *rand.Rand, l *fakeLocale) string {
+		return fakePick(r, l.cities)
+	},
+	"postcode": func(r *rand.Rand, l *fakeLocale) string {
+		return fakePattern(r, l.postcode)
+	},
+	"address": func(r *rand.Rand, l *fakeLocale) string {
+		city, postcode := fakePick(r, l.cities), fakePattern(r, l.postcode)
+		if l.postcodeFirst {
+			return fakeStreetAddress(r, l) + ", " + postcode + " " + city
+		}
+		return fakeStreetAddress(r, l) + ", " + city + " " + postcode
+	},
+	"country": func(r *rand.Rand, l *fakeLocale) string {
+		return l.country
+	},
+	"ipv4": func(r *rand.Rand, l *fakeLocale) string {
+		return fmt.Sprintf("%d.%d.%d.%d", r.Intn(223)+1, r.Intn(256), r.Intn(256), r.Intn(254)+1)
+	},
+	"uuid": func(r *rand.Rand, l *fakeLocale) string {
+		var b [16]byte
+		_, _ = r.Read(b[:])
+		b[6] = (b[6] & 0x0f) | 0x40
+		b[8] = (b[8] & 0x3f) | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	},
+}
+
+// fakeKindScramble is the kind that scrambles existing values rather than
+// generating new ones.
+const fakeKindScramble = "scramble"
+
+func fakeKinds() []string {
+	kinds := []string{"`" + fakeKindScramble + "`"}
+	for k := range fakeGenerators {
+		kinds = append(kinds, "`"+k+"`")
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func fakeLocaleNames() []string {
+	names := make([]string, 0, len(fakeLocales))
+	for k := range fakeLocales {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func fakeProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Replaces fields of messages with realistic fake data.").
+		Description(`
+Each entry of `+"`fields`"+` maps a xref:configuration:field_paths.adoc[dot separated path] to the kind of value to generate, which is one of `+strings.Join(fakeKinds(), ", ")+`. Fields that do not exist are created, with the exception of the kind `+"`scramble`"+`, which replaces each letter and digit of an existing value with a random letter or digit, preserving case and any other characters, and leaves missing fields missing.
+
+Names, addresses and phone numbers are realistic for the `+"`locale`"+`, and generated email addresses use domains reserved for documentation, such that messages are never accidentally sent to real people.
+
+== Masking
+
+When `+"`preserve_format`"+` is `+"`true`"+` values of the following kinds that already exist are masked in a way that keeps their format:
+
+- `+"`email`"+`: The domain of the original address is kept.
+- `+"`phone_number`"+`: The country calling code and any characters other than digits are kept, and all other digits are replaced.
+- `+"`postcode`"+`: The original postcode is scrambled.
+
+== Reproducibility
+
+When a `+"`seed`"+` is set the value generated for a field that already exists is determined by the seed, the path and the original value, such that the same input always produces the same output regardless of the order in which messages are processed. This keeps masked data consistent across messages and runs, for example a customer ID that appears in many messages is always replaced with the same fake ID, and therefore the seed should be kept secret when masking sensitive data. Values of fields that do not already exist are generated from a sequence seeded once, which is reproducible when messages are processed in the same order by a single thread.
+
+When a `+"`seed`"+` is not set all values are random.`).
+		Field(service.NewStringMapField(fakeFieldFields).
+			Description("A map of paths to the kind of value to generate.").
+			Example(map[string]any{
+				"customer.name":  "name",
+				"customer.email": "email",
+				"customer.phone": "phone_number",
+			})).
+		Field(service.NewStringEnumField(fakeFieldLocale, fakeLocaleNames()...).
+			Description("The locale of generated names, addresses and phone numbers.").
+			Default("en_US")).
+		Field(service.NewIntField(fakeFieldSeed).
+			Description("An optional seed for the random number generator.").
+			Optional().
+			Example(42)).
+		Field(service.NewBoolField(fakeFieldPreserveFormat).
+			Description("Whether to preserve the format of existing values where supported.").
+			Default(false)).
+		Example("Scrub a snapshot", "Mask the personal details of customers within a production snapshot, keeping email domains and phone number country codes, and consistently replacing customer IDs.", `
+pipeline:
+  processors:
+    - fake:
+        locale: en_GB
+        seed: 8675309
+        preserve_format: true
+        fields:
+          id: scramble
+          name: name
+          email: email
+          phone: phone_number
+          address.line1: street_address
+          address.city: city
+          address.postcode: postcode
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"fake", fakeProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return fakeProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type fakeField struct {
+	path string
+	kind string
+}
+
+type fakeProc struct {
+	fields         []fakeField
+	locale         *fakeLocale
+	seeded         bool
+	seed           int64
+	preserveFormat bool
+
+	mut sync.Mutex
+	rng *rand.Rand
+}
+
+func fakeProcFromParsed(conf *service.ParsedConfig) (*fakeProc, error) {
+	p := &fakeProc{}
+
+	fields, err := conf.FieldStringMap(fakeFieldFields)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("at least one field must be specified")
+	}
+	for path, kind := range fields {
+		if _, exists := fakeGenerators[kind]; !exists && kind != fakeKindScramble {
+			return nil, fmt.Errorf("field %v: kind not recognised: %v", path, kind)
+		}
+		p.fields = append(p.fields, fakeField{path: path, kind: kind})
+	}
+	sort.Slice(p.fields, func(i, j int) bool {
+		return p.fields[i].path < p.fields[j].path
+	})
+
+	localeName, err := conf.FieldString(fakeFieldLocale)
+	if err != nil {
+		return nil, err
+	}
+	locale, exists := fakeLocales[localeName]
+	if !exists {
+		return nil, fmt.Errorf("locale not recognised: %v", localeName)
+	}
+	p.locale = &locale
+
+	if conf.Contains(fakeFieldSeed) {
+		seed, err := conf.FieldInt(fakeFieldSeed)
+		if err != nil {
+			return nil, err
+		}
+		p.seeded, p.seed = true, int64(seed)
+		p.rng = rand.New(rand.NewSource(p.seed))
+	} else {
+		p.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	if p.preserveFormat, err = conf.FieldBool(fakeFieldPreserveFormat); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// valueRand returns a generator seeded from the seed, path and original value
+// of a field.
+func (p *fakeProc) valueRand(path, original string) *rand.Rand {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(strconv.FormatInt(p.seed, 10)))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(path))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(original))
+	return rand.New(rand.NewSource(int64(h.Sum64())))
+}
+
+func (p *fakeProc) generate(r *rand.Rand, f fakeField, original string, exists bool) string {
+	if f.kind == fakeKindScramble || (p.preserveFormat && exists && f.kind == "postcode") {
+		return fakeScramble(r, original)
+	}
+	if p.preserveFormat && exists {
+		switch f.kind {
+		case "email":
+			if i := strings.LastIndexByte(original, '@'); i >= 0 {
+				return fakeUsername(r, p.locale) + original[i:]
+			}
+		case "phone_number":
+			return fakePhoneMask(r, original)
+		}
+	}
+	return fakeGenerators[f.kind](r, p.locale)
+}
+
+func fakeOriginalString(v any) (string, bool) {
+	switch t := v.(type) {
+	case nil:
+		return "", false
+	case string:
+		return t, true
+	case json.Number:
+		return t.String(), true
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), true
+	case int, int64, uint64, bool:
+		return fmt.Sprintf("%v", t), true
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+func (p *fakeProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	v, err := msg.AsStructuredMut()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message: %w", err)
+	}
+	gObj := gabs.Wrap(v)
+
+	for _, f := range p.fields {
+		var original string
+		var exists bool
+		if gObj.ExistsP(f.path) {
+			original, exists = fakeOriginalString(gObj.Path(f.path).Data())
+		}
+		if f.kind == fakeKindScramble && !exists {
+			continue
+		}
+
+		var value string
+		if p.seeded && exists {
+			value = p.generate(p.valueRand(f.path, original), f, original, exists)
+		} else {
+			p.mut.Lock()
+			value = p.generate(p.rng, f, original, exists)
+			p.mut.Unlock()
+		}
+
+		if _, err := gObj.SetP(value, f.path); err != nil {
+			return nil, fmt.Errorf("field %v: %w", f.path, err)
+		}
+	}
+
+	msg.SetStructuredMut(gObj.Data())
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *fakeProc) Close(ctx context.Context) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func fakePick(r *rand.Rand, options []string) string {
+	return options[r.Intn(len(options))]
+}
+
+func fakePattern(r *rand.Rand, pattern string) string {
+	var b strings.Builder
+	for _, c := range pattern {
+		switch c {
+		case '#':
+			b.WriteByte(byte('0' + r.Intn(10)))
+		case '@':
+			b.WriteByte(byte('A' + r.Intn(26)))
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+var fakeASCIIReplacer = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ä", "a", "é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "î", "i", "ï", "i", "ó", "o", "ô", "o", "ö", "o", "ú", "u", "ü", "u",
+	"ç", "c", "ñ", "n", "ß", "ss",
+)
+
+// fakeASCII lower cases a name and removes accents and any characters that
+// are not letters, such that it can be used within an email address.
+func fakeASCII(s string) string {
+	var b strings.Builder
+	for _, c := range fakeASCIIReplacer.Replace(strings.ToLower(s)) {
+		if c >= 'a' && c <= 'z' {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+func fakeUsername(r *rand.Rand, l *fakeLocale) string {
+	first, last := fakeASCII(fakePick(r, l.firstNames)), fakeASCII(fakePick(r, l.lastNames))
+	switch r.Intn(3) {
+	case 0:
+		return first + "." + last
+	case 1:
+		return first[:1] + last + strconv.Itoa(r.Intn(100))
+	default:
+		return first + strconv.Itoa(r.Intn(1000))
+	}
+}
+
+func fakeStreetAddress(r *rand.Rand, l *fakeLocale) string {
+	number, street := strconv.Itoa(r.Intn(l.streetNumberTo)+1), fakePick(r, l.streets)
+	if l.numberFirst {
+		return number + " " + street
+	}
+	return street + " " + number
+}
+
+// fakeScramble replaces each letter and digit of a value with a random letter
+// or digit of the same case.
+func fakeScramble(r *rand.Rand, s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			b.WriteByte(byte('0' + r.Intn(10)))
+		case unicode.IsUpper(c):
+			b.WriteByte(byte('A' + r.Intn(26)))
+		case unicode.IsLetter(c):
+			b.WriteByte(byte('a' + r.Intn(26)))
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// fakePhoneMask replaces the digits of a phone number, keeping the country
+// calling code of numbers in international format and all other characters.
+func fakePhoneMask(r *rand.Rand, s string) string {
+	keep := 0
+	if strings.HasPrefix(s, "+") {
+		var digits strings.Builder
+		for i, c := range s[1:] {
+			if c < '0' || c > '9' {
+				continue
+			}
+			digits.WriteRune(c)
+			if _, exists := phoneCodeRegions[digits.String()]; exists {
+				keep = i + 2
+				break
+			}
+			if digits.Len() == 3 {
+				break
+			}
+		}
+	}
+	return s[:keep] + fakeScramble(r, s[keep:])
+}
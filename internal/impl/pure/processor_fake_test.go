@@ -0,0 +1,147 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func fakeProcess(t testing.TB, proc *fakeProc, content string) map[string]any {
+	t.Helper()
+
+	batch, err := proc.Process(context.Background(), service.NewMessage([]byte(content)))
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	v, err := batch[0].AsStructured()
+	require.NoError(t, err)
+	return v.(map[string]any)
+}
+
+func TestFakeGenerate(t *testing.T) {
+	for _, locale := range fakeLocaleNames() {
+		locale := locale
+		t.Run(locale, func(t *testing.T) {
+			conf, err := fakeProcConfig().ParseYAML(`
+locale: `+locale+`
+fields:
+  name: name
+  email: email
+  phone: phone_number
+  address: address
+  ip: ipv4
+  id: uuid
+  country: country
+`, nil)
+			require.NoError(t, err)
+
+			proc, err := fakeProcFromParsed(conf)
+			require.NoError(t, err)
+
+			res := fakeProcess(t, proc, `{"other":"untouched"}`)
+			assert.Equal(t, "untouched", res["other"])
+			assert.Regexp(t, `^\S+ \S+$`, res["name"])
+			assert.Regexp(t, `^[a-z0-9.]+@example\.(com|net|org)$`, res["email"])
+			assert.Regexp(t, `^\+\d+ [\d -]+$`, res["phone"])
+			assert.Regexp(t, `\d`, res["address"])
+			assert.Regexp(t, `^\d+\.\d+\.\d+\.\d+$`, res["ip"])
+			assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, res["id"])
+			assert.Equal(t, fakeLocales[locale].country, res["country"])
+		})
+	}
+}
+
+func TestFakePreserveFormat(t *testing.T) {
+	conf, err := fakeProcConfig().ParseYAML(`
+locale: en_GB
+preserve_format: true
+fields:
+  email: email
+  phone: phone_number
+  postcode: postcode
+  ref: scramble
+  missing: scramble
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := fakeProcFromParsed(conf)
+	require.NoError(t, err)
+
+	res := fakeProcess(t, proc, `{"email":"jane.doe@acme.co.uk","phone":"+44 (0)20 7946-0000","postcode":"SW1A 1AA","ref":"AB-123-cd"}`)
+
+	assert.True(t, strings.HasSuffix(res["email"].(string), "@acme.co.uk"), res["email"])
+	assert.Regexp(t, `^\+44 \(\d\)\d\d \d{4}-\d{4}$`, res["phone"])
+	assert.Regexp(t, `^[A-Z]{2}\d[A-Z] \d[A-Z]{2}$`, res["postcode"])
+	assert.Regexp(t, `^[A-Z]{2}-\d{3}-[a-z]{2}$`, res["ref"])
+	assert.NotContains(t, res, "missing")
+}
+
+func TestFakeSeeded(t *testing.T) {
+	conf, err := fakeProcConfig().ParseYAML(`
+seed: 42
+fields:
+  customer_id: scramble
+  name: name
+  email: email
+`, nil)
+	require.NoError(t, err)
+
+	procA, err := fakeProcFromParsed(conf)
+	require.NoError(t, err)
+
+	procB, err := fakeProcFromParsed(conf)
+	require.NoError(t, err)
+
+	// Existing values map consistently regardless of order.
+	a1 := fakeProcess(t, procA, `{"customer_id":"C-1001","name":"Jane","email":"jane@acme.com"}`)
+	a2 := fakeProcess(t, procA, `{"customer_id":"C-1002","name":"John","email":"john@acme.com"}`)
+	b2 := fakeProcess(t, procB, `{"customer_id":"C-1002","name":"John","email":"john@acme.com"}`)
+	b1 := fakeProcess(t, procB, `{"customer_id":"C-1001","name":"Jane","email":"jane@acme.com"}`)
+	assert.Equal(t, a1, b1)
+	assert.Equal(t, a2, b2)
+	assert.NotEqual(t, a1["customer_id"], a2["customer_id"])
+	assert.Regexp(t, regexp.MustCompile(`^[A-Z]-\d{4}$`), a1["customer_id"])
+
+	// Missing values are generated from a reproducible sequence.
+	procC, err := fakeProcFromParsed(conf)
+	require.NoError(t, err)
+
+	procD, err := fakeProcFromParsed(conf)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, fakeProcess(t, procC, `{}`), fakeProcess(t, procD, `{}`))
+	}
+}
+
+func TestFakeConfigErrors(t *testing.T) {
+	pConf, err := fakeProcConfig().ParseYAML(`
+fields:
+  foo: nope
+`, nil)
+	require.NoError(t, err)
+
+	_, err = fakeProcFromParsed(pConf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "kind not recognised: nope")
+}
@@ -0,0 +1,210 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"strconv"
+
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	fpFieldIgnorePaths = "ignore_paths"
+	fpFieldAlgorithm   = "algorithm"
+	fpFieldMetadataKey = "metadata_key"
+)
+
+func fingerprintProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Computes a stable hash of a JSON document, excluding a list of volatile fields, and writes it to a metadata field.").
+		Description(`
+The document is canonicalised before it is hashed: fields listed in `+"`ignore_paths`"+` are removed and the remaining document is serialised with object keys sorted lexicographically and without whitespace. Two documents that only differ in the ordering of their keys, or in the values of ignored fields, therefore produce the same fingerprint, and a given document always produces the same fingerprint across runs and restarts.
+
+The contents of the message are not modified. Messages that cannot be parsed as JSON are flagged as failed and can be handled using xref:configuration:error_handling.adoc[error handling methods].
+
+== Paths
+
+Each path is a dot separated list of object keys or array indexes, such as `+"`meta.request_id`"+` or `+"`items.0.updated_at`"+`. A segment of `+"`*`"+` matches all keys of an object or all elements of an array, and therefore `+"`items.*.updated_at`"+` removes the field `+"`updated_at`"+` from every element of the array `+"`items`"+`. Paths that do not exist within a document are ignored.`).
+		Field(service.NewStringListField(fpFieldIgnorePaths).
+			Description("A list of paths to remove from the document before it is hashed.").
+			Example([]string{"timestamp", "meta.request_id", "items.*.updated_at"}).
+			Default([]string{})).
+		Field(service.NewStringAnnotatedEnumField(fpFieldAlgorithm, map[string]string{
+			"sha256":   "SHA-256, hex encoded.",
+			"sha512":   "SHA-512, hex encoded.",
+			"sha1":     "SHA-1, hex encoded.",
+			"md5":      "MD5, hex encoded.",
+			"xxhash64": "XXH64, hex encoded. This is significantly faster than the cryptographic algorithms but more prone to collisions.",
+		}).
+			Description("The hash algorithm used to compute the fingerprint.").
+			Default("sha256")).
+		Field(service.NewStringField(fpFieldMetadataKey).
+			Description("The metadata key to store the fingerprint in.").
+			Default("fingerprint")).
+		Example("Change detection", "Drop documents that have not changed since they were last seen, ignoring fields that change on every update.", `
+pipeline:
+  processors:
+    - fingerprint:
+        ignore_paths: [ updated_at, trace.request_id ]
+    - dedupe:
+        cache: changes
+        key: ${! json("id") }-${! @fingerprint }
+
+cache_resources:
+  - label: changes
+    memory:
+      default_ttl: 24h
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"fingerprint", fingerprintProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return fingerprintProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// withoutIgnored returns a copy of v with all ignored paths removed. The
+// original value is left untouched as it may be shared with the message.
+func withoutIgnored(v any, node *pathNode) any {
+	if node == nil || len(node.children) == 0 {
+		return v
+	}
+	switch t := v.(type) {
+	case map[string]any:
+		res := make(map[string]any, len(t))
+		for k, cv := range t {
+			c := node.child(k)
+			if c != nil && c.end {
+				continue
+			}
+			res[k] = withoutIgnored(cv, c)
+		}
+		return res
+	case []any:
+		res := make([]any, 0, len(t))
+		for i, cv := range t {
+			c := node.child(strconv.Itoa(i))
+			if c != nil && c.end {
+				continue
+			}
+			res = append(res, withoutIgnored(cv, c))
+		}
+		return res
+	}
+	return v
+}
+
+func fingerprintHasher(algorithm string) (func() hash.Hash, error) {
+	switch algorithm {
+	case "sha256":
+		return sha256.New, nil
+	case "sha512":
+		return sha512.New, nil
+	case "sha1":
+		return sha1.New, nil
+	case "md5":
+		return md5.New, nil
+	case "xxhash64":
+		return func() hash.Hash {
+			return xxhash.New()
+		}, nil
+	}
+	return nil, fmt.Errorf("algorithm not recognised: %v", algorithm)
+}
+
+//------------------------------------------------------------------------------
+
+type fingerprintProc struct {
+	ignore  *pathNode
+	hasher  func() hash.Hash
+	metaKey string
+}
+
+func fingerprintProcFromParsed(conf *service.ParsedConfig) (*fingerprintProc, error) {
+	paths, err := conf.FieldStringList(fpFieldIgnorePaths)
+	if err != nil {
+		return nil, err
+	}
+	algorithm, err := conf.FieldString(fpFieldAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &fingerprintProc{}
+	if p.ignore, err = parsePaths(paths); err != nil {
+		return nil, err
+	}
+	if p.hasher, err = fingerprintHasher(algorithm); err != nil {
+		return nil, err
+	}
+	if p.metaKey, err = conf.FieldString(fpFieldMetadataKey); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *fingerprintProc) fingerprint(v any) (string, error) {
+	// The JSON encoder sorts map keys, which gives us a canonical form that
+	// is independent of the ordering within the source document.
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(withoutIgnored(v, p.ignore)); err != nil {
+		return "", err
+	}
+
+	h := p.hasher()
+	_, _ = h.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (p *fingerprintProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	v, err := msg.AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %w", err)
+	}
+	fp, err := p.fingerprint(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute fingerprint: %w", err)
+	}
+	msg.MetaSetMut(p.metaKey, fp)
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *fingerprintProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func fingerprintOf(t testing.TB, proc *fingerprintProc, doc string) string {
+	t.Helper()
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(doc)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	mBytes, err := res[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, doc, string(mBytes))
+
+	fp, exists := res[0].MetaGet("fingerprint")
+	require.True(t, exists)
+	return fp
+}
+
+func TestFingerprintKeyOrdering(t *testing.T) {
+	conf, err := fingerprintProcConfig().ParseYAML(`{}`, nil)
+	require.NoError(t, err)
+
+	proc, err := fingerprintProcFromParsed(conf)
+	require.NoError(t, err)
+
+	a := fingerprintOf(t, proc, `{"a":1,"b":{"c":"x","d":[1,2]}}`)
+	b := fingerprintOf(t, proc, `{ "b": { "d": [1, 2], "c": "x" }, "a": 1 }`)
+	assert.Equal(t, a, b)
+
+	// The canonical form of the first document is {"a":1,"b":{"c":"x","d":[1,2]}}
+	assert.Equal(t, "a95fdc35fefe0ab6b786e722697fdf8b341e7c2d6e7683ee4810738a63d28449", a)
+
+	c := fingerprintOf(t, proc, `{"a":1,"b":{"c":"y","d":[1,2]}}`)
+	assert.NotEqual(t, a, c)
+}
+
+func TestFingerprintIgnorePaths(t *testing.T) {
+	conf, err := fingerprintProcConfig().ParseYAML(`
+ignore_paths: [ ts, meta.request_id, items.*.updated_at, tags.0 ]
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := fingerprintProcFromParsed(conf)
+	require.NoError(t, err)
+
+	a := fingerprintOf(t, proc, `{"id":"foo","ts":1,"meta":{"request_id":"a","source":"x"},"items":[{"v":1,"updated_at":1}],"tags":["a","b"]}`)
+	b := fingerprintOf(t, proc, `{"id":"foo","ts":2,"meta":{"request_id":"b","source":"x"},"items":[{"v":1,"updated_at":2}],"tags":["c","b"]}`)
+	assert.Equal(t, a, b)
+
+	c := fingerprintOf(t, proc, `{"id":"foo","ts":2,"meta":{"request_id":"b","source":"y"},"items":[{"v":1,"updated_at":2}],"tags":["c","b"]}`)
+	assert.NotEqual(t, a, c)
+
+	// Ignored paths must not be removed from the message itself.
+	msg := service.NewMessage(nil)
+	msg.SetStructured(map[string]any{"id": "foo", "ts": 1})
+	res, err := proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+
+	v, err := res[0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"id": "foo", "ts": 1}, v)
+}
+
+func TestFingerprintAlgorithms(t *testing.T) {
+	for algo, length := range map[string]int{
+		"sha256":   64,
+		"sha512":   128,
+		"sha1":     40,
+		"md5":      32,
+		"xxhash64": 16,
+	} {
+		conf, err := fingerprintProcConfig().ParseYAML(`
+algorithm: `+algo+`
+metadata_key: fingerprint
+`, nil)
+		require.NoError(t, err)
+
+		proc, err := fingerprintProcFromParsed(conf)
+		require.NoError(t, err)
+
+		assert.Len(t, fingerprintOf(t, proc, `{"a":"b"}`), length, algo)
+	}
+}
+
+func TestFingerprintErrors(t *testing.T) {
+	conf, err := fingerprintProcConfig().ParseYAML(`{}`, nil)
+	require.NoError(t, err)
+
+	proc, err := fingerprintProcFromParsed(conf)
+	require.NoError(t, err)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`not json`)))
+	require.Error(t, err)
+
+	pConf, err := fingerprintProcConfig().ParseYAML(`ignore_paths: [ "foo..bar" ]`, nil)
+	require.NoError(t, err)
+
+	_, err = fingerprintProcFromParsed(pConf)
+	require.Error(t, err)
+}
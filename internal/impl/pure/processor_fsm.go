@@ -0,0 +1,270 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	fsmFieldKey            = "key"
+	fsmFieldState          = "state"
+	fsmFieldTransitions    = "transitions"
+	fsmFieldInitialStates  = "initial_states"
+	fsmFieldAllowUnchanged = "allow_unchanged"
+	fsmFieldCache          = "cache"
+	fsmFieldTTL            = "ttl"
+)
+
+func fsmProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Validates that the messages of each entity follow the transitions of a finite-state machine, storing the current state of each entity in a cache.").
+		Description(`
+For each message the entity is identified by `+"`key`"+` and the state that the message transitions the entity to is resolved from `+"`state`"+`. The current state of the entity is obtained from the cache, and when the transition from the current state to the new state is allowed by `+"`transitions`"+` the new state is stored within the cache and the message passes through unchanged.
+
+Messages with an illegal transition are flagged as failed so that they can be handled using xref:configuration:error_handling.adoc[error handling methods], and the state of their entity is left unchanged. Entities without a state within the cache may begin in any state listed in `+"`initial_states`"+`, or in any state at all when it's empty.
+
+A transition is validated against the state read from the cache before the new state is written, and therefore messages of the same entity that are processed by multiple pipeline threads at the same time are validated against the same state, which allows sequences of transitions that are illegal and stores whichever state is written last. Since transitions are also only meaningful in the order they occurred, the messages of an entity must be processed in order by a single pipeline thread, which can be achieved by partitioning messages by entity upstream.
+
+== Metadata
+
+This processor adds the following metadata fields to each message:
+
+`+"```text"+`
+- fsm_from
+- fsm_to
+`+"```"+`
+
+Where `+"`fsm_from`"+` is the previous state of the entity, which is empty for entities without a previous state, and `+"`fsm_to`"+` is the state of the message.`).
+		Field(service.NewInterpolatedStringField(fsmFieldKey).
+			Description("An interpolated string that resolves to the key of the entity of each message.").
+			Example(`${! this.order_id }`)).
+		Field(service.NewInterpolatedStringField(fsmFieldState).
+			Description("An interpolated string that resolves to the state that each message transitions its entity to.").
+			Example(`${! this.status }`)).
+		Field(service.NewAnyMapField(fsmFieldTransitions).
+			Description("A map of each state to a list of the states that it may transition to. States without an entry are terminal.").
+			Example(map[string]any{
+				"created": []any{"paid", "cancelled"},
+				"paid":    []any{"shipped", "refunded"},
+				"shipped": []any{"delivered"},
+			})).
+		Field(service.NewStringListField(fsmFieldInitialStates).
+			Description("The states that an entity without a previous state may begin in. When empty an entity may begin in any state.").
+			Example([]string{"created"}).
+			Default([]string{})).
+		Field(service.NewBoolField(fsmFieldAllowUnchanged).
+			Description("Whether a message with the same state as the current state of its entity is allowed, which tolerates duplicate deliveries of a message.").
+			Default(false).
+			Advanced()).
+		Field(service.NewStringField(fsmFieldCache).
+			Description("The xref:components:caches/about.adoc[`cache` resource] to store the state of each entity in.")).
+		Field(service.NewStringField(fsmFieldTTL).
+			Description("An optional TTL to set for the state of each entity, after which an entity is considered to have no previous state. Not all caches support per-key TTLs.").
+			Example("72h").
+			Optional().
+			Advanced()).
+		Example("Order lifecycle", "Reject order events that do not follow the lifecycle of an order.", `
+pipeline:
+  processors:
+    - fsm:
+        key: ${! this.order_id }
+        state: ${! this.status }
+        initial_states: [ created ]
+        transitions:
+          created: [ paid, cancelled ]
+          paid: [ shipped, refunded ]
+          shipped: [ delivered ]
+        cache: order_states
+    - switch:
+        - check: errored()
+          processors:
+            - log:
+                message: 'Illegal transition of order ${! this.order_id } from ${! @fsm_from } to ${! @fsm_to }'
+            - mapping: root = deleted()
+
+cache_resources:
+  - label: order_states
+    redis:
+      url: redis://localhost:6379
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"fsm", fsmProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return fsmProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type fsmProc struct {
+	key            *service.InterpolatedString
+	state          *service.InterpolatedString
+	transitions    map[string]map[string]struct{}
+	initialStates  map[string]struct{}
+	allowUnchanged bool
+	cache          string
+	ttl            *time.Duration
+
+	mgr *service.Resources
+}
+
+func fsmProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*fsmProc, error) {
+	p := &fsmProc{
+		transitions:   map[string]map[string]struct{}{},
+		initialStates: map[string]struct{}{},
+		mgr:           mgr,
+	}
+
+	var err error
+	if p.key, err = conf.FieldInterpolatedString(fsmFieldKey); err != nil {
+		return nil, err
+	}
+	if p.state, err = conf.FieldInterpolatedString(fsmFieldState); err != nil {
+		return nil, err
+	}
+
+	transConfs, err := conf.FieldAnyMap(fsmFieldTransitions)
+	if err != nil {
+		return nil, err
+	}
+	for from, toConf := range transConfs {
+		to, err := toConf.FieldStringList()
+		if err != nil {
+			return nil, fmt.Errorf("transitions of state %v must be a list of states: %w", from, err)
+		}
+		tos := map[string]struct{}{}
+		for _, s := range to {
+			tos[s] = struct{}{}
+		}
+		p.transitions[from] = tos
+	}
+
+	initial, err := conf.FieldStringList(fsmFieldInitialStates)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range initial {
+		p.initialStates[s] = struct{}{}
+	}
+
+	if p.allowUnchanged, err = conf.FieldBool(fsmFieldAllowUnchanged); err != nil {
+		return nil, err
+	}
+
+	if p.cache, err = conf.FieldString(fsmFieldCache); err != nil {
+		return nil, err
+	}
+	if !mgr.HasCache(p.cache) {
+		return nil, fmt.Errorf("cache resource '%v' was not found", p.cache)
+	}
+
+	if conf.Contains(fsmFieldTTL) {
+		ttlStr, err := conf.FieldString(fsmFieldTTL)
+		if err != nil {
+			return nil, err
+		}
+		ttl, err := time.ParseDuration(ttlStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ttl: %w", err)
+		}
+		p.ttl = &ttl
+	}
+	return p, nil
+}
+
+func (p *fsmProc) allowed(from, to string, exists bool) bool {
+	if !exists {
+		if len(p.initialStates) == 0 {
+			return true
+		}
+		_, ok := p.initialStates[to]
+		return ok
+	}
+	if from == to && p.allowUnchanged {
+		return true
+	}
+	_, ok := p.transitions[from][to]
+	return ok
+}
+
+func (p *fsmProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	key, err := p.key.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("key interpolation error: %w", err)
+	}
+	to, err := p.state.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("state interpolation error: %w", err)
+	}
+
+	var from string
+	var exists bool
+	var cacheErr error
+	if err := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		fromBytes, err := c.Get(ctx, key)
+		if err != nil {
+			if !errors.Is(err, service.ErrKeyNotFound) {
+				cacheErr = err
+			}
+			return
+		}
+		from, exists = string(fromBytes), true
+	}); err != nil {
+		return nil, err
+	}
+	if cacheErr != nil {
+		return nil, fmt.Errorf("failed to obtain state of %v: %w", key, cacheErr)
+	}
+
+	msg.MetaSetMut("fsm_from", from)
+	msg.MetaSetMut("fsm_to", to)
+
+	if !p.allowed(from, to, exists) {
+		if !exists {
+			return nil, fmt.Errorf("illegal initial state %v of %v", to, key)
+		}
+		return nil, fmt.Errorf("illegal transition of %v from %v to %v", key, from, to)
+	}
+	if exists && from == to {
+		return service.MessageBatch{msg}, nil
+	}
+
+	if err := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		cacheErr = c.Set(ctx, key, []byte(to), p.ttl)
+	}); err != nil {
+		return nil, err
+	}
+	if cacheErr != nil {
+		return nil, fmt.Errorf("failed to store state of %v: %w", key, cacheErr)
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *fsmProc) Close(ctx context.Context) error {
+	return nil
+}
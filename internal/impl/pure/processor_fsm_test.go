@@ -0,0 +1,127 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestFSMTransitions(t *testing.T) {
+	conf, err := fsmProcConfig().ParseYAML(`
+key: ${! this.id }
+state: ${! this.status }
+initial_states: [ created ]
+transitions:
+  created: [ paid, cancelled ]
+  paid: [ shipped ]
+cache: foocache
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := fsmProcFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		input string
+		from  string
+		err   string
+	}{
+		{input: `{"id":"a","status":"paid"}`, err: "illegal initial state paid of a"},
+		{input: `{"id":"a","status":"created"}`},
+		{input: `{"id":"b","status":"created"}`},
+		{input: `{"id":"a","status":"shipped"}`, from: "created", err: "illegal transition of a from created to shipped"},
+		{input: `{"id":"a","status":"paid"}`, from: "created"},
+		{input: `{"id":"a","status":"paid"}`, from: "paid", err: "illegal transition of a from paid to paid"},
+		{input: `{"id":"b","status":"cancelled"}`, from: "created"},
+		{input: `{"id":"a","status":"shipped"}`, from: "paid"},
+		{input: `{"id":"b","status":"paid"}`, from: "cancelled", err: "illegal transition of b from cancelled to paid"},
+	} {
+		msg := service.NewMessage([]byte(test.input))
+		res, err := proc.Process(context.Background(), msg)
+		if test.err != "" {
+			require.EqualError(t, err, test.err, test.input)
+		} else {
+			require.NoError(t, err, test.input)
+			require.Len(t, res, 1)
+		}
+
+		v, _ := msg.MetaGet("fsm_from")
+		assert.Equal(t, test.from, v, test.input)
+
+		v, _ = msg.MetaGet("fsm_to")
+		structured, err := msg.AsStructured()
+		require.NoError(t, err)
+		assert.Equal(t, structured.(map[string]any)["status"], v, test.input)
+	}
+}
+
+func TestFSMAllowUnchanged(t *testing.T) {
+	conf, err := fsmProcConfig().ParseYAML(`
+key: ${! this.id }
+state: ${! this.status }
+allow_unchanged: true
+transitions:
+  created: [ paid ]
+cache: foocache
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := fsmProcFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.NoError(t, err)
+
+	for _, input := range []string{
+		`{"id":"a","status":"created"}`,
+		`{"id":"a","status":"created"}`,
+		`{"id":"a","status":"paid"}`,
+		`{"id":"a","status":"paid"}`,
+	} {
+		_, err := proc.Process(context.Background(), service.NewMessage([]byte(input)))
+		require.NoError(t, err, input)
+	}
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"id":"a","status":"created"}`)))
+	require.Error(t, err)
+}
+
+func TestFSMBadConfig(t *testing.T) {
+	pConf, err := fsmProcConfig().ParseYAML(`
+key: ${! this.id }
+state: ${! this.status }
+transitions:
+  created: paid
+cache: foocache
+`, nil)
+	require.NoError(t, err)
+
+	_, err = fsmProcFromParsed(pConf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.Error(t, err)
+
+	pConf, err = fsmProcConfig().ParseYAML(`
+key: ${! this.id }
+state: ${! this.status }
+transitions: {}
+cache: nope
+`, nil)
+	require.NoError(t, err)
+
+	_, err = fsmProcFromParsed(pConf, service.MockResources())
+	require.Error(t, err)
+}
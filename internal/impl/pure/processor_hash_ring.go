@@ -0,0 +1,372 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	hrFieldKey            = "key"
+	hrFieldNodes          = "nodes"
+	hrFieldNodesCache     = "nodes_cache"
+	hrFieldNodesCacheKey  = "nodes_cache_key"
+	hrFieldVirtualNodes   = "virtual_nodes"
+	hrFieldMetadataKey    = "metadata_key"
+	hrFieldReloadInterval = "reload_interval"
+)
+
+func hashRingProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Assigns the key of each message to a node of a consistent hash ring and writes the node to a metadata field.").
+		Description(`
+Each node is placed on the ring at `+"`virtual_nodes`"+` positions derived from a hash of its identifier, and a key is assigned to the first node found on the ring at or after the hash of the key. The assignment depends only on the key and the set of nodes, and therefore all instances with the same nodes assign a key to the same node. When a node is added it takes over approximately 1/N of the keys from the other nodes, and when a node is removed only its own keys are reassigned, spread evenly across the remaining nodes. More virtual nodes spread the keys more evenly at the cost of memory.
+
+The contents of the message are not modified, and the identifier of the node is written to the metadata field `+"`metadata_key`"+`.
+
+== Membership
+
+The nodes of the ring are either listed with the field `+"`nodes`"+`, or read from the key `+"`nodes_cache_key`"+` of the xref:components:caches/about.adoc[`+"`cache` resource"+`] `+"`nodes_cache`"+`, which allows membership to be managed externally, for example within a `+"`redis`"+` key. The value within the cache is either a JSON array of node identifiers or a list of identifiers separated by newlines.
+
+Nodes read from a cache are loaded when the first message is processed, and loaded again every `+"`reload_interval`"+`, where the ring is rebuilt when the nodes have changed. When the nodes fail to load, or the list of nodes is empty, the error is logged and the previous ring continues to be used.`).
+		Field(service.NewInterpolatedStringField(hrFieldKey).
+			Description("The key to assign to a node.").
+			Example(`${! this.customer_id }`)).
+		Field(service.NewStringListField(hrFieldNodes).
+			Description("The identifiers of the nodes of the ring. Either this field or `nodes_cache` must be set.").
+			Example([]string{"connect-0", "connect-1", "connect-2"}).
+			Optional()).
+		Field(service.NewStringField(hrFieldNodesCache).
+			Description("A cache resource to read the identifiers of the nodes of the ring from.").
+			Optional()).
+		Field(service.NewStringField(hrFieldNodesCacheKey).
+			Description("The key of the cache resource containing the identifiers of the nodes.").
+			Default("hash_ring_nodes")).
+		Field(service.NewIntField(hrFieldVirtualNodes).
+			Description("The number of positions of each node on the ring.").
+			Default(128).
+			Advanced()).
+		Field(service.NewStringField(hrFieldMetadataKey).
+			Description("The metadata key to store the identifier of the node in.").
+			Default("node")).
+		Field(service.NewDurationField(hrFieldReloadInterval).
+			Description("The period of time between loads of the nodes from `nodes_cache`. Set to `0s` in order to disable reloading.").
+			Default("30s").
+			Advanced()).
+		Example("Shard ownership", "Only process the messages of keys owned by this instance, where the instances of a deployment are listed in a redis key.", `
+pipeline:
+  processors:
+    - hash_ring:
+        key: ${! this.device_id }
+        nodes_cache: membership
+        nodes_cache_key: connect_instances
+    - mapping: |
+        root = if @node != env("HOSTNAME") { deleted() }
+
+cache_resources:
+  - label: membership
+    redis:
+      url: redis://localhost:6379
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"hash_ring", hashRingProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return hashRingProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// hashRing is a sorted ring of the hashes of the virtual nodes of each node.
+type hashRing struct {
+	hashes []uint64
+	owners []int
+	nodes  []string
+}
+
+func newHashRing(nodes []string, virtualNodes int) *hashRing {
+	type point struct {
+		hash  uint64
+		owner int
+	}
+	points := make([]point, 0, len(nodes)*virtualNodes)
+	for i, n := range nodes {
+		for v := 0; v < virtualNodes; v++ {
+			points = append(points, point{
+				hash:  xxhash.Sum64String(n + "#" + strconv.Itoa(v)),
+				owner: i,
+			})
+		}
+	}
+	// Ties, which are improbable, are broken by the identifier of the node so
+	// that the ring does not depend on the order of the nodes.
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].hash != points[j].hash {
+			return points[i].hash < points[j].hash
+		}
+		return nodes[points[i].owner] < nodes[points[j].owner]
+	})
+
+	r := &hashRing{
+		hashes: make([]uint64, len(points)),
+		owners: make([]int, len(points)),
+		nodes:  nodes,
+	}
+	for i, p := range points {
+		r.hashes[i], r.owners[i] = p.hash, p.owner
+	}
+	return r
+}
+
+func (r *hashRing) node(key string) string {
+	h := xxhash.Sum64String(key)
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.nodes[r.owners[i]]
+}
+
+// parseHashRingNodes parses a JSON array of node identifiers or a newline
+// separated list of identifiers, removing duplicates and empty identifiers.
+func parseHashRingNodes(b []byte) ([]string, error) {
+	var nodes []string
+	if trimmed := bytes.TrimSpace(b); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &nodes); err != nil {
+			return nil, fmt.Errorf("failed to parse nodes as a JSON array: %w", err)
+		}
+	} else {
+		nodes = strings.Split(string(b), "\n")
+	}
+	return dedupeHashRingNodes(nodes)
+}
+
+func dedupeHashRingNodes(nodes []string) ([]string, error) {
+	seen := map[string]struct{}{}
+	var res []string
+	for _, n := range nodes {
+		if n = strings.TrimSpace(n); n == "" {
+			continue
+		}
+		if _, exists := seen[n]; exists {
+			continue
+		}
+		seen[n] = struct{}{}
+		res = append(res, n)
+	}
+	if len(res) == 0 {
+		return nil, errors.New("the list of nodes is empty")
+	}
+	return res, nil
+}
+
+//------------------------------------------------------------------------------
+
+type hashRingProc struct {
+	key            *service.InterpolatedString
+	nodesCache     string
+	nodesCacheKey  string
+	virtualNodes   int
+	metaKey        string
+	reloadInterval time.Duration
+
+	mgr *service.Resources
+	log *service.Logger
+
+	ringMut   sync.RWMutex
+	ring      *hashRing
+	nodesHash uint64
+
+	reloadMut sync.Mutex
+	lastCheck time.Time
+}
+
+func hashRingProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*hashRingProc, error) {
+	p := &hashRingProc{mgr: mgr, log: mgr.Logger()}
+
+	var err error
+	if p.key, err = conf.FieldInterpolatedString(hrFieldKey); err != nil {
+		return nil, err
+	}
+	if p.virtualNodes, err = conf.FieldInt(hrFieldVirtualNodes); err != nil {
+		return nil, err
+	}
+	if p.virtualNodes < 1 {
+		return nil, errors.New("virtual_nodes must be at least 1")
+	}
+	if p.metaKey, err = conf.FieldString(hrFieldMetadataKey); err != nil {
+		return nil, err
+	}
+	if p.reloadInterval, err = conf.FieldDuration(hrFieldReloadInterval); err != nil {
+		return nil, err
+	}
+	if p.nodesCacheKey, err = conf.FieldString(hrFieldNodesCacheKey); err != nil {
+		return nil, err
+	}
+
+	var nodesList []string
+	if conf.Contains(hrFieldNodes) {
+		if nodesList, err = conf.FieldStringList(hrFieldNodes); err != nil {
+			return nil, err
+		}
+	}
+	if (len(nodesList) > 0) == conf.Contains(hrFieldNodesCache) {
+		return nil, errors.New("exactly one of nodes or nodes_cache must be set")
+	}
+	if len(nodesList) > 0 {
+		nodes, err := dedupeHashRingNodes(nodesList)
+		if err != nil {
+			return nil, err
+		}
+		p.ring = newHashRing(nodes, p.virtualNodes)
+		return p, nil
+	}
+
+	if p.nodesCache, err = conf.FieldString(hrFieldNodesCache); err != nil {
+		return nil, err
+	}
+	if !mgr.HasCache(p.nodesCache) {
+		return nil, fmt.Errorf("cache resource '%v' was not found", p.nodesCache)
+	}
+	return p, nil
+}
+
+// load reads the nodes from the cache and rebuilds the ring when they have
+// changed.
+func (p *hashRingProc) load(ctx context.Context) error {
+	var nodesBytes []byte
+	var cacheErr error
+	if err := p.mgr.AccessCache(ctx, p.nodesCache, func(c service.Cache) {
+		nodesBytes, cacheErr = c.Get(ctx, p.nodesCacheKey)
+	}); err != nil {
+		return err
+	}
+	if cacheErr != nil {
+		return fmt.Errorf("failed to obtain nodes: %w", cacheErr)
+	}
+
+	nodesHash := xxhash.Sum64(nodesBytes)
+	p.ringMut.RLock()
+	unchanged := p.ring != nil && nodesHash == p.nodesHash
+	p.ringMut.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	nodes, err := parseHashRingNodes(nodesBytes)
+	if err != nil {
+		return err
+	}
+	ring := newHashRing(nodes, p.virtualNodes)
+
+	p.ringMut.Lock()
+	p.ring, p.nodesHash = ring, nodesHash
+	p.ringMut.Unlock()
+
+	p.log.Infof("Loaded hash ring with nodes: %v", strings.Join(nodes, ", "))
+	return nil
+}
+
+// maybeReload loads the nodes from the cache when the reload interval has
+// passed since the last check. Only one caller performs the check at a time,
+// and others continue with the current ring.
+func (p *hashRingProc) maybeReload(ctx context.Context) {
+	if p.nodesCache == "" || p.reloadInterval <= 0 || !p.reloadMut.TryLock() {
+		return
+	}
+	defer p.reloadMut.Unlock()
+
+	if time.Since(p.lastCheck) < p.reloadInterval {
+		return
+	}
+	p.lastCheck = time.Now()
+
+	if err := p.load(ctx); err != nil {
+		p.log.Errorf("Failed to reload hash ring nodes, continuing with the previous nodes: %v", err)
+	}
+}
+
+func (p *hashRingProc) currentRing(ctx context.Context) (*hashRing, error) {
+	p.ringMut.RLock()
+	ring := p.ring
+	p.ringMut.RUnlock()
+	if ring != nil {
+		p.maybeReload(ctx)
+		p.ringMut.RLock()
+		ring = p.ring
+		p.ringMut.RUnlock()
+		return ring, nil
+	}
+
+	// The nodes have not yet been loaded from the cache.
+	p.reloadMut.Lock()
+	defer p.reloadMut.Unlock()
+
+	p.ringMut.RLock()
+	ring = p.ring
+	p.ringMut.RUnlock()
+	if ring != nil {
+		return ring, nil
+	}
+	if err := p.load(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load hash ring nodes: %w", err)
+	}
+	p.lastCheck = time.Now()
+
+	p.ringMut.RLock()
+	defer p.ringMut.RUnlock()
+	return p.ring, nil
+}
+
+func (p *hashRingProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	ring, err := p.currentRing(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := p.key.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("key interpolation error: %w", err)
+	}
+
+	msg.MetaSetMut(p.metaKey, ring.node(key))
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *hashRingProc) Close(ctx context.Context) error {
+	return nil
+}
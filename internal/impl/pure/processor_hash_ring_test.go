@@ -0,0 +1,180 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func hashRingAssignments(ring *hashRing, n int) map[string]string {
+	res := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		k := fmt.Sprintf("key-%v", i)
+		res[k] = ring.node(k)
+	}
+	return res
+}
+
+func TestHashRingDistribution(t *testing.T) {
+	ring := newHashRing([]string{"a", "b", "c", "d"}, 1024)
+
+	counts := map[string]int{}
+	for _, n := range hashRingAssignments(ring, 20000) {
+		counts[n]++
+	}
+	require.Len(t, counts, 4)
+	for n, c := range counts {
+		assert.InDelta(t, 5000, c, 750, "node %v", n)
+	}
+
+	// The order of the nodes does not affect assignments.
+	assert.Equal(t, hashRingAssignments(ring, 1000), hashRingAssignments(newHashRing([]string{"d", "c", "b", "a"}, 1024), 1000))
+}
+
+func TestHashRingMinimalReassignment(t *testing.T) {
+	before := hashRingAssignments(newHashRing([]string{"a", "b", "c", "d"}, 128), 20000)
+
+	// Adding a node only moves keys to the new node.
+	added := hashRingAssignments(newHashRing([]string{"a", "b", "c", "d", "e"}, 128), 20000)
+	moved := 0
+	for k, n := range added {
+		if n != before[k] {
+			assert.Equal(t, "e", n)
+			moved++
+		}
+	}
+	assert.InDelta(t, 4000, moved, 1000)
+
+	// Removing a node only moves the keys of that node.
+	removed := hashRingAssignments(newHashRing([]string{"a", "b", "d"}, 128), 20000)
+	for k, n := range removed {
+		if before[k] != "c" {
+			assert.Equal(t, before[k], n)
+		} else {
+			assert.NotEqual(t, "c", n)
+		}
+	}
+}
+
+func TestHashRingStaticNodes(t *testing.T) {
+	conf, err := hashRingProcConfig().ParseYAML(`
+key: ${! this.id }
+nodes: [ a, b, "", a, c ]
+metadata_key: owner
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := hashRingProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	expected := newHashRing([]string{"a", "b", "c"}, 128)
+	for i := 0; i < 10; i++ {
+		id := fmt.Sprintf("id-%v", i)
+		res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"id":"`+id+`"}`)))
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+
+		v, exists := res[0].MetaGetMut("owner")
+		require.True(t, exists)
+		assert.Equal(t, expected.node(id), v)
+	}
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`not json`)))
+	require.Error(t, err)
+}
+
+func TestHashRingCacheNodes(t *testing.T) {
+	tCtx := context.Background()
+	mgr := service.MockResources(service.MockResourcesOptAddCache("foocache"))
+	setNodes := func(v string) {
+		require.NoError(t, mgr.AccessCache(tCtx, "foocache", func(c service.Cache) {
+			require.NoError(t, c.Set(tCtx, "members", []byte(v), nil))
+		}))
+	}
+
+	conf, err := hashRingProcConfig().ParseYAML(`
+key: ${! content() }
+nodes_cache: foocache
+nodes_cache_key: members
+reload_interval: 1ns
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := hashRingProcFromParsed(conf, mgr)
+	require.NoError(t, err)
+
+	// The nodes are loaded when the first message is processed.
+	_, err = proc.Process(tCtx, service.NewMessage([]byte("foo")))
+	require.Error(t, err)
+
+	node := func() string {
+		t.Helper()
+		res, err := proc.Process(tCtx, service.NewMessage([]byte("foo")))
+		require.NoError(t, err)
+		v, _ := res[0].MetaGetMut("node")
+		return v.(string)
+	}
+
+	setNodes(`["a"]`)
+	assert.Equal(t, "a", node())
+
+	setNodes("b\n\nb\n")
+	assert.Equal(t, "b", node())
+
+	// An invalid list of nodes is ignored.
+	setNodes(`[]`)
+	assert.Equal(t, "b", node())
+	setNodes(`[ nope`)
+	assert.Equal(t, "b", node())
+}
+
+func TestHashRingConfigErrors(t *testing.T) {
+	for _, conf := range []string{
+		`
+key: ${! this.id }
+`,
+		`
+key: ${! this.id }
+nodes: [ a ]
+nodes_cache: foocache
+`,
+		`
+key: ${! this.id }
+nodes: [ "" ]
+`,
+		`
+key: ${! this.id }
+nodes_cache: nope
+`,
+		`
+key: ${! this.id }
+nodes: [ a ]
+virtual_nodes: 0
+`,
+	} {
+		pConf, err := hashRingProcConfig().ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		_, err = hashRingProcFromParsed(pConf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+		require.Error(t, err, conf)
+	}
+}
@@ -0,0 +1,302 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"hash"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	mkFieldAlgorithm  = "algorithm"
+	mkFieldLeafFormat = "leaf_format"
+	mkFieldOutput     = "output"
+	mkFieldProofs     = "proofs"
+)
+
+func merkleProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Computes the root of a Merkle tree over the messages of a batch, which allows the integrity of the batch and the membership of each message to be verified.").
+		Description(`
+Each message of a batch is a leaf of the tree, ordered as they are within the batch. The tree is the Merkle Tree Hash defined by https://www.rfc-editor.org/rfc/rfc6962#section-2.1[RFC 6962^], where the hash of a leaf is the hash of the byte `+"`0x00`"+` followed by the contents of the leaf, and the hash of a node is the hash of the byte `+"`0x01`"+` followed by the hashes of its children. When the number of leaves is not a power of two the tree is unbalanced rather than padded, and therefore the root of a batch can only be produced by that exact sequence of messages.
+
+With a `+"`leaf_format`"+` of `+"`canonical_json`"+` the contents of each leaf is the message serialized in the canonical form defined by https://www.rfc-editor.org/rfc/rfc8785[RFC 8785^], the same as the `+"xref:components:processors/canonicalize_json.adoc[`canonicalize_json` processor]"+`, and therefore the root does not change when documents are reformatted. Messages that are not valid JSON are hashed as their raw contents.
+
+== Proofs
+
+When `+"`proofs`"+` is enabled the audit path of each message is added, which is an array of the hex encoded hashes of the siblings of the path from the leaf to the root, ordered from the leaf upwards. Together with the index of the message and the number of leaves the audit path verifies that the message belongs to the tree with the root, as described in https://www.rfc-editor.org/rfc/rfc6962#section-2.1.1[RFC 6962 section 2.1.1^].
+
+== Output
+
+With an `+"`output`"+` of `+"`metadata`"+` the messages of the batch are not modified, and the following metadata fields are added to each message:
+
+`+"```text"+`
+- merkle_root
+- merkle_leaf_index
+- merkle_leaf_count
+- merkle_proof
+`+"```"+`
+
+Where `+"`merkle_proof`"+` is only added when `+"`proofs`"+` is enabled. With an `+"`output`"+` of `+"`message`"+` a message is instead added to the end of the batch, with the root, the number of leaves and the hex encoded hash of each leaf, as well as the audit path of each leaf when `+"`proofs`"+` is enabled:
+
+`+"```json"+`
+{"algorithm":"sha256","root":"5c1f...","leaf_count":3,"leaves":["8a2e...","07bc...","e4d1..."]}
+`+"```"+`
+
+This processor operates on a batch of messages, which are best formed with a xref:configuration:batching.adoc[batching policy].`).
+		Field(service.NewStringAnnotatedEnumField(mkFieldAlgorithm, map[string]string{
+			"sha256": "SHA-256.",
+			"sha512": "SHA-512.",
+			"sha1":   "SHA-1.",
+			"md5":    "MD5.",
+		}).
+			Description("The hash algorithm used to hash leaves and nodes.").
+			Default("sha256")).
+		Field(service.NewStringAnnotatedEnumField(mkFieldLeafFormat, map[string]string{
+			"canonical_json": "The message serialized as canonical JSON.",
+			"raw":            "The raw contents of the message.",
+		}).
+			Description("The form of each message that is hashed as a leaf.").
+			Default("canonical_json")).
+		Field(service.NewStringAnnotatedEnumField(mkFieldOutput, map[string]string{
+			"metadata": "Add the root to the metadata of each message.",
+			"message":  "Add a message containing the root to the end of the batch.",
+		}).
+			Description("How the root is emitted.").
+			Default("metadata")).
+		Field(service.NewBoolField(mkFieldProofs).
+			Description("Whether to add the audit path of each message.").
+			Default(false)).
+		Example("Audit log", "Write a Merkle root of each batch of events to a separate audit topic, with the audit path of each event added to its metadata.", `
+input:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topics: [ events ]
+    consumer_group: auditor
+    batching:
+      count: 1000
+      period: 5s
+
+pipeline:
+  processors:
+    - merkle:
+        proofs: true
+
+output:
+  broker:
+    pattern: fan_out
+    outputs:
+      - kafka_franz:
+          seed_brokers: [ localhost:9092 ]
+          topic: events_audited
+          metadata:
+            include_patterns: [ "^merkle_" ]
+      - kafka_franz:
+          seed_brokers: [ localhost:9092 ]
+          topic: audit_roots
+        processors:
+          - mapping: |
+              root = if batch_index() == 0 {
+                {"root": @merkle_root, "leaf_count": @merkle_leaf_count}
+              } else {
+                deleted()
+              }
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"merkle", merkleProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return merkleProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// merkleTree computes the Merkle Tree Hash of RFC 6962 over a list of leaf
+// hashes, memoising the hashes of subtrees so that audit paths of every leaf
+// can be produced without hashing any subtree twice.
+type merkleTree struct {
+	hasher func() hash.Hash
+	leaves [][]byte
+	memo   map[[2]int][]byte
+}
+
+func (t *merkleTree) leafHash(data []byte) []byte {
+	h := t.hasher()
+	_, _ = h.Write([]byte{0x00})
+	_, _ = h.Write(data)
+	return h.Sum(nil)
+}
+
+func (t *merkleTree) nodeHash(left, right []byte) []byte {
+	h := t.hasher()
+	_, _ = h.Write([]byte{0x01})
+	_, _ = h.Write(left)
+	_, _ = h.Write(right)
+	return h.Sum(nil)
+}
+
+// merkleSplit returns the largest power of two smaller than n.
+func merkleSplit(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+// hash returns the hash of the subtree over the leaves [start, end).
+func (t *merkleTree) hash(start, end int) []byte {
+	switch end - start {
+	case 0:
+		return t.hasher().Sum(nil)
+	case 1:
+		return t.leaves[start]
+	}
+	if h, exists := t.memo[[2]int{start, end}]; exists {
+		return h
+	}
+	k := merkleSplit(end - start)
+	h := t.nodeHash(t.hash(start, start+k), t.hash(start+k, end))
+	t.memo[[2]int{start, end}] = h
+	return h
+}
+
+// path returns the audit path of the leaf m within the subtree over the
+// leaves [start, end), ordered from the leaf upwards.
+func (t *merkleTree) path(m, start, end int) [][]byte {
+	if end-start <= 1 {
+		return nil
+	}
+	k := merkleSplit(end - start)
+	if m < start+k {
+		return append(t.path(m, start, start+k), t.hash(start+k, end))
+	}
+	return append(t.path(m, start+k, end), t.hash(start, start+k))
+}
+
+//------------------------------------------------------------------------------
+
+type merkleProc struct {
+	algorithm     string
+	hasher        func() hash.Hash
+	canonicalJSON bool
+	asMessage     bool
+	proofs        bool
+}
+
+func merkleProcFromParsed(conf *service.ParsedConfig) (*merkleProc, error) {
+	p := &merkleProc{}
+
+	var err error
+	if p.algorithm, err = conf.FieldString(mkFieldAlgorithm); err != nil {
+		return nil, err
+	}
+	if p.hasher, err = fingerprintHasher(p.algorithm); err != nil {
+		return nil, err
+	}
+
+	leafFormat, err := conf.FieldString(mkFieldLeafFormat)
+	if err != nil {
+		return nil, err
+	}
+	p.canonicalJSON = leafFormat == "canonical_json"
+
+	output, err := conf.FieldString(mkFieldOutput)
+	if err != nil {
+		return nil, err
+	}
+	p.asMessage = output == "message"
+
+	if p.proofs, err = conf.FieldBool(mkFieldProofs); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func merkleHexList(hashes [][]byte) []any {
+	res := make([]any, len(hashes))
+	for i, h := range hashes {
+		res[i] = hex.EncodeToString(h)
+	}
+	return res
+}
+
+func (p *merkleProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	if len(batch) == 0 {
+		return nil, nil
+	}
+
+	t := &merkleTree{hasher: p.hasher, memo: map[[2]int][]byte{}}
+	for _, msg := range batch {
+		data, err := msg.AsBytes()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read message: %w", err)
+		}
+		if p.canonicalJSON {
+			if canonical, err := canonicalizeJSON(data); err == nil {
+				data = canonical
+			}
+		}
+		t.leaves = append(t.leaves, t.leafHash(data))
+	}
+
+	root := hex.EncodeToString(t.hash(0, len(t.leaves)))
+
+	if p.asMessage {
+		doc := map[string]any{
+			"algorithm":  p.algorithm,
+			"root":       root,
+			"leaf_count": int64(len(t.leaves)),
+			"leaves":     merkleHexList(t.leaves),
+		}
+		if p.proofs {
+			proofs := make([]any, len(t.leaves))
+			for i := range t.leaves {
+				proofs[i] = merkleHexList(t.path(i, 0, len(t.leaves)))
+			}
+			doc["proofs"] = proofs
+		}
+		rootMsg := service.NewMessage(nil)
+		rootMsg.SetStructuredMut(doc)
+		return []service.MessageBatch{append(batch, rootMsg)}, nil
+	}
+
+	for i, msg := range batch {
+		msg.MetaSetMut("merkle_root", root)
+		msg.MetaSetMut("merkle_leaf_index", int64(i))
+		msg.MetaSetMut("merkle_leaf_count", int64(len(t.leaves)))
+		if p.proofs {
+			msg.MetaSetMut("merkle_proof", merkleHexList(t.path(i, 0, len(t.leaves))))
+		}
+	}
+	return []service.MessageBatch{batch}, nil
+}
+
+func (p *merkleProc) Close(ctx context.Context) error {
+	return nil
+}
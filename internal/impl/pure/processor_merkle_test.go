@@ -0,0 +1,187 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+// verifyMerkleProof verifies an audit path with the algorithm of RFC 9162
+// section 2.1.3.2.
+func verifyMerkleProof(t testing.TB, leaf []byte, index, size int, proof []any, root string) bool {
+	t.Helper()
+
+	nodeHash := func(l, r []byte) []byte {
+		h := sha256.New()
+		h.Write([]byte{0x01})
+		h.Write(l)
+		h.Write(r)
+		return h.Sum(nil)
+	}
+
+	lh := sha256.New()
+	lh.Write([]byte{0x00})
+	lh.Write(leaf)
+	r := lh.Sum(nil)
+
+	fn, sn := index, size-1
+	for _, pv := range proof {
+		p, err := hex.DecodeString(pv.(string))
+		require.NoError(t, err)
+		if sn == 0 {
+			return false
+		}
+		if fn&1 == 1 || fn == sn {
+			r = nodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = nodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	return sn == 0 && hex.EncodeToString(r) == root
+}
+
+func TestMerkleKnownRoot(t *testing.T) {
+	conf, err := merkleProcConfig().ParseYAML(`
+leaf_format: raw
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := merkleProcFromParsed(conf)
+	require.NoError(t, err)
+
+	res, err := proc.ProcessBatch(context.Background(), service.MessageBatch{service.NewMessage(nil)})
+	require.NoError(t, err)
+
+	// The RFC 6962 hash of a single empty leaf.
+	v, _ := res[0][0].MetaGet("merkle_root")
+	assert.Equal(t, "6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d", v)
+}
+
+func TestMerkleProofs(t *testing.T) {
+	conf, err := merkleProcConfig().ParseYAML(`
+leaf_format: raw
+proofs: true
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := merkleProcFromParsed(conf)
+	require.NoError(t, err)
+
+	for size := 1; size <= 17; size++ {
+		var batch service.MessageBatch
+		for i := 0; i < size; i++ {
+			batch = append(batch, service.NewMessage([]byte(fmt.Sprintf("leaf %v", i))))
+		}
+
+		res, err := proc.ProcessBatch(context.Background(), batch)
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+		require.Len(t, res[0], size)
+
+		for i, msg := range res[0] {
+			root, _ := msg.MetaGet("merkle_root")
+			idx, _ := msg.MetaGetMut("merkle_leaf_index")
+			count, _ := msg.MetaGetMut("merkle_leaf_count")
+			proof, _ := msg.MetaGetMut("merkle_proof")
+
+			assert.Equal(t, int64(i), idx)
+			assert.Equal(t, int64(size), count)
+			assert.True(t, verifyMerkleProof(t, []byte(fmt.Sprintf("leaf %v", i)), i, size, proof.([]any), root), "size %v leaf %v", size, i)
+			assert.False(t, verifyMerkleProof(t, []byte("tampered"), i, size, proof.([]any), root), "size %v leaf %v", size, i)
+		}
+	}
+}
+
+func TestMerkleCanonicalJSON(t *testing.T) {
+	conf, err := merkleProcConfig().ParseYAML(`{}`, nil)
+	require.NoError(t, err)
+
+	proc, err := merkleProcFromParsed(conf)
+	require.NoError(t, err)
+
+	rootOf := func(docs ...string) string {
+		var batch service.MessageBatch
+		for _, d := range docs {
+			batch = append(batch, service.NewMessage([]byte(d)))
+		}
+		res, err := proc.ProcessBatch(context.Background(), batch)
+		require.NoError(t, err)
+		v, _ := res[0][0].MetaGet("merkle_root")
+		return v
+	}
+
+	a := rootOf(`{"a":1,"b":[1,2]}`, `not json`)
+	assert.Equal(t, a, rootOf(`{ "b": [1, 2], "a": 1.0 }`, `not json`))
+	assert.NotEqual(t, a, rootOf(`not json`, `{"a":1,"b":[1,2]}`))
+	assert.NotEqual(t, a, rootOf(`{"a":2,"b":[1,2]}`, `not json`))
+}
+
+func TestMerkleMessageOutput(t *testing.T) {
+	conf, err := merkleProcConfig().ParseYAML(`
+output: message
+proofs: true
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := merkleProcFromParsed(conf)
+	require.NoError(t, err)
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1}`)),
+		service.NewMessage([]byte(`{"id":2}`)),
+		service.NewMessage([]byte(`{"id":3}`)),
+	}
+	res, err := proc.ProcessBatch(context.Background(), batch)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 4)
+
+	for _, msg := range res[0][:3] {
+		_, exists := msg.MetaGet("merkle_root")
+		assert.False(t, exists)
+	}
+
+	v, err := res[0][3].AsStructured()
+	require.NoError(t, err)
+	doc := v.(map[string]any)
+
+	assert.Equal(t, "sha256", doc["algorithm"])
+	assert.NotEmpty(t, doc["root"])
+	assert.Equal(t, int64(3), doc["leaf_count"])
+	require.Len(t, doc["leaves"], 3)
+	require.Len(t, doc["proofs"], 3)
+
+	for i, d := range []string{`{"id":1}`, `{"id":2}`, `{"id":3}`} {
+		assert.True(t, verifyMerkleProof(t, []byte(d), i, 3, doc["proofs"].([]any)[i].([]any), doc["root"].(string)))
+
+		h := sha256.Sum256(append([]byte{0x00}, d...))
+		assert.Equal(t, hex.EncodeToString(h[:]), doc["leaves"].([]any)[i])
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	msFieldContentType = "content_type"
+	msFieldRecursive   = "recursive"
+)
+
+func mimeSplitProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Parsing").
+		Version("4.31.0").
+		Summary("Splits a multipart MIME message into a message for each of its parts.").
+		Description(`
+The boundary of the parts is obtained from the Content-Type of the message. When the field `+"`content_type`"+` is set it is resolved for each message, which is useful for payloads such as form uploads where the Content-Type was sent as a header, otherwise the message is parsed as a MIME entity beginning with its own headers, such as an email.
+
+The contents of each part are decoded according to its Content-Transfer-Encoding, where `+"`base64`"+` and `+"`quoted-printable`"+` are supported and all other encodings are emitted as they are. When `+"`recursive`"+` is true parts that are themselves multipart, such as the `+"`multipart/alternative`"+` body of an email with attachments, are split further rather than emitted as a single message.
+
+Messages that are not multipart or fail to parse are flagged as errors.
+
+== Metadata
+
+The metadata of the original message is copied to each part, and the following metadata fields are added:
+
+`+"```text"+`
+- mime_content_type
+- mime_content_disposition
+- mime_filename
+- mime_form_name
+- mime_part_index
+`+"```"+`
+
+Where the fields `+"`mime_filename`"+` and `+"`mime_form_name`"+` are obtained from the Content-Disposition of the part and are only set when present, and `+"`mime_part_index`"+` is the index of the part within the message.`).
+		Field(service.NewInterpolatedStringField(msFieldContentType).
+			Description("An optional Content-Type of each message, including the boundary parameter. When not set the Content-Type is read from the headers at the beginning of the message.").
+			Example(`${! metadata("Content-Type") }`).
+			Optional()).
+		Field(service.NewBoolField(msFieldRecursive).
+			Description("Whether to split parts that are themselves multipart into their own parts.").
+			Default(false)).
+		Example("Form uploads", "Split multipart form uploads received over HTTP into a message for each file, and write them out by their file name.", `
+input:
+  http_server:
+    path: /upload
+
+pipeline:
+  processors:
+    - mime_split:
+        content_type: ${! metadata("Content-Type") }
+    - mapping: |
+        root = if @mime_filename.or("") == "" { deleted() }
+
+output:
+  file:
+    path: ./uploads/${! @mime_filename }
+    codec: all-bytes
+`).
+		Example("Email attachments", "Split raw emails into their parts, including those of nested multipart bodies.", `
+pipeline:
+  processors:
+    - mime_split:
+        recursive: true
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"mime_split", mimeSplitProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return mimeSplitProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type mimeSplitProc struct {
+	contentType *service.InterpolatedString
+	recursive   bool
+}
+
+func mimeSplitProcFromParsed(conf *service.ParsedConfig) (*mimeSplitProc, error) {
+	p := &mimeSplitProc{}
+
+	var err error
+	if conf.Contains(msFieldContentType) {
+		if p.contentType, err = conf.FieldInterpolatedString(msFieldContentType); err != nil {
+			return nil, err
+		}
+	}
+	if p.recursive, err = conf.FieldBool(msFieldRecursive); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+type mimePart struct {
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+func mimeDecodeBody(header textproto.MIMEHeader, r io.Reader) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		r = quotedprintable.NewReader(r)
+	}
+	return io.ReadAll(r)
+}
+
+// splitParts reads each part of a multipart body, descending into nested
+// multipart parts when recursive is enabled.
+func (p *mimeSplitProc) splitParts(contentType string, body io.Reader) ([]mimePart, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse content type: %w", err)
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, fmt.Errorf("content type %v is not multipart", mediaType)
+	}
+	boundary := params["boundary"]
+	if boundary == "" {
+		return nil, errors.New("content type is missing a boundary")
+	}
+
+	var parts []mimePart
+	mr := multipart.NewReader(body, boundary)
+	for {
+		part, err := mr.NextRawPart()
+		if errors.Is(err, io.EOF) {
+			return parts, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read part: %w", err)
+		}
+
+		partBody, err := mimeDecodeBody(part.Header, part)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode part: %w", err)
+		}
+
+		if p.recursive {
+			if partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type")); strings.HasPrefix(partType, "multipart/") {
+				nested, err := p.splitParts(part.Header.Get("Content-Type"), bytes.NewReader(partBody))
+				if err != nil {
+					return nil, err
+				}
+				parts = append(parts, nested...)
+				continue
+			}
+		}
+		parts = append(parts, mimePart{header: part.Header, body: partBody})
+	}
+}
+
+func (p *mimeSplitProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	msgBytes, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var contentType string
+	var body io.Reader
+	if p.contentType != nil {
+		if contentType, err = p.contentType.TryString(msg); err != nil {
+			return nil, fmt.Errorf("content type interpolation error: %w", err)
+		}
+		body = bytes.NewReader(msgBytes)
+	} else {
+		tr := textproto.NewReader(bufio.NewReader(bytes.NewReader(msgBytes)))
+		header, err := tr.ReadMIMEHeader()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read headers: %w", err)
+		}
+		contentType = header.Get("Content-Type")
+		body = tr.R
+	}
+
+	parts, err := p.splitParts(contentType, body)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := make(service.MessageBatch, 0, len(parts))
+	for i, part := range parts {
+		partMsg := msg.Copy()
+		partMsg.SetBytes(part.body)
+
+		partType := part.header.Get("Content-Type")
+		if partType == "" {
+			partType = "text/plain; charset=us-ascii"
+		}
+		partMsg.MetaSetMut("mime_content_type", partType)
+		partMsg.MetaSetMut("mime_part_index", i)
+
+		if disposition := part.header.Get("Content-Disposition"); disposition != "" {
+			partMsg.MetaSetMut("mime_content_disposition", disposition)
+			if _, params, err := mime.ParseMediaType(disposition); err == nil {
+				if filename := params["filename"]; filename != "" {
+					partMsg.MetaSetMut("mime_filename", filename)
+				}
+				if name := params["name"]; name != "" {
+					partMsg.MetaSetMut("mime_form_name", name)
+				}
+			}
+		}
+		batch = append(batch, partMsg)
+	}
+	return batch, nil
+}
+
+func (p *mimeSplitProc) Close(ctx context.Context) error {
+	return nil
+}
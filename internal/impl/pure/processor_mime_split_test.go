@@ -0,0 +1,144 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestMimeSplitForm(t *testing.T) {
+	conf, err := mimeSplitProcConfig().ParseYAML(`content_type: ${! @content_type }`, nil)
+	require.NoError(t, err)
+
+	proc, err := mimeSplitProcFromParsed(conf)
+	require.NoError(t, err)
+
+	body := strings.ReplaceAll(`--foo
+Content-Disposition: form-data; name="title"
+
+hello world
+--foo
+Content-Disposition: form-data; name="upload"; filename="a.txt"
+Content-Type: text/plain
+Content-Transfer-Encoding: base64
+
+aGVsbG8g
+d29ybGQ=
+--foo--
+`, "\n", "\r\n")
+
+	msg := service.NewMessage([]byte(body))
+	msg.MetaSetMut("content_type", `multipart/form-data; boundary=foo`)
+	msg.MetaSetMut("keep", "me")
+
+	res, err := proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+
+	assert.Equal(t, []string{"hello world", "hello world"}, batchContents(t, res))
+
+	meta := func(m *service.Message, k string) any {
+		v, _ := m.MetaGetMut(k)
+		return v
+	}
+
+	assert.Equal(t, "text/plain; charset=us-ascii", meta(res[0], "mime_content_type"))
+	assert.Equal(t, "title", meta(res[0], "mime_form_name"))
+	_, exists := res[0].MetaGetMut("mime_filename")
+	assert.False(t, exists)
+	assert.Equal(t, 0, meta(res[0], "mime_part_index"))
+	assert.Equal(t, "me", meta(res[0], "keep"))
+
+	assert.Equal(t, "text/plain", meta(res[1], "mime_content_type"))
+	assert.Equal(t, `form-data; name="upload"; filename="a.txt"`, meta(res[1], "mime_content_disposition"))
+	assert.Equal(t, "a.txt", meta(res[1], "mime_filename"))
+	assert.Equal(t, "upload", meta(res[1], "mime_form_name"))
+	assert.Equal(t, 1, meta(res[1], "mime_part_index"))
+}
+
+func TestMimeSplitEmail(t *testing.T) {
+	email := strings.ReplaceAll(`From: foo@example.com
+Subject: Hello
+MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="outer"
+
+--outer
+Content-Type: multipart/alternative; boundary="inner"
+
+--inner
+Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+caf=C3=A9
+--inner
+Content-Type: text/html; charset=utf-8
+
+<p>caf&eacute;</p>
+--inner--
+--outer
+Content-Type: application/octet-stream
+Content-Disposition: attachment; filename="data.bin"
+Content-Transfer-Encoding: base64
+
+AAEC
+--outer--
+`, "\n", "\r\n")
+
+	conf, err := mimeSplitProcConfig().ParseYAML(``, nil)
+	require.NoError(t, err)
+
+	flat, err := mimeSplitProcFromParsed(conf)
+	require.NoError(t, err)
+
+	res, err := flat.Process(context.Background(), service.NewMessage([]byte(email)))
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+
+	ct, _ := res[0].MetaGetMut("mime_content_type")
+	assert.Equal(t, `multipart/alternative; boundary="inner"`, ct)
+
+	pConf, err := mimeSplitProcConfig().ParseYAML(`recursive: true`, nil)
+	require.NoError(t, err)
+
+	recursive, err := mimeSplitProcFromParsed(pConf)
+	require.NoError(t, err)
+
+	res, err = recursive.Process(context.Background(), service.NewMessage([]byte(email)))
+	require.NoError(t, err)
+	require.Len(t, res, 3)
+
+	assert.Equal(t, []string{"café", "<p>caf&eacute;</p>", "\x00\x01\x02"}, batchContents(t, res))
+	filename, _ := res[2].MetaGetMut("mime_filename")
+	assert.Equal(t, "data.bin", filename)
+}
+
+func TestMimeSplitNotMultipart(t *testing.T) {
+	conf, err := mimeSplitProcConfig().ParseYAML(`content_type: text/plain`, nil)
+	require.NoError(t, err)
+
+	proc, err := mimeSplitProcFromParsed(conf)
+	require.NoError(t, err)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`hello`)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not multipart")
+}
@@ -0,0 +1,292 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	maFieldKey         = "key"
+	maFieldValue       = "value"
+	maFieldType        = "type"
+	maFieldWindow      = "window"
+	maFieldAlpha       = "alpha"
+	maFieldCache       = "cache"
+	maFieldTTL         = "ttl"
+	maFieldMetadataKey = "metadata_key"
+	maFieldTargetPath  = "target_path"
+)
+
+func movingAverageProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Calculates a moving average of a value for each key, storing the state of each key in a cache, and annotates each message with the average that includes its value.").
+		Description(`
+The `+"`sma`"+` type calculates the simple moving average of the last `+"`window`"+` values of each key, and the `+"`ema`"+` type calculates the exponential moving average of all values of each key with the smoothing factor `+"`alpha`"+`, where the first value of a key is its own average.
+
+The average is written to the metadata field `+"`metadata_key`"+` of each message, and also to `+"`target_path`"+` within the message when it's set. Messages where the value cannot be parsed as a number are flagged as failed so that they can be handled using xref:configuration:error_handling.adoc[error handling methods], and do not affect the average of their key.
+
+== State
+
+The state of each key is limited to the last `+"`window`"+` values for the `+"`sma`"+` type, and to a single value for the `+"`ema`"+` type, and is stored in the cache. Keys that are no longer seen can be evicted by setting a `+"`ttl`"+`, or by using a cache that limits its size such as `+"xref:components:caches/ristretto.adoc[`ristretto`]"+`, where an evicted key starts again from its next value.
+
+Each value is added to the state of its key read from the cache, and the updated state is then written back. Values of the same key that are processed by multiple pipeline threads at the same time are each added to the same state and only the last write is kept, which drops the other values from the average. The values of a key must therefore be processed by a single pipeline thread, which can be achieved by partitioning messages by key upstream.`).
+		Field(service.NewInterpolatedStringField(maFieldKey).
+			Description("An interpolated string that resolves to the key of each message.").
+			Example(`${! this.sensor_id }`)).
+		Field(service.NewInterpolatedStringField(maFieldValue).
+			Description("An interpolated string that resolves to the numeric value of each message.").
+			Example(`${! this.temperature }`)).
+		Field(service.NewStringAnnotatedEnumField(maFieldType, map[string]string{
+			"sma": "A simple moving average over the last `window` values.",
+			"ema": "An exponential moving average with the smoothing factor `alpha`.",
+		}).
+			Description("The type of moving average to calculate.").
+			Default("sma")).
+		Field(service.NewIntField(maFieldWindow).
+			Description("The number of values to average, only relevant when the type is `sma`.").
+			Default(10)).
+		Field(service.NewFloatField(maFieldAlpha).
+			Description("The smoothing factor between zero and one, only relevant when the type is `ema`, where higher values discount older values faster.").
+			Default(0.1)).
+		Field(service.NewStringField(maFieldCache).
+			Description("The xref:components:caches/about.adoc[`cache` resource] to store the state of each key in.")).
+		Field(service.NewStringField(maFieldTTL).
+			Description("An optional TTL to set for the state of each key, after which the average of the key starts again. Not all caches support per-key TTLs.").
+			Example("1h").
+			Optional().
+			Advanced()).
+		Field(service.NewStringField(maFieldMetadataKey).
+			Description("The metadata key to store the average in.").
+			Default("moving_average")).
+		Field(service.NewStringField(maFieldTargetPath).
+			Description("An optional xref:configuration:field_paths.adoc[dot separated path] to also write the average to within each message.").
+			Example("temperature_smoothed").
+			Optional()).
+		Example("Smooth sensor readings", "Add the exponential moving average of the temperature of each sensor to its readings.", `
+pipeline:
+  processors:
+    - moving_average:
+        key: ${! this.sensor_id }
+        value: ${! this.temperature }
+        type: ema
+        alpha: 0.2
+        cache: averages
+        ttl: 1h
+        target_path: temperature_smoothed
+
+cache_resources:
+  - label: averages
+    memory: {}
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"moving_average", movingAverageProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return movingAverageProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type movingAverageProc struct {
+	key        *service.InterpolatedString
+	value      *service.InterpolatedString
+	ema        bool
+	window     int
+	alpha      float64
+	cache      string
+	ttl        *time.Duration
+	metaKey    string
+	targetPath string
+
+	mgr *service.Resources
+}
+
+func movingAverageProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*movingAverageProc, error) {
+	p := &movingAverageProc{mgr: mgr}
+
+	var err error
+	if p.key, err = conf.FieldInterpolatedString(maFieldKey); err != nil {
+		return nil, err
+	}
+	if p.value, err = conf.FieldInterpolatedString(maFieldValue); err != nil {
+		return nil, err
+	}
+
+	maType, err := conf.FieldString(maFieldType)
+	if err != nil {
+		return nil, err
+	}
+	switch maType {
+	case "sma":
+		if p.window, err = conf.FieldInt(maFieldWindow); err != nil {
+			return nil, err
+		}
+		if p.window < 1 {
+			return nil, errors.New("window must be at least 1")
+		}
+	case "ema":
+		p.ema = true
+		if p.alpha, err = conf.FieldFloat(maFieldAlpha); err != nil {
+			return nil, err
+		}
+		if p.alpha <= 0 || p.alpha > 1 {
+			return nil, errors.New("alpha must be greater than zero and no greater than one")
+		}
+	default:
+		return nil, fmt.Errorf("type not recognised: %v", maType)
+	}
+
+	if p.cache, err = conf.FieldString(maFieldCache); err != nil {
+		return nil, err
+	}
+	if !mgr.HasCache(p.cache) {
+		return nil, fmt.Errorf("cache resource '%v' was not found", p.cache)
+	}
+	if conf.Contains(maFieldTTL) {
+		ttlStr, err := conf.FieldString(maFieldTTL)
+		if err != nil {
+			return nil, err
+		}
+		ttl, err := time.ParseDuration(ttlStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ttl: %w", err)
+		}
+		p.ttl = &ttl
+	}
+	if p.metaKey, err = conf.FieldString(maFieldMetadataKey); err != nil {
+		return nil, err
+	}
+	if conf.Contains(maFieldTargetPath) {
+		if p.targetPath, err = conf.FieldString(maFieldTargetPath); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// movingAverageState is the state of a key as it is stored within the cache,
+// where values holds the most recent values of a simple moving average and
+// average holds the current exponential moving average.
+type movingAverageState struct {
+	Values  []float64 `json:"values,omitempty"`
+	Average *float64  `json:"average,omitempty"`
+}
+
+func (p *movingAverageProc) update(state *movingAverageState, v float64) float64 {
+	if p.ema {
+		avg := v
+		if state.Average != nil {
+			avg = p.alpha*v + (1-p.alpha)*(*state.Average)
+		}
+		state.Average = &avg
+		return avg
+	}
+
+	state.Values = append(state.Values, v)
+	if len(state.Values) > p.window {
+		state.Values = state.Values[len(state.Values)-p.window:]
+	}
+	var sum float64
+	for _, x := range state.Values {
+		sum += x
+	}
+	return sum / float64(len(state.Values))
+}
+
+func (p *movingAverageProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	key, err := p.key.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("key interpolation error: %w", err)
+	}
+	valueStr, err := p.value.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("value interpolation error: %w", err)
+	}
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse value %q as a number", valueStr)
+	}
+
+	var state movingAverageState
+	var cacheErr error
+	if err := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		stateBytes, err := c.Get(ctx, key)
+		if err != nil {
+			if !errors.Is(err, service.ErrKeyNotFound) {
+				cacheErr = err
+			}
+			return
+		}
+		if err := json.Unmarshal(stateBytes, &state); err != nil {
+			cacheErr = fmt.Errorf("failed to parse state: %w", err)
+		}
+	}); err != nil {
+		return nil, err
+	}
+	if cacheErr != nil {
+		return nil, fmt.Errorf("failed to obtain state of %v: %w", key, cacheErr)
+	}
+
+	avg := p.update(&state, value)
+
+	if p.targetPath != "" {
+		root, err := msg.AsStructuredMut()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse message as JSON: %w", err)
+		}
+		gObj := gabs.Wrap(root)
+		if _, err := gObj.SetP(avg, p.targetPath); err != nil {
+			return nil, fmt.Errorf("failed to set target path: %w", err)
+		}
+		msg.SetStructuredMut(gObj.Data())
+	}
+
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		cacheErr = c.Set(ctx, key, stateBytes, p.ttl)
+	}); err != nil {
+		return nil, err
+	}
+	if cacheErr != nil {
+		return nil, fmt.Errorf("failed to store state of %v: %w", key, cacheErr)
+	}
+
+	msg.MetaSetMut(p.metaKey, avg)
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *movingAverageProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func movingAverages(t testing.TB, proc *movingAverageProc, contents ...string) []float64 {
+	t.Helper()
+
+	var avgs []float64
+	for _, c := range contents {
+		res, err := proc.Process(context.Background(), service.NewMessage([]byte(c)))
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+
+		v, exists := res[0].MetaGetMut("moving_average")
+		require.True(t, exists)
+		avgs = append(avgs, v.(float64))
+	}
+	return avgs
+}
+
+func TestMovingAverageSMA(t *testing.T) {
+	conf, err := movingAverageProcConfig().ParseYAML(`
+key: ${! this.id }
+value: ${! this.v }
+window: 3
+cache: foocache
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := movingAverageProcFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.NoError(t, err)
+
+	assert.Equal(t, []float64{2, 10, 3, 4, 6, 20}, movingAverages(t, proc,
+		`{"id":"a","v":2}`,
+		`{"id":"b","v":10}`,
+		`{"id":"a","v":4}`,
+		`{"id":"a","v":6}`,
+		`{"id":"a","v":8}`,
+		`{"id":"b","v":30}`,
+	))
+}
+
+func TestMovingAverageEMA(t *testing.T) {
+	conf, err := movingAverageProcConfig().ParseYAML(`
+key: ${! this.id }
+value: ${! this.v }
+type: ema
+alpha: 0.5
+cache: foocache
+target_path: smoothed
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := movingAverageProcFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.NoError(t, err)
+
+	assert.Equal(t, []float64{10, 15, 17.5}, movingAverages(t, proc,
+		`{"id":"a","v":10}`,
+		`{"id":"a","v":20}`,
+		`{"id":"a","v":20}`,
+	))
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"id":"a","v":"0.5"}`)))
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{"id":"a","smoothed":9,"v":"0.5"}`}, batchContents(t, res))
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"id":"a","v":"nope"}`)))
+	require.Error(t, err)
+	assert.Equal(t, []float64{9.5}, movingAverages(t, proc, `{"id":"a","v":10}`))
+}
+
+func TestMovingAverageBadConfig(t *testing.T) {
+	for _, conf := range []string{
+		"window: 0",
+		"type: ema\nalpha: 0",
+		"type: ema\nalpha: 1.5",
+	} {
+		pConf, err := movingAverageProcConfig().ParseYAML(`
+key: foo
+value: ${! this.v }
+cache: foocache
+`+conf, nil)
+		require.NoError(t, err)
+
+		_, err = movingAverageProcFromParsed(pConf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+		require.Error(t, err, conf)
+	}
+}
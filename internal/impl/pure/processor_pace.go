@@ -0,0 +1,181 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	paceFieldRate = "rate"
+	paceFieldUnit = "unit"
+)
+
+func paceProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Delays messages so that they pass through at a steady rate.").
+		Description(`
+Unlike a xref:components:rate_limits/about.adoc[rate limit], which allows bursts of messages up to a limit within each interval, this processor spaces messages evenly according to `+"`rate`"+`, where each message is delayed until the previous message has used its share of time. This is useful for replaying historical data at a controlled speed.
+
+When `+"`unit`"+` is `+"`bytes`"+` the share of time of each message is proportional to its size, such that large messages delay the messages that follow them for longer.
+
+The rate is interpolated for each message, which allows it to be changed dynamically, for example from metadata or an environment variable, and a change of rate applies from the next message. Time that passes whilst no messages arrive is not saved up, and therefore messages that arrive after a pause are paced from the time they arrive rather than allowed through in a burst.
+
+The rate is paced per instance of this processor, and therefore when a pipeline has multiple threads the rate applies to each thread separately. In order to pace an entire stream place this processor within the `+"`processors`"+` of the input, or set the number of pipeline threads to one.`).
+		Field(service.NewInterpolatedStringField(paceFieldRate).
+			Description("The number of units per second to pace messages to, which must resolve to a number greater than zero.").
+			Example("100").
+			Example(`${! env("REPLAY_RATE").or("1000") }`)).
+		Field(service.NewStringAnnotatedEnumField(paceFieldUnit, map[string]string{
+			"messages": "Pace the number of messages per second.",
+			"bytes":    "Pace the number of bytes of message contents per second.",
+		}).
+			Description("The unit of the rate.").
+			Default("messages")).
+		Example("Replay at a fixed rate", "Replay archived events from a bucket at 500 events per second.", `
+input:
+  aws_s3:
+    bucket: events-archive
+    prefix: 2024/05/
+    scanner:
+      lines: {}
+  processors:
+    - pace:
+        rate: "500"
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"pace", paceProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return paceProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type paceProc struct {
+	rate  *service.InterpolatedString
+	bytes bool
+
+	mut  sync.Mutex
+	next time.Time
+
+	now func() time.Time
+}
+
+func paceProcFromParsed(conf *service.ParsedConfig) (*paceProc, error) {
+	p := &paceProc{
+		now: time.Now,
+	}
+
+	var err error
+	if p.rate, err = conf.FieldInterpolatedString(paceFieldRate); err != nil {
+		return nil, err
+	}
+	if static, ok := p.rate.Static(); ok {
+		if _, err := parsePaceRate(static); err != nil {
+			return nil, err
+		}
+	}
+
+	unit, err := conf.FieldString(paceFieldUnit)
+	if err != nil {
+		return nil, err
+	}
+	switch unit {
+	case "messages":
+	case "bytes":
+		p.bytes = true
+	default:
+		return nil, fmt.Errorf("unit not recognised: %v", unit)
+	}
+	return p, nil
+}
+
+func parsePaceRate(s string) (float64, error) {
+	rate, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse rate: %w", err)
+	}
+	if rate <= 0 {
+		return 0, fmt.Errorf("rate must be greater than zero, got %v", s)
+	}
+	return rate, nil
+}
+
+// reserve returns the time at which a message of a given cost may pass
+// through, and reserves the time following it for the message.
+func (p *paceProc) reserve(cost, rate float64) time.Time {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	start := p.now()
+	if p.next.After(start) {
+		start = p.next
+	}
+	p.next = start.Add(time.Duration(cost / rate * float64(time.Second)))
+	return start
+}
+
+func (p *paceProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	rateStr, err := p.rate.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("rate interpolation error: %w", err)
+	}
+	rate, err := parsePaceRate(rateStr)
+	if err != nil {
+		return nil, err
+	}
+
+	cost := 1.0
+	if p.bytes {
+		mBytes, err := msg.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		cost = float64(len(mBytes))
+	}
+
+	wait := p.reserve(cost, rate).Sub(p.now())
+	if wait <= 0 {
+		return service.MessageBatch{msg}, nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *paceProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestPaceReserve(t *testing.T) {
+	conf, err := paceProcConfig().ParseYAML(`rate: "10"`, nil)
+	require.NoError(t, err)
+
+	proc, err := paceProcFromParsed(conf)
+	require.NoError(t, err)
+
+	now := time.Unix(1000, 0)
+	proc.now = func() time.Time { return now }
+
+	assert.Equal(t, now, proc.reserve(1, 10))
+	assert.Equal(t, now.Add(100*time.Millisecond), proc.reserve(1, 10))
+	assert.Equal(t, now.Add(200*time.Millisecond), proc.reserve(5, 10))
+	assert.Equal(t, now.Add(700*time.Millisecond), proc.reserve(1, 100))
+
+	// Idle time is not saved up.
+	now = now.Add(10 * time.Second)
+	assert.Equal(t, now, proc.reserve(1, 10))
+	assert.Equal(t, now.Add(100*time.Millisecond), proc.reserve(1, 10))
+}
+
+func TestPaceMessages(t *testing.T) {
+	conf, err := paceProcConfig().ParseYAML(`rate: ${! @rate }`, nil)
+	require.NoError(t, err)
+
+	proc, err := paceProcFromParsed(conf)
+	require.NoError(t, err)
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		msg := service.NewMessage([]byte("hello"))
+		msg.MetaSetMut("rate", "200")
+
+		res, err := proc.Process(context.Background(), msg)
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 45*time.Millisecond)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte("hello")))
+	require.Error(t, err)
+}
+
+func TestPaceBytes(t *testing.T) {
+	conf, err := paceProcConfig().ParseYAML(`
+rate: "1000"
+unit: bytes
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := paceProcFromParsed(conf)
+	require.NoError(t, err)
+
+	now := time.Unix(1000, 0)
+	proc.now = func() time.Time { return now }
+
+	_, err = proc.Process(context.Background(), service.NewMessage(make([]byte, 500)))
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(500*time.Millisecond), proc.next)
+}
+
+func TestPaceCancelled(t *testing.T) {
+	conf, err := paceProcConfig().ParseYAML(`rate: "0.1"`, nil)
+	require.NoError(t, err)
+
+	proc, err := paceProcFromParsed(conf)
+	require.NoError(t, err)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte("first")))
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer done()
+
+	_, err = proc.Process(ctx, service.NewMessage([]byte("second")))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestPaceBadRate(t *testing.T) {
+	pConf, err := paceProcConfig().ParseYAML(`rate: "0"`, nil)
+	require.NoError(t, err)
+
+	_, err = paceProcFromParsed(pConf)
+	require.Error(t, err)
+}
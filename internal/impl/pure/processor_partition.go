@@ -0,0 +1,238 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	partFieldKey        = "key"
+	partFieldPartitions = "partitions"
+	partFieldQueueSize  = "queue_size"
+	partFieldProcessors = "processors"
+)
+
+func partitionProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Composition").
+		Version("4.31.0").
+		Summary("Routes messages by a hash of a key to one of a number of partitions, each of which executes its own instance of a list of child processors serially, which preserves the order of messages of a key while processing different keys in parallel.").
+		Description(`
+Each partition is a worker with a queue and its own instances of the child `+"`processors`"+`, which are only ever executed by that worker. The messages of a batch are split by the XXH64 hash of their key modulo the number of partitions, and the messages of each partition are queued as a batch for its worker. Since a worker processes the batches of its queue one at a time, in the order they were queued, messages with the same key are processed in order even when the pipeline runs multiple threads, and child processors that keep state, such as a `+"`rate_limit`"+` or a `+"`cached`"+` processor, observe the messages of a key in order.
+
+When the queue of a partition is full, processing blocks until its worker catches up, which applies backpressure to the pipeline for keys that are slow to process without the need to buffer messages of other partitions.
+
+The results of the child processors are returned grouped by partition, in the order of the partitions, and therefore the order of messages of different keys within a batch is not preserved. Child processors are allowed to filter, split or group messages.
+
+Messages where the key cannot be interpolated are flagged as failed and skip the child processors, and can be handled using xref:configuration:error_handling.adoc[error handling methods].`).
+		Field(service.NewInterpolatedStringField(partFieldKey).
+			Description("The key to partition messages by.").
+			Example(`${! json("customer_id") }`).
+			Example(`${! @kafka_key }`)).
+		Field(service.NewIntField(partFieldPartitions).
+			Description("The number of partitions, which is the maximum number of batches processed in parallel.").
+			Default(8)).
+		Field(service.NewIntField(partFieldQueueSize).
+			Description("The number of batches that can be queued for each partition before processing blocks.").
+			Default(1).
+			Advanced()).
+		Field(service.NewProcessorListField(partFieldProcessors).
+			Description("A list of processors executed by each partition.")).
+		Example("Ordered enrichment per customer", "Enrich the events of each customer in the order they arrived, with up to sixteen customers enriched at the same time.", `
+pipeline:
+  threads: 4
+  processors:
+    - partition:
+        key: ${! json("customer_id") }
+        partitions: 16
+        processors:
+          - http:
+              url: http://localhost:8080/enrich
+              verb: POST
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"partition", partitionProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return partitionProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type partitionJobResult struct {
+	batches []service.MessageBatch
+	err     error
+}
+
+type partitionJob struct {
+	ctx    context.Context
+	batch  service.MessageBatch
+	result chan partitionJobResult
+}
+
+type partitionWorker struct {
+	processors []*service.OwnedProcessor
+	queue      chan partitionJob
+}
+
+type partitionProc struct {
+	key     *service.InterpolatedString
+	workers []*partitionWorker
+
+	wg      sync.WaitGroup
+	closing chan struct{}
+	closeMu sync.Once
+}
+
+func partitionProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*partitionProc, error) {
+	p := &partitionProc{
+		closing: make(chan struct{}),
+	}
+
+	var err error
+	if p.key, err = conf.FieldInterpolatedString(partFieldKey); err != nil {
+		return nil, err
+	}
+
+	partitions, err := conf.FieldInt(partFieldPartitions)
+	if err != nil {
+		return nil, err
+	}
+	if partitions < 1 {
+		return nil, fmt.Errorf("partitions must be greater than zero, got %v", partitions)
+	}
+
+	queueSize, err := conf.FieldInt(partFieldQueueSize)
+	if err != nil {
+		return nil, err
+	}
+	if queueSize < 0 {
+		return nil, fmt.Errorf("queue size must not be negative, got %v", queueSize)
+	}
+
+	// Each partition parses its own instances of the child processors so that
+	// processors which keep state are never shared between partitions.
+	for i := 0; i < partitions; i++ {
+		w := &partitionWorker{queue: make(chan partitionJob, queueSize)}
+		if w.processors, err = conf.FieldProcessorList(partFieldProcessors); err != nil {
+			return nil, err
+		}
+		p.workers = append(p.workers, w)
+	}
+
+	for _, w := range p.workers {
+		p.wg.Add(1)
+		go p.loop(w)
+	}
+	return p, nil
+}
+
+func (p *partitionProc) loop(w *partitionWorker) {
+	defer p.wg.Done()
+	for {
+		select {
+		case job := <-w.queue:
+			batches, err := service.ExecuteProcessors(job.ctx, w.processors, job.batch)
+			job.result <- partitionJobResult{batches: batches, err: err}
+		case <-p.closing:
+			return
+		}
+	}
+}
+
+func (p *partitionProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	parts := make([]service.MessageBatch, len(p.workers))
+
+	var failed service.MessageBatch
+	for i, msg := range batch {
+		key, err := batch.TryInterpolatedString(i, p.key)
+		if err != nil {
+			msg.SetError(fmt.Errorf("key interpolation error: %w", err))
+			failed = append(failed, msg)
+			continue
+		}
+		idx := xxhash.Sum64String(key) % uint64(len(p.workers))
+		parts[idx] = append(parts[idx], msg)
+	}
+
+	results := make([]chan partitionJobResult, len(p.workers))
+	for i, part := range parts {
+		if len(part) == 0 {
+			continue
+		}
+		results[i] = make(chan partitionJobResult, 1)
+		select {
+		case p.workers[i].queue <- partitionJob{ctx: ctx, batch: part, result: results[i]}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-p.closing:
+			return nil, errors.New("processor is closed")
+		}
+	}
+
+	var res []service.MessageBatch
+	for _, resChan := range results {
+		if resChan == nil {
+			continue
+		}
+		var r partitionJobResult
+		select {
+		case r = <-resChan:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-p.closing:
+			return nil, errors.New("processor is closed")
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+		res = append(res, r.batches...)
+	}
+	if len(failed) > 0 {
+		res = append(res, failed)
+	}
+	return res, nil
+}
+
+func (p *partitionProc) Close(ctx context.Context) error {
+	p.closeMu.Do(func() {
+		close(p.closing)
+	})
+	p.wg.Wait()
+
+	for _, w := range p.workers {
+		for _, proc := range w.processors {
+			if err := proc.Close(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"hash/fnv"
+
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	pkFieldKey         = "key"
+	pkFieldPartitions  = "partitions"
+	pkFieldAlgorithm   = "algorithm"
+	pkFieldMode        = "mode"
+	pkFieldMetadataKey = "metadata_key"
+)
+
+func partitionKeyProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Computes a partition index from a hash of an interpolated key and writes it to a metadata field.").
+		Description(`
+The partition index is a number between zero and `+"`partitions`"+` (exclusive) that is derived only from the key and the configuration of this processor, and therefore messages with the same key are assigned the same partition across all instances of a pipeline. The contents of the message are not modified, and the partition index is written to metadata as an integer.
+
+== Modes
+
+In `+"`modulo`"+` mode the partition is the hash of the key modulo the number of partitions. When the number of partitions changes almost all keys are assigned a different partition.
+
+In `+"`jump`"+` mode the partition is computed with the https://arxiv.org/abs/1406.2294[jump consistent hash^] algorithm, with the hash of the key as its input. When the number of partitions grows from N to M only approximately (M-N)/M of the keys are assigned a different partition, and those keys are moved to the new partitions.`).
+		Field(service.NewInterpolatedStringField(pkFieldKey).
+			Description("The key to compute the partition of.").
+			Example(`${! json("customer_id") }`)).
+		Field(service.NewIntField(pkFieldPartitions).
+			Description("The number of partitions.")).
+		Field(service.NewStringAnnotatedEnumField(pkFieldAlgorithm, map[string]string{
+			"xxhash64": "XXH64.",
+			"fnv1a_64": "64-bit FNV-1a.",
+			"fnv1a_32": "32-bit FNV-1a.",
+			"crc32":    "CRC-32 with the IEEE polynomial.",
+			"murmur2":  "32-bit Murmur2 as used by the default partitioner of Kafka clients, which in `modulo` mode results in the same partitions as a Kafka producer for the same key.",
+		}).
+			Description("The hash algorithm to apply to the key.").
+			Default("xxhash64")).
+		Field(service.NewStringAnnotatedEnumField(pkFieldMode, map[string]string{
+			"modulo": "The hash of the key modulo the number of partitions.",
+			"jump":   "A jump consistent hash of the key, which minimises the number of keys that move when the number of partitions changes.",
+		}).
+			Description("The method used to map the hash of a key onto a partition.").
+			Default("modulo")).
+		Field(service.NewStringField(pkFieldMetadataKey).
+			Description("The metadata key to store the partition index in.").
+			Default("partition")).
+		Example("Sharded output", "Route messages to one of eight files such that all messages of a customer are written to the same file.", `
+pipeline:
+  processors:
+    - partition_key:
+        key: ${! json("customer_id") }
+        partitions: 8
+        mode: jump
+
+output:
+  file:
+    path: ./shards/${! @partition }.jsonl
+    codec: lines
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"partition_key", partitionKeyProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return partitionKeyProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func partitionKeyHasher(algorithm string) (func([]byte) uint64, error) {
+	switch algorithm {
+	case "xxhash64":
+		return xxhash.Sum64, nil
+	case "fnv1a_64":
+		return func(b []byte) uint64 {
+			h := fnv.New64a()
+			_, _ = h.Write(b)
+			return h.Sum64()
+		}, nil
+	case "fnv1a_32":
+		return func(b []byte) uint64 {
+			h := fnv.New32a()
+			_, _ = h.Write(b)
+			return uint64(h.Sum32())
+		}, nil
+	case "crc32":
+		return func(b []byte) uint64 {
+			return uint64(crc32.ChecksumIEEE(b))
+		}, nil
+	case "murmur2":
+		return func(b []byte) uint64 {
+			// Kafka clients clear the sign bit of the hash rather than
+			// taking its absolute value.
+			return uint64(murmur2(b) & 0x7fffffff)
+		}, nil
+	}
+	return nil, fmt.Errorf("algorithm not recognised: %v", algorithm)
+}
+
+// murmur2 is the 32-bit Murmur2 hash with the seed used by Kafka clients.
+func murmur2(b []byte) uint32 {
+	const (
+		seed uint32 = 0x9747b28c
+		m    uint32 = 0x5bd1e995
+		r           = 24
+	)
+
+	h := seed ^ uint32(len(b))
+	for ; len(b) >= 4; b = b[4:] {
+		k := binary.LittleEndian.Uint32(b)
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+	switch len(b) {
+	case 3:
+		h ^= uint32(b[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(b[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(b[0])
+		h *= m
+	}
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return h
+}
+
+// jumpHash is the jump consistent hash algorithm of Lamping and Veach.
+func jumpHash(key uint64, buckets int) int {
+	var b, j int64 = -1, 0
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
+
+//------------------------------------------------------------------------------
+
+type partitionKeyProc struct {
+	key        *service.InterpolatedString
+	partitions int
+	hasher     func([]byte) uint64
+	jump       bool
+	metaKey    string
+}
+
+func partitionKeyProcFromParsed(conf *service.ParsedConfig) (*partitionKeyProc, error) {
+	p := &partitionKeyProc{}
+
+	var err error
+	if p.key, err = conf.FieldInterpolatedString(pkFieldKey); err != nil {
+		return nil, err
+	}
+	if p.partitions, err = conf.FieldInt(pkFieldPartitions); err != nil {
+		return nil, err
+	}
+	if p.partitions < 1 {
+		return nil, errors.New("partitions must be at least 1")
+	}
+
+	algorithm, err := conf.FieldString(pkFieldAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	if p.hasher, err = partitionKeyHasher(algorithm); err != nil {
+		return nil, err
+	}
+
+	mode, err := conf.FieldString(pkFieldMode)
+	if err != nil {
+		return nil, err
+	}
+	p.jump = mode == "jump"
+
+	if p.metaKey, err = conf.FieldString(pkFieldMetadataKey); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *partitionKeyProc) partition(key []byte) int {
+	h := p.hasher(key)
+	if p.jump {
+		return jumpHash(h, p.partitions)
+	}
+	return int(h % uint64(p.partitions))
+}
+
+func (p *partitionKeyProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	key, err := p.key.TryBytes(msg)
+	if err != nil {
+		return nil, fmt.Errorf("key interpolation error: %w", err)
+	}
+	msg.MetaSetMut(p.metaKey, p.partition(key))
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *partitionKeyProc) Close(ctx context.Context) error {
+	return nil
+}
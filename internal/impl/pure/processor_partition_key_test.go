@@ -0,0 +1,149 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestPartitionKeyMurmur2(t *testing.T) {
+	// Test vectors from the Kafka client library.
+	for input, exp := range map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8": -58897971,
+		"abc": 479470107,
+	} {
+		assert.Equal(t, exp, int32(murmur2([]byte(input))), input)
+	}
+}
+
+func TestPartitionKeyJumpHash(t *testing.T) {
+	for _, test := range []struct {
+		key     uint64
+		buckets int
+		exp     int
+	}{
+		{key: 1, buckets: 1, exp: 0},
+		{key: 42, buckets: 57, exp: 43},
+		{key: 0xDEAD10CC, buckets: 1, exp: 0},
+		{key: 0xDEAD10CC, buckets: 666, exp: 361},
+		{key: 256, buckets: 1024, exp: 520},
+	} {
+		assert.Equal(t, test.exp, jumpHash(test.key, test.buckets), "%v %v", test.key, test.buckets)
+	}
+}
+
+func TestPartitionKeyProcess(t *testing.T) {
+	conf, err := partitionKeyProcConfig().ParseYAML(`
+key: ${! json("id") }
+partitions: 12
+algorithm: murmur2
+metadata_key: kafka_partition
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := partitionKeyProcFromParsed(conf)
+	require.NoError(t, err)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"id":"foobar"}`)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	v, exists := res[0].MetaGetMut("kafka_partition")
+	require.True(t, exists)
+	assert.Equal(t, int((-790332482&0x7fffffff)%12), v)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`nope`)))
+	require.Error(t, err)
+}
+
+func TestPartitionKeyJumpMinimalMovement(t *testing.T) {
+	conf, err := partitionKeyProcConfig().ParseYAML(`
+key: ${! content() }
+partitions: 10
+mode: jump
+`, nil)
+	require.NoError(t, err)
+
+	before, err := partitionKeyProcFromParsed(conf)
+	require.NoError(t, err)
+
+	pConf, err := partitionKeyProcConfig().ParseYAML(`
+key: ${! content() }
+partitions: 11
+mode: jump
+`, nil)
+	require.NoError(t, err)
+
+	after, err := partitionKeyProcFromParsed(pConf)
+	require.NoError(t, err)
+
+	moved := 0
+	for i := 0; i < 1000; i++ {
+		key := []byte(fmt.Sprintf("key-%v", i))
+		a, b := before.partition(key), after.partition(key)
+		assert.Less(t, a, 10)
+		assert.Less(t, b, 11)
+		if a != b {
+			// Keys only ever move to the new partition.
+			assert.Equal(t, 10, b)
+			moved++
+		}
+	}
+	assert.Less(t, moved, 200)
+}
+
+func TestPartitionKeyAlgorithms(t *testing.T) {
+	for _, algo := range []string{"xxhash64", "fnv1a_64", "fnv1a_32", "crc32", "murmur2"} {
+		conf, err := partitionKeyProcConfig().ParseYAML(`
+key: ${! content() }
+partitions: 4
+algorithm: `+algo, nil)
+		require.NoError(t, err)
+
+		proc, err := partitionKeyProcFromParsed(conf)
+		require.NoError(t, err)
+
+		seen := map[int]struct{}{}
+		for i := 0; i < 100; i++ {
+			key := []byte(fmt.Sprintf("key-%v", i))
+			p := proc.partition(key)
+			assert.Equal(t, p, proc.partition(key), algo)
+			seen[p] = struct{}{}
+		}
+		assert.Len(t, seen, 4, algo)
+	}
+}
+
+func TestPartitionKeyBadPartitions(t *testing.T) {
+	pConf, err := partitionKeyProcConfig().ParseYAML(`
+key: foo
+partitions: 0
+`, nil)
+	require.NoError(t, err)
+
+	_, err = partitionKeyProcFromParsed(pConf)
+	require.Error(t, err)
+}
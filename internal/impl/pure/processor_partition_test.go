@@ -0,0 +1,236 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+// partitionKeys returns a key for each of n partitions.
+func partitionKeys(n int) []string {
+	keys := make([]string, n)
+	found := 0
+	for i := 0; found < n; i++ {
+		k := fmt.Sprintf("key%v", i)
+		if idx := xxhash.Sum64String(k) % uint64(n); keys[idx] == "" {
+			keys[idx] = k
+			found++
+		}
+	}
+	return keys
+}
+
+func TestPartitionOrdering(t *testing.T) {
+	conf, err := partitionProcConfig().ParseYAML(`
+key: ${! @key }
+partitions: 4
+processors:
+  - mapping: 'root = content().uppercase()'
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := partitionProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, proc.Close(context.Background())) })
+
+	var batch service.MessageBatch
+	for i := 0; i < 20; i++ {
+		msg := service.NewMessage([]byte(fmt.Sprintf("key%v-%v", i%5, i)))
+		msg.MetaSetMut("key", fmt.Sprintf("key%v", i%5))
+		batch = append(batch, msg)
+	}
+
+	res, err := proc.ProcessBatch(context.Background(), batch)
+	require.NoError(t, err)
+
+	perKey := map[string][]string{}
+	total := 0
+	for _, b := range res {
+		for _, msg := range b {
+			mBytes, err := msg.AsBytes()
+			require.NoError(t, err)
+			k, _ := msg.MetaGet("key")
+			perKey[k] = append(perKey[k], string(mBytes))
+			total++
+		}
+	}
+	assert.Equal(t, 20, total)
+
+	for i := 0; i < 5; i++ {
+		k := fmt.Sprintf("key%v", i)
+		var exp []string
+		for j := i; j < 20; j += 5 {
+			exp = append(exp, fmt.Sprintf("KEY%v-%v", i, j))
+		}
+		assert.Equal(t, exp, perKey[k], k)
+	}
+}
+
+func TestPartitionSerialPerKey(t *testing.T) {
+	conf, err := partitionProcConfig().ParseYAML(`
+key: ${! @key }
+partitions: 4
+processors:
+  - sleep:
+      duration: 50ms
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := partitionProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, proc.Close(context.Background())) })
+
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			msg := service.NewMessage([]byte("foo"))
+			msg.MetaSetMut("key", "same")
+			_, err := proc.ProcessBatch(context.Background(), service.MessageBatch{msg})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	// Batches of the same key are processed one at a time.
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+}
+
+func TestPartitionParallelAcrossKeys(t *testing.T) {
+	conf, err := partitionProcConfig().ParseYAML(`
+key: ${! @key }
+partitions: 4
+processors:
+  - sleep:
+      duration: 200ms
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := partitionProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, proc.Close(context.Background())) })
+
+	var batch service.MessageBatch
+	for _, k := range partitionKeys(4) {
+		msg := service.NewMessage([]byte("foo"))
+		msg.MetaSetMut("key", k)
+		batch = append(batch, msg)
+	}
+
+	start := time.Now()
+	res, err := proc.ProcessBatch(context.Background(), batch)
+	require.NoError(t, err)
+	assert.Len(t, res, 4)
+
+	// Partitions are processed at the same time.
+	assert.Less(t, time.Since(start), 700*time.Millisecond)
+}
+
+func TestPartitionFilterAndErrors(t *testing.T) {
+	conf, err := partitionProcConfig().ParseYAML(`
+key: ${! json("id") }
+partitions: 2
+processors:
+  - mapping: 'root = if this.drop { deleted() }'
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := partitionProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, proc.Close(context.Background())) })
+
+	res, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a","drop":true}`)),
+		service.NewMessage([]byte(`{"id":"b","drop":false}`)),
+		service.NewMessage([]byte(`not json`)),
+	})
+	require.NoError(t, err)
+
+	var contents []string
+	var errored int
+	for _, b := range res {
+		for _, msg := range b {
+			mBytes, err := msg.AsBytes()
+			require.NoError(t, err)
+			contents = append(contents, string(mBytes))
+			if msg.GetError() != nil {
+				errored++
+			}
+		}
+	}
+	assert.ElementsMatch(t, []string{`{"id":"b","drop":false}`, `not json`}, contents)
+	assert.Equal(t, 1, errored)
+}
+
+func TestPartitionBackpressure(t *testing.T) {
+	conf, err := partitionProcConfig().ParseYAML(`
+key: ${! @key }
+partitions: 1
+queue_size: 0
+processors:
+  - sleep:
+      duration: 500ms
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := partitionProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, proc.Close(context.Background())) })
+
+	go func() {
+		msg := service.NewMessage([]byte("foo"))
+		msg.MetaSetMut("key", "a")
+		_, _ = proc.ProcessBatch(context.Background(), service.MessageBatch{msg})
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, done := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer done()
+
+	msg := service.NewMessage([]byte("bar"))
+	msg.MetaSetMut("key", "a")
+	_, err = proc.ProcessBatch(ctx, service.MessageBatch{msg})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestPartitionConfigErrors(t *testing.T) {
+	for _, conf := range []string{
+		`key: foo
+partitions: 0
+processors: []`,
+		`key: foo
+queue_size: -1
+processors: []`,
+	} {
+		pConf, err := partitionProcConfig().ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		_, err = partitionProcFromParsed(pConf, service.MockResources())
+		assert.Error(t, err, conf)
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	pctFieldValue       = "value"
+	pctFieldPercentiles = "percentiles"
+	pctFieldOutput      = "output"
+	pctFieldMetaKey     = "metadata_key"
+
+	pctOutputSummary  = "summary"
+	pctOutputMetadata = "metadata"
+)
+
+func percentilesProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Computes percentiles of a numeric value across all messages of a batch.").
+		Description(`
+The Bloblang query `+"`value`"+` is executed for each message of a batch, and messages where the query fails or returns anything other than a number are skipped. The percentiles are computed exactly by sorting the values, interpolating linearly between the two closest values when a percentile falls between them, which is the same method used by most spreadsheet applications and the default method of NumPy.
+
+The result is an object containing the number of values counted, the minimum and maximum values, and a field for each percentile named after it, e.g. `+"`p50`"+` for the 50th percentile and `+"`p99.9`"+` for the 99.9th percentile:
+
+`+"```json"+`
+{"count":120,"min":3,"max":870,"p50":41.5,"p90":212.1,"p99":799.2}
+`+"```"+`
+
+When no values are counted the minimum, maximum and percentiles are `+"`null`"+`.
+
+This processor is usually preceded by a form of xref:configuration:windowed_processing.adoc[windowing] or xref:configuration:batching.adoc[batching] that determines the period that percentiles are computed over.`).
+		Field(service.NewBloblangField(pctFieldValue).
+			Description("A Bloblang query that returns the number to compute percentiles of for each message.").
+			Examples(`this.latency_ms`, `(this.finished_at.ts_unix_nano() - this.started_at.ts_unix_nano()) / 1000000`)).
+		Field(service.NewFloatListField(pctFieldPercentiles).
+			Description("The percentiles to compute, each between 0 and 100.").
+			Default([]any{50.0, 90.0, 99.0})).
+		Field(service.NewStringAnnotatedEnumField(pctFieldOutput, map[string]string{
+			pctOutputSummary:  "Replace the batch with a single message containing the result, with the metadata of the first message of the batch.",
+			pctOutputMetadata: "Keep all messages of the batch and add the result to each of them as a metadata field named by `metadata_key`.",
+		}).
+			Description("How to output the result.").
+			Default(pctOutputSummary)).
+		Field(service.NewStringField(pctFieldMetaKey).
+			Description("The metadata field to add the result to when `output` is `metadata`.").
+			Default("percentiles").
+			Advanced()).
+		Example("Latency report", "Report percentiles of request latencies every minute.", `
+pipeline:
+  processors:
+    - percentiles:
+        value: this.latency_ms
+        percentiles: [ 50, 90, 99, 99.9 ]
+
+output:
+  broker:
+    outputs:
+      - stdout: {}
+    batching:
+      period: 1m
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"percentiles", percentilesProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return percentilesProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type percentilesProc struct {
+	value       *bloblang.Executor
+	percentiles []float64
+	names       []string
+	metaKey     string
+	summary     bool
+
+	log *service.Logger
+}
+
+func percentilesProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*percentilesProc, error) {
+	p := &percentilesProc{
+		log: mgr.Logger(),
+	}
+
+	var err error
+	if p.value, err = conf.FieldBloblang(pctFieldValue); err != nil {
+		return nil, err
+	}
+	if p.percentiles, err = conf.FieldFloatList(pctFieldPercentiles); err != nil {
+		return nil, err
+	}
+	if len(p.percentiles) == 0 {
+		return nil, fmt.Errorf("field %v must contain at least one percentile", pctFieldPercentiles)
+	}
+	for _, pct := range p.percentiles {
+		if pct < 0 || pct > 100 || math.IsNaN(pct) {
+			return nil, fmt.Errorf("percentile %v is not between 0 and 100", pct)
+		}
+		p.names = append(p.names, "p"+strconv.FormatFloat(pct, 'f', -1, 64))
+	}
+
+	output, err := conf.FieldString(pctFieldOutput)
+	if err != nil {
+		return nil, err
+	}
+	p.summary = output == pctOutputSummary
+
+	if p.metaKey, err = conf.FieldString(pctFieldMetaKey); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// percentileOf returns the percentile pct of sorted values, interpolating
+// linearly between the closest ranks.
+func percentileOf(sorted []float64, pct float64) float64 {
+	rank := pct / 100 * float64(len(sorted)-1)
+	lo, hi := int(math.Floor(rank)), int(math.Ceil(rank))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
+
+func (p *percentilesProc) compute(batch service.MessageBatch) map[string]any {
+	values := make([]float64, 0, len(batch))
+	for i := range batch {
+		resMsg, err := batch.BloblangQuery(i, p.value)
+		if err != nil {
+			p.log.Debugf("Skipping message %v: %v", i, err)
+			continue
+		}
+		if resMsg == nil {
+			continue
+		}
+		v, err := resMsg.AsStructured()
+		if err != nil {
+			p.log.Debugf("Skipping message %v: %v", i, err)
+			continue
+		}
+		f, err := bloblang.ValueAsFloat64(v)
+		if err != nil || math.IsNaN(f) {
+			p.log.Debugf("Skipping message %v: value is not a number", i)
+			continue
+		}
+		values = append(values, f)
+	}
+	sort.Float64s(values)
+
+	res := map[string]any{
+		"count": int64(len(values)),
+	}
+	if len(values) == 0 {
+		res["min"], res["max"] = nil, nil
+		for _, name := range p.names {
+			res[name] = nil
+		}
+		return res
+	}
+
+	res["min"], res["max"] = values[0], values[len(values)-1]
+	for i, pct := range p.percentiles {
+		res[p.names[i]] = percentileOf(values, pct)
+	}
+	return res
+}
+
+func (p *percentilesProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	if len(batch) == 0 {
+		return nil, nil
+	}
+
+	res := p.compute(batch)
+	if p.summary {
+		msg := batch[0].Copy()
+		msg.SetStructuredMut(res)
+		return []service.MessageBatch{{msg}}, nil
+	}
+
+	for _, msg := range batch {
+		// Each message gets its own copy so that later mutations of one do not
+		// leak into the others.
+		resCopy := make(map[string]any, len(res))
+		for k, v := range res {
+			resCopy[k] = v
+		}
+		msg.MetaSetMut(p.metaKey, resCopy)
+	}
+	return []service.MessageBatch{batch}, nil
+}
+
+func (p *percentilesProc) Close(ctx context.Context) error {
+	return nil
+}
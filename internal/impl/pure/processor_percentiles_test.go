@@ -0,0 +1,141 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestPercentilesSummary(t *testing.T) {
+	conf, err := percentilesProcConfig().ParseYAML(`
+value: this.v
+percentiles: [ 0, 25, 50, 90, 99.9, 100 ]
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := percentilesProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	var batch service.MessageBatch
+	for _, v := range []string{`10`, `"nope"`, `1`, `null`, `4`, `2`, `3`} {
+		msg := service.NewMessage([]byte(fmt.Sprintf(`{"v":%v}`, v)))
+		msg.MetaSetMut("window", "foo")
+		batch = append(batch, msg)
+	}
+	batch = append(batch, service.NewMessage([]byte(`not json`)))
+
+	res, err := proc.ProcessBatch(context.Background(), batch)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 1)
+
+	v, err := res[0][0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"count": int64(5),
+		"min":   1.0,
+		"max":   10.0,
+		"p0":    1.0,
+		"p25":   2.0,
+		"p50":   3.0,
+		"p90":   7.6,
+		"p99.9": 9.976,
+		"p100":  10.0,
+	}, roundFloats(v))
+
+	w, _ := res[0][0].MetaGet("window")
+	assert.Equal(t, "foo", w)
+}
+
+func TestPercentilesNoValues(t *testing.T) {
+	conf, err := percentilesProcConfig().ParseYAML(`value: this.v`, nil)
+	require.NoError(t, err)
+
+	proc, err := percentilesProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	res, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{}`)),
+	})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, []string{`{"count":0,"max":null,"min":null,"p50":null,"p90":null,"p99":null}`}, batchContents(t, res[0]))
+}
+
+func TestPercentilesMetadata(t *testing.T) {
+	conf, err := percentilesProcConfig().ParseYAML(`
+value: this.v
+percentiles: [ 50 ]
+output: metadata
+metadata_key: stats
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := percentilesProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	res, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"v":1}`)),
+		service.NewMessage([]byte(`{"v":2}`)),
+	})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, []string{`{"v":1}`, `{"v":2}`}, batchContents(t, res[0]))
+
+	for _, msg := range res[0] {
+		v, exists := msg.MetaGetMut("stats")
+		require.True(t, exists)
+		assert.Equal(t, map[string]any{
+			"count": int64(2),
+			"min":   1.0,
+			"max":   2.0,
+			"p50":   1.5,
+		}, v)
+	}
+}
+
+func TestPercentilesBadConfig(t *testing.T) {
+	for _, conf := range []string{
+		"value: this.v\npercentiles: []",
+		"value: this.v\npercentiles: [ 101 ]",
+		"value: this.v\npercentiles: [ -1 ]",
+	} {
+		pConf, err := percentilesProcConfig().ParseYAML(conf, nil)
+		require.NoError(t, err, conf)
+
+		_, err = percentilesProcFromParsed(pConf, service.MockResources())
+		require.Error(t, err, conf)
+	}
+}
+
+func roundFloats(v any) any {
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return v
+	}
+	for k, f := range obj {
+		if n, ok := f.(float64); ok {
+			obj[k] = float64(int64(n*1e6+0.5)) / 1e6
+		}
+	}
+	return obj
+}
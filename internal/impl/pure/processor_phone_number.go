@@ -0,0 +1,187 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	pnFieldField         = "field"
+	pnFieldDefaultRegion = "default_region"
+	pnFieldOnInvalid     = "on_invalid"
+	pnFieldClassify      = "classify"
+)
+
+func phoneNumberProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Parsing").
+		Version("4.31.0").
+		Summary("Normalizes a phone number within each message to the E.164 format.").
+		Description(`
+Numbers are parsed from either international format, where the number begins with a `+"`+`"+` or the international dialling prefix of the default region, or from the national format of the default region. Spaces, hyphens, dots, slashes and parentheses are ignored, and national trunk prefixes are removed, such that with a default region of `+"`GB`"+` the number `+"`020 7946 0000`"+` is normalized to `+"`+442079460000`"+`.
+
+Numbers are validated against the numbering plans of the following regions, which are also able to classify numbers by type: AU, BR, CN, DE, ES, FR, GB, IE, IN, IT, JP, MX, NL and the regions of the North American Numbering Plan. Numbers of other regions are validated by their country calling code and length only, and are classified as `+"`unknown`"+`.
+
+== Metadata
+
+This processor adds the following metadata fields to each message:
+
+`+"```text"+`
+- phone_number_valid
+- phone_number_region
+- phone_number_type
+`+"```"+`
+
+Where `+"`phone_number_region`"+` is the ISO 3166-1 alpha-2 code of the region of a valid number, and `+"`phone_number_type`"+` is only added when `+"`classify`"+` is `+"`true`"+`, and is one of `+"`fixed_line`, `mobile`, `fixed_line_or_mobile`, `toll_free`, `premium_rate`, `shared_cost`, `voip`, `personal_number`, `pager`, `uan` or `unknown`"+`.`).
+		Field(service.NewStringField(pnFieldField).
+			Description("A xref:configuration:field_paths.adoc[dot separated path] to the phone number of each message, which is replaced with the normalized number.").
+			Example("contact.phone")).
+		Field(service.NewStringField(pnFieldDefaultRegion).
+			Description("The ISO 3166-1 alpha-2 code of the region of numbers that are written in national format. When empty only numbers in international format are valid.").
+			Default("").
+			Example("US").
+			Example("GB")).
+		Field(service.NewStringAnnotatedEnumField(pnFieldOnInvalid, map[string]string{
+			"error": "Flag the message as failed so that it can be handled using xref:configuration:error_handling.adoc[error handling methods].",
+			"flag":  "Leave the number unchanged and set the metadata field `phone_number_valid` to `false`.",
+		}).
+			Description("What to do with messages where the number is invalid.").
+			Default("error")).
+		Field(service.NewBoolField(pnFieldClassify).
+			Description("Whether to classify the type of each valid number, which is added as the metadata field `phone_number_type`.").
+			Default(false)).
+		Example("Normalize contacts", "Normalize the phone numbers of contacts, where numbers without a country calling code are from the US, and drop contacts with numbers that are invalid.", `
+pipeline:
+  processors:
+    - phone_number:
+        field: contact.phone
+        default_region: US
+        on_invalid: flag
+        classify: true
+    - mapping: |
+        root = if @phone_number_valid == "false" { deleted() }
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"phone_number", phoneNumberProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return phoneNumberProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type phoneNumberProc struct {
+	path          string
+	defaultRegion string
+	flagInvalid   bool
+	classify      bool
+}
+
+func phoneNumberProcFromParsed(conf *service.ParsedConfig) (*phoneNumberProc, error) {
+	p := &phoneNumberProc{}
+
+	var err error
+	if p.path, err = conf.FieldString(pnFieldField); err != nil {
+		return nil, err
+	}
+	if p.defaultRegion, err = conf.FieldString(pnFieldDefaultRegion); err != nil {
+		return nil, err
+	}
+	p.defaultRegion = strings.ToUpper(p.defaultRegion)
+	if _, exists := phoneRegionCodes[p.defaultRegion]; p.defaultRegion != "" && !exists {
+		return nil, fmt.Errorf("default region %v not recognised", p.defaultRegion)
+	}
+
+	onInvalid, err := conf.FieldString(pnFieldOnInvalid)
+	if err != nil {
+		return nil, err
+	}
+	switch onInvalid {
+	case "error":
+	case "flag":
+		p.flagInvalid = true
+	default:
+		return nil, fmt.Errorf("on_invalid action not recognised: %v", onInvalid)
+	}
+
+	if p.classify, err = conf.FieldBool(pnFieldClassify); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *phoneNumberProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	v, err := msg.AsStructuredMut()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message: %w", err)
+	}
+
+	gObj := gabs.Wrap(v)
+	if !gObj.ExistsP(p.path) {
+		return nil, fmt.Errorf("field %v not found", p.path)
+	}
+
+	var raw string
+	switch t := gObj.Path(p.path).Data().(type) {
+	case string:
+		raw = t
+	case json.Number:
+		raw = t.String()
+	case int, int64, uint64:
+		raw = fmt.Sprintf("%d", t)
+	case float64:
+		raw = fmt.Sprintf("%.0f", t)
+	default:
+		return nil, fmt.Errorf("field %v is not a string, got %T", p.path, t)
+	}
+
+	num, err := parsePhoneNumber(raw, p.defaultRegion)
+	if err != nil {
+		if p.flagInvalid {
+			msg.MetaSetMut("phone_number_valid", "false")
+			return service.MessageBatch{msg}, nil
+		}
+		return nil, err
+	}
+
+	if _, err := gObj.SetP(num.e164(), p.path); err != nil {
+		return nil, err
+	}
+	msg.SetStructuredMut(gObj.Data())
+
+	msg.MetaSetMut("phone_number_valid", "true")
+	msg.MetaSetMut("phone_number_region", num.region)
+	if p.classify {
+		msg.MetaSetMut("phone_number_type", num.kind)
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *phoneNumberProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,141 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestParsePhoneNumber(t *testing.T) {
+	for _, test := range []struct {
+		input  string
+		region string
+		e164   string
+		from   string
+		kind   string
+	}{
+		{input: "+1 (415) 555-2671", e164: "+14155552671", from: "US", kind: phoneTypeFixedLineOrMobile},
+		{input: "415.555.2671", region: "US", e164: "+14155552671", from: "US", kind: phoneTypeFixedLineOrMobile},
+		{input: "1-416-555-0123", region: "US", e164: "+14165550123", from: "CA", kind: phoneTypeFixedLineOrMobile},
+		{input: "011 44 7911 123456", region: "US", e164: "+447911123456", from: "GB", kind: phoneTypeMobile},
+		{input: "1-800-555-0199", region: "CA", e164: "+18005550199", from: "US", kind: phoneTypeTollFree},
+		{input: "020 7946 0000", region: "GB", e164: "+442079460000", from: "GB", kind: phoneTypeFixedLine},
+		{input: "+44 (0)20 7946 0000", e164: "+442079460000", from: "GB", kind: phoneTypeFixedLine},
+		{input: "0800 123 4567", region: "GB", e164: "+448001234567", from: "GB", kind: phoneTypeTollFree},
+		{input: "06 12 34 56 78", region: "FR", e164: "+33612345678", from: "FR", kind: phoneTypeMobile},
+		{input: "0049 30 1234567", region: "FR", e164: "+49301234567", from: "DE", kind: phoneTypeFixedLine},
+		{input: "06 1234 5678", region: "IT", e164: "+390612345678", from: "IT", kind: phoneTypeFixedLine},
+		{input: "0412 345 678", region: "AU", e164: "+61412345678", from: "AU", kind: phoneTypeMobile},
+		{input: "(11) 91234-5678", region: "BR", e164: "+5511912345678", from: "BR", kind: phoneTypeMobile},
+		{input: "8 800 555 3535", region: "RU", e164: "+78005553535", from: "RU", kind: phoneTypeUnknown},
+		{input: "082 123 4567", region: "ZA", e164: "+27821234567", from: "ZA", kind: phoneTypeUnknown},
+	} {
+		p, err := parsePhoneNumber(test.input, test.region)
+		require.NoError(t, err, test.input)
+		assert.Equal(t, test.e164, p.e164(), test.input)
+		assert.Equal(t, test.from, p.region, test.input)
+		assert.Equal(t, test.kind, p.kind, test.input)
+	}
+
+	for _, test := range []struct {
+		input  string
+		region string
+	}{
+		{input: "415 555 2671"},
+		{input: "415 555 267", region: "US"},
+		{input: "+1 415 555 26711"},
+		{input: "06 12 34 56 7", region: "FR"},
+		{input: "+44 20 7946 00000"},
+		{input: "call me maybe", region: "US"},
+		{input: "+999 1234 5678"},
+		{input: "+27 12", region: "ZA"},
+		{input: ""},
+	} {
+		_, err := parsePhoneNumber(test.input, test.region)
+		assert.ErrorIs(t, err, errPhoneInvalid, test.input)
+	}
+}
+
+func TestPhoneNumberProcessor(t *testing.T) {
+	conf, err := phoneNumberProcConfig().ParseYAML(`
+field: contact.phone
+default_region: gb
+classify: true
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := phoneNumberProcFromParsed(conf)
+	require.NoError(t, err)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"contact":{"phone":"07911 123456"}}`)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, []string{`{"contact":{"phone":"+447911123456"}}`}, batchContents(t, res))
+
+	for k, exp := range map[string]string{
+		"phone_number_valid":  "true",
+		"phone_number_region": "GB",
+		"phone_number_type":   "mobile",
+	} {
+		v, _ := res[0].MetaGet(k)
+		assert.Equal(t, exp, v, k)
+	}
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"contact":{"phone":"123"}}`)))
+	require.Error(t, err)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"contact":{}}`)))
+	require.Error(t, err)
+}
+
+func TestPhoneNumberProcessorFlag(t *testing.T) {
+	conf, err := phoneNumberProcConfig().ParseYAML(`
+field: phone
+on_invalid: flag
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := phoneNumberProcFromParsed(conf)
+	require.NoError(t, err)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"phone":"555 1234"}`)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, []string{`{"phone":"555 1234"}`}, batchContents(t, res))
+	require.NoError(t, res[0].GetError())
+
+	v, _ := res[0].MetaGet("phone_number_valid")
+	assert.Equal(t, "false", v)
+
+	_, exists := res[0].MetaGet("phone_number_type")
+	assert.False(t, exists)
+}
+
+func TestPhoneNumberProcessorBadRegion(t *testing.T) {
+	pConf, err := phoneNumberProcConfig().ParseYAML(`
+field: phone
+default_region: XY
+`, nil)
+	require.NoError(t, err)
+
+	_, err = phoneNumberProcFromParsed(pConf)
+	require.Error(t, err)
+}
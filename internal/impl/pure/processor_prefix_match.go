@@ -0,0 +1,400 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	pmFieldPath           = "path"
+	pmFieldCache          = "cache"
+	pmFieldCacheKey       = "cache_key"
+	pmFieldTarget         = "target"
+	pmFieldOutputPath     = "output_path"
+	pmFieldReloadInterval = "reload_interval"
+)
+
+func prefixMatchProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Mapping").
+		Version("4.31.0").
+		Summary("Finds the longest prefix of a value within a set of prefix mappings, and writes the value associated with the prefix to the message.").
+		Description(`
+The mappings are loaded into a trie, and therefore the time taken to match a value only depends on the length of the value rather than the number of mappings, which makes this processor suitable for classifying messages against large sets of prefixes such as URL paths or phone number prefixes.
+
+When a prefix matches, the value associated with it is written to the message at `+"`output_path`"+`, and the matched prefix is written to the metadata field `+"`prefix_match_prefix`"+`. When no prefix matches the message is left unchanged. An empty prefix matches every value, and can therefore be used as a default.
+
+== Mappings
+
+The mappings are read either from the file at `+"`path`"+`, or from the key `+"`cache_key`"+` of the xref:components:caches/about.adoc[`+"`cache` resource"+`] `+"`cache`"+`, and are in one of the following formats:
+
+- A JSON object where each key is a prefix, and each value is any JSON value associated with it.
+- CSV rows of two columns, without a header, where the first column is a prefix and the second column is a string value associated with it.
+
+== Reloading
+
+The mappings are read again every `+"`reload_interval`"+`, and when they have changed the trie is rebuilt, which allows the mappings to be modified while the pipeline is running. When modified mappings fail to load the error is logged and the previous mappings continue to be used.`).
+		Field(service.NewStringField(pmFieldPath).
+			Description("The path of a file containing the mappings. Either this field or `cache` must be set.").
+			Example("./routes.json").
+			Optional()).
+		Field(service.NewStringField(pmFieldCache).
+			Description("A xref:components:caches/about.adoc[`cache` resource] to read the mappings from. Either this field or `path` must be set.").
+			Optional()).
+		Field(service.NewStringField(pmFieldCacheKey).
+			Description("The key of the cache to read the mappings from.").
+			Default("prefix_mappings").
+			Advanced()).
+		Field(service.NewInterpolatedStringField(pmFieldTarget).
+			Description("The value to match against the prefixes.").
+			Example(`${! this.request.path }`).
+			Example(`${! this.phone_number.trim_prefix("+") }`)).
+		Field(service.NewStringField(pmFieldOutputPath).
+			Description("A xref:configuration:field_paths.adoc[dot separated path] to write the value associated with the matched prefix to.").
+			Example("route.service")).
+		Field(service.NewDurationField(pmFieldReloadInterval).
+			Description("The period of time between loads of the mappings. Set to `0s` in order to disable reloading.").
+			Default("30s").
+			Advanced()).
+		Example("Phone number prefixes", "Classify phone numbers by the longest matching country or carrier prefix from a CSV file with rows such as `44,GB` and `447,GB Mobile`.", `
+pipeline:
+  processors:
+    - prefix_match:
+        path: ./prefixes.csv
+        target: ${! this.number.trim_prefix("+") }
+        output_path: number_class
+`).
+		Example("URL routing", "Route requests to the service owning the longest matching path prefix, with routes maintained within a Redis key as a JSON object such as `{\"/api/\":\"api\",\"/api/users/\":\"users\"}`.", `
+pipeline:
+  processors:
+    - prefix_match:
+        cache: routes
+        cache_key: http_routes
+        target: ${! this.path }
+        output_path: service
+
+cache_resources:
+  - label: routes
+    redis:
+      url: redis://localhost:6379
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"prefix_match", prefixMatchProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return prefixMatchProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type prefixTrieNode struct {
+	children map[byte]*prefixTrieNode
+	set      bool
+	value    any
+}
+
+type prefixTrie struct {
+	root prefixTrieNode
+}
+
+func (t *prefixTrie) insert(prefix string, value any) {
+	node := &t.root
+	for i := 0; i < len(prefix); i++ {
+		if node.children == nil {
+			node.children = map[byte]*prefixTrieNode{}
+		}
+		next, exists := node.children[prefix[i]]
+		if !exists {
+			next = &prefixTrieNode{}
+			node.children[prefix[i]] = next
+		}
+		node = next
+	}
+	node.set, node.value = true, value
+}
+
+// longest returns the longest prefix of s within the trie and its value.
+func (t *prefixTrie) longest(s string) (prefix string, value any, ok bool) {
+	node := &t.root
+	if node.set {
+		value, ok = node.value, true
+	}
+	for i := 0; i < len(s); i++ {
+		if node = node.children[s[i]]; node == nil {
+			break
+		}
+		if node.set {
+			prefix, value, ok = s[:i+1], node.value, true
+		}
+	}
+	return
+}
+
+// copyMappingValue returns a deep copy of a value of the mappings, which are
+// shared by all messages and must not be modified by later processors.
+func copyMappingValue(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		res := make(map[string]any, len(t))
+		for k, cv := range t {
+			res[k] = copyMappingValue(cv)
+		}
+		return res
+	case []any:
+		res := make([]any, len(t))
+		for i, cv := range t {
+			res[i] = copyMappingValue(cv)
+		}
+		return res
+	}
+	return v
+}
+
+func parsePrefixMappings(b []byte) (*prefixTrie, error) {
+	t := &prefixTrie{}
+	if trimmed := bytes.TrimSpace(b); len(trimmed) > 0 && trimmed[0] == '{' {
+		var obj map[string]any
+		dec := json.NewDecoder(bytes.NewReader(trimmed))
+		dec.UseNumber()
+		if err := dec.Decode(&obj); err != nil {
+			return nil, fmt.Errorf("failed to parse mappings as JSON: %w", err)
+		}
+		for k, v := range obj {
+			t.insert(k, v)
+		}
+		return t, nil
+	}
+
+	r := csv.NewReader(bytes.NewReader(b))
+	r.FieldsPerRecord = 2
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse mappings as CSV: %w", err)
+		}
+		t.insert(record[0], record[1])
+	}
+	return t, nil
+}
+
+//------------------------------------------------------------------------------
+
+type prefixMatchProc struct {
+	path           string
+	cache          string
+	cacheKey       string
+	target         *service.InterpolatedString
+	outputPath     string
+	reloadInterval time.Duration
+
+	mgr *service.Resources
+	log *service.Logger
+
+	trieMut      sync.RWMutex
+	trie         *prefixTrie
+	mappingsHash uint64
+
+	reloadMut sync.Mutex
+	lastCheck time.Time
+}
+
+func prefixMatchProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*prefixMatchProc, error) {
+	p := &prefixMatchProc{mgr: mgr, log: mgr.Logger()}
+
+	var err error
+	if p.target, err = conf.FieldInterpolatedString(pmFieldTarget); err != nil {
+		return nil, err
+	}
+	if p.outputPath, err = conf.FieldString(pmFieldOutputPath); err != nil {
+		return nil, err
+	}
+	if p.reloadInterval, err = conf.FieldDuration(pmFieldReloadInterval); err != nil {
+		return nil, err
+	}
+	if p.cacheKey, err = conf.FieldString(pmFieldCacheKey); err != nil {
+		return nil, err
+	}
+
+	if conf.Contains(pmFieldPath) == conf.Contains(pmFieldCache) {
+		return nil, errors.New("exactly one of path or cache must be set")
+	}
+	if conf.Contains(pmFieldCache) {
+		if p.cache, err = conf.FieldString(pmFieldCache); err != nil {
+			return nil, err
+		}
+		if !mgr.HasCache(p.cache) {
+			return nil, fmt.Errorf("cache resource '%v' was not found", p.cache)
+		}
+		return p, nil
+	}
+
+	if p.path, err = conf.FieldString(pmFieldPath); err != nil {
+		return nil, err
+	}
+	if err := p.load(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to load prefix mappings: %w", err)
+	}
+	p.lastCheck = time.Now()
+	return p, nil
+}
+
+func (p *prefixMatchProc) readMappings(ctx context.Context) ([]byte, error) {
+	if p.path != "" {
+		return service.ReadFile(p.mgr.FS(), p.path)
+	}
+
+	var b []byte
+	var cacheErr error
+	if err := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		b, cacheErr = c.Get(ctx, p.cacheKey)
+	}); err != nil {
+		return nil, err
+	}
+	return b, cacheErr
+}
+
+// load reads the mappings and rebuilds the trie when they have changed.
+func (p *prefixMatchProc) load(ctx context.Context) error {
+	b, err := p.readMappings(ctx)
+	if err != nil {
+		return err
+	}
+
+	mappingsHash := xxhash.Sum64(b)
+	p.trieMut.RLock()
+	unchanged := p.trie != nil && mappingsHash == p.mappingsHash
+	p.trieMut.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	trie, err := parsePrefixMappings(b)
+	if err != nil {
+		return err
+	}
+
+	p.trieMut.Lock()
+	p.trie, p.mappingsHash = trie, mappingsHash
+	p.trieMut.Unlock()
+	return nil
+}
+
+// maybeReload loads the mappings when the reload interval has passed since the
+// last check. Only one caller performs the check at a time, and others
+// continue with the current trie.
+func (p *prefixMatchProc) maybeReload(ctx context.Context) {
+	if p.reloadInterval <= 0 || !p.reloadMut.TryLock() {
+		return
+	}
+	defer p.reloadMut.Unlock()
+
+	if time.Since(p.lastCheck) < p.reloadInterval {
+		return
+	}
+	p.lastCheck = time.Now()
+
+	if err := p.load(ctx); err != nil {
+		p.log.Errorf("Failed to reload prefix mappings, continuing with the previous mappings: %v", err)
+	}
+}
+
+func (p *prefixMatchProc) currentTrie(ctx context.Context) (*prefixTrie, error) {
+	p.trieMut.RLock()
+	trie := p.trie
+	p.trieMut.RUnlock()
+	if trie != nil {
+		p.maybeReload(ctx)
+		p.trieMut.RLock()
+		trie = p.trie
+		p.trieMut.RUnlock()
+		return trie, nil
+	}
+
+	// The mappings have not yet been loaded from the cache.
+	p.reloadMut.Lock()
+	defer p.reloadMut.Unlock()
+
+	p.trieMut.RLock()
+	trie = p.trie
+	p.trieMut.RUnlock()
+	if trie != nil {
+		return trie, nil
+	}
+	if err := p.load(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load prefix mappings: %w", err)
+	}
+	p.lastCheck = time.Now()
+
+	p.trieMut.RLock()
+	defer p.trieMut.RUnlock()
+	return p.trie, nil
+}
+
+func (p *prefixMatchProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	trie, err := p.currentTrie(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := p.target.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("target interpolation error: %w", err)
+	}
+
+	prefix, value, ok := trie.longest(target)
+	if !ok {
+		return service.MessageBatch{msg}, nil
+	}
+
+	root, err := msg.AsStructuredMut()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %w", err)
+	}
+	gObj := gabs.Wrap(root)
+	if _, err := gObj.SetP(copyMappingValue(value), p.outputPath); err != nil {
+		return nil, fmt.Errorf("failed to set output: %w", err)
+	}
+	msg.SetStructuredMut(gObj.Data())
+	msg.MetaSetMut("prefix_match_prefix", prefix)
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *prefixMatchProc) Close(ctx context.Context) error {
+	return nil
+}
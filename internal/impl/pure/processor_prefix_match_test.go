@@ -0,0 +1,217 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func prefixMatchResult(t testing.TB, proc *prefixMatchProc, doc string) (string, string) {
+	t.Helper()
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(doc)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	mBytes, err := res[0].AsBytes()
+	require.NoError(t, err)
+	prefix, _ := res[0].MetaGet("prefix_match_prefix")
+	return string(mBytes), prefix
+}
+
+func TestPrefixTrieLongest(t *testing.T) {
+	trie := &prefixTrie{}
+	trie.insert("44", "GB")
+	trie.insert("447", "GB Mobile")
+	trie.insert("1", "NANP")
+
+	for _, test := range []struct {
+		value, prefix string
+		result        any
+		ok            bool
+	}{
+		{value: "447700900123", prefix: "447", result: "GB Mobile", ok: true},
+		{value: "442079460000", prefix: "44", result: "GB", ok: true},
+		{value: "44", prefix: "44", result: "GB", ok: true},
+		{value: "4", ok: false},
+		{value: "33123", ok: false},
+		{value: "", ok: false},
+	} {
+		prefix, result, ok := trie.longest(test.value)
+		assert.Equal(t, test.ok, ok, test.value)
+		assert.Equal(t, test.prefix, prefix, test.value)
+		assert.Equal(t, test.result, result, test.value)
+	}
+
+	trie.insert("", "default")
+	prefix, result, ok := trie.longest("33123")
+	assert.True(t, ok)
+	assert.Equal(t, "", prefix)
+	assert.Equal(t, "default", result)
+}
+
+func TestPrefixMatchFileCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prefixes.csv")
+	require.NoError(t, os.WriteFile(path, []byte("44,GB\n447,GB Mobile\n\"1\",NANP\n"), 0o644))
+
+	conf, err := prefixMatchProcConfig().ParseYAML(`
+path: `+path+`
+target: ${! this.number }
+output_path: class.name
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := prefixMatchProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	doc, prefix := prefixMatchResult(t, proc, `{"number":"447700900123"}`)
+	assert.Equal(t, `{"class":{"name":"GB Mobile"},"number":"447700900123"}`, doc)
+	assert.Equal(t, "447", prefix)
+
+	doc, prefix = prefixMatchResult(t, proc, `{"number":"33123"}`)
+	assert.Equal(t, `{"number":"33123"}`, doc)
+	assert.Equal(t, "", prefix)
+}
+
+func TestPrefixMatchFileReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"/api/":{"service":"api"},"/api/users/":{"service":"users","weight":2}}`), 0o644))
+
+	conf, err := prefixMatchProcConfig().ParseYAML(`
+path: `+path+`
+target: ${! this.path }
+output_path: route
+reload_interval: 1ms
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := prefixMatchProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	doc, _ := prefixMatchResult(t, proc, `{"path":"/api/users/123"}`)
+	assert.Equal(t, `{"path":"/api/users/123","route":{"service":"users","weight":2}}`, doc)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"/api/":{"service":"api2"}}`), 0o644))
+	time.Sleep(5 * time.Millisecond)
+
+	doc, _ = prefixMatchResult(t, proc, `{"path":"/api/users/123"}`)
+	assert.Equal(t, `{"path":"/api/users/123","route":{"service":"api2"}}`, doc)
+
+	// Invalid mappings are logged and the previous mappings are kept.
+	require.NoError(t, os.WriteFile(path, []byte(`{"/api/":`), 0o644))
+	time.Sleep(5 * time.Millisecond)
+
+	doc, _ = prefixMatchResult(t, proc, `{"path":"/api/users/123"}`)
+	assert.Equal(t, `{"path":"/api/users/123","route":{"service":"api2"}}`, doc)
+}
+
+func TestPrefixMatchValuesNotShared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"/":{"tags":["a"]}}`), 0o644))
+
+	conf, err := prefixMatchProcConfig().ParseYAML(`
+path: `+path+`
+target: ${! this.path }
+output_path: route
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := prefixMatchProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"path":"/foo"}`)))
+	require.NoError(t, err)
+
+	v, err := res[0].AsStructuredMut()
+	require.NoError(t, err)
+	v.(map[string]any)["route"].(map[string]any)["tags"].([]any)[0] = "changed"
+
+	doc, _ := prefixMatchResult(t, proc, `{"path":"/bar"}`)
+	assert.Equal(t, `{"path":"/bar","route":{"tags":["a"]}}`, doc)
+}
+
+func TestPrefixMatchCache(t *testing.T) {
+	res := service.MockResources(service.MockResourcesOptAddCache("foocache"))
+	conf, err := prefixMatchProcConfig().ParseYAML(`
+cache: foocache
+cache_key: routes
+target: ${! this.path }
+output_path: service
+reload_interval: 1ms
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := prefixMatchProcFromParsed(conf, res)
+	require.NoError(t, err)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"path":"/api"}`)))
+	require.Error(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, res.AccessCache(ctx, "foocache", func(c service.Cache) {
+		require.NoError(t, c.Set(ctx, "routes", []byte(`{"":"default","/api":"api"}`), nil))
+	}))
+
+	doc, prefix := prefixMatchResult(t, proc, `{"path":"/api/foo"}`)
+	assert.Equal(t, `{"path":"/api/foo","service":"api"}`, doc)
+	assert.Equal(t, "/api", prefix)
+
+	doc, prefix = prefixMatchResult(t, proc, `{"path":"/other"}`)
+	assert.Equal(t, `{"path":"/other","service":"default"}`, doc)
+	assert.Equal(t, "", prefix)
+
+	require.NoError(t, res.AccessCache(ctx, "foocache", func(c service.Cache) {
+		require.NoError(t, c.Set(ctx, "routes", []byte("/api,api2\n"), nil))
+	}))
+	time.Sleep(5 * time.Millisecond)
+
+	doc, _ = prefixMatchResult(t, proc, `{"path":"/api/foo"}`)
+	assert.Equal(t, `{"path":"/api/foo","service":"api2"}`, doc)
+}
+
+func TestPrefixMatchConfigErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.csv")
+	require.NoError(t, os.WriteFile(path, []byte("a,b,c\n"), 0o644))
+
+	for _, conf := range []string{
+		`target: foo
+output_path: bar`,
+		`path: ` + path + `
+cache: foocache
+target: foo
+output_path: bar`,
+		`path: ` + path + `
+target: foo
+output_path: bar`,
+		`cache: nope
+target: foo
+output_path: bar`,
+	} {
+		pConf, err := prefixMatchProcConfig().ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		_, err = prefixMatchProcFromParsed(pConf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+		assert.Error(t, err, conf)
+	}
+}
@@ -0,0 +1,404 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/Jeffail/gabs/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	rlFieldBlockingKeys = "blocking_keys"
+	rlFieldComparisons  = "comparisons"
+	rlFieldField        = "field"
+	rlFieldFunction     = "function"
+	rlFieldThreshold    = "threshold"
+	rlFieldID           = "id"
+)
+
+func recordLinkageProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Groups similar records of a batch into clusters and writes a cluster ID to the metadata of each message.").
+		Description(`
+Two records match when every comparison results in a similarity that is greater than or equal to its threshold, and records are clustered transitively, such that when a record matches two others all three are part of the same cluster.
+
+== Blocking
+
+Comparing every pair of records of a batch becomes expensive as batches grow, and so records are only compared when they share the value of one or more `+"`blocking_keys`"+`, which are usually cheap and coarse such as the first letters of a surname or a postcode. Records where a blocking key resolves to an empty string are not blocked by that key. The cost of a batch therefore depends on the size of the largest block rather than the size of the batch.
+
+== Comparisons
+
+Each comparison identifies a field of the record and a function that computes a similarity between 0 and 1 of the values of two records:
+
+- `+"`exact`"+`: 1 when the values are equal, and 0 otherwise.
+- `+"`levenshtein`"+`: One minus the https://en.wikipedia.org/wiki/Levenshtein_distance[Levenshtein distance^] of the values divided by the length of the longest value.
+- `+"`jaro_winkler`"+`: The https://en.wikipedia.org/wiki/Jaro%E2%80%93Winkler_distance[Jaro-Winkler similarity^] of the values, which favours values with a common prefix.
+
+Numbers and other non-string values are converted into their JSON representation before they are compared, and a comparison of a field that is missing from either record never matches. Comparisons are case sensitive, and values can be normalised beforehand with a mapping.
+
+== Metadata
+
+This processor adds the following metadata fields to each message:
+
+`+"```text"+`
+- cluster_id
+- cluster_size
+`+"```"+`
+
+Where `+"`cluster_id`"+` is the ID of the first record of the cluster within the batch, and `+"`cluster_size`"+` is the number of records of the batch within the cluster. Records that do not match any other record form a cluster of their own. Messages that cannot be parsed as JSON are flagged as failed.`).
+		Field(service.NewInterpolatedStringListField(rlFieldBlockingKeys).
+			Description("A list of keys, where only records that share the value of at least one key are compared.").
+			Example([]string{`${! this.surname.slice(0, 2).lowercase() }`, `${! this.postcode }`})).
+		Field(service.NewObjectListField(rlFieldComparisons,
+			service.NewStringField(rlFieldField).
+				Description("A xref:configuration:field_paths.adoc[dot separated path] of the field to compare."),
+			service.NewStringEnumField(rlFieldFunction, "exact", "levenshtein", "jaro_winkler").
+				Description("The function used to compute the similarity of two values.").
+				Default("exact"),
+			service.NewFloatField(rlFieldThreshold).
+				Description("The minimum similarity for the values to match.").
+				Default(1.0),
+		).
+			Description("The comparisons that must all match for two records to be linked.")).
+		Field(service.NewInterpolatedStringField(rlFieldID).
+			Description("An optional ID of each record, which is used as the ID of a cluster. By default the index of the record within the batch is used.").
+			Example(`${! this.customer_id }`).
+			Optional()).
+		Example("Customer deduplication", "Link customer records with similar names and identical dates of birth, and keep only the first record of each cluster.", `
+pipeline:
+  processors:
+    - record_linkage:
+        id: ${! this.id }
+        blocking_keys:
+          - ${! this.date_of_birth }
+        comparisons:
+          - field: name
+            function: jaro_winkler
+            threshold: 0.9
+          - field: date_of_birth
+    - mapping: |
+        root = if @cluster_id != this.id.string() { deleted() }
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"record_linkage", recordLinkageProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return recordLinkageProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// levenshteinSimilarity returns one minus the edit distance of two strings
+// normalised by the length of the longest.
+func levenshteinSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return 1 - float64(prev[len(rb)])/float64(max(len(ra), len(rb)))
+}
+
+// jaroWinklerSimilarity returns the Jaro similarity of two strings boosted by
+// the length of their common prefix, up to four characters.
+func jaroWinklerSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+
+	window := max(len(ra), len(rb))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+
+	matchedA := make([]bool, len(ra))
+	matchedB := make([]bool, len(rb))
+	matches := 0
+	for i := range ra {
+		lo, hi := max(0, i-window), min(len(rb)-1, i+window)
+		for j := lo; j <= hi; j++ {
+			if !matchedB[j] && ra[i] == rb[j] {
+				matchedA[i], matchedB[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions, j := 0, 0
+	for i := range ra {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[j] {
+			j++
+		}
+		if ra[i] != rb[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(ra)) + m/float64(len(rb)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < min(4, len(ra), len(rb)) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+//------------------------------------------------------------------------------
+
+type linkageComparison struct {
+	field      string
+	similarity func(a, b string) float64
+	threshold  float64
+}
+
+type recordLinkageProc struct {
+	blockingKeys []*service.InterpolatedString
+	comparisons  []linkageComparison
+	id           *service.InterpolatedString
+}
+
+func recordLinkageProcFromParsed(conf *service.ParsedConfig) (*recordLinkageProc, error) {
+	p := &recordLinkageProc{}
+
+	var err error
+	if p.blockingKeys, err = conf.FieldInterpolatedStringList(rlFieldBlockingKeys); err != nil {
+		return nil, err
+	}
+	if len(p.blockingKeys) == 0 {
+		return nil, errors.New("at least one blocking key must be specified")
+	}
+
+	cConfs, err := conf.FieldObjectList(rlFieldComparisons)
+	if err != nil {
+		return nil, err
+	}
+	if len(cConfs) == 0 {
+		return nil, errors.New("at least one comparison must be specified")
+	}
+	for _, cConf := range cConfs {
+		var c linkageComparison
+		if c.field, err = cConf.FieldString(rlFieldField); err != nil {
+			return nil, err
+		}
+		fn, err := cConf.FieldString(rlFieldFunction)
+		if err != nil {
+			return nil, err
+		}
+		switch fn {
+		case "exact":
+			c.similarity = func(a, b string) float64 {
+				if a == b {
+					return 1
+				}
+				return 0
+			}
+		case "levenshtein":
+			c.similarity = levenshteinSimilarity
+		case "jaro_winkler":
+			c.similarity = jaroWinklerSimilarity
+		default:
+			return nil, fmt.Errorf("comparison function not recognised: %v", fn)
+		}
+		if c.threshold, err = cConf.FieldFloat(rlFieldThreshold); err != nil {
+			return nil, err
+		}
+		p.comparisons = append(p.comparisons, c)
+	}
+
+	if conf.Contains(rlFieldID) {
+		if p.id, err = conf.FieldInterpolatedString(rlFieldID); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// linkageValue returns the comparable form of a field of a record, and false
+// when the field is missing.
+func linkageValue(root any, path string) (string, bool) {
+	v := gabs.Wrap(root).Path(path).Data()
+	switch t := v.(type) {
+	case nil:
+		return "", false
+	case string:
+		return t, true
+	}
+	vBytes, err := json.Marshal(v)
+	if err != nil {
+		return "", false
+	}
+	return string(vBytes), true
+}
+
+type linkageRecord struct {
+	values  []string
+	present []bool
+}
+
+func (p *recordLinkageProc) matches(a, b *linkageRecord) bool {
+	for i, c := range p.comparisons {
+		if !a.present[i] || !b.present[i] {
+			return false
+		}
+		if c.similarity(a.values[i], b.values[i]) < c.threshold {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *recordLinkageProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	records := make([]*linkageRecord, len(batch))
+
+	// For each blocking key the indexes of records for each value.
+	blocks := make([]map[string][]int, len(p.blockingKeys))
+	for k := range blocks {
+		blocks[k] = map[string][]int{}
+	}
+
+	for i, msg := range batch {
+		root, err := msg.AsStructured()
+		if err != nil {
+			msg.SetError(fmt.Errorf("failed to parse message as JSON: %w", err))
+			continue
+		}
+
+		r := &linkageRecord{
+			values:  make([]string, len(p.comparisons)),
+			present: make([]bool, len(p.comparisons)),
+		}
+		for j, c := range p.comparisons {
+			r.values[j], r.present[j] = linkageValue(root, c.field)
+		}
+		records[i] = r
+
+		for k, key := range p.blockingKeys {
+			v, err := batch.TryInterpolatedString(i, key)
+			if err != nil {
+				return nil, fmt.Errorf("blocking key %v interpolation error: %w", k, err)
+			}
+			if v != "" {
+				blocks[k][v] = append(blocks[k][v], i)
+			}
+		}
+	}
+
+	// Union find of the records, where the root of each cluster is always the
+	// record with the lowest index.
+	parents := make([]int, len(batch))
+	for i := range parents {
+		parents[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parents[i] != i {
+			parents[i] = find(parents[i])
+		}
+		return parents[i]
+	}
+
+	for _, keyBlocks := range blocks {
+		for _, indexes := range keyBlocks {
+			for x := 0; x < len(indexes); x++ {
+				for y := x + 1; y < len(indexes); y++ {
+					a, b := find(indexes[x]), find(indexes[y])
+					if a == b || !p.matches(records[indexes[x]], records[indexes[y]]) {
+						continue
+					}
+					if a < b {
+						parents[b] = a
+					} else {
+						parents[a] = b
+					}
+				}
+			}
+		}
+	}
+
+	sizes := map[int]int{}
+	for i := range batch {
+		if records[i] != nil {
+			sizes[find(i)]++
+		}
+	}
+
+	for i, msg := range batch {
+		if records[i] == nil {
+			continue
+		}
+		root := find(i)
+
+		clusterID := strconv.Itoa(root)
+		if p.id != nil {
+			var err error
+			if clusterID, err = batch.TryInterpolatedString(root, p.id); err != nil {
+				msg.SetError(fmt.Errorf("id interpolation error: %w", err))
+				continue
+			}
+		}
+		msg.MetaSetMut("cluster_id", clusterID)
+		msg.MetaSetMut("cluster_size", sizes[root])
+	}
+	return []service.MessageBatch{batch}, nil
+}
+
+func (p *recordLinkageProc) Close(ctx context.Context) error {
+	return nil
+}
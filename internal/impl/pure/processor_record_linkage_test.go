@@ -0,0 +1,138 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestRecordLinkageSimilarity(t *testing.T) {
+	assert.InDelta(t, 1-3.0/7.0, levenshteinSimilarity("kitten", "sitting"), 0.0001)
+	assert.InDelta(t, 1.0, levenshteinSimilarity("", ""), 0.0001)
+	assert.InDelta(t, 0.0, levenshteinSimilarity("abc", ""), 0.0001)
+	assert.InDelta(t, 0.75, levenshteinSimilarity("añob", "año"), 0.0001)
+
+	assert.InDelta(t, 0.9611, jaroWinklerSimilarity("MARTHA", "MARHTA"), 0.0001)
+	assert.InDelta(t, 0.84, jaroWinklerSimilarity("DWAYNE", "DUANE"), 0.0001)
+	assert.InDelta(t, 0.8133, jaroWinklerSimilarity("DIXON", "DICKSONX"), 0.0001)
+	assert.InDelta(t, 0.0, jaroWinklerSimilarity("abc", "xyz"), 0.0001)
+	assert.InDelta(t, 1.0, jaroWinklerSimilarity("abc", "abc"), 0.0001)
+}
+
+func recordLinkageClusters(t testing.TB, batch service.MessageBatch) (ids []string, sizes []int) {
+	t.Helper()
+
+	for _, m := range batch {
+		require.NoError(t, m.GetError())
+		id, exists := m.MetaGet("cluster_id")
+		require.True(t, exists)
+		ids = append(ids, id)
+
+		size, exists := m.MetaGetMut("cluster_size")
+		require.True(t, exists)
+		sizes = append(sizes, size.(int))
+	}
+	return
+}
+
+func TestRecordLinkageClusters(t *testing.T) {
+	pConf, err := recordLinkageProcConfig().ParseYAML(`
+blocking_keys:
+  - ${! this.dob }
+  - ${! this.email.or("") }
+comparisons:
+  - field: name
+    function: jaro_winkler
+    threshold: 0.9
+  - field: dob
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := recordLinkageProcFromParsed(pConf)
+	require.NoError(t, err)
+
+	res, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"name":"Martha Jones","dob":"1980-01-01"}`)),
+		service.NewMessage([]byte(`{"name":"Bob Smith","dob":"1980-01-01"}`)),
+		service.NewMessage([]byte(`{"name":"Marhta Jones","dob":"1980-01-01","email":"m@example.com"}`)),
+		service.NewMessage([]byte(`{"name":"Martha Jones","dob":"1990-01-01"}`)),
+		service.NewMessage([]byte(`{"name":"Martha Jonez","dob":"1980-01-01","email":"m@example.com"}`)),
+		service.NewMessage([]byte(`{"name":"Bob Smith"}`)),
+	})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	ids, sizes := recordLinkageClusters(t, res[0])
+	assert.Equal(t, []string{"0", "1", "0", "3", "0", "5"}, ids)
+	assert.Equal(t, []int{3, 1, 3, 1, 3, 1}, sizes)
+}
+
+func TestRecordLinkageTransitiveAndID(t *testing.T) {
+	pConf, err := recordLinkageProcConfig().ParseYAML(`
+id: ${! this.id }
+blocking_keys: [ all ]
+comparisons:
+  - field: code
+    function: levenshtein
+    threshold: 0.75
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := recordLinkageProcFromParsed(pConf)
+	require.NoError(t, err)
+
+	// The first and last records only match via the second.
+	res, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a","code":"ABCD"}`)),
+		service.NewMessage([]byte(`{"id":"b","code":"XYZW"}`)),
+		service.NewMessage([]byte(`{"id":"c","code":"ABCE"}`)),
+		service.NewMessage([]byte(`{"id":"d","code":"ABFE"}`)),
+	})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	ids, sizes := recordLinkageClusters(t, res[0])
+	assert.Equal(t, []string{"a", "b", "a", "a"}, ids)
+	assert.Equal(t, []int{3, 1, 3, 3}, sizes)
+}
+
+func TestRecordLinkageBadJSON(t *testing.T) {
+	pConf, err := recordLinkageProcConfig().ParseYAML(`
+blocking_keys: [ all ]
+comparisons: [ { field: name } ]
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := recordLinkageProcFromParsed(pConf)
+	require.NoError(t, err)
+
+	res, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`nope`)),
+		service.NewMessage([]byte(`{"name":"foo"}`)),
+	})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Error(t, res[0][0].GetError())
+
+	id, exists := res[0][1].MetaGet("cluster_id")
+	require.True(t, exists)
+	assert.Equal(t, "1", id)
+}
@@ -0,0 +1,284 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	rcFieldReferences  = "references"
+	rcFieldPath        = "path"
+	rcFieldCache       = "cache"
+	rcFieldOptional    = "optional"
+	rcFieldParallelism = "parallelism"
+)
+
+func referenceCheckProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Checks that the IDs referenced by each message of a batch exist as keys within cache resources, and flags messages with dangling references as failed.").
+		Description(`
+Each reference identifies a field within messages and the xref:components:caches/about.adoc[`+"`cache`"+` resource] that its value is expected to exist within as a key. When the field contains an array each element is checked. Numbers and other non-string values are converted into their JSON representation in order to form a key.
+
+The keys of an entire batch are resolved up front, duplicate keys are only requested once for each cache, and the requests are performed concurrently up to the limit set by `+"`parallelism`"+`.
+
+Messages with one or more references that do not exist are flagged as failed and can be handled using xref:configuration:error_handling.adoc[error handling methods]. The metadata field `+"`missing_references`"+` is set on these messages to an array of objects, each containing the `+"`path`"+`, `+"`cache`"+` and `+"`key`"+` of a missing reference. Messages where a cache lookup fails for any other reason are also flagged as failed.`).
+		Field(service.NewObjectListField(rcFieldReferences,
+			service.NewStringField(rcFieldPath).
+				Description("A xref:configuration:field_paths.adoc[dot separated path] of the field containing the referenced ID."),
+			service.NewStringField(rcFieldCache).
+				Description("The cache resource that the referenced ID must exist within."),
+			service.NewBoolField(rcFieldOptional).
+				Description("Whether messages where the field is missing or null are allowed, otherwise they are considered dangling references.").
+				Default(false),
+		).
+			Description("The references to check for each message.")).
+		Field(service.NewIntField(rcFieldParallelism).
+			Description("The maximum number of keys to obtain from each cache concurrently.").
+			Default(10).
+			Advanced()).
+		Example("Orphaned orders", "Check that the customer and products of an order exist before the order is emitted, and route orders with dangling references to a dead letter queue.", `
+pipeline:
+  processors:
+    - reference_check:
+        references:
+          - path: customer_id
+            cache: customers
+          - path: items.product_ids
+            cache: products
+          - path: coupon_id
+            cache: coupons
+            optional: true
+
+output:
+  switch:
+    cases:
+      - check: errored()
+        output:
+          file:
+            path: ./orphans.jsonl
+      - output:
+          stdout: {}
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"reference_check", referenceCheckProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return referenceCheckProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type referenceRule struct {
+	path     string
+	cache    string
+	optional bool
+}
+
+type referenceCheckProc struct {
+	rules       []referenceRule
+	parallelism int
+
+	accessCache func(ctx context.Context, name string, fn func(c service.Cache)) error
+}
+
+func referenceCheckProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*referenceCheckProc, error) {
+	p := &referenceCheckProc{
+		accessCache: mgr.AccessCache,
+	}
+
+	refConfs, err := conf.FieldObjectList(rcFieldReferences)
+	if err != nil {
+		return nil, err
+	}
+	if len(refConfs) == 0 {
+		return nil, errors.New("at least one reference must be specified")
+	}
+	for _, rConf := range refConfs {
+		var r referenceRule
+		if r.path, err = rConf.FieldString(rcFieldPath); err != nil {
+			return nil, err
+		}
+		if r.cache, err = rConf.FieldString(rcFieldCache); err != nil {
+			return nil, err
+		}
+		if !mgr.HasCache(r.cache) {
+			return nil, fmt.Errorf("cache resource '%v' was not found", r.cache)
+		}
+		if r.optional, err = rConf.FieldBool(rcFieldOptional); err != nil {
+			return nil, err
+		}
+		p.rules = append(p.rules, r)
+	}
+
+	if p.parallelism, err = conf.FieldInt(rcFieldParallelism); err != nil {
+		return nil, err
+	}
+	if p.parallelism < 1 {
+		return nil, errors.New("parallelism must be at least 1")
+	}
+	return p, nil
+}
+
+// referenceKeys returns the keys referenced by a rule within a document, and
+// whether the reference is missing entirely.
+func referenceKeys(root any, path string) (keys []string, missing bool, err error) {
+	v := gabs.Wrap(root).Path(path).Data()
+	values, isArray := v.([]any)
+	if !isArray {
+		if v == nil {
+			return nil, true, nil
+		}
+		values = []any{v}
+	}
+	for _, e := range values {
+		switch t := e.(type) {
+		case nil:
+			return nil, true, nil
+		case string:
+			keys = append(keys, t)
+		default:
+			kBytes, err := json.Marshal(t)
+			if err != nil {
+				return nil, false, err
+			}
+			keys = append(keys, string(kBytes))
+		}
+	}
+	return keys, false, nil
+}
+
+type missingReference struct {
+	rule referenceRule
+	key  string
+}
+
+func (p *referenceCheckProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	// For each message a list of keys for each rule.
+	msgKeys := make([][][]string, len(batch))
+	msgMissing := make([][]missingReference, len(batch))
+	msgErrs := make([]error, len(batch))
+
+	uniqueKeys := map[string][]string{}
+	seen := map[string]map[string]struct{}{}
+	for i, msg := range batch {
+		root, err := msg.AsStructured()
+		if err != nil {
+			msgErrs[i] = fmt.Errorf("failed to parse message as JSON: %w", err)
+			continue
+		}
+
+		msgKeys[i] = make([][]string, len(p.rules))
+		for j, r := range p.rules {
+			keys, missing, err := referenceKeys(root, r.path)
+			if err != nil {
+				msgErrs[i] = fmt.Errorf("failed to resolve reference '%v': %w", r.path, err)
+				break
+			}
+			if missing {
+				if !r.optional {
+					msgMissing[i] = append(msgMissing[i], missingReference{rule: r})
+				}
+				continue
+			}
+
+			msgKeys[i][j] = keys
+			if seen[r.cache] == nil {
+				seen[r.cache] = map[string]struct{}{}
+			}
+			for _, k := range keys {
+				if _, exists := seen[r.cache][k]; !exists {
+					seen[r.cache][k] = struct{}{}
+					uniqueKeys[r.cache] = append(uniqueKeys[r.cache], k)
+				}
+			}
+		}
+	}
+
+	results := make(map[string]map[string]cacheGetResult, len(uniqueKeys))
+	for cache, keys := range uniqueKeys {
+		res, err := cacheGetAll(ctx, func(ctx context.Context, fn func(c service.Cache)) error {
+			return p.accessCache(ctx, cache, fn)
+		}, p.parallelism, keys)
+		if err != nil {
+			return nil, err
+		}
+		results[cache] = res
+	}
+
+	for i, msg := range batch {
+		if msgErrs[i] != nil {
+			msg.SetError(msgErrs[i])
+			continue
+		}
+
+		var cacheErr error
+		missing := msgMissing[i]
+		for j, r := range p.rules {
+			for _, k := range msgKeys[i][j] {
+				res := results[r.cache][k]
+				if errors.Is(res.err, service.ErrKeyNotFound) {
+					missing = append(missing, missingReference{rule: r, key: k})
+				} else if res.err != nil && cacheErr == nil {
+					cacheErr = res.err
+				}
+			}
+		}
+		if cacheErr != nil {
+			msg.SetError(cacheErr)
+			continue
+		}
+		if len(missing) == 0 {
+			continue
+		}
+
+		missingMeta := make([]any, 0, len(missing))
+		descriptions := make([]string, 0, len(missing))
+		for _, m := range missing {
+			missingMeta = append(missingMeta, map[string]any{
+				"path":  m.rule.path,
+				"cache": m.rule.cache,
+				"key":   m.key,
+			})
+			if m.key == "" {
+				descriptions = append(descriptions, fmt.Sprintf("%v (missing)", m.rule.path))
+			} else {
+				descriptions = append(descriptions, fmt.Sprintf("%v (%v not found in %v)", m.rule.path, m.key, m.rule.cache))
+			}
+		}
+		msg.MetaSetMut("missing_references", missingMeta)
+		msg.SetError(fmt.Errorf("dangling references: %v", strings.Join(descriptions, ", ")))
+	}
+	return []service.MessageBatch{batch}, nil
+}
+
+func (p *referenceCheckProc) Close(ctx context.Context) error {
+	return nil
+}
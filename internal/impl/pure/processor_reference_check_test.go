@@ -0,0 +1,107 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestReferenceCheck(t *testing.T) {
+	conf, err := referenceCheckProcConfig().ParseYAML(`
+references:
+  - path: customer_id
+    cache: customers
+  - path: product_ids
+    cache: products
+    optional: true
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := referenceCheckProcFromParsed(conf, service.MockResources(
+		service.MockResourcesOptAddCache("customers"),
+		service.MockResourcesOptAddCache("products"),
+	))
+	require.NoError(t, err)
+
+	caches := map[string]*countingCache{
+		"customers": {
+			values: map[string][]byte{"c1": nil, "c2": nil, "broken": nil},
+			gets:   map[string]int{},
+		},
+		"products": {
+			values: map[string][]byte{"1": nil, "2": nil},
+			gets:   map[string]int{},
+		},
+	}
+	proc.accessCache = func(ctx context.Context, name string, fn func(c service.Cache)) error {
+		fn(caches[name])
+		return nil
+	}
+
+	res, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"customer_id":"c1","product_ids":[1,2]}`)),
+		service.NewMessage([]byte(`{"customer_id":"c2"}`)),
+		service.NewMessage([]byte(`{"customer_id":"c3","product_ids":[1,3]}`)),
+		service.NewMessage([]byte(`{"product_ids":[2]}`)),
+		service.NewMessage([]byte(`{"customer_id":"broken"}`)),
+		service.NewMessage([]byte(`not json`)),
+	})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 6)
+
+	assert.NoError(t, res[0][0].GetError())
+	assert.NoError(t, res[0][1].GetError())
+
+	assert.EqualError(t, res[0][2].GetError(), "dangling references: customer_id (c3 not found in customers), product_ids (3 not found in products)")
+	v, exists := res[0][2].MetaGetMut("missing_references")
+	require.True(t, exists)
+	assert.Equal(t, []any{
+		map[string]any{"path": "customer_id", "cache": "customers", "key": "c3"},
+		map[string]any{"path": "product_ids", "cache": "products", "key": "3"},
+	}, v)
+
+	assert.EqualError(t, res[0][3].GetError(), "dangling references: customer_id (missing)")
+	v, exists = res[0][3].MetaGetMut("missing_references")
+	require.True(t, exists)
+	assert.Equal(t, []any{
+		map[string]any{"path": "customer_id", "cache": "customers", "key": ""},
+	}, v)
+
+	assert.EqualError(t, res[0][4].GetError(), "cache is broken")
+	assert.Error(t, res[0][5].GetError())
+
+	// Each unique key is only obtained once per cache.
+	assert.Equal(t, map[string]int{"c1": 1, "c2": 1, "c3": 1, "broken": 1}, caches["customers"].gets)
+	assert.Equal(t, map[string]int{"1": 1, "2": 1, "3": 1}, caches["products"].gets)
+}
+
+func TestReferenceCheckMissingResource(t *testing.T) {
+	pConf, err := referenceCheckProcConfig().ParseYAML(`
+references:
+  - path: customer_id
+    cache: nope
+`, nil)
+	require.NoError(t, err)
+
+	_, err = referenceCheckProcFromParsed(pConf, service.MockResources())
+	require.Error(t, err)
+}
@@ -0,0 +1,283 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	rndFieldPaths        = "paths"
+	rndFieldScale        = "scale"
+	rndFieldRoundingMode = "rounding_mode"
+	rndFieldFormat       = "format"
+)
+
+func roundProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Mapping").
+		Version("4.31.0").
+		Summary("Rounds numbers within a JSON document to a fixed number of decimal places.").
+		Description(`
+Each number targeted by `+"`paths`"+` is rounded to `+"`scale`"+` decimal places using decimal arithmetic, which means the result is never affected by the binary representation of floating point numbers: `+"`2.675`"+` rounded to two decimal places with `+"`half_up`"+` is always `+"`2.68`"+`. The rounded number is written back with exactly `+"`scale`"+` decimal places, either as a JSON number or, when `+"`format`"+` is `+"`string`"+`, as a string, which preserves trailing zeros for consumers that parse JSON numbers as floating point.
+
+Messages where a targeted value exists but isn't a number are flagged as failed and can be handled using xref:configuration:error_handling.adoc[error handling methods].
+
+== Paths
+
+Each path is a dot separated list of object keys or array indexes, such as `+"`total`"+` or `+"`items.0.price`"+`. A segment of `+"`*`"+` matches all keys of an object or all elements of an array, and therefore `+"`items.*.price`"+` targets the field `+"`price`"+` of every element of the array `+"`items`"+`. Paths that do not exist within a document are ignored.`).
+		Field(service.NewStringListField(rndFieldPaths).
+			Description("A list of paths to numbers that should be rounded.").
+			Example([]string{"total", "items.*.price"})).
+		Field(service.NewIntField(rndFieldScale).
+			Description("The number of decimal places to round to.").
+			Default(2)).
+		Field(service.NewStringAnnotatedEnumField(rndFieldRoundingMode, map[string]string{
+			"half_even": "Round to the nearest neighbour, and to the even neighbour when equidistant. Also known as banker's rounding.",
+			"half_up":   "Round to the nearest neighbour, and away from zero when equidistant.",
+			"floor":     "Round towards negative infinity.",
+			"ceil":      "Round towards positive infinity.",
+		}).
+			Description("The rounding mode to apply.").
+			Default("half_even")).
+		Field(service.NewStringAnnotatedEnumField(rndFieldFormat, map[string]string{
+			"number": "Write the rounded value as a JSON number.",
+			"string": "Write the rounded value as a string.",
+		}).
+			Description("The format of the rounded values.").
+			Default("number")).
+		Example("Monetary amounts", "Round the total and the price of each item of an order to cents, as strings in order to preserve trailing zeros.", `
+pipeline:
+  processors:
+    - round:
+        paths: [ total, items.*.price ]
+        scale: 2
+        rounding_mode: half_up
+        format: string
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"round", roundProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return roundProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type roundProc struct {
+	paths    *pathNode
+	scale    int
+	mode     string
+	asString bool
+	exp      *big.Int
+}
+
+func roundProcFromParsed(conf *service.ParsedConfig) (*roundProc, error) {
+	paths, err := conf.FieldStringList(rndFieldPaths)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("at least one path must be specified")
+	}
+
+	p := &roundProc{}
+	if p.paths, err = parsePaths(paths); err != nil {
+		return nil, err
+	}
+	if p.scale, err = conf.FieldInt(rndFieldScale); err != nil {
+		return nil, err
+	}
+	if p.scale < 0 {
+		return nil, fmt.Errorf("scale must not be negative, got %v", p.scale)
+	}
+	if p.mode, err = conf.FieldString(rndFieldRoundingMode); err != nil {
+		return nil, err
+	}
+
+	var format string
+	if format, err = conf.FieldString(rndFieldFormat); err != nil {
+		return nil, err
+	}
+	p.asString = format == "string"
+
+	p.exp = new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(p.scale)), nil)
+	return p, nil
+}
+
+// decimalRat parses a numeric value as an exact rational number. Floating
+// point values are parsed from their shortest decimal representation, which
+// is the number that was originally written.
+func decimalRat(v any) (*big.Rat, bool) {
+	var s string
+	switch t := v.(type) {
+	case json.Number:
+		s = t.String()
+	case float64:
+		s = strconv.FormatFloat(t, 'g', -1, 64)
+	case float32:
+		s = strconv.FormatFloat(float64(t), 'g', -1, 32)
+	case int:
+		s = strconv.Itoa(t)
+	case int64:
+		s = strconv.FormatInt(t, 10)
+	case int32:
+		s = strconv.FormatInt(int64(t), 10)
+	case uint64:
+		s = strconv.FormatUint(t, 10)
+	case uint32:
+		s = strconv.FormatUint(uint64(t), 10)
+	default:
+		return nil, false
+	}
+	return new(big.Rat).SetString(s)
+}
+
+// round returns the decimal representation of r rounded to the configured
+// scale.
+func (p *roundProc) round(r *big.Rat) string {
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(p.exp))
+	num, den := scaled.Num(), scaled.Denom()
+
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Sign() != 0 {
+		sign := int64(num.Sign())
+		switch p.mode {
+		case "floor":
+			if sign < 0 {
+				q.Sub(q, big.NewInt(1))
+			}
+		case "ceil":
+			if sign > 0 {
+				q.Add(q, big.NewInt(1))
+			}
+		case "half_up", "half_even":
+			twiceRem := new(big.Int).Abs(rem)
+			twiceRem.Lsh(twiceRem, 1)
+			c := twiceRem.Cmp(den)
+			if c > 0 || (c == 0 && (p.mode == "half_up" || q.Bit(0) == 1)) {
+				q.Add(q, big.NewInt(sign))
+			}
+		}
+	}
+
+	digits := new(big.Int).Abs(q).String()
+	if p.scale > 0 {
+		if len(digits) <= p.scale {
+			digits = strings.Repeat("0", p.scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-p.scale] + "." + digits[len(digits)-p.scale:]
+	}
+	if q.Sign() < 0 {
+		digits = "-" + digits
+	}
+	return digits
+}
+
+func (p *roundProc) roundValue(v any, path []string) (any, error) {
+	r, ok := decimalRat(v)
+	if !ok {
+		return nil, fmt.Errorf("value at path %v is not a number: %T", strings.Join(path, "."), v)
+	}
+	rounded := p.round(r)
+	if p.asString {
+		return rounded, nil
+	}
+	return json.Number(rounded), nil
+}
+
+// walk rounds the values targeted by node, the writes are collected rather
+// than applied so that a document is left untouched when any of its targets
+// is invalid.
+func (p *roundProc) walk(v any, node *pathNode, path []string, writes *[]func()) error {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, cv := range t {
+			c := node.child(k)
+			if c == nil {
+				continue
+			}
+			cPath := append(path[:len(path):len(path)], k)
+			if c.end {
+				nv, err := p.roundValue(cv, cPath)
+				if err != nil {
+					return err
+				}
+				k := k
+				*writes = append(*writes, func() { t[k] = nv })
+				continue
+			}
+			if err := p.walk(cv, c, cPath, writes); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for i, cv := range t {
+			c := node.child(strconv.Itoa(i))
+			if c == nil {
+				continue
+			}
+			cPath := append(path[:len(path):len(path)], strconv.Itoa(i))
+			if c.end {
+				nv, err := p.roundValue(cv, cPath)
+				if err != nil {
+					return err
+				}
+				i := i
+				*writes = append(*writes, func() { t[i] = nv })
+				continue
+			}
+			if err := p.walk(cv, c, cPath, writes); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *roundProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	v, err := msg.AsStructuredMut()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %w", err)
+	}
+
+	var writes []func()
+	if err := p.walk(v, p.paths, nil, &writes); err != nil {
+		return nil, err
+	}
+	for _, w := range writes {
+		w()
+	}
+	msg.SetStructuredMut(v)
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *roundProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,161 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func roundDoc(t testing.TB, proc *roundProc, doc string) string {
+	t.Helper()
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(doc)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	mBytes, err := res[0].AsBytes()
+	require.NoError(t, err)
+	return string(mBytes)
+}
+
+func TestRoundModes(t *testing.T) {
+	inputs := []string{"2.675", "2.665", "-2.675", "-2.665", "1.001", "-1.001", "0.005", "-0.005", "3", "1e-3", "12.3456789"}
+
+	for mode, expected := range map[string][]string{
+		"half_even": {"2.68", "2.66", "-2.68", "-2.66", "1.00", "-1.00", "0.00", "0.00", "3.00", "0.00", "12.35"},
+		"half_up":   {"2.68", "2.67", "-2.68", "-2.67", "1.00", "-1.00", "0.01", "-0.01", "3.00", "0.00", "12.35"},
+		"floor":     {"2.67", "2.66", "-2.68", "-2.67", "1.00", "-1.01", "0.00", "-0.01", "3.00", "0.00", "12.34"},
+		"ceil":      {"2.68", "2.67", "-2.67", "-2.66", "1.01", "-1.00", "0.01", "0.00", "3.00", "0.01", "12.35"},
+	} {
+		conf, err := roundProcConfig().ParseYAML(`
+paths: [ v ]
+rounding_mode: `+mode+`
+format: string
+`, nil)
+		require.NoError(t, err)
+
+		proc, err := roundProcFromParsed(conf)
+		require.NoError(t, err)
+
+		for i, in := range inputs {
+			assert.Equal(t, `{"v":"`+expected[i]+`"}`, roundDoc(t, proc, `{"v":`+in+`}`), "%v: %v", mode, in)
+		}
+	}
+}
+
+func TestRoundScale(t *testing.T) {
+	conf, err := roundProcConfig().ParseYAML(`
+paths: [ a, b ]
+scale: 0
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := roundProcFromParsed(conf)
+	require.NoError(t, err)
+
+	assert.Equal(t, `{"a":2,"b":-2}`, roundDoc(t, proc, `{"a":2.5,"b":-1.5}`))
+
+	pConf, err := roundProcConfig().ParseYAML(`
+paths: [ a ]
+scale: 4
+`, nil)
+	require.NoError(t, err)
+
+	proc, err = roundProcFromParsed(pConf)
+	require.NoError(t, err)
+
+	assert.Equal(t, `{"a":0.0001}`, roundDoc(t, proc, `{"a":0.00006}`))
+	assert.Equal(t, `{"a":123456789012345678901234567890.1235}`, roundDoc(t, proc, `{"a":123456789012345678901234567890.12346}`))
+}
+
+func TestRoundPaths(t *testing.T) {
+	conf, err := roundProcConfig().ParseYAML(`
+paths: [ total, items.*.price, missing.value ]
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := roundProcFromParsed(conf)
+	require.NoError(t, err)
+
+	assert.Equal(t,
+		`{"items":[{"name":"a","price":1.50},{"name":"b","price":10.00}],"total":11.50}`,
+		roundDoc(t, proc, `{"total":11.499,"items":[{"name":"a","price":1.5},{"name":"b","price":10}]}`),
+	)
+}
+
+func TestRoundStructuredValues(t *testing.T) {
+	conf, err := roundProcConfig().ParseYAML(`
+paths: [ a, b ]
+rounding_mode: half_up
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := roundProcFromParsed(conf)
+	require.NoError(t, err)
+
+	msg := service.NewMessage(nil)
+	msg.SetStructured(map[string]any{"a": 2.675, "b": int64(7)})
+
+	res, err := proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+
+	mBytes, err := res[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":2.68,"b":7.00}`, string(mBytes))
+}
+
+func TestRoundNotNumber(t *testing.T) {
+	conf, err := roundProcConfig().ParseYAML(`
+paths: [ a, items.*.price ]
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := roundProcFromParsed(conf)
+	require.NoError(t, err)
+
+	msg := service.NewMessage([]byte(`{"a":1.234,"items":[{"price":1},{"price":"1.50"}]}`))
+	_, err = proc.Process(context.Background(), msg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "items.1.price")
+
+	// Documents with invalid targets are left untouched.
+	mBytes, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1.234,"items":[{"price":1},{"price":"1.50"}]}`, string(mBytes))
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`not json`)))
+	require.Error(t, err)
+}
+
+func TestRoundConfigErrors(t *testing.T) {
+	for _, conf := range []string{
+		`paths: []`,
+		`paths: [ "a..b" ]`,
+		`paths: [ a ]
+scale: -1`,
+	} {
+		pConf, err := roundProcConfig().ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		_, err = roundProcFromParsed(pConf)
+		assert.Error(t, err, conf)
+	}
+}
@@ -0,0 +1,656 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	reFieldPath           = "path"
+	reFieldCache          = "cache"
+	reFieldCacheKey       = "cache_key"
+	reFieldMetadataKey    = "metadata_key"
+	reFieldFailOn         = "fail_on"
+	reFieldReloadInterval = "reload_interval"
+)
+
+func rulesEngineProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Mapping").
+		Version("4.31.0").
+		Summary("Evaluates each message against a set of rules loaded as data, and attaches the events of the rules that match to the metadata of the message.").
+		Description(`
+The rule set is a JSON document in the format of the https://github.com/CacheControl/json-rules-engine[json-rules-engine^] library, either an object with a field `+"`rules`"+` or an array of rules, where each rule has conditions and an event:
+
+`+"```json"+`
+{
+  "rules": [
+    {
+      "name": "high_value_new_customer",
+      "priority": 10,
+      "conditions": {
+        "all": [
+          { "fact": "order.total", "operator": "greaterThanInclusive", "value": 1000 },
+          { "any": [
+            { "fact": "customer.age_days", "operator": "lessThan", "value": 30 },
+            { "not": { "fact": "customer.verified", "operator": "equal", "value": true } }
+          ] }
+        ]
+      },
+      "event": { "type": "review", "params": { "queue": "fraud" } }
+    }
+  ]
+}
+`+"```"+`
+
+Every rule is evaluated against each message, and the events of the rules that match are written to the metadata field `+"`metadata_key`"+` as an array of objects, each containing the `+"`type`"+` and `+"`params`"+` of the event and the `+"`rule`"+` name, ordered by priority descending and then by the order of the rule set. When no rules match the array is empty. These events can be used to route messages, for example with the `+"xref:components:outputs/switch.adoc[`switch` output]"+` and the check `+"`@rule_events.any(e -> e.type == \"review\")`"+`.
+
+Messages that match a rule with an event type listed in `+"`fail_on`"+` are also flagged as failed with an error naming the rules, and can be handled using xref:configuration:error_handling.adoc[error handling methods].
+
+== Conditions
+
+A condition is either `+"`all`"+` or `+"`any`"+` with an array of conditions, `+"`not`"+` with a single condition, or a comparison of a fact. The fact of a comparison is a xref:configuration:field_paths.adoc[dot separated path] into the message, and a comparison may also have a `+"`path`"+` that is applied to the value of the fact, where a leading `+"`$.`"+` is ignored. When the value of a comparison is an object with a `+"`fact`"+` field it resolves to the value of that fact of the message. Facts that do not exist within a message are `+"`null`"+`.
+
+The following operators are supported:
+
+|===
+| Operator | Matches
+
+| `+"`equal`"+`, `+"`notEqual`"+`
+| Facts that are equal, or not equal, to the value. Numbers are compared by value.
+
+| `+"`lessThan`"+`, `+"`lessThanInclusive`"+`, `+"`greaterThan`"+`, `+"`greaterThanInclusive`"+`
+| Numeric facts that satisfy the comparison with a numeric value.
+
+| `+"`in`"+`, `+"`notIn`"+`
+| Facts that are, or are not, an element of the array value.
+
+| `+"`contains`"+`, `+"`doesNotContain`"+`
+| Array facts that contain, or do not contain, the value as an element, and string facts that contain, or do not contain, the string value.
+|===
+
+== Reloading
+
+The rule set is read either from the file at `+"`path`"+`, or from the key `+"`cache_key`"+` of the xref:components:caches/about.adoc[`+"`cache` resource"+`] `+"`cache`"+`, and is read again every `+"`reload_interval`"+`. When it has changed the rules are replaced, which allows the rules to be modified while the pipeline is running. When a modified rule set fails to load the error is logged and the previous rules continue to be used.`).
+		Field(service.NewStringField(reFieldPath).
+			Description("The path of a file containing the rule set. Either this field or `cache` must be set.").
+			Example("./rules.json").
+			Optional()).
+		Field(service.NewStringField(reFieldCache).
+			Description("A xref:components:caches/about.adoc[`cache` resource] to read the rule set from. Either this field or `path` must be set.").
+			Optional()).
+		Field(service.NewStringField(reFieldCacheKey).
+			Description("The key of the cache to read the rule set from.").
+			Default("rules").
+			Advanced()).
+		Field(service.NewStringField(reFieldMetadataKey).
+			Description("The metadata key to store the events of the matched rules in.").
+			Default("rule_events")).
+		Field(service.NewStringListField(reFieldFailOn).
+			Description("A list of event types that flag a message as failed when a rule with the event matches.").
+			Example([]string{"invalid"}).
+			Default([]string{})).
+		Field(service.NewDurationField(reFieldReloadInterval).
+			Description("The period of time between loads of the rule set. Set to `0s` in order to disable reloading.").
+			Default("30s").
+			Advanced()).
+		Example("Validation rules", "Flag orders that break validation rules maintained by analysts within a Redis key as failed, and route them to a separate topic.", `
+pipeline:
+  processors:
+    - rules_engine:
+        cache: rules
+        cache_key: order_validation
+        fail_on: [ invalid ]
+
+output:
+  switch:
+    cases:
+      - check: errored()
+        output:
+          kafka_franz:
+            seed_brokers: [ localhost:9092 ]
+            topic: invalid_orders
+      - output:
+          kafka_franz:
+            seed_brokers: [ localhost:9092 ]
+            topic: orders
+
+cache_resources:
+  - label: rules
+    redis:
+      url: redis://localhost:6379
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"rules_engine", rulesEngineProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return rulesEngineProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type reCondition struct {
+	all  []*reCondition
+	any  []*reCondition
+	not  *reCondition
+	kind int
+
+	fact      string
+	path      string
+	operator  string
+	value     any
+	valueFact string
+}
+
+const (
+	reCompare = iota
+	reAll
+	reAny
+	reNot
+)
+
+type reRule struct {
+	name       string
+	priority   float64
+	conditions *reCondition
+	event      map[string]any
+}
+
+var reOperators = map[string]struct{}{
+	"equal": {}, "notEqual": {},
+	"lessThan": {}, "lessThanInclusive": {}, "greaterThan": {}, "greaterThanInclusive": {},
+	"in": {}, "notIn": {},
+	"contains": {}, "doesNotContain": {},
+}
+
+func parseRECondition(v any) (*reCondition, error) {
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected condition object, got %T", v)
+	}
+
+	parseList := func(key string) ([]*reCondition, error) {
+		arr, ok := obj[key].([]any)
+		if !ok {
+			return nil, fmt.Errorf("expected %v to be an array, got %T", key, obj[key])
+		}
+		conds := make([]*reCondition, 0, len(arr))
+		for i, e := range arr {
+			c, err := parseRECondition(e)
+			if err != nil {
+				return nil, fmt.Errorf("%v.%v: %w", key, i, err)
+			}
+			conds = append(conds, c)
+		}
+		return conds, nil
+	}
+
+	c := &reCondition{}
+	var err error
+	switch {
+	case obj["all"] != nil:
+		c.kind = reAll
+		c.all, err = parseList("all")
+	case obj["any"] != nil:
+		c.kind = reAny
+		c.any, err = parseList("any")
+	case obj["not"] != nil:
+		c.kind = reNot
+		if c.not, err = parseRECondition(obj["not"]); err != nil {
+			err = fmt.Errorf("not: %w", err)
+		}
+	default:
+		c.kind = reCompare
+		if c.fact, _ = obj["fact"].(string); c.fact == "" {
+			return nil, errors.New("condition must have one of all, any, not or fact")
+		}
+		if c.operator, _ = obj["operator"].(string); c.operator == "" {
+			return nil, fmt.Errorf("condition of fact %v must have an operator", c.fact)
+		}
+		if _, exists := reOperators[c.operator]; !exists {
+			return nil, fmt.Errorf("operator not recognised: %v", c.operator)
+		}
+		if p, _ := obj["path"].(string); p != "" {
+			c.path = strings.TrimPrefix(strings.TrimPrefix(p, "$"), ".")
+		}
+		c.value = obj["value"]
+		if vObj, ok := c.value.(map[string]any); ok {
+			if f, ok := vObj["fact"].(string); ok {
+				c.valueFact = f
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func parseRuleSet(b []byte) ([]*reRule, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse rule set as JSON: %w", err)
+	}
+
+	rulesArr, ok := doc.([]any)
+	if !ok {
+		obj, isObj := doc.(map[string]any)
+		if !isObj {
+			return nil, fmt.Errorf("expected rule set to be an object or array, got %T", doc)
+		}
+		if rulesArr, ok = obj["rules"].([]any); !ok {
+			return nil, errors.New("expected rule set to have an array field rules")
+		}
+	}
+
+	rules := make([]*reRule, 0, len(rulesArr))
+	for i, rv := range rulesArr {
+		rObj, ok := rv.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("rule %v: expected object, got %T", i, rv)
+		}
+
+		r := &reRule{name: fmt.Sprintf("%v", i), priority: 1}
+		if name, _ := rObj["name"].(string); name != "" {
+			r.name = name
+		}
+		if p, exists := rObj["priority"]; exists {
+			f, err := bloblang.ValueAsFloat64(p)
+			if err != nil {
+				return nil, fmt.Errorf("rule %v: invalid priority: %w", r.name, err)
+			}
+			r.priority = f
+		}
+
+		var err error
+		if r.conditions, err = parseRECondition(rObj["conditions"]); err != nil {
+			return nil, fmt.Errorf("rule %v: %w", r.name, err)
+		}
+		if r.conditions.kind == reCompare {
+			return nil, fmt.Errorf("rule %v: conditions must be all, any or not", r.name)
+		}
+
+		if r.event, ok = rObj["event"].(map[string]any); !ok {
+			return nil, fmt.Errorf("rule %v: expected event object, got %T", r.name, rObj["event"])
+		}
+		if t, _ := r.event["type"].(string); t == "" {
+			return nil, fmt.Errorf("rule %v: event must have a type", r.name)
+		}
+		rules = append(rules, r)
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].priority > rules[j].priority
+	})
+	return rules, nil
+}
+
+func reFact(root any, path string) any {
+	if path == "" {
+		return root
+	}
+	return gabs.Wrap(root).Path(path).Data()
+}
+
+func reIsNumber(v any) bool {
+	switch v.(type) {
+	case json.Number, float64, float32, int, int64, int32, uint64, uint32:
+		return true
+	}
+	return false
+}
+
+func reNumbers(a, b any) (float64, float64, bool) {
+	if !reIsNumber(a) || !reIsNumber(b) {
+		return 0, 0, false
+	}
+	fa, err := bloblang.ValueAsFloat64(a)
+	if err != nil {
+		return 0, 0, false
+	}
+	fb, err := bloblang.ValueAsFloat64(b)
+	if err != nil {
+		return 0, 0, false
+	}
+	return fa, fb, true
+}
+
+func reEqual(a, b any) bool {
+	if fa, fb, ok := reNumbers(a, b); ok {
+		return fa == fb
+	}
+	switch ta := a.(type) {
+	case []any:
+		tb, ok := b.([]any)
+		if !ok || len(ta) != len(tb) {
+			return false
+		}
+		for i := range ta {
+			if !reEqual(ta[i], tb[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		tb, ok := b.(map[string]any)
+		if !ok || len(ta) != len(tb) {
+			return false
+		}
+		for k, v := range ta {
+			if !reEqual(v, tb[k]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func reContains(container, v any) bool {
+	switch t := container.(type) {
+	case []any:
+		for _, e := range t {
+			if reEqual(e, v) {
+				return true
+			}
+		}
+	case string:
+		if s, ok := v.(string); ok {
+			return strings.Contains(t, s)
+		}
+	}
+	return false
+}
+
+func (c *reCondition) eval(root any) bool {
+	switch c.kind {
+	case reAll:
+		for _, sub := range c.all {
+			if !sub.eval(root) {
+				return false
+			}
+		}
+		return true
+	case reAny:
+		for _, sub := range c.any {
+			if sub.eval(root) {
+				return true
+			}
+		}
+		return false
+	case reNot:
+		return !c.not.eval(root)
+	}
+
+	fact := reFact(reFact(root, c.fact), c.path)
+	value := c.value
+	if c.valueFact != "" {
+		value = reFact(root, c.valueFact)
+	}
+
+	switch c.operator {
+	case "equal":
+		return reEqual(fact, value)
+	case "notEqual":
+		return !reEqual(fact, value)
+	case "in":
+		return reContains(value, fact)
+	case "notIn":
+		return !reContains(value, fact)
+	case "contains":
+		return reContains(fact, value)
+	case "doesNotContain":
+		return !reContains(fact, value)
+	}
+
+	fa, fb, ok := reNumbers(fact, value)
+	if !ok {
+		return false
+	}
+	switch c.operator {
+	case "lessThan":
+		return fa < fb
+	case "lessThanInclusive":
+		return fa <= fb
+	case "greaterThan":
+		return fa > fb
+	case "greaterThanInclusive":
+		return fa >= fb
+	}
+	return false
+}
+
+//------------------------------------------------------------------------------
+
+type rulesEngineProc struct {
+	path           string
+	cache          string
+	cacheKey       string
+	metaKey        string
+	failOn         map[string]struct{}
+	reloadInterval time.Duration
+
+	mgr *service.Resources
+	log *service.Logger
+
+	rulesMut  sync.RWMutex
+	rules     []*reRule
+	rulesHash uint64
+	loaded    bool
+
+	reloadMut sync.Mutex
+	lastCheck time.Time
+}
+
+func rulesEngineProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*rulesEngineProc, error) {
+	p := &rulesEngineProc{mgr: mgr, log: mgr.Logger(), failOn: map[string]struct{}{}}
+
+	var err error
+	if p.metaKey, err = conf.FieldString(reFieldMetadataKey); err != nil {
+		return nil, err
+	}
+	failOn, err := conf.FieldStringList(reFieldFailOn)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range failOn {
+		p.failOn[t] = struct{}{}
+	}
+	if p.reloadInterval, err = conf.FieldDuration(reFieldReloadInterval); err != nil {
+		return nil, err
+	}
+	if p.cacheKey, err = conf.FieldString(reFieldCacheKey); err != nil {
+		return nil, err
+	}
+
+	if conf.Contains(reFieldPath) == conf.Contains(reFieldCache) {
+		return nil, errors.New("exactly one of path or cache must be set")
+	}
+	if conf.Contains(reFieldCache) {
+		if p.cache, err = conf.FieldString(reFieldCache); err != nil {
+			return nil, err
+		}
+		if !mgr.HasCache(p.cache) {
+			return nil, fmt.Errorf("cache resource '%v' was not found", p.cache)
+		}
+		return p, nil
+	}
+
+	if p.path, err = conf.FieldString(reFieldPath); err != nil {
+		return nil, err
+	}
+	if err := p.load(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to load rule set: %w", err)
+	}
+	p.lastCheck = time.Now()
+	return p, nil
+}
+
+func (p *rulesEngineProc) readRuleSet(ctx context.Context) ([]byte, error) {
+	if p.path != "" {
+		return service.ReadFile(p.mgr.FS(), p.path)
+	}
+
+	var b []byte
+	var cacheErr error
+	if err := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		b, cacheErr = c.Get(ctx, p.cacheKey)
+	}); err != nil {
+		return nil, err
+	}
+	return b, cacheErr
+}
+
+// load reads the rule set and replaces the rules when it has changed.
+func (p *rulesEngineProc) load(ctx context.Context) error {
+	b, err := p.readRuleSet(ctx)
+	if err != nil {
+		return err
+	}
+
+	rulesHash := xxhash.Sum64(b)
+	p.rulesMut.RLock()
+	unchanged := p.loaded && rulesHash == p.rulesHash
+	p.rulesMut.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	rules, err := parseRuleSet(b)
+	if err != nil {
+		return err
+	}
+
+	p.rulesMut.Lock()
+	p.rules, p.rulesHash, p.loaded = rules, rulesHash, true
+	p.rulesMut.Unlock()
+
+	p.log.Infof("Loaded rule set with %v rules", len(rules))
+	return nil
+}
+
+// maybeReload loads the rule set when the reload interval has passed since the
+// last check. Only one caller performs the check at a time, and others
+// continue with the current rules.
+func (p *rulesEngineProc) maybeReload(ctx context.Context) {
+	if p.reloadInterval <= 0 || !p.reloadMut.TryLock() {
+		return
+	}
+	defer p.reloadMut.Unlock()
+
+	if time.Since(p.lastCheck) < p.reloadInterval {
+		return
+	}
+	p.lastCheck = time.Now()
+
+	if err := p.load(ctx); err != nil {
+		p.log.Errorf("Failed to reload rule set, continuing with the previous rules: %v", err)
+	}
+}
+
+func (p *rulesEngineProc) currentRules(ctx context.Context) ([]*reRule, error) {
+	p.rulesMut.RLock()
+	loaded := p.loaded
+	p.rulesMut.RUnlock()
+	if loaded {
+		p.maybeReload(ctx)
+		p.rulesMut.RLock()
+		defer p.rulesMut.RUnlock()
+		return p.rules, nil
+	}
+
+	// The rule set has not yet been loaded from the cache.
+	p.reloadMut.Lock()
+	defer p.reloadMut.Unlock()
+
+	p.rulesMut.RLock()
+	loaded = p.loaded
+	p.rulesMut.RUnlock()
+	if !loaded {
+		if err := p.load(ctx); err != nil {
+			return nil, fmt.Errorf("failed to load rule set: %w", err)
+		}
+		p.lastCheck = time.Now()
+	}
+
+	p.rulesMut.RLock()
+	defer p.rulesMut.RUnlock()
+	return p.rules, nil
+}
+
+func (p *rulesEngineProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	rules, err := p.currentRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := msg.AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %w", err)
+	}
+
+	events := []any{}
+	var failed []string
+	for _, r := range rules {
+		if !r.conditions.eval(root) {
+			continue
+		}
+		eventType := r.event["type"].(string)
+		events = append(events, map[string]any{
+			"type":   eventType,
+			"params": copyMappingValue(r.event["params"]),
+			"rule":   r.name,
+		})
+		if _, exists := p.failOn[eventType]; exists {
+			failed = append(failed, r.name)
+		}
+	}
+
+	msg.MetaSetMut(p.metaKey, events)
+	if len(failed) > 0 {
+		msg.SetError(fmt.Errorf("message matched rules: %v", strings.Join(failed, ", ")))
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *rulesEngineProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,192 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const rulesEngineTestRules = `{
+  "rules": [
+    {
+      "name": "small",
+      "conditions": { "all": [ { "fact": "order.total", "operator": "lessThan", "value": 10 } ] },
+      "event": { "type": "invalid", "params": { "reason": "too small" } }
+    },
+    {
+      "name": "review",
+      "priority": 10,
+      "conditions": {
+        "all": [
+          { "fact": "order.total", "operator": "greaterThanInclusive", "value": 1000 },
+          { "any": [
+            { "fact": "customer.age_days", "operator": "lessThan", "value": 30 },
+            { "not": { "fact": "customer.verified", "operator": "equal", "value": true } }
+          ] }
+        ]
+      },
+      "event": { "type": "review", "params": { "queue": "fraud" } }
+    },
+    {
+      "name": "vip",
+      "conditions": { "any": [ { "fact": "customer.tags", "operator": "contains", "value": "vip" } ] },
+      "event": { "type": "priority" }
+    }
+  ]
+}`
+
+func rulesEngineEvents(t testing.TB, proc *rulesEngineProc, doc string) ([]any, error) {
+	t.Helper()
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(doc)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	v, exists := res[0].MetaGetMut("rule_events")
+	require.True(t, exists)
+	return v.([]any), res[0].GetError()
+}
+
+func TestRulesEngineConditions(t *testing.T) {
+	rules, err := parseRuleSet([]byte(`[
+  { "name": "ops", "conditions": { "all": [
+    { "fact": "a", "operator": "equal", "value": 5 },
+    { "fact": "a", "operator": "notEqual", "value": "5" },
+    { "fact": "a", "operator": "lessThanInclusive", "value": 5 },
+    { "fact": "a", "operator": "greaterThan", "value": 4.5 },
+    { "fact": "b", "operator": "in", "value": [ "x", "y" ] },
+    { "fact": "b", "operator": "notIn", "value": [ "z" ] },
+    { "fact": "b", "operator": "doesNotContain", "value": "z" },
+    { "fact": "c", "path": "$.d.e", "operator": "equal", "value": { "nested": [ 1, 2 ] } },
+    { "fact": "a", "operator": "equal", "value": { "fact": "f" } },
+    { "fact": "missing", "operator": "equal", "value": null },
+    { "fact": "missing", "operator": "notEqual", "value": 1 },
+    { "fact": "t", "operator": "notEqual", "value": 1 }
+  ] }, "event": { "type": "ok" } }
+]`))
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+
+	doc := map[string]any{
+		"a": 5,
+		"b": "x",
+		"c": map[string]any{"d": map[string]any{"e": map[string]any{"nested": []any{1.0, 2}}}},
+		"f": 5.0,
+		"t": true,
+	}
+	assert.True(t, rules[0].conditions.eval(doc))
+
+	doc["a"] = 6
+	assert.False(t, rules[0].conditions.eval(doc))
+}
+
+func TestRulesEngineFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(rulesEngineTestRules), 0o644))
+
+	conf, err := rulesEngineProcConfig().ParseYAML(`
+path: `+path+`
+fail_on: [ invalid ]
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := rulesEngineProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	events, err := rulesEngineEvents(t, proc, `{"order":{"total":1500},"customer":{"age_days":100,"verified":false,"tags":["vip"]}}`)
+	require.NoError(t, err)
+	assert.Equal(t, []any{
+		map[string]any{"type": "review", "params": map[string]any{"queue": "fraud"}, "rule": "review"},
+		map[string]any{"type": "priority", "params": nil, "rule": "vip"},
+	}, events)
+
+	events, err = rulesEngineEvents(t, proc, `{"order":{"total":1500},"customer":{"age_days":100,"verified":true}}`)
+	require.NoError(t, err)
+	assert.Equal(t, []any{}, events)
+
+	events, err = rulesEngineEvents(t, proc, `{"order":{"total":5}}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "small")
+	assert.Equal(t, []any{
+		map[string]any{"type": "invalid", "params": map[string]any{"reason": "too small"}, "rule": "small"},
+	}, events)
+}
+
+func TestRulesEngineCacheReload(t *testing.T) {
+	res := service.MockResources(service.MockResourcesOptAddCache("foocache"))
+	conf, err := rulesEngineProcConfig().ParseYAML(`
+cache: foocache
+reload_interval: 1ms
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := rulesEngineProcFromParsed(conf, res)
+	require.NoError(t, err)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{}`)))
+	require.Error(t, err)
+
+	ctx := context.Background()
+	setRules := func(rules string) {
+		require.NoError(t, res.AccessCache(ctx, "foocache", func(c service.Cache) {
+			require.NoError(t, c.Set(ctx, "rules", []byte(rules), nil))
+		}))
+	}
+
+	setRules(`[{"name":"a","conditions":{"all":[{"fact":"v","operator":"equal","value":1}]},"event":{"type":"one"}}]`)
+	events, err := rulesEngineEvents(t, proc, `{"v":1}`)
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+
+	setRules(`[{"name":"a","conditions":{"all":[{"fact":"v","operator":"equal","value":2}]},"event":{"type":"two"}}]`)
+	time.Sleep(5 * time.Millisecond)
+	events, err = rulesEngineEvents(t, proc, `{"v":1}`)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	// Invalid rule sets are logged and the previous rules are kept.
+	setRules(`[{"name":"a","conditions":{"all":[{"fact":"v","operator":"nope","value":2}]},"event":{"type":"two"}}]`)
+	time.Sleep(5 * time.Millisecond)
+	events, err = rulesEngineEvents(t, proc, `{"v":2}`)
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+}
+
+func TestRulesEngineParseErrors(t *testing.T) {
+	for _, rules := range []string{
+		`not json`,
+		`"foo"`,
+		`{"rules":"foo"}`,
+		`[{"conditions":{"all":[]}}]`,
+		`[{"conditions":{"all":[]},"event":{}}]`,
+		`[{"conditions":{"fact":"a","operator":"equal","value":1},"event":{"type":"a"}}]`,
+		`[{"conditions":{"all":[{"fact":"a","value":1}]},"event":{"type":"a"}}]`,
+		`[{"conditions":{"all":[{"operator":"equal","value":1}]},"event":{"type":"a"}}]`,
+		`[{"conditions":{"any":"foo"},"event":{"type":"a"}}]`,
+		`[{"priority":"high","conditions":{"all":[]},"event":{"type":"a"}}]`,
+	} {
+		_, err := parseRuleSet([]byte(rules))
+		assert.Error(t, err, rules)
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	sesFieldKey           = "key"
+	sesFieldTimestamp     = "timestamp"
+	sesFieldInactivityGap = "inactivity_gap"
+	sesFieldMaxDuration   = "max_duration"
+	sesFieldCache         = "cache"
+)
+
+func sessionizeProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Groups the messages of each key into sessions that end after a period of inactivity or once they reach a maximum duration, and writes the ID of the session of each message to metadata.").
+		Description(`
+The session of each key is stored in a cache. A message begins a new session with a newly generated ID when its key has no session, when the time since the previous message of the key exceeds `+"`inactivity_gap`"+`, or when the time since the start of the session exceeds `+"`max_duration`"+`. Otherwise the message belongs to the current session of its key. The contents of messages are not modified.
+
+The time of each message is resolved from `+"`timestamp`"+` when specified, which must resolve to either an RFC 3339 timestamp or a unix timestamp in seconds, and is otherwise the time that the message is processed. Messages that arrive out of order belong to the current session of their key, and do not extend it.
+
+Sessions are stored with a TTL of `+"`inactivity_gap`"+`, and therefore the cache only holds sessions that can still be continued. Not all caches support per-key TTLs.
+
+Each message reads the session of its key from the cache and then writes the updated session back. When messages of the same key are processed by multiple pipeline threads at the same time they can read the same session, in which case an expired session can be replaced by two new sessions with different IDs, or the time of the latest message of a session can be moved back. Messages of a key should therefore be processed by a single pipeline thread, for example by partitioning messages by key upstream.
+
+== Metadata
+
+This processor adds the following metadata fields to each message:
+
+`+"```text"+`
+- session_id
+- session_new
+- session_started_at
+`+"```"+`
+
+Where `+"`session_new`"+` is a boolean that is true for the first message of a session, and `+"`session_started_at`"+` is the time of the first message of the session in RFC 3339 format.`).
+		Field(service.NewInterpolatedStringField(sesFieldKey).
+			Description("An interpolated string that resolves to the key to group sessions by, such as a user ID.").
+			Example(`${! this.user_id }`)).
+		Field(service.NewInterpolatedStringField(sesFieldTimestamp).
+			Description("An optional interpolated string that resolves to the time of each message.").
+			Example(`${! this.event_time }`).
+			Optional()).
+		Field(service.NewDurationField(sesFieldInactivityGap).
+			Description("The maximum period between the messages of a session.").
+			Default("30m")).
+		Field(service.NewDurationField(sesFieldMaxDuration).
+			Description("The maximum period between the first and last messages of a session.").
+			Default("24h")).
+		Field(service.NewStringField(sesFieldCache).
+			Description("The xref:components:caches/about.adoc[`cache` resource] to store the session of each key in.")).
+		Example("Clickstream sessions", "Assign page views to sessions that end after thirty minutes of inactivity, adding the session ID to each page view.", `
+pipeline:
+  processors:
+    - sessionize:
+        key: ${! this.user_id }
+        timestamp: ${! this.timestamp }
+        inactivity_gap: 30m
+        max_duration: 12h
+        cache: sessions
+    - mutation: |
+        root.session_id = @session_id
+
+cache_resources:
+  - label: sessions
+    redis:
+      url: redis://localhost:6379
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"sessionize", sessionizeProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return sessionizeProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type sessionizeProc struct {
+	key           *service.InterpolatedString
+	timestamp     *service.InterpolatedString
+	inactivityGap time.Duration
+	maxDuration   time.Duration
+	cache         string
+
+	mgr *service.Resources
+	now func() time.Time
+}
+
+func sessionizeProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*sessionizeProc, error) {
+	p := &sessionizeProc{
+		mgr: mgr,
+		now: time.Now,
+	}
+
+	var err error
+	if p.key, err = conf.FieldInterpolatedString(sesFieldKey); err != nil {
+		return nil, err
+	}
+	if conf.Contains(sesFieldTimestamp) {
+		if p.timestamp, err = conf.FieldInterpolatedString(sesFieldTimestamp); err != nil {
+			return nil, err
+		}
+	}
+	if p.inactivityGap, err = conf.FieldDuration(sesFieldInactivityGap); err != nil {
+		return nil, err
+	}
+	if p.inactivityGap <= 0 {
+		return nil, errors.New("inactivity_gap must be greater than zero")
+	}
+	if p.maxDuration, err = conf.FieldDuration(sesFieldMaxDuration); err != nil {
+		return nil, err
+	}
+	if p.maxDuration <= 0 {
+		return nil, errors.New("max_duration must be greater than zero")
+	}
+	if p.cache, err = conf.FieldString(sesFieldCache); err != nil {
+		return nil, err
+	}
+	if !mgr.HasCache(p.cache) {
+		return nil, fmt.Errorf("cache resource '%v' was not found", p.cache)
+	}
+	return p, nil
+}
+
+// sessionState is the state of a session as it is stored within the cache,
+// with times stored as unix nanoseconds.
+type sessionState struct {
+	ID    string `json:"id"`
+	Start int64  `json:"start"`
+	Last  int64  `json:"last"`
+}
+
+func sessionParseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	secs, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse timestamp %q: expected RFC 3339 or unix seconds", s)
+	}
+	return time.Unix(0, int64(secs*float64(time.Second))), nil
+}
+
+func (p *sessionizeProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	key, err := p.key.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("key interpolation error: %w", err)
+	}
+
+	ts := p.now()
+	if p.timestamp != nil {
+		tsStr, err := p.timestamp.TryString(msg)
+		if err != nil {
+			return nil, fmt.Errorf("timestamp interpolation error: %w", err)
+		}
+		if ts, err = sessionParseTime(tsStr); err != nil {
+			return nil, err
+		}
+	}
+
+	var state sessionState
+	var exists bool
+	var cacheErr error
+	if err := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		stateBytes, err := c.Get(ctx, key)
+		if err != nil {
+			if !errors.Is(err, service.ErrKeyNotFound) {
+				cacheErr = err
+			}
+			return
+		}
+		if err := json.Unmarshal(stateBytes, &state); err != nil {
+			cacheErr = fmt.Errorf("failed to parse session: %w", err)
+			return
+		}
+		exists = true
+	}); err != nil {
+		return nil, err
+	}
+	if cacheErr != nil {
+		return nil, fmt.Errorf("failed to obtain session of %v: %w", key, cacheErr)
+	}
+
+	tsNano := ts.UnixNano()
+	isNew := !exists ||
+		time.Duration(tsNano-state.Last) > p.inactivityGap ||
+		time.Duration(tsNano-state.Start) > p.maxDuration
+	if isNew {
+		id, err := uuid.NewV4()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate session ID: %w", err)
+		}
+		state = sessionState{ID: id.String(), Start: tsNano, Last: tsNano}
+	} else if tsNano > state.Last {
+		state.Last = tsNano
+	}
+
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		cacheErr = c.Set(ctx, key, stateBytes, &p.inactivityGap)
+	}); err != nil {
+		return nil, err
+	}
+	if cacheErr != nil {
+		return nil, fmt.Errorf("failed to store session of %v: %w", key, cacheErr)
+	}
+
+	msg.MetaSetMut("session_id", state.ID)
+	msg.MetaSetMut("session_new", isNew)
+	msg.MetaSetMut("session_started_at", time.Unix(0, state.Start).UTC().Format(time.RFC3339Nano))
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *sessionizeProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type sessionResult struct {
+	id    string
+	isNew bool
+}
+
+func sessionizeEvents(t testing.TB, proc *sessionizeProc, events ...string) []sessionResult {
+	t.Helper()
+
+	var results []sessionResult
+	for _, e := range events {
+		res, err := proc.Process(context.Background(), service.NewMessage([]byte(e)))
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+
+		id, exists := res[0].MetaGet("session_id")
+		require.True(t, exists)
+		isNew, exists := res[0].MetaGetMut("session_new")
+		require.True(t, exists)
+		results = append(results, sessionResult{id: id, isNew: isNew.(bool)})
+	}
+	return results
+}
+
+func TestSessionizeInactivityGap(t *testing.T) {
+	conf, err := sessionizeProcConfig().ParseYAML(`
+key: ${! this.user }
+timestamp: ${! this.ts }
+inactivity_gap: 30m
+cache: foocache
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := sessionizeProcFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.NoError(t, err)
+
+	res := sessionizeEvents(t, proc,
+		`{"user":"a","ts":"2024-06-01T10:00:00Z"}`,
+		`{"user":"b","ts":"2024-06-01T10:05:00Z"}`,
+		`{"user":"a","ts":"2024-06-01T10:20:00Z"}`,
+		`{"user":"a","ts":"2024-06-01T10:45:00Z"}`,
+		`{"user":"a","ts":"2024-06-01T11:16:00Z"}`,
+		// Out of order messages belong to the current session.
+		`{"user":"a","ts":"2024-06-01T11:00:00Z"}`,
+	)
+
+	assert.True(t, res[0].isNew)
+	assert.True(t, res[1].isNew)
+	assert.NotEqual(t, res[0].id, res[1].id)
+
+	assert.False(t, res[2].isNew)
+	assert.False(t, res[3].isNew)
+	assert.Equal(t, res[0].id, res[2].id)
+	assert.Equal(t, res[0].id, res[3].id)
+
+	assert.True(t, res[4].isNew)
+	assert.NotEqual(t, res[0].id, res[4].id)
+	assert.False(t, res[5].isNew)
+	assert.Equal(t, res[4].id, res[5].id)
+}
+
+func TestSessionizeMaxDuration(t *testing.T) {
+	conf, err := sessionizeProcConfig().ParseYAML(`
+key: ${! this.user }
+timestamp: ${! this.ts }
+inactivity_gap: 30m
+max_duration: 1h
+cache: foocache
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := sessionizeProcFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.NoError(t, err)
+
+	res := sessionizeEvents(t, proc,
+		`{"user":"a","ts":1717236000}`,
+		`{"user":"a","ts":1717237800}`,
+		`{"user":"a","ts":1717239600}`,
+		`{"user":"a","ts":1717239660}`,
+	)
+
+	assert.True(t, res[0].isNew)
+	assert.False(t, res[1].isNew)
+	assert.False(t, res[2].isNew)
+	assert.True(t, res[3].isNew)
+	assert.Equal(t, res[0].id, res[2].id)
+	assert.NotEqual(t, res[0].id, res[3].id)
+}
+
+func TestSessionizeStartedAt(t *testing.T) {
+	conf, err := sessionizeProcConfig().ParseYAML(`
+key: ${! this.user }
+timestamp: ${! this.ts }
+cache: foocache
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := sessionizeProcFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.NoError(t, err)
+
+	for _, ts := range []string{"2024-06-01T10:00:00Z", "2024-06-01T10:10:00Z"} {
+		res, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"user":"a","ts":"`+ts+`"}`)))
+		require.NoError(t, err)
+		started, _ := res[0].MetaGet("session_started_at")
+		assert.Equal(t, "2024-06-01T10:00:00Z", started)
+	}
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"user":"a","ts":"nope"}`)))
+	require.Error(t, err)
+}
@@ -0,0 +1,171 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	shufFieldSeed = "seed"
+	shufFieldBy   = "by"
+)
+
+func shuffleProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Randomly reorders the messages of a batch, optionally from a seed so that the order is reproducible.").
+		Description(`
+When a `+"`seed`"+` is set it is resolved against the first message of each batch, and batches with the same seed and the same number of messages (or groups) are always shuffled into the same order. Seeds that are not integers are hashed. Without a seed each batch is shuffled differently.
+
+When the field `+"`by`"+` is set messages that resolve to the same key are kept together in their original order, and the groups are shuffled as a whole instead of individual messages. Groups are positioned at the first message of each key.`).
+		Field(service.NewInterpolatedStringField(shufFieldSeed).
+			Description("An optional seed for the shuffle, resolved against the first message of each batch.").
+			Example("42").
+			Example(`${! @test_run_id }`).
+			Optional()).
+		Field(service.NewInterpolatedStringField(shufFieldBy).
+			Description("An optional key to group messages by, where the groups are shuffled rather than individual messages.").
+			Example(`${! this.user_id }`).
+			Optional()).
+		Example("Break up ordering", "Shuffle batches of events such that consecutive events from the same customer are spread out before being fanned out to workers, whilst keeping the events of each account in order.", `
+pipeline:
+  processors:
+    - shuffle:
+        by: ${! this.account_id }
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"shuffle", shuffleProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return shuffleProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type shuffleProc struct {
+	seed *service.InterpolatedString
+	by   *service.InterpolatedString
+
+	rngMut sync.Mutex
+	rng    *rand.Rand
+}
+
+func shuffleProcFromParsed(conf *service.ParsedConfig) (*shuffleProc, error) {
+	p := &shuffleProc{
+		rng: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	var err error
+	if conf.Contains(shufFieldSeed) {
+		if p.seed, err = conf.FieldInterpolatedString(shufFieldSeed); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(shufFieldBy) {
+		if p.by, err = conf.FieldInterpolatedString(shufFieldBy); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// shuffleFn returns a function that shuffles n elements, either from the seed
+// of the batch or from the shared random source.
+func (p *shuffleProc) shuffleFn(batch service.MessageBatch) (func(n int, swap func(i, j int)), error) {
+	if p.seed == nil {
+		return func(n int, swap func(i, j int)) {
+			p.rngMut.Lock()
+			p.rng.Shuffle(n, swap)
+			p.rngMut.Unlock()
+		}, nil
+	}
+
+	seedStr, err := batch.TryInterpolatedString(0, p.seed)
+	if err != nil {
+		return nil, fmt.Errorf("seed interpolation error: %w", err)
+	}
+	seed, err := strconv.ParseInt(seedStr, 10, 64)
+	if err != nil {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(seedStr))
+		seed = int64(h.Sum64())
+	}
+	return rand.New(rand.NewSource(seed)).Shuffle, nil
+}
+
+func (p *shuffleProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	if len(batch) < 2 {
+		return []service.MessageBatch{batch}, nil
+	}
+
+	shuffle, err := p.shuffleFn(batch)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.by == nil {
+		shuffled := make(service.MessageBatch, len(batch))
+		copy(shuffled, batch)
+		shuffle(len(shuffled), func(i, j int) {
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		})
+		return []service.MessageBatch{shuffled}, nil
+	}
+
+	var groups []service.MessageBatch
+	groupIndexes := map[string]int{}
+	for i, msg := range batch {
+		key, err := batch.TryInterpolatedString(i, p.by)
+		if err != nil {
+			return nil, fmt.Errorf("by interpolation error: %w", err)
+		}
+		gIndex, exists := groupIndexes[key]
+		if !exists {
+			gIndex = len(groups)
+			groupIndexes[key] = gIndex
+			groups = append(groups, nil)
+		}
+		groups[gIndex] = append(groups[gIndex], msg)
+	}
+
+	shuffle(len(groups), func(i, j int) {
+		groups[i], groups[j] = groups[j], groups[i]
+	})
+
+	shuffled := make(service.MessageBatch, 0, len(batch))
+	for _, g := range groups {
+		shuffled = append(shuffled, g...)
+	}
+	return []service.MessageBatch{shuffled}, nil
+}
+
+func (p *shuffleProc) Close(ctx context.Context) error {
+	return nil
+}
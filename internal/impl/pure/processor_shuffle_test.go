@@ -0,0 +1,116 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func shuffleTestBatch(n int, keyFn func(i int) string) service.MessageBatch {
+	var batch service.MessageBatch
+	for i := 0; i < n; i++ {
+		batch = append(batch, service.NewMessage([]byte(fmt.Sprintf(`{"id":%v,"key":%q}`, i, keyFn(i)))))
+	}
+	return batch
+}
+
+func TestShuffleSeeded(t *testing.T) {
+	newBatch := func() service.MessageBatch {
+		return shuffleTestBatch(20, func(i int) string { return "" })
+	}
+
+	conf, err := shuffleProcConfig().ParseYAML(`seed: '42'`, nil)
+	require.NoError(t, err)
+
+	proc, err := shuffleProcFromParsed(conf)
+	require.NoError(t, err)
+
+	first, err := proc.ProcessBatch(context.Background(), newBatch())
+	require.NoError(t, err)
+	require.Len(t, first, 1)
+
+	second, err := proc.ProcessBatch(context.Background(), newBatch())
+	require.NoError(t, err)
+	require.Len(t, second, 1)
+
+	firstContents, secondContents := batchContents(t, first[0]), batchContents(t, second[0])
+	assert.Equal(t, firstContents, secondContents)
+	assert.NotEqual(t, batchContents(t, newBatch()), firstContents)
+
+	sort.Strings(firstContents)
+	expected := batchContents(t, newBatch())
+	sort.Strings(expected)
+	assert.Equal(t, expected, firstContents)
+
+	pConf, err := shuffleProcConfig().ParseYAML(`seed: 'meow'`, nil)
+	require.NoError(t, err)
+
+	other, err := shuffleProcFromParsed(pConf)
+	require.NoError(t, err)
+
+	third, err := other.ProcessBatch(context.Background(), newBatch())
+	require.NoError(t, err)
+	assert.NotEqual(t, secondContents, batchContents(t, third[0]))
+}
+
+func TestShuffleGrouped(t *testing.T) {
+	keys := []string{"a", "b", "c", "d"}
+	batch := shuffleTestBatch(20, func(i int) string { return keys[i%len(keys)] })
+
+	conf, err := shuffleProcConfig().ParseYAML(`
+seed: '7'
+by: ${! this.key }
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := shuffleProcFromParsed(conf)
+	require.NoError(t, err)
+
+	res, err := proc.ProcessBatch(context.Background(), batch)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 20)
+
+	var keyOrder []string
+	lastID := map[string]float64{}
+	for _, msg := range res[0] {
+		v, err := msg.AsStructured()
+		require.NoError(t, err)
+
+		obj := v.(map[string]any)
+		key := obj["key"].(string)
+		if len(keyOrder) == 0 || keyOrder[len(keyOrder)-1] != key {
+			keyOrder = append(keyOrder, key)
+		}
+
+		id, err := obj["id"].(interface{ Float64() (float64, error) }).Float64()
+		require.NoError(t, err)
+		if last, exists := lastID[key]; exists {
+			assert.Greater(t, id, last, "messages of a group should keep their order")
+		}
+		lastID[key] = id
+	}
+
+	// Each group is contiguous.
+	assert.Len(t, keyOrder, len(keys))
+}
@@ -0,0 +1,441 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	sjFieldSide     = "side"
+	sjFieldKey      = "key"
+	sjFieldWindow   = "window"
+	sjFieldJoinType = "join_type"
+	sjFieldCache    = "cache"
+
+	sjSideLeft  = "left"
+	sjSideRight = "right"
+)
+
+func streamJoinProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Joins messages from two streams that share a key and arrive within a window of each other, buffering messages of each side in a cache until they are matched.").
+		Description(`
+Each message belongs to either the `+"`left`"+` or `+"`right`"+` side of the join, determined by the field `+"`side`"+`, which usually resolves a metadata field set on the messages of each input. When a message arrives it is joined with every message of the opposite side with the same key that arrived within the `+"`window`"+`, and is then buffered so that it can be joined with messages of the opposite side that arrive later. Buffered messages are not emitted, only the joined messages are.
+
+A joined message is a JSON object with the fields `+"`left`"+` and `+"`right`"+` containing the parsed contents of the two messages, or their raw contents as a string when they are not valid JSON, and has the metadata of both messages, where the metadata of the left message takes precedence.
+
+== Join types
+
+With the `+"`inner`"+` join type messages that are not joined with any other message before their window expires are dropped. With the `+"`left`"+`, `+"`right`"+` and `+"`outer`"+` join types unmatched messages of the left side, the right side, or both sides respectively are emitted once their window expires, with the field of the opposite side set to `+"`null`"+`.
+
+Expired messages are detected when a batch is processed, and therefore they are emitted along with the results of the next batch to arrive after their window expired. Messages that arrive without a steady flow of other messages might be emitted long after their window expired.
+
+== State
+
+Buffered messages are stored in the cache under the key prefixed with the side, and are removed once their window expires. Messages are buffered with a TTL of the `+"`window`"+` for the `+"`inner`"+` join type, and otherwise without a TTL so that they are present when they expire, and therefore the cache must retain entries for at least the window.
+
+The keys of buffered messages are tracked in memory by each instance of the processor in order to detect expired messages, and the state of a key is read and written by separate cache operations, therefore both inputs should be fed into a single pipeline thread, as shown in the example.
+
+Messages where the side or key cannot be resolved, or the side is neither `+"`left`"+` nor `+"`right`"+`, are flagged as failed and emitted as they are so that they can be handled using xref:configuration:error_handling.adoc[error handling methods].`).
+		Field(service.NewInterpolatedStringField(sjFieldSide).
+			Description("An interpolated string that resolves to the side of the join each message belongs to, either `left` or `right`.").
+			Example(`${! @join_side }`)).
+		Field(service.NewInterpolatedStringField(sjFieldKey).
+			Description("An interpolated string that resolves to the key messages are joined on.").
+			Example(`${! this.order_id }`)).
+		Field(service.NewDurationField(sjFieldWindow).
+			Description("The maximum period between the arrival of two messages for them to be joined.").
+			Example("30s")).
+		Field(service.NewStringEnumField(sjFieldJoinType, "inner", "left", "right", "outer").
+			Description("The type of join, which determines whether messages that were never joined are emitted once their window expires.").
+			Default("inner")).
+		Field(service.NewStringField(sjFieldCache).
+			Description("The xref:components:caches/about.adoc[`cache` resource] to buffer messages in.")).
+		Example("Orders and payments", "Join orders with their payments that arrive within a minute of each other, and emit orders without a payment once their window expires.", `
+input:
+  broker:
+    inputs:
+      - kafka_franz:
+          seed_brokers: [ localhost:9092 ]
+          topics: [ orders ]
+          consumer_group: joiner
+        processors:
+          - mapping: 'meta join_side = "left"'
+      - kafka_franz:
+          seed_brokers: [ localhost:9092 ]
+          topics: [ payments ]
+          consumer_group: joiner
+        processors:
+          - mapping: 'meta join_side = "right"'
+
+pipeline:
+  threads: 1
+  processors:
+    - stream_join:
+        side: ${! @join_side }
+        key: ${! this.order_id }
+        window: 1m
+        join_type: left
+        cache: joins
+
+cache_resources:
+  - label: joins
+    memory:
+      default_ttl: 5m
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"stream_join", streamJoinProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return streamJoinProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type streamJoinProc struct {
+	side     *service.InterpolatedString
+	key      *service.InterpolatedString
+	window   time.Duration
+	joinType string
+	cache    string
+
+	// The earliest expiry of the messages buffered under each cache key.
+	pendingMut sync.Mutex
+	pending    map[string]time.Time
+
+	now func() time.Time
+	mgr *service.Resources
+}
+
+func streamJoinProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*streamJoinProc, error) {
+	p := &streamJoinProc{
+		pending: map[string]time.Time{},
+		now:     time.Now,
+		mgr:     mgr,
+	}
+
+	var err error
+	if p.side, err = conf.FieldInterpolatedString(sjFieldSide); err != nil {
+		return nil, err
+	}
+	if p.key, err = conf.FieldInterpolatedString(sjFieldKey); err != nil {
+		return nil, err
+	}
+	if p.window, err = conf.FieldDuration(sjFieldWindow); err != nil {
+		return nil, err
+	}
+	if p.window <= 0 {
+		return nil, errors.New("window must be greater than zero")
+	}
+	if p.joinType, err = conf.FieldString(sjFieldJoinType); err != nil {
+		return nil, err
+	}
+	if p.cache, err = conf.FieldString(sjFieldCache); err != nil {
+		return nil, err
+	}
+	if !mgr.HasCache(p.cache) {
+		return nil, fmt.Errorf("cache resource '%v' was not found", p.cache)
+	}
+	return p, nil
+}
+
+// streamJoinEntry is a buffered message as it is stored within the cache.
+type streamJoinEntry struct {
+	Content  []byte         `json:"content"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+	Expires  int64          `json:"expires"`
+	Matched  bool           `json:"matched,omitempty"`
+}
+
+func streamJoinCacheKey(side, key string) string {
+	return side + ":" + key
+}
+
+func streamJoinOpposite(side string) string {
+	if side == sjSideLeft {
+		return sjSideRight
+	}
+	return sjSideLeft
+}
+
+// emitsUnmatched returns whether unmatched messages of a side are emitted once
+// they expire.
+func (p *streamJoinProc) emitsUnmatched(side string) bool {
+	return p.joinType == "outer" || p.joinType == side
+}
+
+func (p *streamJoinProc) getEntries(ctx context.Context, cacheKey string) ([]streamJoinEntry, error) {
+	var entries []streamJoinEntry
+	var cacheErr error
+	if err := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		entriesBytes, err := c.Get(ctx, cacheKey)
+		if err != nil {
+			if !errors.Is(err, service.ErrKeyNotFound) {
+				cacheErr = err
+			}
+			return
+		}
+		if err := json.Unmarshal(entriesBytes, &entries); err != nil {
+			cacheErr = fmt.Errorf("failed to parse buffered messages: %w", err)
+		}
+	}); err != nil {
+		return nil, err
+	}
+	if cacheErr != nil {
+		return nil, fmt.Errorf("failed to obtain buffered messages of %v: %w", cacheKey, cacheErr)
+	}
+	return entries, nil
+}
+
+// setEntries stores the buffered messages of a cache key, or deletes the key
+// when there are none, and updates the tracked expiry of the key.
+func (p *streamJoinProc) setEntries(ctx context.Context, cacheKey string, entries []streamJoinEntry) error {
+	var entriesBytes []byte
+	if len(entries) > 0 {
+		var err error
+		if entriesBytes, err = json.Marshal(entries); err != nil {
+			return err
+		}
+	}
+
+	var ttl *time.Duration
+	if p.joinType == "inner" {
+		ttl = &p.window
+	}
+
+	var cacheErr error
+	if err := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		if len(entries) == 0 {
+			if cacheErr = c.Delete(ctx, cacheKey); errors.Is(cacheErr, service.ErrKeyNotFound) {
+				cacheErr = nil
+			}
+			return
+		}
+		cacheErr = c.Set(ctx, cacheKey, entriesBytes, ttl)
+	}); err != nil {
+		return err
+	}
+	if cacheErr != nil {
+		return fmt.Errorf("failed to store buffered messages of %v: %w", cacheKey, cacheErr)
+	}
+
+	p.pendingMut.Lock()
+	defer p.pendingMut.Unlock()
+	if len(entries) == 0 {
+		delete(p.pending, cacheKey)
+		return nil
+	}
+	earliest := entries[0].Expires
+	for _, e := range entries[1:] {
+		earliest = min(earliest, e.Expires)
+	}
+	p.pending[cacheKey] = time.Unix(0, earliest)
+	return nil
+}
+
+func streamJoinContent(b []byte) any {
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return string(b)
+	}
+	return v
+}
+
+// joinedMessage creates a message from a left and a right message, either of
+// which may be nil.
+func joinedMessage(left, right *streamJoinEntry) *service.Message {
+	msg := service.NewMessage(nil)
+	doc := map[string]any{sjSideLeft: nil, sjSideRight: nil}
+	for _, s := range []struct {
+		side  string
+		entry *streamJoinEntry
+	}{{sjSideRight, right}, {sjSideLeft, left}} {
+		if s.entry == nil {
+			continue
+		}
+		doc[s.side] = streamJoinContent(s.entry.Content)
+		for k, v := range s.entry.Metadata {
+			msg.MetaSetMut(k, v)
+		}
+	}
+	msg.SetStructuredMut(doc)
+	return msg
+}
+
+func streamJoinEntryFromMessage(msg *service.Message, expires time.Time) (streamJoinEntry, error) {
+	content, err := msg.AsBytes()
+	if err != nil {
+		return streamJoinEntry{}, err
+	}
+	e := streamJoinEntry{Content: content, Expires: expires.UnixNano()}
+	_ = msg.MetaWalkMut(func(k string, v any) error {
+		if e.Metadata == nil {
+			e.Metadata = map[string]any{}
+		}
+		e.Metadata[k] = v
+		return nil
+	})
+	return e, nil
+}
+
+// expire removes the expired messages of each tracked cache key, returning
+// the unmatched messages that should be emitted.
+func (p *streamJoinProc) expire(ctx context.Context, now time.Time) (service.MessageBatch, error) {
+	p.pendingMut.Lock()
+	var expiredKeys []string
+	for k, t := range p.pending {
+		if !t.After(now) {
+			expiredKeys = append(expiredKeys, k)
+		}
+	}
+	p.pendingMut.Unlock()
+
+	var out service.MessageBatch
+	for _, cacheKey := range expiredKeys {
+		entries, err := p.getEntries(ctx, cacheKey)
+		if err != nil {
+			return out, err
+		}
+
+		side, _, _ := strings.Cut(cacheKey, ":")
+		live := entries[:0]
+		for i := range entries {
+			if entries[i].Expires > now.UnixNano() {
+				live = append(live, entries[i])
+				continue
+			}
+			if entries[i].Matched || !p.emitsUnmatched(side) {
+				continue
+			}
+			if side == sjSideLeft {
+				out = append(out, joinedMessage(&entries[i], nil))
+			} else {
+				out = append(out, joinedMessage(nil, &entries[i]))
+			}
+		}
+		if err := p.setEntries(ctx, cacheKey, live); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+// join joins a message with the buffered messages of the opposite side and
+// buffers it.
+func (p *streamJoinProc) join(ctx context.Context, msg *service.Message, side, key string, now time.Time) (service.MessageBatch, error) {
+	entry, err := streamJoinEntryFromMessage(msg, now.Add(p.window))
+	if err != nil {
+		return nil, err
+	}
+
+	oppositeKey := streamJoinCacheKey(streamJoinOpposite(side), key)
+	opposites, err := p.getEntries(ctx, oppositeKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var out service.MessageBatch
+	matchedAny := false
+	for i := range opposites {
+		if opposites[i].Expires <= now.UnixNano() {
+			continue
+		}
+		if side == sjSideLeft {
+			out = append(out, joinedMessage(&entry, &opposites[i]))
+		} else {
+			out = append(out, joinedMessage(&opposites[i], &entry))
+		}
+		if !opposites[i].Matched {
+			opposites[i].Matched = true
+			matchedAny = true
+		}
+	}
+	if matchedAny {
+		if err := p.setEntries(ctx, oppositeKey, opposites); err != nil {
+			return nil, err
+		}
+	}
+	entry.Matched = len(out) > 0
+
+	ownKey := streamJoinCacheKey(side, key)
+	entries, err := p.getEntries(ctx, ownKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.setEntries(ctx, ownKey, append(entries, entry)); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (p *streamJoinProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	now := p.now()
+
+	out, err := p.expire(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, msg := range batch {
+		side, err := p.side.TryString(msg)
+		if err != nil {
+			msg.SetError(fmt.Errorf("side interpolation error: %w", err))
+			out = append(out, msg)
+			continue
+		}
+		if side != sjSideLeft && side != sjSideRight {
+			msg.SetError(fmt.Errorf("side must be either left or right, got %q", side))
+			out = append(out, msg)
+			continue
+		}
+		key, err := p.key.TryString(msg)
+		if err != nil {
+			msg.SetError(fmt.Errorf("key interpolation error: %w", err))
+			out = append(out, msg)
+			continue
+		}
+
+		joined, err := p.join(ctx, msg, side, key, now)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, joined...)
+	}
+
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return []service.MessageBatch{out}, nil
+}
+
+func (p *streamJoinProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,241 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func streamJoinMsg(side, content string) *service.Message {
+	msg := service.NewMessage([]byte(content))
+	msg.MetaSetMut("side", side)
+	return msg
+}
+
+func streamJoinResults(t testing.TB, proc *streamJoinProc, msgs ...*service.Message) []string {
+	t.Helper()
+
+	batches, err := proc.ProcessBatch(context.Background(), msgs)
+	require.NoError(t, err)
+
+	var results []string
+	for _, b := range batches {
+		for _, m := range b {
+			mBytes, err := m.AsBytes()
+			require.NoError(t, err)
+			results = append(results, string(mBytes))
+		}
+	}
+	return results
+}
+
+func TestStreamJoinInner(t *testing.T) {
+	conf, err := streamJoinProcConfig().ParseYAML(`
+side: ${! @side }
+key: ${! this.id }
+window: 10s
+cache: foocache
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := streamJoinProcFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.NoError(t, err)
+
+	now := time.Unix(1000, 0)
+	proc.now = func() time.Time { return now }
+
+	assert.Empty(t, streamJoinResults(t, proc,
+		streamJoinMsg("left", `{"id":"a","l":1}`),
+		streamJoinMsg("left", `{"id":"b","l":2}`),
+	))
+
+	now = now.Add(5 * time.Second)
+	assert.Equal(t, []string{
+		`{"left":{"id":"a","l":1},"right":{"id":"a","r":1}}`,
+	}, streamJoinResults(t, proc, streamJoinMsg("right", `{"id":"a","r":1}`)))
+
+	// A buffered message is joined with every message of the opposite side
+	// within the window.
+	assert.Equal(t, []string{
+		`{"left":{"id":"a","l":3},"right":{"id":"a","r":1}}`,
+		`{"left":{"id":"a","l":1},"right":{"id":"a","r":2}}`,
+		`{"left":{"id":"a","l":3},"right":{"id":"a","r":2}}`,
+	}, streamJoinResults(t, proc,
+		streamJoinMsg("left", `{"id":"a","l":3}`),
+		streamJoinMsg("right", `{"id":"a","r":2}`),
+	))
+
+	// The window of the first messages has expired, and unmatched messages
+	// of an inner join are dropped.
+	now = now.Add(6 * time.Second)
+	assert.Equal(t, []string{
+		`{"left":{"id":"a","l":3},"right":{"id":"a","r":3}}`,
+	}, streamJoinResults(t, proc,
+		streamJoinMsg("right", `{"id":"a","r":3}`),
+		streamJoinMsg("right", `{"id":"b","r":4}`),
+	))
+}
+
+func TestStreamJoinOuter(t *testing.T) {
+	for _, test := range []struct {
+		joinType string
+		expected []string
+	}{
+		{joinType: "inner"},
+		{joinType: "left", expected: []string{`{"left":{"id":"a"},"right":null}`}},
+		{joinType: "right", expected: []string{`{"left":null,"right":{"id":"b"}}`}},
+		{joinType: "outer", expected: []string{`{"left":{"id":"a"},"right":null}`, `{"left":null,"right":{"id":"b"}}`}},
+	} {
+		test := test
+		t.Run(test.joinType, func(t *testing.T) {
+			conf, err := streamJoinProcConfig().ParseYAML(`
+side: ${! @side }
+key: ${! this.id }
+window: 10s
+join_type: `+test.joinType+`
+cache: foocache
+`, nil)
+			require.NoError(t, err)
+
+			proc, err := streamJoinProcFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+			require.NoError(t, err)
+
+			now := time.Unix(1000, 0)
+			proc.now = func() time.Time { return now }
+
+			assert.Empty(t, streamJoinResults(t, proc,
+				streamJoinMsg("left", `{"id":"a"}`),
+				streamJoinMsg("left", `{"id":"c"}`),
+			))
+			now = now.Add(time.Second)
+			assert.Empty(t, streamJoinResults(t, proc, streamJoinMsg("right", `{"id":"b"}`)))
+			assert.Len(t, streamJoinResults(t, proc, streamJoinMsg("right", `{"id":"c"}`)), 1)
+
+			// Expired messages are emitted with the next batch, and matched
+			// messages are never emitted as unmatched.
+			now = now.Add(20 * time.Second)
+			results := streamJoinResults(t, proc)
+			assert.ElementsMatch(t, test.expected, results)
+
+			assert.Empty(t, streamJoinResults(t, proc))
+			assert.Empty(t, proc.pending)
+		})
+	}
+}
+
+func TestStreamJoinMetadata(t *testing.T) {
+	conf, err := streamJoinProcConfig().ParseYAML(`
+side: ${! @side }
+key: ${! @key }
+window: 10s
+cache: foocache
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := streamJoinProcFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.NoError(t, err)
+
+	now := time.Unix(1000, 0)
+	proc.now = func() time.Time { return now }
+
+	left := streamJoinMsg("left", `{"id":"a"}`)
+	left.MetaSetMut("key", "a")
+	left.MetaSetMut("foo", "from left")
+	left.MetaSetMut("bar", "from left")
+	_, err = proc.ProcessBatch(context.Background(), service.MessageBatch{left})
+	require.NoError(t, err)
+
+	right := streamJoinMsg("right", `not json`)
+	right.MetaSetMut("key", "a")
+	right.MetaSetMut("foo", "from right")
+	right.MetaSetMut("baz", "from right")
+	batches, err := proc.ProcessBatch(context.Background(), service.MessageBatch{right})
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	require.Len(t, batches[0], 1)
+
+	mBytes, err := batches[0][0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"left":{"id":"a"},"right":"not json"}`, string(mBytes))
+
+	meta := map[string]any{}
+	_ = batches[0][0].MetaWalkMut(func(k string, v any) error {
+		meta[k] = v
+		return nil
+	})
+	assert.Equal(t, map[string]any{
+		"side": "left",
+		"key":  "a",
+		"foo":  "from left",
+		"bar":  "from left",
+		"baz":  "from right",
+	}, meta)
+}
+
+func TestStreamJoinBadSide(t *testing.T) {
+	conf, err := streamJoinProcConfig().ParseYAML(`
+side: ${! @side }
+key: ${! this.id }
+window: 10s
+cache: foocache
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := streamJoinProcFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.NoError(t, err)
+
+	now := time.Unix(1000, 0)
+	proc.now = func() time.Time { return now }
+
+	batches, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		streamJoinMsg("middle", `{"id":"a"}`),
+		streamJoinMsg("left", `{"id":"a"}`),
+	})
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	require.Len(t, batches[0], 1)
+	require.Error(t, batches[0][0].GetError())
+	assert.Contains(t, batches[0][0].GetError().Error(), "side must be either left or right")
+}
+
+func TestStreamJoinConfigErrors(t *testing.T) {
+	for _, conf := range []string{
+		`
+side: ${! @side }
+key: ${! this.id }
+window: 10s
+cache: nope
+`,
+		`
+side: ${! @side }
+key: ${! this.id }
+window: 0s
+cache: foocache
+`,
+	} {
+		pConf, err := streamJoinProcConfig().ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		_, err = streamJoinProcFromParsed(pConf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+		require.Error(t, err)
+	}
+}
@@ -0,0 +1,407 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	tokFieldMode       = "mode"
+	tokFieldField      = "field"
+	tokFieldTarget     = "target"
+	tokFieldLowercase  = "lowercase"
+	tokFieldPattern    = "pattern"
+	tokFieldNgramSize  = "ngram_size"
+	tokFieldNgramUnit  = "ngram_unit"
+	tokFieldMergesFile = "merges_file"
+	tokFieldVocabFile  = "vocab_file"
+	tokFieldUnkToken   = "unk_token"
+)
+
+func tokenizeProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Parsing").
+		Version("4.31.0").
+		Summary("Splits text into an array of tokens, for generating features for machine learning models within a pipeline.").
+		Description(`
+The text is read from the raw contents of messages, or from the string at the path `+"`field`"+` of structured messages, and the resulting array of tokens replaces the whole message unless a `+"`target`"+` path of a structured message is set.
+
+== Byte pair encoding
+
+With the mode `+"`bpe`"+` the text is first split by whitespace into words, and each word is then split into characters which are merged into subwords according to the ranked pairs of the merges file. The merges file contains a pair of symbols separated by a space per line in order of priority, and lines beginning with `+"`#`"+` are ignored, which matches the `+"`merges.txt`"+` format produced by most BPE trainers.
+
+When a `+"`vocab_file`"+` is also specified, which must contain a JSON object of tokens to their IDs, the resulting array contains the ID of each token instead, and tokens missing from the vocabulary are replaced by the ID of the `+"`unk_token`"+`.`).
+		Field(service.NewStringAnnotatedEnumField(tokFieldMode, map[string]string{
+			"whitespace": "Split the text by whitespace.",
+			"regex":      "Emit each match of the regular expression `pattern` as a token.",
+			"ngram":      "Emit each sequence of `ngram_size` consecutive words or characters as a token.",
+			"bpe":        "Split words into subwords with byte pair encoding according to `merges_file`.",
+		}).
+			Description("The method of tokenization.").
+			Default("whitespace")).
+		Field(service.NewStringField(tokFieldField).
+			Description("An optional xref:configuration:field_paths.adoc[dot separated path] to a string field to tokenize instead of the raw contents.").
+			Default("").
+			Example("text")).
+		Field(service.NewStringField(tokFieldTarget).
+			Description("An optional dot separated path to store the array of tokens at, when empty the array replaces the whole message.").
+			Default("").
+			Example("tokens")).
+		Field(service.NewBoolField(tokFieldLowercase).
+			Description("Whether to convert the text to lowercase before it is tokenized.").
+			Default(false)).
+		Field(service.NewStringField(tokFieldPattern).
+			Description("The regular expression to match tokens with when the mode is `regex`.").
+			Default(`\w+`).
+			Advanced()).
+		Field(service.NewIntField(tokFieldNgramSize).
+			Description("The number of words or characters in each token when the mode is `ngram`.").
+			Default(2)).
+		Field(service.NewStringEnumField(tokFieldNgramUnit, "word", "char").
+			Description("Whether n-grams are made of words, which are joined with a space, or characters.").
+			Default("word").
+			Advanced()).
+		Field(service.NewStringField(tokFieldMergesFile).
+			Description("The path of a file of BPE merges, which is required when the mode is `bpe`.").
+			Default("").
+			Example("./model/merges.txt")).
+		Field(service.NewStringField(tokFieldVocabFile).
+			Description("An optional path of a JSON vocabulary file mapping BPE tokens to IDs.").
+			Default("").
+			Example("./model/vocab.json")).
+		Field(service.NewStringField(tokFieldUnkToken).
+			Description("The vocabulary token used for BPE tokens that are not within the vocabulary.").
+			Default("<unk>").
+			Advanced()).
+		Example("Word bigrams", "Add lowercase word bigrams of the field `text` of documents as the field `features`.", `
+pipeline:
+  processors:
+    - tokenize:
+        mode: ngram
+        ngram_size: 2
+        lowercase: true
+        field: text
+        target: features
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"tokenize", tokenizeProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return tokenizeProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type tokenizeProc struct {
+	field     string
+	target    string
+	lowercase bool
+	tokenize  func(text string) ([]any, error)
+}
+
+func tokenizeProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*tokenizeProc, error) {
+	p := &tokenizeProc{}
+
+	var err error
+	if p.field, err = conf.FieldString(tokFieldField); err != nil {
+		return nil, err
+	}
+	if p.target, err = conf.FieldString(tokFieldTarget); err != nil {
+		return nil, err
+	}
+	if p.lowercase, err = conf.FieldBool(tokFieldLowercase); err != nil {
+		return nil, err
+	}
+
+	mode, err := conf.FieldString(tokFieldMode)
+	if err != nil {
+		return nil, err
+	}
+	switch mode {
+	case "whitespace":
+		p.tokenize = func(text string) ([]any, error) {
+			return stringsToAny(strings.Fields(text)), nil
+		}
+	case "regex":
+		patternStr, err := conf.FieldString(tokFieldPattern)
+		if err != nil {
+			return nil, err
+		}
+		pattern, err := regexp.Compile(patternStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile pattern: %w", err)
+		}
+		p.tokenize = func(text string) ([]any, error) {
+			return stringsToAny(pattern.FindAllString(text, -1)), nil
+		}
+	case "ngram":
+		size, err := conf.FieldInt(tokFieldNgramSize)
+		if err != nil {
+			return nil, err
+		}
+		if size < 1 {
+			return nil, fmt.Errorf("ngram_size must be greater than zero, got %v", size)
+		}
+		unit, err := conf.FieldString(tokFieldNgramUnit)
+		if err != nil {
+			return nil, err
+		}
+		p.tokenize = func(text string) ([]any, error) {
+			if unit == "char" {
+				return stringsToAny(charNgrams(text, size)), nil
+			}
+			return stringsToAny(wordNgrams(strings.Fields(text), size)), nil
+		}
+	case "bpe":
+		enc, err := bpeEncoderFromParsed(conf, mgr)
+		if err != nil {
+			return nil, err
+		}
+		p.tokenize = enc.encode
+	default:
+		return nil, fmt.Errorf("mode not recognised: %v", mode)
+	}
+	return p, nil
+}
+
+func stringsToAny(s []string) []any {
+	res := make([]any, len(s))
+	for i, v := range s {
+		res[i] = v
+	}
+	return res
+}
+
+func wordNgrams(words []string, n int) []string {
+	if len(words) < n {
+		return []string{}
+	}
+	res := make([]string, 0, len(words)-n+1)
+	for i := 0; i+n <= len(words); i++ {
+		res = append(res, strings.Join(words[i:i+n], " "))
+	}
+	return res
+}
+
+func charNgrams(text string, n int) []string {
+	runes := []rune(text)
+	if len(runes) < n {
+		return []string{}
+	}
+	res := make([]string, 0, len(runes)-n+1)
+	for i := 0; i+n <= len(runes); i++ {
+		res = append(res, string(runes[i:i+n]))
+	}
+	return res
+}
+
+//------------------------------------------------------------------------------
+
+type bpePair struct {
+	left, right string
+}
+
+type bpeEncoder struct {
+	ranks map[bpePair]int
+	vocab map[string]int
+	unkID int
+}
+
+func bpeEncoderFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*bpeEncoder, error) {
+	mergesPath, err := conf.FieldString(tokFieldMergesFile)
+	if err != nil {
+		return nil, err
+	}
+	if mergesPath == "" {
+		return nil, errors.New("a merges_file must be specified when the mode is bpe")
+	}
+	mergesBytes, err := service.ReadFile(mgr.FS(), mergesPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read merges file: %w", err)
+	}
+
+	enc := &bpeEncoder{ranks: map[bpePair]int{}}
+	scanner := bufio.NewScanner(bytes.NewReader(mergesBytes))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.Fields(line)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("merges file line %v: expected two symbols, got %v", lineNum, len(parts))
+		}
+		pair := bpePair{left: parts[0], right: parts[1]}
+		if _, exists := enc.ranks[pair]; !exists {
+			enc.ranks[pair] = len(enc.ranks)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read merges file: %w", err)
+	}
+
+	vocabPath, err := conf.FieldString(tokFieldVocabFile)
+	if err != nil {
+		return nil, err
+	}
+	if vocabPath == "" {
+		return enc, nil
+	}
+
+	vocabBytes, err := service.ReadFile(mgr.FS(), vocabPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vocab file: %w", err)
+	}
+	if err := json.Unmarshal(vocabBytes, &enc.vocab); err != nil {
+		return nil, fmt.Errorf("failed to parse vocab file: %w", err)
+	}
+
+	unkToken, err := conf.FieldString(tokFieldUnkToken)
+	if err != nil {
+		return nil, err
+	}
+	var exists bool
+	if enc.unkID, exists = enc.vocab[unkToken]; !exists {
+		return nil, fmt.Errorf("unk_token %q is not present in the vocab file", unkToken)
+	}
+	return enc, nil
+}
+
+// mergeWord splits a word into characters and repeatedly merges the adjacent
+// pair of symbols with the lowest rank until no ranked pairs remain.
+func (b *bpeEncoder) mergeWord(word string) []string {
+	symbols := make([]string, 0, len(word))
+	for _, r := range word {
+		symbols = append(symbols, string(r))
+	}
+
+	for len(symbols) > 1 {
+		bestRank := -1
+		var best bpePair
+		for i := 0; i < len(symbols)-1; i++ {
+			pair := bpePair{left: symbols[i], right: symbols[i+1]}
+			if rank, exists := b.ranks[pair]; exists && (bestRank == -1 || rank < bestRank) {
+				bestRank, best = rank, pair
+			}
+		}
+		if bestRank == -1 {
+			break
+		}
+
+		merged := symbols[:0]
+		for i := 0; i < len(symbols); i++ {
+			if i < len(symbols)-1 && symbols[i] == best.left && symbols[i+1] == best.right {
+				merged = append(merged, best.left+best.right)
+				i++
+				continue
+			}
+			merged = append(merged, symbols[i])
+		}
+		symbols = merged
+	}
+	return symbols
+}
+
+func (b *bpeEncoder) encode(text string) ([]any, error) {
+	res := []any{}
+	for _, word := range strings.Fields(text) {
+		for _, token := range b.mergeWord(word) {
+			if b.vocab == nil {
+				res = append(res, token)
+				continue
+			}
+			id, exists := b.vocab[token]
+			if !exists {
+				id = b.unkID
+			}
+			res = append(res, id)
+		}
+	}
+	return res, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (p *tokenizeProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	var text string
+	var gObj *gabs.Container
+	if p.field == "" {
+		mBytes, err := msg.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		text = string(mBytes)
+	} else {
+		v, err := msg.AsStructuredMut()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse message: %w", err)
+		}
+		gObj = gabs.Wrap(v)
+		if !gObj.ExistsP(p.field) {
+			return nil, fmt.Errorf("field %v not found", p.field)
+		}
+		var ok bool
+		if text, ok = gObj.Path(p.field).Data().(string); !ok {
+			return nil, fmt.Errorf("field %v is not a string, got %T", p.field, gObj.Path(p.field).Data())
+		}
+	}
+
+	if p.lowercase {
+		text = strings.ToLower(text)
+	}
+	tokens, err := p.tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.target == "" {
+		msg.SetStructuredMut(tokens)
+		return service.MessageBatch{msg}, nil
+	}
+
+	if gObj == nil {
+		v, err := msg.AsStructuredMut()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse message: %w", err)
+		}
+		gObj = gabs.Wrap(v)
+	}
+	if _, err := gObj.SetP(tokens, p.target); err != nil {
+		return nil, fmt.Errorf("failed to set target %v: %w", p.target, err)
+	}
+	msg.SetStructuredMut(gObj.Data())
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *tokenizeProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,168 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestTokenizeModes(t *testing.T) {
+	tests := []struct {
+		name     string
+		conf     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "whitespace",
+			conf:     `mode: whitespace`,
+			input:    "The quick  brown\tfox",
+			expected: `["The","quick","brown","fox"]`,
+		},
+		{
+			name:     "regex lowercase",
+			conf:     "mode: regex\nlowercase: true",
+			input:    "Hello, World! It's 2024.",
+			expected: `["hello","world","it","s","2024"]`,
+		},
+		{
+			name:     "word bigrams",
+			conf:     "mode: ngram",
+			input:    "a b c d",
+			expected: `["a b","b c","c d"]`,
+		},
+		{
+			name:     "char trigrams",
+			conf:     "mode: ngram\nngram_size: 3\nngram_unit: char",
+			input:    "café",
+			expected: `["caf","afé"]`,
+		},
+		{
+			name:     "ngram too short",
+			conf:     "mode: ngram\nngram_size: 3",
+			input:    "a b",
+			expected: `[]`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			pConf, err := tokenizeProcConfig().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			proc, err := tokenizeProcFromParsed(pConf, service.MockResources())
+			require.NoError(t, err)
+
+			batch, err := proc.Process(context.Background(), service.NewMessage([]byte(test.input)))
+			require.NoError(t, err)
+			require.Len(t, batch, 1)
+
+			mBytes, err := batch[0].AsBytes()
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, string(mBytes))
+		})
+	}
+}
+
+func TestTokenizeFieldTarget(t *testing.T) {
+	conf, err := tokenizeProcConfig().ParseYAML(`
+field: doc.text
+target: doc.tokens
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := tokenizeProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	batch, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"doc":{"text":"foo bar"}}`)))
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	mBytes, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"doc":{"text":"foo bar","tokens":["foo","bar"]}}`, string(mBytes))
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"doc":{"text":5}}`)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not a string")
+}
+
+func TestTokenizeBPE(t *testing.T) {
+	dir := t.TempDir()
+
+	mergesPath := filepath.Join(dir, "merges.txt")
+	require.NoError(t, os.WriteFile(mergesPath, []byte(`#version: 0.2
+l o
+lo w
+e r
+low e
+`), 0o644))
+
+	vocabPath := filepath.Join(dir, "vocab.json")
+	require.NoError(t, os.WriteFile(vocabPath, []byte(`{"<unk>":0,"low":1,"er":2,"lowe":3,"s":4,"t":5}`), 0o644))
+
+	input := "lower lowest newer"
+
+	conf, err := tokenizeProcConfig().ParseYAML(`
+mode: bpe
+merges_file: `+mergesPath+`
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := tokenizeProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	batch, err := proc.Process(context.Background(), service.NewMessage([]byte(input)))
+	require.NoError(t, err)
+
+	mBytes, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `["low","er","lowe","s","t","n","e","w","er"]`, string(mBytes))
+
+	pConf, err := tokenizeProcConfig().ParseYAML(`
+mode: bpe
+merges_file: `+mergesPath+`
+vocab_file: `+vocabPath+`
+`, nil)
+	require.NoError(t, err)
+
+	proc, err = tokenizeProcFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	batch, err = proc.Process(context.Background(), service.NewMessage([]byte(input)))
+	require.NoError(t, err)
+
+	mBytes, err = batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `[1,2,3,4,5,0,0,0,2]`, string(mBytes))
+}
+
+func TestTokenizeBPEMissingMerges(t *testing.T) {
+	pConf, err := tokenizeProcConfig().ParseYAML(`mode: bpe`, nil)
+	require.NoError(t, err)
+
+	_, err = tokenizeProcFromParsed(pConf, service.MockResources())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "merges_file must be specified")
+}
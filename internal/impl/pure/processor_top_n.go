@@ -0,0 +1,264 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	topNFieldKey      = "key"
+	topNFieldScore    = "score"
+	topNFieldN        = "n"
+	topNFieldInterval = "interval"
+)
+
+func topNProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Maintains the top N keys by score from a stream of messages and emits them as a ranked list.").
+		Description(`
+Each message provides a key and a score, where the score of a key is replaced by the score of each subsequent message with the same key. Messages are consumed and replaced with a single message containing the current top `+"`n`"+` keys ordered by score descending, with ties ordered by key:
+
+`+"```json"+`
+[
+  {"rank":1,"key":"foo","score":35},
+  {"rank":2,"key":"bar","score":12.5}
+]
+`+"```"+`
+
+When an `+"`interval`"+` is set messages are dropped after updating the scores, and the top keys are instead emitted at most once per interval. Since processors only run when a message arrives the top keys are emitted with the first message that arrives after each interval has passed.
+
+=== Memory
+
+Only the top `+"`n`"+` keys are held in memory, and the key with the lowest score is evicted when a key that isn't held arrives with a higher score. A consequence of this is that when the score of a held key decreases it may remain in the top keys above keys that were previously evicted, until those keys are seen again.
+
+The top keys are held per instance of this processor, and therefore when a pipeline has multiple threads each thread maintains its own top keys. In order to rank an entire stream place this processor within the `+"`processors`"+` of the input, or set the number of pipeline threads to one.`).
+		Field(service.NewInterpolatedStringField(topNFieldKey).
+			Description("The key to rank.").
+			Example("${! this.article_id }")).
+		Field(service.NewInterpolatedStringField(topNFieldScore).
+			Description("The score of the key, which must resolve to a number.").
+			Example("${! this.views }")).
+		Field(service.NewIntField(topNFieldN).
+			Description("The number of top keys to maintain.").
+			Example(10)).
+		Field(service.NewDurationField(topNFieldInterval).
+			Description("An optional interval at which to emit the top keys. When not set the top keys are emitted for every message.").
+			Optional().
+			Example("10s")).
+		Example("Trending articles", "Emit the ten most viewed articles from a stream of view counts every five seconds.", `
+pipeline:
+  threads: 1
+  processors:
+    - top_n:
+        key: ${! this.article_id }
+        score: ${! this.views }
+        n: 10
+        interval: 5s
+
+output:
+  http_server:
+    ws_path: /trending
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"top_n", topNProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return topNProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type topNEntry struct {
+	key   string
+	score float64
+	index int
+}
+
+// topNHeap is a min heap of entries ordered by score, where the entry at the
+// root is the next to be evicted.
+type topNHeap []*topNEntry
+
+func (h topNHeap) Len() int { return len(h) }
+
+func (h topNHeap) Less(i, j int) bool {
+	if h[i].score == h[j].score {
+		return h[i].key > h[j].key
+	}
+	return h[i].score < h[j].score
+}
+
+func (h topNHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *topNHeap) Push(x any) {
+	e := x.(*topNEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *topNHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
+
+type topNProc struct {
+	key      *service.InterpolatedString
+	score    *service.InterpolatedString
+	n        int
+	interval time.Duration
+
+	mut      sync.Mutex
+	heap     topNHeap
+	entries  map[string]*topNEntry
+	lastEmit time.Time
+
+	now func() time.Time
+}
+
+func topNProcFromParsed(conf *service.ParsedConfig) (*topNProc, error) {
+	p := &topNProc{
+		entries: map[string]*topNEntry{},
+		now:     time.Now,
+	}
+
+	var err error
+	if p.key, err = conf.FieldInterpolatedString(topNFieldKey); err != nil {
+		return nil, err
+	}
+	if p.score, err = conf.FieldInterpolatedString(topNFieldScore); err != nil {
+		return nil, err
+	}
+	if p.n, err = conf.FieldInt(topNFieldN); err != nil {
+		return nil, err
+	}
+	if p.n < 1 {
+		return nil, errors.New("n must be greater than zero")
+	}
+	if conf.Contains(topNFieldInterval) {
+		if p.interval, err = conf.FieldDuration(topNFieldInterval); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// update sets the score of a key, evicting the key with the lowest score when
+// the key is new and the top keys are full.
+func (p *topNProc) update(key string, score float64) {
+	if e, exists := p.entries[key]; exists {
+		e.score = score
+		heap.Fix(&p.heap, e.index)
+		return
+	}
+	if len(p.heap) < p.n {
+		e := &topNEntry{key: key, score: score}
+		heap.Push(&p.heap, e)
+		p.entries[key] = e
+		return
+	}
+	lowest := p.heap[0]
+	if score < lowest.score || (score == lowest.score && key > lowest.key) {
+		return
+	}
+	delete(p.entries, lowest.key)
+	lowest.key, lowest.score = key, score
+	p.entries[key] = lowest
+	heap.Fix(&p.heap, 0)
+}
+
+// ranked returns the top keys ordered by rank.
+func (p *topNProc) ranked() []any {
+	sorted := make([]*topNEntry, len(p.heap))
+	copy(sorted, p.heap)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].score == sorted[j].score {
+			return sorted[i].key < sorted[j].key
+		}
+		return sorted[i].score > sorted[j].score
+	})
+
+	ranked := make([]any, len(sorted))
+	for i, e := range sorted {
+		ranked[i] = map[string]any{
+			"rank":  i + 1,
+			"key":   e.key,
+			"score": e.score,
+		}
+	}
+	return ranked
+}
+
+func (p *topNProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	key, err := p.key.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("key interpolation error: %w", err)
+	}
+	scoreStr, err := p.score.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("score interpolation error: %w", err)
+	}
+	score, err := strconv.ParseFloat(scoreStr, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse score: %w", err)
+	}
+	if math.IsNaN(score) {
+		return nil, errors.New("score must be a number, got NaN")
+	}
+
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	p.update(key, score)
+
+	if p.interval > 0 {
+		now := p.now()
+		if now.Sub(p.lastEmit) < p.interval {
+			return nil, nil
+		}
+		p.lastEmit = now
+	}
+
+	out := service.NewMessage(nil)
+	out.SetStructuredMut(p.ranked())
+	return service.MessageBatch{out}, nil
+}
+
+func (p *topNProc) Close(ctx context.Context) error {
+	return nil
+}
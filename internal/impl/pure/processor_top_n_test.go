@@ -0,0 +1,154 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func topNProcess(t testing.TB, proc *topNProc, content string) service.MessageBatch {
+	t.Helper()
+
+	batch, err := proc.Process(context.Background(), service.NewMessage([]byte(content)))
+	require.NoError(t, err)
+	return batch
+}
+
+func TestTopNRanking(t *testing.T) {
+	conf, err := topNProcConfig().ParseYAML(`
+key: ${! this.id }
+score: ${! this.score }
+n: 3
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := topNProcFromParsed(conf)
+	require.NoError(t, err)
+
+	for _, content := range []string{
+		`{"id":"a","score":5}`,
+		`{"id":"b","score":10}`,
+		`{"id":"c","score":1}`,
+		`{"id":"d","score":7}`,
+		`{"id":"e","score":0.5}`,
+		`{"id":"a","score":12}`,
+	} {
+		topNProcess(t, proc, content)
+	}
+
+	batch := topNProcess(t, proc, `{"id":"f","score":7}`)
+	require.Len(t, batch, 1)
+
+	mBytes, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+  {"rank":1,"key":"a","score":12},
+  {"rank":2,"key":"b","score":10},
+  {"rank":3,"key":"d","score":7}
+]`, string(mBytes))
+
+	assert.Len(t, proc.entries, 3)
+	assert.Len(t, proc.heap, 3)
+}
+
+func TestTopNScoreDecrease(t *testing.T) {
+	conf, err := topNProcConfig().ParseYAML(`
+key: ${! this.id }
+score: ${! this.score }
+n: 2
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := topNProcFromParsed(conf)
+	require.NoError(t, err)
+
+	topNProcess(t, proc, `{"id":"a","score":5}`)
+	topNProcess(t, proc, `{"id":"b","score":10}`)
+	topNProcess(t, proc, `{"id":"b","score":1}`)
+
+	batch := topNProcess(t, proc, `{"id":"c","score":3}`)
+	require.Len(t, batch, 1)
+
+	mBytes, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+  {"rank":1,"key":"a","score":5},
+  {"rank":2,"key":"c","score":3}
+]`, string(mBytes))
+}
+
+func TestTopNInterval(t *testing.T) {
+	conf, err := topNProcConfig().ParseYAML(`
+key: ${! this.id }
+score: ${! this.score }
+n: 5
+interval: 10s
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := topNProcFromParsed(conf)
+	require.NoError(t, err)
+
+	now := time.Unix(1000, 0)
+	proc.now = func() time.Time { return now }
+
+	assert.Len(t, topNProcess(t, proc, `{"id":"a","score":1}`), 1)
+	assert.Empty(t, topNProcess(t, proc, `{"id":"b","score":2}`))
+
+	now = now.Add(9 * time.Second)
+	assert.Empty(t, topNProcess(t, proc, `{"id":"c","score":3}`))
+
+	now = now.Add(time.Second)
+	batch := topNProcess(t, proc, `{"id":"d","score":4}`)
+	require.Len(t, batch, 1)
+
+	v, err := batch[0].AsStructured()
+	require.NoError(t, err)
+	assert.Len(t, v, 4)
+}
+
+func TestTopNBadScore(t *testing.T) {
+	conf, err := topNProcConfig().ParseYAML(`
+key: ${! this.id }
+score: ${! this.score }
+n: 5
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := topNProcFromParsed(conf)
+	require.NoError(t, err)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"id":"a","score":"lots"}`)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse score")
+	assert.Empty(t, proc.entries)
+
+	pConf, err := topNProcConfig().ParseYAML(`
+key: foo
+score: "1"
+n: 0
+`, nil)
+	require.NoError(t, err)
+
+	_, err = topNProcFromParsed(pConf)
+	require.Error(t, err)
+}
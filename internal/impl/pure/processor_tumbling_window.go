@@ -0,0 +1,535 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	twFieldWindow          = "window"
+	twFieldGroupBy         = "group_by"
+	twFieldTimestamp       = "timestamp"
+	twFieldAllowedLateness = "allowed_lateness"
+	twFieldLateMessages    = "late_messages"
+	twFieldAggregations    = "aggregations"
+	twFieldAggType         = "type"
+	twFieldAggValue        = "value"
+	twFieldCache           = "cache"
+	twFieldCacheKey        = "cache_key"
+)
+
+func tumblingWindowProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Aggregates messages into fixed, non-overlapping windows of time grouped by a key, and emits a message with the aggregations of each group when its window closes.").
+		Description(`
+Windows are aligned to wall-clock boundaries, and therefore a window of `+"`1m`"+` always starts at the beginning of a minute. Messages are assigned to the window of their time, which is resolved from `+"`timestamp`"+` when specified, and must be either an RFC 3339 timestamp or a unix timestamp in seconds, and is otherwise the time that the message is processed. Messages are consumed by the aggregations and then dropped.
+
+A window closes once the current time has passed the end of the window plus `+"`allowed_lateness`"+`, and then a message is emitted for each group of the window with the results of each aggregation:
+
+`+"```json"+`
+{"key":"eu-west","window_start":"2024-01-02T03:04:00Z","window_end":"2024-01-02T03:05:00Z","requests":1834,"latency_avg":41.2}
+`+"```"+`
+
+Closed windows are detected when a batch is processed, and therefore their results are emitted along with the results of the next batch to arrive after they closed.
+
+== Aggregations
+
+Each aggregation executes its Bloblang query `+"`value`"+` against every message, and messages where the query fails or returns `+"`null`"+` are skipped by the aggregation. The `+"`sum`"+`, `+"`avg`"+`, `+"`min`"+` and `+"`max`"+` aggregations also skip values that are not numbers. When a group has no values for an aggregation its result is `+"`null`"+`, apart from `+"`count`"+` and `+"`sum`"+` which are zero.
+
+== Late messages
+
+Messages whose time falls within a window that has already closed are late, and are either dropped or flagged as failed according to `+"`late_messages`"+`. Failed messages are passed through unchanged, and can be routed elsewhere using xref:configuration:error_handling.adoc[error handling methods]. Late messages only occur when `+"`timestamp`"+` is specified.
+
+== State
+
+The state of each window holds a fixed number of values per group, and therefore the memory used is bounded by the number of distinct groups within the open windows. Each instance of this processor has its own windows, and when a pipeline has multiple threads each thread has its own instance. Messages are distributed across threads regardless of their group, and so each thread emits a partial result for the same group and window, which would need to be combined downstream. In order to emit a single result per group and window define this processor as a xref:configuration:resources.adoc[processor resource], and reference it from the pipeline with the `+"xref:components:processors/resource.adoc[`resource` processor]"+`, so that all threads aggregate into the same windows.
+
+Processors are unable to emit messages when they are closed, and therefore when a `+"`cache`"+` is configured the open windows are stored within it when the pipeline shuts down, and are restored when the next batch is processed after a restart. Any windows that closed in the meantime are then emitted. Without a cache the open windows are discarded when the pipeline shuts down. Instances of the processor must not store their windows under the same `+"`cache_key`"+`, as they overwrite each other, which is another reason to share a single instance between threads as a resource.`).
+		Field(service.NewDurationField(twFieldWindow).
+			Description("The duration of each window.").
+			Example("1m").
+			Example("1h")).
+		Field(service.NewInterpolatedStringField(twFieldGroupBy).
+			Description("An interpolated string that resolves to the key to group messages by within each window.").
+			Example(`${! this.region }`).
+			Default("")).
+		Field(service.NewInterpolatedStringField(twFieldTimestamp).
+			Description("An optional interpolated string that resolves to the time of each message.").
+			Example(`${! this.event_time }`).
+			Optional()).
+		Field(service.NewDurationField(twFieldAllowedLateness).
+			Description("The period of time after the end of a window during which messages of the window are still accepted.").
+			Default("0s")).
+		Field(service.NewStringAnnotatedEnumField(twFieldLateMessages, map[string]string{
+			"drop":  "Drop late messages.",
+			"error": "Flag late messages as failed and pass them through.",
+		}).
+			Description("What to do with messages that arrive after their window has closed.").
+			Default("drop")).
+		Field(service.NewObjectMapField(twFieldAggregations,
+			service.NewStringAnnotatedEnumField(twFieldAggType, map[string]string{
+				"count": "The number of values.",
+				"sum":   "The sum of numeric values.",
+				"avg":   "The mean of numeric values.",
+				"min":   "The minimum of numeric values.",
+				"max":   "The maximum of numeric values.",
+				"last":  "The last value.",
+			}).
+				Description("The type of aggregation."),
+			service.NewBloblangField(twFieldAggValue).
+				Description("A xref:guides:bloblang/about.adoc[Bloblang query] that resolves to the value to aggregate. When empty the `count` aggregation counts every message, and all other types of aggregation require a query.").
+				Example("root = this.latency_ms").
+				Default(""),
+		).
+			Description("A map of aggregations, where each key is the name of a field of the emitted messages.").
+			Example(map[string]any{
+				"requests":    map[string]any{"type": "count"},
+				"latency_avg": map[string]any{"type": "avg", "value": "root = this.latency_ms"},
+			})).
+		Field(service.NewStringField(twFieldCache).
+			Description("An optional xref:components:caches/about.adoc[cache resource] to store the open windows within when the pipeline shuts down.").
+			Optional()).
+		Field(service.NewStringField(twFieldCacheKey).
+			Description("The key of the cache to store the open windows under.").
+			Default("tumbling_window_state").
+			Advanced()).
+		Example("Request rollups", "Roll up HTTP request logs into the number of requests, mean latency and maximum latency of each endpoint per minute, accepting requests that arrive up to ten seconds late.", `
+pipeline:
+  threads: 1
+  processors:
+    - tumbling_window:
+        window: 1m
+        group_by: ${! this.endpoint }
+        timestamp: ${! this.time }
+        allowed_lateness: 10s
+        aggregations:
+          requests:
+            type: count
+          latency_avg:
+            type: avg
+            value: root = this.latency_ms
+          latency_max:
+            type: max
+            value: root = this.latency_ms
+        cache: rollups
+
+cache_resources:
+  - label: rollups
+    file:
+      directory: ./state
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"tumbling_window", tumblingWindowProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return tumblingWindowProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type twAggregation struct {
+	name  string
+	typ   string
+	value *bloblang.Executor
+}
+
+// twAggState is the state of an aggregation for a group of a window, where
+// count is the number of values accepted by the aggregation.
+type twAggState struct {
+	Count int64   `json:"count"`
+	Sum   float64 `json:"sum"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Last  any     `json:"last,omitempty"`
+}
+
+type twGroup struct {
+	Start time.Time    `json:"start"`
+	Key   string       `json:"key"`
+	Aggs  []twAggState `json:"aggs"`
+}
+
+type tumblingWindowProc struct {
+	window       time.Duration
+	groupBy      *service.InterpolatedString
+	timestamp    *service.InterpolatedString
+	lateness     time.Duration
+	errorOnLate  bool
+	aggregations []twAggregation
+	cache        string
+	cacheKey     string
+
+	mgr *service.Resources
+	log *service.Logger
+	now func() time.Time
+
+	mut      sync.Mutex
+	groups   map[string]*twGroup
+	restored bool
+}
+
+// twOptionalBloblang parses a bloblang field that may be left empty, in which
+// case a nil executor is returned.
+func twOptionalBloblang(conf *service.ParsedConfig, field string) (*bloblang.Executor, error) {
+	str, err := conf.FieldString(field)
+	if err != nil || str == "" {
+		return nil, err
+	}
+	return conf.FieldBloblang(field)
+}
+
+func tumblingWindowProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*tumblingWindowProc, error) {
+	p := &tumblingWindowProc{
+		mgr:    mgr,
+		log:    mgr.Logger(),
+		now:    time.Now,
+		groups: map[string]*twGroup{},
+	}
+
+	var err error
+	if p.window, err = conf.FieldDuration(twFieldWindow); err != nil {
+		return nil, err
+	}
+	if p.window <= 0 {
+		return nil, errors.New("window must be greater than zero")
+	}
+	if p.groupBy, err = conf.FieldInterpolatedString(twFieldGroupBy); err != nil {
+		return nil, err
+	}
+	if conf.Contains(twFieldTimestamp) {
+		if p.timestamp, err = conf.FieldInterpolatedString(twFieldTimestamp); err != nil {
+			return nil, err
+		}
+	}
+	if p.lateness, err = conf.FieldDuration(twFieldAllowedLateness); err != nil {
+		return nil, err
+	}
+	lateMessages, err := conf.FieldString(twFieldLateMessages)
+	if err != nil {
+		return nil, err
+	}
+	p.errorOnLate = lateMessages == "error"
+
+	aggConfs, err := conf.FieldObjectMap(twFieldAggregations)
+	if err != nil {
+		return nil, err
+	}
+	if len(aggConfs) == 0 {
+		return nil, errors.New("at least one aggregation must be specified")
+	}
+	names := make([]string, 0, len(aggConfs))
+	for name := range aggConfs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		aConf := aggConfs[name]
+		agg := twAggregation{name: name}
+		if agg.typ, err = aConf.FieldString(twFieldAggType); err != nil {
+			return nil, fmt.Errorf("aggregation '%v': %w", name, err)
+		}
+		if agg.value, err = twOptionalBloblang(aConf, twFieldAggValue); err != nil {
+			return nil, fmt.Errorf("aggregation '%v': %w", name, err)
+		}
+		if agg.value == nil && agg.typ != "count" {
+			return nil, fmt.Errorf("aggregation '%v': a value query is required for the type %v", name, agg.typ)
+		}
+		p.aggregations = append(p.aggregations, agg)
+	}
+
+	if conf.Contains(twFieldCache) {
+		if p.cache, err = conf.FieldString(twFieldCache); err != nil {
+			return nil, err
+		}
+		if !mgr.HasCache(p.cache) {
+			return nil, fmt.Errorf("cache resource '%v' was not found", p.cache)
+		}
+	}
+	if p.cacheKey, err = conf.FieldString(twFieldCacheKey); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func twGroupID(start time.Time, key string) string {
+	return fmt.Sprintf("%v:%v", start.UnixNano(), key)
+}
+
+// restore loads the open windows stored within the cache by a previous
+// instance.
+func (p *tumblingWindowProc) restore(ctx context.Context) error {
+	if p.restored || p.cache == "" {
+		return nil
+	}
+
+	var stateBytes []byte
+	var cacheErr error
+	if err := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		if stateBytes, cacheErr = c.Get(ctx, p.cacheKey); cacheErr == nil {
+			cacheErr = c.Delete(ctx, p.cacheKey)
+		}
+	}); err != nil {
+		return err
+	}
+	if cacheErr != nil {
+		if errors.Is(cacheErr, service.ErrKeyNotFound) {
+			p.restored = true
+			return nil
+		}
+		return fmt.Errorf("failed to obtain stored windows: %w", cacheErr)
+	}
+
+	var groups []*twGroup
+	if err := json.Unmarshal(stateBytes, &groups); err != nil {
+		return fmt.Errorf("failed to parse stored windows: %w", err)
+	}
+	for _, g := range groups {
+		if len(g.Aggs) != len(p.aggregations) {
+			p.log.Warnf("Discarding stored window of group %v as the aggregations have changed", g.Key)
+			continue
+		}
+		p.groups[twGroupID(g.Start, g.Key)] = g
+	}
+	p.restored = true
+	return nil
+}
+
+func (p *tumblingWindowProc) isClosed(start, now time.Time) bool {
+	return !start.Add(p.window + p.lateness).After(now)
+}
+
+func (p *tumblingWindowProc) result(g *twGroup) *service.Message {
+	doc := map[string]any{
+		"key":          g.Key,
+		"window_start": g.Start.UTC().Format(time.RFC3339Nano),
+		"window_end":   g.Start.Add(p.window).UTC().Format(time.RFC3339Nano),
+	}
+	for i, agg := range p.aggregations {
+		s := g.Aggs[i]
+		var v any
+		switch agg.typ {
+		case "count":
+			v = s.Count
+		case "sum":
+			v = s.Sum
+		case "avg":
+			if s.Count > 0 {
+				v = s.Sum / float64(s.Count)
+			}
+		case "min":
+			if s.Count > 0 {
+				v = s.Min
+			}
+		case "max":
+			if s.Count > 0 {
+				v = s.Max
+			}
+		case "last":
+			v = s.Last
+		}
+		doc[agg.name] = v
+	}
+
+	msg := service.NewMessage(nil)
+	msg.SetStructuredMut(doc)
+	return msg
+}
+
+// closeWindows returns a result message for each group of the open windows
+// that have closed, ordered by window and then key.
+func (p *tumblingWindowProc) closeWindows(now time.Time) service.MessageBatch {
+	var closed []*twGroup
+	for id, g := range p.groups {
+		if p.isClosed(g.Start, now) {
+			closed = append(closed, g)
+			delete(p.groups, id)
+		}
+	}
+	sort.Slice(closed, func(i, j int) bool {
+		if !closed[i].Start.Equal(closed[j].Start) {
+			return closed[i].Start.Before(closed[j].Start)
+		}
+		return closed[i].Key < closed[j].Key
+	})
+
+	var out service.MessageBatch
+	for _, g := range closed {
+		out = append(out, p.result(g))
+	}
+	return out
+}
+
+func (p *tumblingWindowProc) aggregate(batch service.MessageBatch, i int, g *twGroup) {
+	for j, agg := range p.aggregations {
+		s := &g.Aggs[j]
+		if agg.value == nil {
+			s.Count++
+			continue
+		}
+
+		resMsg, err := batch.BloblangQuery(i, agg.value)
+		if err != nil {
+			p.log.Debugf("Skipping message %v for aggregation %v: %v", i, agg.name, err)
+			continue
+		}
+		if resMsg == nil {
+			continue
+		}
+		// Queries that result in a string set the raw contents of the result.
+		v, err := resMsg.AsStructured()
+		if err != nil {
+			b, _ := resMsg.AsBytes()
+			v = string(b)
+		}
+		if v == nil {
+			continue
+		}
+
+		switch agg.typ {
+		case "count":
+			s.Count++
+		case "last":
+			s.Count++
+			s.Last = v
+		default:
+			f, err := bloblang.ValueAsFloat64(v)
+			if err != nil || math.IsNaN(f) {
+				p.log.Debugf("Skipping message %v for aggregation %v: value is not a number", i, agg.name)
+				continue
+			}
+			if s.Count == 0 || f < s.Min {
+				s.Min = f
+			}
+			if s.Count == 0 || f > s.Max {
+				s.Max = f
+			}
+			s.Sum += f
+			s.Count++
+		}
+	}
+}
+
+func (p *tumblingWindowProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if err := p.restore(ctx); err != nil {
+		return nil, err
+	}
+
+	now := p.now()
+	out := p.closeWindows(now)
+
+	for i, msg := range batch {
+		key, err := batch.TryInterpolatedString(i, p.groupBy)
+		if err != nil {
+			msg.SetError(fmt.Errorf("group by interpolation error: %w", err))
+			out = append(out, msg)
+			continue
+		}
+
+		ts := now
+		if p.timestamp != nil {
+			tsStr, err := batch.TryInterpolatedString(i, p.timestamp)
+			if err != nil {
+				msg.SetError(fmt.Errorf("timestamp interpolation error: %w", err))
+				out = append(out, msg)
+				continue
+			}
+			if ts, err = sessionParseTime(tsStr); err != nil {
+				msg.SetError(err)
+				out = append(out, msg)
+				continue
+			}
+		}
+
+		start := ts.Truncate(p.window)
+		if p.isClosed(start, now) {
+			if p.errorOnLate {
+				msg.SetError(fmt.Errorf("message is late for the window starting at %v", start.UTC().Format(time.RFC3339Nano)))
+				out = append(out, msg)
+			}
+			continue
+		}
+
+		id := twGroupID(start, key)
+		g, exists := p.groups[id]
+		if !exists {
+			g = &twGroup{Start: start, Key: key, Aggs: make([]twAggState, len(p.aggregations))}
+			p.groups[id] = g
+		}
+		p.aggregate(batch, i, g)
+	}
+
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return []service.MessageBatch{out}, nil
+}
+
+func (p *tumblingWindowProc) Close(ctx context.Context) error {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if len(p.groups) == 0 {
+		return nil
+	}
+	if p.cache == "" {
+		p.log.Warnf("Discarding %v groups of open windows", len(p.groups))
+		return nil
+	}
+
+	groups := make([]*twGroup, 0, len(p.groups))
+	for _, g := range p.groups {
+		groups = append(groups, g)
+	}
+	stateBytes, err := json.Marshal(groups)
+	if err != nil {
+		return err
+	}
+
+	var cacheErr error
+	if err := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		cacheErr = c.Set(ctx, p.cacheKey, stateBytes, nil)
+	}); err != nil {
+		return err
+	}
+	if cacheErr != nil {
+		return fmt.Errorf("failed to store open windows: %w", cacheErr)
+	}
+	p.groups = map[string]*twGroup{}
+	return nil
+}
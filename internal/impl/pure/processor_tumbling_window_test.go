@@ -0,0 +1,226 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func tumblingWindowProcess(t testing.TB, proc *tumblingWindowProc, docs ...string) []string {
+	t.Helper()
+
+	var batch service.MessageBatch
+	for _, d := range docs {
+		batch = append(batch, service.NewMessage([]byte(d)))
+	}
+	res, err := proc.ProcessBatch(context.Background(), batch)
+	require.NoError(t, err)
+
+	var out []string
+	for _, b := range res {
+		for _, msg := range b {
+			mBytes, err := msg.AsBytes()
+			require.NoError(t, err)
+			if msg.GetError() != nil {
+				mBytes = append([]byte("error: "), mBytes...)
+			}
+			out = append(out, string(mBytes))
+		}
+	}
+	return out
+}
+
+const tumblingWindowTestConf = `
+window: 1m
+group_by: ${! this.region }
+aggregations:
+  count:
+    type: count
+  total:
+    type: sum
+    value: root = this.latency
+  mean:
+    type: avg
+    value: root = this.latency
+  fastest:
+    type: min
+    value: root = this.latency
+  slowest:
+    type: max
+    value: root = this.latency
+  status:
+    type: last
+    value: root = this.status
+`
+
+func TestTumblingWindowAggregations(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 10, 0, time.UTC)
+	conf, err := tumblingWindowProcConfig().ParseYAML(tumblingWindowTestConf, nil)
+	require.NoError(t, err)
+
+	proc, err := tumblingWindowProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	proc.now = func() time.Time { return now }
+
+	assert.Empty(t, tumblingWindowProcess(t, proc,
+		`{"region":"eu","latency":10,"status":"ok"}`,
+		`{"region":"us","latency":5,"status":"ok"}`,
+		`{"region":"eu","latency":30,"status":"slow"}`,
+	))
+
+	now = now.Add(30 * time.Second)
+	assert.Empty(t, tumblingWindowProcess(t, proc,
+		`{"region":"eu","latency":"nope"}`,
+	))
+
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, []string{
+		`{"count":3,"fastest":10,"key":"eu","mean":20,"slowest":30,"status":"slow","total":40,"window_end":"2024-01-02T03:05:00Z","window_start":"2024-01-02T03:04:00Z"}`,
+		`{"count":1,"fastest":5,"key":"us","mean":5,"slowest":5,"status":"ok","total":5,"window_end":"2024-01-02T03:05:00Z","window_start":"2024-01-02T03:04:00Z"}`,
+	}, tumblingWindowProcess(t, proc,
+		`{"region":"us","status":"new"}`,
+	))
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, []string{
+		`{"count":1,"fastest":null,"key":"us","mean":null,"slowest":null,"status":"new","total":0,"window_end":"2024-01-02T03:06:00Z","window_start":"2024-01-02T03:05:00Z"}`,
+	}, tumblingWindowProcess(t, proc))
+}
+
+func TestTumblingWindowLateMessages(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 5, 5, 0, time.UTC)
+	conf := `
+window: 1m
+timestamp: ${! this.ts }
+allowed_lateness: 10s
+late_messages: error
+aggregations:
+  count:
+    type: count
+`
+	pConf, err := tumblingWindowProcConfig().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	proc, err := tumblingWindowProcFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	proc.now = func() time.Time { return now }
+
+	assert.Equal(t, []string{
+		`error: {"ts":"2024-01-02T03:03:59Z"}`,
+	}, tumblingWindowProcess(t, proc,
+		`{"ts":"2024-01-02T03:04:30Z"}`,
+		`{"ts":"2024-01-02T03:03:59Z"}`,
+		`{"ts":"2024-01-02T03:05:01Z"}`,
+	))
+
+	now = now.Add(5 * time.Second)
+	assert.Equal(t, []string{
+		`{"count":1,"key":"","window_end":"2024-01-02T03:05:00Z","window_start":"2024-01-02T03:04:00Z"}`,
+		`error: {"ts":"2024-01-02T03:04:50Z"}`,
+	}, tumblingWindowProcess(t, proc,
+		`{"ts":"2024-01-02T03:04:50Z"}`,
+	))
+
+	conf = `
+window: 1m
+timestamp: ${! this.ts }
+aggregations:
+  count:
+    type: count
+`
+	pConf, err = tumblingWindowProcConfig().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	proc, err = tumblingWindowProcFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	proc.now = func() time.Time { return now }
+
+	assert.Empty(t, tumblingWindowProcess(t, proc, `{"ts":"2024-01-02T03:03:59Z"}`))
+}
+
+func TestTumblingWindowCacheRestore(t *testing.T) {
+	res := service.MockResources(service.MockResourcesOptAddCache("foocache"))
+	now := time.Date(2024, 1, 2, 3, 4, 10, 0, time.UTC)
+
+	conf := `
+window: 1m
+group_by: ${! this.region }
+cache: foocache
+aggregations:
+  total:
+    type: sum
+    value: root = this.v
+`
+	pConf, err := tumblingWindowProcConfig().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	proc, err := tumblingWindowProcFromParsed(pConf, res)
+	require.NoError(t, err)
+	proc.now = func() time.Time { return now }
+
+	assert.Empty(t, tumblingWindowProcess(t, proc, `{"region":"eu","v":1.5}`, `{"region":"eu","v":2}`))
+	require.NoError(t, proc.Close(context.Background()))
+
+	now = now.Add(time.Minute)
+	pConf, err = tumblingWindowProcConfig().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	proc, err = tumblingWindowProcFromParsed(pConf, res)
+	require.NoError(t, err)
+	proc.now = func() time.Time { return now }
+
+	assert.Equal(t, []string{
+		`{"key":"eu","total":3.5,"window_end":"2024-01-02T03:05:00Z","window_start":"2024-01-02T03:04:00Z"}`,
+	}, tumblingWindowProcess(t, proc, `{"region":"eu","v":1}`))
+
+	// The stored windows are only restored once.
+	require.NoError(t, res.AccessCache(context.Background(), "foocache", func(c service.Cache) {
+		_, err := c.Get(context.Background(), "tumbling_window_state")
+		assert.ErrorIs(t, err, service.ErrKeyNotFound)
+	}))
+}
+
+func TestTumblingWindowConfigErrors(t *testing.T) {
+	for _, conf := range []string{
+		`window: 1m
+aggregations: {}`,
+		`window: 0s
+aggregations:
+  c:
+    type: count`,
+		`window: 1m
+aggregations:
+  s:
+    type: sum`,
+		`window: 1m
+cache: nope
+aggregations:
+  c:
+    type: count`,
+	} {
+		pConf, err := tumblingWindowProcConfig().ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		_, err = tumblingWindowProcFromParsed(pConf, service.MockResources())
+		assert.Error(t, err, conf)
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/Jeffail/gabs/v2"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	urFieldMode  = "mode"
+	urFieldField = "field"
+)
+
+func utf8RepairProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Parsing").
+		Version("4.31.0").
+		Summary("Validates that message contents are valid UTF-8, and repairs invalid byte sequences.").
+		Description(`
+By default the raw contents of messages are validated, and when `+"`field`"+` is set the string value at that path of structured messages is validated instead. Messages that are already valid are passed through unchanged.
+
+Each byte that is not part of a valid UTF-8 sequence is handled separately, such that with the mode `+"`replace`"+` the bytes `+"`0xC3 0x28`"+` become `+"`�(`"+`. With the mode `+"`error`"+` messages are left unchanged and flagged as failed with an error giving the offset of the first invalid byte, and can be handled using xref:configuration:error_handling.adoc[error handling].`).
+		Field(service.NewStringAnnotatedEnumField(urFieldMode, map[string]string{
+			"replace": "Replace each invalid byte with the replacement character U+FFFD.",
+			"strip":   "Remove each invalid byte.",
+			"error":   "Flag messages with invalid bytes as failed.",
+		}).
+			Description("How to handle invalid byte sequences.").
+			Default("replace")).
+		Field(service.NewStringField(urFieldField).
+			Description("An optional xref:configuration:field_paths.adoc[dot separated path] to a string field to validate instead of the raw contents.").
+			Default("").
+			Example("message")).
+		Example("Repair before encoding", "Replace invalid bytes in lines read from a legacy system before they are parsed and encoded as JSON.", `
+pipeline:
+  processors:
+    - utf8_repair:
+        mode: replace
+    - mapping: 'root.line = content().string()'
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"utf8_repair", utf8RepairProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return utf8RepairProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type utf8RepairProc struct {
+	mode string
+	path string
+}
+
+func utf8RepairProcFromParsed(conf *service.ParsedConfig) (*utf8RepairProc, error) {
+	p := &utf8RepairProc{}
+
+	var err error
+	if p.mode, err = conf.FieldString(urFieldMode); err != nil {
+		return nil, err
+	}
+	switch p.mode {
+	case "replace", "strip", "error":
+	default:
+		return nil, fmt.Errorf("mode not recognised: %v", p.mode)
+	}
+	if p.path, err = conf.FieldString(urFieldField); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+var utf8Replacement = []byte(string(utf8.RuneError))
+
+// repair returns the repaired bytes and false when the input is already
+// valid, in which case it is returned as is.
+func (p *utf8RepairProc) repair(b []byte) ([]byte, bool, error) {
+	if utf8.Valid(b) {
+		return b, false, nil
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(len(b))
+	for i := 0; i < len(b); {
+		r, size := utf8.DecodeRune(b[i:])
+		if r == utf8.RuneError && size == 1 {
+			switch p.mode {
+			case "error":
+				return nil, false, fmt.Errorf("invalid UTF-8 byte 0x%02X at offset %v", b[i], i)
+			case "replace":
+				buf.Write(utf8Replacement)
+			}
+			i++
+			continue
+		}
+		buf.Write(b[i : i+size])
+		i += size
+	}
+	return buf.Bytes(), true, nil
+}
+
+func (p *utf8RepairProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	if p.path == "" {
+		mBytes, err := msg.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		repaired, changed, err := p.repair(mBytes)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			msg.SetBytes(repaired)
+		}
+		return service.MessageBatch{msg}, nil
+	}
+
+	v, err := msg.AsStructuredMut()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message: %w", err)
+	}
+	gObj := gabs.Wrap(v)
+	if !gObj.ExistsP(p.path) {
+		return nil, fmt.Errorf("field %v not found", p.path)
+	}
+
+	var raw []byte
+	switch t := gObj.Path(p.path).Data().(type) {
+	case string:
+		raw = []byte(t)
+	case []byte:
+		raw = t
+	default:
+		return nil, fmt.Errorf("field %v is not a string, got %T", p.path, t)
+	}
+
+	repaired, changed, err := p.repair(raw)
+	if err != nil {
+		return nil, fmt.Errorf("field %v: %w", p.path, err)
+	}
+	if changed {
+		if _, err := gObj.SetP(string(repaired), p.path); err != nil {
+			return nil, err
+		}
+		msg.SetStructuredMut(gObj.Data())
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *utf8RepairProc) Close(ctx context.Context) error {
+	return nil
+}
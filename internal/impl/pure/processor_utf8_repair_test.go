@@ -0,0 +1,107 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestUTF8RepairRaw(t *testing.T) {
+	input := []byte("ok \xc3\x28 caf\xc3\xa9 \xff\xfe end")
+
+	tests := []struct {
+		mode     string
+		expected string
+		errMsg   string
+	}{
+		{mode: "replace", expected: "ok �( café �� end"},
+		{mode: "strip", expected: "ok ( café  end"},
+		{mode: "error", errMsg: "invalid UTF-8 byte 0xC3 at offset 3"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.mode, func(t *testing.T) {
+			conf, err := utf8RepairProcConfig().ParseYAML(`mode: `+test.mode, nil)
+			require.NoError(t, err)
+
+			proc, err := utf8RepairProcFromParsed(conf)
+			require.NoError(t, err)
+
+			batch, err := proc.Process(context.Background(), service.NewMessage(input))
+			if test.errMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, batch, 1)
+
+			mBytes, err := batch[0].AsBytes()
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, string(mBytes))
+		})
+	}
+}
+
+func TestUTF8RepairValidUnchanged(t *testing.T) {
+	conf, err := utf8RepairProcConfig().ParseYAML(`mode: error`, nil)
+	require.NoError(t, err)
+
+	proc, err := utf8RepairProcFromParsed(conf)
+	require.NoError(t, err)
+
+	batch, err := proc.Process(context.Background(), service.NewMessage([]byte("héllo wörld")))
+	require.NoError(t, err)
+
+	mBytes, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "héllo wörld", string(mBytes))
+}
+
+func TestUTF8RepairField(t *testing.T) {
+	conf, err := utf8RepairProcConfig().ParseYAML(`
+mode: strip
+field: doc.text
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := utf8RepairProcFromParsed(conf)
+	require.NoError(t, err)
+
+	msg := service.NewMessage(nil)
+	msg.SetStructuredMut(map[string]any{
+		"doc": map[string]any{"text": "bad \x80byte", "id": 1},
+	})
+
+	batch, err := proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+
+	v, err := batch[0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"doc": map[string]any{"text": "bad byte", "id": 1},
+	}, v)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"doc":{"text":5}}`)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not a string")
+}
@@ -0,0 +1,210 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	vdFieldKey     = "key"
+	vdFieldVersion = "version"
+	vdFieldCache   = "cache"
+	vdFieldTTL     = "ttl"
+)
+
+func versionDedupeProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.31.0").
+		Summary("Drops messages with a version that is no greater than the highest version previously seen for their key, storing the highest version of each key in a cache.").
+		Description(`
+This processor is useful for consuming upsert streams where updates can arrive out of order, as it prevents stale updates from overwriting newer state downstream. A message is passed through and its version stored when its version is greater than the stored version of its key, or when its key has no stored version, and is otherwise dropped.
+
+Versions can either be numbers, or timestamps in RFC 3339 format, and all versions of a key must be of the same kind. Messages where the version cannot be parsed, or where the kind of the version differs from the stored version, are flagged as failed so that they can be handled using xref:configuration:error_handling.adoc[error handling methods], and do not affect the stored version of their key.
+
+== State
+
+A version is compared with the version of its key read from the cache before it is stored, and therefore when messages of the same key are processed by multiple pipeline threads at the same time an older version can be stored over a newer one, after which stale messages that should be dropped are passed through. The messages of a key do not need to arrive in order, but must be processed by a single pipeline thread, which can be achieved by partitioning messages by key upstream. Keys that are no longer seen can be evicted by setting a `+"`ttl`"+`, after which the next version of the key is always passed through.`).
+		Field(service.NewInterpolatedStringField(vdFieldKey).
+			Description("An interpolated string that resolves to the key of each message.").
+			Example(`${! this.id }`)).
+		Field(service.NewInterpolatedStringField(vdFieldVersion).
+			Description("An interpolated string that resolves to the version of each message, either a number or an RFC 3339 timestamp.").
+			Example(`${! this.version }`).
+			Example(`${! this.updated_at }`)).
+		Field(service.NewStringField(vdFieldCache).
+			Description("The xref:components:caches/about.adoc[`cache` resource] to store the highest version of each key in.")).
+		Field(service.NewStringField(vdFieldTTL).
+			Description("An optional TTL to set for the stored version of each key. Not all caches support per-key TTLs.").
+			Example("24h").
+			Optional().
+			Advanced()).
+		Example("Drop stale updates", "Drop updates of a customer that are older than the most recent update already processed.", `
+pipeline:
+  processors:
+    - version_dedupe:
+        key: ${! this.customer_id }
+        version: ${! this.updated_at }
+        cache: versions
+
+cache_resources:
+  - label: versions
+    redis:
+      url: redis://localhost:6379
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"version_dedupe", versionDedupeProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return versionDedupeProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type versionDedupeProc struct {
+	key     *service.InterpolatedString
+	version *service.InterpolatedString
+	cache   string
+	ttl     *time.Duration
+
+	mgr *service.Resources
+}
+
+func versionDedupeProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*versionDedupeProc, error) {
+	p := &versionDedupeProc{mgr: mgr}
+
+	var err error
+	if p.key, err = conf.FieldInterpolatedString(vdFieldKey); err != nil {
+		return nil, err
+	}
+	if p.version, err = conf.FieldInterpolatedString(vdFieldVersion); err != nil {
+		return nil, err
+	}
+	if p.cache, err = conf.FieldString(vdFieldCache); err != nil {
+		return nil, err
+	}
+	if !mgr.HasCache(p.cache) {
+		return nil, fmt.Errorf("cache resource '%v' was not found", p.cache)
+	}
+	if conf.Contains(vdFieldTTL) {
+		ttlStr, err := conf.FieldString(vdFieldTTL)
+		if err != nil {
+			return nil, err
+		}
+		ttl, err := time.ParseDuration(ttlStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ttl: %w", err)
+		}
+		p.ttl = &ttl
+	}
+	return p, nil
+}
+
+// recordVersion is a parsed version, which is either a number or a timestamp.
+type recordVersion struct {
+	num    float64
+	ts     time.Time
+	isTime bool
+}
+
+func parseRecordVersion(s string) (recordVersion, error) {
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return recordVersion{num: f}, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return recordVersion{ts: t, isTime: true}, nil
+	}
+	return recordVersion{}, fmt.Errorf("failed to parse version %q as a number or an RFC 3339 timestamp", s)
+}
+
+// newerThan returns whether v is greater than o, or an error if the versions
+// are of different kinds.
+func (v recordVersion) newerThan(o recordVersion) (bool, error) {
+	if v.isTime != o.isTime {
+		return false, errors.New("version kinds do not match, versions must either all be numbers or all be timestamps")
+	}
+	if v.isTime {
+		return v.ts.After(o.ts), nil
+	}
+	return v.num > o.num, nil
+}
+
+func (p *versionDedupeProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	key, err := p.key.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("key interpolation error: %w", err)
+	}
+	versionStr, err := p.version.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("version interpolation error: %w", err)
+	}
+	version, err := parseRecordVersion(versionStr)
+	if err != nil {
+		return nil, err
+	}
+
+	var stored []byte
+	var cacheErr error
+	if err := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		if stored, cacheErr = c.Get(ctx, key); errors.Is(cacheErr, service.ErrKeyNotFound) {
+			stored, cacheErr = nil, nil
+		}
+	}); err != nil {
+		return nil, err
+	}
+	if cacheErr != nil {
+		return nil, fmt.Errorf("failed to obtain version of %v: %w", key, cacheErr)
+	}
+
+	if stored != nil {
+		storedVersion, err := parseRecordVersion(string(stored))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse stored version of %v: %w", key, err)
+		}
+		newer, err := version.newerThan(storedVersion)
+		if err != nil {
+			return nil, err
+		}
+		if !newer {
+			return nil, nil
+		}
+	}
+
+	if err := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		cacheErr = c.Set(ctx, key, []byte(versionStr), p.ttl)
+	}); err != nil {
+		return nil, err
+	}
+	if cacheErr != nil {
+		return nil, fmt.Errorf("failed to store version of %v: %w", key, cacheErr)
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *versionDedupeProc) Close(ctx context.Context) error {
+	return nil
+}
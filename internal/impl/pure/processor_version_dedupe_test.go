@@ -0,0 +1,113 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func versionDedupePassed(t testing.TB, proc *versionDedupeProc, contents ...string) []string {
+	t.Helper()
+
+	var passed []string
+	for _, c := range contents {
+		res, err := proc.Process(context.Background(), service.NewMessage([]byte(c)))
+		require.NoError(t, err)
+		for _, m := range res {
+			b, err := m.AsBytes()
+			require.NoError(t, err)
+			passed = append(passed, string(b))
+		}
+	}
+	return passed
+}
+
+func TestVersionDedupeNumeric(t *testing.T) {
+	conf, err := versionDedupeProcConfig().ParseYAML(`
+key: ${! this.id }
+version: ${! this.v }
+cache: foocache
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := versionDedupeProcFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		`{"id":"a","v":1}`,
+		`{"id":"b","v":1}`,
+		`{"id":"a","v":3}`,
+		`{"id":"a","v":3.5}`,
+	}, versionDedupePassed(t, proc,
+		`{"id":"a","v":1}`,
+		`{"id":"b","v":1}`,
+		`{"id":"a","v":3}`,
+		`{"id":"a","v":2}`,
+		`{"id":"a","v":3}`,
+		`{"id":"b","v":0}`,
+		`{"id":"a","v":3.5}`,
+	))
+}
+
+func TestVersionDedupeTimestamps(t *testing.T) {
+	conf, err := versionDedupeProcConfig().ParseYAML(`
+key: ${! this.id }
+version: ${! this.updated_at }
+cache: foocache
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := versionDedupeProcFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		`{"id":"a","updated_at":"2024-06-01T12:00:00Z"}`,
+		`{"id":"a","updated_at":"2024-06-01T13:00:00.5+01:00"}`,
+	}, versionDedupePassed(t, proc,
+		`{"id":"a","updated_at":"2024-06-01T12:00:00Z"}`,
+		`{"id":"a","updated_at":"2024-06-01T11:59:59Z"}`,
+		`{"id":"a","updated_at":"2024-06-01T13:00:00+01:00"}`,
+		`{"id":"a","updated_at":"2024-06-01T13:00:00.5+01:00"}`,
+	))
+}
+
+func TestVersionDedupeErrors(t *testing.T) {
+	conf, err := versionDedupeProcConfig().ParseYAML(`
+key: ${! this.id }
+version: ${! this.v }
+cache: foocache
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := versionDedupeProcFromParsed(conf, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+	require.NoError(t, err)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"id":"a","v":"nope"}`)))
+	require.ErrorContains(t, err, "failed to parse version")
+
+	assert.Equal(t, []string{`{"id":"a","v":5}`}, versionDedupePassed(t, proc, `{"id":"a","v":5}`))
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"id":"a","v":"2024-06-01T12:00:00Z"}`)))
+	require.ErrorContains(t, err, "kinds do not match")
+
+	// Failed messages do not affect the stored version.
+	assert.Equal(t, []string{`{"id":"a","v":6}`}, versionDedupePassed(t, proc, `{"id":"a","v":4}`, `{"id":"a","v":6}`))
+}
@@ -0,0 +1,410 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	ymFieldOperator      = "operator"
+	ymFieldMultiDocument = "multi_document"
+	ymFieldIndent        = "indent"
+)
+
+func yamlProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Parsing").
+		Version("4.31.0").
+		Summary("Converts messages between YAML and JSON documents.").
+		Description(`
+The order of the keys of objects is preserved in both directions, and comments are discarded.
+
+When converting YAML to JSON anchors and aliases are expanded, merge keys (`+"`<<`"+`) are applied, timestamps are converted into RFC 3339 strings, binary values are kept as base64 encoded strings, and the values of custom tags such as `+"`!Ref`"+` are converted according to their contents with the tag discarded. Keys that are not strings are converted into strings. Special floating point values such as `+"`.inf`"+` and `+"`.nan`"+` cannot be represented in JSON and cause the message to fail.
+
+A message containing multiple YAML documents fails unless `+"`multi_document`"+` is enabled, in which case one message is emitted for each document, and a message without any documents is converted into `+"`null`"+`.
+
+Messages that cannot be parsed are flagged as failed and passed on unchanged, and can therefore be handled with xref:configuration:error_handling.adoc[error handling patterns].`).
+		Field(service.NewStringAnnotatedEnumField(ymFieldOperator, map[string]string{
+			"to_json":   "Convert YAML messages to JSON format.",
+			"from_json": "Convert JSON messages to YAML format.",
+		}).Description("The operation to perform on messages.")).
+		Field(service.NewBoolField(ymFieldMultiDocument).
+			Description("Whether YAML messages containing multiple documents are converted into one message per document when converting to JSON.").
+			Default(false)).
+		Field(service.NewIntField(ymFieldIndent).
+			Description("The number of spaces to indent YAML documents with when converting from JSON.").
+			Default(2).
+			Advanced()).
+		Example("Kubernetes manifests", "Convert a multi-document YAML manifest into one JSON message per resource, and drop resources other than deployments.", `
+pipeline:
+  processors:
+    - yaml:
+        operator: to_json
+        multi_document: true
+    - mapping: |
+        root = if this.kind != "Deployment" { deleted() }
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"yaml", yamlProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return yamlProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type yamlProc struct {
+	toJSON        bool
+	multiDocument bool
+	indent        int
+}
+
+func yamlProcFromParsed(conf *service.ParsedConfig) (*yamlProc, error) {
+	p := &yamlProc{}
+
+	operator, err := conf.FieldString(ymFieldOperator)
+	if err != nil {
+		return nil, err
+	}
+	switch operator {
+	case "to_json":
+		p.toJSON = true
+	case "from_json":
+	default:
+		return nil, fmt.Errorf("operator not recognised: %v", operator)
+	}
+	if p.multiDocument, err = conf.FieldBool(ymFieldMultiDocument); err != nil {
+		return nil, err
+	}
+	if p.indent, err = conf.FieldInt(ymFieldIndent); err != nil {
+		return nil, err
+	}
+	if p.indent < 1 {
+		return nil, errors.New("indent must be at least 1")
+	}
+	return p, nil
+}
+
+func (p *yamlProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	mBytes, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.toJSON {
+		res, err := jsonToYAML(mBytes, p.indent)
+		if err != nil {
+			return nil, err
+		}
+		msg.SetBytes(res)
+		return service.MessageBatch{msg}, nil
+	}
+
+	docs, err := yamlToJSON(mBytes)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) > 1 && !p.multiDocument {
+		return nil, fmt.Errorf("message contains %v YAML documents, enable multi_document to convert each document into a message", len(docs))
+	}
+
+	batch := make(service.MessageBatch, len(docs))
+	for i, doc := range docs {
+		if i == len(docs)-1 {
+			batch[i] = msg
+		} else {
+			batch[i] = msg.Copy()
+		}
+		batch[i].SetBytes(doc)
+	}
+	return batch, nil
+}
+
+func (p *yamlProc) Close(ctx context.Context) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// yamlToJSON converts each document of a YAML stream into a JSON document. A
+// stream without documents is converted into null.
+func yamlToJSON(b []byte) ([][]byte, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+
+	var docs [][]byte
+	for {
+		var node yaml.Node
+		if err := dec.Decode(&node); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse YAML document: %w", err)
+		}
+
+		var buf bytes.Buffer
+		if err := yamlWriteJSON(&buf, &node); err != nil {
+			return nil, fmt.Errorf("failed to convert YAML document %v to JSON: %w", len(docs), err)
+		}
+		docs = append(docs, buf.Bytes())
+	}
+	if len(docs) == 0 {
+		docs = append(docs, []byte("null"))
+	}
+	return docs, nil
+}
+
+func yamlWriteJSON(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+		return yamlWriteJSON(buf, node.Content[0])
+	case yaml.AliasNode:
+		return yamlWriteJSON(buf, node.Alias)
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, c := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := yamlWriteJSON(buf, c); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	case yaml.MappingNode:
+		keys, values, err := yamlMappingPairs(node)
+		if err != nil {
+			return err
+		}
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			kBytes, _ := json.Marshal(k)
+			buf.Write(kBytes)
+			buf.WriteByte(':')
+			if err := yamlWriteJSON(buf, values[k]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	case yaml.ScalarNode:
+		return yamlWriteScalar(buf, node)
+	}
+	return fmt.Errorf("unexpected YAML node kind: %v", node.Kind)
+}
+
+func yamlWriteScalar(buf *bytes.Buffer, node *yaml.Node) error {
+	var v any
+	switch node.ShortTag() {
+	case "!!null":
+		buf.WriteString("null")
+		return nil
+	case "!!bool", "!!int", "!!float":
+		if err := node.Decode(&v); err != nil {
+			return err
+		}
+		if f, ok := v.(float64); ok && (math.IsInf(f, 0) || math.IsNaN(f)) {
+			return fmt.Errorf("line %v: value %v cannot be represented in JSON", node.Line, node.Value)
+		}
+	case "!!timestamp":
+		var t time.Time
+		if err := node.Decode(&t); err != nil {
+			return err
+		}
+		v = t.Format(time.RFC3339Nano)
+	default:
+		v = node.Value
+	}
+	vBytes, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	buf.Write(vBytes)
+	return nil
+}
+
+// yamlMappingPairs returns the keys of a mapping in order along with their
+// values, where the keys of merged mappings are added at the position of the
+// merge key unless they are set explicitly by the mapping.
+func yamlMappingPairs(node *yaml.Node) ([]string, map[string]*yaml.Node, error) {
+	explicit := map[string]struct{}{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if k := node.Content[i]; k.ShortTag() != "!!merge" {
+			explicit[k.Value] = struct{}{}
+		}
+	}
+
+	var keys []string
+	values := map[string]*yaml.Node{}
+	add := func(k string, v *yaml.Node, merged bool) {
+		if _, exists := values[k]; !exists {
+			keys = append(keys, k)
+		} else if merged {
+			return
+		}
+		values[k] = v
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		k, v := node.Content[i], node.Content[i+1]
+		if k.ShortTag() != "!!merge" {
+			add(k.Value, v, false)
+			continue
+		}
+
+		sources := []*yaml.Node{v}
+		if yamlResolveAlias(v).Kind == yaml.SequenceNode {
+			sources = yamlResolveAlias(v).Content
+		}
+		for _, src := range sources {
+			src = yamlResolveAlias(src)
+			if src.Kind != yaml.MappingNode {
+				return nil, nil, fmt.Errorf("line %v: merge key must reference a mapping", k.Line)
+			}
+			mKeys, mValues, err := yamlMappingPairs(src)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, mk := range mKeys {
+				if _, isExplicit := explicit[mk]; !isExplicit {
+					add(mk, mValues[mk], true)
+				}
+			}
+		}
+	}
+	return keys, values, nil
+}
+
+func yamlResolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+//------------------------------------------------------------------------------
+
+// jsonToYAML converts a JSON document into a YAML document. The document is
+// parsed token by token so that the order of object keys is preserved.
+func jsonToYAML(b []byte, indent int) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	node, err := jsonReadYAMLNode(dec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as JSON: %w", err)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("failed to parse message as JSON: unexpected data after the end of the JSON document")
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(indent)
+	if err := enc.Encode(node); err != nil {
+		return nil, fmt.Errorf("failed to convert JSON to YAML: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to convert JSON to YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func jsonReadYAMLNode(dec *json.Decoder) (*yaml.Node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("unexpected end of JSON document")
+		}
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			for dec.More() {
+				kTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				k, ok := kTok.(string)
+				if !ok {
+					return nil, fmt.Errorf("unexpected object key: %v", kTok)
+				}
+				v, err := jsonReadYAMLNode(dec)
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}, v)
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			return node, nil
+		case '[':
+			node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			for dec.More() {
+				v, err := jsonReadYAMLNode(dec)
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, v)
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			return node, nil
+		}
+		return nil, fmt.Errorf("unexpected delimiter: %v", t)
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: t}, nil
+	case json.Number:
+		// Numbers are left untagged, as JSON numbers are resolved as either
+		// integers or floats by YAML, including those that overflow.
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: t.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(t)}, nil
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+	return nil, fmt.Errorf("unexpected token: %v", tok)
+}
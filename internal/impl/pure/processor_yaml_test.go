@@ -0,0 +1,220 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func yamlProcResults(t testing.TB, proc *yamlProc, content string) ([]string, error) {
+	t.Helper()
+
+	batch, err := proc.Process(context.Background(), service.NewMessage([]byte(content)))
+	if err != nil {
+		return nil, err
+	}
+
+	var results []string
+	for _, m := range batch {
+		mBytes, err := m.AsBytes()
+		require.NoError(t, err)
+		results = append(results, string(mBytes))
+	}
+	return results, nil
+}
+
+func TestYAMLToJSON(t *testing.T) {
+	conf, err := yamlProcConfig().ParseYAML(`operator: to_json`, nil)
+	require.NoError(t, err)
+
+	proc, err := yamlProcFromParsed(conf)
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "key order",
+			input: `
+zebra: 1
+apple: two
+mango: [ 3.5, true, null, ~ ]
+`,
+			expected: `{"zebra":1,"apple":"two","mango":[3.5,true,null,null]}`,
+		},
+		{
+			name: "anchors and merge keys",
+			input: `
+base: &base
+  host: localhost
+  port: 80
+other: &other
+  tls: true
+svc:
+  name: web
+  <<: [ *base, *other ]
+  port: 8080
+copy: *base
+`,
+			expected: `{"base":{"host":"localhost","port":80},"other":{"tls":true},"svc":{"name":"web","host":"localhost","tls":true,"port":8080},"copy":{"host":"localhost","port":80}}`,
+		},
+		{
+			name: "yaml types",
+			input: `
+hex: 0x1F
+ts: 2024-01-02T03:04:05Z
+date: 2024-01-02
+quoted: "123"
+bin: !!binary aGVsbG8=
+ref: !Ref MyBucket
+3: int key
+true: bool key
+`,
+			expected: `{"hex":31,"ts":"2024-01-02T03:04:05Z","date":"2024-01-02T00:00:00Z","quoted":"123","bin":"aGVsbG8=","ref":"MyBucket","3":"int key","true":"bool key"}`,
+		},
+		{
+			name:     "block scalars",
+			input:    "text: |\n  line one\n  line two\n",
+			expected: `{"text":"line one\nline two\n"}`,
+		},
+		{
+			name:     "empty",
+			input:    "",
+			expected: `null`,
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			res, err := yamlProcResults(t, proc, test.input)
+			require.NoError(t, err)
+			assert.Equal(t, []string{test.expected}, res)
+		})
+	}
+}
+
+func TestYAMLToJSONErrors(t *testing.T) {
+	conf, err := yamlProcConfig().ParseYAML(`operator: to_json`, nil)
+	require.NoError(t, err)
+
+	proc, err := yamlProcFromParsed(conf)
+	require.NoError(t, err)
+
+	_, err = yamlProcResults(t, proc, "foo: [ bar")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse YAML document")
+
+	_, err = yamlProcResults(t, proc, "foo: .inf")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot be represented in JSON")
+
+	_, err = yamlProcResults(t, proc, "foo: 1\n---\nbar: 2\n")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "enable multi_document")
+}
+
+func TestYAMLToJSONMultiDocument(t *testing.T) {
+	conf, err := yamlProcConfig().ParseYAML(`
+operator: to_json
+multi_document: true
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := yamlProcFromParsed(conf)
+	require.NoError(t, err)
+
+	msg := service.NewMessage([]byte("kind: Service\n---\nkind: Deployment\n...\n---\n- 1\n"))
+	msg.MetaSetMut("foo", "bar")
+
+	batch, err := proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, batch, 3)
+
+	var results []string
+	for _, m := range batch {
+		mBytes, err := m.AsBytes()
+		require.NoError(t, err)
+		results = append(results, string(mBytes))
+
+		v, _ := m.MetaGetMut("foo")
+		assert.Equal(t, "bar", v)
+	}
+	assert.Equal(t, []string{`{"kind":"Service"}`, `{"kind":"Deployment"}`, `[1]`}, results)
+}
+
+func TestYAMLFromJSON(t *testing.T) {
+	conf, err := yamlProcConfig().ParseYAML(`operator: from_json`, nil)
+	require.NoError(t, err)
+
+	proc, err := yamlProcFromParsed(conf)
+	require.NoError(t, err)
+
+	res, err := yamlProcResults(t, proc, `{"zebra":1,"apple":{"b":"true","a":["x",2.5,null,false]},"text":"line one\nline two","big":12345678901234567890,"empty":{}}`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`zebra: 1
+apple:
+  b: "true"
+  a:
+    - x
+    - 2.5
+    - null
+    - false
+text: |-
+  line one
+  line two
+big: 12345678901234567890
+empty: {}
+`}, res)
+
+	_, err = yamlProcResults(t, proc, `{"foo":`)
+	require.Error(t, err)
+
+	_, err = yamlProcResults(t, proc, `{"foo":1} {"bar":2}`)
+	require.Error(t, err)
+}
+
+func TestYAMLRoundTrip(t *testing.T) {
+	conf, err := yamlProcConfig().ParseYAML(`operator: to_json`, nil)
+	require.NoError(t, err)
+
+	toJSON, err := yamlProcFromParsed(conf)
+	require.NoError(t, err)
+
+	pConf, err := yamlProcConfig().ParseYAML(`
+operator: from_json
+indent: 4
+`, nil)
+	require.NoError(t, err)
+
+	fromJSON, err := yamlProcFromParsed(pConf)
+	require.NoError(t, err)
+
+	input := `{"b":{"y":["1",1,true,1e+21],"x":"no"},"a":"2024-01-02"}`
+
+	yamlRes, err := yamlProcResults(t, fromJSON, input)
+	require.NoError(t, err)
+	require.Len(t, yamlRes, 1)
+
+	jsonRes, err := yamlProcResults(t, toJSON, yamlRes[0])
+	require.NoError(t, err)
+	assert.Equal(t, []string{input}, jsonRes)
+}
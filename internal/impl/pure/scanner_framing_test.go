@@ -0,0 +1,270 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func scanAll(t testing.TB, creator service.BatchScannerCreator, data []byte) ([]string, error) {
+	t.Helper()
+
+	scanner, err := creator.Create(io.NopCloser(bytes.NewReader(data)), func(context.Context, error) error {
+		return nil
+	}, service.NewScannerSourceDetails())
+	require.NoError(t, err)
+	defer scanner.Close(context.Background())
+
+	var res []string
+	for {
+		batch, _, err := scanner.NextBatch(context.Background())
+		if errors.Is(err, io.EOF) {
+			return res, nil
+		}
+		if err != nil {
+			return res, err
+		}
+		res = append(res, batchContents(t, batch)...)
+	}
+}
+
+func TestLengthPrefixedScanner(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		conf        string
+		input       []byte
+		output      []string
+		errContains string
+	}{
+		{
+			name:   "big endian 4 bytes",
+			conf:   `{}`,
+			input:  []byte("\x00\x00\x00\x03foo\x00\x00\x00\x00\x00\x00\x00\x06bar\nba"),
+			output: []string{"foo", "", "bar\nba"},
+		},
+		{
+			name: "little endian 2 bytes",
+			conf: `
+length_bytes: 2
+endianness: little
+`,
+			input:  []byte("\x03\x00foo\x01\x00x"),
+			output: []string{"foo", "x"},
+		},
+		{
+			name:        "truncated frame",
+			conf:        `length_bytes: 1`,
+			input:       []byte("\x03foo\x05ba"),
+			output:      []string{"foo"},
+			errContains: "part way through a frame",
+		},
+		{
+			name:        "frame too large",
+			conf:        `max_size: 4`,
+			input:       []byte("\x00\x00\x00\x05hello"),
+			errContains: "exceeds the maximum",
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			pConf, err := lengthPrefixedScannerSpec().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			creator, err := lengthPrefixedScannerFromParsed(pConf)
+			require.NoError(t, err)
+
+			res, err := scanAll(t, creator, test.input)
+			if test.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, test.output, res)
+		})
+	}
+}
+
+func TestNetstringScanner(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		input       string
+		output      []string
+		errContains string
+	}{
+		{
+			name:   "frames",
+			input:  "5:hello,0:,11:hello\nworld,",
+			output: []string{"hello", "", "hello\nworld"},
+		},
+		{
+			name:        "missing comma",
+			input:       "3:foo,3:barx",
+			output:      []string{"foo"},
+			errContains: "missing a trailing comma",
+		},
+		{
+			name:        "bad length",
+			input:       "x:foo,",
+			errContains: "unexpected character",
+		},
+		{
+			name:        "leading zero",
+			input:       "03:foo,",
+			errContains: "leading zero",
+		},
+		{
+			name:        "truncated",
+			input:       "5:hel",
+			errContains: "part way through a netstring",
+		},
+		{
+			name:        "too large",
+			input:       "100:foo",
+			errContains: "exceeds the maximum",
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			pConf, err := netstringScannerSpec().ParseYAML(`max_size: 50`, nil)
+			require.NoError(t, err)
+
+			creator, err := netstringScannerFromParsed(pConf)
+			require.NoError(t, err)
+
+			res, err := scanAll(t, creator, []byte(test.input))
+			if test.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, test.output, res)
+		})
+	}
+}
+
+func TestNDJSONScanner(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		conf        string
+		input       string
+		output      []string
+		errContains string
+	}{
+		{
+			name:   "lines",
+			conf:   `{}`,
+			input:  "{\"a\":1}\r\n\n  \n[1,2]\n\"foo\"",
+			output: []string{`{"a":1}`, `[1,2]`, `"foo"`},
+		},
+		{
+			name:        "invalid line errors",
+			conf:        `{}`,
+			input:       "{\"a\":1}\nnope\n{\"b\":2}\n",
+			output:      []string{`{"a":1}`},
+			errContains: "line 2 is not valid JSON",
+		},
+		{
+			name:   "invalid line skipped",
+			conf:   `on_invalid: skip`,
+			input:  "{\"a\":1}\nnope\n{\"b\":2}\n",
+			output: []string{`{"a":1}`, `{"b":2}`},
+		},
+		{
+			name: "long line skipped",
+			conf: `
+max_line_size: 10
+on_invalid: skip
+`,
+			input:  "{\"a\":1}\n{\"b\":\"" + string(bytes.Repeat([]byte("x"), 5000)) + "\"}\n{\"c\":\"xx\"}",
+			output: []string{`{"a":1}`, `{"c":"xx"}`},
+		},
+		{
+			name:        "long line errors",
+			conf:        `max_line_size: 10`,
+			input:       "{\"a\":1}\n{\"b\":\"xxxxxxxxxxx\"}\n",
+			output:      []string{`{"a":1}`},
+			errContains: "line 2 exceeds the maximum size of 10 bytes",
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			pConf, err := ndjsonScannerSpec().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			creator, err := ndjsonScannerFromParsed(pConf, service.MockResources().Logger())
+			require.NoError(t, err)
+
+			res, err := scanAll(t, creator, []byte(test.input))
+			if test.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, test.output, res)
+		})
+	}
+}
+
+func TestNDJSONScannerFlag(t *testing.T) {
+	pConf, err := ndjsonScannerSpec().ParseYAML(`
+max_line_size: 10
+on_invalid: flag
+`, nil)
+	require.NoError(t, err)
+
+	creator, err := ndjsonScannerFromParsed(pConf, service.MockResources().Logger())
+	require.NoError(t, err)
+
+	scanner, err := creator.Create(io.NopCloser(bytes.NewReader([]byte("{\"a\":1}\nnope\n{\"b\":\"xxxxxxxx\"}\n"))), func(context.Context, error) error {
+		return nil
+	}, service.NewScannerSourceDetails())
+	require.NoError(t, err)
+	defer scanner.Close(context.Background())
+
+	var contents, errs []string
+	for {
+		batch, _, err := scanner.NextBatch(context.Background())
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		for _, m := range batch {
+			b, err := m.AsBytes()
+			require.NoError(t, err)
+			contents = append(contents, string(b))
+
+			errStr := ""
+			if mErr := m.GetError(); mErr != nil {
+				errStr = mErr.Error()
+			}
+			errs = append(errs, errStr)
+		}
+	}
+
+	assert.Equal(t, []string{`{"a":1}`, `nope`, `{"b":"xxxx`}, contents)
+	assert.Equal(t, []string{"", "line 2 is not valid JSON", "line 3 exceeds the maximum size of 10 bytes"}, errs)
+}
@@ -0,0 +1,171 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	lpsFieldLengthBytes = "length_bytes"
+	lpsFieldEndianness  = "endianness"
+	lpsFieldMaxSize     = "max_size"
+)
+
+func lengthPrefixedScannerSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.31.0").
+		Summary("Consume frames that are each prefixed with an unsigned integer of their length in bytes.").
+		Description(`
+A data stream that ends part way through a frame results in an error, and a frame with a length that exceeds `+"`max_size`"+` results in an error without consuming the frame.`).
+		Fields(
+			service.NewIntField(lpsFieldLengthBytes).
+				Description("The size in bytes of the length prefix of each frame, which must be either 1, 2, 4 or 8.").
+				Default(4),
+			service.NewStringEnumField(lpsFieldEndianness, "big", "little").
+				Description("The byte order of the length prefix of each frame.").
+				Default("big"),
+			service.NewIntField(lpsFieldMaxSize).
+				Description("The maximum size in bytes of a frame, excluding its length prefix.").
+				Default(16*1024*1024).
+				Advanced(),
+		)
+}
+
+func init() {
+	err := service.RegisterBatchScannerCreator("length_prefixed", lengthPrefixedScannerSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchScannerCreator, error) {
+			return lengthPrefixedScannerFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func lengthPrefixedScannerFromParsed(conf *service.ParsedConfig) (*lengthPrefixedScannerCreator, error) {
+	c := &lengthPrefixedScannerCreator{}
+
+	var err error
+	if c.lengthBytes, err = conf.FieldInt(lpsFieldLengthBytes); err != nil {
+		return nil, err
+	}
+	switch c.lengthBytes {
+	case 1, 2, 4, 8:
+	default:
+		return nil, fmt.Errorf("length_bytes must be either 1, 2, 4 or 8, got %v", c.lengthBytes)
+	}
+
+	endianness, err := conf.FieldString(lpsFieldEndianness)
+	if err != nil {
+		return nil, err
+	}
+	switch endianness {
+	case "big":
+		c.order = binary.BigEndian
+	case "little":
+		c.order = binary.LittleEndian
+	default:
+		return nil, fmt.Errorf("endianness not recognised: %v", endianness)
+	}
+
+	if c.maxSize, err = conf.FieldInt(lpsFieldMaxSize); err != nil {
+		return nil, err
+	}
+	if c.maxSize <= 0 {
+		return nil, errors.New("max_size must be greater than zero")
+	}
+	return c, nil
+}
+
+type lengthPrefixedScannerCreator struct {
+	lengthBytes int
+	order       binary.ByteOrder
+	maxSize     int
+}
+
+func (c *lengthPrefixedScannerCreator) Create(rdr io.ReadCloser, aFn service.AckFunc, details *service.ScannerSourceDetails) (service.BatchScanner, error) {
+	return service.AutoAggregateBatchScannerAcks(&lengthPrefixedScanner{
+		c:      c,
+		r:      rdr,
+		br:     bufio.NewReader(rdr),
+		prefix: make([]byte, 8),
+	}, aFn), nil
+}
+
+func (c *lengthPrefixedScannerCreator) Close(context.Context) error {
+	return nil
+}
+
+type lengthPrefixedScanner struct {
+	c      *lengthPrefixedScannerCreator
+	r      io.ReadCloser
+	br     *bufio.Reader
+	prefix []byte
+}
+
+func (s *lengthPrefixedScanner) NextBatch(ctx context.Context) (service.MessageBatch, error) {
+	if s.r == nil {
+		return nil, io.EOF
+	}
+
+	prefix := s.prefix[:s.c.lengthBytes]
+	if _, err := io.ReadFull(s.br, prefix); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errors.New("data ended part way through a length prefix")
+		}
+		return nil, err
+	}
+
+	var size uint64
+	switch s.c.lengthBytes {
+	case 1:
+		size = uint64(prefix[0])
+	case 2:
+		size = uint64(s.c.order.Uint16(prefix))
+	case 4:
+		size = uint64(s.c.order.Uint32(prefix))
+	case 8:
+		size = s.c.order.Uint64(prefix)
+	}
+	if size > uint64(s.c.maxSize) {
+		return nil, fmt.Errorf("frame size %v exceeds the maximum of %v", size, s.c.maxSize)
+	}
+
+	frame := make([]byte, size)
+	if _, err := io.ReadFull(s.br, frame); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errors.New("data ended part way through a frame")
+		}
+		return nil, err
+	}
+	return service.MessageBatch{service.NewMessage(frame)}, nil
+}
+
+func (s *lengthPrefixedScanner) Close(ctx context.Context) error {
+	if s.r == nil {
+		return nil
+	}
+	err := s.r.Close()
+	s.r = nil
+	return err
+}
@@ -0,0 +1,189 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	ndsFieldMaxLineSize = "max_line_size"
+	ndsFieldOnInvalid   = "on_invalid"
+)
+
+func ndjsonScannerSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.31.0").
+		Summary("Consume a stream of newline delimited JSON documents, where each line is emitted as a message.").
+		Description(`
+The data is streamed and only a single line is held in memory at any given time, which makes this scanner suitable for very large objects. Lines that are empty or only contain whitespace are ignored, and a trailing carriage return is removed from each line.
+
+Lines that are not valid JSON, or that exceed `+"`max_line_size`"+`, are handled according to the `+"`on_invalid`"+` field.`).
+		Fields(
+			service.NewIntField(ndsFieldMaxLineSize).
+				Description("The maximum size in bytes of a line.").
+				Default(64*1024*1024).
+				Advanced(),
+			service.NewStringAnnotatedEnumField(ndsFieldOnInvalid, map[string]string{
+				"error": "Return an error, which stops the consumption of the stream.",
+				"skip":  "Skip the line and continue consuming the stream.",
+				"flag":  "Emit the line as a message that is flagged as failed, which can be handled using xref:configuration:error_handling.adoc[error handling methods]. Lines that exceed the maximum size are truncated to that size.",
+			}).
+				Description("Determines how lines that are invalid are handled.").
+				Default("error"),
+		)
+}
+
+func init() {
+	err := service.RegisterBatchScannerCreator("ndjson", ndjsonScannerSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchScannerCreator, error) {
+			return ndjsonScannerFromParsed(conf, mgr.Logger())
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func ndjsonScannerFromParsed(conf *service.ParsedConfig, log *service.Logger) (*ndjsonScannerCreator, error) {
+	c := &ndjsonScannerCreator{log: log}
+
+	var err error
+	if c.maxLineSize, err = conf.FieldInt(ndsFieldMaxLineSize); err != nil {
+		return nil, err
+	}
+	if c.maxLineSize <= 0 {
+		return nil, errors.New("max_line_size must be greater than zero")
+	}
+	if c.onInvalid, err = conf.FieldString(ndsFieldOnInvalid); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+type ndjsonScannerCreator struct {
+	maxLineSize int
+	onInvalid   string
+	log         *service.Logger
+}
+
+func (c *ndjsonScannerCreator) Create(rdr io.ReadCloser, aFn service.AckFunc, details *service.ScannerSourceDetails) (service.BatchScanner, error) {
+	return service.AutoAggregateBatchScannerAcks(&ndjsonScanner{
+		maxLineSize: c.maxLineSize,
+		onInvalid:   c.onInvalid,
+		log:         c.log,
+		r:           rdr,
+		br:          bufio.NewReader(rdr),
+	}, aFn), nil
+}
+
+func (c *ndjsonScannerCreator) Close(context.Context) error {
+	return nil
+}
+
+type ndjsonScanner struct {
+	maxLineSize int
+	onInvalid   string
+	log         *service.Logger
+	r           io.ReadCloser
+	br          *bufio.Reader
+
+	lineNum int
+}
+
+// readLine reads the next line, holding no more than maxLineSize bytes of it
+// in memory. The remainder of lines that exceed the maximum size is discarded.
+func (s *ndjsonScanner) readLine() (line []byte, tooLong bool, err error) {
+	size := 0
+	for {
+		frag, rErr := s.br.ReadSlice('\n')
+		ended := rErr == nil
+		if ended {
+			frag = frag[:len(frag)-1]
+		}
+
+		size += len(frag)
+		if remaining := s.maxLineSize - len(line); remaining > 0 {
+			line = append(line, frag[:min(len(frag), remaining)]...)
+		}
+
+		if ended {
+			break
+		}
+		if errors.Is(rErr, bufio.ErrBufferFull) {
+			continue
+		}
+		if errors.Is(rErr, io.EOF) && size > 0 {
+			break
+		}
+		return nil, false, rErr
+	}
+	return bytes.TrimSuffix(line, []byte("\r")), size > s.maxLineSize, nil
+}
+
+func (s *ndjsonScanner) NextBatch(ctx context.Context) (service.MessageBatch, error) {
+	if s.r == nil {
+		return nil, io.EOF
+	}
+
+	for {
+		line, tooLong, err := s.readLine()
+		if err != nil {
+			return nil, err
+		}
+		s.lineNum++
+
+		var invalidErr error
+		if tooLong {
+			invalidErr = fmt.Errorf("line %v exceeds the maximum size of %v bytes", s.lineNum, s.maxLineSize)
+		} else if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		} else if !json.Valid(line) {
+			invalidErr = fmt.Errorf("line %v is not valid JSON", s.lineNum)
+		}
+
+		if invalidErr == nil {
+			return service.MessageBatch{service.NewMessage(line)}, nil
+		}
+
+		switch s.onInvalid {
+		case "skip":
+			s.log.Debugf("Skipping invalid line: %v", invalidErr)
+		case "flag":
+			msg := service.NewMessage(line)
+			msg.SetError(invalidErr)
+			return service.MessageBatch{msg}, nil
+		default:
+			return nil, invalidErr
+		}
+	}
+}
+
+func (s *ndjsonScanner) Close(ctx context.Context) error {
+	if s.r == nil {
+		return nil
+	}
+	err := s.r.Close()
+	s.r = nil
+	return err
+}
@@ -0,0 +1,151 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pure
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	nssFieldMaxSize = "max_size"
+)
+
+func netstringScannerSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.31.0").
+		Summary("Consume frames encoded as https://cr.yp.to/proto/netstrings.txt[netstrings^].").
+		Description(`
+Each frame is encoded as its length in bytes written as an ASCII decimal number, followed by a colon, the contents of the frame and a trailing comma, such that the frame ` + "`hello`" + ` is encoded as ` + "`5:hello,`" + `. Malformed frames result in an error.`).
+		Fields(
+			service.NewIntField(nssFieldMaxSize).
+				Description("The maximum size in bytes of a frame.").
+				Default(16 * 1024 * 1024).
+				Advanced(),
+		)
+}
+
+func init() {
+	err := service.RegisterBatchScannerCreator("netstring", netstringScannerSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchScannerCreator, error) {
+			return netstringScannerFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func netstringScannerFromParsed(conf *service.ParsedConfig) (*netstringScannerCreator, error) {
+	c := &netstringScannerCreator{}
+
+	var err error
+	if c.maxSize, err = conf.FieldInt(nssFieldMaxSize); err != nil {
+		return nil, err
+	}
+	if c.maxSize <= 0 {
+		return nil, errors.New("max_size must be greater than zero")
+	}
+	return c, nil
+}
+
+type netstringScannerCreator struct {
+	maxSize int
+}
+
+func (c *netstringScannerCreator) Create(rdr io.ReadCloser, aFn service.AckFunc, details *service.ScannerSourceDetails) (service.BatchScanner, error) {
+	return service.AutoAggregateBatchScannerAcks(&netstringScanner{
+		maxSize: c.maxSize,
+		r:       rdr,
+		br:      bufio.NewReader(rdr),
+	}, aFn), nil
+}
+
+func (c *netstringScannerCreator) Close(context.Context) error {
+	return nil
+}
+
+type netstringScanner struct {
+	maxSize int
+	r       io.ReadCloser
+	br      *bufio.Reader
+}
+
+func (s *netstringScanner) readLength() (int, error) {
+	size, digits := 0, 0
+	for {
+		b, err := s.br.ReadByte()
+		if err != nil {
+			if errors.Is(err, io.EOF) && digits > 0 {
+				return 0, errors.New("data ended part way through a netstring length")
+			}
+			return 0, err
+		}
+		if b == ':' {
+			if digits == 0 {
+				return 0, errors.New("netstring is missing a length")
+			}
+			return size, nil
+		}
+		if b < '0' || b > '9' {
+			return 0, fmt.Errorf("unexpected character %q within netstring length", b)
+		}
+		if digits > 0 && size == 0 {
+			return 0, errors.New("netstring length has a leading zero")
+		}
+		size = size*10 + int(b-'0')
+		digits++
+		if size > s.maxSize {
+			return 0, fmt.Errorf("netstring size exceeds the maximum of %v", s.maxSize)
+		}
+	}
+}
+
+func (s *netstringScanner) NextBatch(ctx context.Context) (service.MessageBatch, error) {
+	if s.r == nil {
+		return nil, io.EOF
+	}
+
+	size, err := s.readLength()
+	if err != nil {
+		return nil, err
+	}
+
+	frame := make([]byte, size+1)
+	if _, err := io.ReadFull(s.br, frame); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errors.New("data ended part way through a netstring")
+		}
+		return nil, err
+	}
+	if frame[size] != ',' {
+		return nil, errors.New("netstring is missing a trailing comma")
+	}
+	return service.MessageBatch{service.NewMessage(frame[:size])}, nil
+}
+
+func (s *netstringScanner) Close(ctx context.Context) error {
+	if s.r == nil {
+		return nil
+	}
+	err := s.r.Close()
+	s.r = nil
+	return err
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	_ "github.com/redpanda-data/benthos/v4/public/components/pure"
+	"github.com/redpanda-data/benthos/v4/public/service"
 	"github.com/redpanda-data/benthos/v4/public/service/integration"
 )
 
@@ -270,6 +271,96 @@ output:
 			integration.StreamTestOptPort(resource.GetPort("6379/tcp")),
 		)
 	})
+
+	// SORTED SET
+	t.Run("zset", func(t *testing.T) {
+		t.Parallel()
+		template := `
+output:
+  redis_zset:
+    url: tcp://localhost:$PORT
+    key: zset-$ID
+    score: ${! json("id") }
+    max_in_flight: $MAX_IN_FLIGHT
+    batching:
+      count: $OUTPUT_BATCH_COUNT
+`
+		zsetGetFn := func(ctx context.Context, testID, id string) (string, []string, error) {
+			client := redis.NewClient(&redis.Options{
+				Addr:    fmt.Sprintf("localhost:%v", resource.GetPort("6379/tcp")),
+				Network: "tcp",
+			})
+			res, err := client.ZRangeByScore(ctx, "zset-"+testID, &redis.ZRangeBy{
+				Min: id,
+				Max: id,
+			}).Result()
+			if err != nil {
+				return "", nil, err
+			}
+			if len(res) != 1 {
+				return "", nil, fmt.Errorf("expected one member with score %v, found %v", id, len(res))
+			}
+			return res[0], nil, nil
+		}
+		suite := integration.StreamTests(
+			integration.StreamTestOutputOnlySendSequential(10, zsetGetFn),
+			integration.StreamTestOutputOnlySendBatch(10, zsetGetFn),
+		)
+		suite.Run(
+			t, template,
+			integration.StreamTestOptSleepAfterInput(100*time.Millisecond),
+			integration.StreamTestOptSleepAfterOutput(100*time.Millisecond),
+			integration.StreamTestOptPort(resource.GetPort("6379/tcp")),
+		)
+	})
+
+	t.Run("zset capped", func(t *testing.T) {
+		t.Parallel()
+
+		conf, err := redisZSetOutputConfig().ParseYAML(fmt.Sprintf(`
+url: tcp://localhost:%v
+key: zset-capped
+member: ${! this.name }
+score: ${! this.score }
+max_length: 3
+min_score: "2"
+`, resource.GetPort("6379/tcp")), nil)
+		require.NoError(t, err)
+
+		w, err := newRedisZSetWriter(conf, service.MockResources())
+		require.NoError(t, err)
+
+		ctx := context.Background()
+		require.NoError(t, w.Connect(ctx))
+		t.Cleanup(func() {
+			_ = w.Close(ctx)
+		})
+
+		var batch service.MessageBatch
+		for _, doc := range []string{
+			`{"name":"a","score":1}`,
+			`{"name":"b","score":5}`,
+			`{"name":"c","score":3}`,
+			`{"name":"d","score":4}`,
+			`{"name":"e","score":2}`,
+			`{"name":"f","score":6}`,
+		} {
+			batch = append(batch, service.NewMessage([]byte(doc)))
+		}
+		require.NoError(t, w.WriteBatch(ctx, batch))
+
+		members, err := client.ZRange(ctx, "zset-capped", 0, -1).Result()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"d", "b", "f"}, members)
+
+		require.NoError(t, w.WriteBatch(ctx, service.MessageBatch{
+			service.NewMessage([]byte(`{"name":"a","score":10}`)),
+		}))
+
+		members, err = client.ZRange(ctx, "zset-capped", 0, -1).Result()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"b", "f", "a"}, members)
+	})
 }
 
 func BenchmarkIntegrationRedis(b *testing.B) {
@@ -0,0 +1,293 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	zoFieldKey       = "key"
+	zoFieldMember    = "member"
+	zoFieldScore     = "score"
+	zoFieldMaxLength = "max_length"
+	zoFieldMinScore  = "min_score"
+	zoFieldBatching  = "batching"
+)
+
+func redisZSetOutputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.31.0").
+		Summary(`Adds messages to a Redis sorted set (which is created if it doesn't already exist) using the ZADD command.`).
+		Description(`
+The fields `+"`key`, `member` and `score`"+` support xref:configuration:interpolation.adoc#bloblang-queries[interpolation functions], allowing you to determine the sorted set, member and score of each message. The score must resolve to a number, and when a member already exists within the set its score is updated.
+
+== Capping sorted sets
+
+Sorted sets can be kept to a bounded size with the fields `+"`max_length`"+` and `+"`min_score`"+`. After each batch is written the members with the lowest scores are removed from each set that was written to until it holds at most `+"`max_length`"+` members, and members with a score lower than `+"`min_score`"+` are removed. Therefore, sets that are ordered by time should use a timestamp as the score, so that the oldest members are removed first.`+service.OutputPerformanceDocs(true, true)).
+		Categories("Services").
+		Fields(clientFields()...).
+		Fields(
+			service.NewInterpolatedStringField(zoFieldKey).
+				Description("The key of the sorted set to add each message to.").
+				Examples("leaderboard", "${! @.kafka_key }", "events-${! this.user_id }"),
+			service.NewInterpolatedStringField(zoFieldMember).
+				Description("The member to add to the sorted set for each message.").
+				Examples("${! this.player }", "${! this.event_id }").
+				Default("${! content() }"),
+			service.NewInterpolatedStringField(zoFieldScore).
+				Description("The score of the member, which must resolve to a number.").
+				Examples("${! this.points }", "${! timestamp_unix_milli() }", "${! this.created_at.ts_unix() }"),
+			service.NewIntField(zoFieldMaxLength).
+				Description("An optional maximum number of members of each sorted set written to, where the members with the lowest scores are removed first.").
+				Example(100).
+				Optional(),
+			service.NewInterpolatedStringField(zoFieldMinScore).
+				Description("An optional minimum score of members of each sorted set written to, members with a lower score are removed. This field is resolved against the last message of a batch written to each set.").
+				Example("${! timestamp_unix() - 3600 }").
+				Optional(),
+			service.NewOutputMaxInFlightField(),
+			service.NewBatchPolicyField(zoFieldBatching),
+		).
+		Example("Bounded leaderboard", "Maintain the top 100 players of each game.", `
+output:
+  redis_zset:
+    url: tcp://localhost:6379
+    key: leaderboard-${! this.game }
+    member: ${! this.player }
+    score: ${! this.points }
+    max_length: 100
+`).
+		Example("Time ordered index", "Index event IDs by the time they occurred, keeping the last 24 hours.", `
+output:
+  redis_zset:
+    url: tcp://localhost:6379
+    key: events
+    member: ${! this.id }
+    score: ${! this.timestamp.ts_unix() }
+    min_score: ${! timestamp_unix() - 86400 }
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"redis_zset", redisZSetOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPol service.BatchPolicy, mif int, err error) {
+			if batchPol, err = conf.FieldBatchPolicy(zoFieldBatching); err != nil {
+				return
+			}
+			if mif, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newRedisZSetWriter(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type redisZSetWriter struct {
+	log *service.Logger
+
+	key       *service.InterpolatedString
+	member    *service.InterpolatedString
+	score     *service.InterpolatedString
+	maxLength int64
+	minScore  *service.InterpolatedString
+
+	clientCtor func() (redis.UniversalClient, error)
+	client     redis.UniversalClient
+	connMut    sync.RWMutex
+}
+
+func newRedisZSetWriter(conf *service.ParsedConfig, mgr *service.Resources) (r *redisZSetWriter, err error) {
+	r = &redisZSetWriter{
+		log: mgr.Logger(),
+		clientCtor: func() (redis.UniversalClient, error) {
+			return getClient(conf)
+		},
+	}
+	if _, err = getClient(conf); err != nil {
+		return
+	}
+
+	if r.key, err = conf.FieldInterpolatedString(zoFieldKey); err != nil {
+		return
+	}
+	if r.member, err = conf.FieldInterpolatedString(zoFieldMember); err != nil {
+		return
+	}
+	if r.score, err = conf.FieldInterpolatedString(zoFieldScore); err != nil {
+		return
+	}
+	if conf.Contains(zoFieldMaxLength) {
+		var maxLength int
+		if maxLength, err = conf.FieldInt(zoFieldMaxLength); err != nil {
+			return
+		}
+		if maxLength < 1 {
+			return nil, errors.New("max_length must be greater than zero")
+		}
+		r.maxLength = int64(maxLength)
+	}
+	if conf.Contains(zoFieldMinScore) {
+		if r.minScore, err = conf.FieldInterpolatedString(zoFieldMinScore); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (r *redisZSetWriter) Connect(ctx context.Context) error {
+	r.connMut.Lock()
+	defer r.connMut.Unlock()
+
+	client, err := r.clientCtor()
+	if err != nil {
+		return err
+	}
+	if _, err = client.Ping(ctx).Result(); err != nil {
+		return err
+	}
+	r.client = client
+	return nil
+}
+
+func parseZScore(s string) (float64, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, fmt.Errorf("score must be a number: %w", err)
+	}
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, fmt.Errorf("score must be a finite number: %v", s)
+	}
+	return f, nil
+}
+
+func (r *redisZSetWriter) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	r.connMut.RLock()
+	client := r.client
+	r.connMut.RUnlock()
+
+	if client == nil {
+		return service.ErrNotConnected
+	}
+
+	pipe := client.Pipeline()
+
+	// The index of the last message written to each key, which determines
+	// the trimming of the set.
+	lastIndexes := map[string]int{}
+	var keys []string
+
+	adds := make([]*redis.IntCmd, len(batch))
+	for i := range batch {
+		key, err := batch.TryInterpolatedString(i, r.key)
+		if err != nil {
+			return fmt.Errorf("key interpolation error: %w", err)
+		}
+		member, err := batch.TryInterpolatedString(i, r.member)
+		if err != nil {
+			return fmt.Errorf("member interpolation error: %w", err)
+		}
+		scoreStr, err := batch.TryInterpolatedString(i, r.score)
+		if err != nil {
+			return fmt.Errorf("score interpolation error: %w", err)
+		}
+		score, err := parseZScore(scoreStr)
+		if err != nil {
+			return err
+		}
+
+		adds[i] = pipe.ZAdd(ctx, key, redis.Z{Score: score, Member: member})
+		if _, exists := lastIndexes[key]; !exists {
+			keys = append(keys, key)
+		}
+		lastIndexes[key] = i
+	}
+
+	for _, key := range keys {
+		if r.maxLength > 0 {
+			_ = pipe.ZRemRangeByRank(ctx, key, 0, -(r.maxLength + 1))
+		}
+		if r.minScore != nil {
+			minScoreStr, err := batch.TryInterpolatedString(lastIndexes[key], r.minScore)
+			if err != nil {
+				return fmt.Errorf("min score interpolation error: %w", err)
+			}
+			minScore, err := parseZScore(minScoreStr)
+			if err != nil {
+				return fmt.Errorf("min score: %w", err)
+			}
+			_ = pipe.ZRemRangeByScore(ctx, key, "-inf", "("+strconv.FormatFloat(minScore, 'f', -1, 64))
+		}
+	}
+
+	cmders, err := pipe.Exec(ctx)
+	if err != nil && !errors.As(err, new(redis.Error)) {
+		_ = r.disconnect()
+		r.log.Errorf("Error from redis: %v\n", err)
+		return service.ErrNotConnected
+	}
+
+	var batchErr *service.BatchError
+	for i, cmd := range adds {
+		if cmdErr := cmd.Err(); cmdErr != nil {
+			if batchErr == nil {
+				batchErr = service.NewBatchError(batch, cmdErr)
+			}
+			batchErr.Failed(i, cmdErr)
+		}
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+
+	// Failing to trim a set does not affect the messages that were added, so
+	// these errors are logged rather than causing the batch to be retried.
+	for _, cmd := range cmders[len(adds):] {
+		if cmdErr := cmd.Err(); cmdErr != nil {
+			r.log.Errorf("Failed to trim sorted set: %v", cmdErr)
+		}
+	}
+	return nil
+}
+
+func (r *redisZSetWriter) disconnect() error {
+	r.connMut.Lock()
+	defer r.connMut.Unlock()
+	if r.client != nil {
+		err := r.client.Close()
+		r.client = nil
+		return err
+	}
+	return nil
+}
+
+func (r *redisZSetWriter) Close(context.Context) error {
+	return r.disconnect()
+}
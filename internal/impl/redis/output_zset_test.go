@@ -0,0 +1,62 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestRedisZSetOutputConfErrors(t *testing.T) {
+	conf, err := redisZSetOutputConfig().ParseYAML(`
+url: redis://localhost:6379
+key: foo
+score: ${! this.score }
+max_length: 0
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newRedisZSetWriter(conf, service.MockResources())
+	require.Error(t, err)
+
+	_, err = redisZSetOutputConfig().ParseYAML(`
+url: redis://localhost:6379
+key: foo
+`, nil)
+	require.Error(t, err)
+}
+
+func TestRedisZSetParseScore(t *testing.T) {
+	for input, exp := range map[string]float64{
+		"10":                   10,
+		" -1.5 ":               -1.5,
+		"1.7e+09":              1.7e9,
+		"1718000000123":        1718000000123,
+		"0.000000000000000001": 1e-18,
+	} {
+		f, err := parseZScore(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, exp, f, input)
+	}
+
+	for _, input := range []string{"", "nope", "inf", "-Inf", "NaN"} {
+		_, err := parseZScore(input)
+		require.Error(t, err, input)
+	}
+}
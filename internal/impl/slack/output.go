@@ -0,0 +1,468 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	soFieldWebhookURL       = "webhook_url"
+	soFieldBotToken         = "bot_token"
+	soFieldChannel          = "channel"
+	soFieldText             = "text"
+	soFieldThreadTS         = "thread_ts"
+	soFieldChannelRateLimit = "channel_rate_limit"
+	soFieldMaxRetries       = "max_retries"
+	soFieldCoalesce         = "coalesce"
+	soFieldBatching         = "batching"
+)
+
+func outputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.31.0").
+		Categories("Services", "Social").
+		Summary("Posts messages to Slack via an incoming webhook or the Web API.").
+		Description(`
+Messages are posted either to an https://api.slack.com/messaging/webhooks[incoming webhook^] when `+"`webhook_url`"+` is set, or to the https://api.slack.com/methods/chat.postMessage[`+"`chat.postMessage`"+` Web API method^] authenticated with a bot token when `+"`bot_token`"+` is set, in which case `+"`channel`"+` is required.
+
+When `+"`text`"+` is set each message is posted as its result. Otherwise messages that are JSON objects are posted as they are, which allows https://api.slack.com/block-kit[Block Kit^] payloads to be written with a mapping, and other messages are posted as text.
+
+== Rate limits
+
+Messages posted to the same channel are spaced by at least `+"`channel_rate_limit`"+`, which by default matches the limit of one message per second per channel imposed by Slack. When Slack responds with a status of 429 posts to the channel are paused for the period of the `+"`Retry-After`"+` header, after which the message is retried, up to `+"`max_retries`"+` times before the write is failed and retried by the pipeline. Posts to a webhook are always limited as a single channel.
+
+The limits are shared by all messages in flight for this output, but not with other outputs or instances of Redpanda Connect posting to the same workspace.
+
+== Coalescing
+
+When `+"`coalesce`"+` is `+"`true`"+` the text of messages within a batch that are posted to the same channel and thread are joined with line breaks and posted as a single message, which combined with a `+"`batching`"+` period allows bursts of alerts to be posted without being throttled. Block Kit payloads are never coalesced.`).
+		Fields(
+			service.NewStringField(soFieldWebhookURL).
+				Description("The URL of an incoming webhook to post messages to.").
+				Secret().
+				Optional(),
+			service.NewStringField(soFieldBotToken).
+				Description("A bot token used to post messages with the Web API.").
+				Secret().
+				Optional(),
+			service.NewInterpolatedStringField(soFieldChannel).
+				Description("The channel to post messages to, which is required when posting with the Web API and is otherwise ignored.").
+				Optional().
+				Example("#alerts").
+				Example("${! @channel_id }"),
+			service.NewInterpolatedStringField(soFieldText).
+				Description("An optional template of the text of each message.").
+				Optional().
+				Example(`${! this.severity.uppercase() }: ${! this.summary }`),
+			service.NewInterpolatedStringField(soFieldThreadTS).
+				Description("An optional timestamp of a parent message to post replies to. Messages where this resolves to an empty string are posted to the channel.").
+				Optional().
+				Example(`${! @slack_thread_ts.or("") }`),
+			service.NewDurationField(soFieldChannelRateLimit).
+				Description("The minimum period between messages posted to the same channel.").
+				Default("1s").
+				Advanced(),
+			service.NewIntField(soFieldMaxRetries).
+				Description("The maximum number of times a message is retried after a response with a status of 429 before the write is failed.").
+				Default(5).
+				Advanced(),
+			service.NewBoolField(soFieldCoalesce).
+				Description("Whether to join the text of messages in a batch that are posted to the same channel and thread.").
+				Default(false),
+			service.NewOutputMaxInFlightField().Default(1),
+			service.NewBatchPolicyField(soFieldBatching),
+		).
+		Example("Coalesced alerts", "Post alerts to a channel, joining alerts that arrive within five seconds of each other into a single message.", `
+output:
+  slack:
+    bot_token: ${SLACK_BOT_TOKEN}
+    channel: "#alerts"
+    text: '${! this.severity.uppercase() }: ${! this.summary }'
+    coalesce: true
+    batching:
+      period: 5s
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("slack", outputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(soFieldBatching); err != nil {
+				return
+			}
+			out, err = outputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+const defaultAPIURL = "https://slack.com/api"
+
+type output struct {
+	webhookURL string
+	botToken   string
+	channel    *service.InterpolatedString
+	text       *service.InterpolatedString
+	threadTS   *service.InterpolatedString
+	maxRetries int
+	coalesce   bool
+
+	apiURL  string
+	limiter *channelLimiter
+	client  *http.Client
+	log     *service.Logger
+}
+
+func outputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*output, error) {
+	o := &output{
+		apiURL: defaultAPIURL,
+		client: &http.Client{Timeout: 30 * time.Second},
+		log:    mgr.Logger(),
+	}
+
+	var err error
+	if conf.Contains(soFieldWebhookURL) {
+		if o.webhookURL, err = conf.FieldString(soFieldWebhookURL); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(soFieldBotToken) {
+		if o.botToken, err = conf.FieldString(soFieldBotToken); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(soFieldChannel) {
+		if o.channel, err = conf.FieldInterpolatedString(soFieldChannel); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case o.webhookURL == "" && o.botToken == "":
+		return nil, errors.New("either webhook_url or bot_token must be set")
+	case o.webhookURL != "" && o.botToken != "":
+		return nil, errors.New("webhook_url and bot_token cannot both be set")
+	case o.botToken != "" && o.channel == nil:
+		return nil, errors.New("channel is required when bot_token is set")
+	}
+
+	if conf.Contains(soFieldText) {
+		if o.text, err = conf.FieldInterpolatedString(soFieldText); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(soFieldThreadTS) {
+		if o.threadTS, err = conf.FieldInterpolatedString(soFieldThreadTS); err != nil {
+			return nil, err
+		}
+	}
+
+	interval, err := conf.FieldDuration(soFieldChannelRateLimit)
+	if err != nil {
+		return nil, err
+	}
+	o.limiter = newChannelLimiter(interval)
+
+	if o.maxRetries, err = conf.FieldInt(soFieldMaxRetries); err != nil {
+		return nil, err
+	}
+	if o.coalesce, err = conf.FieldBool(soFieldCoalesce); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (o *output) Connect(ctx context.Context) error {
+	return nil
+}
+
+// post is a single message to be posted, which is either text or a payload
+// object, along with the indexes of the batch messages it was created from.
+type post struct {
+	channel  string
+	threadTS string
+	text     string
+	payload  map[string]any
+	indexes  []int
+}
+
+func (o *output) postFromMessage(batch service.MessageBatch, i int) (p post, err error) {
+	if o.channel != nil {
+		if p.channel, err = batch.TryInterpolatedString(i, o.channel); err != nil {
+			return p, fmt.Errorf("channel interpolation error: %w", err)
+		}
+	}
+	if o.threadTS != nil {
+		if p.threadTS, err = batch.TryInterpolatedString(i, o.threadTS); err != nil {
+			return p, fmt.Errorf("thread_ts interpolation error: %w", err)
+		}
+	}
+	if o.text != nil {
+		if p.text, err = batch.TryInterpolatedString(i, o.text); err != nil {
+			return p, fmt.Errorf("text interpolation error: %w", err)
+		}
+		return p, nil
+	}
+
+	mBytes, err := batch[i].AsBytes()
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(mBytes, &p.payload); err != nil || p.payload == nil {
+		p.payload = nil
+		p.text = string(mBytes)
+	}
+	return p, nil
+}
+
+// coalescePosts joins the text and batch indexes of posts to the same channel
+// and thread into the first of them, preserving the order of the first post of
+// each.
+func coalescePosts(posts []post) []post {
+	type target struct{ channel, threadTS string }
+
+	indexes := map[target]int{}
+	coalesced := make([]post, 0, len(posts))
+	for _, p := range posts {
+		if p.payload != nil {
+			coalesced = append(coalesced, p)
+			continue
+		}
+		t := target{channel: p.channel, threadTS: p.threadTS}
+		if i, exists := indexes[t]; exists {
+			coalesced[i].text += "\n" + p.text
+			coalesced[i].indexes = append(coalesced[i].indexes, p.indexes...)
+			continue
+		}
+		indexes[t] = len(coalesced)
+		coalesced = append(coalesced, p)
+	}
+	return coalesced
+}
+
+func (o *output) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	var batchErr *service.BatchError
+	fail := func(indexes []int, err error) {
+		if batchErr == nil {
+			batchErr = service.NewBatchError(batch, err)
+		}
+		for _, i := range indexes {
+			batchErr.Failed(i, err)
+		}
+	}
+
+	posts := make([]post, 0, len(batch))
+	for i := range batch {
+		p, err := o.postFromMessage(batch, i)
+		if err != nil {
+			fail([]int{i}, err)
+			continue
+		}
+		p.indexes = []int{i}
+		posts = append(posts, p)
+	}
+	if o.coalesce {
+		posts = coalescePosts(posts)
+	}
+
+	for _, p := range posts {
+		if err := o.send(ctx, p); err != nil {
+			fail(p.indexes, err)
+		}
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+func (o *output) send(ctx context.Context, p post) error {
+	body := map[string]any{}
+	for k, v := range p.payload {
+		body[k] = v
+	}
+	if p.payload == nil {
+		body["text"] = p.text
+	}
+	if p.threadTS != "" {
+		body["thread_ts"] = p.threadTS
+	}
+
+	url, limitKey := o.webhookURL, "webhook"
+	if o.botToken != "" {
+		url, limitKey = o.apiURL+"/chat.postMessage", p.channel
+		body["channel"] = p.channel
+	}
+
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := o.limiter.wait(ctx, limitKey); err != nil {
+			return err
+		}
+
+		retryAfter, err := o.do(ctx, url, bodyBytes)
+		if err == nil {
+			return nil
+		}
+		if retryAfter <= 0 {
+			return err
+		}
+
+		o.limiter.pause(limitKey, time.Now().Add(retryAfter))
+		if attempt >= o.maxRetries {
+			return err
+		}
+		o.log.Debugf("Rate limited by Slack, retrying after %v", retryAfter)
+	}
+}
+
+// do performs a post and returns the period to wait before retrying when the
+// post was rate limited.
+func (o *output) do(ctx context.Context, url string, body []byte) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if o.botToken != "" {
+		req.Header.Set("Authorization", "Bearer "+o.botToken)
+	}
+
+	res, err := o.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	resBody, _ := io.ReadAll(io.LimitReader(res.Body, 64*1024))
+
+	if res.StatusCode == http.StatusTooManyRequests {
+		return parseRetryAfter(res.Header.Get("Retry-After")), errors.New("rate limited by slack")
+	}
+	if res.StatusCode/100 != 2 {
+		return 0, fmt.Errorf("slack responded with status %v: %s", res.StatusCode, bytes.TrimSpace(resBody))
+	}
+
+	if o.botToken != "" {
+		var apiRes struct {
+			OK    bool   `json:"ok"`
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(resBody, &apiRes); err != nil {
+			return 0, fmt.Errorf("failed to parse slack response: %w", err)
+		}
+		if !apiRes.OK {
+			return 0, fmt.Errorf("slack responded with error: %v", apiRes.Error)
+		}
+	}
+	return 0, nil
+}
+
+func parseRetryAfter(v string) time.Duration {
+	if secs, err := strconv.Atoi(strings.TrimSpace(v)); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return time.Second
+}
+
+func (o *output) Close(ctx context.Context) error {
+	o.client.CloseIdleConnections()
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// channelLimiter spaces posts to each channel by an interval, and allows posts
+// to a channel to be paused until a given time.
+type channelLimiter struct {
+	interval time.Duration
+
+	mut  sync.Mutex
+	next map[string]time.Time
+}
+
+func newChannelLimiter(interval time.Duration) *channelLimiter {
+	return &channelLimiter{
+		interval: interval,
+		next:     map[string]time.Time{},
+	}
+}
+
+// wait blocks until a post to a channel is allowed, and reserves the
+// following interval of the channel.
+func (l *channelLimiter) wait(ctx context.Context, channel string) error {
+	for {
+		l.mut.Lock()
+		now := time.Now()
+		next := l.next[channel]
+		if !next.After(now) {
+			l.prune(now)
+			l.next[channel] = now.Add(l.interval)
+			l.mut.Unlock()
+			return nil
+		}
+		l.mut.Unlock()
+
+		select {
+		case <-time.After(next.Sub(now)):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// pause prevents posts to a channel until a given time.
+func (l *channelLimiter) pause(channel string, until time.Time) {
+	l.mut.Lock()
+	if until.After(l.next[channel]) {
+		l.next[channel] = until
+	}
+	l.mut.Unlock()
+}
+
+// prune removes channels that are no longer limited once there are many of
+// them, in order to bound memory when channels are dynamic.
+func (l *channelLimiter) prune(now time.Time) {
+	if len(l.next) < 1024 {
+		return
+	}
+	for k, t := range l.next {
+		if !t.After(now) {
+			delete(l.next, k)
+		}
+	}
+}
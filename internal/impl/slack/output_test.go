@@ -0,0 +1,287 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type recordingServer struct {
+	mut       sync.Mutex
+	bodies    []map[string]any
+	auth      []string
+	responses []func(w http.ResponseWriter)
+}
+
+func (s *recordingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	b, _ := io.ReadAll(r.Body)
+	var body map[string]any
+	_ = json.Unmarshal(b, &body)
+	s.bodies = append(s.bodies, body)
+	s.auth = append(s.auth, r.Header.Get("Authorization"))
+
+	if len(s.responses) > 0 {
+		res := s.responses[0]
+		s.responses = s.responses[1:]
+		res(w)
+		return
+	}
+	_, _ = w.Write([]byte(`{"ok":true}`))
+}
+
+func TestSlackWebhook(t *testing.T) {
+	rec := &recordingServer{}
+	ts := httptest.NewServer(rec)
+	t.Cleanup(ts.Close)
+
+	conf, err := outputSpec().ParseYAML(`
+webhook_url: `+ts.URL+`
+thread_ts: ${! @thread.or("") }
+channel_rate_limit: 1ms
+`, nil)
+	require.NoError(t, err)
+
+	o, err := outputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	msgA := service.NewMessage([]byte(`hello world`))
+	msgB := service.NewMessage([]byte(`{"blocks":[{"type":"section","text":{"type":"mrkdwn","text":"*hi*"}}]}`))
+	msgB.MetaSetMut("thread", "1717243200.000100")
+
+	require.NoError(t, o.WriteBatch(context.Background(), service.MessageBatch{msgA, msgB}))
+
+	assert.Equal(t, []map[string]any{
+		{"text": "hello world"},
+		{
+			"blocks": []any{
+				map[string]any{"type": "section", "text": map[string]any{"type": "mrkdwn", "text": "*hi*"}},
+			},
+			"thread_ts": "1717243200.000100",
+		},
+	}, rec.bodies)
+	assert.Equal(t, []string{"", ""}, rec.auth)
+}
+
+func TestSlackWebAPICoalesce(t *testing.T) {
+	rec := &recordingServer{}
+	ts := httptest.NewServer(rec)
+	t.Cleanup(ts.Close)
+
+	conf, err := outputSpec().ParseYAML(`
+bot_token: xoxb-foo
+channel: ${! @channel }
+text: '${! this.severity }: ${! this.summary }'
+coalesce: true
+channel_rate_limit: 1ms
+`, nil)
+	require.NoError(t, err)
+
+	o, err := outputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	o.apiURL = ts.URL
+
+	var batch service.MessageBatch
+	for _, v := range []struct{ channel, content string }{
+		{"#a", `{"severity":"WARN","summary":"disk at 80%"}`},
+		{"#b", `{"severity":"INFO","summary":"deployed"}`},
+		{"#a", `{"severity":"CRIT","summary":"disk at 99%"}`},
+	} {
+		msg := service.NewMessage([]byte(v.content))
+		msg.MetaSetMut("channel", v.channel)
+		batch = append(batch, msg)
+	}
+
+	require.NoError(t, o.WriteBatch(context.Background(), batch))
+
+	assert.Equal(t, []map[string]any{
+		{"channel": "#a", "text": "WARN: disk at 80%\nCRIT: disk at 99%"},
+		{"channel": "#b", "text": "INFO: deployed"},
+	}, rec.bodies)
+	assert.Equal(t, []string{"Bearer xoxb-foo", "Bearer xoxb-foo"}, rec.auth)
+}
+
+func TestSlackWebAPIError(t *testing.T) {
+	rec := &recordingServer{
+		responses: []func(w http.ResponseWriter){
+			func(w http.ResponseWriter) {
+				_, _ = w.Write([]byte(`{"ok":false,"error":"channel_not_found"}`))
+			},
+		},
+	}
+	ts := httptest.NewServer(rec)
+	t.Cleanup(ts.Close)
+
+	conf, err := outputSpec().ParseYAML(`
+bot_token: xoxb-foo
+channel: "#nope"
+`, nil)
+	require.NoError(t, err)
+
+	o, err := outputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	o.apiURL = ts.URL
+
+	err = o.WriteBatch(context.Background(), service.MessageBatch{service.NewMessage([]byte("hi"))})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "channel_not_found")
+}
+
+func TestSlackCoalescePartialFailure(t *testing.T) {
+	rec := &recordingServer{
+		responses: []func(w http.ResponseWriter){
+			func(w http.ResponseWriter) {
+				_, _ = w.Write([]byte(`{"ok":true}`))
+			},
+			func(w http.ResponseWriter) {
+				_, _ = w.Write([]byte(`{"ok":false,"error":"channel_not_found"}`))
+			},
+		},
+	}
+	ts := httptest.NewServer(rec)
+	t.Cleanup(ts.Close)
+
+	conf, err := outputSpec().ParseYAML(`
+bot_token: xoxb-foo
+channel: ${! @channel }
+coalesce: true
+channel_rate_limit: 1ms
+`, nil)
+	require.NoError(t, err)
+
+	o, err := outputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	o.apiURL = ts.URL
+
+	var batch service.MessageBatch
+	for _, v := range []struct{ channel, content string }{
+		{"#a", "foo"},
+		{"#b", "bar"},
+		{"#b", "baz"},
+	} {
+		msg := service.NewMessage([]byte(v.content))
+		msg.MetaSetMut("channel", v.channel)
+		batch = append(batch, msg)
+	}
+
+	err = o.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+
+	var batchErr *service.BatchError
+	require.True(t, errors.As(err, &batchErr))
+
+	var retry service.MessageBatch
+	batchErr.WalkMessages(func(i int, m *service.Message, err error) bool {
+		if err != nil {
+			retry = append(retry, m)
+		}
+		return true
+	})
+	require.Len(t, retry, 2)
+
+	require.NoError(t, o.WriteBatch(context.Background(), retry))
+
+	assert.Equal(t, []map[string]any{
+		{"channel": "#a", "text": "foo"},
+		{"channel": "#b", "text": "bar\nbaz"},
+		{"channel": "#b", "text": "bar\nbaz"},
+	}, rec.bodies)
+}
+
+func TestSlackRetryAfter(t *testing.T) {
+	rateLimited := func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}
+	rec := &recordingServer{
+		responses: []func(w http.ResponseWriter){rateLimited},
+	}
+	ts := httptest.NewServer(rec)
+	t.Cleanup(ts.Close)
+
+	conf, err := outputSpec().ParseYAML(`
+webhook_url: `+ts.URL+`
+channel_rate_limit: 1ms
+max_retries: 1
+`, nil)
+	require.NoError(t, err)
+
+	o, err := outputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	start := time.Now()
+	require.NoError(t, o.WriteBatch(context.Background(), service.MessageBatch{service.NewMessage([]byte("hi"))}))
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+	assert.Len(t, rec.bodies, 2)
+
+	rec.mut.Lock()
+	rec.responses = []func(w http.ResponseWriter){rateLimited, rateLimited}
+	rec.mut.Unlock()
+
+	ctx, done := context.WithTimeout(context.Background(), 5*time.Second)
+	defer done()
+	err = o.WriteBatch(ctx, service.MessageBatch{service.NewMessage([]byte("hi"))})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "rate limited")
+}
+
+func TestSlackChannelLimiter(t *testing.T) {
+	l := newChannelLimiter(50 * time.Millisecond)
+
+	start := time.Now()
+	require.NoError(t, l.wait(context.Background(), "a"))
+	require.NoError(t, l.wait(context.Background(), "b"))
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+
+	require.NoError(t, l.wait(context.Background(), "a"))
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	l.pause("b", time.Now().Add(time.Hour))
+	ctx, done := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer done()
+	require.ErrorIs(t, l.wait(ctx, "b"), context.DeadlineExceeded)
+}
+
+func TestSlackConfigErrors(t *testing.T) {
+	for _, conf := range []string{
+		`{}`,
+		`{ webhook_url: http://foo, bot_token: bar }`,
+		`{ bot_token: bar }`,
+	} {
+		pConf, err := outputSpec().ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		_, err = outputFromParsed(pConf, service.MockResources())
+		assert.Error(t, err, conf)
+	}
+}
@@ -0,0 +1,715 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package smtp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+
+	"github.com/redpanda-data/connect/v4/internal/retries"
+)
+
+const (
+	soFieldAddress         = "address"
+	soFieldSecurity        = "security"
+	soFieldTLS             = "tls"
+	soFieldAuth            = "auth"
+	soFieldAuthUsername    = "username"
+	soFieldAuthPassword    = "password"
+	soFieldFrom            = "from"
+	soFieldTo              = "to"
+	soFieldCC              = "cc"
+	soFieldBCC             = "bcc"
+	soFieldSubject         = "subject"
+	soFieldHeaders         = "headers"
+	soFieldBody            = "body"
+	soFieldBodyType        = "body_type"
+	soFieldAttachments     = "attachments"
+	soFieldAttFilename     = "filename"
+	soFieldAttContent      = "content"
+	soFieldAttContentType  = "content_type"
+	soFieldTimeout         = "timeout"
+	soFieldBatching        = "batching"
+	soSecurityNone         = "none"
+	soSecurityStartTLS     = "starttls"
+	soSecurityTLS          = "tls"
+	defaultAttachmentCType = "application/octet-stream"
+)
+
+func outputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.31.0").
+		Categories("Services").
+		Summary("Sends each message as an email via an SMTP server.").
+		Description(`
+The sender, recipients and subject of each email are interpolated from the message. The body of each email is the result of `+"`body`"+` when set, and otherwise the contents of the message, and is sent either as plain text or as HTML depending on `+"`body_type`"+`.
+
+== Attachments
+
+Each entry of `+"`attachments`"+` is a Bloblang mapping of the contents of a file to attach to each email, along with an interpolated file name and content type. When the mapping of an attachment results in `+"`deleted()`"+` or an empty value the attachment is omitted from the email. When a content type is not specified it is derived from the extension of the file name.
+
+== Connections
+
+Connections to the server are kept open and reused by subsequent batches, with up to `+"`max_in_flight`"+` connections open at any given time. The messages of a batch are sent in order over a single connection.
+
+Emails that are rejected with a transient error, which is any reply with a 4xx code, or that fail due to a connection error are retried according to the `+"`max_retries`"+` and `+"`backoff`"+` fields. Emails that are rejected with a permanent error, which is any reply with a 5xx code, are failed immediately. Failed emails are retried by the pipeline independently of the rest of their batch.`).
+		Fields(
+			service.NewStringField(soFieldAddress).
+				Description("The address of the SMTP server, including its port.").
+				Example("smtp.example.com:587").
+				Example("smtp.example.com:465"),
+			service.NewStringAnnotatedEnumField(soFieldSecurity, map[string]string{
+				soSecurityStartTLS: "Connect in plain text and upgrade the connection with the `STARTTLS` command, which is typically used on port 587.",
+				soSecurityTLS:      "Connect with TLS, which is typically used on port 465.",
+				soSecurityNone:     "Connect in plain text without encryption, which is only recommended for local servers.",
+			}).
+				Description("The method used to secure the connection to the server.").
+				Default(soSecurityStartTLS),
+			service.NewTLSField(soFieldTLS).
+				Description("Custom TLS settings of the connection, which are used when `security` is either `starttls` or `tls`."),
+			service.NewObjectField(soFieldAuth,
+				service.NewStringField(soFieldAuthUsername).
+					Description("The username to authenticate with."),
+				service.NewStringField(soFieldAuthPassword).
+					Description("The password to authenticate with.").
+					Secret(),
+			).
+				Description("Optional credentials to authenticate with using the `PLAIN` mechanism, which requires a secure connection unless the server is on the local host.").
+				Optional(),
+			service.NewInterpolatedStringField(soFieldFrom).
+				Description("The sender of each email.").
+				Example("Alerts <alerts@example.com>"),
+			service.NewInterpolatedStringField(soFieldTo).
+				Description("A comma separated list of recipients of each email.").
+				Example("ops@example.com, Jane Doe <jane@example.com>").
+				Example(`${! this.owner.email }`),
+			service.NewInterpolatedStringField(soFieldCC).
+				Description("An optional comma separated list of recipients to copy in to each email.").
+				Optional(),
+			service.NewInterpolatedStringField(soFieldBCC).
+				Description("An optional comma separated list of recipients to blind copy in to each email, which are not listed in the headers of the email.").
+				Optional(),
+			service.NewInterpolatedStringField(soFieldSubject).
+				Description("The subject of each email.").
+				Example(`[${! this.severity.uppercase() }] ${! this.summary }`),
+			service.NewInterpolatedStringMapField(soFieldHeaders).
+				Description("Additional headers to add to each email.").
+				Example(map[string]any{"Reply-To": "support@example.com"}).
+				Default(map[string]any{}).
+				Advanced(),
+			service.NewInterpolatedStringField(soFieldBody).
+				Description("An optional template of the body of each email, otherwise the contents of the message are used.").
+				Optional(),
+			service.NewStringEnumField(soFieldBodyType, "text", "html").
+				Description("Whether the body of each email is plain text or HTML.").
+				Default("text"),
+			service.NewObjectListField(soFieldAttachments,
+				service.NewInterpolatedStringField(soFieldAttFilename).
+					Description("The file name of the attachment."),
+				service.NewBloblangField(soFieldAttContent).
+					Description("A mapping that results in the contents of the attachment."),
+				service.NewInterpolatedStringField(soFieldAttContentType).
+					Description("The content type of the attachment, which is derived from the file name when omitted.").
+					Optional(),
+			).
+				Description("A list of files to attach to each email.").
+				Default([]any{}),
+			service.NewDurationField(soFieldTimeout).
+				Description("The maximum period to wait for each operation with the server.").
+				Default("30s").
+				Advanced(),
+			service.NewOutputMaxInFlightField().Default(1),
+			service.NewBatchPolicyField(soFieldBatching),
+		).
+		Fields(retries.CommonRetryBackOffFields(3, "1s", "30s", "5m")...).
+		Example("Alert emails", "Send an HTML email for each alert with the original alert attached as a JSON file.", `
+output:
+  smtp:
+    address: smtp.example.com:587
+    auth:
+      username: alerts@example.com
+      password: ${SMTP_PASSWORD}
+    from: Alerts <alerts@example.com>
+    to: ${! this.owner.email }
+    subject: '[${! this.severity.uppercase() }] ${! this.summary }'
+    body_type: html
+    body: |
+      <h1>${! this.summary }</h1>
+      <p>${! this.description.escape_html() }</p>
+    attachments:
+      - filename: alert-${! this.id }.json
+        content: root = this.format_json()
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("smtp", outputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(soFieldBatching); err != nil {
+				return
+			}
+			out, err = outputFromParsed(conf, mgr, maxInFlight)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type attachment struct {
+	filename    *service.InterpolatedString
+	content     *bloblang.Executor
+	contentType *service.InterpolatedString
+}
+
+type output struct {
+	address  string
+	host     string
+	security string
+	tlsConf  *tls.Config
+	auth     smtp.Auth
+	timeout  time.Duration
+
+	from        *service.InterpolatedString
+	to          *service.InterpolatedString
+	cc          *service.InterpolatedString
+	bcc         *service.InterpolatedString
+	subject     *service.InterpolatedString
+	headers     map[string]*service.InterpolatedString
+	body        *service.InterpolatedString
+	html        bool
+	attachments []attachment
+
+	backoffCtor func() backoff.BackOff
+	pool        chan *conn
+	log         *service.Logger
+	now         func() time.Time
+}
+
+func outputFromParsed(conf *service.ParsedConfig, mgr *service.Resources, poolSize int) (*output, error) {
+	o := &output{
+		pool: make(chan *conn, poolSize),
+		log:  mgr.Logger(),
+		now:  time.Now,
+	}
+
+	var err error
+	if o.address, err = conf.FieldString(soFieldAddress); err != nil {
+		return nil, err
+	}
+	if o.host, _, err = net.SplitHostPort(o.address); err != nil {
+		return nil, fmt.Errorf("failed to parse address: %w", err)
+	}
+	if o.security, err = conf.FieldString(soFieldSecurity); err != nil {
+		return nil, err
+	}
+	if o.tlsConf, err = conf.FieldTLS(soFieldTLS); err != nil {
+		return nil, err
+	}
+	if o.tlsConf == nil {
+		o.tlsConf = &tls.Config{}
+	}
+	if o.tlsConf.ServerName == "" {
+		o.tlsConf.ServerName = o.host
+	}
+	if conf.Contains(soFieldAuth) {
+		authConf := conf.Namespace(soFieldAuth)
+		username, err := authConf.FieldString(soFieldAuthUsername)
+		if err != nil {
+			return nil, err
+		}
+		password, err := authConf.FieldString(soFieldAuthPassword)
+		if err != nil {
+			return nil, err
+		}
+		o.auth = smtp.PlainAuth("", username, password, o.host)
+	}
+	if o.timeout, err = conf.FieldDuration(soFieldTimeout); err != nil {
+		return nil, err
+	}
+
+	if o.from, err = conf.FieldInterpolatedString(soFieldFrom); err != nil {
+		return nil, err
+	}
+	if o.to, err = conf.FieldInterpolatedString(soFieldTo); err != nil {
+		return nil, err
+	}
+	if conf.Contains(soFieldCC) {
+		if o.cc, err = conf.FieldInterpolatedString(soFieldCC); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(soFieldBCC) {
+		if o.bcc, err = conf.FieldInterpolatedString(soFieldBCC); err != nil {
+			return nil, err
+		}
+	}
+	if o.subject, err = conf.FieldInterpolatedString(soFieldSubject); err != nil {
+		return nil, err
+	}
+	if o.headers, err = conf.FieldInterpolatedStringMap(soFieldHeaders); err != nil {
+		return nil, err
+	}
+	if conf.Contains(soFieldBody) {
+		if o.body, err = conf.FieldInterpolatedString(soFieldBody); err != nil {
+			return nil, err
+		}
+	}
+	bodyType, err := conf.FieldString(soFieldBodyType)
+	if err != nil {
+		return nil, err
+	}
+	o.html = bodyType == "html"
+
+	attConfs, err := conf.FieldObjectList(soFieldAttachments)
+	if err != nil {
+		return nil, err
+	}
+	for _, aConf := range attConfs {
+		var a attachment
+		if a.filename, err = aConf.FieldInterpolatedString(soFieldAttFilename); err != nil {
+			return nil, err
+		}
+		if a.content, err = aConf.FieldBloblang(soFieldAttContent); err != nil {
+			return nil, err
+		}
+		if aConf.Contains(soFieldAttContentType) {
+			if a.contentType, err = aConf.FieldInterpolatedString(soFieldAttContentType); err != nil {
+				return nil, err
+			}
+		}
+		o.attachments = append(o.attachments, a)
+	}
+
+	if o.backoffCtor, err = retries.CommonRetryBackOffCtorFromParsed(conf); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+//------------------------------------------------------------------------------
+
+// conn is a client connection to the server, the underlying connection is
+// retained in order to set deadlines on operations.
+type conn struct {
+	*smtp.Client
+	nc net.Conn
+}
+
+func (o *output) dial(ctx context.Context) (*conn, error) {
+	dialer := &net.Dialer{Timeout: o.timeout}
+
+	var nc net.Conn
+	var err error
+	if o.security == soSecurityTLS {
+		nc, err = (&tls.Dialer{NetDialer: dialer, Config: o.tlsConf}).DialContext(ctx, "tcp", o.address)
+	} else {
+		nc, err = dialer.DialContext(ctx, "tcp", o.address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	_ = nc.SetDeadline(time.Now().Add(o.timeout))
+
+	client, err := smtp.NewClient(nc, o.host)
+	if err != nil {
+		_ = nc.Close()
+		return nil, err
+	}
+	c := &conn{Client: client, nc: nc}
+	if o.security == soSecurityStartTLS {
+		if err := c.StartTLS(o.tlsConf); err != nil {
+			_ = c.Close()
+			return nil, fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if o.auth != nil {
+		if err := c.Auth(o.auth); err != nil {
+			_ = c.Close()
+			return nil, fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// acquire obtains an idle connection from the pool, or opens a new connection
+// when there are none.
+func (o *output) acquire(ctx context.Context) (*conn, error) {
+	for {
+		select {
+		case c := <-o.pool:
+			_ = c.nc.SetDeadline(time.Now().Add(o.timeout))
+			if err := c.Noop(); err == nil {
+				return c, nil
+			}
+			_ = c.Close()
+			continue
+		default:
+		}
+		return o.dial(ctx)
+	}
+}
+
+func (o *output) release(c *conn) {
+	select {
+	case o.pool <- c:
+	default:
+		_ = c.Quit()
+	}
+}
+
+func (o *output) Connect(ctx context.Context) error {
+	c, err := o.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	o.release(c)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type email struct {
+	from  string
+	rcpts []string
+	data  []byte
+}
+
+func (o *output) parseAddressList(batch service.MessageBatch, i int, field string, s *service.InterpolatedString) ([]*mail.Address, error) {
+	if s == nil {
+		return nil, nil
+	}
+	str, err := batch.TryInterpolatedString(i, s)
+	if err != nil {
+		return nil, fmt.Errorf("%v interpolation error: %w", field, err)
+	}
+	if strings.TrimSpace(str) == "" {
+		return nil, nil
+	}
+	addrs, err := mail.ParseAddressList(str)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %v addresses: %w", field, err)
+	}
+	return addrs, nil
+}
+
+func joinAddresses(addrs []*mail.Address) string {
+	strs := make([]string, len(addrs))
+	for i, a := range addrs {
+		strs[i] = a.String()
+	}
+	return strings.Join(strs, ", ")
+}
+
+func writeHeader(buf *bytes.Buffer, key, value string) {
+	buf.WriteString(key)
+	buf.WriteString(": ")
+	buf.WriteString(value)
+	buf.WriteString("\r\n")
+}
+
+// writeBase64 writes data encoded as base64 with lines of 76 characters.
+func writeBase64(buf *bytes.Buffer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76])
+		buf.WriteString("\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded)
+	buf.WriteString("\r\n")
+}
+
+func (o *output) emailFromMessage(batch service.MessageBatch, i int) (*email, error) {
+	fromStr, err := batch.TryInterpolatedString(i, o.from)
+	if err != nil {
+		return nil, fmt.Errorf("from interpolation error: %w", err)
+	}
+	from, err := mail.ParseAddress(fromStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse from address: %w", err)
+	}
+
+	to, err := o.parseAddressList(batch, i, soFieldTo, o.to)
+	if err != nil {
+		return nil, err
+	}
+	cc, err := o.parseAddressList(batch, i, soFieldCC, o.cc)
+	if err != nil {
+		return nil, err
+	}
+	bcc, err := o.parseAddressList(batch, i, soFieldBCC, o.bcc)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &email{from: from.Address}
+	for _, addrs := range [][]*mail.Address{to, cc, bcc} {
+		for _, a := range addrs {
+			e.rcpts = append(e.rcpts, a.Address)
+		}
+	}
+	if len(e.rcpts) == 0 {
+		return nil, errors.New("email has no recipients")
+	}
+
+	subject, err := batch.TryInterpolatedString(i, o.subject)
+	if err != nil {
+		return nil, fmt.Errorf("subject interpolation error: %w", err)
+	}
+
+	var body []byte
+	if o.body != nil {
+		if body, err = batch.TryInterpolatedBytes(i, o.body); err != nil {
+			return nil, fmt.Errorf("body interpolation error: %w", err)
+		}
+	} else if body, err = batch[i].AsBytes(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writeHeader(&buf, "From", from.String())
+	if len(to) > 0 {
+		writeHeader(&buf, "To", joinAddresses(to))
+	}
+	if len(cc) > 0 {
+		writeHeader(&buf, "Cc", joinAddresses(cc))
+	}
+	writeHeader(&buf, "Subject", mime.QEncoding.Encode("utf-8", subject))
+	writeHeader(&buf, "Date", o.now().Format(time.RFC1123Z))
+
+	headerKeys := make([]string, 0, len(o.headers))
+	for k := range o.headers {
+		headerKeys = append(headerKeys, k)
+	}
+	sort.Strings(headerKeys)
+	for _, k := range headerKeys {
+		v, err := batch.TryInterpolatedString(i, o.headers[k])
+		if err != nil {
+			return nil, fmt.Errorf("header %v interpolation error: %w", k, err)
+		}
+		writeHeader(&buf, textproto.CanonicalMIMEHeaderKey(k), mime.QEncoding.Encode("utf-8", v))
+	}
+	writeHeader(&buf, "MIME-Version", "1.0")
+
+	bodyType := "text/plain; charset=utf-8"
+	if o.html {
+		bodyType = "text/html; charset=utf-8"
+	}
+
+	var qpBuf bytes.Buffer
+	qpw := quotedprintable.NewWriter(&qpBuf)
+	_, _ = qpw.Write(body)
+	_ = qpw.Close()
+
+	type attachmentContent struct {
+		filename    string
+		contentType string
+		data        []byte
+	}
+	var attachments []attachmentContent
+	for j, a := range o.attachments {
+		resMsg, err := batch.BloblangQuery(i, a.content)
+		if err != nil {
+			return nil, fmt.Errorf("attachment %v mapping error: %w", j, err)
+		}
+		if resMsg == nil {
+			continue
+		}
+		data, err := resMsg.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		if len(data) == 0 {
+			continue
+		}
+
+		ac := attachmentContent{data: data}
+		if ac.filename, err = batch.TryInterpolatedString(i, a.filename); err != nil {
+			return nil, fmt.Errorf("attachment %v filename interpolation error: %w", j, err)
+		}
+		if a.contentType != nil {
+			if ac.contentType, err = batch.TryInterpolatedString(i, a.contentType); err != nil {
+				return nil, fmt.Errorf("attachment %v content type interpolation error: %w", j, err)
+			}
+		}
+		if ac.contentType == "" {
+			if ac.contentType = mime.TypeByExtension(filepath.Ext(ac.filename)); ac.contentType == "" {
+				ac.contentType = defaultAttachmentCType
+			}
+		}
+		attachments = append(attachments, ac)
+	}
+
+	if len(attachments) == 0 {
+		writeHeader(&buf, "Content-Type", bodyType)
+		writeHeader(&buf, "Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		buf.Write(qpBuf.Bytes())
+		e.data = buf.Bytes()
+		return e, nil
+	}
+
+	var partsBuf bytes.Buffer
+	mw := multipart.NewWriter(&partsBuf)
+	writeHeader(&buf, "Content-Type", mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": mw.Boundary()}))
+	buf.WriteString("\r\n")
+
+	pw, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {bodyType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	_, _ = pw.Write(qpBuf.Bytes())
+
+	for _, a := range attachments {
+		if _, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {a.contentType},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.filename})},
+			"Content-Transfer-Encoding": {"base64"},
+		}); err != nil {
+			return nil, err
+		}
+		writeBase64(&partsBuf, a.data)
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	buf.Write(partsBuf.Bytes())
+	e.data = buf.Bytes()
+	return e, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (o *output) send(c *conn, e *email) error {
+	_ = c.nc.SetDeadline(time.Now().Add(o.timeout))
+	if err := c.Mail(e.from); err != nil {
+		return err
+	}
+	for _, rcpt := range e.rcpts {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(e.data); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func (o *output) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	var c *conn
+	defer func() {
+		if c != nil {
+			o.release(c)
+		}
+	}()
+
+	var batchErr *service.BatchError
+	for i := range batch {
+		e, err := o.emailFromMessage(batch, i)
+		if err == nil {
+			err = backoff.RetryNotify(func() error {
+				if c == nil {
+					var dErr error
+					if c, dErr = o.acquire(ctx); dErr != nil {
+						return dErr
+					}
+				}
+
+				sErr := o.send(c, e)
+				if sErr == nil {
+					return nil
+				}
+
+				var tpErr *textproto.Error
+				if !errors.As(sErr, &tpErr) {
+					// The state of the connection is unknown and so it is
+					// discarded.
+					_ = c.Close()
+					c = nil
+					return sErr
+				}
+
+				// The transaction is abandoned so that the connection can be
+				// reused for subsequent attempts.
+				if rErr := c.Reset(); rErr != nil {
+					_ = c.Close()
+					c = nil
+				}
+				if tpErr.Code >= 500 {
+					return backoff.Permanent(sErr)
+				}
+				return sErr
+			}, backoff.WithContext(o.backoffCtor(), ctx), func(err error, d time.Duration) {
+				o.log.Warnf("Failed to send email, retrying in %v: %v", d, err)
+			})
+		}
+		if err != nil {
+			if batchErr == nil {
+				batchErr = service.NewBatchError(batch, err)
+			}
+			batchErr.Failed(i, err)
+		}
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+func (o *output) Close(ctx context.Context) error {
+	for {
+		select {
+		case c := <-o.pool:
+			_ = c.nc.SetDeadline(time.Now().Add(o.timeout))
+			_ = c.Quit()
+		default:
+			return nil
+		}
+	}
+}
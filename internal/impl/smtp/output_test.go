@@ -0,0 +1,335 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package smtp
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+type receivedMail struct {
+	from  string
+	rcpts []string
+	data  []byte
+}
+
+// fakeServer is a minimal SMTP server that records the emails it receives.
+type fakeServer struct {
+	ln net.Listener
+
+	mut         sync.Mutex
+	mails       []receivedMail
+	auths       []string
+	conns       int
+	mailReplies []string
+}
+
+func newFakeServer(t testing.TB) *fakeServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	s := &fakeServer{ln: ln}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mut.Lock()
+			s.conns++
+			s.mut.Unlock()
+			go s.handle(c)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) handle(c net.Conn) {
+	tp := textproto.NewConn(c)
+	defer tp.Close()
+
+	_ = tp.PrintfLine("220 localhost ESMTP")
+
+	var current receivedMail
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(cmd) {
+		case "EHLO":
+			_ = tp.PrintfLine("250-localhost")
+			_ = tp.PrintfLine("250 AUTH PLAIN")
+		case "AUTH":
+			s.mut.Lock()
+			s.auths = append(s.auths, arg)
+			s.mut.Unlock()
+			_ = tp.PrintfLine("235 OK")
+		case "MAIL":
+			s.mut.Lock()
+			var reply string
+			if len(s.mailReplies) > 0 {
+				reply, s.mailReplies = s.mailReplies[0], s.mailReplies[1:]
+			}
+			s.mut.Unlock()
+			if reply != "" {
+				_ = tp.PrintfLine("%v", reply)
+				continue
+			}
+			current = receivedMail{from: strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")}
+			_ = tp.PrintfLine("250 OK")
+		case "RCPT":
+			current.rcpts = append(current.rcpts, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			_ = tp.PrintfLine("250 OK")
+		case "DATA":
+			_ = tp.PrintfLine("354 Go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			current.data = data
+			s.mut.Lock()
+			s.mails = append(s.mails, current)
+			s.mut.Unlock()
+			current = receivedMail{}
+			_ = tp.PrintfLine("250 OK")
+		case "RSET":
+			current = receivedMail{}
+			_ = tp.PrintfLine("250 OK")
+		case "NOOP":
+			_ = tp.PrintfLine("250 OK")
+		case "QUIT":
+			_ = tp.PrintfLine("221 Bye")
+			return
+		default:
+			_ = tp.PrintfLine("502 Unknown command")
+		}
+	}
+}
+
+func (s *fakeServer) received() []receivedMail {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return append([]receivedMail(nil), s.mails...)
+}
+
+func TestSMTPOutputText(t *testing.T) {
+	s := newFakeServer(t)
+
+	conf, err := outputSpec().ParseYAML(`
+address: `+s.ln.Addr().String()+`
+security: none
+auth:
+  username: foo
+  password: bar
+from: Alerts <alerts@example.com>
+to: ${! this.to }
+cc: ops@example.com
+bcc: audit@example.com
+subject: 'Alert: ${! this.summary }'
+headers:
+  reply-to: support@example.com
+body: ${! this.summary } happened
+`, nil)
+	require.NoError(t, err)
+
+	o, err := outputFromParsed(conf, service.MockResources(), 1)
+	require.NoError(t, err)
+	o.now = func() time.Time {
+		return time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	}
+	t.Cleanup(func() { _ = o.Close(context.Background()) })
+
+	require.NoError(t, o.Connect(context.Background()))
+
+	require.NoError(t, o.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"to":"Jane Doe <jane@example.com>, bob@example.com","summary":"Disk full"}`)),
+		service.NewMessage([]byte(`{"to":"bob@example.com","summary":"Disk empty"}`)),
+	}))
+
+	mails := s.received()
+	require.Len(t, mails, 2)
+	assert.Equal(t, "alerts@example.com", mails[0].from)
+	assert.Equal(t, []string{"jane@example.com", "bob@example.com", "ops@example.com", "audit@example.com"}, mails[0].rcpts)
+	assert.Equal(t, []string{"bob@example.com", "ops@example.com", "audit@example.com"}, mails[1].rcpts)
+
+	m, err := mail.ReadMessage(bytes.NewReader(mails[0].data))
+	require.NoError(t, err)
+	assert.Equal(t, `"Alerts" <alerts@example.com>`, m.Header.Get("From"))
+	assert.Equal(t, `"Jane Doe" <jane@example.com>, <bob@example.com>`, m.Header.Get("To"))
+	assert.Equal(t, `<ops@example.com>`, m.Header.Get("Cc"))
+	assert.Empty(t, m.Header.Get("Bcc"))
+	assert.Equal(t, "Alert: Disk full", m.Header.Get("Subject"))
+	assert.Equal(t, "support@example.com", m.Header.Get("Reply-To"))
+	assert.Equal(t, "Sat, 01 Jun 2024 12:00:00 +0000", m.Header.Get("Date"))
+	assert.Equal(t, "text/plain; charset=utf-8", m.Header.Get("Content-Type"))
+
+	body, err := io.ReadAll(quotedprintable.NewReader(m.Body))
+	require.NoError(t, err)
+	assert.Equal(t, "Disk full happened", strings.TrimSpace(string(body)))
+
+	// Both emails are sent over a single authenticated connection.
+	s.mut.Lock()
+	assert.Equal(t, 1, s.conns)
+	assert.Len(t, s.auths, 1)
+	s.mut.Unlock()
+}
+
+func TestSMTPOutputAttachments(t *testing.T) {
+	s := newFakeServer(t)
+
+	conf, err := outputSpec().ParseYAML(`
+address: `+s.ln.Addr().String()+`
+security: none
+from: alerts@example.com
+to: ops@example.com
+subject: Report
+body_type: html
+body: '<h1>${! this.title }</h1>'
+attachments:
+  - filename: report.json
+    content: 'root = this.data.format_json(no_indent: true)'
+  - filename: data.bin
+    content: root = this.raw.or(deleted())
+    content_type: application/x-custom
+`, nil)
+	require.NoError(t, err)
+
+	o, err := outputFromParsed(conf, service.MockResources(), 1)
+	require.NoError(t, err)
+	o.now = func() time.Time {
+		return time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	}
+	t.Cleanup(func() { _ = o.Close(context.Background()) })
+
+	require.NoError(t, o.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"title":"Daily","data":{"a":1}}`)),
+	}))
+
+	mails := s.received()
+	require.Len(t, mails, 1)
+
+	m, err := mail.ReadMessage(bytes.NewReader(mails[0].data))
+	require.NoError(t, err)
+
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/mixed", mediaType)
+
+	mr := multipart.NewReader(m.Body, params["boundary"])
+
+	part, err := mr.NextRawPart()
+	require.NoError(t, err)
+	assert.Equal(t, "text/html; charset=utf-8", part.Header.Get("Content-Type"))
+	body, err := io.ReadAll(quotedprintable.NewReader(part))
+	require.NoError(t, err)
+	assert.Equal(t, "<h1>Daily</h1>", string(body))
+
+	part, err = mr.NextRawPart()
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", part.Header.Get("Content-Type"))
+	assert.Equal(t, "report.json", part.FileName())
+	encoded, err := io.ReadAll(part)
+	require.NoError(t, err)
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, string(decoded))
+
+	// The second attachment is omitted as its mapping was deleted.
+	_, err = mr.NextRawPart()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestSMTPOutputRetries(t *testing.T) {
+	s := newFakeServer(t)
+	s.mailReplies = []string{"451 Try again later", "", "550 No such user"}
+
+	conf, err := outputSpec().ParseYAML(`
+address: `+s.ln.Addr().String()+`
+security: none
+from: alerts@example.com
+to: ${! this.to }
+subject: Hello
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+`, nil)
+	require.NoError(t, err)
+
+	o, err := outputFromParsed(conf, service.MockResources(), 1)
+	require.NoError(t, err)
+	o.now = func() time.Time {
+		return time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	}
+	t.Cleanup(func() { _ = o.Close(context.Background()) })
+
+	err = o.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"to":"a@example.com"}`)),
+		service.NewMessage([]byte(`{"to":"b@example.com"}`)),
+		service.NewMessage([]byte(`{"to":""}`)),
+	})
+	require.Error(t, err)
+
+	var batchErr *service.BatchError
+	require.True(t, errors.As(err, &batchErr))
+
+	var failed []int
+	batchErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	assert.Equal(t, []int{1, 2}, failed)
+
+	mails := s.received()
+	require.Len(t, mails, 1)
+	assert.Equal(t, []string{"a@example.com"}, mails[0].rcpts)
+}
+
+func TestSMTPOutputBadConfig(t *testing.T) {
+	pConf, err := outputSpec().ParseYAML(`
+address: nope
+from: a@example.com
+to: b@example.com
+subject: foo
+`, nil)
+	require.NoError(t, err)
+
+	_, err = outputFromParsed(pConf, service.MockResources(), 1)
+	require.Error(t, err)
+}
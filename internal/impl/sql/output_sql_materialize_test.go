@@ -0,0 +1,253 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestSQLMaterializeUpsertSuffix(t *testing.T) {
+	tests := []struct {
+		name     string
+		conf     string
+		expected string
+	}{
+		{
+			name: "postgres versioned",
+			conf: `
+driver: postgres
+dsn: woof
+table: users
+columns: [ id, name, updated_at ]
+key_columns: [ id ]
+version_column: updated_at
+args_mapping: 'root = [ this.id, this.name, this.updated_at ]'
+`,
+			expected: "ON CONFLICT (id) DO UPDATE SET name = excluded.name, updated_at = excluded.updated_at WHERE users.updated_at < excluded.updated_at",
+		},
+		{
+			name: "sqlite unversioned",
+			conf: `
+driver: sqlite
+dsn: woof
+table: users
+columns: [ tenant, id, name ]
+key_columns: [ tenant, id ]
+args_mapping: 'root = [ this.tenant, this.id, this.name ]'
+`,
+			expected: "ON CONFLICT (tenant, id) DO UPDATE SET name = excluded.name",
+		},
+		{
+			name: "mysql versioned",
+			conf: `
+driver: mysql
+dsn: woof
+table: users
+columns: [ id, version, name ]
+key_columns: [ id ]
+version_column: version
+args_mapping: 'root = [ this.id, this.version, this.name ]'
+`,
+			expected: "ON DUPLICATE KEY UPDATE name = IF(version < VALUES(version), VALUES(name), name), version = IF(version < VALUES(version), VALUES(version), version)",
+		},
+		{
+			name: "keys only",
+			conf: `
+driver: postgres
+dsn: woof
+table: users
+columns: [ id ]
+key_columns: [ id ]
+args_mapping: 'root = [ this.id ]'
+`,
+			expected: "ON CONFLICT (id) DO NOTHING",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			pConf, err := sqlMaterializeOutputConfig().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			out, err := newSQLMaterializeOutputFromConfig(pConf, service.MockResources())
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, out.upsertSuffix)
+		})
+	}
+}
+
+func TestSQLMaterializeConfigErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		conf   string
+		errStr string
+	}{
+		{
+			name: "unsupported driver",
+			conf: `
+driver: oracle
+dsn: woof
+table: users
+columns: [ id ]
+key_columns: [ id ]
+args_mapping: 'root = [ this.id ]'
+`,
+			errStr: "driver oracle is not supported",
+		},
+		{
+			name: "missing key column",
+			conf: `
+driver: postgres
+dsn: woof
+table: users
+columns: [ id ]
+key_columns: [ uid ]
+args_mapping: 'root = [ this.id ]'
+`,
+			errStr: "key column uid is not present in columns",
+		},
+		{
+			name: "missing version column",
+			conf: `
+driver: postgres
+dsn: woof
+table: users
+columns: [ id ]
+key_columns: [ id ]
+version_column: ver
+args_mapping: 'root = [ this.id ]'
+`,
+			errStr: "version column ver is not present in columns",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			pConf, err := sqlMaterializeOutputConfig().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			_, err = newSQLMaterializeOutputFromConfig(pConf, service.MockResources())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errStr)
+		})
+	}
+}
+
+func TestSQLMaterializeSQLite(t *testing.T) {
+	ctx := context.Background()
+	dsn := "file:" + filepath.Join(t.TempDir(), "foo.db")
+
+	conf, err := sqlMaterializeOutputConfig().ParseYAML(fmt.Sprintf(`
+driver: sqlite
+dsn: %v
+table: users
+columns: [ id, name, version ]
+key_columns: [ id ]
+version_column: version
+operation: ${! this.op }
+args_mapping: 'root = [ this.id, this.name, this.version ]'
+init_statement: |
+  CREATE TABLE IF NOT EXISTS users (
+    id integer not null primary key,
+    name varchar(50),
+    version integer
+  );
+`, dsn), nil)
+	require.NoError(t, err)
+
+	out, err := newSQLMaterializeOutputFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+
+	require.NoError(t, out.Connect(ctx))
+	t.Cleanup(func() {
+		require.NoError(t, out.Close(ctx))
+	})
+
+	writeBatch := func(docs ...string) {
+		t.Helper()
+		var batch service.MessageBatch
+		for _, d := range docs {
+			batch = append(batch, service.NewMessage([]byte(d)))
+		}
+		require.NoError(t, out.WriteBatch(ctx, batch))
+	}
+
+	readRows := func() map[int64]string {
+		t.Helper()
+		rows, err := out.db.Query("SELECT id, name, version FROM users")
+		require.NoError(t, err)
+		defer rows.Close()
+
+		res := map[int64]string{}
+		for rows.Next() {
+			var id, version int64
+			var name string
+			require.NoError(t, rows.Scan(&id, &name, &version))
+			res[id] = fmt.Sprintf("%v@%v", name, version)
+		}
+		require.NoError(t, rows.Err())
+		return res
+	}
+
+	writeBatch(
+		`{"op":"insert","id":1,"name":"a","version":1}`,
+		`{"op":"insert","id":2,"name":"b","version":1}`,
+		`{"op":"update","id":1,"name":"aa","version":3}`,
+		`{"op":"update","id":1,"name":"stale","version":2}`,
+		`{"op":"insert","id":3,"name":"c","version":1}`,
+	)
+	assert.Equal(t, map[int64]string{
+		1: "aa@3",
+		2: "b@1",
+		3: "c@1",
+	}, readRows())
+
+	writeBatch(
+		`{"op":"update","id":1,"name":"older","version":2}`,
+		`{"op":"update","id":2,"name":"bb","version":2}`,
+		`{"op":"delete","id":3,"name":"c","version":2}`,
+	)
+	assert.Equal(t, map[int64]string{
+		1: "aa@3",
+		2: "bb@2",
+	}, readRows())
+
+	writeBatch(
+		`{"op":"delete","id":2,"name":"bb","version":1}`,
+	)
+	assert.Equal(t, map[int64]string{
+		1: "aa@3",
+		2: "bb@2",
+	}, readRows())
+
+	err = out.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"op":"truncate","id":1,"name":"a","version":4}`)),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unrecognised operation")
+}
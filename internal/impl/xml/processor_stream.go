@@ -0,0 +1,203 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xml
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	spFieldElement = "element"
+	spFieldCast    = "cast"
+)
+
+func xmlStreamProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Parsing").
+		Beta().
+		Version("4.31.0").
+		Summary(`Splits an XML document into a message for each occurrence of a repeated element, converting each one into a JSON structure without parsing the whole document into a tree.`).
+		Description(`
+The document is read with a streaming decoder and only the elements matched by the `+"`element`"+` path are converted, which keeps the memory required to process large XML exports proportional to the size of a single record rather than the whole document.
+
+The path consists of element names separated by slashes. A path beginning with a slash must match from the root element of the document, whereas a relative path matches any element where the trailing elements of its ancestry match. Names are matched on their local part, ignoring any namespace prefix. Matched elements are not searched for further matches.
+
+Each matched element is converted following the same rules as the `+"xref:components:processors/xml.adoc[`xml` processor]"+`, where attributes are prefixed with a hyphen, `+"`-`"+`, and the value of a simple element with attributes is given the key `+"`#text`"+`. The resulting message contains the value of the element itself rather than an object keyed by the element name.
+
+For example, given the following XML and the element path `+"`/catalog/book`"+`:
+
+`+"```xml"+`
+<catalog>
+  <book id="1"><title>Foo</title></book>
+  <book id="2"><title>Bar</title></book>
+</catalog>
+`+"```"+`
+
+The resulting messages would be:
+
+`+"```json"+`
+{"-id":"1","title":"Foo"}
+{"-id":"2","title":"Bar"}
+`+"```"+`
+
+Documents that contain no matching elements result in no messages, and documents that fail to parse are flagged as errors.`).
+		Fields(
+			service.NewStringField(spFieldElement).
+				Description("A slash separated path of the repeated element to emit as messages.").
+				Example("/catalog/book").
+				Example("record"),
+			service.NewBoolField(spFieldCast).
+				Description("Whether to try to cast values that are numbers and booleans to the right type. Default: all values are strings.").
+				Default(false),
+		).
+		Example("Split an export", "Read a large XML export with a scanner that consumes the whole file, and emit each product as a JSON message.", `
+input:
+  file:
+    paths: [ ./products.xml ]
+    scanner:
+      to_the_end: {}
+
+pipeline:
+  processors:
+    - xml_stream:
+        element: /export/products/product
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"xml_stream", xmlStreamProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return xmlStreamProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type xmlStreamProc struct {
+	log      *service.Logger
+	path     []string
+	absolute bool
+	cast     bool
+}
+
+func xmlStreamProcFromParsed(pConf *service.ParsedConfig, mgr *service.Resources) (*xmlStreamProc, error) {
+	p := &xmlStreamProc{
+		log: mgr.Logger(),
+	}
+
+	elementStr, err := pConf.FieldString(spFieldElement)
+	if err != nil {
+		return nil, err
+	}
+	p.absolute = strings.HasPrefix(elementStr, "/")
+	for _, name := range strings.Split(strings.Trim(elementStr, "/"), "/") {
+		if name == "" {
+			return nil, fmt.Errorf("element path %q contains an empty name", elementStr)
+		}
+		p.path = append(p.path, name)
+	}
+
+	if p.cast, err = pConf.FieldBool(spFieldCast); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// matches returns true when the current stack of element names matches the
+// configured path.
+func (p *xmlStreamProc) matches(stack []string) bool {
+	if len(stack) < len(p.path) || (p.absolute && len(stack) != len(p.path)) {
+		return false
+	}
+	tail := stack[len(stack)-len(p.path):]
+	for i, name := range p.path {
+		if tail[i] != name {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *xmlStreamProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	mBytes, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(mBytes))
+	dec.Strict = false
+	dec.CharsetReader = charset.NewReaderLabel
+
+	var batch service.MessageBatch
+	var stack []string
+	for {
+		startOffset := dec.InputOffset()
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			p.log.Debugf("Failed to parse part as XML: %v", err)
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name.Local)
+			if !p.matches(stack) {
+				continue
+			}
+			if err := dec.Skip(); err != nil {
+				p.log.Debugf("Failed to parse part as XML: %v", err)
+				return nil, err
+			}
+			stack = stack[:len(stack)-1]
+
+			root, err := ToMap(mBytes[startOffset:dec.InputOffset()], p.cast)
+			if err != nil {
+				return nil, err
+			}
+
+			var v any
+			for _, v = range root {
+				break
+			}
+			resMsg := msg.Copy()
+			resMsg.SetStructuredMut(v)
+			batch = append(batch, resMsg)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+	return batch, nil
+}
+
+func (p *xmlStreamProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xml
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func TestXMLStream(t *testing.T) {
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<export xmlns:p="http://example.com/product">
+  <products>
+    <p:product id="1"><name>Foo</name><price>10</price></p:product>
+    <p:product id="2"><name>Bar</name><tag>a</tag><tag>b</tag></p:product>
+    <p:product>Baz</p:product>
+  </products>
+  <archive>
+    <product id="3"><name>Old</name></product>
+  </archive>
+</export>`
+
+	tests := []struct {
+		name    string
+		element string
+		cast    bool
+		output  []string
+	}{
+		{
+			name:    "absolute path",
+			element: "/export/products/product",
+			output: []string{
+				`{"-id":"1","name":"Foo","price":"10"}`,
+				`{"-id":"2","name":"Bar","tag":["a","b"]}`,
+				`"Baz"`,
+			},
+		},
+		{
+			name:    "relative path",
+			element: "product",
+			output: []string{
+				`{"-id":"1","name":"Foo","price":"10"}`,
+				`{"-id":"2","name":"Bar","tag":["a","b"]}`,
+				`"Baz"`,
+				`{"-id":"3","name":"Old"}`,
+			},
+		},
+		{
+			name:    "relative nested path with cast",
+			element: "archive/product",
+			cast:    true,
+			output: []string{
+				`{"-id":3,"name":"Old"}`,
+			},
+		},
+		{
+			name:    "no matches",
+			element: "/products/product",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			pConf, err := xmlStreamProcSpec().ParseYAML(`element: `+test.element, nil)
+			require.NoError(t, err)
+
+			proc, err := xmlStreamProcFromParsed(pConf, service.MockResources())
+			require.NoError(t, err)
+			proc.cast = test.cast
+
+			batch, err := proc.Process(context.Background(), service.NewMessage([]byte(input)))
+			require.NoError(t, err)
+
+			var actual []string
+			for _, m := range batch {
+				mBytes, err := m.AsBytes()
+				require.NoError(t, err)
+				actual = append(actual, string(mBytes))
+			}
+			assert.Equal(t, test.output, actual)
+		})
+	}
+}
+
+func TestXMLStreamErrors(t *testing.T) {
+	pConf, err := xmlStreamProcSpec().ParseYAML(`element: /a//b`, nil)
+	require.NoError(t, err)
+
+	_, err = xmlStreamProcFromParsed(pConf, service.MockResources())
+	require.Error(t, err)
+
+	pConf, err = xmlStreamProcSpec().ParseYAML(`element: a/b`, nil)
+	require.NoError(t, err)
+
+	proc, err := xmlStreamProcFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`<a><b>foo</a`)))
+	require.Error(t, err)
+}
@@ -0,0 +1,175 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build x_benthos_extra
+// +build x_benthos_extra
+
+package xslt
+
+/*
+#cgo pkg-config: libxslt libxml-2.0
+
+#include <stdlib.h>
+#include <libxml/parser.h>
+#include <libxml/xmlerror.h>
+#include <libxslt/xslt.h>
+#include <libxslt/xsltInternals.h>
+#include <libxslt/transform.h>
+#include <libxslt/security.h>
+#include <libxslt/xsltutils.h>
+
+static void xslt_silent_error(void *ctx, const char *msg, ...) {}
+
+static void xslt_init(void) {
+	xmlInitParser();
+	xmlSetGenericErrorFunc(NULL, xslt_silent_error);
+	xsltSetGenericErrorFunc(NULL, xslt_silent_error);
+
+	xsltSecurityPrefsPtr prefs = xsltNewSecurityPrefs();
+	xsltSetSecurityPrefs(prefs, XSLT_SECPREF_WRITE_FILE, xsltSecurityForbid);
+	xsltSetSecurityPrefs(prefs, XSLT_SECPREF_CREATE_DIRECTORY, xsltSecurityForbid);
+	xsltSetSecurityPrefs(prefs, XSLT_SECPREF_READ_NETWORK, xsltSecurityForbid);
+	xsltSetSecurityPrefs(prefs, XSLT_SECPREF_WRITE_NETWORK, xsltSecurityForbid);
+	xsltSetDefaultSecurityPrefs(prefs);
+}
+
+static const char *xslt_last_error(void) {
+	xmlErrorPtr err = xmlGetLastError();
+	if (err == NULL || err->message == NULL) {
+		return "";
+	}
+	return err->message;
+}
+
+static xsltStylesheetPtr xslt_parse(const char *data, int len, const char *url) {
+	xmlResetLastError();
+	xmlDocPtr doc = xmlReadMemory(data, len, url, NULL, XML_PARSE_NONET);
+	if (doc == NULL) {
+		return NULL;
+	}
+	xsltStylesheetPtr style = xsltParseStylesheetDoc(doc);
+	if (style == NULL) {
+		xmlFreeDoc(doc);
+	}
+	return style;
+}
+
+// Returns 0 on success, 1 if the document could not be parsed, 2 if the
+// transformation failed and 3 if the result could not be serialised.
+static int xslt_apply(xsltStylesheetPtr style, const char *data, int len, xmlChar **out, int *outLen) {
+	xmlResetLastError();
+	xmlDocPtr doc = xmlReadMemory(data, len, NULL, NULL, XML_PARSE_NONET);
+	if (doc == NULL) {
+		return 1;
+	}
+	xmlDocPtr res = xsltApplyStylesheet(style, doc, NULL);
+	xmlFreeDoc(doc);
+	if (res == NULL) {
+		return 2;
+	}
+	int rc = xsltSaveResultToString(out, outLen, res, style);
+	xmlFreeDoc(res);
+	if (rc != 0) {
+		return 3;
+	}
+	return 0;
+}
+
+static void xslt_free_result(xmlChar *out) {
+	xmlFree(out);
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+func init() {
+	C.xslt_init()
+}
+
+// lastError returns the most recent libxml2 error of the calling thread, and
+// therefore must be called from the same locked OS thread as the failed call.
+func lastError(fallback string) error {
+	if msg := strings.TrimSpace(C.GoString(C.xslt_last_error())); msg != "" {
+		return fmt.Errorf("%v: %v", fallback, msg)
+	}
+	return errors.New(fallback)
+}
+
+type stylesheet struct {
+	ptr      C.xsltStylesheetPtr
+	freeOnce sync.Once
+}
+
+func parseStylesheet(b []byte, path string) (*stylesheet, error) {
+	if len(b) == 0 {
+		return nil, errors.New("stylesheet is empty")
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	var cURL *C.char
+	if path != "" {
+		cURL = C.CString(path)
+		defer C.free(unsafe.Pointer(cURL))
+	}
+
+	cData := C.CBytes(b)
+	defer C.free(cData)
+
+	ptr := C.xslt_parse((*C.char)(cData), C.int(len(b)), cURL)
+	if ptr == nil {
+		return nil, lastError("invalid stylesheet")
+	}
+	return &stylesheet{ptr: ptr}, nil
+}
+
+func (s *stylesheet) apply(doc []byte) ([]byte, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	cData := C.CBytes(doc)
+	defer C.free(cData)
+
+	var out *C.xmlChar
+	var outLen C.int
+	switch C.xslt_apply(s.ptr, (*C.char)(cData), C.int(len(doc)), &out, &outLen) {
+	case 0:
+	case 1:
+		return nil, lastError("failed to parse message as XML")
+	case 2:
+		return nil, lastError("failed to apply stylesheet")
+	default:
+		return nil, errors.New("failed to serialise transformation result")
+	}
+	if out == nil {
+		return []byte{}, nil
+	}
+	defer C.xslt_free_result(out)
+	return C.GoBytes(unsafe.Pointer(out), outLen), nil
+}
+
+func (s *stylesheet) free() {
+	s.freeOnce.Do(func() {
+		C.xsltFreeStylesheet(s.ptr)
+	})
+}
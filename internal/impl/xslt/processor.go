@@ -0,0 +1,148 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build x_benthos_extra
+// +build x_benthos_extra
+
+package xslt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	xpFieldStylesheet = "stylesheet"
+	xpFieldFile       = "file"
+)
+
+func xsltProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Parsing").
+		Version("4.31.0").
+		Summary("Applies an XSLT stylesheet to XML documents, replacing the contents of each message with the result of the transformation.").
+		Description(`
+The stylesheet is parsed and compiled when the processor is created, and therefore an invalid stylesheet will prevent the pipeline from starting. Messages that are not valid XML documents, or that fail to be transformed, will be flagged as failed and can be handled using xref:configuration:error_handling.adoc[error handling methods].
+
+This processor is implemented with http://xmlsoft.org/XSLT/[libxslt^], which supports XSLT 1.0 only. Stylesheets that require XSLT 2.0 or later are not supported. Stylesheets are not permitted to write files or directories, and documents are never loaded over the network.
+
+By default Redpanda Connect does not build with components that require linking to external libraries. If you wish to build Redpanda Connect locally with this component then set the build tag `+"`x_benthos_extra`"+`:
+
+`+"```bash"+`
+# With go
+go install -tags "x_benthos_extra" github.com/redpanda-data/connect/v4/cmd/redpanda-connect@latest
+
+# Using make
+make TAGS=x_benthos_extra
+`+"```"+`
+
+There is a specific docker tag postfix `+"`-cgo`"+` for C builds containing this component.`).
+		Field(service.NewStringField(xpFieldStylesheet).
+			Description("An inline XSLT stylesheet to apply. One of `"+xpFieldStylesheet+"` or `"+xpFieldFile+"` must be defined.").
+			Example(`<xsl:stylesheet version="1.0" xmlns:xsl="http://www.w3.org/1999/XSL/Transform">
+  <xsl:template match="/">
+    <name><xsl:value-of select="/person/name"/></name>
+  </xsl:template>
+</xsl:stylesheet>`).
+			Optional()).
+		Field(service.NewStringField(xpFieldFile).
+			Description("The path of a file containing an XSLT stylesheet to apply. Relative `xsl:include` and `xsl:import` paths are resolved from the location of this file. One of `"+xpFieldStylesheet+"` or `"+xpFieldFile+"` must be defined.").
+			Example("./stylesheets/orders.xsl").
+			Optional()).
+		LintRule(fmt.Sprintf(`
+let styleLen = (this.%v | "").length()
+let fileLen = (this.%v | "").length()
+root = if $styleLen == 0 && $fileLen == 0 {
+  "either the stylesheet or file field must be specified"
+} else if $styleLen > 0 && $fileLen > 0 {
+  "cannot specify both the stylesheet and file fields"
+}`, xpFieldStylesheet, xpFieldFile)).
+		Example("Convert to CSV", "Stylesheets can output text as well as XML, here we flatten a list of orders into CSV rows.", `
+pipeline:
+  processors:
+    - xslt:
+        stylesheet: |
+          <xsl:stylesheet version="1.0" xmlns:xsl="http://www.w3.org/1999/XSL/Transform">
+            <xsl:output method="text"/>
+            <xsl:template match="/orders">
+              <xsl:for-each select="order">
+                <xsl:value-of select="@id"/>,<xsl:value-of select="total"/><xsl:text>&#10;</xsl:text>
+              </xsl:for-each>
+            </xsl:template>
+          </xsl:stylesheet>
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"xslt", xsltProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return xsltProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type xsltProc struct {
+	style *stylesheet
+}
+
+func xsltProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*xsltProc, error) {
+	inline, _ := conf.FieldString(xpFieldStylesheet)
+	file, _ := conf.FieldString(xpFieldFile)
+	if inline == "" && file == "" {
+		return nil, fmt.Errorf("either a `%v` or `%v` must be specified", xpFieldStylesheet, xpFieldFile)
+	}
+
+	styleBytes := []byte(inline)
+	if file != "" {
+		var err error
+		if styleBytes, err = service.ReadFile(mgr.FS(), file); err != nil {
+			return nil, fmt.Errorf("failed to open target file: %w", err)
+		}
+	}
+
+	style, err := parseStylesheet(styleBytes, file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse stylesheet: %w", err)
+	}
+	return &xsltProc{style: style}, nil
+}
+
+func (p *xsltProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	mBytes, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(mBytes) == 0 {
+		return nil, errors.New("message is empty")
+	}
+
+	res, err := p.style.apply(mBytes)
+	if err != nil {
+		return nil, err
+	}
+	msg.SetBytes(res)
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *xsltProc) Close(ctx context.Context) error {
+	p.style.free()
+	return nil
+}
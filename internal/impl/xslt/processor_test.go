@@ -0,0 +1,129 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build x_benthos_extra
+// +build x_benthos_extra
+
+package xslt
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const testStylesheet = `<xsl:stylesheet version="1.0" xmlns:xsl="http://www.w3.org/1999/XSL/Transform">
+  <xsl:output method="xml" omit-xml-declaration="yes"/>
+  <xsl:template match="/person">
+    <greeting>Hello <xsl:value-of select="name"/></greeting>
+  </xsl:template>
+</xsl:stylesheet>`
+
+func TestXSLTInline(t *testing.T) {
+	conf, err := xsltProcSpec().ParseYAML(`
+stylesheet: |
+  <xsl:stylesheet version="1.0" xmlns:xsl="http://www.w3.org/1999/XSL/Transform">
+    <xsl:output method="text"/>
+    <xsl:template match="/orders">
+      <xsl:for-each select="order">
+        <xsl:value-of select="@id"/>,<xsl:value-of select="total"/><xsl:text>&#10;</xsl:text>
+      </xsl:for-each>
+    </xsl:template>
+  </xsl:stylesheet>
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := xsltProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = proc.Close(context.Background()) })
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(
+		`<orders><order id="1"><total>10</total></order><order id="2"><total>20</total></order></orders>`,
+	)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	mBytes, err := res[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "1,10\n2,20\n", string(mBytes))
+}
+
+func TestXSLTFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "greet.xsl")
+	require.NoError(t, os.WriteFile(path, []byte(testStylesheet), 0o644))
+
+	conf, err := xsltProcSpec().ParseYAML(`file: `+path, nil)
+	require.NoError(t, err)
+
+	proc, err := xsltProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = proc.Close(context.Background()) })
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			res, err := proc.Process(context.Background(), service.NewMessage([]byte(
+				`<person><name>Ash</name></person>`,
+			)))
+			require.NoError(t, err)
+			require.Len(t, res, 1)
+
+			mBytes, err := res[0].AsBytes()
+			require.NoError(t, err)
+			assert.Equal(t, "<greeting>Hello Ash</greeting>\n", string(mBytes))
+		}()
+	}
+	wg.Wait()
+}
+
+func TestXSLTBadDocument(t *testing.T) {
+	conf, err := xsltProcSpec().ParseYAML("stylesheet: '"+testStylesheet+"'", nil)
+	require.NoError(t, err)
+
+	proc, err := xsltProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = proc.Close(context.Background()) })
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`<person><name>Ash</person>`)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse message as XML")
+
+	_, err = proc.Process(context.Background(), service.NewMessage(nil))
+	require.Error(t, err)
+}
+
+func TestXSLTBadStylesheet(t *testing.T) {
+	for _, style := range []string{
+		`<xsl:stylesheet version="1.0"`,
+		`<foo>not a stylesheet</foo>`,
+		`<xsl:stylesheet version="1.0" xmlns:xsl="http://www.w3.org/1999/XSL/Transform"><xsl:template match="/"><xsl:value-of select="???"/></xsl:template></xsl:stylesheet>`,
+	} {
+		pConf, err := xsltProcSpec().ParseYAML("stylesheet: '"+style+"'", nil)
+		require.NoError(t, err)
+
+		_, err = xsltProcFromParsed(pConf, service.MockResources())
+		require.Error(t, err, style)
+	}
+}
@@ -26,6 +26,7 @@ import (
 	_ "github.com/redpanda-data/connect/v4/public/components/aws"
 	_ "github.com/redpanda-data/connect/v4/public/components/azure"
 	_ "github.com/redpanda-data/connect/v4/public/components/beanstalkd"
+	_ "github.com/redpanda-data/connect/v4/public/components/bolt"
 	_ "github.com/redpanda-data/connect/v4/public/components/cassandra"
 	_ "github.com/redpanda-data/connect/v4/public/components/changelog"
 	_ "github.com/redpanda-data/connect/v4/public/components/cockroachdb"
@@ -36,12 +37,14 @@ import (
 	_ "github.com/redpanda-data/connect/v4/public/components/discord"
 	_ "github.com/redpanda-data/connect/v4/public/components/elasticsearch"
 	_ "github.com/redpanda-data/connect/v4/public/components/gcp"
+	_ "github.com/redpanda-data/connect/v4/public/components/graphql"
 	_ "github.com/redpanda-data/connect/v4/public/components/hdfs"
 	_ "github.com/redpanda-data/connect/v4/public/components/influxdb"
 	_ "github.com/redpanda-data/connect/v4/public/components/io"
 	_ "github.com/redpanda-data/connect/v4/public/components/jaeger"
 	_ "github.com/redpanda-data/connect/v4/public/components/javascript"
 	_ "github.com/redpanda-data/connect/v4/public/components/kafka"
+	_ "github.com/redpanda-data/connect/v4/public/components/ldap"
 	_ "github.com/redpanda-data/connect/v4/public/components/maxmind"
 	_ "github.com/redpanda-data/connect/v4/public/components/memcached"
 	_ "github.com/redpanda-data/connect/v4/public/components/mongodb"
@@ -52,6 +55,7 @@ import (
 	_ "github.com/redpanda-data/connect/v4/public/components/nsq"
 	_ "github.com/redpanda-data/connect/v4/public/components/opensearch"
 	_ "github.com/redpanda-data/connect/v4/public/components/otlp"
+	_ "github.com/redpanda-data/connect/v4/public/components/postgresql"
 	_ "github.com/redpanda-data/connect/v4/public/components/prometheus"
 	_ "github.com/redpanda-data/connect/v4/public/components/pulsar"
 	_ "github.com/redpanda-data/connect/v4/public/components/pure"
@@ -60,6 +64,8 @@ import (
 	_ "github.com/redpanda-data/connect/v4/public/components/redis"
 	_ "github.com/redpanda-data/connect/v4/public/components/sentry"
 	_ "github.com/redpanda-data/connect/v4/public/components/sftp"
+	_ "github.com/redpanda-data/connect/v4/public/components/slack"
+	_ "github.com/redpanda-data/connect/v4/public/components/smtp"
 	_ "github.com/redpanda-data/connect/v4/public/components/snowflake"
 	_ "github.com/redpanda-data/connect/v4/public/components/splunk"
 	_ "github.com/redpanda-data/connect/v4/public/components/sql"
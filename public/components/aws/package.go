@@ -17,6 +17,7 @@ package aws
 import (
 	// Bring in the internal plugin definitions.
 	_ "github.com/redpanda-data/connect/v4/internal/impl/aws"
+	_ "github.com/redpanda-data/connect/v4/internal/impl/crypto/aws"
 	_ "github.com/redpanda-data/connect/v4/internal/impl/elasticsearch/aws"
 	_ "github.com/redpanda-data/connect/v4/internal/impl/kafka/aws"
 	_ "github.com/redpanda-data/connect/v4/internal/impl/opensearch/aws"
@@ -16,5 +16,6 @@ package gcp
 
 import (
 	// Bring in the internal plugin definitions.
+	_ "github.com/redpanda-data/connect/v4/internal/impl/crypto/gcp"
 	_ "github.com/redpanda-data/connect/v4/internal/impl/gcp"
 )